@@ -0,0 +1,61 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// listAgents shows the versions of the node agents
+func (c *Cli) listAgents(ctx *cli.Context) error {
+	agents, err := c.client.AgentsList(ctx.Context)
+	if err != nil {
+		return err
+	}
+	for _, a := range agents {
+		version := a.AgentVersion
+		if version == "" {
+			version = "unknown"
+		}
+		status := "ok"
+		if a.Skewed {
+			status = "outdated (" + a.DesiredVersion + ")"
+		}
+		if a.ProtocolError != "" {
+			status = "refused: " + a.ProtocolError
+		}
+		if !a.AgentAlive {
+			status += ", offline"
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\n",
+			a.BackendID, a.Host, version, status)
+	}
+	return nil
+}
+
+// setAgentRelease sets the desired agent version
+func (c *Cli) setAgentRelease(ctx *cli.Context) error {
+	version := ctx.Args().Get(0)
+	if version == "" {
+		return fmt.Errorf("require: <version>")
+	}
+	release, err := c.client.AgentReleaseSet(ctx.Context, &store.AgentRelease{
+		Version: version,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Agent release:", release.Version)
+	return nil
+}
+
+// removeAgentRelease deletes the desired agent version
+func (c *Cli) removeAgentRelease(ctx *cli.Context) error {
+	if _, err := c.client.AgentReleaseDelete(ctx.Context); err != nil {
+		return err
+	}
+	fmt.Println("agent release removed")
+	return nil
+}
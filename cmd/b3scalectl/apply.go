@@ -0,0 +1,143 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+	"gopkg.in/yaml.v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
+)
+
+// normalizeYAML converts the maps decoded by yaml
+// into maps with string keys, so the document can
+// be encoded as JSON.
+func normalizeYAML(v interface{}) (interface{}, error) {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			key, ok := k.(string)
+			if !ok {
+				return nil, fmt.Errorf("invalid key: %v", k)
+			}
+			n, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			m[key] = n
+		}
+		return m, nil
+	case []interface{}:
+		for i, item := range val {
+			n, err := normalizeYAML(item)
+			if err != nil {
+				return nil, err
+			}
+			val[i] = n
+		}
+		return val, nil
+	}
+	return v, nil
+}
+
+// readClusterDeclaration reads a YAML or JSON
+// document describing the cluster.
+func readClusterDeclaration(filename string) (*v1.ClusterDeclaration, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc, err = normalizeYAML(doc)
+	if err != nil {
+		return nil, err
+	}
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	decl := &v1.ClusterDeclaration{}
+	if err := json.Unmarshal(data, decl); err != nil {
+		return nil, err
+	}
+	return decl, nil
+}
+
+// printPlan displays the changes
+func printPlan(changes []*v1.PlanChange) {
+	for _, change := range changes {
+		symbol := "~"
+		switch change.Action {
+		case v1.PlanCreate:
+			symbol = "+"
+		case v1.PlanRemove:
+			symbol = "-"
+		}
+		line := fmt.Sprintf("  %s %s %s", symbol, change.Resource, change.Key)
+		if len(change.Fields) > 0 {
+			line += " (" + strings.Join(change.Fields, ", ") + ")"
+		}
+		fmt.Println(line)
+	}
+}
+
+// confirm asks the user for approval
+func confirm(question string) bool {
+	fmt.Print(question + " [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
+
+// applyCluster brings the cluster into the state
+// declared in a file. The plan is shown first.
+func (c *Cli) applyCluster(ctx *cli.Context) error {
+	filename := ctx.String("file")
+	if filename == "" {
+		return fmt.Errorf("require: -f <cluster.yml>")
+	}
+	decl, err := readClusterDeclaration(filename)
+	if err != nil {
+		return err
+	}
+	decl.Prune = ctx.Bool("prune")
+
+	plan, err := c.client.ClusterApply(ctx.Context, decl, true, "")
+	if err != nil {
+		return err
+	}
+	if len(plan.Changes) == 0 {
+		fmt.Println("No changes. The cluster matches the declaration.")
+		c.returnCode = RetNoChange
+		return nil
+	}
+
+	fmt.Println("Plan:")
+	printPlan(plan.Changes)
+
+	if ctx.Bool("dry") {
+		return nil
+	}
+	if !ctx.Bool("yes") && !confirm("Apply these changes?") {
+		c.returnCode = RetNoChange
+		return nil
+	}
+
+	// The changes are only applied if they are
+	// still the same as in the plan shown.
+	res, err := c.client.ClusterApply(ctx.Context, decl, false, plan.Plan)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Applied", len(res.Changes), "changes.")
+	return nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// listBans shows all bans
+func (c *Cli) listBans(ctx *cli.Context) error {
+	bans, err := c.client.BansList(ctx.Context, url.Values{})
+	if err != nil {
+		return err
+	}
+	for _, b := range bans {
+		frontendID := "*"
+		if b.FrontendID != nil {
+			frontendID = *b.FrontendID
+		}
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n",
+			b.ID, frontendID, b.Kind, b.Pattern, b.Reason)
+	}
+	return nil
+}
+
+// addBan blocks a meeting ID or user names
+func (c *Cli) addBan(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <kind> <pattern>")
+	}
+	ban := &store.Ban{
+		Kind:    ctx.Args().Get(0),
+		Pattern: ctx.Args().Get(1),
+		Reason:  ctx.String("reason"),
+	}
+	if key := ctx.String("frontend"); key != "" {
+		frontend, err := getFrontendByKey(ctx.Context, c.client, key)
+		if err != nil {
+			return err
+		}
+		if frontend == nil {
+			return fmt.Errorf("no such frontend")
+		}
+		ban.FrontendID = &frontend.ID
+	}
+	ban, err := c.client.BanCreate(ctx.Context, ban)
+	if err != nil {
+		return err
+	}
+	fmt.Println("Ban:", ban.ID)
+	return nil
+}
+
+// removeBan deletes a ban
+func (c *Cli) removeBan(ctx *cli.Context) error {
+	id := ctx.Args().Get(0)
+	if id == "" {
+		return fmt.Errorf("require: <id>")
+	}
+	if _, err := c.client.BanDelete(ctx.Context, id); err != nil {
+		return err
+	}
+	fmt.Println("ban removed")
+	return nil
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
+)
+
+// botHelp lists the commands of the bot
+const botHelp = "commands: status, backends, drain <host>, resume <host>"
+
+// The chatBot reports the health of the cluster into an
+// ops channel through an incoming webhook and accepts a
+// safe subset of commands as slash command (Mattermost or
+// Slack). The commands are mapped onto the admin API.
+type chatBot struct {
+	client  v1.Client
+	token   string
+	webhook *notify.WebhookChannel
+}
+
+// runBot starts the chat ops bot
+func (c *Cli) runBot(ctx *cli.Context) error {
+	interval, err := time.ParseDuration(ctx.String("interval"))
+	if err != nil {
+		return err
+	}
+	b := &chatBot{
+		client: c.client,
+		token:  ctx.String("token"),
+	}
+	if url := ctx.String("webhook"); url != "" {
+		b.webhook = &notify.WebhookChannel{
+			URL:    url,
+			Format: notify.FormatText,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	if b.token == "" && b.webhook == nil {
+		return fmt.Errorf("require: --token or --webhook")
+	}
+
+	if b.webhook != nil {
+		go b.reportHealth(ctx.Context, interval)
+	}
+	if b.token == "" {
+		<-ctx.Context.Done() // Only report
+		return nil
+	}
+	srv := &http.Server{
+		Addr:         ctx.String("listen"),
+		Handler:      b,
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 30 * time.Second,
+	}
+	fmt.Fprintln(os.Stderr, "accepting slash commands on", srv.Addr)
+	return srv.ListenAndServe()
+}
+
+// reportHealth posts the health of the cluster when it
+// changes. The health is checked in the interval.
+func (b *chatBot) reportHealth(ctx context.Context, interval time.Duration) {
+	last := ""
+	for {
+		report, err := b.health(ctx)
+		if err != nil {
+			report = "could not retrieve the cluster health: " + err.Error()
+		}
+		if report != last {
+			if err := b.webhook.Send(ctx, &notify.Event{
+				Type:    "cluster_health",
+				Subject: "cluster",
+				Message: report,
+				Time:    time.Now().UTC(),
+			}); err != nil {
+				fmt.Fprintln(os.Stderr, "could not post health report:", err)
+			} else {
+				last = report
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(interval):
+		}
+	}
+}
+
+// health summarizes the state of the backends
+func (b *chatBot) health(ctx context.Context) (string, error) {
+	backends, err := b.client.BackendsList(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	ready := 0
+	meetings, attendees := uint(0), uint(0)
+	problems := []string{}
+	for _, s := range backends {
+		meetings += s.MeetingsCount
+		attendees += s.AttendeesCount
+		if s.NodeState == "ready" && s.AdminState == "ready" {
+			ready++
+			continue
+		}
+		problems = append(problems, fmt.Sprintf(
+			"%s (node: %s, admin: %s)",
+			s.Backend.Host, s.NodeState, s.AdminState))
+	}
+	report := fmt.Sprintf(
+		"%d of %d backends ready, %d meetings, %d attendees",
+		ready, len(backends), meetings, attendees)
+	if len(problems) > 0 {
+		report += "\nnot ready: " + strings.Join(problems, ", ")
+	}
+	return report, nil
+}
+
+// ServeHTTP handles slash commands
+func (b *chatBot) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	token := r.PostForm.Get("token")
+	if subtle.ConstantTimeCompare([]byte(token), []byte(b.token)) != 1 {
+		http.Error(w, "invalid token", http.StatusUnauthorized)
+		return
+	}
+	user := r.PostForm.Get("user_name")
+	args := strings.Fields(r.PostForm.Get("text"))
+	fmt.Fprintln(os.Stderr, "command by", user+":", strings.Join(args, " "))
+
+	reply, err := b.command(r.Context(), args)
+	if err != nil {
+		reply = "error: " + err.Error()
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"response_type": "in_channel",
+		"text":          reply,
+	})
+}
+
+// command runs a bot command
+func (b *chatBot) command(ctx context.Context, args []string) (string, error) {
+	if len(args) == 0 {
+		return botHelp, nil
+	}
+	switch args[0] {
+	case "status":
+		return b.health(ctx)
+	case "backends":
+		return b.listBackends(ctx)
+	case "drain":
+		return b.setAdminState(ctx, args[1:], "stopped")
+	case "resume":
+		return b.setAdminState(ctx, args[1:], "ready")
+	}
+	return botHelp, nil
+}
+
+// listBackends formats the backends as list
+func (b *chatBot) listBackends(ctx context.Context) (string, error) {
+	backends, err := b.client.BackendsList(ctx, nil)
+	if err != nil {
+		return "", err
+	}
+	lines := make([]string, 0, len(backends))
+	for _, s := range backends {
+		lines = append(lines, fmt.Sprintf(
+			"%s node: %s, admin: %s, meetings: %d, attendees: %d",
+			s.Backend.Host, s.NodeState, s.AdminState,
+			s.MeetingsCount, s.AttendeesCount))
+	}
+	if len(lines) == 0 {
+		return "no backends", nil
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// setAdminState drains or resumes a backend. A drained
+// backend does not get new meetings, running meetings
+// are not affected.
+func (b *chatBot) setAdminState(
+	ctx context.Context,
+	args []string,
+	adminState string,
+) (string, error) {
+	if len(args) != 1 {
+		return "", fmt.Errorf("require: <host>")
+	}
+	host := args[0]
+	if !strings.HasSuffix(host, "/") {
+		host += "/"
+	}
+	state, err := getBackendByHost(ctx, b.client, host)
+	if err != nil {
+		return "", err
+	}
+	if state == nil {
+		return "", fmt.Errorf("backend not found")
+	}
+	if state.AdminState == adminState {
+		return "no changes", nil
+	}
+	state.AdminState = adminState
+	if _, err := b.client.BackendUpdate(ctx, state); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s admin state: %s", host, adminState), nil
+}
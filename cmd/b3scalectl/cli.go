@@ -4,14 +4,17 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"net/url"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/urfave/cli/v2"
 	"golang.org/x/term"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
 	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
@@ -139,6 +142,10 @@ func NewCli() *Cli {
 								Name:  "secret",
 								Usage: "the frontend specific bbb secret",
 							},
+							&cli.StringFlag{
+								Name:  "parent",
+								Usage: "the key of the parent frontend, empty for none",
+							},
 							&cli.StringFlag{
 								Name:    "opts",
 								Aliases: []string{"j"},
@@ -147,6 +154,21 @@ func NewCli() *Cli {
 						},
 						Action: c.setFrontend,
 					},
+					{
+						Name:  "logging",
+						Usage: "change the log level and format of the server",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "level",
+								Usage: "the log level, e.g. debug",
+							},
+							&cli.StringFlag{
+								Name:  "format",
+								Usage: "the log format: plain or structured",
+							},
+						},
+						Action: c.setLogging,
+					},
 				},
 			},
 			{
@@ -210,17 +232,407 @@ func NewCli() *Cli {
 					},
 				},
 			},
+			{
+				Name:  "rotate",
+				Usage: "regenerate credentials",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "frontend",
+						Usage: "regenerate the secret of a frontend <key>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "overlap",
+								Usage: "accept the previous secret for this duration",
+								Value: "24h",
+							},
+						},
+						Action: c.rotateFrontendSecret,
+					},
+				},
+			},
+			{
+				Name:  "keys",
+				Usage: "manage additional keys of a frontend",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "list the additional keys of a <frontend>",
+						Action: c.listFrontendKeys,
+					},
+					{
+						Name:  "add",
+						Usage: "add a <key> to a <frontend>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "secret",
+								Usage: "the secret of the key, generated if empty",
+							},
+							&cli.StringFlag{
+								Name:  "description",
+								Usage: "describe the key, e.g. staging",
+							},
+						},
+						Action: c.addFrontendKey,
+					},
+					{
+						Name:    "remove",
+						Aliases: []string{"rm"},
+						Usage:   "remove a <key> from a <frontend>",
+						Action:  c.removeFrontendKey,
+					},
+				},
+			},
+			{
+				Name:  "rooms",
+				Usage: "manage the rooms of a frontend",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "list the rooms of a <frontend>",
+						Action: c.listFrontendRooms,
+					},
+					{
+						Name:  "add",
+						Usage: "add a room <meeting id> to a <frontend>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "name",
+								Usage: "the name of the meeting",
+							},
+							&cli.StringSliceFlag{
+								Name:  "param",
+								Usage: "an additional create parameter, e.g. record=true",
+							},
+						},
+						Action: c.addFrontendRoom,
+					},
+					{
+						Name:  "schedule",
+						Usage: "schedule a room <meeting id> of a <frontend> with a <cron expression>, or remove the schedule without",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "timezone",
+								Usage: "the timezone of the schedule",
+								Value: "UTC",
+							},
+							&cli.IntFlag{
+								Name:  "duration",
+								Usage: "the duration of the meeting in minutes",
+							},
+						},
+						Action: c.scheduleFrontendRoom,
+					},
+					{
+						Name:    "remove",
+						Aliases: []string{"rm"},
+						Usage:   "remove a room <meeting id> from a <frontend>",
+						Action:  c.removeFrontendRoom,
+					},
+				},
+			},
+			{
+				Name:  "pins",
+				Usage: "pin meetings to backends",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "list the meeting pins",
+						Action: c.listMeetingPins,
+					},
+					{
+						Name:  "add",
+						Usage: "pin a <meeting id> of a <frontend> to a backend <host>",
+						Flags: []cli.Flag{
+							&cli.DurationFlag{
+								Name:  "ttl",
+								Usage: "the time until the pin expires",
+								Value: 24 * time.Hour,
+							},
+							&cli.StringFlag{
+								Name:  "note",
+								Usage: "a note for the support, e.g. the event",
+							},
+						},
+						Action: c.addMeetingPin,
+					},
+					{
+						Name:    "remove",
+						Aliases: []string{"rm"},
+						Usage:   "remove the pin of a <meeting id> of a <frontend>",
+						Action:  c.removeMeetingPin,
+					},
+				},
+			},
+			{
+				Name:  "bans",
+				Usage: "block meeting IDs and user names",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "list the bans",
+						Action: c.listBans,
+					},
+					{
+						Name: "add",
+						Usage: "ban a <kind> (meeting_id or full_name) with a <pattern>, " +
+							"the full name pattern is a regular expression",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:  "frontend",
+								Usage: "limit the ban to the frontend with the key",
+							},
+							&cli.StringFlag{
+								Name:  "reason",
+								Usage: "the reason of the ban",
+							},
+						},
+						Action: c.addBan,
+					},
+					{
+						Name:    "remove",
+						Aliases: []string{"rm"},
+						Usage:   "remove a ban by <id>",
+						Action:  c.removeBan,
+					},
+				},
+			},
+			{
+				Name:  "agents",
+				Usage: "show and update the versions of the node agents",
+				Subcommands: []*cli.Command{
+					{
+						Name:   "list",
+						Usage:  "list the versions of the agents",
+						Action: c.listAgents,
+					},
+					{
+						Name: "release",
+						Usage: "set the desired agent <version>, " +
+							"other versions are reported as outdated",
+						Action: c.setAgentRelease,
+					},
+					{
+						Name:   "unrelease",
+						Usage:  "remove the desired agent version",
+						Action: c.removeAgentRelease,
+					},
+				},
+			},
 			{
 				Name:  "end",
 				Usage: "force ending things on a backend",
 				Subcommands: []*cli.Command{
 					{
-						Name:   "meetings",
-						Usage:  "end all meetings on a given <host>",
+						Name: "meetings",
+						Usage: "end all meetings on a given <host>, " +
+							"or with --all in the whole cluster",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "wait",
+								Usage: "wait until the meetings are ended",
+							},
+							&cli.BoolFlag{
+								Name:  "all",
+								Usage: "end the meetings on all backends",
+							},
+							&cli.StringFlag{
+								Name:  "message",
+								Usage: "announce the end in the chat of the meetings",
+							},
+							&cli.StringFlag{
+								Name:  "delay",
+								Usage: "end the meetings after a delay, e.g. 5m",
+							},
+						},
 						Action: c.endAllMeetings,
 					},
 				},
 			},
+			{
+				Name:  "reconcile",
+				Usage: "compare the store with the backends",
+				Subcommands: []*cli.Command{
+					{
+						Name: "meetings",
+						Usage: "list meetings known only to the store " +
+							"or only to the backend <host>, or all backends",
+						Action: c.reconcileMeetings,
+					},
+					{
+						Name: "recordings",
+						Usage: "list recordings known only to the store " +
+							"or only to the backend <host>, or all backends",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{
+								Name:  "import",
+								Usage: "add recordings missing in the store",
+							},
+						},
+						Action: c.reconcileRecordings,
+					},
+				},
+			},
+			{
+				Name: "broadcast",
+				Usage: "post a <message> to the chat of all running " +
+					"meetings on a given <host>",
+				Action: c.broadcastMessage,
+			},
+			{
+				Name:  "collect",
+				Usage: "collect information from a backend",
+				Subcommands: []*cli.Command{
+					{
+						Name: "diagnostics",
+						Usage: "download bbb-conf --check and log " +
+							"excerpts from the node agent of <host>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{
+								Name:    "output",
+								Aliases: []string{"o"},
+								Usage:   "the file for the bundle (.tar.gz)",
+							},
+						},
+						Action: c.collectDiagnostics,
+					},
+				},
+			},
+			{
+				Name:  "bot",
+				Usage: "run a chat ops bot reporting the cluster health and accepting slash commands",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "the listen address for the slash commands",
+						Value: "127.0.0.1:8093",
+					},
+					&cli.StringFlag{
+						Name:    "token",
+						Usage:   "the token of the slash command",
+						EnvVars: []string{"B3SCALE_BOT_TOKEN"},
+					},
+					&cli.StringFlag{
+						Name:  "webhook",
+						Usage: "the incoming webhook of the ops channel for health reports",
+					},
+					&cli.StringFlag{
+						Name:  "interval",
+						Usage: "check the cluster health in this interval",
+						Value: "1m",
+					},
+				},
+				Action: c.runBot,
+			},
+			{
+				Name:  "apply",
+				Usage: "apply a declaration of backends and frontends",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "the cluster declaration (yaml or json)",
+					},
+					&cli.BoolFlag{
+						Name:  "prune",
+						Usage: "remove backends and frontends not declared",
+					},
+					&cli.BoolFlag{
+						Name:  "dry",
+						Usage: "only show the plan",
+					},
+					&cli.BoolFlag{
+						Name:  "yes",
+						Usage: "apply without asking for confirmation",
+					},
+				},
+				Action: c.applyCluster,
+			},
+			{
+				Name:  "dump",
+				Usage: "export the cluster configuration as json",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "write the dump to a file instead of stdout",
+					},
+					&cli.BoolFlag{
+						Name:  "meetings",
+						Usage: "include meetings and recordings",
+					},
+				},
+				Action: c.dumpCluster,
+			},
+			{
+				Name:  "restore",
+				Usage: "import a dump of the cluster configuration",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "file",
+						Aliases: []string{"f"},
+						Usage:   "the dump to restore",
+					},
+					&cli.BoolFlag{
+						Name:  "dry",
+						Usage: "only show what would be restored",
+					},
+					&cli.BoolFlag{
+						Name:  "yes",
+						Usage: "restore without asking for confirmation",
+					},
+				},
+				Action: c.restoreCluster,
+			},
+			{
+				Name: "recover",
+				Usage: "rebuild meetings and recordings in the store " +
+					"from the backend <host>, or all backends",
+				Action: c.recoverCluster,
+			},
+			{
+				Name:  "replay",
+				Usage: "replay captured request traces <trace file>...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name: "target",
+						Usage: "the api endpoint, e.g. " +
+							"https://staging/bbb/<frontend>/bigbluebutton/api/",
+					},
+					&cli.StringFlag{
+						Name:  "secret",
+						Usage: "the secret of the frontend or backend",
+					},
+					&cli.StringFlag{
+						Name:  "meeting-id",
+						Usage: "replace the meeting id of the requests",
+					},
+					&cli.BoolFlag{
+						Name:  "keep-timing",
+						Usage: "wait between requests as recorded",
+					},
+					&cli.BoolFlag{
+						Name:  "mock",
+						Usage: "replay against a mock backend with the recorded responses",
+					},
+				},
+				Action: c.replayTraces,
+			},
+			{
+				Name:  "mock-backend",
+				Usage: "serve a mock backend answering with the responses of <trace file>...",
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:  "listen",
+						Usage: "the listen address",
+						Value: "127.0.0.1:8090",
+					},
+					&cli.StringFlag{
+						Name:  "secret",
+						Usage: "the secret of the backend, checksums are not verified without",
+					},
+				},
+				Action: c.serveMockBackend,
+			},
 			{
 				Name:   "version",
 				Action: c.showVersion,
@@ -232,6 +644,12 @@ func NewCli() *Cli {
 
 // init initializes the app
 func (c *Cli) init(ctx *cli.Context) error {
+	// Replaying traces does not require the API
+	switch ctx.Args().First() {
+	case "replay", "mock-backend":
+		return nil
+	}
+
 	apiHost := ctx.String("api")
 	tokenFilename := apiHost + ".access_token"
 
@@ -313,7 +731,34 @@ func (c *Cli) showStatus(ctx *cli.Context) error {
 	fmt.Println("")
 	fmt.Println("server version:", status.Version, "\tbuild:", status.Build)
 	fmt.Println("   api version:", status.API)
+	if status.Database != "" {
+		fmt.Println("      database:", status.Database)
+	}
 	fmt.Println("")
+
+	// Print the cluster topology
+	topology, err := c.client.ClusterTopology(ctx.Context, nil)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("backends: %d\tmeetings: %d\tattendees: %d\n",
+		len(topology.Backends),
+		topology.Meetings,
+		topology.Attendees)
+	fmt.Println("")
+	for _, b := range topology.Backends {
+		fmt.Printf("%s\n  Health:\t %s", b.Host, b.Health)
+		if b.Health == store.BackendHealthError && b.LastError != nil {
+			fmt.Printf(" (%s)", *b.LastError)
+		}
+		fmt.Println("")
+		fmt.Printf("  MC/AC:\t %d/%d\n", b.MeetingsCount, b.AttendeesCount)
+		fmt.Printf("  Version:\t %s\tAgent: %s\n", b.Version, b.AgentVersion)
+		if len(b.Tags) > 0 {
+			fmt.Printf("  Tags:\t %s\n", strings.Join(b.Tags, ", "))
+		}
+		fmt.Println("")
+	}
 	return nil
 }
 
@@ -352,6 +797,12 @@ func (c *Cli) setFrontend(ctx *cli.Context) error {
 				return err
 			}
 		}
+		if ctx.IsSet("parent") {
+			if state.ParentID, err = c.getParentID(
+				ctx, ctx.String("parent")); err != nil {
+				return err
+			}
+		}
 		if !dry {
 			state, err = c.client.FrontendCreate(ctx.Context, state)
 			if err != nil {
@@ -381,6 +832,14 @@ func (c *Cli) setFrontend(ctx *cli.Context) error {
 			changes = true
 		}
 
+		if ctx.IsSet("parent") {
+			if state.ParentID, err = c.getParentID(
+				ctx, ctx.String("parent")); err != nil {
+				return err
+			}
+			changes = true
+		}
+
 		if !changes {
 			fmt.Println("no changes")
 			c.returnCode = RetNoChange
@@ -400,6 +859,22 @@ func (c *Cli) setFrontend(ctx *cli.Context) error {
 	return nil
 }
 
+// getParentID resolves the key of the parent frontend.
+// An empty key removes the parent.
+func (c *Cli) getParentID(ctx *cli.Context, key string) (*string, error) {
+	if key == "" {
+		return nil, nil
+	}
+	parent, err := getFrontendByKey(ctx.Context, c.client, key)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("parent frontend not found: %s", key)
+	}
+	return &parent.ID, nil
+}
+
 // deleteFrontend removes a frontend from the cluster
 func (c *Cli) deleteFrontend(ctx *cli.Context) error {
 	dry := ctx.Bool("dry")
@@ -428,6 +903,114 @@ func (c *Cli) deleteFrontend(ctx *cli.Context) error {
 	return err
 }
 
+// rotateFrontendSecret regenerates the frontend secret.
+// The new secret is only shown once.
+func (c *Cli) rotateFrontendSecret(ctx *cli.Context) error {
+	key := ctx.Args().Get(0)
+	if key == "" {
+		return fmt.Errorf("require: <frontend key>")
+	}
+
+	state, err := getFrontendByKey(ctx.Context, c.client, key)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+
+	res, err := c.client.FrontendRegenerateSecret(
+		ctx.Context, state, url.Values{
+			"overlap": []string{ctx.String("overlap")},
+		})
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Frontend:", state.Frontend.Key)
+	fmt.Println("New secret:", res.Secret)
+	fmt.Println("Previous secret expires at:", res.PreviousSecretExpiresAt)
+	return nil
+}
+
+// listFrontendKeys shows the additional keys of a frontend
+func (c *Cli) listFrontendKeys(ctx *cli.Context) error {
+	key := ctx.Args().Get(0)
+	if key == "" {
+		return fmt.Errorf("require: <frontend key>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, key)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	keys, err := c.client.FrontendKeysList(ctx.Context, state)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		fmt.Printf("%s\t%s\t%s\t%s\n", k.ID, k.Key, k.Secret, k.Description)
+	}
+	return nil
+}
+
+// addFrontendKey adds a key and secret pair to a frontend
+func (c *Cli) addFrontendKey(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <key>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	key, err := c.client.FrontendKeyCreate(ctx.Context, state, &store.FrontendKey{
+		Key:         ctx.Args().Get(1),
+		Secret:      ctx.String("secret"),
+		Description: ctx.String("description"),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Frontend:", state.Frontend.Key)
+	fmt.Println("Key:", key.Key)
+	fmt.Println("Secret:", key.Secret)
+	return nil
+}
+
+// removeFrontendKey deletes an additional key of a frontend
+func (c *Cli) removeFrontendKey(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <key>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	keys, err := c.client.FrontendKeysList(ctx.Context, state)
+	if err != nil {
+		return err
+	}
+	for _, k := range keys {
+		if k.Key != ctx.Args().Get(1) {
+			continue
+		}
+		if _, err := c.client.FrontendKeyDelete(ctx.Context, state, k.ID); err != nil {
+			return err
+		}
+		fmt.Println("key removed")
+		return nil
+	}
+	return fmt.Errorf("no such key")
+}
+
 // showFrontend displays information about a frontend
 func (c *Cli) showFrontend(ctx *cli.Context) error {
 	key := ctx.Args().Get(0)
@@ -464,6 +1047,26 @@ func (c *Cli) showFrontends(ctx *cli.Context) error {
 	return nil
 }
 
+// setLogging changes the logging of the server
+func (c *Cli) setLogging(ctx *cli.Context) error {
+	opts := &v1.LoggingOptions{
+		Level:  ctx.String("level"),
+		Format: ctx.String("format"),
+	}
+	if opts.Level == "" && opts.Format == "" {
+		return fmt.Errorf("require: --level or --format")
+	}
+	if ctx.Bool("dry") {
+		return nil
+	}
+	opts, err := c.client.LoggingUpdate(ctx.Context, opts)
+	if err != nil {
+		return err
+	}
+	fmt.Println("level:", opts.Level, "format:", opts.Format)
+	return nil
+}
+
 // setBackend manages the backends in the cluster
 func (c *Cli) setBackend(ctx *cli.Context) error {
 	adminState := ctx.String("state")
@@ -700,16 +1303,25 @@ func (c *Cli) deleteBackend(ctx *cli.Context) error {
 		(state.NodeState != "ready" && state.NodeState != "init")
 
 	if hardDelete {
-		// The node is down anyhow we can issue a direct delete
+		// The node is down anyhow we can issue a direct delete.
+		// This is refused if meetings are still running,
+		// unless the deletion is forced.
 		if dry {
 			fmt.Println("skipping delete backend (dry run)")
 			return nil
 		}
 		fmt.Println("deleting backend")
-		state, err = c.client.BackendDelete(
-			ctx.Context, state, url.Values{
-				"force": []string{"true"},
-			})
+		query := url.Values{"hard": []string{"true"}}
+		if force {
+			query = url.Values{"force": []string{"true"}}
+		}
+		state, err = c.client.BackendDelete(ctx.Context, state, query)
+		if apiErr, ok := err.(v1.APIError); ok &&
+			apiErr["error"] == "backend_in_use" {
+			return fmt.Errorf(
+				"%v: %v; use --force to delete it anyway",
+				apiErr["message"], apiErr["running_meetings"])
+		}
 		if err != nil {
 			return err
 		}
@@ -731,6 +1343,12 @@ func (c *Cli) deleteBackend(ctx *cli.Context) error {
 
 // end all meetings on a backend
 func (c *Cli) endAllMeetings(ctx *cli.Context) error {
+	if ctx.Bool("all") ||
+		ctx.String("message") != "" ||
+		ctx.String("delay") != "" {
+		return c.endMeetingsGracefully(ctx)
+	}
+
 	// Args should be host
 	if ctx.NArg() < 1 {
 		return fmt.Errorf("require: <host>")
@@ -748,11 +1366,168 @@ func (c *Cli) endAllMeetings(ctx *cli.Context) error {
 	if err != nil {
 		return err
 	}
-	fmt.Println(cmd)
+	if !ctx.Bool("wait") {
+		fmt.Println(cmd)
+		return nil
+	}
+
+	fmt.Println("waiting for command", cmd.ID)
+	cmd, err = c.client.CommandWait(ctx.Context, cmd.ID)
+	if err != nil {
+		return err
+	}
+	if cmd.State != "success" {
+		return fmt.Errorf("ending meetings failed: %v", cmd.Result)
+	}
+	fmt.Println("all meetings ended")
 
 	return nil
 }
 
+// endMeetingsGracefully announces the end of the meetings
+// on a backend or in the cluster and ends them after
+// the delay.
+func (c *Cli) endMeetingsGracefully(ctx *cli.Context) error {
+	req := &cluster.EndMeetingsRequest{
+		Message: ctx.String("message"),
+		Delay:   ctx.String("delay"),
+	}
+	if !ctx.Bool("all") {
+		// Args should be host
+		if ctx.NArg() < 1 {
+			return fmt.Errorf("require: <host> or --all")
+		}
+		state, err := getBackendByHost(
+			ctx.Context, c.client, ctx.Args().Get(0))
+		if err != nil {
+			return err
+		}
+		if state == nil {
+			return fmt.Errorf("no such backend")
+		}
+		req.BackendID = state.ID
+	}
+
+	res, err := c.client.MeetingsEnd(ctx.Context, req)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("ending %d meetings at %s\n",
+		res.Meetings, res.EndAt.Local().Format(time.RFC3339))
+	if !ctx.Bool("wait") {
+		return nil
+	}
+
+	failed := 0
+	for _, cmd := range res.Commands {
+		if cmd.Action != cluster.CmdEndMeeting {
+			continue
+		}
+		cmd, err = c.client.CommandWait(ctx.Context, cmd.ID)
+		if err != nil {
+			return err
+		}
+		if cmd.State != "success" {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("ending %d meetings failed", failed)
+	}
+	fmt.Println("all meetings ended")
+	return nil
+}
+
+// broadcastMessage announces something, like an
+// upcoming maintenance, in the meetings of a backend.
+func (c *Cli) broadcastMessage(ctx *cli.Context) error {
+	// Args should be host and message
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <host> <message>")
+	}
+	host := ctx.Args().Get(0)
+	state, err := getBackendByHost(ctx.Context, c.client, host)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such backend")
+	}
+	message := strings.Join(ctx.Args().Slice()[1:], " ")
+	cmds, err := c.client.BackendBroadcast(
+		ctx.Context, state.ID, &cluster.BackendBroadcastRequest{
+			Message: message,
+		})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("broadcasting to %d meetings\n", len(cmds))
+	return nil
+}
+
+// collectDiagnostics requests a diagnostic bundle
+// from the node agent and downloads it.
+func (c *Cli) collectDiagnostics(ctx *cli.Context) error {
+	// Args should be host
+	if ctx.NArg() < 1 {
+		return fmt.Errorf("require: <host>")
+	}
+	host := ctx.Args().Get(0)
+	state, err := getBackendByHost(ctx.Context, c.client, host)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such backend")
+	}
+
+	cmd, err := c.client.CommandCreate(ctx.Context, &cluster.CommandRequest{
+		Action:    cluster.CmdCollectDiagnostics,
+		BackendID: state.ID,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("waiting for the node agent, command", cmd.ID)
+	cmd, err = c.client.CommandWait(ctx.Context, cmd.ID)
+	if err != nil {
+		return err
+	}
+	if cmd.State != "success" {
+		return fmt.Errorf("collecting diagnostics failed: %v", cmd.Result)
+	}
+
+	// The result is the uploaded bundle
+	bundles, err := c.client.BackendDiagnosticsList(ctx.Context, state.ID)
+	if err != nil {
+		return err
+	}
+	var bundle *store.BackendDiagnostics
+	for _, b := range bundles {
+		if b.CommandID != nil && *b.CommandID == cmd.ID {
+			bundle = b
+		}
+	}
+	if bundle == nil {
+		return fmt.Errorf("the diagnostics were not uploaded")
+	}
+	data, err := c.client.BackendDiagnosticsRetrieve(
+		ctx.Context, state.ID, bundle.ID)
+	if err != nil {
+		return err
+	}
+
+	filename := ctx.String("output")
+	if filename == "" {
+		filename = fmt.Sprintf("diagnostics-%s.tar.gz", state.Backend.Host)
+	}
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		return err
+	}
+	fmt.Println("diagnostics written to", filename)
+	return nil
+}
+
 // show the current version
 func (c *Cli) showVersion(ctx *cli.Context) error {
 	fmt.Printf("b3scalectl v.%s\t%s\n",
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// dumpCluster writes the cluster configuration to a
+// file or stdout. The dump contains secrets.
+func (c *Cli) dumpCluster(ctx *cli.Context) error {
+	query := url.Values{}
+	if ctx.Bool("meetings") {
+		query.Set("meetings", "true")
+	}
+	dump, err := c.client.ClusterDump(ctx.Context, query)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(dump, "", "  ")
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+
+	filename := ctx.String("output")
+	if filename == "" || filename == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	if err := ioutil.WriteFile(filename, data, 0600); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr,
+		"Dumped %d frontends, %d backends and %d meetings to %s\n",
+		len(dump.Frontends), len(dump.Backends), len(dump.Meetings),
+		filename)
+	return nil
+}
+
+// restoreCluster imports a dump
+func (c *Cli) restoreCluster(ctx *cli.Context) error {
+	filename := ctx.String("file")
+	if filename == "" {
+		return fmt.Errorf("require: -f <dump.json>")
+	}
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+	dump := &store.Dump{}
+	if err := json.Unmarshal(data, dump); err != nil {
+		return err
+	}
+	if dump.Version != store.DumpVersion {
+		return store.ErrUnsupportedDumpVersion
+	}
+
+	fmt.Printf("Restoring %d frontends, %d backends and %d meetings from %s\n",
+		len(dump.Frontends), len(dump.Backends), len(dump.Meetings),
+		dump.CreatedAt)
+	if ctx.Bool("dry") {
+		return nil
+	}
+	if !ctx.Bool("yes") && !confirm("Existing frontends and backends "+
+		"with the same key or host are overwritten. Continue?") {
+		c.returnCode = RetNoChange
+		return nil
+	}
+
+	res, err := c.client.ClusterRestore(ctx.Context, dump)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Restored %d frontends, %d backends, %d meetings "+
+		"and %d recordings.\n",
+		res.Frontends, res.Backends, res.Meetings, res.Recordings)
+	return nil
+}
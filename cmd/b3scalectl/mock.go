@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// Responses of the mock backend without a recording
+const (
+	mockSuccessResponse = `<response>
+<returncode>SUCCESS</returncode>
+</response>`
+	mockChecksumErrorResponse = `<response>
+<returncode>FAILED</returncode>
+<messageKey>checksumError</messageKey>
+<message>Checksums do not match</message>
+</response>`
+)
+
+// A mockBackend is a fake BBB node answering the
+// requests with the responses recorded in traces.
+// The recorded responses of a resource are served in
+// order, the last one is repeated. Resources without
+// a recording are answered with a success response.
+type mockBackend struct {
+	secret string
+
+	mtx       sync.Mutex
+	responses map[string][]*bbb.Trace
+	served    map[string]int
+}
+
+// newMockBackend creates a mock backend with the
+// recorded responses. Without a secret, checksums
+// are not verified.
+func newMockBackend(secret string, traces []*bbb.Trace) *mockBackend {
+	responses := map[string][]*bbb.Trace{}
+	for _, t := range traces {
+		responses[t.Resource] = append(responses[t.Resource], t)
+	}
+	return &mockBackend{
+		secret:    secret,
+		responses: responses,
+		served:    map[string]int{},
+	}
+}
+
+// next retrieves the recorded response of the resource
+func (m *mockBackend) next(resource string) *bbb.Trace {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	recorded := m.responses[resource]
+	if len(recorded) == 0 {
+		return nil
+	}
+	i := m.served[resource]
+	if i >= len(recorded) {
+		i = len(recorded) - 1
+	}
+	m.served[resource]++
+	return recorded[i]
+}
+
+// ServeHTTP implements http.Handler
+func (m *mockBackend) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	resource := strings.TrimPrefix(r.URL.Path, "/bigbluebutton/api")
+	resource = strings.TrimPrefix(resource, "/")
+
+	params := bbb.Params{}
+	values := r.URL.Query()
+	for k := range values {
+		params[k] = values.Get(k)
+	}
+	checksum, _ := params.Checksum()
+	req := &bbb.Request{
+		Request:  r,
+		Resource: resource,
+		Params:   params,
+		Checksum: checksum,
+	}
+
+	w.Header().Set("Content-Type", "text/xml")
+	if m.secret != "" && req.VerifySecret(m.secret) != nil {
+		w.Write([]byte(mockChecksumErrorResponse))
+		return
+	}
+
+	trace := m.next(resource)
+	if trace == nil {
+		w.Write([]byte(mockSuccessResponse))
+		return
+	}
+	if trace.Status != 0 {
+		w.WriteHeader(trace.Status)
+	}
+	w.Write(trace.Response)
+}
+
+// readTraceFiles reads the traces of all files
+func readTraceFiles(filenames []string) ([]*bbb.Trace, error) {
+	traces := []*bbb.Trace{}
+	for _, filename := range filenames {
+		t, err := bbb.ReadTraces(filename)
+		if err != nil {
+			return nil, err
+		}
+		traces = append(traces, t...)
+	}
+	return traces, nil
+}
+
+// serveMockBackend starts a mock backend answering with
+// the responses of captured traces. It can be added as a
+// backend to a staging cluster.
+func (c *Cli) serveMockBackend(ctx *cli.Context) error {
+	traces, err := readTraceFiles(ctx.Args().Slice())
+	if err != nil {
+		return err
+	}
+	listen := ctx.String("listen")
+	fmt.Println("Serving", len(traces), "recorded responses on",
+		"http://"+listen+"/bigbluebutton/api/")
+	return http.ListenAndServe(
+		listen, newMockBackend(ctx.String("secret"), traces))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// listMeetingPins shows all meeting pins
+func (c *Cli) listMeetingPins(ctx *cli.Context) error {
+	pins, err := c.client.MeetingPinsList(ctx.Context, url.Values{})
+	if err != nil {
+		return err
+	}
+	for _, p := range pins {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.ID, p.FrontendID, p.MeetingID, p.BackendID,
+			p.ExpiresAt.Format(time.RFC3339), p.Note)
+	}
+	return nil
+}
+
+// addMeetingPin pins a meeting of a frontend to a backend
+func (c *Cli) addMeetingPin(ctx *cli.Context) error {
+	if ctx.NArg() < 3 {
+		return fmt.Errorf("require: <frontend key> <meeting id> <host>")
+	}
+	frontend, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	backend, err := getBackendByHost(ctx.Context, c.client, ctx.Args().Get(2))
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		return fmt.Errorf("no such backend")
+	}
+	pin, err := c.client.MeetingPinCreate(ctx.Context, &store.MeetingPin{
+		FrontendID: frontend.ID,
+		MeetingID:  ctx.Args().Get(1),
+		BackendID:  backend.ID,
+		Note:       ctx.String("note"),
+		ExpiresAt:  time.Now().Add(ctx.Duration("ttl")),
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Meeting ID:", pin.MeetingID)
+	fmt.Println("Backend:", backend.Backend.Host)
+	fmt.Println("Expires:", pin.ExpiresAt.Local().Format(time.RFC3339))
+	return nil
+}
+
+// removeMeetingPin removes the pin of a meeting
+func (c *Cli) removeMeetingPin(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <meeting id>")
+	}
+	frontend, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	pins, err := c.client.MeetingPinsList(ctx.Context, url.Values{
+		"frontend_id": []string{frontend.ID},
+	})
+	if err != nil {
+		return err
+	}
+	for _, p := range pins {
+		if p.MeetingID != ctx.Args().Get(1) {
+			continue
+		}
+		if _, err := c.client.MeetingPinDelete(ctx.Context, p.ID); err != nil {
+			return err
+		}
+		fmt.Println("pin removed")
+		return nil
+	}
+	return fmt.Errorf("no such pin")
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/urfave/cli/v2"
+)
+
+// reconcileMeetings compares the meetings in the store
+// with the meetings running on the backends. Without a
+// host, all backends are compared.
+func (c *Cli) reconcileMeetings(ctx *cli.Context) error {
+	query := url.Values{}
+	if ctx.NArg() > 0 {
+		query.Set("backend_host", ctx.Args().Get(0))
+	}
+	reports, err := c.client.MeetingsReconcile(ctx.Context, query)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no such backend")
+	}
+
+	inconsistent := 0
+	for _, r := range reports {
+		fmt.Printf("%s\t%s\n", r.BackendID, r.Host)
+		if r.Error != "" {
+			fmt.Printf("  Error:\t %s\n", r.Error)
+			inconsistent++
+			continue
+		}
+		fmt.Printf("  Matched:\t %d\n", r.Matched)
+		for _, m := range r.OnlyStore {
+			fmt.Printf("  Only store:\t %s\t%s\t%s\n",
+				m.MeetingID, m.InternalMeetingID, m.MeetingName)
+		}
+		for _, m := range r.OnlyBackend {
+			fmt.Printf("  Only backend:\t %s\t%s\t%s\n",
+				m.MeetingID, m.InternalMeetingID, m.MeetingName)
+		}
+		if !r.Consistent() {
+			inconsistent++
+		}
+	}
+	if inconsistent > 0 {
+		return fmt.Errorf(
+			"meetings of %d backends are inconsistent", inconsistent)
+	}
+	return nil
+}
+
+// reconcileRecordings compares the recordings in the
+// store with the recordings of the backends. With
+// --import, missing recordings are added to the store.
+func (c *Cli) reconcileRecordings(ctx *cli.Context) error {
+	query := url.Values{}
+	if ctx.NArg() > 0 {
+		query.Set("backend_host", ctx.Args().Get(0))
+	}
+	importMissing := ctx.Bool("import")
+	reports, err := c.client.RecordingsReconcile(
+		ctx.Context, query, importMissing)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no such backend")
+	}
+
+	inconsistent := 0
+	for _, r := range reports {
+		fmt.Printf("%s\t%s\n", r.BackendID, r.Host)
+		if r.Error != "" {
+			fmt.Printf("  Error:\t %s\n", r.Error)
+			inconsistent++
+			continue
+		}
+		fmt.Printf("  Matched:\t %d\n", r.Matched)
+		for _, rec := range r.OnlyStore {
+			fmt.Printf("  Only store:\t %s\t%s\t%s\n",
+				rec.RecordID, rec.MeetingID, rec.Name)
+		}
+		for _, rec := range r.OnlyBackend {
+			fmt.Printf("  Only backend:\t %s\t%s\t%s\n",
+				rec.RecordID, rec.MeetingID, rec.Name)
+		}
+		if importMissing {
+			fmt.Printf("  Imported:\t %d\n", r.Imported)
+			if r.Error == "" && len(r.OnlyStore) == 0 {
+				continue
+			}
+		}
+		if !r.Consistent() {
+			inconsistent++
+		}
+	}
+	if inconsistent > 0 {
+		return fmt.Errorf(
+			"recordings of %d backends are inconsistent", inconsistent)
+	}
+	return nil
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/urfave/cli/v2"
+)
+
+// recoverCluster rebuilds the meetings and recordings
+// in the store from the live state of the backends, e.g.
+// after a database loss. Without a host, all backends
+// are recovered.
+func (c *Cli) recoverCluster(ctx *cli.Context) error {
+	query := url.Values{}
+	if ctx.NArg() > 0 {
+		query.Set("backend_host", ctx.Args().Get(0))
+	}
+	reports, err := c.client.ClusterRecover(ctx.Context, query)
+	if err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return fmt.Errorf("no such backend")
+	}
+
+	failed := 0
+	for _, r := range reports {
+		fmt.Printf("%s\t%s\n", r.BackendID, r.Host)
+		if r.Error != "" {
+			fmt.Printf("  Error:\t %s\n", r.Error)
+			failed++
+			continue
+		}
+		fmt.Printf("  Meetings:\t %d\n", r.Meetings)
+		fmt.Printf("  Recordings:\t %d\n", r.Recordings)
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d backends could not be recovered", failed)
+	}
+	return nil
+}
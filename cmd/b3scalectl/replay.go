@@ -0,0 +1,189 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"regexp"
+	"time"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// reReturncode extracts the returncode of a BBB response
+var reReturncode = regexp.MustCompile(`<returncode>\s*(\w+)\s*</returncode>`)
+
+// responseOutcome summarizes a response for comparing
+// the recorded with the replayed response.
+func responseOutcome(status int, body []byte) string {
+	m := reReturncode.FindSubmatch(body)
+	if m == nil {
+		return fmt.Sprintf("HTTP %d", status)
+	}
+	return string(m[1])
+}
+
+// replayTrace sends the traced request to the target.
+// The request is signed with the secret.
+func replayTrace(
+	client *http.Client,
+	target *bbb.Backend,
+	trace *bbb.Trace,
+	meetingID string,
+) (string, error) {
+	params := make(bbb.Params, len(trace.Params))
+	for k, v := range trace.Params {
+		params[k] = v
+	}
+	if meetingID != "" {
+		params[bbb.ParamMeetingID] = meetingID
+	}
+
+	req := &bbb.Request{
+		Request:  &http.Request{Method: trace.Method},
+		Resource: trace.Resource,
+		Params:   params,
+		Body:     trace.Body,
+		Backend:  target,
+	}
+
+	var body *bytes.Reader
+	if req.HasBody() {
+		body = bytes.NewReader(req.Body)
+	} else {
+		body = bytes.NewReader(nil)
+	}
+	httpReq, err := http.NewRequest(trace.Method, req.URL(), body)
+	if err != nil {
+		return "", err
+	}
+	if req.HasBody() {
+		httpReq.Header.Set("Content-Type", "application/xml")
+	}
+	res, err := client.Do(httpReq)
+	if err != nil {
+		return "", err
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return "", err
+	}
+	return responseOutcome(res.StatusCode, data), nil
+}
+
+// replayOptions configure replaying the traces
+type replayOptions struct {
+	MeetingID  string
+	KeepTiming bool
+}
+
+// replay sends the traces to the target and prints the
+// outcome of each request. The number of responses
+// differing from the recording is returned.
+func replay(
+	out io.Writer,
+	client *http.Client,
+	target *bbb.Backend,
+	traces []*bbb.Trace,
+	opts *replayOptions,
+) (int, error) {
+	diffs := 0
+	for i, trace := range traces {
+		if opts.KeepTiming && i > 0 {
+			time.Sleep(trace.Time.Sub(traces[i-1].Time))
+		}
+		expected := responseOutcome(trace.Status, trace.Response)
+		outcome, err := replayTrace(client, target, trace, opts.MeetingID)
+		if err != nil {
+			return diffs, err
+		}
+		if outcome != expected {
+			diffs++
+			fmt.Fprintf(out, "DIFF  %-20s recorded: %s, replayed: %s\n",
+				trace.Resource, expected, outcome)
+			continue
+		}
+		fmt.Fprintf(out, "OK    %-20s %s\n", trace.Resource, outcome)
+	}
+	return diffs, nil
+}
+
+// startMockBackend serves the mock backend on a
+// random local port. The server is closed with the
+// listener.
+func startMockBackend(mock *mockBackend) (net.Listener, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	go http.Serve(l, mock)
+	return l, nil
+}
+
+// replayTraces replays captured request traces against
+// a cluster, a backend or the mock backend and compares
+// the outcome with the recorded responses.
+func (c *Cli) replayTraces(ctx *cli.Context) error {
+	if ctx.NArg() == 0 {
+		return fmt.Errorf("require: <trace file>")
+	}
+	useMock := ctx.Bool("mock")
+	if !useMock && (!ctx.IsSet("target") || !ctx.IsSet("secret")) {
+		return fmt.Errorf("require: --target and --secret, or --mock")
+	}
+	target := &bbb.Backend{
+		Host:   ctx.String("target"),
+		Secret: ctx.String("secret"),
+	}
+	opts := &replayOptions{
+		MeetingID:  ctx.String("meeting-id"),
+		KeepTiming: ctx.Bool("keep-timing"),
+	}
+
+	client := &http.Client{
+		Timeout: 60 * time.Second,
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // Do not follow joins
+		},
+	}
+
+	diffs := 0
+	for _, filename := range ctx.Args().Slice() {
+		traces, err := bbb.ReadTraces(filename)
+		if err != nil {
+			return err
+		}
+		var mock net.Listener
+		if useMock {
+			// Each file is replayed against a mock
+			// backend with its recorded responses.
+			target.Secret = "mock-secret"
+			mock, err = startMockBackend(newMockBackend(target.Secret, traces))
+			if err != nil {
+				return err
+			}
+			target.Host = "http://" + mock.Addr().String() + "/bigbluebutton/api/"
+		}
+		fmt.Println("Replaying", len(traces), "requests from", filename)
+
+		n, err := replay(ctx.App.Writer, client, target, traces, opts)
+		if mock != nil {
+			mock.Close()
+		}
+		diffs += n
+		if err != nil {
+			return err
+		}
+	}
+
+	if diffs > 0 {
+		return fmt.Errorf("%d responses differ from the recording", diffs)
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// recordedTraces are requests with their recorded responses
+func recordedTraces() []*bbb.Trace {
+	now := time.Now()
+	return []*bbb.Trace{
+		{
+			Time:     now,
+			Method:   http.MethodGet,
+			Resource: bbb.ResourceIsMeetingRunning,
+			Params:   bbb.Params{"meetingID": "m1"},
+			Status:   http.StatusOK,
+			Response: []byte(`<response>
+<returncode>SUCCESS</returncode><running>false</running>
+</response>`),
+		},
+		{
+			Time:     now.Add(time.Second),
+			Method:   http.MethodGet,
+			Resource: bbb.ResourceGetMeetingInfo,
+			Params:   bbb.Params{"meetingID": "m1"},
+			Status:   http.StatusOK,
+			Response: []byte(`<response>
+<returncode>FAILED</returncode><messageKey>notFound</messageKey>
+</response>`),
+		},
+	}
+}
+
+func TestResponseOutcome(t *testing.T) {
+	body := []byte("<response>\n<returncode> SUCCESS </returncode></response>")
+	if o := responseOutcome(200, body); o != "SUCCESS" {
+		t.Error("unexpected outcome:", o)
+	}
+	if o := responseOutcome(502, []byte("bad gateway")); o != "HTTP 502" {
+		t.Error("unexpected outcome:", o)
+	}
+}
+
+func TestReplayTrace(t *testing.T) {
+	var received *http.Request
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received = r
+			w.Write([]byte("<response><returncode>SUCCESS</returncode></response>"))
+		}))
+	defer server.Close()
+
+	target := &bbb.Backend{
+		Host:   server.URL + "/bigbluebutton/api/",
+		Secret: "secret",
+	}
+	trace := recordedTraces()[0]
+	outcome, err := replayTrace(http.DefaultClient, target, trace, "m2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if outcome != "SUCCESS" {
+		t.Error("unexpected outcome:", outcome)
+	}
+	if received.URL.Path != "/bigbluebutton/api/isMeetingRunning" {
+		t.Error("unexpected path:", received.URL.Path)
+	}
+	if received.URL.Query().Get("meetingID") != "m2" {
+		t.Error("meeting id should be replaced:", received.URL.RawQuery)
+	}
+	if received.URL.Query().Get("checksum") == "" {
+		t.Error("request should be signed")
+	}
+	if trace.Params["meetingID"] != "m1" {
+		t.Error("trace should not be modified")
+	}
+}
+
+func TestMockBackend(t *testing.T) {
+	traces := recordedTraces()
+	traces = append(traces, &bbb.Trace{
+		Method:   http.MethodGet,
+		Resource: bbb.ResourceIsMeetingRunning,
+		Params:   bbb.Params{"meetingID": "m1"},
+		Status:   http.StatusOK,
+		Response: []byte(`<response>
+<returncode>SUCCESS</returncode><running>true</running>
+</response>`),
+	})
+	server := httptest.NewServer(newMockBackend("secret", traces))
+	defer server.Close()
+
+	target := &bbb.Backend{
+		Host:   server.URL + "/bigbluebutton/api/",
+		Secret: "secret",
+	}
+	get := func(resource string, target *bbb.Backend) string {
+		req := &bbb.Request{
+			Resource: resource,
+			Params:   bbb.Params{"meetingID": "m1"},
+			Backend:  target,
+		}
+		res, err := http.Get(req.URL())
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer res.Body.Close()
+		buf := &bytes.Buffer{}
+		buf.ReadFrom(res.Body)
+		return buf.String()
+	}
+
+	// The recordings are served in order, the
+	// last one is repeated.
+	for _, running := range []string{"false", "true", "true"} {
+		body := get(bbb.ResourceIsMeetingRunning, target)
+		if !strings.Contains(body, "<running>"+running+"</running>") {
+			t.Error("unexpected response:", body)
+		}
+	}
+
+	// Resources without a recording succeed
+	body := get(bbb.ResourceEnd, target)
+	if !strings.Contains(body, "SUCCESS") {
+		t.Error("unexpected response:", body)
+	}
+
+	// Checksums are verified
+	body = get(bbb.ResourceEnd, &bbb.Backend{
+		Host:   target.Host,
+		Secret: "wrong",
+	})
+	if !strings.Contains(body, "checksumError") {
+		t.Error("expected a checksum error:", body)
+	}
+}
+
+func TestReplayAgainstMockBackend(t *testing.T) {
+	traces := recordedTraces()
+	mock, err := startMockBackend(newMockBackend("secret", traces))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mock.Close()
+
+	target := &bbb.Backend{
+		Host:   "http://" + mock.Addr().String() + "/bigbluebutton/api/",
+		Secret: "secret",
+	}
+	out := &bytes.Buffer{}
+	diffs, err := replay(
+		out, http.DefaultClient, target, traces, &replayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs != 0 {
+		t.Error("unexpected diffs:", out.String())
+	}
+
+	// A changed outcome is reported
+	changed := recordedTraces()
+	changed[1].Response = []byte(
+		"<response><returncode>SUCCESS</returncode></response>")
+	out.Reset()
+	diffs, err = replay(
+		out, http.DefaultClient, target, changed, &replayOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if diffs != 1 || !strings.Contains(out.String(), "DIFF") {
+		t.Error("expected a diff:", out.String())
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// listFrontendRooms shows the rooms of a frontend
+func (c *Cli) listFrontendRooms(ctx *cli.Context) error {
+	key := ctx.Args().Get(0)
+	if key == "" {
+		return fmt.Errorf("require: <frontend key>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, key)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	rooms, err := c.client.FrontendRoomsList(ctx.Context, state)
+	if err != nil {
+		return err
+	}
+	for _, r := range rooms {
+		fmt.Printf("%s\t%s\t%s\t%s\t%s\n",
+			r.ID, r.MeetingID, r.Name, r.ModeratorPW, r.AttendeePW)
+	}
+	return nil
+}
+
+// addFrontendRoom adds a room to a frontend. The
+// meeting is created when the room is joined.
+func (c *Cli) addFrontendRoom(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <meeting id>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	params := bbb.Params{}
+	for _, p := range ctx.StringSlice("param") {
+		kv := strings.SplitN(p, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid param, expected key=value: %s", p)
+		}
+		params[kv[0]] = kv[1]
+	}
+	room, err := c.client.FrontendRoomCreate(ctx.Context, state, &store.Room{
+		MeetingID: ctx.Args().Get(1),
+		Name:      ctx.String("name"),
+		Params:    params,
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Println("Frontend:", state.Frontend.Key)
+	fmt.Println("Meeting ID:", room.MeetingID)
+	fmt.Println("Moderator password:", room.ModeratorPW)
+	fmt.Println("Attendee password:", room.AttendeePW)
+	return nil
+}
+
+// removeFrontendRoom deletes a room of a frontend
+func (c *Cli) removeFrontendRoom(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <meeting id>")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	rooms, err := c.client.FrontendRoomsList(ctx.Context, state)
+	if err != nil {
+		return err
+	}
+	for _, r := range rooms {
+		if r.MeetingID != ctx.Args().Get(1) {
+			continue
+		}
+		if _, err := c.client.FrontendRoomDelete(ctx.Context, state, r.ID); err != nil {
+			return err
+		}
+		fmt.Println("room removed")
+		return nil
+	}
+	return fmt.Errorf("no such room")
+}
+
+// scheduleFrontendRoom sets or removes the schedule of a room
+func (c *Cli) scheduleFrontendRoom(ctx *cli.Context) error {
+	if ctx.NArg() < 2 {
+		return fmt.Errorf("require: <frontend key> <meeting id> [<cron expression>]")
+	}
+	state, err := getFrontendByKey(ctx.Context, c.client, ctx.Args().Get(0))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		return fmt.Errorf("no such frontend")
+	}
+	rooms, err := c.client.FrontendRoomsList(ctx.Context, state)
+	if err != nil {
+		return err
+	}
+	for _, r := range rooms {
+		if r.MeetingID != ctx.Args().Get(1) {
+			continue
+		}
+		room, err := c.client.FrontendRoomScheduleUpdate(
+			ctx.Context, state, r.ID, &v1.RoomScheduleRequest{
+				Schedule: ctx.Args().Get(2),
+				Timezone: ctx.String("timezone"),
+				Duration: ctx.Int("duration"),
+			})
+		if err != nil {
+			return err
+		}
+		if room.NextStartAt == nil {
+			fmt.Println("room not scheduled")
+			return nil
+		}
+		fmt.Println("Next start:", room.NextStartAt)
+		return nil
+	}
+	return fmt.Errorf("no such room")
+}
@@ -0,0 +1,752 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/billing"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/experiments"
+	"gitlab.com/infra.run/public/b3scale/pkg/history"
+	"gitlab.com/infra.run/public/b3scale/pkg/logging"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Errors
+var (
+	ErrNoFrontends = errors.New("no frontends configured")
+)
+
+// A configCheck validates a part of the configuration.
+// The hint is shown when the check fails. A failed check
+// marked as warning does not invalidate the configuration.
+//
+// Checks only parse and validate the values into the
+// bootConfig, they are applied afterwards.
+type configCheck struct {
+	Name  string
+	Hint  string
+	Warn  bool
+	Check func() error
+}
+
+// bootConfig is the configuration of the daemon
+// collected from the environment.
+type bootConfig struct {
+	ListenHTTP   string
+	ListenAdmin  string
+	DbConnStr    string
+	DbPoolSize   int
+	LogLevel     string
+	LogFormat    string
+	LogSampling  string
+	SlowRequest  string
+	SlowQuery    string
+	Timeouts     string
+	SlowBackend  string
+	CmdQueueAge  string
+	TraceDir     string
+	TraceMeeting string
+	StaticConfig string
+	Faults       string
+	Mirror       string
+	Experiments  string
+	Billing      string
+	BillingFmt   string
+	IDFormat     string
+	Retention    string
+	History      string
+	HistoryFmt   string
+	Discovery    string
+	JoinCache    string
+	RIB          string
+	RoomLead     string
+	Notify       string
+	Warmup       string
+	Probes       string
+	Proxy        string
+	NoProxy      string
+	DNSRefresh   string
+	MaxResponse  string
+	Overload     string
+	Admission    string
+	PublicStats  string
+	TrustProxies string
+
+	DbMinConns    string
+	DbIdleTime    string
+	DbHealthCheck string
+	DbConnect     string
+
+	LogOptions           *logging.Options
+	SlowRequestThreshold time.Duration
+	SlowQueryThreshold   time.Duration
+	CmdQueueAgeThreshold time.Duration
+	RequestTimeouts      map[string]time.Duration
+	Tracer               *bbb.Tracer
+	Notifier             *notify.Notifier
+	HistoryRetention     map[string]time.Duration
+	JoinCacheTTL         time.Duration
+	RoomPrecreateLead    time.Duration
+	WarmupSteps          []string
+	MaxResponseSize      int64
+	ProbeInterval        time.Duration
+	IDGenerator          store.IDGenerator
+	FaultPolicy          *config.FaultPolicy
+	MirrorPolicy         *config.MirrorPolicy
+	OverloadPolicy       *config.OverloadPolicy
+	AdmissionPolicy      *config.AdmissionPolicy
+	PublicStatsPolicy    *config.PublicStatsPolicy
+	TrustedProxies       []*net.IPNet
+	ExperimentsList      []*experiments.Experiment
+	SlowBackendThreshold time.Duration
+	DNSRefreshInterval   time.Duration
+	DiscoverMeetings     int
+	MeetingsRIB          cluster.RIB
+	DbPoolMinConns       int
+	DbPoolIdleTime       time.Duration
+	DbPoolHealthCheck    time.Duration
+	DbConnectTimeout     time.Duration
+	BillingExporter      billing.Exporter
+	HistoryTarget        history.Target
+	HistoryFormat        history.Format
+}
+
+// checkListenAddress validates a host:port listen address
+func checkListenAddress(listen string) error {
+	_, port, err := net.SplitHostPort(listen)
+	if err != nil {
+		return err
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port: %s", port)
+	}
+	return nil
+}
+
+// checkWritableDir makes sure files can be
+// created in the directory
+func checkWritableDir(dir string) error {
+	f, err := os.CreateTemp(dir, ".b3scale-check-*")
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return os.Remove(f.Name())
+}
+
+// checkFrontends makes sure at least one frontend
+// exists or will be provisioned.
+func checkFrontends(staticConfig string) error {
+	static := []config.FrontendsConfig{
+		&config.FrontendsEnvConfig{},
+	}
+	if staticConfig != "" {
+		static = append(static, &config.FrontendsFileConfig{
+			Filename: staticConfig,
+		})
+	}
+	for _, c := range static {
+		frontends, err := c.Load()
+		if err != nil {
+			return err
+		}
+		if len(frontends) > 0 {
+			return nil
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	frontends, err := store.GetFrontendStates(ctx, tx, store.Q())
+	if err != nil {
+		return err
+	}
+	if len(frontends) == 0 {
+		return ErrNoFrontends
+	}
+	return nil
+}
+
+// configChecks creates the validation pass for the
+// configuration. Connecting to the database is part of the
+// checks, so the store is initialized afterwards.
+func configChecks(cfg *bootConfig, dbPoolSizeStr string) []*configCheck {
+	return []*configCheck{
+		{
+			Name: "logging",
+			Hint: "set " + config.EnvLogLevel + " to a level like " +
+				"info or debug, " + config.EnvLogFormat +
+				" to plain or structured, and " + config.EnvLogSampling +
+				" to a list like isMeetingRunning=10,getMeetings=5",
+			Check: func() error {
+				opts := &logging.Options{
+					Level:    cfg.LogLevel,
+					Format:   cfg.LogFormat,
+					Sampling: cfg.LogSampling,
+				}
+				if err := opts.Validate(); err != nil {
+					return err
+				}
+				cfg.LogOptions = opts
+				return nil
+			},
+		},
+		{
+			Name: "slow request threshold",
+			Hint: "set " + config.EnvSlowRequest +
+				" to a duration like 2s, or 0 to disable",
+			Check: func() error {
+				threshold, err := time.ParseDuration(cfg.SlowRequest)
+				if err != nil {
+					return err
+				}
+				cfg.SlowRequestThreshold = threshold
+				return nil
+			},
+		},
+		{
+			Name: "slow query threshold",
+			Hint: "set " + config.EnvSlowQuery +
+				" to a duration like 500ms, or 0 to disable",
+			Check: func() error {
+				threshold, err := time.ParseDuration(cfg.SlowQuery)
+				if err != nil {
+					return err
+				}
+				cfg.SlowQueryThreshold = threshold
+				return nil
+			},
+		},
+		{
+			Name: "slow backend threshold",
+			Hint: "set " + config.EnvSlowBackend +
+				" to a duration like 1500ms or leave it empty",
+			Check: func() error {
+				if cfg.SlowBackend == "" {
+					return nil // Shedding is disabled
+				}
+				threshold, err := time.ParseDuration(cfg.SlowBackend)
+				if err != nil {
+					return err
+				}
+				cfg.SlowBackendThreshold = threshold
+				return nil
+			},
+		},
+		{
+			Name: "command queue age threshold",
+			Hint: "set " + config.EnvCmdQueueAge +
+				" to a duration like 1m, or 0 to disable",
+			Check: func() error {
+				threshold, err := time.ParseDuration(cfg.CmdQueueAge)
+				if err != nil {
+					return err
+				}
+				cfg.CmdQueueAgeThreshold = threshold
+				return nil
+			},
+		},
+		{
+			Name: "backend request timeouts",
+			Hint: "set " + config.EnvTimeouts +
+				" to a list like default=60s,create=120s",
+			Check: func() error {
+				timeouts, err := config.ParseTimeouts(cfg.Timeouts)
+				if err != nil {
+					return err
+				}
+				cfg.RequestTimeouts = timeouts
+				return nil
+			},
+		},
+		{
+			Name: "request tracing",
+			Hint: "make sure " + config.EnvTraceDir + " is writable",
+			Check: func() error {
+				if cfg.TraceMeeting == "" {
+					return nil // Tracing is disabled
+				}
+				tracer, err := bbb.NewTracer(
+					cfg.TraceDir, strings.Split(cfg.TraceMeeting, ","))
+				if err != nil {
+					return err
+				}
+				cfg.Tracer = tracer
+				return nil
+			},
+		},
+		{
+			Name: "fault injection",
+			Hint: "set " + config.EnvFaults + " to a list like " +
+				"latency=2s,error_rate=0.1,drop_rate=0.2 or leave it empty",
+			Check: func() error {
+				policy, err := config.ParseFaultPolicy(cfg.Faults)
+				if err != nil {
+					return err
+				}
+				cfg.FaultPolicy = policy
+				return nil
+			},
+		},
+		{
+			Name: "request mirroring",
+			Hint: "set " + config.EnvMirror + " to a list like " +
+				"url=https://staging/bigbluebutton/api/,secret=...,rate=0.05 or leave it empty",
+			Check: func() error {
+				policy, err := config.ParseMirrorPolicy(cfg.Mirror)
+				if err != nil {
+					return err
+				}
+				cfg.MirrorPolicy = policy
+				return nil
+			},
+		},
+		{
+			Name: "experiments",
+			Hint: "set " + config.EnvExperiments +
+				" to the path of a JSON file with experiments or leave it empty",
+			Check: func() error {
+				if cfg.Experiments == "" {
+					return nil // No experiments
+				}
+				exps, err := experiments.Load(cfg.Experiments)
+				if err != nil {
+					return err
+				}
+				cfg.ExperimentsList = exps
+				return nil
+			},
+		},
+		{
+			Name: "notifications",
+			Hint: "set " + config.EnvNotify +
+				" to the path of a JSON file with notification channels and routes or leave it empty",
+			Check: func() error {
+				if cfg.Notify == "" {
+					return nil // No notifications
+				}
+				n, err := notify.Load(cfg.Notify)
+				if err != nil {
+					return err
+				}
+				n.SetDedup(notify.StoreDedup{})
+				cfg.Notifier = n
+				return nil
+			},
+		},
+		{
+			Name: "billing export",
+			Hint: "set " + config.EnvBilling + " to a writable directory " +
+				"or an http(s) URL, and " + config.EnvBillingFmt +
+				" to csv or json",
+			Check: func() error {
+				if cfg.Billing == "" {
+					return nil // Billing export is disabled
+				}
+				format, err := billing.GetFormat(cfg.BillingFmt)
+				if err != nil {
+					return err
+				}
+				exporter := billing.NewExporter(cfg.Billing, format)
+				if e, ok := exporter.(*billing.FileExporter); ok {
+					if err := checkWritableDir(e.Dir); err != nil {
+						return err
+					}
+				}
+				cfg.BillingExporter = exporter
+				return nil
+			},
+		},
+		{
+			Name: "history export",
+			Hint: "set " + config.EnvHistory + " to a writable directory " +
+				"or an s3://bucket/prefix URL with the credentials in " +
+				config.EnvS3AccessKey + " and " + config.EnvS3SecretKey +
+				", and " + config.EnvHistoryFmt + " to csv or parquet",
+			Check: func() error {
+				if cfg.History == "" {
+					return nil // History export is disabled
+				}
+				format, err := history.GetFormat(cfg.HistoryFmt)
+				if err != nil {
+					return err
+				}
+				target, err := history.NewTarget(
+					cfg.History,
+					config.EnvOpt(config.EnvS3AccessKey, ""),
+					config.EnvOpt(config.EnvS3SecretKey, ""))
+				if err != nil {
+					return err
+				}
+				if t, ok := target.(*history.DirTarget); ok {
+					if err := checkWritableDir(t.Dir); err != nil {
+						return err
+					}
+				}
+				cfg.HistoryTarget = target
+				cfg.HistoryFormat = format
+				return nil
+			},
+		},
+		{
+			Name: "history retention",
+			Hint: "set " + config.EnvRetention +
+				" to a list like usage=730d,meetings=365d,audit_log=365d or leave it empty",
+			Check: func() error {
+				retention, err := config.ParseRetention(cfg.Retention)
+				if err != nil {
+					return err
+				}
+				cfg.HistoryRetention = retention
+				return nil
+			},
+		},
+		{
+			Name: "meeting discovery",
+			Hint: "set " + config.EnvDiscovery +
+				" to the number of backends asked for unknown meetings, or 0",
+			Check: func() error {
+				n, err := strconv.Atoi(cfg.Discovery)
+				if err != nil {
+					return err
+				}
+				if n < 0 {
+					return fmt.Errorf("must not be negative: %d", n)
+				}
+				cfg.DiscoverMeetings = n
+				return nil
+			},
+		},
+		{
+			Name: "join cache",
+			Hint: "set " + config.EnvJoinCache +
+				" to a duration like 5m, or 0 to disable",
+			Check: func() error {
+				ttl, err := time.ParseDuration(cfg.JoinCache)
+				if err != nil {
+					return err
+				}
+				if ttl < 0 {
+					return fmt.Errorf("must not be negative: %s", ttl)
+				}
+				cfg.JoinCacheTTL = ttl
+				return nil
+			},
+		},
+		{
+			Name: "room schedules",
+			Hint: "set " + config.EnvRoomLead +
+				" to a duration like 5m",
+			Check: func() error {
+				lead, err := time.ParseDuration(cfg.RoomLead)
+				if err != nil {
+					return err
+				}
+				if lead <= 0 {
+					return fmt.Errorf("must be positive: %s", lead)
+				}
+				cfg.RoomPrecreateLead = lead
+				return nil
+			},
+		},
+		{
+			Name: "backend warm-up",
+			Hint: "set " + config.EnvWarmup +
+				" to a list of steps like create,recordings",
+			Check: func() error {
+				steps, err := cluster.ParseWarmupSteps(cfg.Warmup)
+				if err != nil {
+					return err
+				}
+				cfg.WarmupSteps = steps
+				return nil
+			},
+		},
+		{
+			Name: "backend proxy",
+			Hint: "set " + config.EnvProxy +
+				" to a URL like http://egress.example.net:3128",
+			Check: func() error {
+				if cfg.Proxy == "" {
+					return nil // Use the environment
+				}
+				_, err := bbb.ParseProxyURL(cfg.Proxy)
+				return err
+			},
+		},
+		{
+			Name: "backend dns refresh",
+			Hint: "set " + config.EnvDNSRefresh +
+				" to a duration like 30s, or 0 to disable",
+			Check: func() error {
+				interval, err := time.ParseDuration(cfg.DNSRefresh)
+				if err != nil {
+					return err
+				}
+				if interval < 0 {
+					return fmt.Errorf("must not be negative: %s", interval)
+				}
+				cfg.DNSRefreshInterval = interval
+				return nil
+			},
+		},
+		{
+			Name: "backend max response size",
+			Hint: "set " + config.EnvMaxResponse +
+				" to a size like 64M, or 0 to disable",
+			Check: func() error {
+				size, err := config.ParseSize(cfg.MaxResponse)
+				if err != nil {
+					return err
+				}
+				cfg.MaxResponseSize = size
+				return nil
+			},
+		},
+		{
+			Name: "overload queue",
+			Hint: "set " + config.EnvOverload + " to a list like " +
+				"wait=10s,size=100,per_frontend=10 or leave it empty",
+			Check: func() error {
+				policy, err := config.ParseOverloadPolicy(cfg.Overload)
+				if err != nil {
+					return err
+				}
+				cfg.OverloadPolicy = policy
+				return nil
+			},
+		},
+		{
+			Name: "join admission",
+			Hint: "set " + config.EnvAdmission + " to a list like " +
+				"burst=20,window=1s,spread=5s,prewarm=true or leave it empty",
+			Check: func() error {
+				policy, err := config.ParseAdmissionPolicy(cfg.Admission)
+				if err != nil {
+					return err
+				}
+				cfg.AdmissionPolicy = policy
+				return nil
+			},
+		},
+		{
+			Name: "public statistics",
+			Hint: "set " + config.EnvPublicStats + " to a list like " +
+				"round=10,noise=5,hours=24,cache=5m or leave it empty",
+			Check: func() error {
+				policy, err := config.ParsePublicStatsPolicy(cfg.PublicStats)
+				if err != nil {
+					return err
+				}
+				cfg.PublicStatsPolicy = policy
+				return nil
+			},
+		},
+		{
+			Name: "trusted proxies",
+			Hint: "set " + config.EnvTrustProxies + " to a list of " +
+				"networks like 127.0.0.0/8,::1",
+			Check: func() error {
+				networks, err := config.ParseNetworkList(cfg.TrustProxies)
+				if err != nil {
+					return err
+				}
+				cfg.TrustedProxies = networks
+				return nil
+			},
+		},
+		{
+			Name: "synthetic probes",
+			Hint: "set " + config.EnvProbes +
+				" to a duration like 5m, or 0 to disable",
+			Check: func() error {
+				interval, err := time.ParseDuration(cfg.Probes)
+				if err != nil {
+					return err
+				}
+				if interval < 0 {
+					return fmt.Errorf("must not be negative: %s", interval)
+				}
+				cfg.ProbeInterval = interval
+				return nil
+			},
+		},
+		{
+			Name: "rib",
+			Hint: "set " + config.EnvRIB +
+				" to postgres, memory or a redis url like redis://localhost:6379/1",
+			Check: func() error {
+				rib, err := cluster.NewRIB(cfg.RIB)
+				if err != nil {
+					return err
+				}
+				cfg.MeetingsRIB = rib
+				return nil
+			},
+		},
+		{
+			Name: "id format",
+			Hint: "set " + config.EnvIDFormat + " to uuid4 or uuid7",
+			Check: func() error {
+				ids, err := store.GetIDGenerator(cfg.IDFormat)
+				if err != nil {
+					return err
+				}
+				cfg.IDGenerator = ids
+				return nil
+			},
+		},
+		{
+			Name: "listen address",
+			Hint: "set " + config.EnvListenHTTP +
+				" to host:port, e.g. 127.0.0.1:42353",
+			Check: func() error {
+				return checkListenAddress(cfg.ListenHTTP)
+			},
+		},
+		{
+			Name: "admin listen address",
+			Hint: "set " + config.EnvListenAdmin +
+				" to host:port, e.g. 127.0.0.1:42354, or leave it empty",
+			Check: func() error {
+				if cfg.ListenAdmin == "" {
+					return nil
+				}
+				if cfg.ListenAdmin == cfg.ListenHTTP {
+					return fmt.Errorf("must differ from %s", config.EnvListenHTTP)
+				}
+				return checkListenAddress(cfg.ListenAdmin)
+			},
+		},
+		{
+			Name: "database pool size",
+			Hint: "set " + config.EnvDbPoolSize + " to a positive number",
+			Check: func() error {
+				size, err := strconv.Atoi(dbPoolSizeStr)
+				if err != nil {
+					return err
+				}
+				if size <= 0 {
+					return store.ErrMaxConnsUnconfigured
+				}
+				cfg.DbPoolSize = size
+				return nil
+			},
+		},
+		{
+			Name: "database pool",
+			Hint: "set " + config.EnvDbMinConns + " to a number not " +
+				"exceeding the pool size, and " + config.EnvDbIdleTime +
+				", " + config.EnvDbHealth + " and " + config.EnvDbConnect +
+				" to durations like 30m",
+			Check: func() error {
+				minConns, err := strconv.Atoi(cfg.DbMinConns)
+				if err != nil {
+					return err
+				}
+				if minConns < 0 || minConns > cfg.DbPoolSize {
+					return fmt.Errorf(
+						"min conns must be between 0 and %d", cfg.DbPoolSize)
+				}
+				idleTime, err := time.ParseDuration(cfg.DbIdleTime)
+				if err != nil {
+					return err
+				}
+				healthCheck, err := time.ParseDuration(cfg.DbHealthCheck)
+				if err != nil {
+					return err
+				}
+				if healthCheck <= 0 {
+					return fmt.Errorf("health check period must be positive")
+				}
+				connectTimeout, err := time.ParseDuration(cfg.DbConnect)
+				if err != nil {
+					return err
+				}
+				if connectTimeout < 0 {
+					return fmt.Errorf("connect timeout must not be negative")
+				}
+				cfg.DbPoolMinConns = minConns
+				cfg.DbPoolIdleTime = idleTime
+				cfg.DbPoolHealthCheck = healthCheck
+				cfg.DbConnectTimeout = connectTimeout
+				return nil
+			},
+		},
+		{
+			Name: "database",
+			Hint: "check " + config.EnvDbURL + " and apply the " +
+				"migrations with `b3scaled --migrate`",
+			Check: func() error {
+				if cfg.DbPoolSize == 0 {
+					return store.ErrMaxConnsUnconfigured
+				}
+				return store.Connect(&store.ConnectOpts{
+					URL:               cfg.DbConnStr,
+					MaxConns:          int32(cfg.DbPoolSize),
+					MinConns:          int32(cfg.DbPoolMinConns),
+					MaxConnIdleTime:   cfg.DbPoolIdleTime,
+					HealthCheckPeriod: cfg.DbPoolHealthCheck,
+					ConnectTimeout:    cfg.DbConnectTimeout,
+				})
+			},
+		},
+		{
+			// A fresh install has no frontends yet. They are
+			// added through the API, so b3scaled must start.
+			Name: "frontends",
+			Hint: "add a frontend with `b3scalectl add frontend`, " +
+				"or declare one in " + config.EnvStaticConfig +
+				" or " + config.EnvFrontendKey,
+			Warn: true,
+			Check: func() error {
+				return checkFrontends(cfg.StaticConfig)
+			},
+		},
+	}
+}
+
+// runConfigChecks executes all checks and prints the
+// results. False is returned if a check failed, failed
+// warnings are only reported.
+func runConfigChecks(checks []*configCheck) bool {
+	ok := true
+	for _, c := range checks {
+		err := c.Check()
+		if err != nil && c.Warn {
+			fmt.Printf("[WARN] %s: %s\n", c.Name, err)
+			fmt.Printf("       hint: %s\n", c.Hint)
+			continue
+		}
+		if err != nil {
+			fmt.Printf("[FAIL] %s: %s\n", c.Name, err)
+			fmt.Printf("       hint: %s\n", c.Hint)
+			ok = false
+			continue
+		}
+		fmt.Printf("[ OK ] %s\n", c.Name)
+	}
+	return ok
+}
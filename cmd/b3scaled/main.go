@@ -1,20 +1,120 @@
 package main
 
 import (
-	"strconv"
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
+	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 
+	"gitlab.com/infra.run/public/b3scale/db"
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/billing"
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/history"
 	"gitlab.com/infra.run/public/b3scale/pkg/http"
+	v1 "gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
 	"gitlab.com/infra.run/public/b3scale/pkg/logging"
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
 	"gitlab.com/infra.run/public/b3scale/pkg/middlewares/requests"
 	"gitlab.com/infra.run/public/b3scale/pkg/middlewares/routing"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
+	"gitlab.com/infra.run/public/b3scale/pkg/systemd"
 )
 
+// dbOutageTolerance is the time the database may be
+// unavailable, before the watchdog restarts the service.
+// Shorter outages, like a failover, only degrade the service.
+const dbOutageTolerance = 5 * time.Minute
+
+// checkDatabase is used as health check for
+// the systemd watchdog.
+func checkDatabase() error {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 5*time.Second)
+	defer cancel()
+	err := store.CheckHealth(ctx)
+	if err == nil {
+		return nil
+	}
+	if _, since := store.Health(); time.Since(since) < dbOutageTolerance {
+		return nil // degraded
+	}
+	return err
+}
+
+// applyMigrations brings the database schema up to date
+// with the migrations embedded in the binary.
+func applyMigrations() error {
+	ctx := context.Background()
+	url := config.EnvOpt(config.EnvDbURL, config.EnvDbURLDefault)
+	applied, err := store.Migrate(ctx, url, db.Schema)
+	for _, m := range applied {
+		fmt.Println("applied migration:", m.Name)
+	}
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		fmt.Println("the database schema is up to date")
+	}
+	return nil
+}
+
+// applyConfig applies the validated configuration
+func applyConfig(cfg *bootConfig) error {
+	if err := logging.Setup(cfg.LogOptions); err != nil {
+		return err
+	}
+	if err := bbb.ConfigureProxy(cfg.Proxy, cfg.NoProxy); err != nil {
+		return err
+	}
+
+	bbb.SlowRequestThreshold = cfg.SlowRequestThreshold
+	for resource, timeout := range cfg.RequestTimeouts {
+		bbb.RequestTimeouts[resource] = timeout
+	}
+	bbb.ActiveTracer = cfg.Tracer
+	bbb.MaxResponseSize = cfg.MaxResponseSize
+
+	store.SlowQueryThreshold = cfg.SlowQueryThreshold
+	store.IDs = cfg.IDGenerator
+
+	cluster.CommandQueueAgeThreshold = cfg.CmdQueueAgeThreshold
+	cluster.HistoryRetention = cfg.HistoryRetention
+	cluster.JoinCacheTTL = cfg.JoinCacheTTL
+	cluster.RoomPrecreateLead = cfg.RoomPrecreateLead
+	cluster.WarmupSteps = cfg.WarmupSteps
+	cluster.ProbeInterval = cfg.ProbeInterval
+
+	notify.Default = cfg.Notifier
+	http.TrustedProxies = cfg.TrustedProxies
+	return nil
+}
+
 func main() {
+	// Flags
+	checkOnly := flag.Bool(
+		"check-config", false, "validate the configuration and exit")
+	printConfig := flag.Bool(
+		"print-default-config", false,
+		"print the default configuration and exit")
+	migrate := flag.Bool(
+		"migrate", false, "apply the database migrations and exit")
+	flag.Parse()
+
+	if *printConfig {
+		os.Stdout.Write(config.DefaultConfig())
+		os.Exit(0)
+	}
+
 	// Check if the enviroment was configured, when not try to
 	// load the environment from .env or from a sysconfig env file
 	if chk := config.EnvOpt(config.EnvDbURL, "unconfigured"); chk == "unconfigured" {
@@ -24,80 +124,287 @@ func main() {
 		})
 	}
 
-	quit := make(chan bool)
+	quit := make(chan os.Signal, 1)
+	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	banner() // Most important.
 
+	if *migrate {
+		if err := applyMigrations(); err != nil {
+			fmt.Println("migration failed:", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
 	// Config
-	listenHTTP := config.EnvOpt(config.EnvListenHTTP, config.EnvListenHTTPDefault)
-	dbConnStr := config.EnvOpt(config.EnvDbURL, config.EnvDbURLDefault)
+	cfg := &bootConfig{
+		ListenHTTP:   config.EnvOpt(config.EnvListenHTTP, config.EnvListenHTTPDefault),
+		ListenAdmin:  config.EnvOpt(config.EnvListenAdmin, ""),
+		DbConnStr:    config.EnvOpt(config.EnvDbURL, config.EnvDbURLDefault),
+		LogLevel:     config.EnvOpt(config.EnvLogLevel, config.EnvLogLevelDefault),
+		LogFormat:    config.EnvOpt(config.EnvLogFormat, config.EnvLogFormatDefault),
+		LogSampling:  config.EnvOpt(config.EnvLogSampling, ""),
+		SlowRequest:  config.EnvOpt(config.EnvSlowRequest, config.EnvSlowRequestDefault),
+		SlowQuery:    config.EnvOpt(config.EnvSlowQuery, config.EnvSlowQueryDefault),
+		Timeouts:     config.EnvOpt(config.EnvTimeouts, config.EnvTimeoutsDefault),
+		SlowBackend:  config.EnvOpt(config.EnvSlowBackend, ""),
+		CmdQueueAge:  config.EnvOpt(config.EnvCmdQueueAge, config.EnvCmdQueueAgeDefault),
+		TraceDir:     config.EnvOpt(config.EnvTraceDir, config.EnvTraceDirDefault),
+		TraceMeeting: config.EnvOpt(config.EnvTraceMeeting, ""),
+		StaticConfig: config.EnvOpt(config.EnvStaticConfig, ""),
+		Faults:       config.EnvOpt(config.EnvFaults, ""),
+		Mirror:       config.EnvOpt(config.EnvMirror, ""),
+		Experiments:  config.EnvOpt(config.EnvExperiments, ""),
+		Billing:      config.EnvOpt(config.EnvBilling, ""),
+		BillingFmt:   config.EnvOpt(config.EnvBillingFmt, config.EnvBillingFmtDefault),
+		IDFormat:     config.EnvOpt(config.EnvIDFormat, config.EnvIDFormatDefault),
+		Retention:    config.EnvOpt(config.EnvRetention, ""),
+		History:      config.EnvOpt(config.EnvHistory, ""),
+		HistoryFmt:   config.EnvOpt(config.EnvHistoryFmt, config.EnvHistoryFmtDefault),
+		Discovery:    config.EnvOpt(config.EnvDiscovery, config.EnvDiscoveryDefault),
+		JoinCache:    config.EnvOpt(config.EnvJoinCache, config.EnvJoinCacheDefault),
+		RIB:          config.EnvOpt(config.EnvRIB, config.EnvRIBDefault),
+		RoomLead:     config.EnvOpt(config.EnvRoomLead, config.EnvRoomLeadDefault),
+		Notify:       config.EnvOpt(config.EnvNotify, ""),
+		Warmup:       config.EnvOpt(config.EnvWarmup, ""),
+		Probes:       config.EnvOpt(config.EnvProbes, config.EnvProbesDefault),
+		Proxy:        config.EnvOpt(config.EnvProxy, ""),
+		NoProxy:      config.EnvOpt(config.EnvNoProxy, ""),
+		DNSRefresh:   config.EnvOpt(config.EnvDNSRefresh, config.EnvDNSRefreshDefault),
+		MaxResponse:  config.EnvOpt(config.EnvMaxResponse, config.EnvMaxResponseDefault),
+		Overload:     config.EnvOpt(config.EnvOverload, ""),
+		Admission:    config.EnvOpt(config.EnvAdmission, ""),
+		PublicStats:  config.EnvOpt(config.EnvPublicStats, ""),
+		TrustProxies: config.EnvOpt(config.EnvTrustProxies, config.EnvTrustProxiesDefault),
+
+		DbMinConns:    config.EnvOpt(config.EnvDbMinConns, config.EnvDbMinConnsDefault),
+		DbIdleTime:    config.EnvOpt(config.EnvDbIdleTime, config.EnvDbIdleTimeDefault),
+		DbHealthCheck: config.EnvOpt(config.EnvDbHealth, config.EnvDbHealthDefault),
+		DbConnect:     config.EnvOpt(config.EnvDbConnect, config.EnvDbConnectDefault),
+	}
 	dbPoolSizeStr := config.EnvOpt(config.EnvDbPoolSize, config.EnvDbPoolSizeDefault)
-	loglevel := config.EnvOpt(config.EnvLogLevel, config.EnvLogLevelDefault)
-	logFormat := config.EnvOpt(config.EnvLogFormat, config.EnvLogFormatDefault)
 	revProxyEnabled := config.IsEnabled(config.EnvOpt(
 		config.EnvReverseProxy, config.EnvReverseProxyDefault))
+	bbb.UseH2C = config.IsEnabled(config.EnvOpt(
+		config.EnvBackendH2C, config.EnvBackendH2CDefault))
 
-	dbPoolSize, err := strconv.Atoi(dbPoolSizeStr)
-
-	// Configure logging
-	if err := logging.Setup(&logging.Options{
-		Level:  loglevel,
-		Format: logFormat,
-	}); err != nil {
-		panic(err)
+	// Validate the configuration. This will initialize
+	// the database connection. Until the logging is
+	// configured, only warnings are logged.
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	ok := runConfigChecks(configChecks(cfg, dbPoolSizeStr))
+	if *checkOnly {
+		if !ok {
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+	if !ok {
+		fmt.Println("the configuration is invalid, see above.")
+		os.Exit(1)
 	}
+	if err := applyConfig(cfg); err != nil {
+		fmt.Println("could not apply the configuration:", err)
+		os.Exit(1)
+	}
+
+	// Allow changing the log level with SIGUSR1 / SIGUSR2
+	logging.HandleSignals()
 
 	log.Info().Msg("booting b3scale")
-	log.Debug().Str("url", dbConnStr).Msg("using database")
+	log.Debug().Str("url", cfg.DbConnStr).Msg("using database")
 
 	if revProxyEnabled {
 		log.Info().Msg("reverse proxy mode is enabled")
 	}
+	if bbb.ActiveTracer != nil {
+		log.Warn().
+			Str("dir", cfg.TraceDir).
+			Str("meetings", cfg.TraceMeeting).
+			Msg("request tracing is enabled")
+	}
 
-	// Initialize postgres connection
-	err = store.Connect(&store.ConnectOpts{
-		URL:      dbConnStr,
-		MaxConns: int32(dbPoolSize),
-		MinConns: 8,
-	})
-	if err != nil {
-		log.Fatal().Err(err).Msg("database connection")
+	if cfg.FaultPolicy != nil {
+		log.Warn().
+			Str("policy", cfg.Faults).
+			Msg("fault injection is enabled, do not use this in production")
+	}
+
+	if cfg.MirrorPolicy != nil {
+		log.Info().
+			Str("url", cfg.MirrorPolicy.URL).
+			Float64("rate", cfg.MirrorPolicy.Rate).
+			Msg("request mirroring is enabled")
+	}
+
+	for _, e := range cfg.ExperimentsList {
+		log.Info().
+			Str("experiment", e.Name).
+			Int("arms", len(e.Arms)).
+			Msg("experiment is active")
 	}
 
 	log.Info().
-		Int("maxConnections", dbPoolSize).
+		Int("maxConnections", cfg.DbPoolSize).
+		Int("minConnections", cfg.DbPoolMinConns).
+		Dur("maxConnIdleTime", cfg.DbPoolIdleTime).
+		Dur("healthCheckPeriod", cfg.DbPoolHealthCheck).
+		Dur("connectTimeout", cfg.DbConnectTimeout).
 		Msg("database pool")
 
+	// Provision static backends and frontends
+	if cfg.StaticConfig != "" {
+		log.Info().Str("file", cfg.StaticConfig).Msg("using static config")
+		if err := cluster.ProvisionStatic(
+			context.Background(),
+			&config.BackendsFileConfig{Filename: cfg.StaticConfig},
+			&config.FrontendsFileConfig{Filename: cfg.StaticConfig},
+		); err != nil {
+			log.Fatal().Err(err).Msg("static config")
+		}
+	}
+	if err := cluster.ProvisionStatic(
+		context.Background(), nil, &config.FrontendsEnvConfig{},
+	); err != nil {
+		log.Fatal().Err(err).Msg("frontend from environment")
+	}
+
 	// Initialize cluster
 	ctrl := cluster.NewController()
 
 	// Create router and configure middlewares.
 	// The middlewares are executes in reverse order.
 	router := cluster.NewRouter(ctrl)
+	router.UseRIB(cfg.MeetingsRIB)
+	go router.StartRIBSweep()
+	router.Use(routing.Canary)
+	if cfg.SlowBackendThreshold > 0 {
+		router.Use(routing.ShedSlowBackends(cfg.SlowBackendThreshold))
+	}
 	router.Use(routing.SortLoad)
 	router.Use(routing.RequiredTags)
+	router.Use(routing.RoutingSchedule)
+	if len(cfg.ExperimentsList) > 0 {
+		router.Use(routing.ExperimentPools)
+	}
+	if cfg.FaultPolicy != nil {
+		router.Use(routing.DropBackends(cfg.FaultPolicy))
+	}
 
 	// Start cluster request handler, and apply middlewares.
 	// The middlewares are executes in reverse order.
 	gateway := cluster.NewGateway(ctrl, &cluster.GatewayOptions{})
 
-	gateway.Use(requests.AdminRequestHandler(router))
-	gateway.Use(requests.RecordingsRequestHandler(
-		router, &requests.RecordingsHandlerOptions{}))
-	gateway.Use(requests.MeetingsRequestHandler(
+	// The requests are handled by resource at the
+	// end of the middleware chain.
+	gateway.HandleResources(requests.NewAdminHandler(router).Resources())
+	gateway.HandleResources(requests.NewRecordingsHandler(
+		router, &requests.RecordingsHandlerOptions{}).Resources())
+	gateway.HandleResources(requests.NewMeetingsHandler(
 		router, &requests.MeetingsHandlerOptions{
-			UseReverseProxy: revProxyEnabled,
-		}))
+			UseReverseProxy:  revProxyEnabled,
+			DiscoverMeetings: cfg.DiscoverMeetings,
+			PrewarmMeetings:  cfg.AdmissionPolicy != nil && cfg.AdmissionPolicy.Prewarm,
+			Gateway:          gateway,
+		}).Resources())
+
+	if cfg.OverloadPolicy != nil {
+		gateway.Use(requests.QueueOverloaded(cfg.OverloadPolicy))
+	}
+	if cfg.AdmissionPolicy != nil {
+		gateway.Use(requests.SmoothJoins(cfg.AdmissionPolicy))
+	}
 
+	if len(cfg.ExperimentsList) > 0 {
+		gateway.Use(requests.AssignExperiments(cfg.ExperimentsList))
+	}
+	gateway.Use(requests.MirrorCanary())
 	gateway.Use(requests.SetDefaultPresentation())
+	gateway.Use(requests.SetBranding())
+	gateway.Use(requests.SetGuestPolicy())
+	gateway.Use(requests.TagRequestID())
 	gateway.Use(requests.BindMeetingFrontend())
 	gateway.Use(requests.RewriteUniqueMeetingID())
+	gateway.Use(requests.TrackPolling())
+	gateway.Use(requests.CountUsage())
+	gateway.Use(requests.RejectReplays())
+	gateway.Use(requests.ExpireJoinLinks())
+	if cfg.MirrorPolicy != nil {
+		gateway.Use(requests.MirrorRequests(cfg.MirrorPolicy))
+	}
+	gateway.Use(requests.RejectBanned())
+	// Names are sanitized before checking the bans
+	gateway.Use(requests.SanitizeNames())
+	if cfg.FaultPolicy != nil {
+		gateway.Use(requests.InjectFaults(cfg.FaultPolicy))
+	}
 
 	// Start cluster controller
 	go ctrl.Start()
 
+	// Create the meetings of scheduled rooms
+	go cluster.NewRoomScheduler(gateway).Start()
+
+	// Pick up changed addresses of the backend hosts
+	if cfg.DNSRefreshInterval > 0 {
+		go bbb.StartDNSRefresh(cfg.DNSRefreshInterval)
+	}
+
+	// Create, join and end probe meetings on the backends
+	if cluster.ProbeInterval > 0 {
+		go cluster.NewProber().Start()
+	}
+
+	// Store the request counts of the frontends
+	go metrics.Usage.Start(context.Background(), metrics.UsageFlushInterval)
+
+	// Export the billing report of the previous day
+	if cfg.BillingExporter != nil {
+		go billing.Start(
+			context.Background(),
+			cfg.BillingExporter,
+			2*metrics.UsageFlushInterval)
+	}
+
+	// Export the history of the previous day
+	if cfg.HistoryTarget != nil {
+		go history.Start(
+			context.Background(),
+			cfg.HistoryTarget,
+			cfg.HistoryFormat,
+			2*metrics.UsageFlushInterval)
+	}
+
+	// Track the availability of the database
+	go store.MonitorHealth(context.Background(), 10*time.Second)
+
+	// Background tasks bound to the server are
+	// stopped on shutdown.
+	ctx, cancel := context.WithCancel(context.Background())
+	go v1.StartClusterEvents(ctx)
+
 	// Start HTTP interface
-	httpServer := http.NewServer("http", ctrl, gateway)
-	go httpServer.Start(listenHTTP)
+	httpServer := http.NewServer("http", ctrl, gateway, router,
+		&http.ServerOptions{
+			ListenAdmin: cfg.ListenAdmin,
+			PublicStats: cfg.PublicStatsPolicy,
+		})
+	go httpServer.Start(cfg.ListenHTTP)
 
-	<-quit
+	// Notify systemd and start the watchdog heartbeat
+	if _, err := systemd.Notify(systemd.StateReady); err != nil {
+		log.Error().Err(err).Msg("systemd notify")
+	}
+	go systemd.Watchdog(context.Background(), checkDatabase)
+
+	sig := <-quit
+	log.Info().Str("signal", sig.String()).Msg("shutting down")
+	cancel()
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := metrics.Usage.Flush(flushCtx); err != nil {
+		log.Error().Err(err).Msg("could not store frontend usage")
+	}
+	cancelFlush()
+	systemd.Notify(systemd.StateStopping)
 }
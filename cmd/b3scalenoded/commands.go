@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// The CommandHandler executes the commands queued
+// for the node agent of the backend.
+type CommandHandler struct {
+	backend *store.BackendState
+}
+
+// NewCommandHandler creates a handler for the backend
+func NewCommandHandler(backend *store.BackendState) *CommandHandler {
+	return &CommandHandler{
+		backend: backend,
+	}
+}
+
+// Handle invokes the handler function of the command
+func (h *CommandHandler) Handle(
+	ctx context.Context,
+	cmd *store.Command,
+) (interface{}, error) {
+	switch cmd.Action {
+	case cluster.CmdCollectDiagnostics:
+		log.Debug().Str("cmd", cluster.CmdCollectDiagnostics).Msg("EXEC")
+		return h.handleCollectDiagnostics(ctx, cmd)
+	default:
+		return nil, fmt.Errorf("unknown command: %s", cmd.Action)
+	}
+}
+
+// receiveCommands processes the commands for the node
+// agent of the backend. Commands without a handler are
+// left to the b3scale instances.
+func receiveCommands(backend *store.BackendState) {
+	handler := NewCommandHandler(backend)
+	queue := store.NewHandlerCommandQueue(
+		store.BackendAgentHandler(backend.ID))
+	for {
+		if err := queue.Receive(handler.Handle); err != nil {
+			log.Error().Err(err).Msg("receive next command")
+			time.Sleep(1 * time.Second)
+		}
+	}
+}
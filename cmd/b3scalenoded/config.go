@@ -10,6 +10,7 @@ import (
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/events"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
@@ -27,6 +28,27 @@ const (
 	CfgSecret       = "securitySalt"
 )
 
+// The release file of BBB contains the installed
+// version, e.g. BIGBLUEBUTTON_RELEASE=2.6.10
+const (
+	BBBReleaseFile = "/etc/bigbluebutton/bigbluebutton-release"
+	BBBReleaseKey  = "BIGBLUEBUTTON_RELEASE"
+)
+
+// Read the installed BBB version. The version is
+// empty if the release file is not present.
+func readBBBVersion(filename string) string {
+	props, err := config.ReadPropertiesFile(filename)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Msg("could not detect the BBB version")
+		return ""
+	}
+	version, _ := props.Get(BBBReleaseKey)
+	return version
+}
+
 // Make a redis url from the BBB config
 func configRedisURL(conf config.Properties) string {
 	host, ok := conf.Get("redisHost")
@@ -44,6 +66,20 @@ func configRedisURL(conf config.Properties) string {
 		pass, host, port)
 }
 
+// Select the events transport. With auto, the
+// transport is detected when connected to redis.
+func configEventsTransport(setting string) (events.Transport, error) {
+	switch setting {
+	case string(events.TransportPubSub):
+		return events.TransportPubSub, nil
+	case string(events.TransportStreams):
+		return events.TransportStreams, nil
+	case string(events.TransportAuto):
+		return events.TransportAuto, nil
+	}
+	return "", fmt.Errorf("unknown events transport: %s", setting)
+}
+
 // Try to resolve the backend state in the cluster by
 // serverURL und secret we have in the config.
 // Update the secret if it was changed.
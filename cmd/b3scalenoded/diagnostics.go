@@ -0,0 +1,182 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// DiagnosticsLogFiles are included in the diagnostic
+// bundle. Missing files are skipped.
+var DiagnosticsLogFiles = []string{
+	"/var/log/bigbluebutton/bbb-web.log",
+	"/var/log/bigbluebutton/bbb-rap-worker.log",
+	"/var/log/bbb-apps-akka/bbb-apps-akka.log",
+	"/var/log/bbb-fsesl-akka/bbb-fsesl-akka.log",
+	"/var/log/bbb-webrtc-sfu/bbb-webrtc-sfu.log",
+	"/opt/freeswitch/var/log/freeswitch/freeswitch.log",
+	"/var/log/nginx/error.log",
+}
+
+// Limits of the diagnostics collection
+const (
+	// diagnosticsLogTail is the maximum number of
+	// bytes read from the end of a log file.
+	diagnosticsLogTail = 512 * 1024
+
+	// diagnosticsCheckTimeout limits the runtime
+	// of bbb-conf --check.
+	diagnosticsCheckTimeout = 40 * time.Second
+)
+
+// tailFile reads the last bytes of a file, starting
+// with the first complete line.
+func tailFile(filename string, size int64) ([]byte, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	offset := info.Size() - size
+	if offset < 0 {
+		offset = 0
+	}
+	if _, err := f.Seek(offset, io.SeekStart); err != nil {
+		return nil, err
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		if i := bytes.IndexByte(data, '\n'); i >= 0 {
+			data = data[i+1:]
+		}
+	}
+	return data, nil
+}
+
+// runConfCheck runs bbb-conf --check. The output is
+// included even if the check fails.
+func runConfCheck(ctx context.Context) []byte {
+	ctx, cancel := context.WithTimeout(ctx, diagnosticsCheckTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "bbb-conf", "--check").CombinedOutput()
+	if err != nil {
+		out = append(out, []byte("\nbbb-conf --check failed: "+err.Error()+"\n")...)
+	}
+	return out
+}
+
+// collectDiagnostics creates a compressed tar archive
+// with the output of bbb-conf --check and the tails
+// of the log files.
+func collectDiagnostics(ctx context.Context) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	zw := gzip.NewWriter(buf)
+	tw := tar.NewWriter(zw)
+	now := time.Now()
+
+	add := func(name string, data []byte) error {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0644,
+			Size:    int64(len(data)),
+			ModTime: now,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+
+	if err := add("bbb-conf-check.txt", runConfCheck(ctx)); err != nil {
+		return nil, err
+	}
+	for _, filename := range DiagnosticsLogFiles {
+		data, err := tailFile(filename, diagnosticsLogTail)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("file", filename).
+				Msg("could not read log file for diagnostics")
+			continue
+		}
+		name := filepath.Join("logs", filepath.Base(filename))
+		if err := add(name, data); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// handleCollectDiagnostics collects the diagnostic
+// bundle and uploads it to the store.
+func (h *CommandHandler) handleCollectDiagnostics(
+	ctx context.Context,
+	cmd *store.Command,
+) (interface{}, error) {
+	req := &cluster.CollectDiagnosticsRequest{}
+	if err := cmd.FetchParams(ctx, req); err != nil {
+		return nil, err
+	}
+	if req.BackendID != h.backend.ID {
+		return nil, fmt.Errorf(
+			"diagnostics requested for backend: %s", req.BackendID)
+	}
+	bundle, err := collectDiagnostics(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	diagnostics := &store.BackendDiagnostics{
+		BackendID: h.backend.ID,
+		CommandID: &cmd.ID,
+		Bundle:    bundle,
+	}
+	if err := diagnostics.Save(ctx, tx); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+
+	log.Info().
+		Str("diagnosticsID", diagnostics.ID).
+		Int("size", diagnostics.Size).
+		Msg("uploaded diagnostics")
+
+	return diagnostics, nil
+}
@@ -12,7 +12,8 @@ import (
 )
 
 // The EventHandler processes BBB Events and updates
-// the cluster state
+// the cluster state. Events are delivered at least once,
+// so applying an event must be idempotent.
 type EventHandler struct {
 	backend *store.BackendState
 }
@@ -46,6 +47,19 @@ func (h *EventHandler) Dispatch(ctx context.Context, e bbb.Event) error {
 		return h.onUserJoinedMeeting(ctx, e.(*bbb.UserJoinedMeetingEvent))
 	case *bbb.UserLeftMeetingEvent:
 		return h.onUserLeftMeeting(ctx, e.(*bbb.UserLeftMeetingEvent))
+	case *bbb.UserRoleChangedEvent:
+		return h.onUserRoleChanged(ctx, e.(*bbb.UserRoleChangedEvent))
+
+	case *bbb.ScreenshareStartedEvent,
+		*bbb.ScreenshareStoppedEvent,
+		*bbb.PadCreatedEvent,
+		*bbb.PadUpdatedEvent:
+		log.Debug().
+			Str("type", fmt.Sprintf("%T", e)).
+			Str("event", fmt.Sprintf("%v", e)).
+			Msg("meeting activity")
+	case *bbb.RecordingReadyEvent:
+		return h.onRecordingReady(ctx, e.(*bbb.RecordingReadyEvent))
 
 	default:
 		log.Error().
@@ -66,9 +80,9 @@ func (h *EventHandler) onMeetingCreated(
 		Str("meetingID", e.MeetingID).
 		Msg("meeting created")
 
-	// TODO this might be handled through the context...
+	// The meeting might not yet be in the cluster state.
 	deadline := 5 * time.Second
-	mstate, err := awaitInternalMeeting(
+	mstate, err := store.AwaitMeetingState(
 		ctx, e.InternalMeetingID, deadline)
 	if err != nil {
 		return err
@@ -105,7 +119,7 @@ func (h *EventHandler) onMeetingEnded(
 		Str("internalMeetingID", e.InternalMeetingID).
 		Msg("meeting ended")
 	deadline := 5 * time.Second
-	mstate, err := awaitInternalMeeting(
+	mstate, err := store.AwaitMeetingState(
 		ctx, e.InternalMeetingID, deadline)
 	if err != nil {
 		return err
@@ -115,6 +129,7 @@ func (h *EventHandler) onMeetingEnded(
 			Str("internalMeetingID", e.InternalMeetingID).
 			Msg("meeting identified by internalMeetingID " +
 				"is unknown to the cluster")
+		return nil // however we are done here
 	}
 	// Reset meeting state
 	mstate.Meeting.Running = false
@@ -186,13 +201,16 @@ func (h *EventHandler) onUserJoinedMeeting(
 		return nil // however we are done here
 	}
 
-	// Update state attendees list
-	if mstate.Meeting.Attendees == nil {
-		mstate.Meeting.Attendees = []*bbb.Attendee{}
+	// Update state attendees list. Events might be
+	// replayed, so an attendee already present is replaced.
+	attendees := make([]*bbb.Attendee, 0, len(mstate.Meeting.Attendees)+1)
+	for _, a := range mstate.Meeting.Attendees {
+		if a.InternalUserID == e.Attendee.InternalUserID {
+			continue
+		}
+		attendees = append(attendees, a)
 	}
-	mstate.Meeting.Attendees = append(
-		mstate.Meeting.Attendees,
-		e.Attendee)
+	mstate.Meeting.Attendees = append(attendees, e.Attendee)
 
 	if err := mstate.Save(ctx, tx); err != nil {
 		return err
@@ -252,3 +270,68 @@ func (h *EventHandler) onUserLeftMeeting(
 
 	return tx.Commit(ctx)
 }
+
+// handle event: UserRoleChanged
+func (h *EventHandler) onUserRoleChanged(
+	ctx context.Context,
+	e *bbb.UserRoleChangedEvent,
+) error {
+	log.Info().
+		Str("internalUserID", e.InternalUserID).
+		Str("internalMeetingID", e.InternalMeetingID).
+		Str("role", e.Role).
+		Msg("user role changed")
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	mstate, err := store.GetMeetingState(ctx, tx, store.Q().
+		Where("meetings.internal_id = ?", e.InternalMeetingID))
+	if err != nil {
+		return err
+	}
+
+	if mstate == nil {
+		log.Warn().
+			Str("internalMeetingID", e.InternalMeetingID).
+			Msg("meeting identified by internalMeetingID " +
+				"is unknown to the cluster")
+		return nil // however we are done here
+	}
+
+	// Update the role of the attendee
+	for _, a := range mstate.Meeting.Attendees {
+		if a.InternalUserID == e.InternalUserID {
+			a.Role = e.Role
+		}
+	}
+	if err := mstate.Save(ctx, tx); err != nil {
+		return err
+	}
+
+	return tx.Commit(ctx)
+}
+
+// handle event: RecordingReady
+func (h *EventHandler) onRecordingReady(
+	ctx context.Context,
+	e *bbb.RecordingReadyEvent,
+) error {
+	if !e.Success {
+		log.Warn().
+			Str("internalMeetingID", e.InternalMeetingID).
+			Str("meetingID", e.MeetingID).
+			Str("workflow", e.Workflow).
+			Msg("publishing recording failed")
+		return nil
+	}
+	log.Info().
+		Str("internalMeetingID", e.InternalMeetingID).
+		Str("meetingID", e.MeetingID).
+		Str("workflow", e.Workflow).
+		Msg("recording ready")
+	return nil
+}
@@ -4,16 +4,20 @@ import (
 	"context"
 	"flag"
 	"fmt"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/go-redis/redis/v8"
 	"github.com/rs/zerolog/log"
 
-	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
 	"gitlab.com/infra.run/public/b3scale/pkg/events"
 	"gitlab.com/infra.run/public/b3scale/pkg/logging"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
+	"gitlab.com/infra.run/public/b3scale/pkg/systemd"
 )
 
 // Flags and parameters
@@ -29,6 +33,14 @@ func init() {
 		&autoregister, "a", false, usage+" (shorthand)")
 }
 
+// lastHeartbeat is the unix timestamp of the last
+// successful agent heartbeat.
+var lastHeartbeat int64
+
+// heartbeatTimeout is the maximum age of the last
+// successful heartbeat before the agent is considered unhealthy.
+const heartbeatTimeout = 10 * time.Second
+
 func heartbeat(backend *store.BackendState) {
 	ctx := context.Background()
 	for {
@@ -48,8 +60,8 @@ func heartbeat(backend *store.BackendState) {
 				Err(err).
 				Msg("update heartbeat failed")
 			tx.Rollback(ctx)
-		} else {
-			tx.Commit(ctx)
+		} else if err := tx.Commit(ctx); err == nil {
+			atomic.StoreInt64(&lastHeartbeat, time.Now().Unix())
 		}
 
 		conn.Release()
@@ -58,6 +70,42 @@ func heartbeat(backend *store.BackendState) {
 	}
 }
 
+// checkHeartbeat is used as health check for
+// the systemd watchdog.
+func checkHeartbeat() error {
+	last := time.Unix(atomic.LoadInt64(&lastHeartbeat), 0)
+	if time.Since(last) > heartbeatTimeout {
+		return fmt.Errorf("last heartbeat at %s", last)
+	}
+	return nil
+}
+
+// reload re-reads the BBB properties file and
+// updates the backend. The redis subscription
+// is not affected by this.
+func reload(ctx context.Context, bbbPropFile, redisURL string) {
+	systemd.Notify(systemd.StateReloading)
+	defer systemd.Notify(systemd.StateReady)
+
+	log.Info().
+		Str("file", bbbPropFile).
+		Msg("reloading bbb config")
+
+	bbbConf, err := config.ReadPropertiesFile(bbbPropFile)
+	if err != nil {
+		log.Error().Err(err).Msg("could not read bbb config")
+		return
+	}
+	if _, err := configToBackendState(ctx, bbbConf); err != nil {
+		log.Error().Err(err).Msg("could not update backend state")
+		return
+	}
+	if configRedisURL(bbbConf) != redisURL {
+		log.Warn().
+			Msg("redis connection changed, restart required to apply")
+	}
+}
+
 func main() {
 	ctx := context.Background()
 
@@ -77,6 +125,9 @@ func main() {
 	dbConnStr := config.EnvOpt(config.EnvDbURL, config.EnvDbURLDefault)
 	loglevel := config.EnvOpt(config.EnvLogLevel, config.EnvLogLevelDefault)
 	loadFactor := config.GetLoadFactor()
+	eventsTransport := config.EnvOpt(config.EnvBBBEvents, config.EnvBBBEventsDefault)
+	idFormat := config.EnvOpt(config.EnvIDFormat, config.EnvIDFormatDefault)
+	dbConnect := config.EnvOpt(config.EnvDbConnect, config.EnvDbConnectDefault)
 
 	// Configure logging
 	if err := logging.Setup(&logging.Options{
@@ -84,6 +135,7 @@ func main() {
 	}); err != nil {
 		panic(err)
 	}
+	logging.HandleSignals()
 
 	// Parse flags
 	flag.Parse()
@@ -95,12 +147,30 @@ func main() {
 			Err(err).Msg("could not read bbb config")
 	}
 
+	// The backend and the diagnostics use the same
+	// IDs as the rest of the cluster.
+	ids, err := store.GetIDGenerator(idFormat)
+	if err != nil {
+		log.Fatal().Err(err).Msg("id format")
+	}
+	store.IDs = ids
+
+	connectTimeout, err := time.ParseDuration(dbConnect)
+	if err != nil {
+		log.Fatal().Err(err).Msg("database connect timeout")
+	}
+
 	log.Info().Msg("booting b3scalenoded")
 	// Initialize postgres connection
 	if err := store.Connect(&store.ConnectOpts{
-		URL:      dbConnStr,
-		MaxConns: 16,
-		MinConns: 1,
+		URL:            dbConnStr,
+		MaxConns:       16,
+		MinConns:       1,
+		ConnectTimeout: connectTimeout,
+
+		// The compatibility is checked by the b3scale
+		// instances through the agent protocol.
+		AcceptNewerSchema: true,
 	}); err != nil {
 		log.Fatal().Err(err).Msg("database connection")
 	}
@@ -125,8 +195,9 @@ func main() {
 		}
 	}
 
-	// Set backend load factor
+	// Set backend load factor and version
 	backend.LoadFactor = loadFactor
+	backend.Version = readBBBVersion(BBBReleaseFile)
 
 	conn, err := store.Acquire(ctx)
 	if err != nil {
@@ -147,6 +218,7 @@ func main() {
 	}
 	log.Info().
 		Float64("loadFactor", loadFactor).
+		Str("version", backend.Version).
 		Msg("setting load_factor")
 	if err := tx.Commit(ctx); err != nil {
 		log.Error().
@@ -155,6 +227,12 @@ func main() {
 	}
 	conn.Release()
 
+	// Report the agent version and check for updates
+	if err := reportAgentVersion(ctx, backend); err != nil {
+		log.Error().Err(err).Msg("could not report agent version")
+	}
+	go checkAgentRelease()
+
 	// Make redis client
 	redisOpts, err := redis.ParseURL(configRedisURL(bbbConf))
 	if err != nil {
@@ -164,23 +242,78 @@ func main() {
 	// Mark the presence of the noded
 	go heartbeat(backend)
 
+	// Execute the commands for this backend
+	go receiveCommands(backend)
+
+	// Keep track of the processed events
+	offsets := newOffsetTracker(backend.ID)
+	go offsets.Start()
+
+	// Handle signals: Reload the config on SIGHUP
+	// and notify systemd when stopping.
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			if sig == syscall.SIGHUP {
+				reload(ctx, bbbPropFile, configRedisURL(bbbConf))
+				continue
+			}
+			log.Info().Str("signal", sig.String()).Msg("shutting down")
+			systemd.Notify(systemd.StateStopping)
+			if err := offsets.Flush(ctx); err != nil {
+				log.Error().Err(err).Msg("could not store event offsets")
+			}
+			os.Exit(0)
+		}
+	}()
+
 	rdb := redis.NewClient(redisOpts)
-	monitor := events.NewMonitor(rdb)
+	transport, err := configEventsTransport(eventsTransport)
+	if err != nil {
+		log.Fatal().Err(err).Msg("events transport")
+	}
+	if transport == events.TransportAuto {
+		transport, err = events.DetectTransport(ctx, rdb)
+		if err != nil {
+			log.Fatal().Err(err).Msg("detect events transport")
+		}
+	}
+	log.Info().
+		Str("transport", string(transport)).
+		Msg("using events transport")
+
+	// Resume after the last processed events
+	lastOffsets, err := loadEventOffsets(ctx, backend)
+	if err != nil {
+		log.Fatal().Err(err).Msg("load event offsets")
+	}
+
+	monitor := events.NewMonitor(rdb, &events.MonitorOptions{
+		Transport: transport,
+		Offsets:   lastOffsets,
+	})
 	channel := monitor.Subscribe()
-	for ev := range channel {
+
+	// We are ready. Start sending the watchdog
+	// heartbeat, if configured.
+	if _, err := systemd.Notify(systemd.StateReady); err != nil {
+		log.Error().Err(err).Msg("systemd notify")
+	}
+	go systemd.Watchdog(ctx, checkHeartbeat)
+
+	for delivery := range channel {
+		// The offset is committed when the event was
+		// handled. Events without stream position (pubsub)
+		// can not be replayed.
+		var pending *pendingOffset
+		if delivery.ID != "" {
+			pending = offsets.Add(delivery.Stream, delivery.ID)
+		}
+
 		// We are handling an event in it's own goroutine
-		go func(ev bbb.Event) {
-			handler := NewEventHandler(backend)
-			ctx, cancel := context.WithTimeout(
-				context.Background(), 15*time.Second)
-			defer cancel()
-
-			err := handler.Dispatch(ctx, ev)
-			if err != nil {
-				log.Error().
-					Err(err).
-					Msg("event handler")
-			}
-		}(ev)
+		handler := NewEventHandler(backend)
+		go handleEvent(
+			handler.Dispatch, offsets, delivery.Event, pending)
 	}
 }
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// offsetsFlushInterval is the interval in which
+// processed offsets are written to the database.
+const offsetsFlushInterval = 1 * time.Second
+
+// eventTimeout is the timeout for applying an event
+const eventTimeout = 15 * time.Second
+
+// eventRetryDelays are the delays between the attempts
+// to apply a failed event.
+var eventRetryDelays = []time.Duration{
+	1 * time.Second,
+	5 * time.Second,
+	15 * time.Second,
+	30 * time.Second,
+}
+
+// pendingOffset is an event in progress
+type pendingOffset struct {
+	stream string
+	id     string
+	done   bool
+}
+
+// The offsetTracker keeps track of the events in
+// progress. Events are handled concurrently, so an offset
+// can only be committed when all events before it are done.
+// This way events are processed at least once.
+type offsetTracker struct {
+	backendID string
+
+	mu        sync.Mutex
+	pending   map[string][]*pendingOffset
+	committed map[string]string
+	dirty     bool
+}
+
+// newOffsetTracker creates a new tracker for a backend
+func newOffsetTracker(backendID string) *offsetTracker {
+	return &offsetTracker{
+		backendID: backendID,
+		pending:   make(map[string][]*pendingOffset),
+		committed: make(map[string]string),
+	}
+}
+
+// Add registers an event as in progress
+func (t *offsetTracker) Add(stream, id string) *pendingOffset {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p := &pendingOffset{stream: stream, id: id}
+	t.pending[stream] = append(t.pending[stream], p)
+	return p
+}
+
+// Done marks the event as processed and advances the
+// committed offset over all completed events.
+func (t *offsetTracker) Done(p *pendingOffset) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	p.done = true
+
+	queue := t.pending[p.stream]
+	n := 0
+	for ; n < len(queue) && queue[n].done; n++ {
+		t.committed[p.stream] = queue[n].id
+		t.dirty = true
+	}
+	t.pending[p.stream] = queue[n:]
+}
+
+// takeCommitted returns a copy of the committed offsets,
+// if they changed since they were taken last.
+func (t *offsetTracker) takeCommitted() map[string]string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.dirty {
+		return nil
+	}
+	offsets := make(map[string]string, len(t.committed))
+	for stream, id := range t.committed {
+		offsets[stream] = id
+	}
+	t.dirty = false
+	return offsets
+}
+
+// Flush writes the committed offsets to the database
+func (t *offsetTracker) Flush(ctx context.Context) error {
+	offsets := t.takeCommitted()
+	if offsets == nil {
+		return nil
+	}
+
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	for stream, id := range offsets {
+		if err := store.SetEventOffset(
+			ctx, tx, t.backendID, stream, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Start periodically flushes the offsets
+func (t *offsetTracker) Start() {
+	for {
+		time.Sleep(offsetsFlushInterval)
+		ctx, cancel := context.WithTimeout(
+			context.Background(), 5*time.Second)
+		if err := t.Flush(ctx); err != nil {
+			log.Error().Err(err).Msg("could not store event offsets")
+			t.mu.Lock()
+			t.dirty = true
+			t.mu.Unlock()
+		}
+		cancel()
+	}
+}
+
+// handleEvent applies the event and marks the offset as
+// done only when the event was applied. A failed event is
+// retried. When all attempts failed, the offset is not
+// committed. This holds back the offsets of all later
+// events of the stream, so they are replayed together
+// with the failed event after a restart.
+func handleEvent(
+	dispatch func(context.Context, bbb.Event) error,
+	offsets *offsetTracker,
+	ev bbb.Event,
+	pending *pendingOffset,
+) {
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(
+			context.Background(), eventTimeout)
+		err := dispatch(ctx, ev)
+		cancel()
+		if err == nil {
+			if pending != nil {
+				offsets.Done(pending)
+			}
+			return
+		}
+
+		if attempt >= len(eventRetryDelays) {
+			logEvent := log.Error().Err(err)
+			if pending != nil {
+				logEvent = logEvent.
+					Str("stream", pending.stream).
+					Str("id", pending.id)
+			}
+			logEvent.Msg("event handler failed, giving up")
+			return
+		}
+		delay := eventRetryDelays[attempt]
+		log.Error().
+			Err(err).
+			Dur("retryIn", delay).
+			Msg("event handler")
+		time.Sleep(delay)
+	}
+}
+
+// loadEventOffsets retrieves the persisted offsets
+// of the backend.
+func loadEventOffsets(
+	ctx context.Context,
+	backend *store.BackendState,
+) (map[string]string, error) {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return store.GetEventOffsets(ctx, tx, backend.ID)
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestOffsetTrackerInOrder(t *testing.T) {
+	tracker := newOffsetTracker("backend1")
+	p1 := tracker.Add("stream", "1-0")
+	p2 := tracker.Add("stream", "2-0")
+
+	tracker.Done(p1)
+	tracker.Done(p2)
+
+	offsets := tracker.takeCommitted()
+	if offsets["stream"] != "2-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+	if offsets := tracker.takeCommitted(); offsets != nil {
+		t.Error("unchanged offsets should not be stored again:", offsets)
+	}
+}
+
+func TestOffsetTrackerOutOfOrder(t *testing.T) {
+	tracker := newOffsetTracker("backend1")
+	p1 := tracker.Add("stream", "1-0")
+	p2 := tracker.Add("stream", "2-0")
+	p3 := tracker.Add("stream", "3-0")
+
+	// An event completed before earlier events
+	// must not advance the offset.
+	tracker.Done(p2)
+	if offsets := tracker.takeCommitted(); offsets != nil {
+		t.Error("offset advanced over pending event:", offsets)
+	}
+
+	tracker.Done(p1)
+	offsets := tracker.takeCommitted()
+	if offsets["stream"] != "2-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+
+	tracker.Done(p3)
+	offsets = tracker.takeCommitted()
+	if offsets["stream"] != "3-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
+
+func TestOffsetTrackerStreams(t *testing.T) {
+	tracker := newOffsetTracker("backend1")
+	a := tracker.Add("stream-a", "1-0")
+	b := tracker.Add("stream-b", "5-0")
+
+	// Streams are tracked independently
+	tracker.Done(b)
+	offsets := tracker.takeCommitted()
+	if offsets["stream-b"] != "5-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+	if _, ok := offsets["stream-a"]; ok {
+		t.Error("unexpected offset of pending stream:", offsets)
+	}
+
+	tracker.Done(a)
+	offsets = tracker.takeCommitted()
+	if offsets["stream-a"] != "1-0" || offsets["stream-b"] != "5-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
+
+func TestOffsetTrackerWithoutEvents(t *testing.T) {
+	// With pubsub, deliveries have no ID and are not
+	// tracked. Nothing is stored.
+	tracker := newOffsetTracker("backend1")
+	if offsets := tracker.takeCommitted(); offsets != nil {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
+
+func TestHandleEventFailed(t *testing.T) {
+	eventRetryDelays = []time.Duration{0, 0}
+
+	tracker := newOffsetTracker("backend1")
+	pending := tracker.Add("stream", "1-0")
+
+	attempts := 0
+	dispatch := func(context.Context, bbb.Event) error {
+		attempts++
+		return errors.New("database unavailable")
+	}
+	handleEvent(dispatch, tracker, &bbb.MeetingEndedEvent{}, pending)
+
+	if attempts != 3 {
+		t.Error("unexpected attempts:", attempts)
+	}
+	// The event must be replayed, so the offset
+	// is not committed.
+	if offsets := tracker.takeCommitted(); offsets != nil {
+		t.Error("offset of failed event committed:", offsets)
+	}
+
+	// Later events are held back as well
+	next := tracker.Add("stream", "2-0")
+	tracker.Done(next)
+	if offsets := tracker.takeCommitted(); offsets != nil {
+		t.Error("offset advanced over failed event:", offsets)
+	}
+}
+
+func TestHandleEventRetry(t *testing.T) {
+	eventRetryDelays = []time.Duration{0, 0}
+
+	tracker := newOffsetTracker("backend1")
+	pending := tracker.Add("stream", "1-0")
+
+	attempts := 0
+	dispatch := func(context.Context, bbb.Event) error {
+		attempts++
+		if attempts == 1 {
+			return errors.New("database unavailable")
+		}
+		return nil
+	}
+	handleEvent(dispatch, tracker, &bbb.MeetingEndedEvent{}, pending)
+
+	if attempts != 2 {
+		t.Error("unexpected attempts:", attempts)
+	}
+	offsets := tracker.takeCommitted()
+	if offsets["stream"] != "1-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// agentReleaseInterval is the interval in which
+// the desired agent release is checked.
+const agentReleaseInterval = 5 * time.Minute
+
+// reportAgentVersion stores the version and the protocol
+// version of the agent. The b3scale instances refuse
+// agents with an unsupported protocol.
+func reportAgentVersion(
+	ctx context.Context,
+	backend *store.BackendState,
+) error {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if err := backend.UpdateAgentVersion(
+		ctx, tx, config.Version, cluster.AgentProtocolVersion,
+	); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// checkAgentRelease periodically compares the version
+// of the agent with the desired release. The agent is
+// not updated, the skew is reported for the operator.
+func checkAgentRelease() {
+	ctx := context.Background()
+	for {
+		release, err := getAgentRelease(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("could not check agent release")
+		} else if release.IsSkewed(config.Version) {
+			log.Warn().
+				Str("version", config.Version).
+				Str("desired", release.Version).
+				Msg("the node agent is outdated")
+		}
+		time.Sleep(agentReleaseInterval)
+	}
+}
+
+// getAgentRelease retrieves the desired release
+func getAgentRelease(ctx context.Context) (*store.AgentRelease, error) {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	return store.GetAgentRelease(ctx, tx)
+}
@@ -75,5 +75,8 @@ if [ $OPT_CLEAR -eq 1 ]; then
 fi
 
 ## Apply sql scripts
-$PSQL -v ON_ERROR_STOP=on < schema/0001_initial_tables.sql
+for script in schema/*.sql; do
+    echo "++ applying $script"
+    $PSQL -v ON_ERROR_STOP=on < $script
+done
 
@@ -0,0 +1,13 @@
+// Package db contains the database schema of b3scale
+package db
+
+import (
+	"embed"
+)
+
+// Schema contains the migrations in schema/. The files
+// are applied in order, each file inserts its version
+// into the __meta__ table.
+//
+//go:embed schema/*.sql
+var Schema embed.FS
@@ -0,0 +1,47 @@
+
+--
+-- ----------------------
+-- b3scale schema v.1.1.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Frontend secret rotation and audit log.
+--
+
+-- When a frontend secret is regenerated, the previous
+-- secret is still accepted until the end of the
+-- overlap window.
+ALTER TABLE frontends
+  ADD COLUMN previous_secret            text      NULL DEFAULT NULL,
+  ADD COLUMN previous_secret_expires_at TIMESTAMP NULL DEFAULT NULL;
+
+
+-- The audit log records administrative actions
+-- like rotating secrets.
+CREATE TABLE audit_log (
+    id      uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    -- The actor is the subject of the API request,
+    -- e.g. the account ref.
+    actor   VARCHAR(255) NOT NULL,
+
+    -- The action is a well known identifier like
+    -- `frontend_secret_regenerated`.
+    action  VARCHAR(80)  NOT NULL,
+
+    -- The affected resource
+    resource_type VARCHAR(80)  NOT NULL,
+    resource_id   VARCHAR(255) NOT NULL,
+
+    -- Additional information. Never store secrets here.
+    details jsonb NOT NULL DEFAULT '{}'::jsonb,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_audit_log_resource ON audit_log
+ USING BTREE ( resource_type, resource_id );
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (2, 'frontend secret rotation and audit log');
@@ -0,0 +1,28 @@
+--
+-- ----------------------
+-- b3scale schema v.1.2.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Persisted event stream offsets.
+--
+
+-- The node agent stores the ID of the last processed
+-- entry of each redis stream, so events are replayed
+-- after a restart.
+CREATE TABLE event_offsets (
+    backend_id  uuid NOT NULL
+                REFERENCES backends(id)
+                ON DELETE CASCADE,
+
+    stream      VARCHAR(255) NOT NULL,
+    last_id     VARCHAR(80)  NOT NULL,
+
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (backend_id, stream)
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (3, 'event stream offsets');
@@ -0,0 +1,37 @@
+--
+-- ----------------------
+-- b3scale schema v.1.3.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Notify when a meeting becomes available.
+--
+
+-- AfterMeetingsChanged
+-- BBB events might arrive before the meeting state
+-- was stored. Listeners are notified with the internal
+-- meeting ID, so they do not have to poll.
+CREATE FUNCTION after_meetings_changed() RETURNS TRIGGER AS $$
+BEGIN
+  IF NEW.internal_id IS NOT NULL THEN
+    PERFORM pg_notify('meetings_available', NEW.internal_id);
+  END IF;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+-- Updates only notify when the internal ID changes,
+-- not on every update of the meeting state.
+CREATE TRIGGER meetings_inserted  AFTER INSERT
+    ON meetings
+  FOR EACH ROW  EXECUTE PROCEDURE after_meetings_changed();
+
+CREATE TRIGGER meetings_changed  AFTER UPDATE OF internal_id
+    ON meetings
+  FOR EACH ROW
+  WHEN (OLD.internal_id IS DISTINCT FROM NEW.internal_id)
+  EXECUTE PROCEDURE after_meetings_changed();
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (4, 'notify available meetings');
@@ -0,0 +1,99 @@
+--
+-- ----------------------
+-- b3scale schema v.1.4.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Notify about cluster events.
+--
+
+-- NotifyClusterEvent
+-- Cluster events are published on the cluster_events
+-- channel as JSON. The payload only contains identifiers
+-- and states, so it stays below the notify size limit.
+CREATE FUNCTION notify_cluster_event(
+    event_type TEXT,
+    event_data jsonb
+) RETURNS VOID AS $$
+BEGIN
+  PERFORM pg_notify('cluster_events', json_build_object(
+    'type', event_type,
+    'time', now(),
+    'data', event_data)::text);
+END
+$$ LANGUAGE plpgsql;
+
+-- AfterBackendsChanged
+CREATE FUNCTION after_backends_changed() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    PERFORM notify_cluster_event('backend_added', jsonb_build_object(
+      'id', NEW.id,
+      'host', NEW.host,
+      'node_state', NEW.node_state,
+      'admin_state', NEW.admin_state));
+  ELSIF TG_OP = 'DELETE' THEN
+    PERFORM notify_cluster_event('backend_removed', jsonb_build_object(
+      'id', OLD.id,
+      'host', OLD.host));
+  ELSIF OLD.node_state  IS DISTINCT FROM NEW.node_state OR
+        OLD.admin_state IS DISTINCT FROM NEW.admin_state THEN
+    PERFORM notify_cluster_event('backend_state_changed', jsonb_build_object(
+      'id', NEW.id,
+      'host', NEW.host,
+      'node_state', NEW.node_state,
+      'admin_state', NEW.admin_state));
+  END IF;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER backends_events  AFTER INSERT OR UPDATE OR DELETE
+    ON backends
+  FOR EACH ROW  EXECUTE PROCEDURE after_backends_changed();
+
+-- AfterMeetingsCreatedOrEnded
+CREATE FUNCTION after_meetings_created_or_ended() RETURNS TRIGGER AS $$
+BEGIN
+  IF TG_OP = 'INSERT' THEN
+    PERFORM notify_cluster_event('meeting_created', jsonb_build_object(
+      'id', NEW.id,
+      'internal_id', NEW.internal_id,
+      'backend_id', NEW.backend_id,
+      'frontend_id', NEW.frontend_id));
+  ELSE
+    PERFORM notify_cluster_event('meeting_ended', jsonb_build_object(
+      'id', OLD.id,
+      'internal_id', OLD.internal_id,
+      'backend_id', OLD.backend_id,
+      'frontend_id', OLD.frontend_id));
+  END IF;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER meetings_events  AFTER INSERT OR DELETE
+    ON meetings
+  FOR EACH ROW  EXECUTE PROCEDURE after_meetings_created_or_ended();
+
+-- AfterCommandsStopped
+CREATE FUNCTION after_commands_stopped() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM notify_cluster_event('command_executed', jsonb_build_object(
+    'id', NEW.id,
+    'action', NEW.action,
+    'state', NEW.state));
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER commands_events  AFTER UPDATE OF state
+    ON commands
+  FOR EACH ROW
+  WHEN (NEW.state IN ('success', 'error') AND
+        OLD.state IS DISTINCT FROM NEW.state)
+  EXECUTE PROCEDURE after_commands_stopped();
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (5, 'notify cluster events');
@@ -0,0 +1,39 @@
+--
+-- ----------------------
+-- b3scale schema v.1.5.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Experiment assignments of meetings.
+--
+
+-- Each created meeting taking part in an experiment
+-- is recorded with the assigned arm. The assignments
+-- are kept after the meeting ended, so the usage of
+-- the arms can be compared.
+CREATE TABLE experiment_assignments (
+    id          SERIAL PRIMARY KEY,
+
+    experiment  VARCHAR(255) NOT NULL,
+    arm         VARCHAR(255) NOT NULL,
+
+    meeting_id  VARCHAR(255) NOT NULL,
+    frontend_id uuid         NULL
+                REFERENCES frontends(id)
+                ON DELETE SET NULL,
+    backend_id  uuid         NULL
+                REFERENCES backends(id)
+                ON DELETE SET NULL,
+
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX experiment_assignments_experiment_arm_idx
+          ON experiment_assignments (experiment, arm);
+
+CREATE INDEX experiment_assignments_meeting_id_idx
+          ON experiment_assignments (meeting_id);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (6, 'experiment assignments');
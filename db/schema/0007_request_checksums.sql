@@ -0,0 +1,30 @@
+--
+-- ----------------------
+-- b3scale schema v.1.6.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Seen request checksums for replay protection.
+--
+
+-- The checksums of requests of frontends with replay
+-- protection are remembered until they expire. A request
+-- with a remembered checksum and nonce is a replay.
+CREATE TABLE request_checksums (
+    frontend_id uuid         NOT NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+    checksum    VARCHAR(255) NOT NULL,
+    nonce       VARCHAR(255) NOT NULL DEFAULT '',
+
+    expires_at  TIMESTAMP    NOT NULL,
+
+    PRIMARY KEY (frontend_id, checksum, nonce)
+);
+
+CREATE INDEX request_checksums_expires_at_idx
+          ON request_checksums (expires_at);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (7, 'request checksums');
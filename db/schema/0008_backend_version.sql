@@ -0,0 +1,18 @@
+--
+-- ----------------------
+-- b3scale schema v.1.7.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: BBB version of the backends.
+--
+
+-- The version is detected by the node agent. Requests
+-- are adapted to the version, e.g. deprecated parameters
+-- are rewritten. Empty if unknown.
+ALTER TABLE backends
+  ADD COLUMN bbb_version VARCHAR(64) NOT NULL DEFAULT '';
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (8, 'backend version');
@@ -0,0 +1,29 @@
+--
+-- ----------------------
+-- b3scale schema v.1.8.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: API usage of the frontends per day.
+--
+
+-- The requests of a frontend are counted per day
+-- and resource. The counts of all instances are added up.
+CREATE TABLE frontend_usage (
+    frontend_id uuid        NOT NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+    day         DATE        NOT NULL,
+    resource    VARCHAR(64) NOT NULL,
+
+    requests    BIGINT      NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (frontend_id, day, resource)
+);
+
+CREATE INDEX frontend_usage_day_idx
+          ON frontend_usage (day);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (9, 'frontend usage');
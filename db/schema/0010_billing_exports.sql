@@ -0,0 +1,20 @@
+--
+-- ----------------------
+-- b3scale schema v.1.9.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Billing exports of the frontend usage.
+--
+
+-- Each day is exported once. An instance claims a day
+-- before exporting it, so the export is not repeated
+-- by other instances.
+CREATE TABLE billing_exports (
+    day         DATE      PRIMARY KEY,
+    exported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (10, 'billing exports');
@@ -0,0 +1,26 @@
+--
+-- ----------------------
+-- b3scale schema v.1.10.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Notify when commands are finished.
+--
+
+-- Instances waiting for the result of a command are
+-- notified with the command id as payload.
+CREATE FUNCTION after_commands_finished() RETURNS TRIGGER AS $$
+BEGIN
+  PERFORM pg_notify('commands_finished', NEW.id::text);
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER command_finished AFTER UPDATE OF state ON commands
+  FOR EACH ROW
+  WHEN (NEW.state IN ('success', 'error'))
+  EXECUTE PROCEDURE after_commands_finished();
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (11, 'commands finished notification');
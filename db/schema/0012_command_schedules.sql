@@ -0,0 +1,33 @@
+--
+-- ----------------------
+-- b3scale schema v.1.11.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Scheduled and recurring commands.
+--
+
+-- Commands with a run_at time are not processed before.
+ALTER TABLE commands
+  ADD COLUMN run_at TIMESTAMP NULL DEFAULT NULL;
+
+-- Recurring commands are queued by the instances
+-- when the next run is due. The next run is calculated
+-- from the cron expression.
+CREATE TABLE command_schedules (
+    name        VARCHAR(255) PRIMARY KEY,
+    cron        VARCHAR(255) NOT NULL,
+
+    action      VARCHAR(80)  NOT NULL,
+    params      json         NULL,
+
+    next_run_at TIMESTAMP    NOT NULL,
+    last_run_at TIMESTAMP    NULL DEFAULT NULL,
+
+    created_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at  TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (12, 'command schedules');
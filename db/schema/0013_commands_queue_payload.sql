@@ -0,0 +1,30 @@
+--
+-- ----------------------
+-- b3scale schema v.1.12.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Notify the queued command.
+--
+
+-- Instances are notified with the seq and the action
+-- of the new command as payload ("<seq>:<action>"), so
+-- the command can be fetched directly instead of
+-- competing for the head of the queue.
+CREATE OR REPLACE FUNCTION after_commands_insert() RETURNS TRIGGER AS $$
+BEGIN
+  -- Housekeeping: Remove expired commands.
+  DELETE FROM commands
+   WHERE (deadline + interval '1 minute') 
+         < now() AT TIME ZONE 'utc';
+
+  -- Finally inform instances, that a new command
+  -- was queued.
+  PERFORM pg_notify('commands_queue', NEW.seq::text || ':' || NEW.action);
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (13, 'commands queue notification payload');
@@ -0,0 +1,21 @@
+--
+-- ----------------------
+-- b3scale schema v.1.13.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Commands for a specific handler.
+--
+
+-- Commands without handler are processed by any b3scale
+-- instance. Otherwise only the handler with the identity
+-- processes the command, e.g. the node agent of a backend.
+ALTER TABLE commands
+  ADD COLUMN handler VARCHAR(255) NULL DEFAULT NULL;
+
+CREATE INDEX commands_handler_state_index
+    ON commands (handler, state);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (14, 'command handlers');
@@ -0,0 +1,34 @@
+--
+-- ----------------------
+-- b3scale schema v.1.14.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Diagnostic bundles of the backends.
+--
+
+-- The node agent uploads a bundle with the output of
+-- bbb-conf --check and log excerpts, collected by the
+-- collect_diagnostics command.
+CREATE TABLE backend_diagnostics (
+    id          uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    backend_id  uuid NOT NULL
+                REFERENCES backends(id)
+                ON DELETE CASCADE,
+
+    -- The command requesting the bundle
+    command_id  uuid NULL DEFAULT NULL,
+
+    -- Compressed tar archive
+    bundle      bytea NOT NULL,
+
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX backend_diagnostics_backend_id_index
+    ON backend_diagnostics (backend_id, created_at);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (15, 'backend diagnostics');
@@ -0,0 +1,33 @@
+--
+-- ----------------------
+-- b3scale schema v.1.15.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Store recordings by BBB record ID.
+--
+
+-- BBB record IDs are not UUIDs, but the internal meeting
+-- ID with a timestamp. Recordings outlive their meeting,
+-- so the reference to the meeting is removed.
+ALTER TABLE recording_text_tracks
+    DROP CONSTRAINT recording_text_tracks_record_id_fkey;
+ALTER TABLE recordings
+    DROP CONSTRAINT recordings_internal_meeting_id_fkey;
+
+ALTER TABLE recordings
+    ALTER COLUMN id TYPE VARCHAR(255);
+ALTER TABLE recording_text_tracks
+    ALTER COLUMN record_id TYPE VARCHAR(255);
+
+ALTER TABLE recording_text_tracks
+    ADD CONSTRAINT recording_text_tracks_record_id_fkey
+    FOREIGN KEY (record_id) REFERENCES recordings(id)
+    ON DELETE CASCADE;
+
+CREATE INDEX recordings_backend_id_index
+    ON recordings (backend_id);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (16, 'recordings record id');
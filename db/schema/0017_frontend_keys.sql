@@ -0,0 +1,34 @@
+--
+-- ----------------------
+-- b3scale schema v.1.16.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Additional keys of frontends.
+--
+
+-- A frontend can have additional key and secret pairs,
+-- e.g. for the staging and production nodes of an LMS.
+-- Requests with an additional key are handled like
+-- requests of the frontend.
+CREATE TABLE frontend_keys (
+    id          uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    frontend_id uuid NOT NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+
+    key         VARCHAR(255) NOT NULL UNIQUE,
+    secret      VARCHAR(255) NOT NULL,
+
+    description TEXT NOT NULL DEFAULT '',
+
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX frontend_keys_frontend_id_index
+    ON frontend_keys (frontend_id);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (17, 'frontend keys');
@@ -0,0 +1,24 @@
+--
+-- ----------------------
+-- b3scale schema v.1.17.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Parent frontends.
+--
+
+-- A frontend can belong to a parent frontend, e.g. a
+-- faculty within a university. Settings not set are
+-- inherited from the parent, and the usage can be
+-- reported for the parent.
+ALTER TABLE frontends
+    ADD COLUMN parent_id uuid NULL DEFAULT NULL
+               REFERENCES frontends(id)
+               ON DELETE SET NULL;
+
+CREATE INDEX frontends_parent_id_index
+    ON frontends (parent_id);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (18, 'frontend parents');
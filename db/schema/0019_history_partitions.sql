@@ -0,0 +1,130 @@
+--
+-- ----------------------
+-- b3scale schema v.1.18.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Partition the history and prune it.
+--
+
+-- The usage of the frontends is partitioned by month,
+-- so the history can be pruned by dropping partitions.
+-- Rows without a partition end up in the default
+-- partition.
+ALTER TABLE frontend_usage RENAME TO frontend_usage_old;
+ALTER TABLE frontend_usage_old
+    RENAME CONSTRAINT frontend_usage_pkey TO frontend_usage_old_pkey;
+ALTER INDEX frontend_usage_day_idx RENAME TO frontend_usage_old_day_idx;
+
+CREATE TABLE frontend_usage (
+    frontend_id uuid        NOT NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+    day         DATE        NOT NULL,
+    resource    VARCHAR(64) NOT NULL,
+
+    requests    BIGINT      NOT NULL DEFAULT 0,
+
+    PRIMARY KEY (frontend_id, day, resource)
+) PARTITION BY RANGE (day);
+
+CREATE INDEX frontend_usage_day_idx
+          ON frontend_usage (day);
+
+CREATE TABLE frontend_usage_default
+    PARTITION OF frontend_usage DEFAULT;
+
+-- CreateFrontendUsagePartition
+-- Creates the partition of a month, e.g. frontend_usage_2026_10,
+-- unless it exists. Rows of the month in the default partition
+-- are moved to the new partition.
+CREATE FUNCTION create_frontend_usage_partition(
+    for_month DATE
+) RETURNS TEXT AS $$
+DECLARE
+  first_day DATE := date_trunc('month', for_month)::date;
+  next_day  DATE := (date_trunc('month', for_month) + interval '1 month')::date;
+  part_name TEXT := 'frontend_usage_' || to_char(for_month, 'YYYY_MM');
+BEGIN
+  -- Instances maintain the partitions concurrently
+  PERFORM pg_advisory_xact_lock(hashtext('frontend_usage_partitions'));
+  IF to_regclass(part_name) IS NOT NULL THEN
+    RETURN part_name;
+  END IF;
+
+  EXECUTE format(
+    'CREATE TABLE %I (LIKE frontend_usage INCLUDING DEFAULTS)',
+    part_name);
+  EXECUTE format(
+    'INSERT INTO %I SELECT * FROM frontend_usage_default
+      WHERE day >= %L AND day < %L',
+    part_name, first_day, next_day);
+  DELETE FROM frontend_usage_default
+   WHERE day >= first_day AND day < next_day;
+  EXECUTE format(
+    'ALTER TABLE frontend_usage ATTACH PARTITION %I
+       FOR VALUES FROM (%L) TO (%L)',
+    part_name, first_day, next_day);
+  RETURN part_name;
+END
+$$ LANGUAGE plpgsql;
+
+-- DropFrontendUsagePartitions
+-- Removes the usage before a day. Only partitions ending
+-- before the day are dropped, so the usage of a month
+-- is removed at once.
+CREATE FUNCTION drop_frontend_usage_partitions(
+    before_day DATE
+) RETURNS INTEGER AS $$
+DECLARE
+  part_name TEXT;
+  dropped   INTEGER := 0;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('frontend_usage_partitions'));
+  FOR part_name IN
+    SELECT c.relname
+      FROM pg_inherits AS i
+      JOIN pg_class AS c ON c.oid = i.inhrelid
+     WHERE i.inhparent = 'frontend_usage'::regclass
+       AND c.relname ~ '^frontend_usage_[0-9]{4}_[0-9]{2}$'
+  LOOP
+    IF to_date(substr(part_name, 16), 'YYYY_MM') + interval '1 month'
+       <= before_day THEN
+      EXECUTE format('DROP TABLE %I', part_name);
+      dropped := dropped + 1;
+    END IF;
+  END LOOP;
+  DELETE FROM frontend_usage_default WHERE day < before_day;
+  RETURN dropped;
+END
+$$ LANGUAGE plpgsql;
+
+-- Create the partitions for the existing usage
+-- up to the next month and move the rows.
+SELECT create_frontend_usage_partition(month::date)
+  FROM generate_series(
+         date_trunc('month', LEAST(
+           (SELECT min(day) FROM frontend_usage_old),
+           CURRENT_DATE)),
+         date_trunc('month', CURRENT_DATE + interval '1 month'),
+         interval '1 month') AS month;
+
+INSERT INTO frontend_usage (frontend_id, day, resource, requests)
+     SELECT frontend_id, day, resource, requests
+       FROM frontend_usage_old;
+
+DROP TABLE frontend_usage_old;
+
+
+-- The audit log is append only. A BRIN index is
+-- small and sufficient for pruning old entries.
+CREATE INDEX audit_log_created_at_index
+    ON audit_log USING BRIN (created_at);
+
+-- Expired commands are removed for each new command.
+CREATE INDEX commands_deadline_index
+    ON commands (deadline);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (19, 'history partitions');
@@ -0,0 +1,36 @@
+--
+-- ----------------------
+-- b3scale schema v.1.19.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Persistent rooms of frontends.
+--
+
+-- A room maps a stable meeting ID of a frontend to
+-- the parameters of the meeting. Joining a room creates
+-- the meeting if it is not running.
+CREATE TABLE rooms (
+    id           uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    frontend_id  uuid NOT NULL
+                 REFERENCES frontends(id)
+                 ON DELETE CASCADE,
+
+    meeting_id   VARCHAR(255) NOT NULL,
+    name         TEXT NOT NULL DEFAULT '',
+
+    -- Additional create parameters
+    params       JSONB NOT NULL DEFAULT '{}',
+
+    moderator_pw VARCHAR(255) NOT NULL,
+    attendee_pw  VARCHAR(255) NOT NULL,
+
+    created_at   TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (frontend_id, meeting_id)
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (20, 'rooms');
@@ -0,0 +1,24 @@
+--
+-- ----------------------
+-- b3scale schema v.1.20.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Schedules of rooms.
+--
+
+-- A room can have a schedule, e.g. for a weekly lecture.
+-- The meeting is created a few minutes before the start.
+ALTER TABLE rooms
+    ADD COLUMN schedule      VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN timezone      VARCHAR(64)  NOT NULL DEFAULT 'UTC',
+    ADD COLUMN duration      INTEGER      NOT NULL DEFAULT 0,
+    ADD COLUMN next_start_at TIMESTAMP    NULL;
+
+CREATE INDEX rooms_next_start_at_index
+    ON rooms (next_start_at)
+ WHERE next_start_at IS NOT NULL;
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (21, 'room schedules');
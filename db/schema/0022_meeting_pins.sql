@@ -0,0 +1,42 @@
+--
+-- ----------------------
+-- b3scale schema v.1.21.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Pin meetings to backends.
+--
+
+-- A pin overrides the routing of a new meeting of a
+-- frontend, e.g. for placing a high-profile event on
+-- the strongest backend. The pin is ignored after
+-- it expired.
+CREATE TABLE meeting_pins (
+    id          uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    frontend_id uuid NOT NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+
+    -- The meeting ID used by the frontend
+    meeting_id  VARCHAR(255) NOT NULL,
+
+    backend_id  uuid NOT NULL
+                REFERENCES backends(id)
+                ON DELETE CASCADE,
+
+    note        TEXT NOT NULL DEFAULT '',
+    created_by  VARCHAR(255) NOT NULL DEFAULT '',
+
+    expires_at  TIMESTAMP NOT NULL,
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE (frontend_id, meeting_id)
+);
+
+CREATE INDEX meeting_pins_expires_at_index
+    ON meeting_pins (expires_at);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (22, 'meeting pins');
@@ -0,0 +1,31 @@
+--
+-- ----------------------
+-- b3scale schema v.1.22.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Ban meeting IDs and user names.
+--
+
+-- A ban blocks meetings by ID or users by a pattern
+-- of the full name, e.g. in case of spam or abuse.
+-- A ban without frontend applies to all frontends.
+CREATE TABLE bans (
+    id          uuid DEFAULT uuid_generate_v4() PRIMARY KEY,
+
+    frontend_id uuid NULL
+                REFERENCES frontends(id)
+                ON DELETE CASCADE,
+
+    -- The kind is either meeting_id or full_name
+    kind        VARCHAR(32) NOT NULL,
+    pattern     TEXT NOT NULL,
+
+    reason      TEXT NOT NULL DEFAULT '',
+    created_by  VARCHAR(255) NOT NULL DEFAULT '',
+    created_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (23, 'bans');
@@ -0,0 +1,29 @@
+--
+-- ----------------------
+-- b3scale schema v.1.23.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Track the versions of the node agents.
+--
+
+-- The version of b3scalenoded running on the backend
+ALTER TABLE backends
+  ADD COLUMN agent_version VARCHAR(64) NOT NULL DEFAULT '';
+
+-- The desired version of the node agents. There is at
+-- most one release. The agents report the version skew,
+-- but do not update themselves.
+CREATE TABLE agent_releases (
+    id          INTEGER PRIMARY KEY DEFAULT 1
+                CHECK (id = 1),
+
+    version     VARCHAR(64) NOT NULL,
+
+    created_by  VARCHAR(255) NOT NULL DEFAULT '',
+    updated_at  TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (24, 'agent versions');
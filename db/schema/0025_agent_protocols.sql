@@ -0,0 +1,17 @@
+--
+-- ----------------------
+-- b3scale schema v.1.24.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Track the protocol versions of the node agents.
+--
+
+-- The protocol version advertised by the node agent.
+-- Agents before the handshake are version 0.
+ALTER TABLE backends
+  ADD COLUMN agent_protocol INTEGER NOT NULL DEFAULT 0;
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (25, 'agent protocols');
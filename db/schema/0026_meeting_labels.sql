@@ -0,0 +1,19 @@
+--
+-- ----------------------
+-- b3scale schema v.1.25.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Labels of meetings, like the course or department.
+--
+
+-- The labels are attached when the meeting is created
+-- and used to filter the meetings and the usage.
+ALTER TABLE meetings
+  ADD COLUMN labels JSONB NOT NULL DEFAULT '{}'::jsonb;
+
+CREATE INDEX meetings_labels_idx ON meetings USING GIN (labels);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (26, 'meeting labels');
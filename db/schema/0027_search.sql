@@ -0,0 +1,50 @@
+--
+-- ----------------------
+-- b3scale schema v.1.26.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Full text search over meetings and recordings.
+--
+
+-- MeetingSearchDocument
+-- The name, ID and metadata of a meeting as text
+-- search document. The 'simple' configuration is used,
+-- because meeting names are in any language.
+CREATE FUNCTION meeting_search_document(
+    meeting_id TEXT,
+    state jsonb
+) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('simple',
+                   COALESCE(state->>'MeetingName', '')), 'A') ||
+         setweight(to_tsvector('simple',
+                   COALESCE(meeting_id, '')), 'B') ||
+         setweight(jsonb_to_tsvector('simple',
+                   COALESCE(state->'Metadata', '{}'::jsonb),
+                   '["string"]'), 'C')
+$$ LANGUAGE sql IMMUTABLE;
+
+-- RecordingSearchDocument
+-- The name, meeting ID and metadata of a recording
+-- as text search document.
+CREATE FUNCTION recording_search_document(
+    state jsonb
+) RETURNS tsvector AS $$
+  SELECT setweight(to_tsvector('simple',
+                   COALESCE(state->>'Name', '')), 'A') ||
+         setweight(to_tsvector('simple',
+                   COALESCE(state->>'MeetingID', '')), 'B') ||
+         setweight(jsonb_to_tsvector('simple',
+                   COALESCE(state->'Metadata', '{}'::jsonb),
+                   '["string"]'), 'C')
+$$ LANGUAGE sql IMMUTABLE;
+
+CREATE INDEX meetings_search_idx ON meetings
+       USING GIN (meeting_search_document(id, state));
+
+CREATE INDEX recordings_search_idx ON recordings
+       USING GIN (recording_search_document(state));
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (27, 'search');
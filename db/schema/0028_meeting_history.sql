@@ -0,0 +1,65 @@
+--
+-- ----------------------
+-- b3scale schema v.1.27.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: History of ended meetings and its exports.
+--
+
+-- Meetings are removed from the store when they end.
+-- The history keeps a summary of the ended meetings.
+-- Frontends and backends are not referenced, because
+-- the history outlives them.
+CREATE TABLE meeting_history (
+    id            BIGSERIAL    PRIMARY KEY,
+
+    meeting_id    VARCHAR(255) NOT NULL,
+    internal_id   VARCHAR(255) NULL,
+    meeting_name  TEXT         NOT NULL DEFAULT '',
+
+    frontend_id   uuid         NULL,
+    backend_id    uuid         NULL,
+
+    labels        JSONB        NOT NULL DEFAULT '{}'::jsonb,
+
+    -- The participant count of the last sync
+    participants  INTEGER      NOT NULL DEFAULT 0,
+
+    created_at    TIMESTAMP    NOT NULL,
+    ended_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX meeting_history_ended_at_idx
+          ON meeting_history (ended_at);
+
+-- AfterMeetingsDeleted
+CREATE FUNCTION after_meetings_deleted() RETURNS TRIGGER AS $$
+BEGIN
+  INSERT INTO meeting_history (
+    meeting_id, internal_id, meeting_name,
+    frontend_id, backend_id, labels,
+    participants, created_at
+  ) VALUES (
+    OLD.id, OLD.internal_id, COALESCE(OLD.state->>'MeetingName', ''),
+    OLD.frontend_id, OLD.backend_id, OLD.labels,
+    COALESCE((OLD.state->>'ParticipantCount')::int, 0), OLD.created_at
+  );
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER meetings_history  AFTER DELETE
+    ON meetings
+  FOR EACH ROW  EXECUTE PROCEDURE after_meetings_deleted();
+
+-- Each day of the history is exported once,
+-- like the billing exports.
+CREATE TABLE history_exports (
+    day         DATE      PRIMARY KEY,
+    exported_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (28, 'meeting history');
@@ -0,0 +1,132 @@
+--
+-- ----------------------
+-- b3scale schema v.1.28.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Partition the meeting history by month.
+--
+
+-- The history of the meetings is partitioned by the
+-- month the meetings ended, like the frontend usage.
+-- The sequence of the ids is kept.
+ALTER TABLE meeting_history RENAME TO meeting_history_old;
+ALTER TABLE meeting_history_old
+    RENAME CONSTRAINT meeting_history_pkey TO meeting_history_old_pkey;
+ALTER INDEX meeting_history_ended_at_idx
+    RENAME TO meeting_history_old_ended_at_idx;
+ALTER SEQUENCE meeting_history_id_seq OWNED BY NONE;
+
+CREATE TABLE meeting_history (
+    id            BIGINT       NOT NULL
+                  DEFAULT nextval('meeting_history_id_seq'),
+
+    meeting_id    VARCHAR(255) NOT NULL,
+    internal_id   VARCHAR(255) NULL,
+    meeting_name  TEXT         NOT NULL DEFAULT '',
+
+    frontend_id   uuid         NULL,
+    backend_id    uuid         NULL,
+
+    labels        JSONB        NOT NULL DEFAULT '{}'::jsonb,
+
+    -- The participant count of the last sync
+    participants  INTEGER      NOT NULL DEFAULT 0,
+
+    created_at    TIMESTAMP    NOT NULL,
+    ended_at      TIMESTAMP    NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id, ended_at)
+) PARTITION BY RANGE (ended_at);
+
+ALTER SEQUENCE meeting_history_id_seq OWNED BY meeting_history.id;
+
+CREATE INDEX meeting_history_ended_at_idx
+          ON meeting_history (ended_at);
+
+CREATE TABLE meeting_history_default
+    PARTITION OF meeting_history DEFAULT;
+
+-- CreateMeetingHistoryPartition
+-- Creates the partition of a month, e.g. meeting_history_2026_10,
+-- unless it exists. Rows of the month in the default partition
+-- are moved to the new partition.
+CREATE FUNCTION create_meeting_history_partition(
+    for_month DATE
+) RETURNS TEXT AS $$
+DECLARE
+  first_day DATE := date_trunc('month', for_month)::date;
+  next_day  DATE := (date_trunc('month', for_month) + interval '1 month')::date;
+  part_name TEXT := 'meeting_history_' || to_char(for_month, 'YYYY_MM');
+BEGIN
+  -- Instances maintain the partitions concurrently
+  PERFORM pg_advisory_xact_lock(hashtext('meeting_history_partitions'));
+  IF to_regclass(part_name) IS NOT NULL THEN
+    RETURN part_name;
+  END IF;
+
+  EXECUTE format(
+    'CREATE TABLE %I (LIKE meeting_history INCLUDING DEFAULTS)',
+    part_name);
+  EXECUTE format(
+    'INSERT INTO %I SELECT * FROM meeting_history_default
+      WHERE ended_at >= %L AND ended_at < %L',
+    part_name, first_day, next_day);
+  DELETE FROM meeting_history_default
+   WHERE ended_at >= first_day AND ended_at < next_day;
+  EXECUTE format(
+    'ALTER TABLE meeting_history ATTACH PARTITION %I
+       FOR VALUES FROM (%L) TO (%L)',
+    part_name, first_day, next_day);
+  RETURN part_name;
+END
+$$ LANGUAGE plpgsql;
+
+-- DropMeetingHistoryPartitions
+-- Removes the history before a time. Only partitions
+-- ending before the time are dropped, so the history
+-- of a month is removed at once.
+CREATE FUNCTION drop_meeting_history_partitions(
+    before_time TIMESTAMP
+) RETURNS INTEGER AS $$
+DECLARE
+  part_name TEXT;
+  dropped   INTEGER := 0;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('meeting_history_partitions'));
+  FOR part_name IN
+    SELECT c.relname
+      FROM pg_inherits AS i
+      JOIN pg_class AS c ON c.oid = i.inhrelid
+     WHERE i.inhparent = 'meeting_history'::regclass
+       AND c.relname ~ '^meeting_history_[0-9]{4}_[0-9]{2}$'
+  LOOP
+    IF to_date(substr(part_name, 17), 'YYYY_MM') + interval '1 month'
+       <= before_time THEN
+      EXECUTE format('DROP TABLE %I', part_name);
+      dropped := dropped + 1;
+    END IF;
+  END LOOP;
+  DELETE FROM meeting_history_default WHERE ended_at < before_time;
+  RETURN dropped;
+END
+$$ LANGUAGE plpgsql;
+
+-- Create the partitions for the existing history
+-- up to the next month and move the rows.
+SELECT create_meeting_history_partition(month::date)
+  FROM generate_series(
+         date_trunc('month', LEAST(
+           (SELECT min(ended_at) FROM meeting_history_old),
+           CURRENT_DATE)),
+         date_trunc('month', CURRENT_DATE + interval '1 month'),
+         interval '1 month') AS month;
+
+INSERT INTO meeting_history
+     SELECT * FROM meeting_history_old;
+
+DROP TABLE meeting_history_old;
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (29, 'meeting history partitions');
@@ -0,0 +1,25 @@
+--
+-- ----------------------
+-- b3scale schema v.1.29.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Sent notifications.
+--
+
+-- The notifications of operational events are remembered
+-- until the repeat interval is over, so all instances
+-- send an event only once. The key is the type and the
+-- subject of the event.
+CREATE TABLE notifications_sent (
+    key         TEXT      PRIMARY KEY,
+    sent_at     TIMESTAMP NOT NULL,
+    expires_at  TIMESTAMP NOT NULL
+);
+
+CREATE INDEX notifications_sent_expires_at_idx
+          ON notifications_sent (expires_at);
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (30, 'notifications');
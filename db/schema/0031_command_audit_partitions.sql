@@ -0,0 +1,289 @@
+--
+-- ----------------------
+-- b3scale schema v.1.30.0
+-- ----------------------
+--
+-- %% Author:      annika
+-- %% Description: Partition the commands and the audit log.
+--
+
+-- The commands are partitioned by the day they were
+-- created. Days where all commands are expired are
+-- dropped instead of deleting the rows.
+-- The sequence of the seq is kept.
+DELETE FROM commands
+ WHERE (deadline + interval '1 minute')
+       < now() AT TIME ZONE 'utc';
+
+ALTER TABLE commands RENAME TO commands_old;
+ALTER TABLE commands_old
+    RENAME CONSTRAINT commands_pkey TO commands_old_pkey;
+ALTER INDEX commands_handler_state_index
+    RENAME TO commands_old_handler_state_index;
+ALTER INDEX commands_deadline_index
+    RENAME TO commands_old_deadline_index;
+ALTER SEQUENCE commands_seq_seq OWNED BY NONE;
+
+CREATE TABLE commands (
+    id      uuid          NOT NULL
+                          DEFAULT uuid_generate_v4(),
+    seq     INTEGER       NOT NULL
+                          DEFAULT nextval('commands_seq_seq'),
+
+    state   command_state DEFAULT 'requested',
+
+    action  VARCHAR(80)   NOT NULL,
+    params  json          NULL,
+    result  json          NULL,
+
+    deadline   TIMESTAMP  NOT NULL,
+    started_at TIMESTAMP  NULL       DEFAULT NULL,
+    stopped_at TIMESTAMP  NULL       DEFAULT NULL,
+    created_at TIMESTAMP  NOT NULL   DEFAULT CURRENT_TIMESTAMP,
+
+    run_at     TIMESTAMP    NULL DEFAULT NULL,
+    handler    VARCHAR(255) NULL DEFAULT NULL,
+
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+ALTER SEQUENCE commands_seq_seq OWNED BY commands.seq;
+
+CREATE INDEX commands_handler_state_index
+    ON commands (handler, state);
+CREATE INDEX commands_deadline_index
+    ON commands (deadline);
+
+CREATE TABLE commands_default
+    PARTITION OF commands DEFAULT;
+
+-- CreateCommandsPartition
+-- Creates the partition of a day, e.g. commands_2026_10_16,
+-- unless it exists. Rows of the day in the default partition
+-- are moved to the new partition.
+CREATE FUNCTION create_commands_partition(
+    for_day DATE
+) RETURNS TEXT AS $$
+DECLARE
+  next_day  DATE := for_day + 1;
+  part_name TEXT := 'commands_' || to_char(for_day, 'YYYY_MM_DD');
+BEGIN
+  -- Instances maintain the partitions concurrently
+  PERFORM pg_advisory_xact_lock(hashtext('commands_partitions'));
+  IF to_regclass(part_name) IS NOT NULL THEN
+    RETURN part_name;
+  END IF;
+
+  EXECUTE format(
+    'CREATE TABLE %I (LIKE commands INCLUDING DEFAULTS)',
+    part_name);
+  EXECUTE format(
+    'INSERT INTO %I SELECT * FROM commands_default
+      WHERE created_at >= %L AND created_at < %L',
+    part_name, for_day, next_day);
+  DELETE FROM commands_default
+   WHERE created_at >= for_day AND created_at < next_day;
+  EXECUTE format(
+    'ALTER TABLE commands ATTACH PARTITION %I
+       FOR VALUES FROM (%L) TO (%L)',
+    part_name, for_day, next_day);
+  RETURN part_name;
+END
+$$ LANGUAGE plpgsql;
+
+-- DropCommandsPartitions
+-- Removes the partitions of the days before a time,
+-- when all commands of the day are expired.
+CREATE FUNCTION drop_commands_partitions(
+    before_time TIMESTAMP
+) RETURNS INTEGER AS $$
+DECLARE
+  part_name TEXT;
+  expired   BOOLEAN;
+  dropped   INTEGER := 0;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('commands_partitions'));
+  FOR part_name IN
+    SELECT c.relname
+      FROM pg_inherits AS i
+      JOIN pg_class AS c ON c.oid = i.inhrelid
+     WHERE i.inhparent = 'commands'::regclass
+       AND c.relname ~ '^commands_[0-9]{4}_[0-9]{2}_[0-9]{2}$'
+  LOOP
+    IF to_date(substr(part_name, 10), 'YYYY_MM_DD') + 1
+       <= before_time THEN
+      EXECUTE format(
+        'SELECT NOT EXISTS (
+           SELECT 1 FROM %I
+            WHERE (deadline + interval ''1 minute'')
+                  >= now() AT TIME ZONE ''utc'')',
+        part_name) INTO expired;
+      IF expired THEN
+        EXECUTE format('DROP TABLE %I', part_name);
+        dropped := dropped + 1;
+      END IF;
+    END IF;
+  END LOOP;
+  RETURN dropped;
+END
+$$ LANGUAGE plpgsql;
+
+-- Create the partitions for the existing commands
+-- up to the next day and move the rows.
+SELECT create_commands_partition(day::date)
+  FROM generate_series(
+         date_trunc('day', LEAST(
+           (SELECT min(created_at) FROM commands_old),
+           CURRENT_DATE)),
+         date_trunc('day', CURRENT_DATE + interval '1 day'),
+         interval '1 day') AS day;
+
+INSERT INTO commands (
+    id, seq, state, action, params, result,
+    deadline, started_at, stopped_at, created_at,
+    run_at, handler
+) SELECT id, seq, state, action, params, result,
+         deadline, started_at, stopped_at, created_at,
+         run_at, handler
+    FROM commands_old;
+
+DROP TABLE commands_old;
+
+-- The triggers are created after moving the rows,
+-- so the instances are not notified again.
+CREATE TRIGGER  command_insert    AFTER INSERT ON commands
+  FOR EACH ROW  EXECUTE PROCEDURE after_commands_insert();
+
+CREATE TRIGGER commands_events  AFTER UPDATE OF state
+    ON commands
+  FOR EACH ROW
+  WHEN (NEW.state IN ('success', 'error') AND
+        OLD.state IS DISTINCT FROM NEW.state)
+  EXECUTE PROCEDURE after_commands_stopped();
+
+CREATE TRIGGER command_finished AFTER UPDATE OF state ON commands
+  FOR EACH ROW
+  WHEN (NEW.state IN ('success', 'error'))
+  EXECUTE PROCEDURE after_commands_finished();
+
+
+-- The audit log is partitioned by month, like the
+-- meeting history.
+ALTER TABLE audit_log RENAME TO audit_log_old;
+ALTER TABLE audit_log_old
+    RENAME CONSTRAINT audit_log_pkey TO audit_log_old_pkey;
+ALTER INDEX idx_audit_log_resource
+    RENAME TO idx_audit_log_old_resource;
+ALTER INDEX audit_log_created_at_index
+    RENAME TO audit_log_old_created_at_index;
+
+CREATE TABLE audit_log (
+    id      uuid NOT NULL DEFAULT uuid_generate_v4(),
+
+    actor   VARCHAR(255) NOT NULL,
+    action  VARCHAR(80)  NOT NULL,
+
+    resource_type VARCHAR(80)  NOT NULL,
+    resource_id   VARCHAR(255) NOT NULL,
+
+    details jsonb NOT NULL DEFAULT '{}'::jsonb,
+
+    created_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+
+    PRIMARY KEY (id, created_at)
+) PARTITION BY RANGE (created_at);
+
+CREATE INDEX idx_audit_log_resource ON audit_log
+ USING BTREE ( resource_type, resource_id );
+CREATE INDEX audit_log_created_at_index
+    ON audit_log (created_at);
+
+CREATE TABLE audit_log_default
+    PARTITION OF audit_log DEFAULT;
+
+-- CreateAuditLogPartition
+-- Creates the partition of a month, e.g. audit_log_2026_10,
+-- unless it exists. Rows of the month in the default partition
+-- are moved to the new partition.
+CREATE FUNCTION create_audit_log_partition(
+    for_month DATE
+) RETURNS TEXT AS $$
+DECLARE
+  first_day DATE := date_trunc('month', for_month)::date;
+  next_day  DATE := (date_trunc('month', for_month) + interval '1 month')::date;
+  part_name TEXT := 'audit_log_' || to_char(for_month, 'YYYY_MM');
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('audit_log_partitions'));
+  IF to_regclass(part_name) IS NOT NULL THEN
+    RETURN part_name;
+  END IF;
+
+  EXECUTE format(
+    'CREATE TABLE %I (LIKE audit_log INCLUDING DEFAULTS)',
+    part_name);
+  EXECUTE format(
+    'INSERT INTO %I SELECT * FROM audit_log_default
+      WHERE created_at >= %L AND created_at < %L',
+    part_name, first_day, next_day);
+  DELETE FROM audit_log_default
+   WHERE created_at >= first_day AND created_at < next_day;
+  EXECUTE format(
+    'ALTER TABLE audit_log ATTACH PARTITION %I
+       FOR VALUES FROM (%L) TO (%L)',
+    part_name, first_day, next_day);
+  RETURN part_name;
+END
+$$ LANGUAGE plpgsql;
+
+-- DropAuditLogPartitions
+-- Removes the entries before a time. The partitions
+-- ending before the time are dropped, the remaining
+-- entries are deleted.
+CREATE FUNCTION drop_audit_log_partitions(
+    before_time TIMESTAMP
+) RETURNS INTEGER AS $$
+DECLARE
+  part_name TEXT;
+  dropped   INTEGER := 0;
+BEGIN
+  PERFORM pg_advisory_xact_lock(hashtext('audit_log_partitions'));
+  FOR part_name IN
+    SELECT c.relname
+      FROM pg_inherits AS i
+      JOIN pg_class AS c ON c.oid = i.inhrelid
+     WHERE i.inhparent = 'audit_log'::regclass
+       AND c.relname ~ '^audit_log_[0-9]{4}_[0-9]{2}$'
+  LOOP
+    IF to_date(substr(part_name, 11), 'YYYY_MM') + interval '1 month'
+       <= before_time THEN
+      EXECUTE format('DROP TABLE %I', part_name);
+      dropped := dropped + 1;
+    END IF;
+  END LOOP;
+  DELETE FROM audit_log WHERE created_at < before_time;
+  RETURN dropped;
+END
+$$ LANGUAGE plpgsql;
+
+-- Create the partitions for the existing entries
+-- up to the next month and move the rows.
+SELECT create_audit_log_partition(month::date)
+  FROM generate_series(
+         date_trunc('month', LEAST(
+           (SELECT min(created_at) FROM audit_log_old),
+           CURRENT_DATE)),
+         date_trunc('month', CURRENT_DATE + interval '1 month'),
+         interval '1 month') AS month;
+
+INSERT INTO audit_log (
+    id, actor, action, resource_type, resource_id,
+    details, created_at
+) SELECT id, actor, action, resource_type, resource_id,
+         details, created_at
+    FROM audit_log_old;
+
+DROP TABLE audit_log_old;
+
+
+INSERT INTO __meta__ (version, description)
+     VALUES (31, 'command and audit log partitions');
@@ -0,0 +1,22 @@
+package db
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestSchemaVersion(t *testing.T) {
+	migrations, err := store.GetMigrations(Schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	last := migrations[len(migrations)-1]
+	if last.Version != store.SchemaVersion {
+		t.Error("the schema version does not match the migrations:",
+			last.Version, store.SchemaVersion)
+	}
+}
@@ -19,8 +19,34 @@ const (
 	ResourceSetConfigXML           = "setConfigXML"
 	ResourceGetRecordingTextTracks = "getRecordingTextTracks"
 	ResourcePutRecordingTextTrack  = "putRecordingTextTrack"
+	ResourceSendChatMessage        = "sendChatMessage"
 )
 
+// ParamGuestPolicy is the create parameter
+// for the guest policy of a meeting.
+const ParamGuestPolicy = "guestPolicy"
+
+// Guest policies
+const (
+	GuestPolicyAlwaysAccept     = "ALWAYS_ACCEPT"
+	GuestPolicyAlwaysDeny       = "ALWAYS_DENY"
+	GuestPolicyAskModerator     = "ASK_MODERATOR"
+	GuestPolicyAlwaysAcceptAuth = "ALWAYS_ACCEPT_AUTH"
+)
+
+// IsValidGuestPolicy checks if the guest policy
+// is known to BBB.
+func IsValidGuestPolicy(policy string) bool {
+	switch policy {
+	case GuestPolicyAlwaysAccept,
+		GuestPolicyAlwaysDeny,
+		GuestPolicyAskModerator,
+		GuestPolicyAlwaysAcceptAuth:
+		return true
+	}
+	return false
+}
+
 // API is the bbb api interface
 type API interface {
 	Join(*Request) (*JoinResponse, error)
@@ -7,46 +7,198 @@ package bbb
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/logging"
 )
 
+// SlowRequestThreshold is the duration after which a
+// request to a backend is considered slow and will be logged
+// as a warning. Setting the threshold to zero disables this.
+var SlowRequestThreshold = 2 * time.Second
+
+// MaxResponseSize limits the size of a response body
+// read from a backend. Setting the limit to zero disables this.
+var MaxResponseSize int64
+
+// ErrResponseTooLarge is returned when the body of
+// a backend response exceeds the MaxResponseSize.
+var ErrResponseTooLarge = errors.New(
+	"backend response exceeds the size limit")
+
+// OverloadError is returned when a backend rejects a
+// request with 503 Service Unavailable or 429 Too Many Requests
+// without a BBB API response, e.g. from a reverse proxy.
+type OverloadError struct {
+	Status     int
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *OverloadError) Error() string {
+	return fmt.Sprintf("backend is overloaded: %d %s",
+		e.Status, http.StatusText(e.Status))
+}
+
+// IsOverloadStatus checks if the HTTP status
+// signals that the backend is overloaded.
+func IsOverloadStatus(status int) bool {
+	return status == http.StatusServiceUnavailable ||
+		status == http.StatusTooManyRequests
+}
+
+// ParseRetryAfter reads the Retry-After header. The
+// header is either a delay in seconds or a date.
+func ParseRetryAfter(h http.Header) time.Duration {
+	val := h.Get("Retry-After")
+	if val == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(val); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(val); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// decodeError replaces an error decoding the response
+// with an OverloadError if the backend is overloaded.
+func decodeError(res *http.Response, err error) error {
+	if !IsOverloadStatus(res.StatusCode) {
+		return err
+	}
+	return &OverloadError{
+		Status:     res.StatusCode,
+		RetryAfter: ParseRetryAfter(res.Header),
+	}
+}
+
+// streamDecoders read large responses directly from
+// the response body instead of buffering it first.
+var streamDecoders = map[string]func(io.Reader) (Response, error){
+	ResourceGetRecordings: func(r io.Reader) (Response, error) {
+		return DecodeGetRecordingsResponse(r)
+	},
+}
+
+// incrementalDecoders keep the response body open
+// and decode the response while it is streamed to
+// the client. The response closes the body.
+var incrementalDecoders = map[string]func(io.ReadCloser) (Response, error){
+	ResourceGetRecordings: func(r io.ReadCloser) (Response, error) {
+		return StreamGetRecordingsResponse(r)
+	},
+}
+
+// responseBody is the body of a response decoded
+// while streaming. Closing the body ends the request.
+type responseBody struct {
+	io.Reader
+	body   io.Closer
+	cancel context.CancelFunc
+}
+
+// Close drains and closes the body, so the
+// connection can be reused.
+func (b *responseBody) Close() error {
+	defer b.cancel()
+	_, err := io.Copy(ioutil.Discard, b.Reader)
+	if cerr := b.body.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// limitedBody fails with ErrResponseTooLarge
+// when more than n bytes are read.
+type limitedBody struct {
+	r io.Reader
+	n int64
+}
+
+// limitResponseBody applies the MaxResponseSize
+func limitResponseBody(r io.Reader) io.Reader {
+	if MaxResponseSize <= 0 {
+		return r
+	}
+	return &limitedBody{r: r, n: MaxResponseSize}
+}
+
+// Read from the body until the limit is exceeded
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.n+1 {
+		p = p[:b.n+1]
+	}
+	n, err := b.r.Read(p)
+	if int64(n) > b.n {
+		b.n = 0
+		return 0, ErrResponseTooLarge
+	}
+	b.n -= int64(n)
+	return n, err
+}
+
 // A Client for communicating with a big blue button
 // instance. Requests are signed and encoded.
 // Responses are decoded.
 type Client struct {
-	conn *http.Client
+	conn       *http.Client
+	timeouts   Timeouts
+	serverName string
 }
 
-// NewClient creates and configures a new http client
-// and creates the big blue client object.
+// NewClient creates the big blue client object.
+// All clients share the http client, so connections
+// to the backends are kept alive and reused.
 func NewClient() *Client {
-	conn := &http.Client{
-		Transport: &http.Transport{
-			ForceAttemptHTTP2:     true,
-			MaxIdleConnsPerHost:   20,
-			IdleConnTimeout:       300 * time.Second,
-			ResponseHeaderTimeout: 60 * time.Second,
-			TLSHandshakeTimeout:   10 * time.Second,
-			ExpectContinueTimeout: 1 * time.Second,
-		},
-		CheckRedirect: func(req *http.Request, via []*http.Request) error {
-			return http.ErrUseLastResponse // Thou shalt not follow redirects
-		},
-	}
-
 	c := &Client{
-		conn: conn,
+		conn: sharedConn,
 	}
 
 	return c
 }
 
+// NewClientWithTimeouts creates a client with request
+// timeouts for a backend. Resources without a timeout
+// use the global RequestTimeouts.
+func NewClientWithTimeouts(timeouts Timeouts) *Client {
+	c := NewClient()
+	c.timeouts = timeouts
+	return c
+}
+
+// NewBackendClient creates a client with the request
+// timeouts and the connection options of a backend.
+func NewBackendClient(
+	timeouts Timeouts,
+	opts *ConnOptions,
+) (*Client, error) {
+	conn, err := connFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	c := &Client{
+		conn:     conn,
+		timeouts: timeouts,
+	}
+	if opts != nil {
+		c.serverName = opts.ServerName
+	}
+	return c, nil
+}
+
 // Internal response decoding
 func unmarshalRequestResponse(req *Request, data []byte) (Response, error) {
 	switch req.Resource {
@@ -78,20 +230,49 @@ func unmarshalRequestResponse(req *Request, data []byte) (Response, error) {
 		return UnmarshalGetRecordingTextTracksResponse(data)
 	case ResourcePutRecordingTextTrack:
 		return UnmarshalPutRecordingTextTrackResponse(data)
+	case ResourceSendChatMessage:
+		return UnmarshalSendChatMessageResponse(data)
 	}
 
 	return nil, fmt.Errorf(
 		"no response decoder for resource: %s", req.Resource)
 }
 
+// UnmarshalResponse decodes the response of
+// a BBB API resource.
+func UnmarshalResponse(resource string, data []byte) (Response, error) {
+	return unmarshalRequestResponse(&Request{Resource: resource}, data)
+}
+
 // Do sends the request to the backend.
 // The request is signed.
 // The response is decoded into a BBB response.
 func (c *Client) Do(ctx context.Context, req *Request) (Response, error) {
-	log.Debug().
-		Str("method", req.Request.Method).
-		Str("url", req.URL()).
-		Msg("client request")
+	return c.do(ctx, req, false)
+}
+
+// Stream sends the request to the backend like Do.
+// Large responses, like getRecordings, are decoded
+// while the response is streamed to the client. The
+// caller must stream, decode or close these responses.
+func (c *Client) Stream(ctx context.Context, req *Request) (Response, error) {
+	return c.do(ctx, req, true)
+}
+
+// do sends the request and decodes the response
+func (c *Client) do(
+	ctx context.Context,
+	req *Request,
+	incremental bool,
+) (Response, error) {
+	// Building the URL signs the request, which
+	// is skipped when debug logging is disabled.
+	if event := log.Debug(); event.Enabled() && logging.Sampled(req.Resource) {
+		event.
+			Str("method", req.Request.Method).
+			Str("url", req.URL()).
+			Msg("client request")
+	}
 
 	httpReqHeader := req.Request.Header.Clone()
 	httpReqHeader.Del("content-length")
@@ -100,6 +281,20 @@ func (c *Client) Do(ctx context.Context, req *Request) (Response, error) {
 	if req.Body != nil {
 		bodyReader = bytes.NewReader(req.Body)
 	}
+	// When the response is decoded while streaming, the
+	// body is closed and the timeout is released later.
+	keepOpen := false
+	cancel := func() {}
+	if timeout := requestTimeout(c.timeouts, req.Resource); timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+	}
+	defer func() {
+		if !keepOpen {
+			cancel()
+		}
+	}()
+
+	ctx, conn := withConnectionTrace(ctx, req.Backend.Host)
 	httpReq, err := http.NewRequestWithContext(
 		ctx,
 		req.Request.Method,
@@ -111,23 +306,82 @@ func (c *Client) Do(ctx context.Context, req *Request) (Response, error) {
 
 	// Set content type and other request headers
 	httpReq.Header = httpReqHeader
+	if c.serverName != "" {
+		httpReq.Host = c.serverName
+	}
 
 	// Perform request
+	t0 := time.Now()
 	httpRes, err := c.conn.Do(httpReq)
+	dt := time.Since(t0)
+	if req.Resource != ResourceCreate {
+		// Creating a meeting takes longer, depending
+		// on the presentation.
+		Latencies.Observe(req.Backend.Host, dt, t0)
+	}
+	if SlowRequestThreshold > 0 && dt > SlowRequestThreshold {
+		log.Warn().
+			Str("resource", req.Resource).
+			Str("backend", req.Backend.Host).
+			Dur("duration", dt).
+			Msg("slow backend request")
+	}
 	if err != nil {
 		return nil, err
 	}
+	conn.observe(req.Backend.Host, httpRes)
 
 	// Read body
-	defer httpRes.Body.Close()
-	data, err := ioutil.ReadAll(httpRes.Body)
-	if err != nil {
-		return nil, err
+	defer func() {
+		if !keepOpen {
+			httpRes.Body.Close()
+		}
+	}()
+	if MaxResponseSize > 0 && httpRes.ContentLength > MaxResponseSize {
+		return nil, ErrResponseTooLarge
 	}
+	body := limitResponseBody(httpRes.Body)
+	tracing := ActiveTracer != nil && ActiveTracer.Traces(req)
 
-	res, err := unmarshalRequestResponse(req, data)
-	if err != nil {
-		return nil, err
+	var res Response
+	decodeIncremental, ok := incrementalDecoders[req.Resource]
+	if ok && incremental && !tracing {
+		res, err = decodeIncremental(&responseBody{
+			Reader: body,
+			body:   httpRes.Body,
+			cancel: cancel,
+		})
+		if err != nil {
+			return nil, decodeError(httpRes, err)
+		}
+		keepOpen = true
+	} else if decode, ok := streamDecoders[req.Resource]; ok && !tracing {
+		res, err = decode(body)
+		if err != nil {
+			return nil, decodeError(httpRes, err)
+		}
+		// Drain the body, so the connection can be reused
+		if _, err := io.Copy(ioutil.Discard, body); err != nil {
+			return nil, err
+		}
+	} else {
+		data, err := ioutil.ReadAll(body)
+		if err != nil {
+			return nil, err
+		}
+
+		// Record the request for debugging
+		if tracing {
+			trace := NewTrace(req, httpRes.StatusCode, data, time.Since(t0))
+			if err := ActiveTracer.Record(trace); err != nil {
+				log.Error().Err(err).Msg("recording trace")
+			}
+		}
+
+		res, err = unmarshalRequestResponse(req, data)
+		if err != nil {
+			return nil, decodeError(httpRes, err)
+		}
 	}
 
 	// Set response header and status
@@ -0,0 +1,185 @@
+// Package client helps frontends written in Go with
+// building and signing requests to the BBB API of a
+// b3scale frontend, and with decoding the responses.
+//
+//	c := client.New(
+//		"https://b3scale.example.net/bbb/frontend1/bigbluebutton/api/",
+//		"secret")
+//	res, err := c.Create(ctx, bbb.Params{
+//		"meetingID": "meeting23",
+//		"name":      "Meeting 23",
+//	}, nil)
+//	url := c.JoinURL(bbb.Params{
+//		"meetingID": "meeting23",
+//		"fullName":  "Annika",
+//		"role":      "MODERATOR",
+//	})
+package client
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// A Client makes requests to the BBB API of a frontend
+type Client struct {
+	api  *bbb.Backend
+	conn *http.Client
+}
+
+// New creates a client for the BBB API endpoint of
+// a frontend, signing the requests with the secret.
+func New(endpoint, secret string) *Client {
+	return &Client{
+		api: &bbb.Backend{
+			Host:   endpoint,
+			Secret: secret,
+		},
+		conn: http.DefaultClient,
+	}
+}
+
+// WithHTTPClient uses the http client for the requests,
+// e.g. for configuring timeouts.
+func (c *Client) WithHTTPClient(conn *http.Client) *Client {
+	c.conn = conn
+	return c
+}
+
+// request builds a signed request for a resource
+func (c *Client) request(resource string, params bbb.Params) *bbb.Request {
+	return (&bbb.Request{
+		Resource: resource,
+		Params:   params,
+	}).WithBackend(c.api)
+}
+
+// URL builds the signed URL of a request to the resource
+func (c *Client) URL(resource string, params bbb.Params) string {
+	return c.request(resource, params).URL()
+}
+
+// JoinURL builds the signed URL for joining a meeting.
+// The URL is opened by the browser of the user.
+func (c *Client) JoinURL(params bbb.Params) string {
+	return c.URL(bbb.ResourceJoin, params)
+}
+
+// Do sends a request to the resource and decodes the
+// response. With a body, the request is a POST with
+// an XML body, like the presentations of a create.
+func (c *Client) Do(
+	ctx context.Context,
+	resource string,
+	params bbb.Params,
+	body []byte,
+) (bbb.Response, error) {
+	method := http.MethodGet
+	var bodyReader io.Reader
+	if body != nil {
+		method = http.MethodPost
+		bodyReader = bytes.NewReader(body)
+	}
+	httpReq, err := http.NewRequestWithContext(
+		ctx, method, c.URL(resource, params), bodyReader)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		httpReq.Header.Set("Content-Type", "application/xml")
+	}
+
+	httpRes, err := c.conn.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer httpRes.Body.Close()
+	data, err := ioutil.ReadAll(httpRes.Body)
+	if err != nil {
+		return nil, err
+	}
+	res, err := bbb.UnmarshalResponse(resource, data)
+	if err != nil {
+		return nil, err
+	}
+	res.SetHeader(httpRes.Header)
+	res.SetStatus(httpRes.StatusCode)
+	return res, nil
+}
+
+// Create creates a meeting. The body is optional.
+func (c *Client) Create(
+	ctx context.Context,
+	params bbb.Params,
+	body []byte,
+) (*bbb.CreateResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceCreate, params, body)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.CreateResponse), nil
+}
+
+// IsMeetingRunning checks if the meeting is running
+func (c *Client) IsMeetingRunning(
+	ctx context.Context,
+	params bbb.Params,
+) (*bbb.IsMeetingRunningResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceIsMeetingRunning, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.IsMeetingRunningResponse), nil
+}
+
+// GetMeetingInfo retrieves the meeting
+func (c *Client) GetMeetingInfo(
+	ctx context.Context,
+	params bbb.Params,
+) (*bbb.GetMeetingInfoResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceGetMeetingInfo, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.GetMeetingInfoResponse), nil
+}
+
+// GetMeetings retrieves the meetings of the frontend
+func (c *Client) GetMeetings(
+	ctx context.Context,
+) (*bbb.GetMeetingsResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceGetMeetings, bbb.Params{}, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.GetMeetingsResponse), nil
+}
+
+// End ends the meeting
+func (c *Client) End(
+	ctx context.Context,
+	params bbb.Params,
+) (*bbb.EndResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceEnd, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.EndResponse), nil
+}
+
+// GetRecordings retrieves the recordings
+func (c *Client) GetRecordings(
+	ctx context.Context,
+	params bbb.Params,
+) (*bbb.GetRecordingsResponse, error) {
+	res, err := c.Do(ctx, bbb.ResourceGetRecordings, params, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.GetRecordingsResponse), nil
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestJoinURL(t *testing.T) {
+	c := New("https://b3scale.example.net/bbb/frontend1/bigbluebutton/api", "secret")
+	joinURL := c.JoinURL(bbb.Params{
+		"meetingID": "meeting23",
+		"fullName":  "Jane Doe",
+	})
+	prefix := "https://b3scale.example.net/bbb/frontend1/bigbluebutton/api/join?" +
+		"fullName=Jane+Doe&meetingID=meeting23&checksum="
+	if !strings.HasPrefix(joinURL, prefix) {
+		t.Error("unexpected join url:", joinURL)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	data, err := ioutil.ReadFile(
+		"../../../testdata/responses/createSuccess.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			// The request is signed with the frontend secret
+			req := &bbb.Request{
+				Request:  r,
+				Resource: bbb.ResourceCreate,
+				Frontend: &bbb.Frontend{Secret: "secret"},
+			}
+			req.Checksum = r.URL.Query().Get("checksum")
+			if err := req.Verify(); err != nil {
+				t.Error(err)
+			}
+			w.Write(data)
+		}))
+	defer srv.Close()
+
+	c := New(srv.URL+"/bigbluebutton/api/", "secret")
+	res, err := c.Create(context.Background(), bbb.Params{
+		"meetingID": "meeting23",
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Returncode != bbb.RetSuccess {
+		t.Error("unexpected returncode:", res.Returncode)
+	}
+	if res.Status() != http.StatusOK {
+		t.Error("unexpected status:", res.Status())
+	}
+}
@@ -0,0 +1,160 @@
+package bbb
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newRecordingsServer(chunked bool) *httptest.Server {
+	data := readTestResponse("getRecordingsSuccess.xml")
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			if chunked {
+				// Flushing before writing the body
+				// omits the content length.
+				w.(http.Flusher).Flush()
+			}
+			w.Write(data)
+		}))
+}
+
+func newRecordingsRequest(host string) *Request {
+	return &Request{
+		Request: &http.Request{
+			Method: http.MethodGet,
+			Header: http.Header{},
+		},
+		Resource: ResourceGetRecordings,
+		Params:   Params{},
+		Backend: &Backend{
+			Host:   host + "/bigbluebutton/api/",
+			Secret: "secret",
+		},
+	}
+}
+
+func TestClientDoStreamsRecordings(t *testing.T) {
+	srv := newRecordingsServer(true)
+	defer srv.Close()
+
+	res, err := NewClient().Do(
+		context.Background(), newRecordingsRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordings, ok := res.(*GetRecordingsResponse)
+	if !ok {
+		t.Fatal("unexpected response:", res)
+	}
+	if len(recordings.Recordings) == 0 {
+		t.Error("expected recordings")
+	}
+	if res.Status() != http.StatusOK {
+		t.Error("unexpected status:", res.Status())
+	}
+}
+
+func TestClientStreamRecordings(t *testing.T) {
+	srv := newRecordingsServer(true)
+	defer srv.Close()
+
+	// The timeout must not end the request
+	// before the response is streamed.
+	client := NewClientWithTimeouts(Timeouts{
+		ResourceGetRecordings: time.Minute,
+	})
+	res, err := client.Stream(
+		context.Background(), newRecordingsRequest(srv.URL))
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordings, ok := res.(*GetRecordingsResponse)
+	if !ok {
+		t.Fatal("unexpected response:", res)
+	}
+	if len(recordings.Recordings) != 0 {
+		t.Error("recordings should be decoded while streaming")
+	}
+	if recordings.Returncode != RetSuccess {
+		t.Error("unexpected returncode:", recordings.Returncode)
+	}
+
+	rec := httptest.NewRecorder()
+	if err := recordings.Stream(rec); err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := UnmarshalGetRecordingsResponse(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed.Recordings) == 0 {
+		t.Error("expected recordings")
+	}
+}
+
+func TestClientDoMaxResponseSize(t *testing.T) {
+	defer func() { MaxResponseSize = 0 }()
+	MaxResponseSize = 512
+
+	for _, chunked := range []bool{false, true} {
+		srv := newRecordingsServer(chunked)
+		_, err := NewClient().Do(
+			context.Background(), newRecordingsRequest(srv.URL))
+		srv.Close()
+		if !errors.Is(err, ErrResponseTooLarge) {
+			t.Error("expected response too large, chunked:", chunked, err)
+		}
+	}
+
+	MaxResponseSize = 1 << 20
+	srv := newRecordingsServer(true)
+	defer srv.Close()
+	if _, err := NewClient().Do(
+		context.Background(), newRecordingsRequest(srv.URL),
+	); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	MaxResponseSize = 4
+	defer func() { MaxResponseSize = 0 }()
+
+	body := limitResponseBody(strings.NewReader("1234"))
+	if data, err := ioutil.ReadAll(body); err != nil || string(data) != "1234" {
+		t.Error("unexpected read:", string(data), err)
+	}
+	body = limitResponseBody(strings.NewReader("12345"))
+	if _, err := ioutil.ReadAll(body); err != ErrResponseTooLarge {
+		t.Error("expected response too large:", err)
+	}
+}
+
+func TestClientDoOverloaded(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/html")
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte("<html>503 Service Unavailable</html>"))
+		}))
+	defer srv.Close()
+
+	req := newRecordingsRequest(srv.URL)
+	req.Resource = ResourceCreate
+	_, err := NewClient().Do(context.Background(), req)
+	var overloadErr *OverloadError
+	if !errors.As(err, &overloadErr) {
+		t.Fatal("expected an overload error:", err)
+	}
+	if overloadErr.RetryAfter != 3*time.Second {
+		t.Error("unexpected retry after:", overloadErr.RetryAfter)
+	}
+}
@@ -0,0 +1,205 @@
+package bbb
+
+import (
+	"strconv"
+	"strings"
+)
+
+// ParamDisabledFeatures is the create parameter
+// replacing the feature flags since BBB 2.5.
+const ParamDisabledFeatures = "disabledFeatures"
+
+// A Version of BBB, e.g. 2.6.10
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// ParseVersion decodes a version like 2.6.10 or 2.7.0-beta.1.
+// Missing minor and patch versions are zero.
+func ParseVersion(s string) (Version, bool) {
+	v := Version{}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	if i := strings.IndexAny(s, "-+ "); i >= 0 {
+		s = s[:i]
+	}
+	if s == "" {
+		return v, false
+	}
+	tokens := strings.SplitN(s, ".", 3)
+	parts := []*int{&v.Major, &v.Minor, &v.Patch}
+	for i, t := range tokens {
+		n, err := strconv.Atoi(t)
+		if err != nil || n < 0 {
+			return v, false
+		}
+		*parts[i] = n
+	}
+	return v, true
+}
+
+// Before checks if the version is older
+func (v Version) Before(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// sendChatMessageSince is the version introducing
+// the sendChatMessage resource.
+var sendChatMessageSince = Version{2, 7, 0}
+
+// SupportsSendChatMessage checks if messages can be posted
+// to the chat of a meeting through the API. Unknown
+// versions are not supported.
+func SupportsSendChatMessage(version string) bool {
+	v, ok := ParseVersion(version)
+	return ok && !v.Before(sendChatMessageSince)
+}
+
+// A paramRename is a parameter with a new name
+// since a version.
+type paramRename struct {
+	resource string
+	legacy   string
+	modern   string
+	since    Version
+}
+
+// A featureFlag is a parameter replaced by an entry
+// in the disabledFeatures since a version.
+type featureFlag struct {
+	resource string
+	legacy   string
+	disabled string // Value of the flag disabling the feature
+	feature  string
+	since    Version
+}
+
+// Deprecated parameters and their replacements
+var (
+	paramRenames = []*paramRename{
+		{
+			resource: ResourceCreate,
+			legacy:   "keepEvents",
+			modern:   "meetingKeepEvents",
+			since:    Version{2, 3, 0},
+		},
+		{
+			resource: ResourceCreate,
+			legacy:   "lockSettingsDisableNote",
+			modern:   "lockSettingsDisableNotes",
+			since:    Version{2, 6, 0},
+		},
+	}
+
+	featureFlags = []*featureFlag{
+		{
+			resource: ResourceCreate,
+			legacy:   "learningDashboardEnabled",
+			disabled: "false",
+			feature:  "learningDashboard",
+			since:    Version{2, 5, 0},
+		},
+		{
+			resource: ResourceCreate,
+			legacy:   "breakoutRoomsEnabled",
+			disabled: "false",
+			feature:  "breakoutRooms",
+			since:    Version{2, 5, 0},
+		},
+		{
+			resource: ResourceCreate,
+			legacy:   "virtualBackgroundsDisabled",
+			disabled: "true",
+			feature:  "virtualBackgrounds",
+			since:    Version{2, 5, 0},
+		},
+	}
+)
+
+// disabledFeatures decodes the list of disabled features
+func (p Params) disabledFeatures() []string {
+	features := []string{}
+	for _, f := range strings.Split(p[ParamDisabledFeatures], ",") {
+		if f = strings.TrimSpace(f); f != "" {
+			features = append(features, f)
+		}
+	}
+	return features
+}
+
+// disableFeature adds a feature to the disabled features
+func (p Params) disableFeature(feature string) {
+	features := p.disabledFeatures()
+	for _, f := range features {
+		if f == feature {
+			return
+		}
+	}
+	p[ParamDisabledFeatures] = strings.Join(
+		append(features, feature), ",")
+}
+
+// featureDisabled checks if the feature is disabled
+func (p Params) featureDisabled(feature string) bool {
+	for _, f := range p.disabledFeatures() {
+		if f == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// AdaptParams rewrites the parameters of a request for
+// the version of the backend: Deprecated parameters are
+// replaced for newer backends, and new parameters are
+// translated to their predecessors for older backends.
+// The parameters are not modified if the version is unknown.
+func AdaptParams(resource string, params Params, version string) {
+	v, ok := ParseVersion(version)
+	if !ok {
+		return
+	}
+	for _, r := range paramRenames {
+		if r.resource != resource {
+			continue
+		}
+		from, to := r.legacy, r.modern
+		if v.Before(r.since) {
+			from, to = r.modern, r.legacy
+		}
+		value, ok := params[from]
+		if !ok {
+			continue
+		}
+		if _, ok := params[to]; !ok {
+			params[to] = value
+		}
+		delete(params, from)
+	}
+	for _, f := range featureFlags {
+		if f.resource != resource {
+			continue
+		}
+		if v.Before(f.since) {
+			if _, ok := params[f.legacy]; !ok && params.featureDisabled(f.feature) {
+				params[f.legacy] = f.disabled
+			}
+			continue
+		}
+		value, ok := params[f.legacy]
+		if !ok {
+			continue
+		}
+		if value == f.disabled {
+			params.disableFeature(f.feature)
+		}
+		delete(params, f.legacy)
+	}
+}
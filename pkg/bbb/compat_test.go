@@ -0,0 +1,105 @@
+package bbb
+
+import (
+	"testing"
+)
+
+func TestParseVersion(t *testing.T) {
+	v, ok := ParseVersion("2.6.10")
+	if !ok || v != (Version{2, 6, 10}) {
+		t.Error("unexpected version:", v, ok)
+	}
+	v, ok = ParseVersion("2.7.0-beta.1")
+	if !ok || v != (Version{2, 7, 0}) {
+		t.Error("unexpected version:", v, ok)
+	}
+	v, ok = ParseVersion("2.4")
+	if !ok || v != (Version{2, 4, 0}) {
+		t.Error("unexpected version:", v, ok)
+	}
+	if _, ok := ParseVersion(""); ok {
+		t.Error("empty version should be invalid")
+	}
+	if _, ok := ParseVersion("two"); ok {
+		t.Error("version should be invalid")
+	}
+}
+
+func TestVersionBefore(t *testing.T) {
+	if !(Version{2, 4, 9}).Before(Version{2, 5, 0}) {
+		t.Error("2.4.9 should be before 2.5.0")
+	}
+	if (Version{2, 5, 0}).Before(Version{2, 5, 0}) {
+		t.Error("2.5.0 should not be before itself")
+	}
+}
+
+func TestSupportsSendChatMessage(t *testing.T) {
+	if !SupportsSendChatMessage("2.7.3") {
+		t.Error("2.7.3 should support sendChatMessage")
+	}
+	if SupportsSendChatMessage("2.6.10") {
+		t.Error("2.6.10 should not support sendChatMessage")
+	}
+	if SupportsSendChatMessage("") {
+		t.Error("unknown versions should not be supported")
+	}
+}
+
+func TestAdaptParamsModern(t *testing.T) {
+	params := Params{
+		"keepEvents":               "true",
+		"learningDashboardEnabled": "false",
+		"breakoutRoomsEnabled":     "true",
+		"disabledFeatures":         "chat",
+	}
+	AdaptParams(ResourceCreate, params, "2.6.10")
+	if params["meetingKeepEvents"] != "true" {
+		t.Error("keepEvents should be renamed:", params)
+	}
+	if _, ok := params["keepEvents"]; ok {
+		t.Error("keepEvents should be removed:", params)
+	}
+	if params["disabledFeatures"] != "chat,learningDashboard" {
+		t.Error("unexpected disabled features:", params["disabledFeatures"])
+	}
+	if _, ok := params["breakoutRoomsEnabled"]; ok {
+		t.Error("feature flag should be removed:", params)
+	}
+}
+
+func TestAdaptParamsLegacy(t *testing.T) {
+	params := Params{
+		"meetingKeepEvents":        "true",
+		"lockSettingsDisableNotes": "true",
+		"disabledFeatures":         "breakoutRooms,virtualBackgrounds",
+	}
+	AdaptParams(ResourceCreate, params, "2.4.3")
+	if params["meetingKeepEvents"] != "true" {
+		t.Error("meetingKeepEvents should be kept:", params)
+	}
+	if params["lockSettingsDisableNote"] != "true" {
+		t.Error("lockSettingsDisableNotes should be renamed:", params)
+	}
+	if params["breakoutRoomsEnabled"] != "false" {
+		t.Error("breakout rooms should be disabled:", params)
+	}
+	if params["virtualBackgroundsDisabled"] != "true" {
+		t.Error("virtual backgrounds should be disabled:", params)
+	}
+
+	// Pre 2.3: The new name is translated
+	params = Params{"meetingKeepEvents": "true"}
+	AdaptParams(ResourceCreate, params, "2.2.31")
+	if params["keepEvents"] != "true" {
+		t.Error("unexpected params:", params)
+	}
+}
+
+func TestAdaptParamsUnknownVersion(t *testing.T) {
+	params := Params{"keepEvents": "true"}
+	AdaptParams(ResourceCreate, params, "")
+	if params["keepEvents"] != "true" || len(params) != 1 {
+		t.Error("params should not be modified:", params)
+	}
+}
@@ -0,0 +1,226 @@
+package bbb
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Errors
+var (
+	ErrClientKeyRequired = errors.New(
+		"client certificate and key are required together")
+	ErrConnectAddressPinned = errors.New(
+		"a connect address can not be used with pinned addresses")
+)
+
+// ConnOptions configure the connections to a backend.
+// Backends with the same options share a http client,
+// so connections are reused.
+type ConnOptions struct {
+	// ClientCert and ClientKey are the paths of the
+	// PEM encoded client certificate and key presented
+	// to the backend (mTLS). The files are reloaded
+	// when they change.
+	ClientCert string
+	ClientKey  string
+
+	// RootCA is the path of the PEM encoded CA
+	// certificates verifying the backend, instead
+	// of the system roots.
+	RootCA string
+
+	// Proxy is the URL of the proxy for connecting to
+	// the backend or ProxyDirect. If empty, the global
+	// proxy configuration is used.
+	Proxy string
+
+	// AddressFamily is the preferred address family
+	// of the connections, PreferIPv4 or PreferIPv6.
+	AddressFamily string
+
+	// Addresses are the IP addresses of the backend Host.
+	// The host is not resolved if addresses are pinned.
+	Host      string
+	Addresses []string
+
+	// ConnectAddress is the host or host:port the
+	// backend Host is connected to, e.g. an internal
+	// address. The Host header and SNI are unchanged.
+	ConnectAddress string
+
+	// ServerName overrides the Host header and the SNI,
+	// e.g. if the backend is registered with an internal
+	// address, but the certificate has a public name.
+	ServerName string
+}
+
+// IsZero is true if no option is set. The host
+// only matters if addresses are pinned.
+func (o *ConnOptions) IsZero() bool {
+	return o == nil || o.key() == (&ConnOptions{}).key()
+}
+
+// key identifies the options
+func (o *ConnOptions) key() string {
+	return strings.Join([]string{
+		o.ClientCert, o.ClientKey, o.RootCA, o.Proxy,
+		o.AddressFamily, o.pinnedHost(),
+		strings.Join(o.Addresses, ","),
+		o.ConnectAddress, o.ServerName,
+	}, "\x00")
+}
+
+// pinnedHost is the host if addresses are pinned or the
+// connect address is set, so backends without pinned
+// addresses share connections.
+func (o *ConnOptions) pinnedHost() string {
+	if len(o.Addresses) == 0 && o.ConnectAddress == "" {
+		return ""
+	}
+	return o.Host
+}
+
+// tlsConfig creates the TLS configuration of the options
+func (o *ConnOptions) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{
+		ServerName: o.ServerName,
+	}
+	if o.RootCA != "" {
+		pool, err := loadCertPool(o.RootCA)
+		if err != nil {
+			return nil, err
+		}
+		cfg.RootCAs = pool
+	}
+	if o.ClientCert != "" || o.ClientKey != "" {
+		if o.ClientCert == "" || o.ClientKey == "" {
+			return nil, ErrClientKeyRequired
+		}
+		loader := &clientCertLoader{
+			certFile: o.ClientCert,
+			keyFile:  o.ClientKey,
+		}
+		cfg.GetClientCertificate = loader.GetClientCertificate
+	}
+	return cfg, nil
+}
+
+// dialer creates the backend dialer of the options
+func (o *ConnOptions) dialer() (*backendDialer, error) {
+	if err := ValidateAddressFamily(o.AddressFamily); err != nil {
+		return nil, err
+	}
+	d := newBackendDialer()
+	d.prefer = o.AddressFamily
+	if len(o.Addresses) > 0 {
+		pinned, err := ParseAddresses(o.Addresses)
+		if err != nil {
+			return nil, err
+		}
+		d.host = o.Host
+		d.pinned = d.sortByPreference(pinned)
+	}
+	if o.ConnectAddress != "" {
+		if len(o.Addresses) > 0 {
+			return nil, ErrConnectAddressPinned
+		}
+		if err := ValidateConnectAddress(o.ConnectAddress); err != nil {
+			return nil, err
+		}
+		d.host = o.Host
+		d.connect = o.ConnectAddress
+	}
+	return d, nil
+}
+
+// loadCertPool reads PEM encoded certificates
+func loadCertPool(filename string) (*x509.CertPool, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no certificates found in %s", filename)
+	}
+	return pool, nil
+}
+
+// optionConns are the http clients by connection options
+var optionConns sync.Map
+
+// connFor retrieves the http client for the options
+func connFor(opts *ConnOptions) (*http.Client, error) {
+	if opts.IsZero() {
+		return sharedConn, nil
+	}
+	key := opts.key()
+	if conn, ok := optionConns.Load(key); ok {
+		return conn.(*http.Client), nil
+	}
+	cfg, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	proxy, err := proxyFor(opts)
+	if err != nil {
+		return nil, err
+	}
+	dialer, err := opts.dialer()
+	if err != nil {
+		return nil, err
+	}
+	conn, _ := optionConns.LoadOrStore(
+		key, newBackendConn(cfg, proxy, dialer))
+	return conn.(*http.Client), nil
+}
+
+// clientCertLoader provides the client certificate and
+// reloads the files when they were modified, e.g. after
+// the certificate was renewed.
+type clientCertLoader struct {
+	certFile string
+	keyFile  string
+
+	mtx     sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+}
+
+// GetClientCertificate implements the tls.Config callback
+func (l *clientCertLoader) GetClientCertificate(
+	*tls.CertificateRequestInfo,
+) (*tls.Certificate, error) {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+
+	modTime := l.modTime
+	for _, f := range []string{l.certFile, l.keyFile} {
+		info, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().After(modTime) {
+			modTime = info.ModTime()
+		}
+	}
+	if l.cert != nil && !modTime.After(l.modTime) {
+		return l.cert, nil
+	}
+
+	cert, err := tls.LoadX509KeyPair(l.certFile, l.keyFile)
+	if err != nil {
+		return nil, err
+	}
+	l.cert = &cert
+	l.modTime = modTime
+	return l.cert, nil
+}
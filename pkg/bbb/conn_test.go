@@ -0,0 +1,127 @@
+package bbb
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeTestClientCert creates a self signed client
+// certificate and key in dir.
+func writeTestClientCert(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "b3scale"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "client.pem")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(
+		&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(
+		&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestConnForMutualTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<response></response>"))
+		}))
+	srv.TLS = &tls.Config{ClientAuth: tls.RequireAnyClientCert}
+	srv.StartTLS()
+	defer srv.Close()
+
+	dir := t.TempDir()
+	certFile, keyFile := writeTestClientCert(t, dir)
+	caFile := filepath.Join(dir, "ca.pem")
+	if err := ioutil.WriteFile(caFile, pem.EncodeToMemory(&pem.Block{
+		Type:  "CERTIFICATE",
+		Bytes: srv.Certificate().Raw,
+	}), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Without a client certificate the
+	// backend rejects the connection
+	conn, err := connFor(&ConnOptions{RootCA: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Get(srv.URL); err == nil {
+		t.Error("expected the connection to be rejected")
+	}
+
+	opts := &ConnOptions{
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+		RootCA:     caFile,
+	}
+	conn, err = connFor(opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := conn.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+
+	// The client is shared by backends with equal options
+	other, err := connFor(&ConnOptions{
+		ClientCert: certFile,
+		ClientKey:  keyFile,
+		RootCA:     caFile,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other != conn {
+		t.Error("expected the client to be shared")
+	}
+}
+
+func TestConnForInvalidOptions(t *testing.T) {
+	if conn, _ := connFor(&ConnOptions{}); conn != sharedConn {
+		t.Error("expected the shared client without options")
+	}
+	opts := &ConnOptions{Host: "bbb01.example.net"}
+	if conn, _ := connFor(opts); conn != sharedConn {
+		t.Error("expected the shared client without pinned addresses")
+	}
+	if _, err := connFor(&ConnOptions{ClientCert: "/cert.pem"}); err == nil {
+		t.Error("expected an error without a client key")
+	}
+	if _, err := connFor(&ConnOptions{RootCA: "/does/not/exist"}); err == nil {
+		t.Error("expected an error for a missing CA")
+	}
+}
@@ -0,0 +1,320 @@
+package bbb
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Address families preferred when connecting to a
+// backend. Without a preference the addresses are
+// tried as sorted by the resolver (RFC 6724), falling
+// back to the other family (happy eyeballs).
+const (
+	PreferIPv4 = "ipv4"
+	PreferIPv6 = "ipv6"
+)
+
+// ValidateAddressFamily checks the preferred address family
+func ValidateAddressFamily(family string) error {
+	switch family {
+	case "", PreferIPv4, PreferIPv6:
+		return nil
+	}
+	return fmt.Errorf("should be %s or %s: %s", PreferIPv4, PreferIPv6, family)
+}
+
+// ParseAddresses parses the pinned IP addresses of a backend
+func ParseAddresses(addrs []string) ([]net.IP, error) {
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ip := net.ParseIP(addr)
+		if ip == nil {
+			return nil, fmt.Errorf("invalid IP address: %s", addr)
+		}
+		ips = append(ips, ip)
+	}
+	return ips, nil
+}
+
+// ValidateConnectAddress checks a connect address,
+// which is a host or host:port.
+func ValidateConnectAddress(addr string) error {
+	host, port, err := net.SplitHostPort(connectTarget(addr, "0"))
+	if err != nil {
+		return err
+	}
+	if host == "" {
+		return fmt.Errorf("host is missing: %s", addr)
+	}
+	if _, err := strconv.ParseUint(port, 10, 16); err != nil {
+		return fmt.Errorf("invalid port: %s", port)
+	}
+	return nil
+}
+
+// connectTarget adds the port to the connect
+// address if it has none.
+func connectTarget(addr, port string) string {
+	if _, _, err := net.SplitHostPort(addr); err == nil {
+		return addr
+	}
+	host := strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+	return net.JoinHostPort(host, port)
+}
+
+// dialers are the backend dialers with connections
+// to hosts, which are re-resolved by RefreshDNS.
+var (
+	dialers    []*backendDialer
+	dialersMtx sync.Mutex
+)
+
+// backendDialer connects to backends
+type backendDialer struct {
+	dialer *net.Dialer
+	prefer string
+
+	// The addresses of the host are pinned or
+	// the host is connected to another address
+	host    string
+	pinned  []net.IP
+	connect string
+
+	// Connections to resolved hosts
+	mtx       sync.Mutex
+	conns     map[*trackedConn]struct{}
+	register  sync.Once
+	closeIdle func()
+}
+
+// newBackendDialer creates a dialer
+func newBackendDialer() *backendDialer {
+	return &backendDialer{
+		dialer: &net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		},
+		conns: map[*trackedConn]struct{}{},
+	}
+}
+
+// DialContext connects to the address. Addresses of the
+// preferred family are tried first, the other family is
+// used if no connection could be established.
+func (d *backendDialer) DialContext(
+	ctx context.Context,
+	network, addr string,
+) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	if d.pinned != nil && host == d.host {
+		return d.dialPinned(ctx, network, port)
+	}
+	if d.connect != "" && host == d.host {
+		addr = connectTarget(d.connect, port)
+		host, _, _ = net.SplitHostPort(addr)
+	}
+	conn, err := d.dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	if net.ParseIP(host) != nil {
+		return conn, nil // Nothing to resolve
+	}
+	return d.track(host, conn), nil
+}
+
+// dial connects to the address with the preferred
+// address family first
+func (d *backendDialer) dial(
+	ctx context.Context,
+	network, addr string,
+) (net.Conn, error) {
+	if network != "tcp" || d.prefer == "" {
+		return d.dialer.DialContext(ctx, network, addr)
+	}
+	primary, fallback := "tcp4", "tcp6"
+	if d.prefer == PreferIPv6 {
+		primary, fallback = "tcp6", "tcp4"
+	}
+	conn, err := d.dialer.DialContext(ctx, primary, addr)
+	if err == nil || ctx.Err() != nil {
+		return conn, err
+	}
+	conn, ferr := d.dialer.DialContext(ctx, fallback, addr)
+	if ferr != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// dialPinned connects to the first reachable
+// pinned address without resolving the host.
+func (d *backendDialer) dialPinned(
+	ctx context.Context,
+	network, port string,
+) (net.Conn, error) {
+	var err error
+	for _, ip := range d.pinned {
+		var conn net.Conn
+		conn, err = d.dialer.DialContext(
+			ctx, network, net.JoinHostPort(ip.String(), port))
+		if err == nil || ctx.Err() != nil {
+			return conn, err
+		}
+	}
+	return nil, err
+}
+
+// sortByPreference moves the addresses of the
+// preferred family to the front.
+func (d *backendDialer) sortByPreference(ips []net.IP) []net.IP {
+	if d.prefer == "" {
+		return ips
+	}
+	preferred := make([]net.IP, 0, len(ips))
+	other := make([]net.IP, 0, len(ips))
+	for _, ip := range ips {
+		isIPv4 := ip.To4() != nil
+		if isIPv4 == (d.prefer == PreferIPv4) {
+			preferred = append(preferred, ip)
+		} else {
+			other = append(other, ip)
+		}
+	}
+	return append(preferred, other...)
+}
+
+// track remembers the connection to a resolved host
+func (d *backendDialer) track(host string, conn net.Conn) net.Conn {
+	addr, ok := conn.RemoteAddr().(*net.TCPAddr)
+	if !ok {
+		return conn
+	}
+	d.register.Do(func() {
+		dialersMtx.Lock()
+		dialers = append(dialers, d)
+		dialersMtx.Unlock()
+	})
+	c := &trackedConn{
+		Conn:   conn,
+		host:   host,
+		ip:     addr.IP,
+		dialer: d,
+	}
+	d.mtx.Lock()
+	d.conns[c] = struct{}{}
+	d.mtx.Unlock()
+	return c
+}
+
+// refresh resolves the hosts of the connections. Idle
+// connections to addresses no longer in the DNS are
+// closed, active connections are closed in the next
+// refresh, when the requests should be completed.
+func (d *backendDialer) refresh(ctx context.Context) {
+	hosts := map[string][]*trackedConn{}
+	d.mtx.Lock()
+	for c := range d.conns {
+		hosts[c.host] = append(hosts[c.host], c)
+	}
+	d.mtx.Unlock()
+
+	closeIdle := false
+	for host, conns := range hosts {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			// Keep the connections, DNS could be unavailable
+			log.Warn().
+				Err(err).
+				Str("host", host).
+				Msg("could not resolve backend host")
+			continue
+		}
+		for _, c := range conns {
+			if containsIP(addrs, c.ip) {
+				c.stale = false
+				continue
+			}
+			if c.stale {
+				c.Close()
+				continue
+			}
+			log.Info().
+				Str("host", host).
+				Str("addr", c.ip.String()).
+				Msg("backend host address changed, closing connection")
+			c.stale = true
+			closeIdle = true
+		}
+	}
+	if closeIdle && d.closeIdle != nil {
+		d.closeIdle()
+	}
+}
+
+// containsIP checks if the ip is in the addresses
+func containsIP(addrs []net.IPAddr, ip net.IP) bool {
+	for _, addr := range addrs {
+		if addr.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// trackedConn is a connection to a resolved host
+type trackedConn struct {
+	net.Conn
+	host   string
+	ip     net.IP
+	dialer *backendDialer
+
+	// stale is only accessed by the refresh
+	stale bool
+}
+
+// Close the connection and stop tracking
+func (c *trackedConn) Close() error {
+	c.dialer.mtx.Lock()
+	delete(c.dialer.conns, c)
+	c.dialer.mtx.Unlock()
+	return c.Conn.Close()
+}
+
+// RefreshDNS re-resolves the hosts of the backend
+// connections, so a changed address is picked up
+// without waiting for the connection to be closed.
+func RefreshDNS(ctx context.Context) {
+	dialersMtx.Lock()
+	refresh := make([]*backendDialer, len(dialers))
+	copy(refresh, dialers)
+	dialersMtx.Unlock()
+
+	for _, d := range refresh {
+		d.refresh(ctx)
+	}
+}
+
+// StartDNSRefresh re-resolves the backend hosts
+// in the interval.
+func StartDNSRefresh(interval time.Duration) {
+	log.Info().
+		Dur("interval", interval).
+		Msg("starting backend dns refresh")
+	for {
+		time.Sleep(interval)
+		ctx, cancel := context.WithTimeout(context.Background(), interval)
+		RefreshDNS(ctx)
+		cancel()
+	}
+}
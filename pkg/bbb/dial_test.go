@@ -0,0 +1,175 @@
+package bbb
+
+import (
+	"context"
+	"net"
+	"testing"
+)
+
+func TestBackendDialerFallback(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	// The backend is only reachable with IPv4
+	for _, family := range []string{"", PreferIPv4, PreferIPv6} {
+		d := newBackendDialer()
+		d.prefer = family
+		conn, err := d.DialContext(
+			context.Background(), "tcp", net.JoinHostPort("127.0.0.1", port))
+		if err != nil {
+			t.Error("unexpected error with preference", family, err)
+			continue
+		}
+		conn.Close()
+	}
+}
+
+func TestBackendDialerPinned(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+
+	d, err := (&ConnOptions{
+		Host:      "bbb01.example.net",
+		Addresses: []string{"127.0.0.1"},
+	}).dialer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := d.DialContext(
+		context.Background(), "tcp", "bbb01.example.net:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if _, err := (&ConnOptions{
+		Addresses: []string{"bbb01.example.net"},
+	}).dialer(); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+}
+
+func TestBackendDialerConnectAddress(t *testing.T) {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		conn, err := l.Accept()
+		if err == nil {
+			conn.Close()
+		}
+	}()
+
+	// The port of the connect address is used
+	d, err := (&ConnOptions{
+		Host:           "bbb01.example.net",
+		ConnectAddress: l.Addr().String(),
+	}).dialer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := d.DialContext(
+		context.Background(), "tcp", "bbb01.example.net:443")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+}
+
+func TestConnectTarget(t *testing.T) {
+	tests := map[string]string{
+		"10.0.0.23":         "10.0.0.23:443",
+		"10.0.0.23:8443":    "10.0.0.23:8443",
+		"bbb01.internal":    "bbb01.internal:443",
+		"2001:db8::23":      "[2001:db8::23]:443",
+		"[2001:db8::23]":    "[2001:db8::23]:443",
+		"[2001:db8::23]:80": "[2001:db8::23]:80",
+	}
+	for addr, expected := range tests {
+		if target := connectTarget(addr, "443"); target != expected {
+			t.Error("unexpected target for", addr, ":", target)
+		}
+	}
+	for _, addr := range []string{":443", "10.0.0.23:http"} {
+		if err := ValidateConnectAddress(addr); err == nil {
+			t.Error("expected an error for", addr)
+		}
+	}
+}
+
+func TestBackendDialerRefresh(t *testing.T) {
+	closed := 0
+	d := newBackendDialer()
+	d.closeIdle = func() { closed++ }
+
+	// The connected address is not an address of the host
+	local, remote := net.Pipe()
+	defer remote.Close()
+	c := &trackedConn{
+		Conn:   local,
+		host:   "localhost",
+		ip:     net.ParseIP("192.0.2.1"),
+		dialer: d,
+	}
+	d.conns[c] = struct{}{}
+
+	d.refresh(context.Background())
+	if !c.stale {
+		t.Fatal("expected the connection to be stale")
+	}
+	if closed != 1 {
+		t.Error("expected idle connections to be closed")
+	}
+	d.refresh(context.Background())
+	if len(d.conns) != 0 {
+		t.Error("expected the stale connection to be closed")
+	}
+}
+
+func TestSortByPreference(t *testing.T) {
+	d := newBackendDialer()
+	d.prefer = PreferIPv6
+	ips := d.sortByPreference([]net.IP{
+		net.ParseIP("192.0.2.1"),
+		net.ParseIP("2001:db8::1"),
+	})
+	if ips[0].String() != "2001:db8::1" {
+		t.Error("expected IPv6 address first:", ips)
+	}
+}
+
+func TestValidateAddressFamily(t *testing.T) {
+	for _, family := range []string{"", PreferIPv4, PreferIPv6} {
+		if err := ValidateAddressFamily(family); err != nil {
+			t.Error(err)
+		}
+	}
+	if err := ValidateAddressFamily("ipv5"); err == nil {
+		t.Error("expected an error")
+	}
+}
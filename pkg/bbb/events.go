@@ -48,3 +48,52 @@ type BreakoutInfo struct {
 	Sequence   int
 	FreeJoin   bool
 }
+
+// UserRoleChangedEvent indicates that a user was
+// promoted or demoted
+type UserRoleChangedEvent struct {
+	InternalMeetingID string
+	InternalUserID    string
+	Role              string
+	ChangedBy         string
+}
+
+// ScreenshareStartedEvent indicates the start of a
+// screenshare in the meeting
+type ScreenshareStartedEvent struct {
+	InternalMeetingID string
+	Stream            string
+}
+
+// ScreenshareStoppedEvent indicates the end of a
+// screenshare in the meeting
+type ScreenshareStoppedEvent struct {
+	InternalMeetingID string
+	Stream            string
+}
+
+// PadCreatedEvent indicates that a shared notes
+// pad was created
+type PadCreatedEvent struct {
+	InternalMeetingID string
+	GroupID           string
+	PadID             string
+	Name              string
+}
+
+// PadUpdatedEvent indicates that a pad was edited
+type PadUpdatedEvent struct {
+	InternalMeetingID string
+	GroupID           string
+	PadID             string
+	InternalUserID    string
+}
+
+// RecordingReadyEvent indicates that the recording
+// processing of a meeting was published (rap-publish-ended)
+type RecordingReadyEvent struct {
+	MeetingID         string
+	InternalMeetingID string
+	Workflow          string
+	Success           bool
+}
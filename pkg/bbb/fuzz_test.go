@@ -0,0 +1,89 @@
+//go:build go1.18
+// +build go1.18
+
+package bbb
+
+import (
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"testing"
+)
+
+// fuzzResources are all resources with a response decoder
+var fuzzResources = []string{
+	ResourceJoin,
+	ResourceCreate,
+	ResourceIsMeetingRunning,
+	ResourceEnd,
+	ResourceGetMeetingInfo,
+	ResourceGetMeetings,
+	ResourceGetRecordings,
+	ResourcePublishRecordings,
+	ResourceDeleteRecordings,
+	ResourceUpdateRecordings,
+	ResourceGetDefaultConfigXML,
+	ResourceSetConfigXML,
+	ResourceGetRecordingTextTracks,
+	ResourcePutRecordingTextTrack,
+	ResourceSendChatMessage,
+}
+
+// FuzzUnmarshalResponse feeds backend responses into
+// every response decoder. Decoded responses are handled
+// like the gateway would: headers and status are set,
+// the response is merged and encoded again.
+func FuzzUnmarshalResponse(f *testing.F) {
+	files, err := filepath.Glob("../../testdata/responses/*")
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, filename := range files {
+		data, err := ioutil.ReadFile(filename)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+	f.Add([]byte("<response></response>"))
+	f.Add([]byte(`{"response": null}`))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		for _, resource := range fuzzResources {
+			res, err := UnmarshalResponse(resource, data)
+			if err != nil {
+				continue
+			}
+			res.SetHeader(res.Header())
+			res.SetStatus(res.Status())
+			other, err := UnmarshalResponse(resource, data)
+			if err != nil {
+				t.Fatal("second decode failed:", err)
+			}
+			res.Merge(other)
+			res.Marshal()
+		}
+	})
+}
+
+// FuzzUnmarshalURLSafeRequest checks the decoder for
+// requests passed through URLs, e.g. when retrying a join.
+func FuzzUnmarshalURLSafeRequest(f *testing.F) {
+	req := JoinRequest(Params{
+		"meetingID": "meeting42",
+		"fullName":  "Jane",
+	})
+	req.Request.URL, _ = url.Parse("/bbb/frontend/join?foo=42")
+	f.Add(req.MarshalURLSafe())
+	f.Add([]byte("eyJtdGgiOiI0In0")) // {"mth":"4"}
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		req, err := UnmarshalURLSafeRequest(data)
+		if err != nil {
+			return
+		}
+		if _, err := UnmarshalURLSafeRequest(req.MarshalURLSafe()); err != nil {
+			t.Fatal("decoding the encoded request failed:", err)
+		}
+	})
+}
@@ -0,0 +1,99 @@
+package bbb
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// latencySample is a measured request duration
+type latencySample struct {
+	at       time.Time
+	duration time.Duration
+}
+
+// latencyWindow holds the most recent samples
+// of a backend in a ring buffer.
+type latencyWindow struct {
+	samples []latencySample
+	next    int
+}
+
+// The LatencyTracker keeps the recent request
+// durations of the backends, so slow backends
+// can be identified.
+type LatencyTracker struct {
+	mtx     sync.Mutex
+	size    int
+	maxAge  time.Duration
+	windows map[string]*latencyWindow
+}
+
+// NewLatencyTracker creates a tracker keeping up
+// to size samples per backend, not older than maxAge.
+func NewLatencyTracker(size int, maxAge time.Duration) *LatencyTracker {
+	return &LatencyTracker{
+		size:    size,
+		maxAge:  maxAge,
+		windows: make(map[string]*latencyWindow),
+	}
+}
+
+// Latencies tracks the requests to all backends
+var Latencies = NewLatencyTracker(128, 5*time.Minute)
+
+// Observe adds a request duration of a backend
+func (t *LatencyTracker) Observe(host string, d time.Duration, now time.Time) {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	w, ok := t.windows[host]
+	if !ok {
+		w = &latencyWindow{
+			samples: make([]latencySample, 0, t.size),
+		}
+		t.windows[host] = w
+	}
+	sample := latencySample{at: now, duration: d}
+	if len(w.samples) < t.size {
+		w.samples = append(w.samples, sample)
+		return
+	}
+	w.samples[w.next] = sample
+	w.next = (w.next + 1) % t.size
+}
+
+// Percentile calculates the latency percentile (0..1)
+// of the recent requests to a backend. The number of
+// samples is returned as well.
+func (t *LatencyTracker) Percentile(
+	host string, p float64, now time.Time,
+) (time.Duration, int) {
+	t.mtx.Lock()
+	w, ok := t.windows[host]
+	if !ok {
+		t.mtx.Unlock()
+		return 0, 0
+	}
+	durations := make([]time.Duration, 0, len(w.samples))
+	for _, s := range w.samples {
+		if now.Sub(s.at) <= t.maxAge {
+			durations = append(durations, s.duration)
+		}
+	}
+	t.mtx.Unlock()
+
+	if len(durations) == 0 {
+		return 0, 0
+	}
+	sort.Slice(durations, func(i, j int) bool {
+		return durations[i] < durations[j]
+	})
+	idx := int(p*float64(len(durations))+0.5) - 1
+	if idx < 0 {
+		idx = 0
+	}
+	if idx >= len(durations) {
+		idx = len(durations) - 1
+	}
+	return durations[idx], len(durations)
+}
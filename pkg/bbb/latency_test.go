@@ -0,0 +1,40 @@
+package bbb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatencyTrackerPercentile(t *testing.T) {
+	tracker := NewLatencyTracker(100, time.Minute)
+	now := time.Now()
+
+	if _, n := tracker.Percentile("b1", 0.95, now); n != 0 {
+		t.Error("expected no samples")
+	}
+
+	for i := 1; i <= 100; i++ {
+		tracker.Observe("b1", time.Duration(i)*time.Millisecond, now)
+	}
+	p95, n := tracker.Percentile("b1", 0.95, now)
+	if n != 100 {
+		t.Error("unexpected number of samples:", n)
+	}
+	if p95 != 95*time.Millisecond {
+		t.Error("unexpected p95:", p95)
+	}
+
+	// The oldest samples are replaced
+	for i := 0; i < 100; i++ {
+		tracker.Observe("b1", time.Second, now)
+	}
+	if p95, _ := tracker.Percentile("b1", 0.95, now); p95 != time.Second {
+		t.Error("unexpected p95:", p95)
+	}
+
+	// Samples expire
+	_, n = tracker.Percentile("b1", 0.95, now.Add(2*time.Minute))
+	if n != 0 {
+		t.Error("expected samples to expire")
+	}
+}
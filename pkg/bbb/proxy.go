@@ -0,0 +1,80 @@
+package bbb
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"golang.org/x/net/http/httpproxy"
+)
+
+// ProxyDirect connects to a backend without a proxy
+const ProxyDirect = "direct"
+
+// proxyFunc selects the proxy of a request
+type proxyFunc func(*http.Request) (*url.URL, error)
+
+// backendProxy selects the proxy for requests to backends
+// without a proxy setting. By default the HTTP_PROXY,
+// HTTPS_PROXY and NO_PROXY environment is used.
+var backendProxy proxyFunc = http.ProxyFromEnvironment
+
+// ConfigureProxy sets the proxy of the backend requests,
+// overriding HTTP(S)_PROXY from the environment. Hosts
+// matching the noProxy list (like NO_PROXY) are
+// connected directly. Empty values keep the environment.
+func ConfigureProxy(proxy, noProxy string) error {
+	cfg := httpproxy.FromEnvironment()
+	if proxy != "" {
+		if _, err := ParseProxyURL(proxy); err != nil {
+			return err
+		}
+		cfg.HTTPProxy = proxy
+		cfg.HTTPSProxy = proxy
+	}
+	if noProxy != "" {
+		cfg.NoProxy = noProxy
+	}
+	fn := cfg.ProxyFunc()
+	backendProxy = func(req *http.Request) (*url.URL, error) {
+		return fn(req.URL)
+	}
+	return nil
+}
+
+// ParseProxyURL parses and checks the URL of a proxy,
+// e.g. http://egress.example.net:3128
+func ParseProxyURL(s string) (*url.URL, error) {
+	u, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "http", "https", "socks5":
+	default:
+		return nil, fmt.Errorf(
+			"proxy should start with http(s):// or socks5://: %s", s)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("proxy host is missing: %s", s)
+	}
+	return u, nil
+}
+
+// proxyFor creates the proxy selection for the options.
+// Without a proxy option the global proxy is used.
+func proxyFor(opts *ConnOptions) (proxyFunc, error) {
+	switch opts.Proxy {
+	case "":
+		return func(req *http.Request) (*url.URL, error) {
+			return backendProxy(req)
+		}, nil
+	case ProxyDirect:
+		return nil, nil
+	}
+	u, err := ParseProxyURL(opts.Proxy)
+	if err != nil {
+		return nil, err
+	}
+	return http.ProxyURL(u), nil
+}
@@ -0,0 +1,69 @@
+package bbb
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestConfigureProxy(t *testing.T) {
+	defer func() { backendProxy = http.ProxyFromEnvironment }()
+
+	if err := ConfigureProxy("ftp://egress", ""); err == nil {
+		t.Error("expected an error for an invalid proxy")
+	}
+	if err := ConfigureProxy(
+		"http://egress.example.net:3128",
+		".internal.example.net,10.0.0.0/8",
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]string{
+		"https://bbb01.example.net/bigbluebutton/api/":          "http://egress.example.net:3128",
+		"https://bbb02.internal.example.net/bigbluebutton/api/": "",
+		"http://10.0.0.23/bigbluebutton/api/":                   "",
+	}
+	for target, expected := range tests {
+		req, _ := http.NewRequest("GET", target, nil)
+		proxy, err := backendProxy(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proxy == nil && expected != "" {
+			t.Error("expected proxy for", target)
+		}
+		if proxy != nil && proxy.String() != expected {
+			t.Error("unexpected proxy for", target, proxy)
+		}
+	}
+}
+
+func TestConnForProxy(t *testing.T) {
+	var requested string
+	proxy := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			requested = r.URL.String()
+			w.Write([]byte("<response></response>"))
+		}))
+	defer proxy.Close()
+
+	conn, err := connFor(&ConnOptions{Proxy: proxy.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := conn.Get("http://bbb01.example.net/bigbluebutton/api/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ioutil.ReadAll(res.Body)
+	res.Body.Close()
+	if requested != "http://bbb01.example.net/bigbluebutton/api/" {
+		t.Error("unexpected request through proxy:", requested)
+	}
+
+	if _, err := connFor(&ConnOptions{Proxy: "egress:3128"}); err == nil {
+		t.Error("expected an error for an invalid proxy")
+	}
+}
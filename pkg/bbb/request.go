@@ -8,10 +8,10 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"regexp"
-	"sort"
 	"strings"
 )
 
@@ -30,27 +30,104 @@ var (
 // Params for the BBB API (we opt for stringly typed.)
 type Params map[string]string
 
+// maxStackParams is the number of keys sorted in
+// a stack allocated buffer when encoding params.
+const maxStackParams = 32
+
 // String of the query parameters.
 // The order of the parameters is made deterministic.
+//
+// This is called for every request to a backend, so
+// allocations are avoided: The keys are sorted in a
+// stack buffer and the result is built in a single
+// preallocated buffer.
 func (p Params) String() string {
-	keys := make([]string, 0, len(p))
-	for key := range p {
+	var buf [maxStackParams]string
+	keys := buf[:0]
+	size := 0
+	for key, val := range p {
 		// We omit the checksum.
-		if key == "checksum" {
+		if key == ParamChecksum {
 			continue
 		}
 		keys = append(keys, key)
+		size += len(key) + len(val) + 2
+	}
+	if len(keys) == 0 {
+		return ""
 	}
-	sort.Strings(keys)
+	sortKeys(keys)
+
+	// Encode query string. Escaped characters
+	// may need some more space.
+	var b strings.Builder
+	b.Grow(size + size/4)
+	for i, k := range keys {
+		if i > 0 {
+			b.WriteByte('&')
+		}
+		b.WriteString(k)
+		b.WriteByte('=')
+		writeQueryEscaped(&b, p[k])
+	}
+	return b.String()
+}
 
-	// Encode query string
-	q := make([]string, 0, len(keys))
-	for _, k := range keys {
-		v := p[k]
-		vStr := url.QueryEscape(v)
-		q = append(q, fmt.Sprintf("%s=%s", k, vStr))
+// sortKeys sorts the keys with an insertion sort;
+// there are only a few params in a request and
+// sort.Strings would allocate.
+func sortKeys(keys []string) {
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+}
+
+// shouldEscape checks if a byte is escaped
+// in a query component, like url.QueryEscape.
+func shouldEscape(c byte) bool {
+	if 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' {
+		return false
+	}
+	switch c {
+	case '-', '_', '.', '~':
+		return false
+	}
+	return true
+}
+
+// writeQueryEscaped writes the value escaped
+// exactly like url.QueryEscape.
+func writeQueryEscaped(b *strings.Builder, s string) {
+	const upperhex = "0123456789ABCDEF"
+
+	// Fast path: Nothing to escape
+	escape := false
+	for i := 0; i < len(s); i++ {
+		if shouldEscape(s[i]) {
+			escape = true
+			break
+		}
+	}
+	if !escape {
+		b.WriteString(s)
+		return
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == ' ':
+			b.WriteByte('+')
+		case shouldEscape(c):
+			b.WriteByte('%')
+			b.WriteByte(upperhex[c>>4])
+			b.WriteByte(upperhex[c&15])
+		default:
+			b.WriteByte(c)
+		}
 	}
-	return strings.Join(q, "&")
 }
 
 // MeetingID retrievs the well known meeting id
@@ -158,6 +235,9 @@ func UnmarshalURLSafeRequest(data []byte) (req *Request, err error) {
 	}()
 
 	req = decodeURLSafeRequest(r)
+	if req.Request.URL == nil {
+		return nil, fmt.Errorf("decoding error: url is missing")
+	}
 	return req, nil
 }
 
@@ -225,6 +305,19 @@ func EndRequest(params Params) *Request {
 	}
 }
 
+// SendChatMessageRequest creates a request posting a
+// message to the public chat of a meeting. The resource
+// is available since BBB 2.7.
+func SendChatMessageRequest(params Params) *Request {
+	return &Request{
+		Request: &http.Request{
+			Method: http.MethodGet,
+		},
+		Resource: ResourceSendChatMessage,
+		Params:   params,
+	}
+}
+
 // CreateRequest creates a new create request
 func CreateRequest(params Params, body []byte) *Request {
 	return &Request{
@@ -262,6 +355,17 @@ func GetMeetingInfoRequest(params Params) *Request {
 	}
 }
 
+// GetRecordingsRequest creates a new getRecordings request
+func GetRecordingsRequest(params Params) *Request {
+	return &Request{
+		Request: &http.Request{
+			Method: http.MethodGet,
+		},
+		Resource: ResourceGetRecordings,
+		Params:   params,
+	}
+}
+
 // IsMeetingRunningRequest makes a new isMeetingRunning request
 func IsMeetingRunningRequest(params Params) *Request {
 	return &Request{
@@ -277,29 +381,43 @@ func IsMeetingRunningRequest(params Params) *Request {
 func (req *Request) calculateChecksumSHA1(query, secret string) []byte {
 	// Calculate checksum with server secret
 	// Basically sign the endpoint + params
-	mac := []byte(req.Resource + query + secret)
 	shasum := sha1.New()
-	shasum.Write(mac)
-	return []byte(hex.EncodeToString(shasum.Sum(nil)))
+	io.WriteString(shasum, req.Resource)
+	io.WriteString(shasum, query)
+	io.WriteString(shasum, secret)
+	var sum [sha1.Size]byte
+	checksum := make([]byte, hex.EncodedLen(sha1.Size))
+	hex.Encode(checksum, shasum.Sum(sum[:0]))
+	return checksum
 }
 
 // Internal calculate checksum with a given secret.
 func (req *Request) calculateChecksumSHA256(query, secret string) []byte {
 	// Calculate checksum with server secret
 	// Basically sign the endpoint + params
-	mac := []byte(req.Resource + query + secret)
 	shasum := sha256.New()
-	shasum.Write(mac)
-	return []byte(hex.EncodeToString(shasum.Sum(nil)))
+	io.WriteString(shasum, req.Resource)
+	io.WriteString(shasum, query)
+	io.WriteString(shasum, secret)
+	var sum [sha256.Size]byte
+	checksum := make([]byte, hex.EncodedLen(sha256.Size))
+	hex.Encode(checksum, shasum.Sum(sum[:0]))
+	return checksum
 }
 
 // Verify request coming from a frontend:
 // Compare checksum with the checksum calculated from the
 // incoming raw query string and the frontend secret
 func (req *Request) Verify() error {
+	return req.VerifySecret(req.Frontend.Secret)
+}
+
+// VerifySecret checks the request checksum against
+// a given secret. This is used e.g. for accepting a
+// previous frontend secret after a secret rotation.
+func (req *Request) VerifySecret(secret string) error {
 	// Use request querystring and remove checksum
 	query := ReQueryChecksum.ReplaceAllString(req.Request.URL.RawQuery, "")
-	secret := req.Frontend.Secret
 
 	var expected []byte
 	if len(req.Checksum) > 40 {
@@ -317,8 +435,12 @@ func (req *Request) Verify() error {
 
 // Sign a request, with the backend secret.
 func (req *Request) Sign() string {
+	return req.signQuery(req.Params.String())
+}
+
+// signQuery calculates the checksum of the encoded params
+func (req *Request) signQuery(query string) string {
 	secret := req.Backend.Secret
-	query := req.Params.String()
 	return string(req.calculateChecksumSHA256(query, secret))
 }
 
@@ -332,16 +454,22 @@ func (req *Request) URL() string {
 		apiBase += "/"
 	}
 
-	// Sign the request and encode params
+	// Sign the request and encode params.
+	// The params are only encoded once.
 	qry := req.Params.String()
-	chksum := req.Sign()
+	chksum := req.signQuery(qry)
 
 	// Build request url
-	reqURL := apiBase + req.Resource
-	if qry == "" {
-		reqURL += "?checksum=" + chksum
-	} else {
-		reqURL += "?" + qry + "&checksum=" + chksum
+	var b strings.Builder
+	b.Grow(len(apiBase) + len(req.Resource) + len(qry) + len(chksum) + 11)
+	b.WriteString(apiBase)
+	b.WriteString(req.Resource)
+	b.WriteByte('?')
+	if qry != "" {
+		b.WriteString(qry)
+		b.WriteByte('&')
 	}
-	return reqURL
+	b.WriteString("checksum=")
+	b.WriteString(chksum)
+	return b.String()
 }
@@ -225,3 +225,65 @@ func TestDecodeURLSafeRequest(t *testing.T) {
 		t.Error("unexpected query")
 	}
 }
+
+func TestParamsStringEscaping(t *testing.T) {
+	values := []string{
+		"", "plain", "Meeting Name", "ä ö ü", "a&b=c", "100%",
+		"https://example.com/path?x=1", "~-_.", "\x00\xff",
+	}
+	for _, v := range values {
+		p := Params{"v": v}
+		expected := "v=" + url.QueryEscape(v)
+		if p.String() != expected {
+			t.Error("unexpected encoding:", p.String(), "expected:", expected)
+		}
+	}
+}
+
+func benchmarkRequest() *Request {
+	return &Request{
+		Backend: &Backend{
+			Host:   "https://bbb.example.com/bigbluebutton/api/",
+			Secret: "639259d4-9dd8-4b25-bf01-95f9567eaf4b",
+		},
+		Resource: ResourceGetMeetingInfo,
+		Params: Params{
+			"meetingID":   "abc123",
+			"name":        "Test Meeting",
+			"attendeePW":  "111222",
+			"moderatorPW": "333444",
+			"logoutURL":   "https://example.com/logout?room=1",
+		},
+	}
+}
+
+func BenchmarkParamsString(b *testing.B) {
+	p := benchmarkRequest().Params
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = p.String()
+	}
+}
+
+func BenchmarkRequestURL(b *testing.B) {
+	req := benchmarkRequest()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = req.URL()
+	}
+}
+
+func BenchmarkRequestSign(b *testing.B) {
+	req := benchmarkRequest()
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_ = req.Sign()
+	}
+}
+
+func TestUnmarshalURLSafeRequestWithoutURL(t *testing.T) {
+	data := base64.RawURLEncoding.EncodeToString([]byte(`{"mth":"4"}`))
+	if _, err := UnmarshalURLSafeRequest([]byte(data)); err == nil {
+		t.Error("expected an error")
+	}
+}
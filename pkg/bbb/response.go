@@ -5,6 +5,7 @@ import (
 	"encoding/xml"
 	"errors"
 	"fmt"
+	"io"
 	"net/http"
 )
 
@@ -43,6 +44,13 @@ type Response interface {
 	SetStatus(int)
 }
 
+// A ResponseStreamer writes its encoding directly to
+// a writer, without building the entire document in
+// memory first. This is used for large responses.
+type ResponseStreamer interface {
+	Stream(w io.Writer) error
+}
+
 // A XMLResponse from the server
 type XMLResponse struct {
 	XMLName    xml.Name `xml:"response"`
@@ -125,7 +133,9 @@ type CreateResponse struct {
 
 // UnmarshalCreateResponse decodes the resonse XML data.
 func UnmarshalCreateResponse(data []byte) (*CreateResponse, error) {
-	res := &CreateResponse{}
+	res := &CreateResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -178,7 +188,9 @@ type JoinResponse struct {
 
 // UnmarshalJoinResponse decodes the serialized XML data
 func UnmarshalJoinResponse(data []byte) (*JoinResponse, error) {
-	res := &JoinResponse{}
+	res := &JoinResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	if err != nil {
 		res.XMLResponse = new(XMLResponse)
@@ -241,7 +253,9 @@ type IsMeetingRunningResponse struct {
 func UnmarshalIsMeetingRunningResponse(
 	data []byte,
 ) (*IsMeetingRunningResponse, error) {
-	res := &IsMeetingRunningResponse{}
+	res := &IsMeetingRunningResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -283,7 +297,9 @@ type EndResponse struct {
 
 // UnmarshalEndResponse decodes the xml resonse
 func UnmarshalEndResponse(data []byte) (*EndResponse, error) {
-	res := &EndResponse{}
+	res := &EndResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -318,6 +334,51 @@ func (res *EndResponse) SetStatus(s int) {
 	res.XMLResponse.SetStatus(s)
 }
 
+// SendChatMessageResponse is the response of the
+// sendChatMessage resource
+type SendChatMessageResponse struct {
+	*XMLResponse
+}
+
+// UnmarshalSendChatMessageResponse decodes the xml response
+func UnmarshalSendChatMessageResponse(data []byte) (*SendChatMessageResponse, error) {
+	res := &SendChatMessageResponse{
+		XMLResponse: new(XMLResponse),
+	}
+	err := xml.Unmarshal(data, res)
+	return res, err
+}
+
+// Marshal SendChatMessageResponse to XML
+func (res *SendChatMessageResponse) Marshal() ([]byte, error) {
+	return xml.Marshal(res)
+}
+
+// Merge SendChatMessageResponses
+func (res *SendChatMessageResponse) Merge(other Response) error {
+	return ErrCantBeMerged
+}
+
+// Header returns the HTTP response headers
+func (res *SendChatMessageResponse) Header() http.Header {
+	return res.XMLResponse.Header()
+}
+
+// SetHeader sets the HTTP response headers
+func (res *SendChatMessageResponse) SetHeader(h http.Header) {
+	res.XMLResponse.SetHeader(h)
+}
+
+// Status returns the HTTP response status code
+func (res *SendChatMessageResponse) Status() int {
+	return res.XMLResponse.Status()
+}
+
+// SetStatus sets the HTTP response status code
+func (res *SendChatMessageResponse) SetStatus(s int) {
+	res.XMLResponse.SetStatus(s)
+}
+
 // GetMeetingInfoResponse contains detailed meeting information
 type GetMeetingInfoResponse struct {
 	*XMLResponse
@@ -328,7 +389,9 @@ type GetMeetingInfoResponse struct {
 func UnmarshalGetMeetingInfoResponse(
 	data []byte,
 ) (*GetMeetingInfoResponse, error) {
-	res := &GetMeetingInfoResponse{}
+	res := &GetMeetingInfoResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -373,7 +436,9 @@ type GetMeetingsResponse struct {
 func UnmarshalGetMeetingsResponse(
 	data []byte,
 ) (*GetMeetingsResponse, error) {
-	res := &GetMeetingsResponse{}
+	res := &GetMeetingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -424,28 +489,224 @@ func (res *GetMeetingsResponse) SetStatus(s int) {
 type GetRecordingsResponse struct {
 	*XMLResponse
 	Recordings []*Recording `xml:"recordings>recording"`
+
+	// When streaming, the recordings are decoded
+	// from the backend response one by one.
+	pending *recordingsDecoder
+	rewrite []func(*Recording)
 }
 
 // UnmarshalGetRecordingsResponse deserializes the response XML
 func UnmarshalGetRecordingsResponse(
 	data []byte,
 ) (*GetRecordingsResponse, error) {
-	res := &GetRecordingsResponse{}
+	res := &GetRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
 
+// DecodeGetRecordingsResponse reads the response XML
+// from a stream. The raw response body is not kept.
+func DecodeGetRecordingsResponse(
+	r io.Reader,
+) (*GetRecordingsResponse, error) {
+	res := &GetRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
+	err := xml.NewDecoder(r).Decode(res)
+	return res, err
+}
+
+// StreamGetRecordingsResponse reads the response XML
+// up to the recordings. The recordings are decoded one
+// by one from the body when the response is streamed
+// or decoded. The body is closed afterwards.
+func StreamGetRecordingsResponse(
+	body io.ReadCloser,
+) (*GetRecordingsResponse, error) {
+	res := &GetRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+		pending: &recordingsDecoder{
+			dec:  xml.NewDecoder(body),
+			body: body,
+		},
+	}
+	if err := res.pending.header(res.XMLResponse); err != nil {
+		body.Close()
+		return nil, err
+	}
+	return res, nil
+}
+
+// EachRecording applies fn to the recordings. Recordings
+// decoded later while streaming are passed to fn as well.
+func (res *GetRecordingsResponse) EachRecording(fn func(*Recording)) {
+	for _, r := range res.Recordings {
+		fn(r)
+	}
+	if res.pending != nil {
+		res.rewrite = append(res.rewrite, fn)
+	}
+}
+
+// nextRecording decodes the next pending recording.
+// At the end of the recordings, nil is returned.
+func (res *GetRecordingsResponse) nextRecording() (*Recording, error) {
+	if res.pending == nil {
+		return nil, nil
+	}
+	r, err := res.pending.next(res.XMLResponse)
+	if err != nil || r == nil {
+		res.Close()
+		return nil, err
+	}
+	for _, fn := range res.rewrite {
+		fn(r)
+	}
+	return r, nil
+}
+
+// DecodeAll reads the pending recordings
+// of a streamed response.
+func (res *GetRecordingsResponse) DecodeAll() error {
+	for {
+		r, err := res.nextRecording()
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			return nil
+		}
+		res.Recordings = append(res.Recordings, r)
+	}
+}
+
+// Close releases the backend response
+// of a streamed response.
+func (res *GetRecordingsResponse) Close() error {
+	if res.pending == nil {
+		return nil
+	}
+	err := res.pending.body.Close()
+	res.pending = nil
+	return err
+}
+
 // Marshal a GetRecordingsResponse to XML
 func (res *GetRecordingsResponse) Marshal() ([]byte, error) {
+	if err := res.DecodeAll(); err != nil {
+		return nil, err
+	}
 	return xml.Marshal(res)
 }
 
+// Stream writes the GetRecordingsResponse as XML. The
+// recordings are encoded one by one. If the writer is
+// a http.Flusher, each recording is flushed, so the
+// response is sent in chunks while encoding.
+func (res *GetRecordingsResponse) Stream(w io.Writer) error {
+	enc := xml.NewEncoder(w)
+	flush := func() error {
+		if err := enc.Flush(); err != nil {
+			return err
+		}
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		return nil
+	}
+	element := func(name string) xml.StartElement {
+		return xml.StartElement{Name: xml.Name{Local: name}}
+	}
+
+	defer res.Close()
+
+	// Header
+	if err := enc.EncodeToken(element("response")); err != nil {
+		return err
+	}
+	if err := enc.EncodeElement(res.Returncode, element("returncode")); err != nil {
+		return err
+	}
+	optional := func() []struct{ name, value string } {
+		return []struct{ name, value string }{
+			{"message", res.Message},
+			{"messageKey", res.MessageKey},
+			{"version", res.Version},
+		}
+	}
+	header := optional()
+	for _, f := range header {
+		if f.value == "" {
+			continue
+		}
+		if err := enc.EncodeElement(f.value, element(f.name)); err != nil {
+			return err
+		}
+	}
+
+	// Recordings
+	if err := enc.EncodeToken(element("recordings")); err != nil {
+		return err
+	}
+	for _, r := range res.Recordings {
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	for {
+		r, err := res.nextRecording()
+		if err != nil {
+			return err
+		}
+		if r == nil {
+			break
+		}
+		if err := enc.Encode(r); err != nil {
+			return err
+		}
+		if err := flush(); err != nil {
+			return err
+		}
+	}
+	if err := enc.EncodeToken(element("recordings").End()); err != nil {
+		return err
+	}
+
+	// BBB sends the message after the recordings
+	// if there are none.
+	for i, f := range optional() {
+		if f.value == "" || header[i].value != "" {
+			continue
+		}
+		if err := enc.EncodeElement(f.value, element(f.name)); err != nil {
+			return err
+		}
+	}
+
+	if err := enc.EncodeToken(element("response").End()); err != nil {
+		return err
+	}
+	return flush()
+}
+
 // Merge another GetRecordingsResponse
 func (res *GetRecordingsResponse) Merge(other Response) error {
 	otherRes, ok := other.(*GetRecordingsResponse)
 	if !ok {
 		return ErrCantBeMerged
 	}
+	if err := res.DecodeAll(); err != nil {
+		return err
+	}
+	if err := otherRes.DecodeAll(); err != nil {
+		return err
+	}
 	err := res.XMLResponse.Merge(otherRes.XMLResponse)
 	if err != nil {
 		return err
@@ -489,7 +750,9 @@ type PublishRecordingsResponse struct {
 func UnmarshalPublishRecordingsResponse(
 	data []byte,
 ) (*PublishRecordingsResponse, error) {
-	res := &PublishRecordingsResponse{}
+	res := &PublishRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -552,7 +815,9 @@ type DeleteRecordingsResponse struct {
 func UnmarshalDeleteRecordingsResponse(
 	data []byte,
 ) (*DeleteRecordingsResponse, error) {
-	res := &DeleteRecordingsResponse{}
+	res := &DeleteRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -612,7 +877,9 @@ type UpdateRecordingsResponse struct {
 func UnmarshalUpdateRecordingsResponse(
 	data []byte,
 ) (*UpdateRecordingsResponse, error) {
-	res := &UpdateRecordingsResponse{}
+	res := &UpdateRecordingsResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -694,22 +961,26 @@ func (res *GetDefaultConfigXMLResponse) Merge(other Response) error {
 
 // Header returns the HTTP response headers
 func (res *GetDefaultConfigXMLResponse) Header() http.Header {
-	return res.Header()
+	if res.header == nil {
+		res.header = make(http.Header)
+		res.header.Add("Content-Type", "application/xml")
+	}
+	return res.header
 }
 
 // SetHeader sets the HTTP response headers
 func (res *GetDefaultConfigXMLResponse) SetHeader(h http.Header) {
-	res.SetHeader(h)
+	res.header = h
 }
 
 // Status returns the HTTP response status code
 func (res *GetDefaultConfigXMLResponse) Status() int {
-	return res.Status()
+	return res.status
 }
 
 // SetStatus sets the HTTP response status code
 func (res *GetDefaultConfigXMLResponse) SetStatus(s int) {
-	res.SetStatus(s)
+	res.status = s
 }
 
 // SetConfigXMLResponse encodes the result of setting the config
@@ -722,7 +993,9 @@ type SetConfigXMLResponse struct {
 func UnmarshalSetConfigXMLResponse(
 	data []byte,
 ) (*SetConfigXMLResponse, error) {
-	res := &SetConfigXMLResponse{}
+	res := &SetConfigXMLResponse{
+		XMLResponse: new(XMLResponse),
+	}
 	err := xml.Unmarshal(data, res)
 	return res, err
 }
@@ -780,8 +1053,14 @@ func UnmarshalGetRecordingTextTracksResponse(
 	res := &JSONResponse{
 		Response: &GetRecordingTextTracksResponse{},
 	}
-	err := json.Unmarshal(data, res)
-	return res.Response.(*GetRecordingTextTracksResponse), err
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	r, ok := res.Response.(*GetRecordingTextTracksResponse)
+	if !ok {
+		return nil, fmt.Errorf("decoding error: response is missing")
+	}
+	return r, nil
 }
 
 // Marshal GetRecordingTextTracksResponse to JSON
@@ -853,8 +1132,14 @@ func UnmarshalPutRecordingTextTrackResponse(
 	res := &JSONResponse{
 		Response: &PutRecordingTextTrackResponse{},
 	}
-	err := json.Unmarshal(data, res)
-	return res.Response.(*PutRecordingTextTrackResponse), err
+	if err := json.Unmarshal(data, res); err != nil {
+		return nil, err
+	}
+	r, ok := res.Response.(*PutRecordingTextTrackResponse)
+	if !ok {
+		return nil, fmt.Errorf("decoding error: response is missing")
+	}
+	return r, nil
 }
 
 // Marshal a PutRecordingTextTrackResponse to JSON
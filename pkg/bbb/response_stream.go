@@ -0,0 +1,114 @@
+package bbb
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+)
+
+// recordingsDecoder reads the recordings of a
+// getRecordings response from the backend one
+// by one, instead of decoding the entire document.
+type recordingsDecoder struct {
+	dec  *xml.Decoder
+	body io.Closer
+
+	// The recordings element was read
+	done bool
+}
+
+// header reads the start of the response and the
+// fields up to the recordings.
+func (d *recordingsDecoder) header(res *XMLResponse) error {
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if start.Name.Local != "response" {
+			return fmt.Errorf(
+				"expected element type <response> but have <%s>",
+				start.Name.Local)
+		}
+		res.XMLName = start.Name
+		return d.fields(res)
+	}
+}
+
+// fields decodes the fields of the response until the
+// recordings start or the response ends.
+func (d *recordingsDecoder) fields(res *XMLResponse) error {
+	for {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			var field *string
+			switch t.Name.Local {
+			case "recordings":
+				if !d.done {
+					return nil
+				}
+			case "returncode":
+				field = &res.Returncode
+			case "message":
+				field = &res.Message
+			case "messageKey":
+				field = &res.MessageKey
+			case "version":
+				field = &res.Version
+			}
+			if field == nil {
+				if err := d.dec.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.dec.DecodeElement(field, &t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			// The response ended without recordings
+			d.done = true
+			return nil
+		}
+	}
+}
+
+// next decodes the next recording. After the last
+// recording, the remaining fields of the response
+// are decoded and nil is returned.
+func (d *recordingsDecoder) next(res *XMLResponse) (*Recording, error) {
+	for !d.done {
+		tok, err := d.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local != "recording" {
+				if err := d.dec.Skip(); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			r := &Recording{}
+			if err := d.dec.DecodeElement(r, &t); err != nil {
+				return nil, err
+			}
+			return r, nil
+		case xml.EndElement:
+			d.done = true
+			if err := d.fields(res); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return nil, nil
+}
@@ -1,8 +1,11 @@
 package bbb
 
 import (
+	"bytes"
 	"errors"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"path"
 	"testing"
 )
@@ -151,6 +154,18 @@ func TestUnmarshalEndResponse(t *testing.T) {
 	}
 }
 
+func TestUnmarshalSendChatMessageResponse(t *testing.T) {
+	data := readTestResponse("sendChatMessageSuccess.xml")
+	response, err := UnmarshalSendChatMessageResponse(data)
+	if err != nil {
+		t.Error(err)
+	}
+
+	if response.XMLResponse.Returncode != RetSuccess {
+		t.Error("Unexpected Returncode:", response.XMLResponse.Returncode)
+	}
+}
+
 func TestMarshalEndResponse(t *testing.T) {
 	res := &EndResponse{
 		&XMLResponse{Returncode: "YAY"},
@@ -401,6 +416,102 @@ func TestMarshalGetRecordingsResponse(t *testing.T) {
 	}
 }
 
+func TestStreamGetRecordingsResponse(t *testing.T) {
+	// The order of the metadata is random,
+	// so the recordings have none.
+	response := &GetRecordingsResponse{
+		XMLResponse: &XMLResponse{
+			Returncode: RetSuccess,
+			MessageKey: "someKey",
+		},
+		Recordings: []*Recording{
+			{RecordID: "rec1", Name: "Fred's Room"},
+			{RecordID: "rec2"},
+		},
+	}
+	empty := &GetRecordingsResponse{
+		XMLResponse: &XMLResponse{Returncode: RetSuccess},
+	}
+	for _, res := range []*GetRecordingsResponse{response, empty} {
+		expected, err := res.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+		rec := httptest.NewRecorder()
+		if err := res.Stream(rec); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Body.String() != string(expected) {
+			t.Error("unexpected stream:", rec.Body.String())
+		}
+		if !rec.Flushed {
+			t.Error("the stream should be flushed")
+		}
+	}
+}
+
+func TestStreamGetRecordingsResponseIncremental(t *testing.T) {
+	data := readTestResponse("getRecordingsSuccess.xml")
+	expected, err := UnmarshalGetRecordingsResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := StreamGetRecordingsResponse(
+		ioutil.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.EachRecording(func(r *Recording) {
+		r.MeetingID = "rewritten"
+	})
+	rec := httptest.NewRecorder()
+	if err := res.Stream(rec); err != nil {
+		t.Fatal(err)
+	}
+	streamed, err := UnmarshalGetRecordingsResponse(rec.Body.Bytes())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(streamed.Recordings) != len(expected.Recordings) {
+		t.Error("unexpected recordings:", len(streamed.Recordings))
+	}
+	for i, r := range streamed.Recordings {
+		if r.RecordID != expected.Recordings[i].RecordID {
+			t.Error("unexpected recording:", r.RecordID)
+		}
+		if r.MeetingID != "rewritten" {
+			t.Error("the recording should be rewritten:", r.MeetingID)
+		}
+	}
+}
+
+func TestStreamGetRecordingsResponseNoRecordings(t *testing.T) {
+	data := []byte(`<response>
+  <returncode>SUCCESS</returncode>
+  <recordings></recordings>
+  <messageKey>noRecordings</messageKey>
+  <message>There are no recordings for the meeting(s).</message>
+</response>`)
+	res, err := StreamGetRecordingsResponse(
+		ioutil.NopCloser(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := res.DecodeAll(); err != nil {
+		t.Fatal(err)
+	}
+	if res.MessageKey != "noRecordings" || len(res.Recordings) != 0 {
+		t.Error("unexpected response:", res.MessageKey, res.Recordings)
+	}
+
+	// Responses without a response element are rejected
+	_, err = StreamGetRecordingsResponse(ioutil.NopCloser(
+		bytes.NewReader([]byte("<html>503 Service Unavailable</html>"))))
+	if err == nil {
+		t.Error("expected an error")
+	}
+}
+
 func TestUnmarshalPublishRecordingsResponse(t *testing.T) {
 	data := readTestResponse("publishRecordingsSuccess.xml")
 	response, err := UnmarshalPublishRecordingsResponse(data)
@@ -584,3 +695,49 @@ func TestMarshalPutRecordingTextTrackResponse(t *testing.T) {
 		t.Error("Unexpected:", string(data), len(data))
 	}
 }
+
+// Malformed responses
+
+func TestUnmarshalResponseWithoutEnvelope(t *testing.T) {
+	data := []byte("<response>0</response>0")
+	for _, resource := range []string{
+		ResourceCreate,
+		ResourceJoin,
+		ResourceGetMeetings,
+		ResourceGetRecordings,
+		ResourceSetConfigXML,
+	} {
+		res, err := UnmarshalResponse(resource, data)
+		if err != nil {
+			t.Fatal(resource, err)
+		}
+		res.SetHeader(http.Header{})
+		res.SetStatus(http.StatusOK)
+		if res.Status() != http.StatusOK {
+			t.Error(resource, "unexpected status:", res.Status())
+		}
+	}
+}
+
+func TestUnmarshalJSONResponseWithoutPayload(t *testing.T) {
+	data := []byte(`{"response": null}`)
+	if _, err := UnmarshalGetRecordingTextTracksResponse(data); err == nil {
+		t.Error("expected an error")
+	}
+	if _, err := UnmarshalPutRecordingTextTrackResponse(data); err == nil {
+		t.Error("expected an error")
+	}
+}
+
+func TestGetDefaultConfigXMLResponseHeader(t *testing.T) {
+	res := &GetDefaultConfigXMLResponse{
+		Config: []byte("<config />"),
+	}
+	if res.Header().Get("Content-Type") != "application/xml" {
+		t.Error("unexpected header:", res.Header())
+	}
+	res.SetStatus(http.StatusOK)
+	if res.Status() != http.StatusOK {
+		t.Error("unexpected status:", res.Status())
+	}
+}
@@ -0,0 +1,95 @@
+package bbb
+
+import (
+	"sync"
+	"time"
+)
+
+// TimeoutDefault is the key of the timeout
+// applied to all other resources.
+const TimeoutDefault = "default"
+
+// Timeouts of requests to a backend by resource
+type Timeouts map[string]time.Duration
+
+// For retrieves the timeout of a resource. The result
+// is false if no timeout is configured.
+func (t Timeouts) For(resource string) (time.Duration, bool) {
+	if timeout, ok := t[resource]; ok {
+		return timeout, true
+	}
+	timeout, ok := t[TimeoutDefault]
+	return timeout, ok
+}
+
+// MaxBackendTimeout limits the timeouts
+// configured for a single backend.
+const MaxBackendTimeout = 5 * time.Minute
+
+// RequestTimeouts apply to all backends. Timeouts
+// of a backend take precedence.
+var RequestTimeouts = Timeouts{
+	TimeoutDefault: 60 * time.Second,
+}
+
+// backendTimeouts are the longest request timeouts
+// of the backends by backend ID.
+var (
+	backendTimeouts   = map[string]time.Duration{}
+	backendTimeoutsMu sync.Mutex
+)
+
+// longest returns the longest of the timeouts
+func (t Timeouts) longest() time.Duration {
+	max := time.Duration(0)
+	for _, timeout := range t {
+		if timeout > max {
+			max = timeout
+		}
+	}
+	return max
+}
+
+// SetBackendTimeouts registers the timeouts of a
+// backend. They are considered by the MaxRequestTimeout.
+func SetBackendTimeouts(backendID string, timeouts Timeouts) {
+	backendTimeoutsMu.Lock()
+	defer backendTimeoutsMu.Unlock()
+	max := timeouts.longest()
+	if max == 0 {
+		delete(backendTimeouts, backendID)
+		return
+	}
+	backendTimeouts[backendID] = max
+}
+
+// MaxRequestTimeout is the longest timeout a
+// request to any backend can have. It is derived from
+// the global timeouts and the timeouts of the backends.
+func MaxRequestTimeout() time.Duration {
+	max := RequestTimeouts.longest()
+	backendTimeoutsMu.Lock()
+	defer backendTimeoutsMu.Unlock()
+	for _, timeout := range backendTimeouts {
+		if timeout > max {
+			max = timeout
+		}
+	}
+	return max
+}
+
+// requestTimeout selects the timeout of the resource
+// from the backend timeouts or the global timeouts.
+func requestTimeout(backend Timeouts, resource string) time.Duration {
+	if timeout, ok := backend[resource]; ok {
+		return timeout
+	}
+	if timeout, ok := RequestTimeouts[resource]; ok {
+		return timeout
+	}
+	if timeout, ok := backend.For(TimeoutDefault); ok {
+		return timeout
+	}
+	timeout, _ := RequestTimeouts.For(TimeoutDefault)
+	return timeout
+}
@@ -0,0 +1,69 @@
+package bbb
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	defer func(timeouts Timeouts) {
+		RequestTimeouts = timeouts
+	}(RequestTimeouts)
+
+	RequestTimeouts = Timeouts{
+		TimeoutDefault: 60 * time.Second,
+		ResourceCreate: 120 * time.Second,
+	}
+	backend := Timeouts{
+		ResourceIsMeetingRunning: 5 * time.Second,
+	}
+
+	if d := requestTimeout(backend, ResourceIsMeetingRunning); d != 5*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+	if d := requestTimeout(backend, ResourceCreate); d != 120*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+	if d := requestTimeout(backend, ResourceEnd); d != 60*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+
+	// The default of the backend precedes the global default
+	backend[TimeoutDefault] = 30 * time.Second
+	if d := requestTimeout(backend, ResourceEnd); d != 30*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+	if d := requestTimeout(nil, ResourceEnd); d != 60*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+}
+
+func TestMaxRequestTimeout(t *testing.T) {
+	defer func(timeouts Timeouts) {
+		RequestTimeouts = timeouts
+	}(RequestTimeouts)
+
+	RequestTimeouts = Timeouts{
+		TimeoutDefault: 60 * time.Second,
+	}
+	if d := MaxRequestTimeout(); d != 60*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+	RequestTimeouts[ResourceCreate] = 10 * time.Minute
+	if d := MaxRequestTimeout(); d != 10*time.Minute {
+		t.Error("unexpected timeout:", d)
+	}
+
+	// Timeouts of a backend
+	delete(RequestTimeouts, ResourceCreate)
+	SetBackendTimeouts("backend1", Timeouts{
+		ResourceCreate: 2 * time.Minute,
+	})
+	if d := MaxRequestTimeout(); d != 2*time.Minute {
+		t.Error("unexpected timeout:", d)
+	}
+	SetBackendTimeouts("backend1", nil)
+	if d := MaxRequestTimeout(); d != 60*time.Second {
+		t.Error("unexpected timeout:", d)
+	}
+}
@@ -0,0 +1,186 @@
+package bbb
+
+/*
+ Request tracing: For debugging protocol incompatibilities,
+ the requests to the backends and their responses can be
+ recorded for selected meetings.
+*/
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// Redacted replaces sensitive values in a trace
+const Redacted = "[REDACTED]"
+
+// sensitiveParams are removed from the traced parameters
+var sensitiveParams = map[string]bool{
+	ParamChecksum: true,
+	"attendeePW":  true,
+	"moderatorPW": true,
+	"password":    true,
+}
+
+// reSensitiveXML matches sensitive elements in a response
+var reSensitiveXML = regexp.MustCompile(
+	`<(attendeePW|moderatorPW|password)>[^<]*</(attendeePW|moderatorPW|password)>`)
+
+// A Trace is a recorded request to a backend
+// and the response.
+type Trace struct {
+	Time      time.Time     `json:"time"`
+	Duration  time.Duration `json:"duration"`
+	Backend   string        `json:"backend"`
+	MeetingID string        `json:"meeting_id"`
+
+	Method   string `json:"method"`
+	Resource string `json:"resource"`
+	Params   Params `json:"params"`
+	Body     []byte `json:"body,omitempty"`
+
+	Status   int    `json:"status"`
+	Response []byte `json:"response"`
+}
+
+// NewTrace creates a sanitized trace from a request
+// and the raw response.
+func NewTrace(
+	req *Request,
+	status int,
+	response []byte,
+	duration time.Duration,
+) *Trace {
+	meetingID, _ := req.Params.MeetingID()
+	params := make(Params, len(req.Params))
+	for k, v := range req.Params {
+		if sensitiveParams[k] {
+			if k != ParamChecksum {
+				params[k] = Redacted
+			}
+			continue
+		}
+		params[k] = v
+	}
+	backend := ""
+	if req.Backend != nil {
+		backend = req.Backend.Host
+	}
+	return &Trace{
+		Time:      time.Now().UTC(),
+		Duration:  duration,
+		Backend:   backend,
+		MeetingID: meetingID,
+		Method:    req.Request.Method,
+		Resource:  req.Resource,
+		Params:    params,
+		Body:      req.Body,
+		Status:    status,
+		Response: reSensitiveXML.ReplaceAll(
+			response, []byte("<$1>"+Redacted+"</$1>")),
+	}
+}
+
+// A Tracer records the traces of selected meetings
+// as JSON lines to files in a directory. There is one
+// file per meeting.
+type Tracer struct {
+	dir      string
+	meetings map[string]bool
+	mtx      sync.Mutex
+}
+
+// ActiveTracer is used by the client to record traces.
+// Tracing is disabled if nil.
+var ActiveTracer *Tracer
+
+// NewTracer creates a new tracer writing to
+// the directory. Only requests with one of the
+// meeting IDs will be traced.
+func NewTracer(dir string, meetingIDs []string) (*Tracer, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	meetings := make(map[string]bool, len(meetingIDs))
+	for _, id := range meetingIDs {
+		id = strings.TrimSpace(id)
+		if id != "" {
+			meetings[id] = true
+		}
+	}
+	return &Tracer{
+		dir:      dir,
+		meetings: meetings,
+	}, nil
+}
+
+// Traces checks if requests for the meeting are traced
+func (t *Tracer) Traces(req *Request) bool {
+	meetingID, ok := req.Params.MeetingID()
+	if !ok {
+		return false
+	}
+	return t.meetings[meetingID]
+}
+
+// Filename returns the trace file of a meeting
+func (t *Tracer) Filename(meetingID string) string {
+	return filepath.Join(
+		t.dir, config.SafeFilename(meetingID)+".jsonl")
+}
+
+// Record appends the trace to the file of the meeting
+func (t *Tracer) Record(trace *Trace) error {
+	data, err := json.Marshal(trace)
+	if err != nil {
+		return err
+	}
+
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	f, err := os.OpenFile(
+		t.Filename(trace.MeetingID),
+		os.O_APPEND|os.O_CREATE|os.O_WRONLY,
+		0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	data = append(data, '\n')
+	_, err = f.Write(data)
+	return err
+}
+
+// ReadTraces reads all traces from a trace file
+func ReadTraces(filename string) ([]*Trace, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	traces := []*Trace{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		trace := &Trace{}
+		if err := json.Unmarshal(line, trace); err != nil {
+			return nil, err
+		}
+		traces = append(traces, trace)
+	}
+	return traces, scanner.Err()
+}
@@ -0,0 +1,71 @@
+package bbb
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewTraceSanitized(t *testing.T) {
+	httpReq, _ := http.NewRequest("GET", "http://bbb/api/create", nil)
+	req := &Request{
+		Request:  httpReq,
+		Resource: ResourceCreate,
+		Params: Params{
+			ParamMeetingID: "meeting23",
+			ParamChecksum:  "1234",
+			"moderatorPW":  "secret",
+		},
+		Backend: &Backend{Host: "http://bbb/api/"},
+	}
+	res := []byte("<response><moderatorPW>secret</moderatorPW></response>")
+	trace := NewTrace(req, http.StatusOK, res, time.Second)
+
+	if _, ok := trace.Params[ParamChecksum]; ok {
+		t.Error("checksum should be removed")
+	}
+	if trace.Params["moderatorPW"] != Redacted {
+		t.Error("password should be redacted")
+	}
+	if strings.Contains(string(trace.Response), "secret") {
+		t.Error("response should be redacted:", string(trace.Response))
+	}
+	if trace.MeetingID != "meeting23" {
+		t.Error("unexpected meeting id:", trace.MeetingID)
+	}
+}
+
+func TestTracerRecord(t *testing.T) {
+	tracer, err := NewTracer(t.TempDir(), []string{"meeting23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	httpReq, _ := http.NewRequest("GET", "http://bbb/api/end", nil)
+	req := &Request{
+		Request:  httpReq,
+		Resource: ResourceEnd,
+		Params:   Params{ParamMeetingID: "meeting23"},
+	}
+	if !tracer.Traces(req) {
+		t.Fatal("expected meeting to be traced")
+	}
+
+	for i := 0; i < 2; i++ {
+		trace := NewTrace(req, http.StatusOK, []byte("<response/>"), 0)
+		if err := tracer.Record(trace); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	traces, err := ReadTraces(tracer.Filename("meeting23"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(traces) != 2 {
+		t.Error("unexpected traces:", traces)
+	}
+	if traces[0].Resource != ResourceEnd {
+		t.Error("unexpected resource:", traces[0].Resource)
+	}
+}
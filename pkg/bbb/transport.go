@@ -0,0 +1,204 @@
+package bbb
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/net/http2"
+)
+
+// UseH2C enables HTTP/2 without TLS (h2c) for backends
+// with a plain http:// host, e.g. behind a local nginx.
+// Backends with https:// hosts negotiate HTTP/2 with ALPN.
+var UseH2C = false
+
+// Connection metrics of the backend requests
+var (
+	BackendRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "backend_requests_total",
+			Help: "Number of requests to a backend",
+		},
+		[]string{
+			// Backend host
+			"backend",
+			// HTTP protocol, e.g. HTTP/2.0
+			"protocol",
+			// Connection is either "new" or "reused"
+			"connection",
+		})
+
+	BackendTLSHandshakes = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name: "backend_tls_handshake_seconds",
+			Help: "Duration of TLS handshakes with a backend",
+		},
+		[]string{
+			// Backend host
+			"backend",
+		})
+)
+
+// backendTransport uses h2c for plain http requests
+// if enabled and the default transport otherwise.
+type backendTransport struct {
+	tls *http.Transport
+	h2c *http2.Transport
+}
+
+// RoundTrip implements the http.RoundTripper interface
+func (t *backendTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if UseH2C && req.URL.Scheme == "http" && !t.proxied(req) {
+		return t.h2c.RoundTrip(req)
+	}
+	return t.tls.RoundTrip(req)
+}
+
+// proxied is true if the request is sent through a
+// proxy. The h2c transport can only connect directly.
+func (t *backendTransport) proxied(req *http.Request) bool {
+	if t.tls.Proxy == nil {
+		return false
+	}
+	proxy, err := t.tls.Proxy(req)
+	return err != nil || proxy != nil
+}
+
+// newBackendTransport creates a transport for backend
+// clients. The TLS config is optional, requests are
+// sent directly if the proxy is nil.
+func newBackendTransport(
+	tlsConfig *tls.Config,
+	proxy proxyFunc,
+	dialer *backendDialer,
+) *backendTransport {
+	t := &backendTransport{
+		tls: &http.Transport{
+			Proxy:                 proxy,
+			DialContext:           dialer.DialContext,
+			TLSClientConfig:       tlsConfig,
+			ForceAttemptHTTP2:     true,
+			MaxIdleConnsPerHost:   20,
+			IdleConnTimeout:       300 * time.Second,
+			TLSHandshakeTimeout:   10 * time.Second,
+			ExpectContinueTimeout: 1 * time.Second,
+		},
+		h2c: &http2.Transport{
+			AllowHTTP: true,
+			DialTLS: func(
+				network, addr string, cfg *tls.Config,
+			) (net.Conn, error) {
+				ctx, cancel := context.WithTimeout(
+					context.Background(), 10*time.Second)
+				defer cancel()
+				return dialer.DialContext(ctx, network, addr)
+			},
+			ReadIdleTimeout: 30 * time.Second,
+		},
+	}
+	dialer.closeIdle = func() {
+		t.tls.CloseIdleConnections()
+		t.h2c.CloseIdleConnections()
+	}
+	return t
+}
+
+// newBackendConn creates a http client for backends
+func newBackendConn(
+	tlsConfig *tls.Config,
+	proxy proxyFunc,
+	dialer *backendDialer,
+) *http.Client {
+	return &http.Client{
+		Transport: newBackendTransport(tlsConfig, proxy, dialer),
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse // Thou shalt not follow redirects
+		},
+	}
+}
+
+// sharedConn is the http client used for all backends
+// without connection options, so connections are reused.
+var sharedConn = newBackendConn(nil, func(
+	req *http.Request,
+) (*url.URL, error) {
+	return backendProxy(req)
+}, newBackendDialer())
+
+// certificateExpiry is the expiry of the certificate
+// chain of each backend host seen in the last handshake.
+var certificateExpiry sync.Map
+
+// recordCertificateExpiry remembers the earliest expiry
+// of the certificates presented by the backend host.
+func recordCertificateExpiry(host string, cs tls.ConnectionState) {
+	var expiry time.Time
+	for _, cert := range cs.PeerCertificates {
+		if expiry.IsZero() || cert.NotAfter.Before(expiry) {
+			expiry = cert.NotAfter
+		}
+	}
+	if !expiry.IsZero() {
+		certificateExpiry.Store(host, expiry)
+	}
+}
+
+// CertificateExpiry retrieves the time the certificate of
+// the backend host expires. The expiry is only known after
+// a TLS handshake with the backend by this instance.
+func CertificateExpiry(host string) (time.Time, bool) {
+	expiry, ok := certificateExpiry.Load(host)
+	if !ok {
+		return time.Time{}, false
+	}
+	return expiry.(time.Time), true
+}
+
+// connectionState is collected while performing
+// a request to a backend.
+type connectionState struct {
+	reused bool
+}
+
+// withConnectionTrace adds a trace to the context
+// observing the connection used for the request.
+func withConnectionTrace(
+	ctx context.Context, host string,
+) (context.Context, *connectionState) {
+	state := &connectionState{}
+	var tlsStart time.Time
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			state.reused = info.Reused
+		},
+		TLSHandshakeStart: func() {
+			tlsStart = time.Now()
+		},
+		TLSHandshakeDone: func(cs tls.ConnectionState, err error) {
+			BackendTLSHandshakes.
+				WithLabelValues(host).
+				Observe(time.Since(tlsStart).Seconds())
+			if err == nil {
+				recordCertificateExpiry(host, cs)
+			}
+		},
+	}
+	return httptrace.WithClientTrace(ctx, trace), state
+}
+
+// observe counts the request with the protocol
+// and connection reuse.
+func (s *connectionState) observe(host string, res *http.Response) {
+	conn := "new"
+	if s.reused {
+		conn = "reused"
+	}
+	BackendRequests.WithLabelValues(host, res.Proto, conn).Inc()
+}
@@ -0,0 +1,37 @@
+package bbb
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSharedConnReuse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("<response></response>"))
+		}))
+	defer srv.Close()
+
+	get := func() *connectionState {
+		ctx, state := withConnectionTrace(context.Background(), srv.URL)
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res, err := sharedConn.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ioutil.ReadAll(res.Body)
+		res.Body.Close()
+		return state
+	}
+
+	get()
+	if state := get(); !state.reused {
+		t.Error("expected the connection to be reused")
+	}
+}
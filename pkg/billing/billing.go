@@ -0,0 +1,172 @@
+package billing
+
+/*
+ Billing: The daily usage of the frontends is combined
+ with the pricing hints in the frontend settings:
+
+    {"billing": {"customer": "uni-42", "plan": "campus",
+                 "currency": "EUR",
+                 "prices": {"create": 0.5, "join": 0.01}}}
+
+ The report is encoded in a format (csv or json) and
+ exported to a directory or pushed to an HTTP endpoint.
+*/
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// A Line is the usage of a resource by a
+// frontend on a day.
+type Line struct {
+	Day         string  `json:"day"`
+	FrontendID  string  `json:"frontend_id"`
+	FrontendKey string  `json:"frontend_key"`
+	Customer    string  `json:"customer"`
+	Plan        string  `json:"plan"`
+	Resource    string  `json:"resource"`
+	Requests    int64   `json:"requests"`
+	UnitPrice   float64 `json:"unit_price"`
+	Amount      float64 `json:"amount"`
+	Currency    string  `json:"currency"`
+}
+
+// A Report contains the billing lines of a period.
+// From and Until are days like 2021-06-01 and
+// are included in the period.
+type Report struct {
+	From  string  `json:"from"`
+	Until string  `json:"until"`
+	Lines []*Line `json:"lines"`
+}
+
+// NewReport combines the usage with the billing settings
+// of the frontends. The frontends are identified by ID.
+// Frontends without billing settings are included
+// without prices.
+func NewReport(
+	from, until string,
+	usage []*store.FrontendUsage,
+	settings map[string]*store.BillingSettings,
+) *Report {
+	lines := make([]*Line, 0, len(usage))
+	for _, u := range usage {
+		line := &Line{
+			Day:         u.Day,
+			FrontendID:  u.FrontendID,
+			FrontendKey: u.FrontendKey,
+			Resource:    u.Resource,
+			Requests:    u.Requests,
+		}
+		if s := settings[u.FrontendID]; s != nil {
+			line.Customer = s.Customer
+			line.Plan = s.Plan
+			line.Currency = s.Currency
+			line.UnitPrice = s.Prices[u.Resource]
+			line.Amount = line.UnitPrice * float64(u.Requests)
+		}
+		lines = append(lines, line)
+	}
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].Day != lines[j].Day {
+			return lines[i].Day < lines[j].Day
+		}
+		if lines[i].FrontendKey != lines[j].FrontendKey {
+			return lines[i].FrontendKey < lines[j].FrontendKey
+		}
+		return lines[i].Resource < lines[j].Resource
+	})
+	return &Report{
+		From:  from,
+		Until: until,
+		Lines: lines,
+	}
+}
+
+// A Format encodes a report
+type Format interface {
+	// Name is used for selecting the format
+	Name() string
+
+	// ContentType of the encoded report
+	ContentType() string
+
+	Encode(w io.Writer, r *Report) error
+}
+
+// Formats are the available report formats by name
+var Formats = map[string]Format{}
+
+// RegisterFormat makes a format available
+func RegisterFormat(f Format) {
+	Formats[f.Name()] = f
+}
+
+// GetFormat looks up a format by name
+func GetFormat(name string) (Format, error) {
+	f, ok := Formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown billing format: %s", name)
+	}
+	return f, nil
+}
+
+func init() {
+	RegisterFormat(CSVFormat{})
+	RegisterFormat(JSONFormat{})
+}
+
+// CSVFormat encodes the lines as CSV with a header
+type CSVFormat struct{}
+
+// Name implements the Format interface
+func (CSVFormat) Name() string { return "csv" }
+
+// ContentType implements the Format interface
+func (CSVFormat) ContentType() string { return "text/csv" }
+
+// Encode implements the Format interface
+func (CSVFormat) Encode(w io.Writer, r *Report) error {
+	out := csv.NewWriter(w)
+	out.Write([]string{
+		"day", "frontend_id", "frontend_key", "customer", "plan",
+		"resource", "requests", "unit_price", "amount", "currency",
+	})
+	for _, l := range r.Lines {
+		out.Write([]string{
+			l.Day,
+			l.FrontendID,
+			l.FrontendKey,
+			l.Customer,
+			l.Plan,
+			l.Resource,
+			strconv.FormatInt(l.Requests, 10),
+			strconv.FormatFloat(l.UnitPrice, 'f', -1, 64),
+			strconv.FormatFloat(l.Amount, 'f', -1, 64),
+			l.Currency,
+		})
+	}
+	out.Flush()
+	return out.Error()
+}
+
+// JSONFormat encodes the report as JSON
+type JSONFormat struct{}
+
+// Name implements the Format interface
+func (JSONFormat) Name() string { return "json" }
+
+// ContentType implements the Format interface
+func (JSONFormat) ContentType() string { return "application/json" }
+
+// Encode implements the Format interface
+func (JSONFormat) Encode(w io.Writer, r *Report) error {
+	return json.NewEncoder(w).Encode(r)
+}
@@ -0,0 +1,131 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func testReport() *Report {
+	usage := []*store.FrontendUsage{
+		{
+			FrontendID:  "f2",
+			FrontendKey: "frontend2",
+			Day:         "2021-06-01",
+			Resource:    "join",
+			Requests:    10,
+		},
+		{
+			FrontendID:  "f1",
+			FrontendKey: "frontend1",
+			Day:         "2021-06-01",
+			Resource:    "join",
+			Requests:    200,
+		},
+		{
+			FrontendID:  "f1",
+			FrontendKey: "frontend1",
+			Day:         "2021-06-01",
+			Resource:    "create",
+			Requests:    4,
+		},
+	}
+	settings := map[string]*store.BillingSettings{
+		"f1": {
+			Customer: "uni-42",
+			Plan:     "campus",
+			Currency: "EUR",
+			Prices: map[string]float64{
+				"create": 0.5,
+				"join":   0.01,
+			},
+		},
+	}
+	return NewReport("2021-06-01", "2021-06-01", usage, settings)
+}
+
+func TestNewReport(t *testing.T) {
+	r := testReport()
+	if len(r.Lines) != 3 {
+		t.Fatal("unexpected lines:", r.Lines)
+	}
+	l := r.Lines[0]
+	if l.FrontendKey != "frontend1" || l.Resource != "create" {
+		t.Error("unexpected order:", l)
+	}
+	if l.Amount != 2 {
+		t.Error("unexpected amount:", l.Amount)
+	}
+	if l.Customer != "uni-42" || l.Currency != "EUR" {
+		t.Error("unexpected billing settings:", l)
+	}
+	l = r.Lines[2]
+	if l.FrontendKey != "frontend2" || l.Amount != 0 || l.Customer != "" {
+		t.Error("frontend without settings should not be priced:", l)
+	}
+}
+
+func TestCSVFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := (CSVFormat{}).Encode(buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatal("unexpected csv:", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "day,frontend_id") {
+		t.Error("unexpected header:", lines[0])
+	}
+	expected := "2021-06-01,f1,frontend1,uni-42,campus,join,200,0.01,2,EUR"
+	if lines[2] != expected {
+		t.Error("unexpected line:", lines[2])
+	}
+}
+
+func TestJSONFormat(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := (JSONFormat{}).Encode(buf, testReport()); err != nil {
+		t.Fatal(err)
+	}
+	r := &Report{}
+	if err := json.Unmarshal(buf.Bytes(), r); err != nil {
+		t.Fatal(err)
+	}
+	if r.From != "2021-06-01" || len(r.Lines) != 3 {
+		t.Error("unexpected report:", r)
+	}
+}
+
+func TestGetFormat(t *testing.T) {
+	if _, err := GetFormat("csv"); err != nil {
+		t.Error(err)
+	}
+	if _, err := GetFormat("xlsx"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
+
+func TestFileExporter(t *testing.T) {
+	dir := t.TempDir()
+	exporter := NewExporter(dir, JSONFormat{})
+	if err := exporter.Export(context.Background(), testReport()); err != nil {
+		t.Fatal(err)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatal("unexpected files:", files)
+	}
+	if files[0].Name() != "billing-2021-06-01.json" {
+		t.Error("unexpected filename:", filepath.Join(dir, files[0].Name()))
+	}
+}
@@ -0,0 +1,217 @@
+package billing
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// An Exporter delivers an encoded report
+type Exporter interface {
+	Export(ctx context.Context, r *Report) error
+}
+
+// NewExporter creates an exporter for the target.
+// Targets starting with http:// or https:// receive
+// the report as a POST request, all other targets
+// are directories.
+func NewExporter(target string, format Format) Exporter {
+	if strings.HasPrefix(target, "http://") ||
+		strings.HasPrefix(target, "https://") {
+		return &HTTPExporter{
+			URL:    target,
+			Format: format,
+			Client: &http.Client{Timeout: 30 * time.Second},
+		}
+	}
+	return &FileExporter{
+		Dir:    target,
+		Format: format,
+	}
+}
+
+// FileExporter writes the report into a directory.
+// The filename is derived from the period.
+type FileExporter struct {
+	Dir    string
+	Format Format
+}
+
+// Filename of the report
+func (e *FileExporter) Filename(r *Report) string {
+	name := "billing-" + r.From
+	if r.Until != r.From {
+		name += "_" + r.Until
+	}
+	return filepath.Join(e.Dir, name+"."+e.Format.Name())
+}
+
+// Export writes the report to a temporary file,
+// which is renamed when complete.
+func (e *FileExporter) Export(ctx context.Context, r *Report) error {
+	filename := e.Filename(r)
+	tmp, err := os.CreateTemp(e.Dir, ".billing-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if err := e.Format.Encode(tmp, r); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
+
+// HTTPExporter posts the report to an endpoint
+type HTTPExporter struct {
+	URL    string
+	Format Format
+	Client *http.Client
+}
+
+// Export posts the encoded report
+func (e *HTTPExporter) Export(ctx context.Context, r *Report) error {
+	body := &bytes.Buffer{}
+	if err := e.Format.Encode(body, r); err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, body)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", e.Format.ContentType())
+	res, err := e.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("billing export rejected: %s", res.Status)
+	}
+	return nil
+}
+
+// dayFormat is the format of the days in a report
+const dayFormat = "2006-01-02"
+
+// BuildReport loads the usage of the frontends in the
+// period and combines it with their billing settings.
+func BuildReport(
+	ctx context.Context,
+	tx pgx.Tx,
+	from, until time.Time,
+) (*Report, error) {
+	fromDay := from.UTC().Format(dayFormat)
+	untilDay := until.UTC().Format(dayFormat)
+	usage, err := store.GetFrontendUsage(ctx, tx, store.Q().
+		Where("frontend_usage.day >= ?::date", fromDay).
+		Where("frontend_usage.day <= ?::date", untilDay))
+	if err != nil {
+		return nil, err
+	}
+	frontends, err := store.GetFrontendStates(ctx, tx, store.Q())
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[string]*store.FrontendState, len(frontends))
+	for _, f := range frontends {
+		byID[f.ID] = f
+	}
+	// Frontends inherit the billing settings of the parent
+	settings := make(map[string]*store.BillingSettings, len(frontends))
+	for _, f := range frontends {
+		var parent *store.FrontendState
+		if f.ParentID != nil {
+			parent = byID[*f.ParentID]
+		}
+		settings[f.ID] = f.EffectiveSettings(parent).Billing
+	}
+	return NewReport(fromDay, untilDay, usage, settings), nil
+}
+
+// ExportDay exports the report of a day, unless
+// it was already exported by another instance. The day is
+// claimed in the same transaction, so a failed export
+// is retried.
+func ExportDay(
+	ctx context.Context,
+	exporter Exporter,
+	day time.Time,
+) (bool, error) {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	claimed, err := store.ClaimBillingExport(ctx, tx, day)
+	if err != nil || !claimed {
+		return false, err
+	}
+	report, err := BuildReport(ctx, tx, day, day)
+	if err != nil {
+		return false, err
+	}
+	if err := exporter.Export(ctx, report); err != nil {
+		return false, err
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ExportInterval is the interval in which the
+// export of the previous day is attempted.
+const ExportInterval = 15 * time.Minute
+
+// Start exports the usage of the previous day
+// periodically until the context is done. The usage
+// is flushed with a delay, so the day is exported
+// after the flush interval has passed.
+func Start(
+	ctx context.Context,
+	exporter Exporter,
+	delay time.Duration,
+) {
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now().UTC()
+		today := time.Date(
+			now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if now.Sub(today) > delay {
+			day := today.AddDate(0, 0, -1)
+			exported, err := ExportDay(ctx, exporter, day)
+			if err != nil {
+				log.Error().Err(err).
+					Str("day", day.Format(dayFormat)).
+					Msg("billing export failed")
+			} else if exported {
+				log.Info().
+					Str("day", day.Format(dayFormat)).
+					Msg("exported billing report")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
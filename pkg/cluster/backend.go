@@ -37,11 +37,27 @@ type Backend struct {
 	client *bbb.Client
 }
 
+// minLatencySamples is the number of recent requests
+// required for estimating the latency of a backend.
+const minLatencySamples = 5
+
 // NewBackend creates a new backend instance with
 // a fresh bbb client.
 func NewBackend(state *store.BackendState) *Backend {
+	// The timeouts are validated when the state is saved
+	timeouts, _ := state.Settings.RequestTimeouts()
+	bbb.SetBackendTimeouts(state.ID, timeouts)
+	client, err := bbb.NewBackendClient(
+		timeouts, state.ConnOptions())
+	if err != nil {
+		log.Error().
+			Err(err).
+			Str("backend", state.ID).
+			Msg("ignoring invalid backend connection options")
+		client = bbb.NewClientWithTimeouts(timeouts)
+	}
 	return &Backend{
-		client: bbb.NewClient(),
+		client: client,
 		state:  state,
 	}
 }
@@ -51,6 +67,12 @@ func (b *Backend) ID() string {
 	return b.state.ID
 }
 
+// IsNodeReady checks if the node agent of the
+// backend is alive and the node is ready.
+func (b *Backend) IsNodeReady() bool {
+	return b.state.IsNodeReady()
+}
+
 // Host retrievs the backend host
 func (b *Backend) Host() string {
 	if b.state.Backend == nil {
@@ -87,6 +109,33 @@ func (b *Backend) HasTags(tags []string) bool {
 	return true
 }
 
+// Canary retrievs the canary settings of the backend.
+// The settings are nil if the backend is not a canary.
+func (b *Backend) Canary() *store.CanarySettings {
+	return b.state.Settings.Canary
+}
+
+// IsCanary checks if the backend is marked as canary
+func (b *Backend) IsCanary() bool {
+	return b.Canary() != nil
+}
+
+// Latency estimates the current latency of the backend
+// as the 95th percentile of the recent requests. Without
+// enough requests, the latency measured by the node
+// state refresh is used.
+func (b *Backend) Latency() time.Duration {
+	if b.state.Backend == nil {
+		return b.state.Latency
+	}
+	p95, n := bbb.Latencies.Percentile(
+		b.state.Backend.Host, 0.95, time.Now())
+	if n < minLatencySamples {
+		return b.state.Latency
+	}
+	return p95
+}
+
 // GetBackends retrievs all backends from the store,
 // filterable with a query.
 func GetBackends(
@@ -200,6 +249,39 @@ func (b *Backend) refreshNodeState(
 		return tx.Commit(ctx)
 	}
 
+	// Refuse node agents speaking an unsupported protocol
+	if err := CheckAgentProtocol(b.state.AgentProtocol); err != nil {
+		errMsg := err.Error()
+		b.state.LastError = &errMsg
+		b.state.NodeState = "error"
+
+		if err := b.state.Save(ctx, tx); err != nil {
+			return err
+		}
+		return tx.Commit(ctx)
+	}
+	if IsNewerAgentProtocol(b.state.AgentProtocol) {
+		log.Warn().
+			Str("backend", b.state.Backend.Host).
+			Int("protocol", b.state.AgentProtocol).
+			Msg("node agent is newer than this instance")
+	}
+
+	// Warm up backends, which were added or recovered,
+	// before they are marked as ready.
+	if b.state.AdminState == "ready" && b.state.NodeState != "ready" {
+		if err := b.warmUp(ctx); err != nil {
+			errMsg := fmt.Sprintf("warm-up failed: %s", err)
+			b.state.LastError = &errMsg
+			b.state.NodeState = "error"
+
+			if err := b.state.Save(ctx, tx); err != nil {
+				return err
+			}
+			return tx.Commit(ctx)
+		}
+	}
+
 	// Update state
 	b.state.SyncedAt = time.Now().UTC()
 	b.state.LastError = nil
@@ -359,6 +441,9 @@ func (b *Backend) Create(
 		req.Request.Header.Set("content-type", "application/xml")
 	}
 
+	// Rewrite deprecated parameters for the BBB version
+	bbb.AdaptParams(req.Resource, req.Params, b.state.Version)
+
 	res, err := b.client.Do(ctx, req.WithBackend(b.state.Backend))
 	if err != nil {
 		return nil, err
@@ -380,14 +465,22 @@ func (b *Backend) Create(
 	if err != nil {
 		return nil, err
 	}
+	var labelSettings *store.MeetingLabelsSettings
+	if frontend := FrontendFromContext(ctx); frontend != nil {
+		labelSettings = frontend.Settings().MeetingLabels
+	}
+	labels := MeetingLabels(req.Params, labelSettings)
+
 	if meetingState == nil {
-		_, err = b.state.CreateMeetingState(ctx, tx, req.Frontend, createRes.Meeting)
+		_, err = b.state.CreateMeetingState(
+			ctx, tx, req.Frontend, createRes.Meeting, labels)
 		if err != nil {
 			return nil, err
 		}
 	} else {
 		// Update state, associate with backend and frontend
 		meetingState.Meeting = createRes.Meeting
+		meetingState.Labels = labels
 		meetingState.SyncedAt = time.Now().UTC()
 		if err := meetingState.Save(ctx, tx); err != nil {
 			return nil, err
@@ -398,9 +491,41 @@ func (b *Backend) Create(
 		return nil, err
 	}
 
+	if frontend := FrontendFromContext(ctx); frontend != nil {
+		meetingID, _ := req.Params.MeetingID()
+		Joins.PutMeeting(meetingID, frontend.ID(), b)
+	}
+
 	return createRes, nil
 }
 
+// PrewarmMeeting requests the meeting info of a freshly
+// created meeting. The meeting is set up on the node and
+// the meeting state is synced before the participants join.
+func (b *Backend) PrewarmMeeting(meetingID string) {
+	ctx, cancel := context.WithTimeout(
+		context.Background(), 30*time.Second)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("prewarm meeting: acquire connection")
+		return
+	}
+	defer conn.Release()
+	ctx = store.ContextWithConnection(ctx, conn)
+
+	req := bbb.GetMeetingInfoRequest(bbb.Params{
+		"meetingID": meetingID,
+	})
+	if _, err := b.GetMeetingInfo(ctx, req); err != nil {
+		log.Warn().
+			Err(err).
+			Str("backend", b.Host()).
+			Str("meetingID", meetingID).
+			Msg("could not prewarm meeting")
+	}
+}
+
 // Join via redirect: The client will receive a
 // redirect to the BBB backend and will join there directly.
 func (b *Backend) Join(
@@ -413,6 +538,7 @@ func (b *Backend) Join(
 	// does not work. The JSESSIONID cookie is not associtated with
 	// the backend domain and thus the sessionToken is not accepted
 	// as valid.
+	bbb.AdaptParams(req.Resource, req.Params, b.state.Version)
 	req = req.WithBackend(b.state.Backend)
 	url := req.URL()
 	body := templates.Redirect(url)
@@ -489,9 +615,24 @@ func (b *Backend) End(
 	if err != nil {
 		return nil, err
 	}
+	if meetingID, ok := req.Params.MeetingID(); ok {
+		Joins.RemoveMeeting(meetingID)
+	}
 	return res.(*bbb.EndResponse), err
 }
 
+// SendChatMessage posts a message to the chat of a meeting
+func (b *Backend) SendChatMessage(
+	ctx context.Context,
+	req *bbb.Request,
+) (*bbb.SendChatMessageResponse, error) {
+	res, err := b.client.Do(ctx, req.WithBackend(b.state.Backend))
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.SendChatMessageResponse), err
+}
+
 // GetMeetingInfo gets the meeting details
 func (b *Backend) GetMeetingInfo(
 	ctx context.Context,
@@ -552,6 +693,16 @@ func (b *Backend) GetMeetingInfo(
 	return res, nil
 }
 
+// Mirror sends a copy of a read-only request to the
+// backend. The response is not processed and the state
+// of the cluster is not updated.
+func (b *Backend) Mirror(
+	ctx context.Context,
+	req *bbb.Request,
+) (bbb.Response, error) {
+	return b.client.Do(ctx, req.WithBackend(b.state.Backend))
+}
+
 // GetMeetings retrieves a list of meetings
 func (b *Backend) GetMeetings(
 	ctx context.Context,
@@ -576,6 +727,20 @@ func (b *Backend) GetRecordings(
 	return res.(*bbb.GetRecordingsResponse), nil
 }
 
+// StreamRecordings retrieves a list of recordings,
+// which are decoded while the response is streamed.
+// The response must be streamed or closed.
+func (b *Backend) StreamRecordings(
+	ctx context.Context,
+	req *bbb.Request,
+) (*bbb.GetRecordingsResponse, error) {
+	res, err := b.client.Stream(ctx, req.WithBackend(b.state.Backend))
+	if err != nil {
+		return nil, err
+	}
+	return res.(*bbb.GetRecordingsResponse), nil
+}
+
 // PublishRecordings publishes a recording
 func (b *Backend) PublishRecordings(
 	ctx context.Context,
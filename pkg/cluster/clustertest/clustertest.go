@@ -0,0 +1,120 @@
+// Package clustertest provides helpers for testing request
+// and router middlewares without a database: frontends,
+// backends and requests, handlers recording the requests
+// passed down the chain, a fake BBB node and assertions
+// of responses against golden files.
+//
+// A table-driven test of a request middleware:
+//
+//	frontend := clustertest.NewFrontend(store.FrontendSettings{...})
+//	next := clustertest.NewHandler(nil)
+//	handler := SanitizeNames()(next.Handle)
+//	req := clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{...})
+//	res, err := handler(clustertest.NewContext(frontend), req)
+package clustertest
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Defaults of the frontends and backends
+const (
+	FrontendID     = "frontend1-id"
+	FrontendKey    = "frontend1"
+	FrontendSecret = "frontend1-secret"
+	BackendSecret  = "backend-secret"
+)
+
+// NewFrontend creates an active frontend with the
+// default key and secret and the settings.
+func NewFrontend(settings store.FrontendSettings) *cluster.Frontend {
+	return cluster.NewFrontend(&store.FrontendState{
+		ID:     FrontendID,
+		Active: true,
+		Frontend: &bbb.Frontend{
+			Key:    FrontendKey,
+			Secret: FrontendSecret,
+		},
+		Settings: settings,
+	})
+}
+
+// NewBackend creates a ready backend with the settings.
+// The host is derived from the ID, e.g. https://b1.example.net/...
+func NewBackend(id string, settings store.BackendSettings) *cluster.Backend {
+	return NewBackendWithHost(
+		id, "https://"+id+".example.net/bigbluebutton/api/", settings)
+}
+
+// NewBackendWithHost creates a ready backend with a host,
+// e.g. the URL of a fake node.
+func NewBackendWithHost(
+	id, host string,
+	settings store.BackendSettings,
+) *cluster.Backend {
+	return cluster.NewBackend(&store.BackendState{
+		ID:         id,
+		NodeState:  "ready",
+		AdminState: "ready",
+		LoadFactor: 1.0,
+		Backend: &bbb.Backend{
+			Host:   host,
+			Secret: BackendSecret,
+		},
+		Settings: settings,
+	})
+}
+
+// NewBackends creates ready backends with the IDs
+func NewBackends(ids ...string) []*cluster.Backend {
+	backends := make([]*cluster.Backend, 0, len(ids))
+	for _, id := range ids {
+		backends = append(backends, NewBackend(id, store.BackendSettings{}))
+	}
+	return backends
+}
+
+// NewRequest creates a request of the default frontend
+// for the resource. Create requests are POST requests.
+func NewRequest(resource string, params bbb.Params) *bbb.Request {
+	if params == nil {
+		params = bbb.Params{}
+	}
+	method := http.MethodGet
+	if resource == bbb.ResourceCreate {
+		method = http.MethodPost
+	}
+	path := "/bbb/" + FrontendKey + "/bigbluebutton/api/" + resource
+	return &bbb.Request{
+		Request: &http.Request{
+			Method: method,
+			URL: &url.URL{
+				Path:     path,
+				RawQuery: params.String(),
+			},
+			Header: http.Header{},
+		},
+		Resource: resource,
+		Params:   params,
+		Frontend: &bbb.Frontend{
+			Key:    FrontendKey,
+			Secret: FrontendSecret,
+		},
+	}
+}
+
+// NewContext creates a request context with the frontend.
+// The frontend may be nil.
+func NewContext(frontend *cluster.Frontend) context.Context {
+	ctx := cluster.NewRequestContext()
+	if frontend != nil {
+		ctx = cluster.ContextWithFrontend(ctx, frontend)
+	}
+	return ctx
+}
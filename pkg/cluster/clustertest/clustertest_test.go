@@ -0,0 +1,73 @@
+package clustertest
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestHandler(t *testing.T) {
+	h := NewHandler(nil)
+	req := NewRequest(bbb.ResourceJoin, bbb.Params{"meetingID": "m1"})
+	res, err := h.Handle(NewContext(nil), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.(*bbb.XMLResponse).Returncode != bbb.RetSuccess {
+		t.Error("unexpected response:", res)
+	}
+	if h.LastRequest() != req || len(h.Requests()) != 1 {
+		t.Error("expected the request to be recorded")
+	}
+}
+
+func TestRouter(t *testing.T) {
+	dropFirst := func(next cluster.RouterHandler) cluster.RouterHandler {
+		return func(
+			ctx context.Context,
+			backends []*cluster.Backend,
+			req *bbb.Request,
+		) ([]*cluster.Backend, error) {
+			return next(ctx, backends[1:], req)
+		}
+	}
+	r := NewRouter()
+	_, err := r.Route(
+		NewContext(NewFrontend(store.FrontendSettings{})),
+		dropFirst,
+		NewBackends("b1", "b2"),
+		NewRequest(bbb.ResourceCreate, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ids := r.BackendIDs(); len(ids) != 1 || ids[0] != "b2" {
+		t.Error("unexpected backends:", ids)
+	}
+}
+
+func TestNode(t *testing.T) {
+	node := NewNode()
+	defer node.Close()
+	node.Respond(bbb.ResourceIsMeetingRunning, `<response>
+<returncode>SUCCESS</returncode>
+<running>true</running>
+</response>`)
+
+	backend := node.Backend("b1")
+	req := NewRequest(bbb.ResourceIsMeetingRunning, bbb.Params{
+		"meetingID": "m1",
+	})
+	res, err := backend.IsMeetingRunning(context.Background(), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Running {
+		t.Error("expected the meeting to be running")
+	}
+	if node.Requests(bbb.ResourceIsMeetingRunning) != 1 {
+		t.Error("expected the request to be counted")
+	}
+}
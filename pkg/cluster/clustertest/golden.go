@@ -0,0 +1,41 @@
+package clustertest
+
+import (
+	"bytes"
+	"flag"
+	"io/ioutil"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// updateGolden rewrites the golden files with the
+// actual responses: go test ./... -args -update-golden
+var updateGolden = flag.Bool(
+	"update-golden", false, "update the golden files of the responses")
+
+// AssertGolden compares the marshaled response with the
+// golden file. The status is checked by the caller.
+func AssertGolden(t *testing.T, res bbb.Response, filename string) {
+	t.Helper()
+	if res == nil {
+		t.Fatal("response is nil")
+	}
+	data, err := res.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *updateGolden {
+		if err := ioutil.WriteFile(filename, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	golden, err := ioutil.ReadFile(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(data), bytes.TrimSpace(golden)) {
+		t.Errorf("response does not match %s:\n%s", filename, data)
+	}
+}
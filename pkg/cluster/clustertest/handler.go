@@ -0,0 +1,126 @@
+package clustertest
+
+import (
+	"context"
+	"sync"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+)
+
+// A Handler is the end of a request middleware chain.
+// It records the requests and returns the response.
+type Handler struct {
+	Response bbb.Response
+	Err      error
+
+	mtx      sync.Mutex
+	requests []*bbb.Request
+}
+
+// NewHandler creates a handler returning the response.
+// Without a response, a success response is returned.
+func NewHandler(res bbb.Response) *Handler {
+	if res == nil {
+		res = &bbb.XMLResponse{
+			Returncode: bbb.RetSuccess,
+		}
+	}
+	return &Handler{
+		Response: res,
+	}
+}
+
+// Handle implements a cluster.RequestHandler
+func (h *Handler) Handle(
+	ctx context.Context,
+	req *bbb.Request,
+) (bbb.Response, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	h.requests = append(h.requests, req)
+	return h.Response, h.Err
+}
+
+// Requests are the requests passed to the handler
+func (h *Handler) Requests() []*bbb.Request {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	requests := make([]*bbb.Request, len(h.requests))
+	copy(requests, h.requests)
+	return requests
+}
+
+// Called is true if a request was passed to the handler
+func (h *Handler) Called() bool {
+	return len(h.Requests()) > 0
+}
+
+// LastRequest is the request passed last to the handler
+func (h *Handler) LastRequest() *bbb.Request {
+	requests := h.Requests()
+	if len(requests) == 0 {
+		return nil
+	}
+	return requests[len(requests)-1]
+}
+
+// A Router is the end of a router middleware chain.
+// It records the backends passed by the middlewares.
+type Router struct {
+	Err error
+
+	mtx      sync.Mutex
+	backends [][]*cluster.Backend
+}
+
+// NewRouter creates a router handler
+func NewRouter() *Router {
+	return &Router{}
+}
+
+// Handle implements a cluster.RouterHandler, returning
+// the backends selected by the middlewares.
+func (r *Router) Handle(
+	ctx context.Context,
+	backends []*cluster.Backend,
+	req *bbb.Request,
+) ([]*cluster.Backend, error) {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	r.backends = append(r.backends, backends)
+	return backends, r.Err
+}
+
+// Backends are the backends passed to the router
+// in the last call.
+func (r *Router) Backends() []*cluster.Backend {
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if len(r.backends) == 0 {
+		return nil
+	}
+	return r.backends[len(r.backends)-1]
+}
+
+// BackendIDs are the IDs of the backends passed
+// in the last call, for comparing the selection.
+func (r *Router) BackendIDs() []string {
+	backends := r.Backends()
+	ids := make([]string, 0, len(backends))
+	for _, b := range backends {
+		ids = append(ids, b.ID())
+	}
+	return ids
+}
+
+// Route passes the backends through the router
+// middleware to the router handler.
+func (r *Router) Route(
+	ctx context.Context,
+	middleware cluster.RouterMiddleware,
+	backends []*cluster.Backend,
+	req *bbb.Request,
+) ([]*cluster.Backend, error) {
+	return middleware(r.Handle)(ctx, backends, req)
+}
@@ -0,0 +1,86 @@
+package clustertest
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// unsupportedResponse is returned by a node
+// for resources without a response.
+const unsupportedResponse = `<response>
+<returncode>FAILED</returncode>
+<messageKey>unsupportedRequest</messageKey>
+<message>This request is not supported.</message>
+</response>`
+
+// A Node is a fake BBB node serving XML responses
+// by resource. Checksums are not verified.
+type Node struct {
+	server *httptest.Server
+
+	mtx       sync.Mutex
+	responses map[string]string
+	requests  map[string]int
+}
+
+// NewNode starts a fake BBB node. The node
+// must be closed after the test.
+func NewNode() *Node {
+	n := &Node{
+		responses: map[string]string{},
+		requests:  map[string]int{},
+	}
+	n.server = httptest.NewServer(http.HandlerFunc(n.serveHTTP))
+	return n
+}
+
+// Close shuts down the node
+func (n *Node) Close() {
+	n.server.Close()
+}
+
+// Host is the API endpoint of the node
+func (n *Node) Host() string {
+	return n.server.URL + "/bigbluebutton/api/"
+}
+
+// Respond sets the XML response of the resource
+func (n *Node) Respond(resource, body string) {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	n.responses[resource] = body
+}
+
+// Requests counts the requests of the resource
+func (n *Node) Requests(resource string) int {
+	n.mtx.Lock()
+	defer n.mtx.Unlock()
+	return n.requests[resource]
+}
+
+// Backend creates a ready backend for the node
+func (n *Node) Backend(id string) *cluster.Backend {
+	return NewBackendWithHost(id, n.Host(), store.BackendSettings{})
+}
+
+// serveHTTP responds with the XML of the resource
+func (n *Node) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	resource := strings.TrimPrefix(r.URL.Path, "/bigbluebutton/api/")
+	resource = strings.TrimPrefix(resource, "/bigbluebutton/api")
+
+	n.mtx.Lock()
+	n.requests[resource]++
+	body, ok := n.responses[resource]
+	n.mtx.Unlock()
+
+	if !ok {
+		body = unsupportedResponse
+	}
+	w.Header().Set("Content-Type", "text/xml")
+	w.Write([]byte(body))
+}
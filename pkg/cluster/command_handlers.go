@@ -0,0 +1,159 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// A CommandHandler executes the commands of an action.
+// The params of a command are decoded into the value
+// created by Params. Params implementing a Validate
+// method are checked before the command is executed.
+type CommandHandler struct {
+	Params func() interface{}
+	Exec   func(ctx context.Context, params interface{}) (interface{}, error)
+}
+
+// paramsValidator is implemented by params
+// which can be checked before the execution.
+type paramsValidator interface {
+	Validate() error
+}
+
+// CommandHandlers maps the actions to their handlers
+type CommandHandlers map[string]*CommandHandler
+
+// Register adds the handler for the action. An already
+// registered handler is replaced.
+func (h CommandHandlers) Register(action string, handler *CommandHandler) {
+	h[action] = handler
+}
+
+// Actions lists the registered actions
+func (h CommandHandlers) Actions() []string {
+	actions := make([]string, 0, len(h))
+	for action := range h {
+		actions = append(actions, action)
+	}
+	sort.Strings(actions)
+	return actions
+}
+
+// Handle decodes and validates the params of the command
+// and executes the handler of the action. It is used as
+// store.CommandHandler for the command queue.
+func (h CommandHandlers) Handle(
+	ctx context.Context,
+	cmd *store.Command,
+) (interface{}, error) {
+	handler, ok := h[cmd.Action]
+	if !ok {
+		return nil, ErrUnknownCommand
+	}
+	params := handler.Params()
+	if err := cmd.FetchParams(ctx, params); err != nil {
+		return nil, err
+	}
+	return handler.exec(ctx, cmd.Action, params)
+}
+
+// exec validates the params and runs the handler.
+// The outcome is reported with the duration.
+func (handler *CommandHandler) exec(
+	ctx context.Context,
+	action string,
+	params interface{},
+) (interface{}, error) {
+	if v, ok := params.(paramsValidator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	log.Debug().Str("cmd", action).Msg("EXEC")
+	start := time.Now()
+	result, err := handler.Exec(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+	log.Debug().
+		Str("cmd", action).
+		Dur("duration", time.Since(start)).
+		Msg("DONE")
+	return result, nil
+}
+
+// commandHandlers registers the handlers of
+// the commands executed by the controller.
+func (c *Controller) commandHandlers() CommandHandlers {
+	h := CommandHandlers{}
+
+	// Backend
+	h.Register(CmdUpdateNodeState, &CommandHandler{
+		Params: func() interface{} { return &UpdateNodeStateRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleUpdateNodeState(ctx, p.(*UpdateNodeStateRequest))
+		},
+	})
+	h.Register(CmdDecommissionBackend, &CommandHandler{
+		Params: func() interface{} { return &DecommissionBackendRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleDecommissionBackend(
+				ctx, p.(*DecommissionBackendRequest))
+		},
+	})
+	h.Register(CmdSyncBackendRecordings, &CommandHandler{
+		Params: func() interface{} { return &SyncBackendRecordingsRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleSyncBackendRecordings(
+				ctx, p.(*SyncBackendRecordingsRequest))
+		},
+	})
+	h.Register(CmdRecoverBackend, &CommandHandler{
+		Params: func() interface{} { return &RecoverBackendRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleRecoverBackend(ctx, p.(*RecoverBackendRequest))
+		},
+	})
+
+	// Meetings
+	h.Register(CmdUpdateMeetingState, &CommandHandler{
+		Params: func() interface{} { return &UpdateMeetingStateRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleUpdateMeetingState(
+				ctx, p.(*UpdateMeetingStateRequest))
+		},
+	})
+	h.Register(CmdEndAllMeetings, &CommandHandler{
+		Params: func() interface{} { return &EndAllMeetingsRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleEndAllMeetings(ctx, p.(*EndAllMeetingsRequest))
+		},
+	})
+	h.Register(CmdSyncBackendMeetings, &CommandHandler{
+		Params: func() interface{} { return &SyncBackendMeetingsRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleSyncBackendMeetings(
+				ctx, p.(*SyncBackendMeetingsRequest))
+		},
+	})
+	h.Register(CmdEndMeeting, &CommandHandler{
+		Params: func() interface{} { return &EndMeetingRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleEndMeeting(ctx, p.(*EndMeetingRequest))
+		},
+	})
+	h.Register(CmdBroadcastMessage, &CommandHandler{
+		Params: func() interface{} { return &BroadcastMessageRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			return c.handleBroadcastMessage(ctx, p.(*BroadcastMessageRequest))
+		},
+	})
+
+	return h
+}
@@ -0,0 +1,84 @@
+package cluster
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestCommandHandlersUnknown(t *testing.T) {
+	h := CommandHandlers{}
+	_, err := h.Handle(context.Background(), &store.Command{
+		Action: "collect_logs",
+	})
+	if err != ErrUnknownCommand {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestCommandHandlerExec(t *testing.T) {
+	executed := false
+	handler := &CommandHandler{
+		Params: func() interface{} { return &EndMeetingRequest{} },
+		Exec: func(ctx context.Context, p interface{}) (interface{}, error) {
+			executed = true
+			return p.(*EndMeetingRequest).ID, nil
+		},
+	}
+
+	// Invalid params are rejected
+	_, err := handler.exec(
+		context.Background(), CmdEndMeeting, &EndMeetingRequest{})
+	verr, ok := err.(store.ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error, got:", err)
+	}
+	if _, ok := verr["id"]; !ok {
+		t.Error("id should be required:", verr)
+	}
+	if executed {
+		t.Error("handler should not be executed")
+	}
+
+	res, err := handler.exec(
+		context.Background(), CmdEndMeeting, &EndMeetingRequest{ID: "m23"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.(string) != "m23" {
+		t.Error("unexpected result:", res)
+	}
+}
+
+func TestControllerCommandHandlers(t *testing.T) {
+	c := NewController()
+	for _, action := range []string{
+		CmdUpdateNodeState,
+		CmdUpdateMeetingState,
+		CmdSyncBackendMeetings,
+		CmdSyncBackendRecordings,
+		CmdRecoverBackend,
+	} {
+		handler, ok := c.handlers[action]
+		if !ok {
+			t.Error("missing handler for:", action)
+			continue
+		}
+		if _, ok := handler.Params().(paramsValidator); !ok {
+			t.Error("params should be validated:", action)
+		}
+		if reflect.TypeOf(handler.Params()) !=
+			reflect.TypeOf(commandTypes[action].params) {
+			t.Error("params do not match the schema:", action)
+		}
+	}
+	// Diagnostics are collected by the node agent
+	if _, ok := c.handlers[CmdCollectDiagnostics]; ok {
+		t.Error("unexpected handler for:", CmdCollectDiagnostics)
+	}
+	if len(c.handlers.Actions()) != len(c.handlers) {
+		t.Error("unexpected actions:", c.handlers.Actions())
+	}
+}
@@ -0,0 +1,184 @@
+package cluster
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// A JSONSchema describes the encoding of a value
+type JSONSchema struct {
+	Type                 interface{}            `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Properties           map[string]*JSONSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *JSONSchema            `json:"items,omitempty"`
+	AdditionalProperties *JSONSchema            `json:"additionalProperties,omitempty"`
+}
+
+// A CommandSchema describes the params and the
+// result of the commands of an action.
+type CommandSchema struct {
+	Action string      `json:"action"`
+	Params *JSONSchema `json:"params"`
+	Result *JSONSchema `json:"result"`
+}
+
+// commandType declares the Go types of the
+// params and the result of an action.
+type commandType struct {
+	params interface{}
+	result interface{}
+}
+
+// commandTypes of all actions, including the
+// commands executed by the node agent.
+var commandTypes = map[string]commandType{
+	CmdUpdateNodeState: {
+		&UpdateNodeStateRequest{}, false},
+	CmdDecommissionBackend: {
+		&DecommissionBackendRequest{}, false},
+	CmdSyncBackendRecordings: {
+		&SyncBackendRecordingsRequest{}, &RecordingsReconciliation{}},
+	CmdRecoverBackend: {
+		&RecoverBackendRequest{}, &Recovery{}},
+	CmdUpdateMeetingState: {
+		&UpdateMeetingStateRequest{}, false},
+	CmdEndAllMeetings: {
+		&EndAllMeetingsRequest{}, false},
+	CmdSyncBackendMeetings: {
+		&SyncBackendMeetingsRequest{}, 0},
+	CmdEndMeeting: {
+		&EndMeetingRequest{}, false},
+	CmdBroadcastMessage: {
+		&BroadcastMessageRequest{}, false},
+	CmdCollectDiagnostics: {
+		&CollectDiagnosticsRequest{}, &store.BackendDiagnostics{}},
+}
+
+// CommandSchemas describes the params and
+// results of the commands by action.
+func CommandSchemas() []*CommandSchema {
+	schemas := make([]*CommandSchema, 0, len(commandTypes))
+	for action, t := range commandTypes {
+		params := jsonSchemaOf(reflect.TypeOf(t.params))
+		params.Type = "object"
+		params.Required = requiredParams(t.params)
+		schemas = append(schemas, &CommandSchema{
+			Action: action,
+			Params: params,
+			Result: jsonSchemaOf(reflect.TypeOf(t.result)),
+		})
+	}
+	sort.Slice(schemas, func(i, j int) bool {
+		return schemas[i].Action < schemas[j].Action
+	})
+	return schemas
+}
+
+// ValidateCommand checks the params of a command before
+// it is queued: The action must be known and the params
+// must be of the declared type and valid.
+func ValidateCommand(cmd *store.Command) error {
+	t, ok := commandTypes[cmd.Action]
+	if !ok {
+		return ErrUnknownCommand
+	}
+	if reflect.TypeOf(cmd.Params) != reflect.TypeOf(t.params) {
+		return fmt.Errorf(
+			"unexpected params for %s: %T", cmd.Action, cmd.Params)
+	}
+	if v, ok := cmd.Params.(paramsValidator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// requiredParams are the fields rejected by the
+// validation of empty params.
+func requiredParams(params interface{}) []string {
+	v, ok := reflect.New(
+		reflect.TypeOf(params).Elem()).Interface().(paramsValidator)
+	if !ok {
+		return nil
+	}
+	verr, ok := v.Validate().(store.ValidationError)
+	if !ok {
+		return nil
+	}
+	required := make([]string, 0, len(verr))
+	for field := range verr {
+		required = append(required, field)
+	}
+	sort.Strings(required)
+	return required
+}
+
+// jsonSchemaOf derives the schema from the type
+// and the json tags of the fields.
+func jsonSchemaOf(t reflect.Type) *JSONSchema {
+	if t == nil {
+		return &JSONSchema{}
+	}
+	if t.Kind() == reflect.Ptr {
+		s := jsonSchemaOf(t.Elem())
+		if typ, ok := s.Type.(string); ok {
+			s.Type = []string{typ, "null"}
+		}
+		return s
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return &JSONSchema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &JSONSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16,
+		reflect.Uint32, reflect.Uint64:
+		return &JSONSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &JSONSchema{Type: "number"}
+	case reflect.String:
+		return &JSONSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &JSONSchema{Type: "string", Format: "byte"}
+		}
+		return &JSONSchema{Type: "array", Items: jsonSchemaOf(t.Elem())}
+	case reflect.Map:
+		return &JSONSchema{
+			Type:                 "object",
+			AdditionalProperties: jsonSchemaOf(t.Elem()),
+		}
+	case reflect.Struct:
+		s := &JSONSchema{
+			Type:       "object",
+			Properties: map[string]*JSONSchema{},
+		}
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue // unexported
+			}
+			name := f.Name
+			if tag := f.Tag.Get("json"); tag != "" {
+				tag = strings.Split(tag, ",")[0]
+				if tag == "-" {
+					continue
+				}
+				if tag != "" {
+					name = tag
+				}
+			}
+			s.Properties[name] = jsonSchemaOf(f.Type)
+		}
+		return s
+	}
+	// Interfaces can be anything
+	return &JSONSchema{}
+}
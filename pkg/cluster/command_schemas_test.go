@@ -0,0 +1,86 @@
+package cluster
+
+import (
+	"reflect"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestCommandSchemas(t *testing.T) {
+	schemas := CommandSchemas()
+	if len(schemas) != len(commandTypes) {
+		t.Fatal("unexpected schemas:", schemas)
+	}
+	var broadcast, recovery *CommandSchema
+	for _, s := range schemas {
+		switch s.Action {
+		case CmdBroadcastMessage:
+			broadcast = s
+		case CmdRecoverBackend:
+			recovery = s
+		}
+	}
+	if broadcast == nil || recovery == nil {
+		t.Fatal("missing schemas")
+	}
+	if !reflect.DeepEqual(
+		broadcast.Params.Required, []string{"meeting_id", "message"}) {
+		t.Error("unexpected required params:", broadcast.Params.Required)
+	}
+	if broadcast.Params.Properties["message"].Type != "string" {
+		t.Error("unexpected params:", broadcast.Params.Properties)
+	}
+	if broadcast.Result.Type != "boolean" {
+		t.Error("unexpected result:", broadcast.Result)
+	}
+	if recovery.Result.Properties["meetings"].Type != "integer" {
+		t.Error("unexpected result:", recovery.Result.Properties)
+	}
+}
+
+func TestJSONSchemaOf(t *testing.T) {
+	s := jsonSchemaOf(reflect.TypeOf(&store.BackendDiagnostics{}))
+	if !reflect.DeepEqual(s.Type, []string{"object", "null"}) {
+		t.Error("unexpected type:", s.Type)
+	}
+	if _, ok := s.Properties["Bundle"]; ok {
+		t.Error("ignored fields should be skipped")
+	}
+	if s.Properties["created_at"].Format != "date-time" {
+		t.Error("unexpected time:", s.Properties["created_at"])
+	}
+	if !reflect.DeepEqual(
+		s.Properties["command_id"].Type, []string{"string", "null"}) {
+		t.Error("unexpected pointer:", s.Properties["command_id"])
+	}
+	s = jsonSchemaOf(reflect.TypeOf(&RecordingsReconciliation{}))
+	if s.Properties["only_store"].Type != "array" {
+		t.Error("unexpected slice:", s.Properties["only_store"])
+	}
+}
+
+func TestValidateCommand(t *testing.T) {
+	err := ValidateCommand(EndMeeting(&EndMeetingRequest{}))
+	if _, ok := err.(store.ValidationError); !ok {
+		t.Error("expected a validation error, got:", err)
+	}
+	err = ValidateCommand(&store.Command{
+		Action: CmdEndMeeting,
+		Params: &EndAllMeetingsRequest{BackendID: "backend23"},
+	})
+	if err == nil {
+		t.Error("params of another type should be rejected")
+	}
+	err = ValidateCommand(&store.Command{Action: "collect_logs"})
+	if err != ErrUnknownCommand {
+		t.Error("unexpected error:", err)
+	}
+	err = ValidateCommand(BroadcastMessage(&BroadcastMessageRequest{
+		MeetingID: "meeting42",
+		Message:   "Maintenance in 5 minutes",
+	}))
+	if err != nil {
+		t.Error(err)
+	}
+}
@@ -3,21 +3,32 @@ package cluster
 // Command Creators
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"time"
 
+	"gitlab.com/infra.run/public/b3scale/pkg/cron"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
 // Commands that can be handled by the controller
 const (
 	// Backend
-	CmdUpdateNodeState     = "update_node_state"
-	CmdDecommissionBackend = "decommission_backend"
+	CmdUpdateNodeState       = "update_node_state"
+	CmdDecommissionBackend   = "decommission_backend"
+	CmdSyncBackendRecordings = "sync_backend_recordings"
+	CmdRecoverBackend        = "recover_backend"
 
 	// Meetings
-	CmdUpdateMeetingState = "update_meeting_state"
-	CmdEndAllMeetings     = "end_all_meetings"
+	CmdUpdateMeetingState  = "update_meeting_state"
+	CmdEndAllMeetings      = "end_all_meetings"
+	CmdSyncBackendMeetings = "sync_backend_meetings"
+	CmdEndMeeting          = "end_meeting"
+	CmdBroadcastMessage    = "broadcast_message"
+
+	// Node agent
+	CmdCollectDiagnostics = "collect_diagnostics"
 )
 
 var (
@@ -26,12 +37,32 @@ var (
 	ErrUnknownCommand = errors.New("command unknown")
 )
 
+// requireParams checks that the params are not empty.
+// The fields are named like the encoded params.
+func requireParams(fields ...string) error {
+	err := store.ValidationError{}
+	for i := 0; i+1 < len(fields); i += 2 {
+		if fields[i+1] == "" {
+			err.Add(fields[i], store.ErrFieldRequired)
+		}
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
 // DecommissionBackendRequest declares the removal
 // of a backend node from the cluster state.
 type DecommissionBackendRequest struct {
 	ID string `json:"id"`
 }
 
+// Validate checks the backend is present
+func (r *DecommissionBackendRequest) Validate() error {
+	return requireParams("id", r.ID)
+}
+
 // DecommissionBackend will remove a given cluster
 // backend from the state.
 func DecommissionBackend(req *DecommissionBackendRequest) *store.Command {
@@ -48,6 +79,11 @@ type UpdateNodeStateRequest struct {
 	ID string // the backend state id
 }
 
+// Validate checks the backend is present
+func (r *UpdateNodeStateRequest) Validate() error {
+	return requireParams("ID", r.ID)
+}
+
 // UpdateNodeState creates a update status command
 func UpdateNodeState(req *UpdateNodeStateRequest) *store.Command {
 	return &store.Command{
@@ -62,6 +98,11 @@ type UpdateMeetingStateRequest struct {
 	ID string // the meeting ID
 }
 
+// Validate checks the meeting is present
+func (r *UpdateMeetingStateRequest) Validate() error {
+	return requireParams("ID", r.ID)
+}
+
 // UpdateMeetingState makes a new meeting refresh command
 func UpdateMeetingState(
 	req *UpdateMeetingStateRequest,
@@ -79,6 +120,11 @@ type EndAllMeetingsRequest struct {
 	BackendID string
 }
 
+// Validate checks the backend is present
+func (r *EndAllMeetingsRequest) Validate() error {
+	return requireParams("BackendID", r.BackendID)
+}
+
 // EndAllMeetings will send end meeting api requests to all running meetings
 // on a backend. This can be usefull to force decommissioning.
 func EndAllMeetings(req *EndAllMeetingsRequest) *store.Command {
@@ -88,3 +134,315 @@ func EndAllMeetings(req *EndAllMeetingsRequest) *store.Command {
 		Deadline: store.NextDeadline(5 * time.Minute),
 	}
 }
+
+// SyncBackendMeetingsRequest contains parameters for the
+// sync backend meetings command.
+type SyncBackendMeetingsRequest struct {
+	BackendID string
+}
+
+// Validate checks the backend is present
+func (r *SyncBackendMeetingsRequest) Validate() error {
+	return requireParams("BackendID", r.BackendID)
+}
+
+// SyncBackendMeetings will refresh the state of all meetings
+// on a backend with meeting info requests.
+func SyncBackendMeetings(req *SyncBackendMeetingsRequest) *store.Command {
+	return &store.Command{
+		Action:   CmdSyncBackendMeetings,
+		Params:   req,
+		Deadline: store.NextDeadline(5 * time.Minute),
+	}
+}
+
+// SyncBackendRecordingsRequest contains parameters for
+// the sync backend recordings command.
+type SyncBackendRecordingsRequest struct {
+	BackendID string `json:"backend_id"`
+}
+
+// Validate checks the backend is present
+func (r *SyncBackendRecordingsRequest) Validate() error {
+	return requireParams("backend_id", r.BackendID)
+}
+
+// SyncBackendRecordings will compare the recordings of
+// a backend with the store and import missing recordings.
+func SyncBackendRecordings(
+	req *SyncBackendRecordingsRequest,
+) *store.Command {
+	return &store.Command{
+		Action:   CmdSyncBackendRecordings,
+		Params:   req,
+		Deadline: store.NextDeadline(5 * time.Minute),
+	}
+}
+
+// RecoverBackendRequest contains parameters for
+// the recover backend command.
+type RecoverBackendRequest struct {
+	BackendID string `json:"backend_id"`
+}
+
+// Validate checks the backend is present
+func (r *RecoverBackendRequest) Validate() error {
+	return requireParams("backend_id", r.BackendID)
+}
+
+// RecoverBackend will rebuild the meetings and recordings
+// of a backend in the store from the live state.
+func RecoverBackend(req *RecoverBackendRequest) *store.Command {
+	return &store.Command{
+		Action:   CmdRecoverBackend,
+		Params:   req,
+		Deadline: store.NextDeadline(5 * time.Minute),
+	}
+}
+
+// EndMeetingRequest contains parameters for the
+// end meeting command.
+type EndMeetingRequest struct {
+	ID string `json:"id"` // the meeting ID
+}
+
+// Validate checks the meeting is present
+func (r *EndMeetingRequest) Validate() error {
+	return requireParams("id", r.ID)
+}
+
+// EndMeeting will send an end request for the meeting
+// to the backend.
+func EndMeeting(req *EndMeetingRequest) *store.Command {
+	return &store.Command{
+		Action:   CmdEndMeeting,
+		Params:   req,
+		Deadline: store.NextDeadline(5 * time.Minute),
+	}
+}
+
+// BroadcastMessageRequest contains parameters for the
+// broadcast message command.
+type BroadcastMessageRequest struct {
+	MeetingID string `json:"meeting_id"`
+	Message   string `json:"message"`
+}
+
+// Validate checks the meeting and message are present
+func (r *BroadcastMessageRequest) Validate() error {
+	return requireParams(
+		"meeting_id", r.MeetingID,
+		"message", r.Message)
+}
+
+// BroadcastMessage posts a message to the chat
+// of a running meeting.
+func BroadcastMessage(req *BroadcastMessageRequest) *store.Command {
+	return &store.Command{
+		Action:   CmdBroadcastMessage,
+		Params:   req,
+		Deadline: store.NextDeadline(2 * time.Minute),
+	}
+}
+
+// CollectDiagnosticsRequest contains parameters for the
+// collect diagnostics command.
+type CollectDiagnosticsRequest struct {
+	BackendID string `json:"backend_id"`
+}
+
+// Validate checks the backend is present
+func (r *CollectDiagnosticsRequest) Validate() error {
+	return requireParams("backend_id", r.BackendID)
+}
+
+// CollectDiagnostics requests a diagnostic bundle from
+// the node agent of the backend. The command is executed
+// by the agent and not by the controller.
+func CollectDiagnostics(req *CollectDiagnosticsRequest) *store.Command {
+	handler := store.BackendAgentHandler(req.BackendID)
+	return &store.Command{
+		Action:   CmdCollectDiagnostics,
+		Params:   req,
+		Handler:  &handler,
+		Deadline: store.NextDeadline(5 * time.Minute),
+	}
+}
+
+// A CommandRequest requests a well known command,
+// e.g. through the API. Depending on the action
+// a backend or meeting is required.
+type CommandRequest struct {
+	Action    string `json:"action"`
+	BackendID string `json:"backend_id,omitempty"`
+	MeetingID string `json:"meeting_id,omitempty"`
+
+	// RunAt delays the command
+	RunAt *time.Time `json:"run_at,omitempty"`
+}
+
+// Validate checks if the action is known and the
+// required parameters are present.
+func (r *CommandRequest) Validate() error {
+	err := store.ValidationError{}
+	switch r.Action {
+	case CmdUpdateNodeState,
+		CmdDecommissionBackend,
+		CmdEndAllMeetings,
+		CmdSyncBackendMeetings,
+		CmdSyncBackendRecordings,
+		CmdRecoverBackend,
+		CmdCollectDiagnostics:
+		if r.BackendID == "" {
+			err.Add("backend_id", store.ErrFieldRequired)
+		}
+	case CmdUpdateMeetingState,
+		CmdEndMeeting:
+		if r.MeetingID == "" {
+			err.Add("meeting_id", store.ErrFieldRequired)
+		}
+	case "":
+		err.Add("action", store.ErrFieldRequired)
+	default:
+		err.Add("action", fmt.Sprintf("unknown action: %s", r.Action))
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// Command creates the command for the request
+// using the typed constructors.
+func (r *CommandRequest) Command() (*store.Command, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	cmd := r.command()
+	if cmd == nil {
+		return nil, ErrUnknownCommand
+	}
+	if err := ValidateCommand(cmd); err != nil {
+		return nil, err
+	}
+	cmd.RunAt = r.RunAt
+	return cmd, nil
+}
+
+// command uses the typed constructor of the action
+func (r *CommandRequest) command() *store.Command {
+	switch r.Action {
+	case CmdUpdateNodeState:
+		return UpdateNodeState(&UpdateNodeStateRequest{
+			ID: r.BackendID,
+		})
+	case CmdDecommissionBackend:
+		return DecommissionBackend(&DecommissionBackendRequest{
+			ID: r.BackendID,
+		})
+	case CmdEndAllMeetings:
+		return EndAllMeetings(&EndAllMeetingsRequest{
+			BackendID: r.BackendID,
+		})
+	case CmdSyncBackendMeetings:
+		return SyncBackendMeetings(&SyncBackendMeetingsRequest{
+			BackendID: r.BackendID,
+		})
+	case CmdSyncBackendRecordings:
+		return SyncBackendRecordings(&SyncBackendRecordingsRequest{
+			BackendID: r.BackendID,
+		})
+	case CmdRecoverBackend:
+		return RecoverBackend(&RecoverBackendRequest{
+			BackendID: r.BackendID,
+		})
+	case CmdCollectDiagnostics:
+		return CollectDiagnostics(&CollectDiagnosticsRequest{
+			BackendID: r.BackendID,
+		})
+	case CmdUpdateMeetingState:
+		return UpdateMeetingState(&UpdateMeetingStateRequest{
+			ID: r.MeetingID,
+		})
+	case CmdEndMeeting:
+		return EndMeeting(&EndMeetingRequest{
+			ID: r.MeetingID,
+		})
+	}
+	return nil
+}
+
+// NewCommandSchedule creates a schedule queueing the
+// requested command. The next run is the first time
+// matching the cron expression after now.
+func NewCommandSchedule(
+	name string,
+	expr string,
+	req *CommandRequest,
+	now time.Time,
+) (*store.CommandSchedule, error) {
+	verr := store.ValidationError{}
+	if name == "" {
+		verr.Add("name", store.ErrFieldRequired)
+	}
+	var next time.Time
+	schedule, err := cron.Parse(expr)
+	if err == nil {
+		next, err = schedule.Next(now)
+	}
+	if err != nil {
+		verr.Add("cron", err.Error())
+	}
+	if err := req.Validate(); err != nil {
+		for field, errs := range err.(store.ValidationError) {
+			for _, e := range errs {
+				verr.Add(field, e)
+			}
+		}
+	}
+	if len(verr) > 0 {
+		return nil, verr
+	}
+	return &store.CommandSchedule{
+		Name:   name,
+		Cron:   expr,
+		Action: req.Action,
+		Params: &CommandRequest{
+			Action:    req.Action,
+			BackendID: req.BackendID,
+			MeetingID: req.MeetingID,
+		},
+		NextRunAt: next,
+	}, nil
+}
+
+// ScheduledCommand advances the schedule to the next run
+// and creates the command of the schedule.
+func ScheduledCommand(
+	s *store.CommandSchedule,
+	now time.Time,
+) (*store.Command, error) {
+	schedule, err := cron.Parse(s.Cron)
+	if err != nil {
+		return nil, err
+	}
+	next, err := schedule.Next(now)
+	if err != nil {
+		return nil, err
+	}
+	lastRun := now.UTC()
+	s.LastRunAt = &lastRun
+	s.NextRunAt = next
+
+	data, err := json.Marshal(s.Params)
+	if err != nil {
+		return nil, err
+	}
+	req := &CommandRequest{}
+	if err := json.Unmarshal(data, req); err != nil {
+		return nil, err
+	}
+	req.Action = s.Action
+	req.RunAt = nil
+	return req.Command()
+}
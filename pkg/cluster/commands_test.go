@@ -0,0 +1,129 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestCommandRequestCommand(t *testing.T) {
+	req := &CommandRequest{
+		Action:    CmdSyncBackendMeetings,
+		BackendID: "backend23",
+	}
+	cmd, err := req.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Action != CmdSyncBackendMeetings {
+		t.Error("unexpected action:", cmd.Action)
+	}
+	params := cmd.Params.(*SyncBackendMeetingsRequest)
+	if params.BackendID != "backend23" {
+		t.Error("unexpected params:", params)
+	}
+	if cmd.Deadline.IsZero() {
+		t.Error("deadline should be set")
+	}
+
+	req = &CommandRequest{
+		Action:    CmdUpdateMeetingState,
+		MeetingID: "meeting42",
+	}
+	cmd, err = req.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Params.(*UpdateMeetingStateRequest).ID != "meeting42" {
+		t.Error("unexpected params:", cmd.Params)
+	}
+	if cmd.Handler != nil {
+		t.Error("unexpected handler:", *cmd.Handler)
+	}
+}
+
+func TestCommandRequestCollectDiagnostics(t *testing.T) {
+	req := &CommandRequest{
+		Action:    CmdCollectDiagnostics,
+		BackendID: "backend23",
+	}
+	cmd, err := req.Command()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The command is routed to the node agent
+	if cmd.Handler == nil || *cmd.Handler != "agent:backend23" {
+		t.Error("unexpected handler:", cmd.Handler)
+	}
+}
+
+func TestCommandRequestValidate(t *testing.T) {
+	err := (&CommandRequest{
+		Action: CmdEndAllMeetings,
+	}).Validate()
+	verr, ok := err.(store.ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error, got:", err)
+	}
+	if _, ok := verr["backend_id"]; !ok {
+		t.Error("backend_id should be required:", verr)
+	}
+
+	err = (&CommandRequest{
+		Action: "collect_logs",
+	}).Validate()
+	verr, ok = err.(store.ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error, got:", err)
+	}
+	if _, ok := verr["action"]; !ok {
+		t.Error("unknown action should be rejected:", verr)
+	}
+}
+
+func TestCommandSchedule(t *testing.T) {
+	now := time.Date(2021, 6, 2, 10, 17, 0, 0, time.UTC)
+	s, err := NewCommandSchedule("nightly-sync", "0 3 * * *", &CommandRequest{
+		Action:    CmdSyncBackendMeetings,
+		BackendID: "backend23",
+	}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.NextRunAt.Equal(time.Date(2021, 6, 3, 3, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected next run:", s.NextRunAt)
+	}
+
+	cmd, err := ScheduledCommand(s, s.NextRunAt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cmd.Action != CmdSyncBackendMeetings {
+		t.Error("unexpected action:", cmd.Action)
+	}
+	if cmd.Params.(*SyncBackendMeetingsRequest).BackendID != "backend23" {
+		t.Error("unexpected params:", cmd.Params)
+	}
+	if !s.NextRunAt.Equal(time.Date(2021, 6, 4, 3, 0, 0, 0, time.UTC)) {
+		t.Error("schedule should advance:", s.NextRunAt)
+	}
+	if s.LastRunAt == nil {
+		t.Error("last run should be set")
+	}
+}
+
+func TestNewCommandScheduleInvalid(t *testing.T) {
+	_, err := NewCommandSchedule("", "0 25 * * *", &CommandRequest{
+		Action: CmdEndAllMeetings,
+	}, time.Now())
+	verr, ok := err.(store.ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error, got:", err)
+	}
+	for _, field := range []string{"name", "cron", "backend_id"} {
+		if _, ok := verr[field]; !ok {
+			t.Error("expected an error for:", field)
+		}
+	}
+}
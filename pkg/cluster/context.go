@@ -8,9 +8,11 @@ type requestContextKey int
 
 // Context keys for Backends and Backend
 var (
-	backendsContextKey = requestContextKey(1)
-	backendContextKey  = requestContextKey(2)
-	frontendContextKey = requestContextKey(3)
+	backendsContextKey  = requestContextKey(1)
+	backendContextKey   = requestContextKey(2)
+	frontendContextKey  = requestContextKey(3)
+	requestIDContextKey = requestContextKey(4)
+	explainContextKey   = requestContextKey(5)
 )
 
 // NewRequestContext create a new context
@@ -68,3 +70,22 @@ func FrontendFromContext(ctx context.Context) *Frontend {
 	}
 	return frontend
 }
+
+// ContextWithRequestID creates a context with the
+// correlation ID of the request
+func ContextWithRequestID(
+	ctx context.Context, id string,
+) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+// RequestIDFromContext retrieves the correlation ID
+// of the request from a context. The result is empty
+// if the request has no ID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, ok := ctx.Value(requestIDContextKey).(string)
+	if !ok {
+		return ""
+	}
+	return id
+}
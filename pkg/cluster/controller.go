@@ -10,6 +10,8 @@ import (
 	"github.com/rs/zerolog/log"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
@@ -23,25 +25,48 @@ const (
 	NodeSyncInterval = 20 * time.Second
 )
 
+// CommandQueueAgeThreshold is the time after which a
+// waiting command is considered stuck and a warning
+// is logged. Use 0 to disable the warning.
+var CommandQueueAgeThreshold = time.Minute
+
+// CertificateExpiryWarning is the time before the expiry
+// of the certificate of a backend, when a notification
+// is sent. Use 0 to disable the notification.
+var CertificateExpiryWarning = 14 * 24 * time.Hour
+
+// HistoryRetention is the time the history is kept,
+// by config.RetentionUsage and config.RetentionAuditLog.
+// Without a retention the history is kept forever.
+var HistoryRetention = map[string]time.Duration{}
+
+// HistoryMaintenanceInterval is the interval in which
+// partitions are created and the history is pruned.
+const HistoryMaintenanceInterval = time.Hour
+
 // The Controller interfaces with the state of the cluster
 // providing methods for retrieving cluster backends and
 // frontends.
 //
 // The controller subscribes to commands.
 type Controller struct {
-	cmds *store.CommandQueue
+	cmds     *store.CommandQueue
+	handlers CommandHandlers
 
-	lastStartBackground time.Time
-	mtx                 sync.Mutex
+	lastStartBackground    time.Time
+	lastHistoryMaintenance time.Time
+	mtx                    sync.Mutex
 }
 
 // NewController will initialize the cluster controller
 // with a database connection. A BBB client will be created
 // which will be used by the backend instances.
 func NewController() *Controller {
-	return &Controller{
+	c := &Controller{
 		cmds: store.NewCommandQueue(),
 	}
+	c.handlers = c.commandHandlers()
+	return c
 }
 
 // Start the controller
@@ -128,46 +153,64 @@ func (c *Controller) StartBackground() {
 	if err := c.warnOfflineBackends(ctx); err != nil {
 		log.Error().Err(err).Msg("warnOfflineBackends")
 	}
+
+	// Forget request checksums after the replay window
+	if err := c.deleteExpiredRequestChecksums(ctx); err != nil {
+		log.Error().Err(err).Msg("deleteExpiredRequestChecksums")
+	}
+
+	// Forget sent notifications after the repeat interval
+	if err := c.deleteExpiredNotifications(ctx); err != nil {
+		log.Error().Err(err).Msg("deleteExpiredNotifications")
+	}
+
+	// Queue the commands of due schedules
+	if err := c.queueScheduledCommands(ctx); err != nil {
+		log.Error().Err(err).Msg("queueScheduledCommands")
+	}
+
+	// Notice stuck command workers
+	if err := c.warnStaleCommandQueue(ctx); err != nil {
+		log.Error().Err(err).Msg("warnStaleCommandQueue")
+	}
+
+	// Notice certificates of backends about to expire
+	if err := c.warnExpiringCertificates(ctx); err != nil {
+		log.Error().Err(err).Msg("warnExpiringCertificates")
+	}
+
+	// End meetings exceeding the lifetime of the frontend
+	if err := c.requestEndExpiredMeetings(ctx); err != nil {
+		log.Error().Err(err).Msg("requestEndExpiredMeetings")
+	}
+
+	// Prepare partitions and prune the history
+	if time.Now().Sub(c.lastHistoryMaintenance) > HistoryMaintenanceInterval {
+		if err := c.maintainHistory(ctx); err != nil {
+			log.Error().Err(err).Msg("maintainHistory")
+		} else {
+			c.lastHistoryMaintenance = time.Now()
+		}
+	}
 }
 
-// Command callback handler: Decode the operation and
-// run the command specific handler. As this is invoked
-// by the CommandQueue, these functions are allowed
+// Command callback handler: Decode the params and
+// run the handler registered for the action. As this is
+// invoked by the CommandQueue, these functions are allowed
 // to crash and will be recovered.
 func (c *Controller) handleCommand(
 	ctx context.Context,
 	cmd *store.Command,
 ) (interface{}, error) {
-	// Invoke command handler
-	switch cmd.Action {
-	case CmdDecommissionBackend:
-		log.Debug().Str("cmd", CmdDecommissionBackend).Msg("EXEC")
-		return c.handleDecommissionBackend(ctx, cmd)
-	case CmdUpdateNodeState:
-		log.Debug().Str("cmd", CmdUpdateNodeState).Msg("EXEC")
-		return c.handleUpdateNodeState(ctx, cmd)
-	case CmdUpdateMeetingState:
-		log.Debug().Str("cmd", CmdUpdateMeetingState).Msg("EXEC")
-		return c.handleUpdateMeetingState(ctx, cmd)
-	case CmdEndAllMeetings:
-		log.Debug().Str("cmd", CmdEndAllMeetings).Msg("EXEC")
-		return c.handleEndAllMeetings(ctx, cmd)
-	default:
-		return nil, ErrUnknownCommand
-	}
+	return c.handlers.Handle(ctx, cmd)
 }
 
 // Command: DecommissionBackend
 // Removes a backend state identified by id from the state
 func (c *Controller) handleDecommissionBackend(
 	ctx context.Context,
-	cmd *store.Command,
+	req *DecommissionBackendRequest,
 ) (interface{}, error) {
-	req := &DecommissionBackendRequest{}
-	if err := cmd.FetchParams(ctx, req); err != nil {
-		return nil, err
-	}
-
 	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -184,29 +227,22 @@ func (c *Controller) handleDecommissionBackend(
 		return false, fmt.Errorf("no such backend: %s", req.ID)
 	}
 
-	// So. This is how this goes: We check if the backend
-	// has active meetings. If this is the case we abort.
+	// So. This is how this goes: The backend is not deleted
+	// while it has active meetings; we abort in this case.
 	// However, as the admin state indicates a non ready state
 	// the router will not longer select this backend
 	// for new meetings - so we are good to go here.
-	mstates, err := store.GetMeetingStates(ctx, tx, store.Q().
-		Where("meetings.backend_id = ?", req.ID).
-		Where("meetings.state->'Running' = ?", true))
-	if err != nil {
-		return nil, err
-	}
-
-	if len(mstates) > 0 {
-		// We have running meetings, so we defer this
-		log.Warn().
-			Int("meetings_running", len(mstates)).
-			Msg("decommission backend deferred, backend has meetings running")
-		return false, nil
-	}
-
+	//
 	// Decommission backend by deleting the state
 	// and related meetings
 	if err := bstate.Delete(ctx, tx); err != nil {
+		if inUse, ok := err.(*store.BackendInUseError); ok {
+			// We have running meetings, so we defer this
+			log.Warn().
+				Int("meetings_running", len(inUse.RunningMeetings)).
+				Msg("decommission backend deferred, backend has meetings running")
+			return false, nil
+		}
 		return false, err
 	}
 
@@ -220,14 +256,8 @@ func (c *Controller) handleDecommissionBackend(
 // Command: UpdateNodeState
 func (c *Controller) handleUpdateNodeState(
 	ctx context.Context,
-	cmd *store.Command,
+	req *UpdateNodeStateRequest,
 ) (interface{}, error) {
-	// Get backend from command
-	req := &UpdateNodeStateRequest{}
-	if err := cmd.FetchParams(ctx, req); err != nil {
-		return nil, err
-	}
-
 	backend, err := GetBackend(ctx, store.Q().
 		Where("id = ?", req.ID))
 	if err != nil {
@@ -249,13 +279,8 @@ func (c *Controller) handleUpdateNodeState(
 // from a backend
 func (c *Controller) handleUpdateMeetingState(
 	ctx context.Context,
-	cmd *store.Command,
+	req *UpdateMeetingStateRequest,
 ) (interface{}, error) {
-	req := &UpdateMeetingStateRequest{}
-	if err := cmd.FetchParams(ctx, req); err != nil {
-		return nil, err
-	}
-
 	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
 	if err != nil {
 		return nil, err
@@ -310,13 +335,8 @@ func (c *Controller) handleUpdateMeetingState(
 // for all meetings on a backend
 func (c *Controller) handleEndAllMeetings(
 	ctx context.Context,
-	cmd *store.Command,
+	req *EndAllMeetingsRequest,
 ) (interface{}, error) {
-	req := &EndAllMeetingsRequest{}
-	if err := cmd.FetchParams(ctx, req); err != nil {
-		return nil, err
-	}
-
 	backend, err := GetBackend(ctx, store.Q().
 		Where("id = ?", req.BackendID))
 	if err != nil {
@@ -370,6 +390,80 @@ func (c *Controller) handleEndAllMeetings(
 	return true, nil
 }
 
+// handleSyncBackendMeetings refreshes the state of
+// all meetings on a backend
+func (c *Controller) handleSyncBackendMeetings(
+	ctx context.Context,
+	req *SyncBackendMeetingsRequest,
+) (interface{}, error) {
+	backend, err := GetBackend(ctx, store.Q().
+		Where("id = ?", req.BackendID))
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return false, fmt.Errorf("no such backend: %s", req.BackendID)
+	}
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	mstates, err := store.GetMeetingStates(ctx, tx, store.Q().
+		Where("backend_id = ?", req.BackendID))
+	if err != nil {
+		return nil, err
+	}
+	tx.Rollback(ctx) // We should not block the connection any longer
+
+	// Unlike the update meeting state command, the
+	// meetings are refreshed even if they were synced
+	// recently.
+	for _, m := range mstates {
+		if err := backend.refreshMeetingState(ctx, m); err != nil {
+			return nil, err
+		}
+	}
+
+	return len(mstates), nil
+}
+
+// handleSyncBackendRecordings imports the recordings
+// of a backend missing in the store
+func (c *Controller) handleSyncBackendRecordings(
+	ctx context.Context,
+	req *SyncBackendRecordingsRequest,
+) (interface{}, error) {
+	backend, err := GetBackend(ctx, store.Q().
+		Where("id = ?", req.BackendID))
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return false, fmt.Errorf("no such backend: %s", req.BackendID)
+	}
+	return backend.ReconcileRecordings(ctx, true)
+}
+
+// handleRecoverBackend rebuilds the meetings and
+// recordings of a backend from the live state
+func (c *Controller) handleRecoverBackend(
+	ctx context.Context,
+	req *RecoverBackendRequest,
+) (interface{}, error) {
+	backend, err := GetBackend(ctx, store.Q().
+		Where("id = ?", req.BackendID))
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return false, fmt.Errorf("no such backend: %s", req.BackendID)
+	}
+	return backend.Recover(ctx)
+}
+
 // Internal command generators
 
 // requestSyncStaleNodes triggers a background sync of the
@@ -502,6 +596,102 @@ func (c *Controller) requestBackendDecommissions(ctx context.Context) error {
 	return nil
 }
 
+// meetingBackend retrieves the meeting and its backend.
+// The meeting is nil if it is already gone.
+func meetingBackend(
+	ctx context.Context,
+	meetingID string,
+) (*store.MeetingState, *Backend, error) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	mstate, err := store.GetMeetingState(ctx, tx, store.Q().
+		Where("id = ?", meetingID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if mstate == nil || mstate.BackendID == nil {
+		return nil, nil, nil
+	}
+	tx.Rollback(ctx) // We should not block the connection any longer
+
+	backend, err := GetBackend(ctx, store.Q().
+		Where("id = ?", *mstate.BackendID))
+	if err != nil {
+		return nil, nil, err
+	}
+	if backend == nil {
+		return nil, nil, fmt.Errorf("no such backend: %s", *mstate.BackendID)
+	}
+	return mstate, backend, nil
+}
+
+// Command: EndMeeting
+// Sends an end request for a single meeting.
+func (c *Controller) handleEndMeeting(
+	ctx context.Context,
+	req *EndMeetingRequest,
+) (interface{}, error) {
+	mstate, backend, err := meetingBackend(ctx, req.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mstate == nil {
+		log.Debug().
+			Str("meetingID", req.ID).
+			Msg("meeting is already gone; end canceled")
+		return false, nil
+	}
+
+	log.Info().
+		Str("backendID", backend.ID()).
+		Str("meetingID", mstate.Meeting.MeetingID).
+		Msg("end meeting")
+
+	res, err := backend.End(ctx, bbb.EndRequest(bbb.Params{
+		"meetingID": mstate.Meeting.MeetingID,
+		"password":  mstate.Meeting.ModeratorPW,
+	}))
+	if err != nil {
+		return nil, err
+	}
+	if res.Returncode != bbb.RetSuccess {
+		return nil, fmt.Errorf("end meeting failed: %s", res.MessageKey)
+	}
+	return true, nil
+}
+
+// Command: BroadcastMessage
+// Posts a message to the chat of a meeting.
+func (c *Controller) handleBroadcastMessage(
+	ctx context.Context,
+	req *BroadcastMessageRequest,
+) (interface{}, error) {
+	mstate, backend, err := meetingBackend(ctx, req.MeetingID)
+	if err != nil {
+		return nil, err
+	}
+	if mstate == nil {
+		return false, nil
+	}
+
+	res, err := backend.SendChatMessage(ctx, bbb.SendChatMessageRequest(
+		bbb.Params{
+			"meetingID": mstate.Meeting.MeetingID,
+			"message":   req.Message,
+		}))
+	if err != nil {
+		return nil, err
+	}
+	if res.Returncode != bbb.RetSuccess {
+		return nil, fmt.Errorf("send chat message failed: %s", res.MessageKey)
+	}
+	return true, nil
+}
+
 // warnOfflineBackends iterates through all unlocked
 // backends and warns the user that there are backends offline
 func (c *Controller) warnOfflineBackends(ctx context.Context) error {
@@ -524,7 +714,243 @@ func (c *Controller) warnOfflineBackends(ctx context.Context) error {
 			Str("backendID", s.ID).
 			Str("host", s.Backend.Host).
 			Msg("noded is not available on the backend host")
+		notify.Notify(notify.NewEvent(
+			notify.EventBackendDown, s.Backend.Host,
+			"noded is not available on the backend host"))
+	}
+
+	return nil
+}
+
+// warnStaleCommandQueue warns the user when commands
+// are waiting longer than the threshold, which indicates
+// that no instance is processing the queue.
+func (c *Controller) warnStaleCommandQueue(ctx context.Context) error {
+	if CommandQueueAgeThreshold <= 0 {
+		return nil
 	}
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
 
+	stats, err := store.GetCommandQueueStats(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if stats.OldestAge > CommandQueueAgeThreshold {
+		log.Warn().
+			Int("requested", stats.Requested).
+			Dur("oldestAge", stats.OldestAge).
+			Msg("commands are waiting too long in the queue")
+		notify.Notify(notify.NewEvent(
+			notify.EventQueueStuck, "commands", fmt.Sprintf(
+				"%d commands are waiting, the oldest for %s",
+				stats.Requested, stats.OldestAge.Round(time.Second))))
+	}
 	return nil
 }
+
+// warnExpiringCertificates notifies when the certificate
+// of a backend expires soon. Only the certificates seen
+// by this instance are checked.
+func (c *Controller) warnExpiringCertificates(ctx context.Context) error {
+	if CertificateExpiryWarning <= 0 {
+		return nil
+	}
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	states, err := store.GetBackendStates(ctx, tx, store.Q())
+	if err != nil {
+		return err
+	}
+	deadline := time.Now().Add(CertificateExpiryWarning)
+	for _, s := range states {
+		expiry, ok := bbb.CertificateExpiry(s.Backend.Host)
+		if !ok || expiry.After(deadline) {
+			continue
+		}
+		log.Warn().
+			Str("backendID", s.ID).
+			Str("host", s.Backend.Host).
+			Time("expiry", expiry).
+			Msg("certificate of backend expires soon")
+		notify.Notify(notify.NewEvent(
+			notify.EventCertificateExpiring, s.Backend.Host,
+			"the certificate expires at "+expiry.UTC().Format(time.RFC3339)))
+	}
+	return nil
+}
+
+// requestEndExpiredMeetings ends the meetings running
+// longer than allowed for the frontend.
+func (c *Controller) requestEndExpiredMeetings(ctx context.Context) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if _, err := QueueEndExpiredMeetings(ctx, tx, time.Now()); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteExpiredRequestChecksums removes the checksums
+// remembered for the replay protection of frontends.
+func (c *Controller) deleteExpiredRequestChecksums(ctx context.Context) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	n, err := store.DeleteExpiredRequestChecksums(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Debug().
+			Int64("count", n).
+			Msg("deleted expired request checksums")
+	}
+	return tx.Commit(ctx)
+}
+
+// deleteExpiredNotifications removes the sent
+// notifications after the repeat interval.
+func (c *Controller) deleteExpiredNotifications(ctx context.Context) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	n, err := store.DeleteExpiredNotifications(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		log.Debug().
+			Int64("count", n).
+			Msg("deleted expired notifications")
+	}
+	return tx.Commit(ctx)
+}
+
+// queueScheduledCommands adds the commands of due
+// schedules to the queue. Only one instance queues
+// the command, as the schedules are locked.
+func (c *Controller) queueScheduledCommands(ctx context.Context) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	due, err := store.GetDueCommandSchedules(ctx, tx)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	for _, s := range due {
+		cmd, err := ScheduledCommand(s, now)
+		if err != nil {
+			// The schedule is broken. The run is skipped,
+			// the schedule continues with the next run.
+			log.Error().
+				Err(err).
+				Str("schedule", s.Name).
+				Msg("could not create scheduled command")
+			if err := s.Save(ctx, tx); err != nil {
+				return err
+			}
+			continue
+		}
+		log.Debug().
+			Str("cmd", cmd.Action).
+			Str("schedule", s.Name).
+			Msg("DISPATCH")
+		if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+			return err
+		}
+		if err := s.Save(ctx, tx); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// maintainHistory creates the partitions of the upcoming
+// month (and day for the commands), drops the expired
+// commands and removes the history exceeding the retention.
+func (c *Controller) maintainHistory(ctx context.Context) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	if err := store.EnsureUsagePartitions(ctx, tx, now); err != nil {
+		return err
+	}
+	if err := store.EnsureMeetingHistoryPartitions(ctx, tx, now); err != nil {
+		return err
+	}
+	if err := store.EnsureAuditLogPartitions(ctx, tx, now); err != nil {
+		return err
+	}
+	if err := store.EnsureCommandsPartitions(ctx, tx, now); err != nil {
+		return err
+	}
+	dropped, err := store.DeleteExpiredCommandsPartitions(ctx, tx, now)
+	if err != nil {
+		return err
+	}
+	if dropped > 0 {
+		log.Debug().
+			Int("partitions", dropped).
+			Msg("removed expired commands")
+	}
+	if r, ok := HistoryRetention[config.RetentionUsage]; ok {
+		dropped, err := store.DeleteFrontendUsageBefore(ctx, tx, now.Add(-r))
+		if err != nil {
+			return err
+		}
+		if dropped > 0 {
+			log.Info().
+				Int("partitions", dropped).
+				Msg("removed frontend usage exceeding the retention")
+		}
+	}
+	if r, ok := HistoryRetention[config.RetentionMeetings]; ok {
+		dropped, err := store.DeleteMeetingHistoryBefore(ctx, tx, now.Add(-r))
+		if err != nil {
+			return err
+		}
+		if dropped > 0 {
+			log.Info().
+				Int("partitions", dropped).
+				Msg("removed meeting history exceeding the retention")
+		}
+	}
+	if r, ok := HistoryRetention[config.RetentionAuditLog]; ok {
+		dropped, err := store.DeleteAuditLogEntriesBefore(ctx, tx, now.Add(-r))
+		if err != nil {
+			return err
+		}
+		if dropped > 0 {
+			log.Info().
+				Int("partitions", dropped).
+				Msg("removed audit log exceeding the retention")
+		}
+	}
+	return tx.Commit(ctx)
+}
@@ -0,0 +1,130 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// MeetingDiscoveryTimeout limits the time spent on
+// asking the backends for an unknown meeting.
+var MeetingDiscoveryTimeout = 3 * time.Second
+
+// lookupMeeting asks the backend for a meeting without
+// touching the store. The meeting is nil if it is not
+// running on the backend.
+func (b *Backend) lookupMeeting(
+	ctx context.Context,
+	meetingID string,
+) (*bbb.Meeting, error) {
+	req := bbb.GetMeetingInfoRequest(bbb.Params{
+		"meetingID": meetingID,
+	}).WithBackend(b.state.Backend)
+	rep, err := b.client.Do(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	res := rep.(*bbb.GetMeetingInfoResponse)
+	if res.Returncode != bbb.RetSuccess || res.Meeting == nil {
+		return nil, nil
+	}
+	return res.Meeting, nil
+}
+
+// discoveredMeeting is the result of a lookup
+type discoveredMeeting struct {
+	backend *Backend
+	meeting *bbb.Meeting
+}
+
+// DiscoverMeeting asks up to limit backends for a meeting
+// unknown to the store, e.g. after the database was
+// restored from a backup. The backends are asked in
+// parallel. When the meeting is found, the meeting state is
+// restored and the backend is returned. Otherwise the
+// backend is nil.
+func (r *Router) DiscoverMeeting(
+	ctx context.Context,
+	req *bbb.Request,
+	limit int,
+) (*Backend, error) {
+	meetingID, ok := req.Params.MeetingID()
+	if !ok {
+		return nil, ErrMeetingIDMissing
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+
+	// Backends with many meetings are asked first
+	backends, err := GetBackends(ctx, store.Q().
+		Where("admin_state = ?", "ready").
+		Where("node_state = ?", "ready").
+		OrderBy("meetings_count DESC").
+		Limit(uint64(limit)))
+	if err != nil {
+		return nil, err
+	}
+	if len(backends) == 0 {
+		return nil, nil
+	}
+
+	lookupCtx, cancel := context.WithTimeout(ctx, MeetingDiscoveryTimeout)
+	defer cancel()
+	results := make(chan *discoveredMeeting, len(backends))
+	for _, b := range backends {
+		go func(b *Backend) {
+			meeting, err := b.lookupMeeting(lookupCtx, meetingID)
+			if err != nil {
+				log.Debug().
+					Err(err).
+					Str("backend", b.Host()).
+					Str("meetingID", meetingID).
+					Msg("meeting discovery failed")
+			}
+			results <- &discoveredMeeting{backend: b, meeting: meeting}
+		}(b)
+	}
+
+	var found *discoveredMeeting
+	for range backends {
+		res := <-results
+		if res.meeting != nil {
+			found = res
+			break
+		}
+	}
+	if found == nil {
+		return nil, nil
+	}
+
+	log.Info().
+		Str("backend", found.backend.Host()).
+		Str("meetingID", meetingID).
+		Msg("discovered meeting unknown to the store")
+
+	// Restore the meeting state
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	mstate, err := found.backend.state.CreateMeetingState(
+		ctx, tx, nil, found.meeting, nil)
+	if err != nil {
+		return nil, err
+	}
+	if frontend := FrontendFromContext(ctx); frontend != nil {
+		if err := mstate.BindFrontendID(ctx, tx, frontend.ID()); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return found.backend, nil
+}
@@ -0,0 +1,56 @@
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBackendLookupMeeting(t *testing.T) {
+	found, err := ioutil.ReadFile(
+		"../../testdata/responses/getMeetingInfoSuccess.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Query().Get("meetingID") != "Demo Meeting" {
+				w.Write([]byte(`<response>
+					<returncode>FAILED</returncode>
+					<messageKey>notFound</messageKey>
+				</response>`))
+				return
+			}
+			w.Write(found)
+		}))
+	defer srv.Close()
+
+	b := NewBackend(store.InitBackendState(&store.BackendState{
+		Backend: &bbb.Backend{
+			Host:   srv.URL + "/bigbluebutton/api/",
+			Secret: "secret",
+		},
+	}))
+
+	ctx := context.Background()
+	meeting, err := b.lookupMeeting(ctx, "Demo Meeting")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meeting == nil || meeting.MeetingID != "Demo Meeting" {
+		t.Error("unexpected meeting:", meeting)
+	}
+
+	meeting, err = b.lookupMeeting(ctx, "unknown")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meeting != nil {
+		t.Error("meeting should not be found:", meeting)
+	}
+}
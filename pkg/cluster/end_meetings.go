@@ -0,0 +1,218 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Limits of the end meetings action
+const (
+	// EndMeetingsMaxDelay is the longest time
+	// the end of the meetings can be delayed.
+	EndMeetingsMaxDelay = time.Hour
+
+	// BroadcastMessageMaxLength is the maximum
+	// length of a chat message accepted by BBB.
+	BroadcastMessageMaxLength = 500
+)
+
+// EndMeetingsRequest ends the running meetings of a
+// backend, or of the whole cluster if no backend is
+// given, e.g. for an emergency maintenance. The message
+// is posted to the meetings before they are ended
+// after the delay. Posting the message requires
+// BBB 2.7+ on all backends of the meetings.
+type EndMeetingsRequest struct {
+	BackendID string `json:"backend_id,omitempty"`
+	Message   string `json:"message,omitempty"`
+	Delay     string `json:"delay,omitempty"`
+}
+
+// Validate checks the message and the delay
+func (r *EndMeetingsRequest) Validate() error {
+	err := store.ValidationError{}
+	if len(r.Message) > BroadcastMessageMaxLength {
+		err.Add("message", "the message is too long")
+	}
+	if _, derr := r.delay(); derr != nil {
+		err.Add("delay", derr.Error())
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// delay parses the duration. Without delay,
+// the meetings are ended right away.
+func (r *EndMeetingsRequest) delay() (time.Duration, error) {
+	if r.Delay == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(r.Delay)
+	if err != nil {
+		return 0, err
+	}
+	if d < 0 || d > EndMeetingsMaxDelay {
+		return 0, fmt.Errorf(
+			"the delay must be between 0 and %s", EndMeetingsMaxDelay)
+	}
+	return d, nil
+}
+
+// EndMeetingsResponse contains the commands queued
+// for ending the meetings.
+type EndMeetingsResponse struct {
+	Meetings int              `json:"meetings"`
+	EndAt    time.Time        `json:"end_at"`
+	Commands []*store.Command `json:"commands"`
+}
+
+// QueueEndMeetings queues the broadcast of the message and
+// the delayed end of each running meeting in the scope
+// of the request. The meetings are ended individually,
+// so a failing backend does not affect other meetings.
+func QueueEndMeetings(
+	ctx context.Context,
+	tx pgx.Tx,
+	req *EndMeetingsRequest,
+	now time.Time,
+) (*EndMeetingsResponse, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	delay, _ := req.delay()
+	endAt := now.UTC().Add(delay)
+
+	q := store.Q()
+	if req.BackendID != "" {
+		q = q.Where("backend_id = ?", req.BackendID)
+	}
+	meetings, err := store.GetMeetingStates(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	backends, err := store.GetBackendStates(ctx, tx, store.Q())
+	if err != nil {
+		return nil, err
+	}
+	// Messages can only be posted through the API
+	// on backends supporting sendChatMessage. Instead
+	// of ending meetings without the announcement,
+	// the request is rejected.
+	canBroadcast := make(map[string]bool, len(backends))
+	hosts := make(map[string]string, len(backends))
+	for _, b := range backends {
+		canBroadcast[b.ID] = bbb.SupportsSendChatMessage(b.Version)
+		hosts[b.ID] = b.Backend.Host
+	}
+	if req.Message != "" {
+		verr := store.ValidationError{}
+		rejected := map[string]bool{}
+		for _, m := range meetings {
+			if m.BackendID == nil || canBroadcast[*m.BackendID] ||
+				rejected[*m.BackendID] {
+				continue
+			}
+			rejected[*m.BackendID] = true
+			verr.Add("message", fmt.Sprintf(
+				"the backend %s does not support sendChatMessage (BBB 2.7+)",
+				hosts[*m.BackendID]))
+		}
+		if len(verr) > 0 {
+			return nil, verr
+		}
+	}
+
+	res := &EndMeetingsResponse{
+		Meetings: len(meetings),
+		EndAt:    endAt,
+		Commands: []*store.Command{},
+	}
+	for _, m := range meetings {
+		if req.Message != "" && m.BackendID != nil {
+			cmd := BroadcastMessage(&BroadcastMessageRequest{
+				MeetingID: m.ID,
+				Message:   req.Message,
+			})
+			if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+				return nil, err
+			}
+			res.Commands = append(res.Commands, cmd)
+		}
+		cmd := EndMeeting(&EndMeetingRequest{
+			ID: m.ID,
+		})
+		cmd.RunAt = &endAt
+		if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+			return nil, err
+		}
+		res.Commands = append(res.Commands, cmd)
+	}
+	return res, nil
+}
+
+// BackendBroadcastRequest posts a message to the
+// running meetings of a backend, e.g. to announce
+// a maintenance before a drain.
+type BackendBroadcastRequest struct {
+	Message string `json:"message"`
+}
+
+// Validate checks the message
+func (r *BackendBroadcastRequest) Validate() error {
+	err := store.ValidationError{}
+	if r.Message == "" {
+		err.Add("message", store.ErrFieldRequired)
+	}
+	if len(r.Message) > BroadcastMessageMaxLength {
+		err.Add("message", "the message is too long")
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// QueueBackendBroadcast queues the message for all
+// running meetings of the backend. The backend must
+// support the sendChatMessage API (BBB 2.7+).
+func QueueBackendBroadcast(
+	ctx context.Context,
+	tx pgx.Tx,
+	backend *store.BackendState,
+	req *BackendBroadcastRequest,
+) ([]*store.Command, error) {
+	if err := req.Validate(); err != nil {
+		return nil, err
+	}
+	if !bbb.SupportsSendChatMessage(backend.Version) {
+		return nil, store.ValidationError{
+			"backend": []string{
+				"the backend does not support sendChatMessage (BBB 2.7+)"},
+		}
+	}
+	meetings, err := store.GetMeetingStates(ctx, tx, store.Q().
+		Where("backend_id = ?", backend.ID))
+	if err != nil {
+		return nil, err
+	}
+	cmds := make([]*store.Command, 0, len(meetings))
+	for _, m := range meetings {
+		cmd := BroadcastMessage(&BroadcastMessageRequest{
+			MeetingID: m.ID,
+			Message:   req.Message,
+		})
+		if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+			return nil, err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return cmds, nil
+}
@@ -0,0 +1,32 @@
+package cluster
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestEndMeetingsRequestValidate(t *testing.T) {
+	req := &EndMeetingsRequest{
+		Message: "The server is shut down for maintenance.",
+		Delay:   "5m",
+	}
+	if err := req.Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (&EndMeetingsRequest{}).Validate(); err != nil {
+		t.Error("the delay should be optional:", err)
+	}
+
+	invalid := []*EndMeetingsRequest{
+		{Delay: "soon"},
+		{Delay: "-1m"},
+		{Delay: "3h"},
+		{Message: string(make([]byte, BroadcastMessageMaxLength+1))},
+	}
+	for _, req := range invalid {
+		if _, ok := req.Validate().(store.ValidationError); !ok {
+			t.Error("expected a validation error for:", req)
+		}
+	}
+}
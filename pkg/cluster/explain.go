@@ -0,0 +1,334 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Routing decisions
+const (
+	DecisionKept     = "kept"
+	DecisionRejected = "rejected"
+)
+
+// RoutingCandidate is a backend known to the cluster
+// and if it is eligible for routing at all.
+type RoutingCandidate struct {
+	ID            string        `json:"id"`
+	Host          string        `json:"host"`
+	Tags          []string      `json:"tags"`
+	AdminState    string        `json:"admin_state"`
+	NodeState     string        `json:"node_state"`
+	LoadFactor    float64       `json:"load_factor"`
+	MeetingsCount uint          `json:"meetings_count"`
+	Stress        float64       `json:"stress"`
+	Latency       time.Duration `json:"latency"`
+	Eligible      bool          `json:"eligible"`
+	Reason        string        `json:"reason,omitempty"`
+}
+
+// A RoutingDecision is the outcome for a single
+// backend within a routing step.
+type RoutingDecision struct {
+	BackendID string `json:"backend_id"`
+	Host      string `json:"host"`
+	Decision  string `json:"decision"`
+	// Position is the index of the backend in the list
+	// passed on to the next middleware.
+	Position int    `json:"position"`
+	Reason   string `json:"reason"`
+}
+
+// A RoutingStep describes the effect of a router
+// middleware on the list of backends. A skipped
+// middleware passed the backends without changes.
+type RoutingStep struct {
+	Middleware string             `json:"middleware"`
+	Sorted     bool               `json:"sorted"`
+	Skipped    bool               `json:"skipped,omitempty"`
+	Note       string             `json:"note,omitempty"`
+	Decisions  []*RoutingDecision `json:"decisions"`
+}
+
+// RoutingExplanation describes how a request
+// would be routed.
+type RoutingExplanation struct {
+	Resource  string `json:"resource"`
+	MeetingID string `json:"meeting_id,omitempty"`
+
+	// MeetingBackend is the host of the backend where
+	// the meeting is already running.
+	MeetingBackend string `json:"meeting_backend,omitempty"`
+
+	Candidates []*RoutingCandidate `json:"candidates"`
+	Steps      []*RoutingStep      `json:"steps"`
+	Selected   string              `json:"selected,omitempty"`
+	Error      string              `json:"error,omitempty"`
+}
+
+// routingTrace collects what the middlewares report
+// while a request is explained. Step is the index of
+// the middleware currently running.
+type routingTrace struct {
+	step    int
+	reasons []map[*Backend]string
+	skipped []string
+}
+
+// newRoutingTrace creates a trace for n middlewares
+func newRoutingTrace(n int) *routingTrace {
+	t := &routingTrace{
+		step:    n - 1,
+		reasons: make([]map[*Backend]string, n),
+		skipped: make([]string, n),
+	}
+	for i := range t.reasons {
+		t.reasons[i] = map[*Backend]string{}
+	}
+	return t
+}
+
+// routingTraceFromContext retrieves the trace
+func routingTraceFromContext(ctx context.Context) *routingTrace {
+	t, ok := ctx.Value(explainContextKey).(*routingTrace)
+	if !ok || t.step < 0 || t.step >= len(t.reasons) {
+		return nil
+	}
+	return t
+}
+
+// IsExplaining is true if the routing of the request is
+// only explained. Middlewares with random decisions should
+// be skipped, so the explanation is deterministic.
+func IsExplaining(ctx context.Context) bool {
+	return routingTraceFromContext(ctx) != nil
+}
+
+// ExplainReason records why the running middleware removed
+// or moved the backend. This is a noop, unless the request
+// is explained.
+func ExplainReason(ctx context.Context, b *Backend, reason string) {
+	if t := routingTraceFromContext(ctx); t != nil {
+		t.reasons[t.step][b] = reason
+	}
+}
+
+// ExplainSkipped records that the running middleware
+// passed the backends without applying itself.
+func ExplainSkipped(ctx context.Context, reason string) {
+	if t := routingTraceFromContext(ctx); t != nil {
+		t.skipped[t.step] = reason
+	}
+}
+
+// funcSuffix matches the name of anonymous functions
+var funcSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// middlewareName derives a readable name from the
+// function implementing the middleware.
+func middlewareName(m RouterMiddleware) string {
+	fn := runtime.FuncForPC(reflect.ValueOf(m).Pointer())
+	if fn == nil {
+		return "unknown"
+	}
+	name := fn.Name()
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		name = name[i+1:]
+	}
+	return funcSuffix.ReplaceAllString(name, "")
+}
+
+// ineligibleReason checks why a backend is not
+// considered when selecting a backend. The conditions
+// are the same as in SelectBackend.
+func ineligibleReason(b *Backend, deadline time.Time) string {
+	if b.state.AdminState != "ready" {
+		return "admin state is " + b.state.AdminState
+	}
+	if b.state.NodeState != "ready" {
+		return "node state is " + b.state.NodeState
+	}
+	if b.state.AgentHeartbeat.Before(deadline) {
+		return "no node agent heartbeat since " +
+			b.state.AgentHeartbeat.Format(time.RFC3339)
+	}
+	return ""
+}
+
+// explainStep compares the backends passed to a
+// middleware with the backends it passed on. The reasons
+// reported by the middleware are added to the decisions.
+func explainStep(
+	name string,
+	in, out []*Backend,
+	reasons map[*Backend]string,
+) *RoutingStep {
+	position := make(map[*Backend]int, len(out))
+	for i, b := range out {
+		position[b] = i
+	}
+	step := &RoutingStep{
+		Middleware: name,
+		Decisions:  make([]*RoutingDecision, 0, len(in)),
+	}
+
+	// The backends kept in their relative order
+	prev := -1
+	for i, b := range in {
+		d := &RoutingDecision{
+			BackendID: b.ID(),
+			Host:      b.Host(),
+		}
+		pos, ok := position[b]
+		if !ok {
+			d.Decision = DecisionRejected
+			d.Position = -1
+			d.Reason = withReason("removed by "+name, reasons[b])
+			step.Decisions = append(step.Decisions, d)
+			continue
+		}
+		d.Decision = DecisionKept
+		d.Position = pos
+		if pos < prev {
+			step.Sorted = true
+		}
+		prev = pos
+		if pos == i {
+			d.Reason = "passed by " + name
+		} else {
+			d.Reason = fmt.Sprintf(
+				"moved from position %d to %d by %s", i, pos, name)
+		}
+		d.Reason = withReason(d.Reason, reasons[b])
+		step.Decisions = append(step.Decisions, d)
+	}
+	return step
+}
+
+// withReason appends the reason reported
+// by the middleware to the decision.
+func withReason(decision, reason string) string {
+	if reason == "" {
+		return decision
+	}
+	return decision + ": " + reason
+}
+
+// Explain runs the routing middleware chain for a
+// hypothetical request and records how each middleware
+// filtered and ordered the backends. No meeting is
+// created and the cluster state is not modified.
+// Middlewares making random decisions skip themselves,
+// so the explanation is the same for the same state.
+func (r *Router) Explain(
+	ctx context.Context,
+	req *bbb.Request,
+) (*RoutingExplanation, error) {
+	explanation := &RoutingExplanation{
+		Resource:   req.Resource,
+		Candidates: []*RoutingCandidate{},
+		Steps:      []*RoutingStep{},
+	}
+
+	// An existing meeting is routed to its backend
+	// without consulting the middlewares.
+	if meetingID, ok := req.Params.MeetingID(); ok {
+		explanation.MeetingID = meetingID
+		backend, err := r.LookupBackend(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		if backend != nil {
+			explanation.MeetingBackend = backend.Host()
+		}
+	}
+
+	all, err := GetBackends(ctx, store.Q().OrderBy("host ASC"))
+	if err != nil {
+		return nil, err
+	}
+	deadline := time.Now().UTC().Add(-heartbeatTimeout)
+	backends := make([]*Backend, 0, len(all))
+	for _, b := range all {
+		reason := ineligibleReason(b, deadline)
+		explanation.Candidates = append(
+			explanation.Candidates, &RoutingCandidate{
+				ID:            b.ID(),
+				Host:          b.Host(),
+				Tags:          b.Tags(),
+				AdminState:    b.state.AdminState,
+				NodeState:     b.state.NodeState,
+				LoadFactor:    b.state.LoadFactor,
+				MeetingsCount: b.state.MeetingsCount,
+				Stress:        b.Stress(),
+				Latency:       b.Latency(),
+				Eligible:      reason == "",
+				Reason:        reason,
+			})
+		if reason == "" {
+			backends = append(backends, b)
+		}
+	}
+
+	// Build an instrumented chain: Each middleware
+	// gets a next handler recording the backends
+	// passed on. The middlewares are executed in
+	// reverse order of use. Middlewares may sort the
+	// backends in place, so copies are recorded.
+	outputs := make([][]*Backend, len(r.middlewares))
+	called := make([]bool, len(r.middlewares))
+	trace := newRoutingTrace(len(r.middlewares))
+	handler := RouterHandler(nilHandler)
+	for i, m := range r.middlewares {
+		i, next := i, handler
+		handler = m(func(
+			ctx context.Context,
+			backends []*Backend,
+			req *bbb.Request,
+		) ([]*Backend, error) {
+			outputs[i] = append([]*Backend{}, backends...)
+			called[i] = true
+			trace.step = i - 1 // The next middleware runs
+			return next(ctx, backends, req)
+		})
+	}
+	traceCtx := context.WithValue(ctx, explainContextKey, trace)
+	selected, err := handler(
+		traceCtx, append([]*Backend{}, backends...), req)
+	if err != nil {
+		explanation.Error = err.Error()
+	}
+
+	in := backends
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		if !called[i] {
+			break // The chain was not continued
+		}
+		step := explainStep(
+			middlewareName(r.middlewares[i]),
+			in, outputs[i], trace.reasons[i])
+		if reason := trace.skipped[i]; reason != "" {
+			step.Skipped = true
+			step.Note = reason
+		}
+		explanation.Steps = append(explanation.Steps, step)
+		in = outputs[i]
+	}
+
+	if err == nil {
+		if len(selected) == 0 {
+			explanation.Error = ErrNoBackendAvailable.Error()
+		} else {
+			explanation.Selected = selected[0].Host()
+		}
+	}
+	return explanation, nil
+}
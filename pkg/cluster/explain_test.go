@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func testRouterMiddleware(next RouterHandler) RouterHandler {
+	return func(
+		ctx context.Context,
+		backends []*Backend,
+		req *bbb.Request,
+	) ([]*Backend, error) {
+		return next(ctx, backends, req)
+	}
+}
+
+func TestMiddlewareName(t *testing.T) {
+	name := middlewareName(testRouterMiddleware)
+	if name != "cluster.testRouterMiddleware" {
+		t.Error("unexpected name:", name)
+	}
+}
+
+func TestExplainStep(t *testing.T) {
+	b1 := &Backend{state: &store.BackendState{ID: "b1"}}
+	b2 := &Backend{state: &store.BackendState{ID: "b2"}}
+	b3 := &Backend{state: &store.BackendState{ID: "b3"}}
+
+	reasons := map[*Backend]string{b2: "missing tags"}
+	step := explainStep(
+		"test", []*Backend{b1, b2, b3}, []*Backend{b3, b1}, reasons)
+	if !step.Sorted {
+		t.Error("expected step to be sorted")
+	}
+	if len(step.Decisions) != 3 {
+		t.Fatal("unexpected decisions:", step.Decisions)
+	}
+	if step.Decisions[0].Decision != DecisionKept ||
+		step.Decisions[0].Position != 1 {
+		t.Error("unexpected decision:", step.Decisions[0])
+	}
+	if step.Decisions[1].Decision != DecisionRejected ||
+		step.Decisions[1].Position != -1 {
+		t.Error("unexpected decision:", step.Decisions[1])
+	}
+	if step.Decisions[1].Reason != "removed by test: missing tags" {
+		t.Error("unexpected reason:", step.Decisions[1].Reason)
+	}
+
+	step = explainStep(
+		"test", []*Backend{b1, b2}, []*Backend{b1, b2}, nil)
+	if step.Sorted {
+		t.Error("step should not be sorted")
+	}
+}
+
+func TestIneligibleReason(t *testing.T) {
+	now := time.Now().UTC()
+	b := &Backend{state: &store.BackendState{
+		AdminState:     "ready",
+		NodeState:      "ready",
+		AgentHeartbeat: now,
+	}}
+	if r := ineligibleReason(b, now.Add(-heartbeatTimeout)); r != "" {
+		t.Error("unexpected reason:", r)
+	}
+	b.state.AdminState = "stopped"
+	if r := ineligibleReason(b, now); r != "admin state is stopped" {
+		t.Error("unexpected reason:", r)
+	}
+}
+
+func TestRoutingTrace(t *testing.T) {
+	b1 := &Backend{state: &store.BackendState{ID: "b1"}}
+	ctx := context.Background()
+	if IsExplaining(ctx) {
+		t.Error("context should not be explaining")
+	}
+	ExplainReason(ctx, b1, "noop") // must not fail
+
+	trace := newRoutingTrace(2)
+	ctx = context.WithValue(ctx, explainContextKey, trace)
+	if !IsExplaining(ctx) {
+		t.Fatal("context should be explaining")
+	}
+	ExplainSkipped(ctx, "random")
+	trace.step = 0
+	ExplainReason(ctx, b1, "missing tags")
+
+	if trace.skipped[1] != "random" || trace.skipped[0] != "" {
+		t.Error("unexpected skipped:", trace.skipped)
+	}
+	if trace.reasons[0][b1] != "missing tags" || len(trace.reasons[1]) != 0 {
+		t.Error("unexpected reasons:", trace.reasons)
+	}
+
+	// The chain ended
+	trace.step = -1
+	if IsExplaining(ctx) {
+		t.Error("trace should be inactive after the last step")
+	}
+}
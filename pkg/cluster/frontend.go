@@ -14,13 +14,31 @@ import (
 // Each frontend has it's own secret for authentication.
 type Frontend struct {
 	state *store.FrontendState
+
+	// settings include the settings
+	// inherited from the parent.
+	settings store.FrontendSettings
 }
 
 // NewFrontend initializes a frontend with the provided
 // config and assigns the ID.
 func NewFrontend(state *store.FrontendState) *Frontend {
 	return &Frontend{
-		state: state,
+		state:    state,
+		settings: state.Settings,
+	}
+}
+
+// newFrontendWithParent initializes a frontend, which
+// inherits the settings of the parent. The parent
+// may be nil.
+func newFrontendWithParent(
+	state *store.FrontendState,
+	parent *store.FrontendState,
+) *Frontend {
+	return &Frontend{
+		state:    state,
+		settings: state.EffectiveSettings(parent),
 	}
 }
 
@@ -34,9 +52,17 @@ func (f *Frontend) Frontend() *bbb.Frontend {
 	return f.state.Frontend
 }
 
-// Settings gets the state settings
+// PreviousSecret returns the previous secret of the
+// frontend, if the overlap window of a secret rotation
+// is not yet over.
+func (f *Frontend) PreviousSecret() (string, bool) {
+	return f.state.ValidPreviousSecret()
+}
+
+// Settings gets the state settings, including
+// the settings inherited from the parent.
 func (f *Frontend) Settings() *store.FrontendSettings {
-	return &f.state.Settings
+	return &f.settings
 }
 
 // String stringifies the frontend
@@ -69,12 +95,20 @@ func GetFrontends(
 	if err != nil {
 		return nil, err
 	}
+	parents, err := store.GetFrontendParents(ctx, tx, states)
+	if err != nil {
+		return nil, err
+	}
 	tx.Rollback(ctx)
 
-	// Make cluster backend from each state
+	// Make cluster frontend from each state
 	frontends := make([]*Frontend, 0, len(states))
 	for _, s := range states {
-		frontends = append(frontends, NewFrontend(s))
+		var parent *store.FrontendState
+		if s.ParentID != nil {
+			parent = parents[*s.ParentID]
+		}
+		frontends = append(frontends, newFrontendWithParent(s, parent))
 	}
 	return frontends, nil
 }
@@ -94,3 +128,38 @@ func GetFrontend(
 	}
 	return frontends[0], nil
 }
+
+// GetFrontendByKey fetches the frontend identified by
+// the key. The key is either the key of the frontend or
+// an additional key, which is returned as well.
+func GetFrontendByKey(
+	ctx context.Context,
+	key string,
+) (*Frontend, *store.FrontendKey, error) {
+	frontend, err := GetFrontend(ctx, store.Q().
+		Where("key = ?", key))
+	if err != nil || frontend != nil {
+		return frontend, nil, err
+	}
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer tx.Rollback(ctx)
+	alias, err := store.GetFrontendKey(ctx, tx, store.Q().
+		Where("key = ?", key))
+	if err != nil {
+		return nil, nil, err
+	}
+	if alias == nil {
+		return nil, nil, nil
+	}
+	tx.Rollback(ctx)
+	frontend, err = GetFrontend(ctx, store.Q().
+		Where("id = ?", alias.FrontendID))
+	if err != nil || frontend == nil {
+		return nil, nil, err
+	}
+	return frontend, alias, nil
+}
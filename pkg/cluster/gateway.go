@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime/debug"
 
 	"github.com/jackc/pgx/v4/pgxpool"
 	"github.com/rs/zerolog/log"
@@ -24,39 +25,53 @@ var (
 	// ErrBackendNotReady will only occure when the routing
 	// selected a backend that can not accept any requests
 	ErrBackendNotReady = errors.New("backend not ready")
+
+	// ErrInternal will be returned when handling the
+	// request failed unexpectedly, e.g. by a panic in
+	// a middleware.
+	ErrInternal = errors.New("internal error while handling the request")
 )
 
 // GatewayOptions have flags for customizing the gateway behaviour.
 type GatewayOptions struct {
 }
 
+// ResourceHandlers map the resources of the
+// BBB API to the handlers of the requests.
+type ResourceHandlers map[string]RequestHandler
+
 // The Gateway accepts bbb cluster requests and dispatches
 // it to the cluster nodes.
 type Gateway struct {
 	opts       *GatewayOptions
 	middleware RequestHandler
+	resources  ResourceHandlers
 	ctrl       *Controller
 }
 
 // NewGateway sets up a new cluster router instance.
 func NewGateway(ctrl *Controller, opts *GatewayOptions) *Gateway {
 	gw := &Gateway{
-		ctrl: ctrl,
-		opts: opts,
+		ctrl:      ctrl,
+		opts:      opts,
+		resources: ResourceHandlers{},
 	}
-	gw.middleware = gw.unhandledRequestHandler(ctrl)
+	gw.middleware = gw.dispatchResource
 	return gw
 }
 
-// The unhandledRequestHandler marks the end of the
-// middleware chain and is the default handler for requests.
-func (gw *Gateway) unhandledRequestHandler(ctrl *Controller) RequestHandler {
-	return func(
-		ctx context.Context, req *bbb.Request,
-	) (bbb.Response, error) {
+// The dispatchResource handler marks the end of the
+// middleware chain and invokes the handler registered
+// for the resource of the request.
+func (gw *Gateway) dispatchResource(
+	ctx context.Context, req *bbb.Request,
+) (bbb.Response, error) {
+	handler, ok := gw.resources[req.Resource]
+	if !ok {
 		// We could not handle the request.
 		return nil, fmt.Errorf("unknown resource: %s", req.Resource)
 	}
+	return handler(ctx, req)
 }
 
 // Use registers a middleware function
@@ -64,6 +79,42 @@ func (gw *Gateway) Use(middleware RequestMiddleware) {
 	gw.middleware = middleware(gw.middleware)
 }
 
+// Handle registers the handler of a resource. A handler
+// registered before for the resource is replaced.
+// The handlers must be registered before requests
+// are dispatched.
+func (gw *Gateway) Handle(resource string, handler RequestHandler) {
+	gw.resources[resource] = handler
+}
+
+// HandleResources registers the handlers of the resources
+func (gw *Gateway) HandleResources(handlers ResourceHandlers) {
+	for resource, handler := range handlers {
+		gw.Handle(resource, handler)
+	}
+}
+
+// handle invokes the middleware chain. A panic in a
+// middleware is recovered and returned as an error, so a
+// response can be sent to the client.
+func (gw *Gateway) handle(
+	ctx context.Context,
+	req *bbb.Request,
+) (res bbb.Response, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			log.Error().
+				Str("resource", req.Resource).
+				Str("panic", fmt.Sprintf("%v", r)).
+				Str("stack", string(debug.Stack())).
+				Msg("recovered from panic in gateway")
+			res = nil
+			err = ErrInternal
+		}
+	}()
+	return gw.middleware(ctx, req)
+}
+
 // Dispatch taks a cluster request and starts the middleware
 // chain. We will always return a bbb response.
 // Any error occoring during routing or dispatching will be
@@ -77,7 +128,7 @@ func (gw *Gateway) Dispatch(
 	go gw.ctrl.StartBackground()
 
 	// Let the middleware chain handle the request
-	res, err := gw.middleware(ctx, req)
+	res, err := gw.handle(ctx, req)
 	if err != nil {
 		be := BackendFromContext(ctx)
 		fe := FrontendFromContext(ctx)
@@ -86,6 +137,7 @@ func (gw *Gateway) Dispatch(
 			Err(err).
 			Str("backend", fmt.Sprintf("%v", be)).
 			Str("frontend", fmt.Sprintf("%v", fe)).
+			Str("requestID", RequestIDFromContext(ctx)).
 			Msg("gateway error")
 		// We encode our error as a BBB error response
 		res = &bbb.XMLResponse{
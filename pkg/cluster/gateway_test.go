@@ -1,9 +1,128 @@
 package cluster
 
 import (
+	"context"
+	"errors"
 	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 )
 
 func TestGatewayRegister(t *testing.T) {
+	gw := testGateway()
+	gw.HandleResources(ResourceHandlers{
+		bbb.ResourceSendChatMessage: func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			return &bbb.SendChatMessageResponse{
+				XMLResponse: &bbb.XMLResponse{
+					Returncode: bbb.RetSuccess,
+				},
+			}, nil
+		},
+	})
+
+	// The middlewares are invoked before the handler
+	resources := []string{}
+	gw.Use(func(next RequestHandler) RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			resources = append(resources, req.Resource)
+			return next(ctx, req)
+		}
+	})
+
+	res, err := gw.handle(context.Background(), &bbb.Request{
+		Resource: bbb.ResourceSendChatMessage,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.(*bbb.SendChatMessageResponse); !ok {
+		t.Errorf("unexpected response: %T", res)
+	}
+
+	// Resources without a handler are rejected
+	_, err = gw.handle(context.Background(), &bbb.Request{
+		Resource: "insertDocument",
+	})
+	if err == nil {
+		t.Error("expected an unknown resource error")
+	}
+	if len(resources) != 2 {
+		t.Error("unexpected requests:", resources)
+	}
+}
+
+// panicMiddleware panics for every request
+func panicMiddleware(next RequestHandler) RequestHandler {
+	return func(
+		ctx context.Context,
+		req *bbb.Request,
+	) (bbb.Response, error) {
+		panic("middleware failed")
+	}
+}
+
+// testGateway creates a gateway where the background
+// jobs are not triggered by dispatching requests.
+func testGateway() *Gateway {
+	ctrl := NewController()
+	ctrl.lastStartBackground = time.Now()
+	return NewGateway(ctrl, &GatewayOptions{})
+}
+
+func TestGatewayDispatchPanic(t *testing.T) {
+	gw := testGateway()
+	gw.Use(panicMiddleware)
+
+	req := &bbb.Request{
+		Resource: bbb.ResourceGetMeetings,
+		Params:   bbb.Params{},
+	}
+	res := gw.Dispatch(context.Background(), nil, req)
+	xmlRes, ok := res.(*bbb.XMLResponse)
+	if !ok {
+		t.Fatalf("unexpected response: %T", res)
+	}
+	if xmlRes.Returncode != bbb.RetFailed {
+		t.Error("unexpected returncode:", xmlRes.Returncode)
+	}
+	if xmlRes.Message != ErrInternal.Error() {
+		t.Error("unexpected message:", xmlRes.Message)
+	}
+}
+
+func TestGatewayHandlePanicInChain(t *testing.T) {
+	gw := testGateway()
+	gw.Use(panicMiddleware)
+
+	// The panic must be recovered, even if it happens
+	// further down in the middleware chain.
+	called := false
+	gw.Use(func(next RequestHandler) RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			called = true
+			return next(ctx, req)
+		}
+	})
 
+	req := &bbb.Request{Resource: bbb.ResourceJoin}
+	res, err := gw.handle(context.Background(), req)
+	if !errors.Is(err, ErrInternal) {
+		t.Error("unexpected error:", err)
+	}
+	if res != nil {
+		t.Error("unexpected response:", res)
+	}
+	if !called {
+		t.Error("middleware should have been called")
+	}
 }
@@ -0,0 +1,153 @@
+package cluster
+
+import (
+	"sync"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// JoinCacheTTL is the time joins are served from the
+// join cache while the database is unavailable.
+// The cache is disabled if zero.
+var JoinCacheTTL time.Duration
+
+// joinCacheMaxAge is the age after which cached
+// meetings are considered ended.
+const joinCacheMaxAge = 24 * time.Hour
+
+// joinCacheSweepInterval limits how often the cache
+// is swept for old entries.
+const joinCacheSweepInterval = 10 * time.Minute
+
+// cachedFrontend is an authenticated frontend. The alias
+// is the additional key used by the request, if any.
+type cachedFrontend struct {
+	frontend *Frontend
+	alias    *store.FrontendKey
+}
+
+// cachedMeeting is the backend of a meeting
+type cachedMeeting struct {
+	frontendID string
+	backend    *store.BackendState
+	cachedAt   time.Time
+}
+
+// A JoinCache is an in-memory snapshot of the frontends
+// and the backends of meetings. It is used for serving
+// joins when the database is unavailable, trading
+// consistency for availability during short outages.
+type JoinCache struct {
+	mtx       sync.RWMutex
+	frontends map[string]*cachedFrontend
+	meetings  map[string]*cachedMeeting
+	sweptAt   time.Time
+}
+
+// Joins is the join cache of this instance
+var Joins = NewJoinCache()
+
+// NewJoinCache creates an empty join cache
+func NewJoinCache() *JoinCache {
+	return &JoinCache{
+		frontends: make(map[string]*cachedFrontend),
+		meetings:  make(map[string]*cachedMeeting),
+		sweptAt:   time.Now(),
+	}
+}
+
+// PutFrontend remembers an authenticated frontend
+// by the key used in the request.
+func (c *JoinCache) PutFrontend(
+	key string,
+	frontend *Frontend,
+	alias *store.FrontendKey,
+) {
+	if JoinCacheTTL == 0 {
+		return
+	}
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.frontends[key] = &cachedFrontend{
+		frontend: frontend,
+		alias:    alias,
+	}
+}
+
+// Frontend retrieves a frontend by the key. The alias is
+// set if the key is an additional key of the frontend.
+func (c *JoinCache) Frontend(key string) (*Frontend, *store.FrontendKey) {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	f, ok := c.frontends[key]
+	if !ok {
+		return nil, nil
+	}
+	return f.frontend, f.alias
+}
+
+// PutMeeting remembers the backend of a meeting
+func (c *JoinCache) PutMeeting(
+	meetingID string,
+	frontendID string,
+	backend *Backend,
+) {
+	if JoinCacheTTL == 0 {
+		return
+	}
+	now := time.Now()
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.meetings[meetingID] = &cachedMeeting{
+		frontendID: frontendID,
+		backend:    backend.state,
+		cachedAt:   now,
+	}
+	if now.Sub(c.sweptAt) < joinCacheSweepInterval {
+		return
+	}
+	c.sweptAt = now
+	for id, m := range c.meetings {
+		if now.Sub(m.cachedAt) > joinCacheMaxAge {
+			delete(c.meetings, id)
+		}
+	}
+}
+
+// RemoveMeeting forgets the meeting, e.g. when ended
+func (c *JoinCache) RemoveMeeting(meetingID string) {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	delete(c.meetings, meetingID)
+}
+
+// Backend retrieves the backend of a meeting of
+// the frontend. The result is nil if the meeting is
+// unknown or belongs to another frontend.
+func (c *JoinCache) Backend(frontendID, meetingID string) *Backend {
+	c.mtx.RLock()
+	defer c.mtx.RUnlock()
+	m, ok := c.meetings[meetingID]
+	if !ok || m.frontendID != frontendID {
+		return nil
+	}
+	if time.Since(m.cachedAt) > joinCacheMaxAge {
+		return nil
+	}
+	return NewBackend(m.backend)
+}
+
+// Serving is true if joins may be served from the cache,
+// because the database is unavailable for less than the TTL.
+func (c *JoinCache) Serving() bool {
+	if JoinCacheTTL == 0 {
+		return false
+	}
+	health, since := store.Health()
+	if health != store.HealthDegraded {
+		// The outage was not noticed by the health check yet.
+		return true
+	}
+	return time.Since(since) < JoinCacheTTL
+}
@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestJoinCache(t *testing.T) {
+	JoinCacheTTL = time.Minute
+	defer func() { JoinCacheTTL = 0 }()
+
+	c := NewJoinCache()
+	frontend := NewFrontend(&store.FrontendState{
+		ID:       "frontend1",
+		Frontend: &bbb.Frontend{Key: "frontend1", Secret: "secret"},
+	})
+	c.PutFrontend("frontend1", frontend, nil)
+	if f, _ := c.Frontend("frontend1"); f != frontend {
+		t.Error("unexpected frontend:", f)
+	}
+	if f, _ := c.Frontend("frontend2"); f != nil {
+		t.Error("unexpected frontend:", f)
+	}
+
+	backend := NewBackend(store.InitBackendState(&store.BackendState{
+		ID:      "backend1",
+		Backend: &bbb.Backend{Host: "https://bbb1/"},
+	}))
+	c.PutMeeting("meeting1", "frontend1", backend)
+	if b := c.Backend("frontend1", "meeting1"); b == nil || b.ID() != "backend1" {
+		t.Error("unexpected backend:", b)
+	}
+	if b := c.Backend("frontend2", "meeting1"); b != nil {
+		t.Error("meeting of other frontend:", b)
+	}
+
+	c.RemoveMeeting("meeting1")
+	if b := c.Backend("frontend1", "meeting1"); b != nil {
+		t.Error("unexpected backend:", b)
+	}
+}
@@ -0,0 +1,51 @@
+package cluster
+
+import (
+	"sort"
+	"strings"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// MeetingLabelParamPrefix marks create parameters
+// with labels, e.g. meta_label_course=physics-101
+const MeetingLabelParamPrefix = "meta_label_"
+
+// MeetingLabels collects the labels of a new meeting
+// from the meta_label_<key> parameters of the create
+// request and the settings of the frontend. Labels of
+// the settings replace labels of the parameters.
+func MeetingLabels(
+	params bbb.Params,
+	settings *store.MeetingLabelsSettings,
+) store.Labels {
+	labels := store.Labels{}
+
+	// Sort the parameters, so the same labels are
+	// dropped when the limit is exceeded.
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		if strings.HasPrefix(strings.ToLower(k), MeetingLabelParamPrefix) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := strings.ToLower(k[len(MeetingLabelParamPrefix):])
+		labels.Set(key, params[k])
+	}
+
+	if settings == nil {
+		return labels
+	}
+	for key, param := range settings.Meta {
+		if value, ok := params[param]; ok {
+			labels.Set(key, value)
+		}
+	}
+	for key, value := range settings.Labels {
+		labels.Set(key, value)
+	}
+	return labels
+}
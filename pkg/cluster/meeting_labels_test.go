@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestMeetingLabels(t *testing.T) {
+	params := bbb.Params{
+		"meetingID":         "m1",
+		"meta_label_course": "physics-101",
+		"meta_label_Term":   "2026-ws",
+		"meta_label_dept":   "physics",
+		"meta_bbb-context":  "Lab",
+	}
+	settings := &store.MeetingLabelsSettings{
+		Labels: store.Labels{"dept": "science"},
+		Meta:   map[string]string{"context": "meta_bbb-context"},
+	}
+
+	labels := MeetingLabels(params, settings)
+	if len(labels) != 4 {
+		t.Error("unexpected labels:", labels)
+	}
+	if labels["course"] != "physics-101" {
+		t.Error("unexpected course:", labels["course"])
+	}
+	if labels["term"] != "2026-ws" {
+		t.Error("unexpected term:", labels["term"])
+	}
+	if labels["dept"] != "science" {
+		t.Error("settings should replace params:", labels["dept"])
+	}
+	if labels["context"] != "Lab" {
+		t.Error("unexpected context:", labels["context"])
+	}
+}
+
+func TestMeetingLabelsWithoutSettings(t *testing.T) {
+	labels := MeetingLabels(bbb.Params{"meetingID": "m1"}, nil)
+	if labels == nil || len(labels) != 0 {
+		t.Error("unexpected labels:", labels)
+	}
+}
@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// QueueEndExpiredMeetings queues the end of meetings
+// running longer than the maximum meeting duration of
+// their frontend. Meetings with an end command still
+// pending are skipped.
+func QueueEndExpiredMeetings(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) ([]*store.Command, error) {
+	// The lifetime may be inherited from the parent
+	frontends, err := store.GetFrontendStates(ctx, tx, store.Q().
+		Where(`settings->'meeting_lifetime' IS NOT NULL
+			OR parent_id IN (
+				SELECT p.id FROM frontends AS p
+				 WHERE p.settings->'meeting_lifetime' IS NOT NULL)`))
+	if err != nil {
+		return nil, err
+	}
+	parents, err := store.GetFrontendParents(ctx, tx, frontends)
+	if err != nil {
+		return nil, err
+	}
+	cmds := []*store.Command{}
+	for _, f := range frontends {
+		var parent *store.FrontendState
+		if f.ParentID != nil {
+			parent = parents[*f.ParentID]
+		}
+		lifetime := f.EffectiveSettings(parent).MeetingLifetime
+		if lifetime == nil {
+			continue
+		}
+		max, err := lifetime.Duration()
+		if err != nil {
+			log.Warn().
+				Err(err).
+				Str("frontend", f.Frontend.Key).
+				Msg("invalid meeting lifetime")
+			continue
+		}
+		meetings, err := store.GetMeetingStates(ctx, tx, store.Q().
+			Where("frontend_id = ?", f.ID).
+			Where("created_at < ?", now.UTC().Add(-max)).
+			Where(`NOT EXISTS (
+				SELECT 1 FROM commands
+				 WHERE commands.action = ?
+				   AND commands.state IN ('requested', 'running')
+				   AND commands.params->>'id' = meetings.id)`,
+				CmdEndMeeting))
+		if err != nil {
+			return nil, err
+		}
+		for _, m := range meetings {
+			log.Info().
+				Str("frontend", f.Frontend.Key).
+				Str("meetingID", m.ID).
+				Dur("maxDuration", max).
+				Msg("meeting exceeded the max duration")
+			cmd := EndMeeting(&EndMeetingRequest{ID: m.ID})
+			if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+				return nil, err
+			}
+			cmds = append(cmds, cmd)
+		}
+	}
+	return cmds, nil
+}
@@ -0,0 +1,299 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ProbeInterval is the interval in which a probe meeting
+// is created, joined and ended on each backend.
+// The probes are disabled if zero.
+var ProbeInterval time.Duration
+
+// Probe steps
+const (
+	ProbeCreate = "create"
+	ProbeJoin   = "join"
+	ProbeEnd    = "end"
+	ProbeTotal  = "total"
+)
+
+// probeMeetingPrefix marks the meeting IDs of probe meetings
+const probeMeetingPrefix = "b3scale-probe-"
+
+// probeTimeout limits a probe of a backend
+const probeTimeout = time.Minute
+
+// ProbeDurations are the durations of the steps of the
+// synthetic probes. The total is the full path from
+// creating to ending the meeting.
+var ProbeDurations = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "backend_probe_duration_seconds",
+		Help: "Duration of the steps of synthetic probe meetings",
+	},
+	[]string{
+		// Backend host
+		"backend",
+		// Step: create, join, end or total
+		"step",
+	})
+
+// ProbeFailures counts the failed synthetic probes
+// by the failing step.
+var ProbeFailures = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "backend_probe_failures_total",
+		Help: "Number of failed synthetic probe meetings",
+	},
+	[]string{
+		// Backend host
+		"backend",
+		// Step: create, join or end
+		"step",
+	})
+
+// The Prober periodically creates, joins and ends a
+// probe meeting on each ready backend. The join fetches
+// the HTML of the client like a browser would.
+type Prober struct {
+	client *http.Client
+}
+
+// NewProber creates a prober
+func NewProber() *Prober {
+	return &Prober{
+		client: &http.Client{Timeout: probeTimeout},
+	}
+}
+
+// Start probes the backends in the probe interval
+func (p *Prober) Start() {
+	log.Info().
+		Dur("interval", ProbeInterval).
+		Msg("starting synthetic probes")
+	for {
+		time.Sleep(ProbeInterval)
+		if err := p.probeBackends(); err != nil {
+			log.Error().Err(err).Msg("probe backends")
+		}
+	}
+}
+
+// probeBackends probes all ready backends concurrently
+func (p *Prober) probeBackends() error {
+	ctx, cancel := context.WithTimeout(context.Background(), probeTimeout)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	backends, err := GetBackends(
+		store.ContextWithConnection(ctx, conn),
+		store.Q().
+			Where("admin_state = ?", "ready").
+			Where("node_state = ?", "ready"))
+	conn.Release()
+	if err != nil {
+		return err
+	}
+
+	done := make(chan struct{}, len(backends))
+	for _, b := range backends {
+		go func(b *Backend) {
+			p.probeBackend(ctx, b)
+			done <- struct{}{}
+		}(b)
+	}
+	for range backends {
+		<-done
+	}
+	return nil
+}
+
+// probeBackend runs the probe and reports the result
+func (p *Prober) probeBackend(ctx context.Context, b *Backend) {
+	t0 := time.Now()
+	step, err := p.probe(ctx, b)
+	if err != nil {
+		ProbeFailures.WithLabelValues(b.Host(), step).Inc()
+		log.Warn().
+			Err(err).
+			Str("backend", b.Host()).
+			Str("step", step).
+			Msg("synthetic probe failed")
+		notify.Notify(notify.NewEvent(
+			notify.EventProbeFailed, b.Host(),
+			fmt.Sprintf("probe meeting failed at %s: %s", step, err)))
+		return
+	}
+	ProbeDurations.WithLabelValues(b.Host(), ProbeTotal).
+		Observe(time.Since(t0).Seconds())
+}
+
+// probe creates, joins and ends a probe meeting. The
+// failing step is returned with the error.
+func (p *Prober) probe(ctx context.Context, b *Backend) (string, error) {
+	meetingID := probeMeetingPrefix + uuid.New().String()
+	moderatorPW := uuid.New().String()
+
+	t := time.Now()
+	if err := b.createProbeMeeting(ctx, meetingID, moderatorPW); err != nil {
+		return ProbeCreate, err
+	}
+	observeProbeStep(b, ProbeCreate, t)
+
+	t = time.Now()
+	if err := p.joinProbeMeeting(ctx, b, meetingID, moderatorPW); err != nil {
+		b.endProbeMeeting(ctx, meetingID, moderatorPW)
+		return ProbeJoin, err
+	}
+	observeProbeStep(b, ProbeJoin, t)
+
+	t = time.Now()
+	if err := b.endProbeMeeting(ctx, meetingID, moderatorPW); err != nil {
+		return ProbeEnd, err
+	}
+	observeProbeStep(b, ProbeEnd, t)
+
+	return "", nil
+}
+
+// observeProbeStep records the duration of a step
+func observeProbeStep(b *Backend, step string, t0 time.Time) {
+	ProbeDurations.WithLabelValues(b.Host(), step).
+		Observe(time.Since(t0).Seconds())
+}
+
+// joinProbeMeeting follows the join URL and fetches
+// the HTML of the client.
+func (p *Prober) joinProbeMeeting(
+	ctx context.Context,
+	b *Backend,
+	meetingID, moderatorPW string,
+) error {
+	req := bbb.JoinRequest(bbb.Params{
+		"meetingID": meetingID,
+		"fullName":  "b3scale probe",
+		"password":  moderatorPW,
+	})
+	bbb.AdaptParams(req.Resource, req.Params, b.state.Version)
+	joinURL := req.WithBackend(b.state.Backend).URL()
+
+	// The session cookie is required by the client
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{
+		Timeout:   p.client.Timeout,
+		Transport: p.client.Transport,
+		Jar:       jar,
+	}
+	httpReq, err := http.NewRequestWithContext(
+		ctx, http.MethodGet, joinURL, nil)
+	if err != nil {
+		return err
+	}
+	res, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if _, err := io.Copy(ioutil.Discard, res.Body); err != nil {
+		return err
+	}
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+	contentType := res.Header.Get("Content-Type")
+	if !strings.HasPrefix(contentType, "text/html") {
+		return fmt.Errorf("unexpected content type: %s", contentType)
+	}
+	return nil
+}
+
+// createProbeMeeting creates a meeting, which is
+// not recorded, with the moderator password.
+func (b *Backend) createProbeMeeting(
+	ctx context.Context,
+	meetingID, moderatorPW string,
+) error {
+	req := bbb.CreateRequest(bbb.Params{
+		"meetingID":   meetingID,
+		"name":        "b3scale probe",
+		"moderatorPW": moderatorPW,
+		"attendeePW":  uuid.New().String(),
+		"record":      "false",
+	}, nil).WithBackend(b.state.Backend)
+	res, err := b.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return probeResponseError(res)
+}
+
+// endProbeMeeting ends a probe meeting. The error
+// is logged, as the meeting expires anyhow.
+func (b *Backend) endProbeMeeting(
+	ctx context.Context,
+	meetingID, moderatorPW string,
+) error {
+	req := bbb.EndRequest(bbb.Params{
+		"meetingID": meetingID,
+		"password":  moderatorPW,
+	}).WithBackend(b.state.Backend)
+	res, err := b.client.Do(ctx, req)
+	if err == nil {
+		err = probeResponseError(res)
+	}
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("backend", b.Host()).
+			Str("meetingID", meetingID).
+			Msg("could not end probe meeting")
+	}
+	return err
+}
+
+// probeResponseError checks the return code of a response
+func probeResponseError(res bbb.Response) error {
+	if res.Status() >= 400 {
+		return fmt.Errorf("unexpected status: %d", res.Status())
+	}
+	var xmlRes *bbb.XMLResponse
+	switch r := res.(type) {
+	case *bbb.XMLResponse:
+		xmlRes = r
+	case *bbb.CreateResponse:
+		xmlRes = r.XMLResponse
+	case *bbb.EndResponse:
+		xmlRes = r.XMLResponse
+	case *bbb.GetMeetingInfoResponse:
+		xmlRes = r.XMLResponse
+	case *bbb.GetRecordingsResponse:
+		xmlRes = r.XMLResponse
+	}
+	if xmlRes == nil {
+		return fmt.Errorf("unexpected response: %T", res)
+	}
+	if xmlRes.Returncode != bbb.RetSuccess {
+		return fmt.Errorf("%s: %s", xmlRes.MessageKey, xmlRes.Message)
+	}
+	return nil
+}
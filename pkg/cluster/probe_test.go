@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestProberProbe(t *testing.T) {
+	created, err := ioutil.ReadFile(
+		"../../testdata/responses/createSuccess.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ended, err := ioutil.ReadFile(
+		"../../testdata/responses/endSuccess.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientHTML := "text/html"
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.HasSuffix(r.URL.Path, "/api/create"):
+				w.Write(created)
+			case strings.HasSuffix(r.URL.Path, "/api/join"):
+				http.Redirect(w, r, "/html5client/join", http.StatusFound)
+			case r.URL.Path == "/html5client/join":
+				w.Header().Set("Content-Type", clientHTML)
+				w.Write([]byte("<html></html>"))
+			case strings.HasSuffix(r.URL.Path, "/api/end"):
+				w.Write(ended)
+			default:
+				http.NotFound(w, r)
+			}
+		}))
+	defer srv.Close()
+
+	b := NewBackend(store.InitBackendState(&store.BackendState{
+		Backend: &bbb.Backend{
+			Host:   srv.URL + "/bigbluebutton/api/",
+			Secret: "secret",
+		},
+	}))
+
+	ctx := context.Background()
+	p := NewProber()
+	if step, err := p.probe(ctx, b); err != nil {
+		t.Fatal(step, err)
+	}
+
+	// The join must deliver the client
+	clientHTML = "application/json"
+	step, err := p.probe(ctx, b)
+	if err == nil {
+		t.Fatal("expected the join to fail")
+	}
+	if step != ProbeJoin {
+		t.Error("unexpected failing step:", step)
+	}
+}
@@ -0,0 +1,49 @@
+package cluster
+
+import (
+	"fmt"
+)
+
+// AgentProtocolVersion is the version of the protocol
+// between the b3scale instances and the node agents: the
+// state of the backends, the events and the commands
+// handled by the agents. Increment the version when the
+// agents need to change, and MinAgentProtocolVersion when
+// older agents no longer work.
+const AgentProtocolVersion = 1
+
+// MinAgentProtocolVersion is the oldest protocol version
+// of the agents accepted by this instance. Version 0 are
+// agents without handshake.
+const MinAgentProtocolVersion = 0
+
+// An AgentProtocolError is returned for node agents
+// which are too old for this instance.
+type AgentProtocolError struct {
+	Protocol int
+}
+
+// Error implements the error interface
+func (e *AgentProtocolError) Error() string {
+	return fmt.Sprintf(
+		"node agent protocol %d is not supported (required: %d - %d), "+
+			"please update b3scalenoded",
+		e.Protocol, MinAgentProtocolVersion, AgentProtocolVersion)
+}
+
+// CheckAgentProtocol checks if the protocol version
+// of a node agent is supported. Newer agents are
+// accepted, as they know the older protocols.
+func CheckAgentProtocol(protocol int) error {
+	if protocol < MinAgentProtocolVersion {
+		return &AgentProtocolError{Protocol: protocol}
+	}
+	return nil
+}
+
+// IsNewerAgentProtocol checks if the agent uses a protocol
+// unknown to this instance. Features of the newer agent
+// are not used.
+func IsNewerAgentProtocol(protocol int) bool {
+	return protocol > AgentProtocolVersion
+}
@@ -0,0 +1,21 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestCheckAgentProtocol(t *testing.T) {
+	if err := CheckAgentProtocol(AgentProtocolVersion); err != nil {
+		t.Error(err)
+	}
+	if err := CheckAgentProtocol(AgentProtocolVersion + 1); err != nil {
+		t.Error("newer agents should be accepted:", err)
+	}
+	if !IsNewerAgentProtocol(AgentProtocolVersion + 1) {
+		t.Error("the protocol should be newer")
+	}
+	err := CheckAgentProtocol(MinAgentProtocolVersion - 1)
+	if _, ok := err.(*AgentProtocolError); !ok {
+		t.Error("expected a protocol error:", err)
+	}
+}
@@ -0,0 +1,117 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ProvisionStatic creates or updates the backends and
+// frontends declared in a static configuration in the
+// store. This is not a replacement for the database,
+// the cluster state is still kept in postgres.
+// Backends and frontends not in the configuration
+// are not touched.
+func ProvisionStatic(
+	ctx context.Context,
+	backends config.BackendsConfig,
+	frontends config.FrontendsConfig,
+) error {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+
+	if backends != nil {
+		bcfgs, err := backends.Load()
+		if err != nil {
+			return err
+		}
+		for _, b := range bcfgs {
+			if err := provisionBackend(ctx, tx, b); err != nil {
+				return err
+			}
+		}
+	}
+
+	if frontends != nil {
+		fcfgs, err := frontends.Load()
+		if err != nil {
+			return err
+		}
+		for _, f := range fcfgs {
+			if err := provisionFrontend(ctx, tx, f); err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit(ctx)
+}
+
+// provisionBackend creates a backend or updates the secret
+func provisionBackend(
+	ctx context.Context,
+	tx pgx.Tx,
+	b *config.Backend,
+) error {
+	state, err := store.GetBackendState(ctx, tx, store.Q().
+		Where("host = ?", b.Host))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = store.InitBackendState(&store.BackendState{
+			Backend: &bbb.Backend{
+				Host:   b.Host,
+				Secret: b.Secret,
+			},
+		})
+		log.Info().
+			Str("host", b.Host).
+			Msg("provisioning static backend")
+	} else if state.Backend.Secret == b.Secret {
+		return nil // Nothing to do here
+	}
+	state.Backend.Secret = b.Secret
+	return state.Save(ctx, tx)
+}
+
+// provisionFrontend creates a frontend or updates the secret
+func provisionFrontend(
+	ctx context.Context,
+	tx pgx.Tx,
+	f *config.Frontend,
+) error {
+	state, err := store.GetFrontendState(ctx, tx, store.Q().
+		Where("key = ?", f.Key))
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		state = store.InitFrontendState(&store.FrontendState{
+			Frontend: &bbb.Frontend{
+				Key:    f.Key,
+				Secret: f.Secret,
+			},
+		})
+		log.Info().
+			Str("key", f.Key).
+			Msg("provisioning static frontend")
+	} else if state.Frontend.Secret == f.Secret {
+		return nil
+	}
+	state.Frontend.Secret = f.Secret
+	return state.Save(ctx, tx)
+}
@@ -0,0 +1,301 @@
+package cluster
+
+import (
+	"context"
+	"sort"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// A ReconciledMeeting is known only to the store
+// or only to the backend.
+type ReconciledMeeting struct {
+	MeetingID         string  `json:"meeting_id"`
+	InternalMeetingID string  `json:"internal_meeting_id"`
+	MeetingName       string  `json:"meeting_name,omitempty"`
+	FrontendID        *string `json:"frontend_id,omitempty"`
+}
+
+// MeetingsReconciliation compares the meetings in the store
+// with the meetings running on a backend. The meetings are
+// identified by the internal meeting ID.
+type MeetingsReconciliation struct {
+	BackendID string `json:"backend_id"`
+	Host      string `json:"host"`
+
+	// Error is set when the backend could not be queried
+	Error string `json:"error,omitempty"`
+
+	// Matched is the number of meetings known
+	// to both, the store and the backend.
+	Matched int `json:"matched"`
+
+	// OnlyStore are meetings in the store, which are
+	// not running on the backend.
+	OnlyStore []*ReconciledMeeting `json:"only_store"`
+
+	// OnlyBackend are meetings running on the backend,
+	// which are unknown to the store.
+	OnlyBackend []*ReconciledMeeting `json:"only_backend"`
+}
+
+// Consistent is true if all meetings are known to
+// both, the store and the backend.
+func (r *MeetingsReconciliation) Consistent() bool {
+	return r.Error == "" &&
+		len(r.OnlyStore) == 0 &&
+		len(r.OnlyBackend) == 0
+}
+
+// ReconcileMeetingStates compares the stored meetings
+// with the meetings running on the backend.
+func ReconcileMeetingStates(
+	stored []*store.MeetingState,
+	running []*bbb.Meeting,
+) *MeetingsReconciliation {
+	r := &MeetingsReconciliation{
+		OnlyStore:   []*ReconciledMeeting{},
+		OnlyBackend: []*ReconciledMeeting{},
+	}
+	known := make(map[string]bool, len(stored))
+	for _, m := range stored {
+		known[m.InternalID] = true
+	}
+	live := make(map[string]bool, len(running))
+	for _, m := range running {
+		live[m.InternalMeetingID] = true
+		if known[m.InternalMeetingID] {
+			r.Matched++
+			continue
+		}
+		r.OnlyBackend = append(r.OnlyBackend, &ReconciledMeeting{
+			MeetingID:         m.MeetingID,
+			InternalMeetingID: m.InternalMeetingID,
+			MeetingName:       m.MeetingName,
+		})
+	}
+	for _, m := range stored {
+		if live[m.InternalID] {
+			continue
+		}
+		rm := &ReconciledMeeting{
+			MeetingID:         m.ID,
+			InternalMeetingID: m.InternalID,
+			FrontendID:        m.FrontendID,
+		}
+		if m.Meeting != nil {
+			rm.MeetingName = m.Meeting.MeetingName
+		}
+		r.OnlyStore = append(r.OnlyStore, rm)
+	}
+	sort.Slice(r.OnlyStore, func(i, j int) bool {
+		return r.OnlyStore[i].MeetingID < r.OnlyStore[j].MeetingID
+	})
+	sort.Slice(r.OnlyBackend, func(i, j int) bool {
+		return r.OnlyBackend[i].MeetingID < r.OnlyBackend[j].MeetingID
+	})
+	return r
+}
+
+// ReconcileMeetings compares the meetings in the store
+// with the live meetings of the backend. The store is
+// not modified. A backend which can not be queried is
+// reported with an error.
+func (b *Backend) ReconcileMeetings(
+	ctx context.Context,
+) (*MeetingsReconciliation, error) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	stored, err := store.GetMeetingStates(ctx, tx, store.Q().
+		Where("backend_id = ?", b.state.ID))
+	if err != nil {
+		return nil, err
+	}
+	tx.Rollback(ctx) // Do not block the connection
+
+	// Without the live meetings, nothing can be compared.
+	r := ReconcileMeetingStates(nil, nil)
+	res, err := b.GetMeetings(ctx, bbb.GetMeetingsRequest(bbb.Params{}))
+	if err != nil {
+		r.Error = err.Error()
+	} else if res.Returncode != bbb.RetSuccess {
+		r.Error = res.MessageKey + ": " + res.Message
+	} else {
+		r = ReconcileMeetingStates(stored, res.Meetings)
+	}
+	r.BackendID = b.state.ID
+	r.Host = b.state.Backend.Host
+	return r, nil
+}
+
+// A ReconciledRecording is known only to the store
+// or only to the backend.
+type ReconciledRecording struct {
+	RecordID          string `json:"record_id"`
+	MeetingID         string `json:"meeting_id"`
+	InternalMeetingID string `json:"internal_meeting_id"`
+	Name              string `json:"name,omitempty"`
+}
+
+// RecordingsReconciliation compares the recordings in the
+// store with the recordings of a backend. The recordings
+// are identified by the record ID.
+type RecordingsReconciliation struct {
+	BackendID string `json:"backend_id"`
+	Host      string `json:"host"`
+
+	// Error is set when the backend could not be queried
+	Error string `json:"error,omitempty"`
+
+	// Matched is the number of recordings known
+	// to both, the store and the backend.
+	Matched int `json:"matched"`
+
+	// OnlyStore are recordings in the store, which
+	// are not present on the backend.
+	OnlyStore []*ReconciledRecording `json:"only_store"`
+
+	// OnlyBackend are recordings on the backend,
+	// which are missing in the store.
+	OnlyBackend []*ReconciledRecording `json:"only_backend"`
+
+	// Imported is the number of recordings added
+	// to the store.
+	Imported int `json:"imported"`
+}
+
+// Consistent is true if all recordings are known to
+// both, the store and the backend.
+func (r *RecordingsReconciliation) Consistent() bool {
+	return r.Error == "" &&
+		len(r.OnlyStore) == 0 &&
+		len(r.OnlyBackend) == 0
+}
+
+func reconciledRecording(rec *bbb.Recording) *ReconciledRecording {
+	return &ReconciledRecording{
+		RecordID:          rec.RecordID,
+		MeetingID:         rec.MeetingID,
+		InternalMeetingID: rec.InternalMeetingID,
+		Name:              rec.Name,
+	}
+}
+
+// ReconcileRecordingStates compares the stored recordings
+// with the recordings of the backend.
+func ReconcileRecordingStates(
+	stored []*store.RecordingState,
+	present []*bbb.Recording,
+) *RecordingsReconciliation {
+	r := &RecordingsReconciliation{
+		OnlyStore:   []*ReconciledRecording{},
+		OnlyBackend: []*ReconciledRecording{},
+	}
+	known := make(map[string]bool, len(stored))
+	for _, rec := range stored {
+		known[rec.ID] = true
+	}
+	live := make(map[string]bool, len(present))
+	for _, rec := range present {
+		live[rec.RecordID] = true
+		if known[rec.RecordID] {
+			r.Matched++
+			continue
+		}
+		r.OnlyBackend = append(r.OnlyBackend, reconciledRecording(rec))
+	}
+	for _, rec := range stored {
+		if live[rec.ID] {
+			continue
+		}
+		rr := &ReconciledRecording{
+			RecordID:          rec.ID,
+			InternalMeetingID: rec.InternalMeetingID,
+		}
+		if rec.Recording != nil {
+			rr = reconciledRecording(rec.Recording)
+			rr.RecordID = rec.ID
+		}
+		r.OnlyStore = append(r.OnlyStore, rr)
+	}
+	sort.Slice(r.OnlyStore, func(i, j int) bool {
+		return r.OnlyStore[i].RecordID < r.OnlyStore[j].RecordID
+	})
+	sort.Slice(r.OnlyBackend, func(i, j int) bool {
+		return r.OnlyBackend[i].RecordID < r.OnlyBackend[j].RecordID
+	})
+	return r
+}
+
+// ReconcileRecordings compares the recordings in the store
+// with the recordings of the backend. With importMissing,
+// recordings missing in the store are added.
+func (b *Backend) ReconcileRecordings(
+	ctx context.Context,
+	importMissing bool,
+) (*RecordingsReconciliation, error) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	stored, err := store.GetRecordingStates(ctx, tx, store.Q().
+		Where("backend_id = ?", b.state.ID))
+	if err != nil {
+		return nil, err
+	}
+	tx.Rollback(ctx) // Do not block the connection
+
+	r := ReconcileRecordingStates(nil, nil)
+	r.BackendID = b.state.ID
+	r.Host = b.state.Backend.Host
+	res, err := b.GetRecordings(ctx, bbb.GetRecordingsRequest(bbb.Params{}))
+	if err != nil {
+		r.Error = err.Error()
+		return r, nil
+	}
+	if res.Returncode != bbb.RetSuccess {
+		r.Error = res.MessageKey + ": " + res.Message
+		return r, nil
+	}
+	r = ReconcileRecordingStates(stored, res.Recordings)
+	r.BackendID = b.state.ID
+	r.Host = b.state.Backend.Host
+	if !importMissing || len(r.OnlyBackend) == 0 {
+		return r, nil
+	}
+
+	missing := make(map[string]bool, len(r.OnlyBackend))
+	for _, rec := range r.OnlyBackend {
+		missing[rec.RecordID] = true
+	}
+	tx, err = store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	for _, rec := range res.Recordings {
+		if !missing[rec.RecordID] {
+			continue
+		}
+		state := store.NewRecordingState(b.state.ID, rec)
+		if err := state.Save(ctx, tx); err != nil {
+			return nil, err
+		}
+		r.Imported++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	log.Info().
+		Str("backend", b.state.Backend.Host).
+		Int("recordings", r.Imported).
+		Msg("imported missing recordings")
+	return r, nil
+}
@@ -0,0 +1,66 @@
+package cluster
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestReconcileMeetingStates(t *testing.T) {
+	stored := []*store.MeetingState{
+		{ID: "m1", InternalID: "i1", Meeting: &bbb.Meeting{}},
+		{ID: "m2", InternalID: "i2", Meeting: &bbb.Meeting{
+			MeetingName: "Gone",
+		}},
+	}
+	running := []*bbb.Meeting{
+		{MeetingID: "m1", InternalMeetingID: "i1"},
+		{MeetingID: "m3", InternalMeetingID: "i3", MeetingName: "Unknown"},
+	}
+	r := ReconcileMeetingStates(stored, running)
+	if r.Matched != 1 {
+		t.Error("unexpected matched:", r.Matched)
+	}
+	if len(r.OnlyStore) != 1 || r.OnlyStore[0].MeetingID != "m2" {
+		t.Error("unexpected only store:", r.OnlyStore)
+	}
+	if r.OnlyStore[0].MeetingName != "Gone" {
+		t.Error("unexpected meeting name:", r.OnlyStore[0].MeetingName)
+	}
+	if len(r.OnlyBackend) != 1 || r.OnlyBackend[0].MeetingID != "m3" {
+		t.Error("unexpected only backend:", r.OnlyBackend)
+	}
+	if r.Consistent() {
+		t.Error("the meetings should not be consistent")
+	}
+
+	r = ReconcileMeetingStates(stored[:1], running[:1])
+	if !r.Consistent() {
+		t.Error("the meetings should be consistent")
+	}
+}
+
+func TestReconcileRecordingStates(t *testing.T) {
+	stored := []*store.RecordingState{
+		{ID: "r1", Recording: &bbb.Recording{RecordID: "r1"}},
+		{ID: "r2", InternalMeetingID: "i2"},
+	}
+	present := []*bbb.Recording{
+		{RecordID: "r1"},
+		{RecordID: "r3", MeetingID: "m3", Name: "Lecture"},
+	}
+	r := ReconcileRecordingStates(stored, present)
+	if r.Matched != 1 {
+		t.Error("unexpected matched:", r.Matched)
+	}
+	if len(r.OnlyStore) != 1 || r.OnlyStore[0].RecordID != "r2" {
+		t.Error("unexpected only store:", r.OnlyStore)
+	}
+	if len(r.OnlyBackend) != 1 || r.OnlyBackend[0].Name != "Lecture" {
+		t.Error("unexpected only backend:", r.OnlyBackend)
+	}
+	if r.Consistent() {
+		t.Error("the recordings should not be consistent")
+	}
+}
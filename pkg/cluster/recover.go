@@ -0,0 +1,88 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// A Recovery reports the meetings and recordings
+// restored from a backend.
+type Recovery struct {
+	BackendID string `json:"backend_id"`
+	Host      string `json:"host"`
+
+	// Error is set when the backend could not be queried
+	Error string `json:"error,omitempty"`
+
+	// Meetings is the number of restored meetings
+	Meetings int `json:"meetings"`
+
+	// Recordings is the number of restored recordings
+	Recordings int `json:"recordings"`
+}
+
+// Recover rebuilds the meetings and recordings of the
+// backend in the store from the live state of the backend,
+// e.g. after a loss of the database. Existing states are
+// updated. The restored meetings are not associated with
+// a frontend, until they are claimed by the next request
+// of a frontend.
+func (b *Backend) Recover(ctx context.Context) (*Recovery, error) {
+	r := &Recovery{
+		BackendID: b.state.ID,
+		Host:      b.state.Backend.Host,
+	}
+
+	// Both lists are required before the store is modified
+	meetings, err := b.GetMeetings(ctx, bbb.GetMeetingsRequest(bbb.Params{}))
+	if err != nil {
+		r.Error = err.Error()
+		return r, nil
+	}
+	if meetings.Returncode != bbb.RetSuccess {
+		r.Error = meetings.MessageKey + ": " + meetings.Message
+		return r, nil
+	}
+	recordings, err := b.GetRecordings(
+		ctx, bbb.GetRecordingsRequest(bbb.Params{}))
+	if err != nil {
+		r.Error = err.Error()
+		return r, nil
+	}
+	if recordings.Returncode != bbb.RetSuccess {
+		r.Error = recordings.MessageKey + ": " + recordings.Message
+		return r, nil
+	}
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	for _, m := range meetings.Meetings {
+		if err := b.state.CreateOrUpdateMeetingState(ctx, tx, m); err != nil {
+			return nil, err
+		}
+		r.Meetings++
+	}
+	for _, rec := range recordings.Recordings {
+		state := store.NewRecordingState(b.state.ID, rec)
+		if err := state.Save(ctx, tx); err != nil {
+			return nil, err
+		}
+		r.Recordings++
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	log.Info().
+		Str("backend", b.state.Backend.Host).
+		Int("meetings", r.Meetings).
+		Int("recordings", r.Recordings).
+		Msg("recovered state from backend")
+	return r, nil
+}
@@ -0,0 +1,76 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+)
+
+// RIBEntryTTL is the time after which an entry of the
+// RIB expires, unless it is refreshed by a join. Expired
+// meetings are looked up in the store again.
+var RIBEntryTTL = time.Hour
+
+// RIBSweepInterval is the interval in which the RIB
+// is compared with the meetings in the store.
+const RIBSweepInterval = 5 * time.Minute
+
+// RIB implementations
+const (
+	RIBPostgres = "postgres"
+	RIBMemory   = "memory"
+)
+
+// A RIB (routing information base) maps meetings to the
+// backends running the meetings. Requests for existing
+// meetings look up the backend in the RIB, so it is on
+// the hot path of joins.
+//
+// The meetings in the store remain the durable state.
+// A RIB may hold a faster copy of the mapping and
+// fall back to the store for unknown meetings.
+type RIB interface {
+	// GetBackendID returns the ID of the backend running
+	// the meeting. The ID is empty if the meeting is unknown.
+	GetBackendID(ctx context.Context, meetingID string) (string, error)
+
+	// SetBackendID associates the meeting with a backend.
+	SetBackendID(ctx context.Context, meetingID, backendID string) error
+
+	// Delete removes the meeting from the RIB.
+	Delete(ctx context.Context, meetingID string) error
+}
+
+// A RIBSweeper is a RIB which can drift from the
+// meetings in the store, e.g. after a crashed meeting.
+type RIBSweeper interface {
+	// Sweep removes the entries not matching the
+	// meetings in the store and returns the number
+	// of removed entries.
+	Sweep(ctx context.Context) (int, error)
+}
+
+// NewRIB creates a RIB from the configuration: Either
+// `postgres`, `memory` or the URL of a redis server like
+// redis://localhost:6379/1
+func NewRIB(spec string) (RIB, error) {
+	spec = strings.TrimSpace(spec)
+	if spec == "" || spec == RIBPostgres {
+		return &PostgresRIB{}, nil
+	}
+	if spec == RIBMemory {
+		return NewMemoryRIB(&PostgresRIB{}), nil
+	}
+	if strings.HasPrefix(spec, "redis://") ||
+		strings.HasPrefix(spec, "rediss://") {
+		opts, err := redis.ParseURL(spec)
+		if err != nil {
+			return nil, err
+		}
+		return NewRedisRIB(redis.NewClient(opts), &PostgresRIB{}), nil
+	}
+	return nil, fmt.Errorf("unsupported rib: %s", spec)
+}
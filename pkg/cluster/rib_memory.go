@@ -0,0 +1,146 @@
+package cluster
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// memoryRIBShards is the number of shards of the
+// memory RIB. Each shard is a sync.Map.
+const memoryRIBShards = 32
+
+// memoryRIBEntry is the backend of a meeting
+type memoryRIBEntry struct {
+	backendID string
+	expiresAt time.Time
+}
+
+// The MemoryRIB keeps the backends of the meetings in
+// the memory of the process. It is meant for deployments
+// with a single b3scale instance: Other instances would
+// not notice changes.
+//
+// Like the RedisRIB, unknown meetings are looked up in
+// the durable RIB and the entries expire after the
+// RIBEntryTTL.
+type MemoryRIB struct {
+	shards  [memoryRIBShards]sync.Map
+	durable RIB
+}
+
+// NewMemoryRIB creates a RIB in memory in
+// front of a durable RIB.
+func NewMemoryRIB(durable RIB) *MemoryRIB {
+	return &MemoryRIB{
+		durable: durable,
+	}
+}
+
+// shard selects the map for the meeting
+func (rib *MemoryRIB) shard(meetingID string) *sync.Map {
+	h := fnv.New32a()
+	h.Write([]byte(meetingID))
+	return &rib.shards[h.Sum32()%memoryRIBShards]
+}
+
+// set stores the entry in the shard
+func (rib *MemoryRIB) set(meetingID, backendID string) {
+	rib.shard(meetingID).Store(meetingID, &memoryRIBEntry{
+		backendID: backendID,
+		expiresAt: time.Now().Add(RIBEntryTTL),
+	})
+}
+
+// GetBackendID looks up the backend of the meeting
+func (rib *MemoryRIB) GetBackendID(
+	ctx context.Context,
+	meetingID string,
+) (string, error) {
+	if e, ok := rib.shard(meetingID).Load(meetingID); ok {
+		entry := e.(*memoryRIBEntry)
+		if time.Now().Before(entry.expiresAt) {
+			return entry.backendID, nil
+		}
+	}
+	id, err := rib.durable.GetBackendID(ctx, meetingID)
+	if err != nil || id == "" {
+		return id, err
+	}
+	rib.set(meetingID, id)
+	return id, nil
+}
+
+// SetBackendID associates the meeting with a backend
+// in memory and the durable RIB. An existing entry
+// is refreshed.
+func (rib *MemoryRIB) SetBackendID(
+	ctx context.Context,
+	meetingID, backendID string,
+) error {
+	if err := rib.durable.SetBackendID(ctx, meetingID, backendID); err != nil {
+		return err
+	}
+	rib.set(meetingID, backendID)
+	return nil
+}
+
+// Delete removes the meeting from memory and
+// the durable RIB.
+func (rib *MemoryRIB) Delete(
+	ctx context.Context,
+	meetingID string,
+) error {
+	if err := rib.durable.Delete(ctx, meetingID); err != nil {
+		return err
+	}
+	rib.shard(meetingID).Delete(meetingID)
+	return nil
+}
+
+// Sweep removes expired entries and the entries of
+// meetings not matching the meetings in the store.
+func (rib *MemoryRIB) Sweep(ctx context.Context) (int, error) {
+	now := time.Now()
+	removed := 0
+	entries := map[string]string{}
+	for i := range rib.shards {
+		rib.shards[i].Range(func(k, v interface{}) bool {
+			entry := v.(*memoryRIBEntry)
+			if now.After(entry.expiresAt) {
+				rib.shards[i].Delete(k)
+				removed++
+				return true
+			}
+			entries[k.(string)] = entry.backendID
+			return true
+		})
+	}
+	if len(entries) == 0 {
+		return removed, nil
+	}
+
+	meetingIDs := make([]string, 0, len(entries))
+	for id := range entries {
+		meetingIDs = append(meetingIDs, id)
+	}
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return removed, err
+	}
+	defer tx.Rollback(ctx)
+	backends, err := store.GetMeetingBackendIDs(ctx, tx, meetingIDs)
+	if err != nil {
+		return removed, err
+	}
+	for id, backendID := range entries {
+		if backends[id] != backendID {
+			rib.shard(id).Delete(id)
+			removed++
+		}
+	}
+	return removed, nil
+}
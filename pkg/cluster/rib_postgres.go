@@ -0,0 +1,43 @@
+package cluster
+
+import (
+	"context"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// The PostgresRIB reads the backends of the meetings
+// from the meetings in the store.
+type PostgresRIB struct{}
+
+// GetBackendID looks up the backend of the meeting
+// in the store.
+func (rib *PostgresRIB) GetBackendID(
+	ctx context.Context,
+	meetingID string,
+) (string, error) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback(ctx)
+	return store.GetMeetingBackendID(ctx, tx, meetingID)
+}
+
+// SetBackendID does nothing: The backend is stored
+// with the meeting state.
+func (rib *PostgresRIB) SetBackendID(
+	ctx context.Context,
+	meetingID, backendID string,
+) error {
+	return nil
+}
+
+// Delete does nothing: The meeting state is removed
+// when the meeting ends.
+func (rib *PostgresRIB) Delete(
+	ctx context.Context,
+	meetingID string,
+) error {
+	return nil
+}
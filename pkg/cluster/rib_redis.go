@@ -0,0 +1,164 @@
+package cluster
+
+import (
+	"context"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ribKeyPrefix is the prefix of the redis keys
+const ribKeyPrefix = "b3scale:rib:"
+
+// ribSweepBatchSize is the number of keys
+// compared with the store at once.
+const ribSweepBatchSize = 500
+
+// The RedisRIB keeps the backends of the meetings in
+// redis. Meetings unknown to redis are looked up in the
+// durable RIB and added. When redis is not available,
+// the durable RIB is used.
+//
+// The entries expire after the RIBEntryTTL. Creating
+// and joining a meeting refreshes the entry, ending the
+// meeting removes it. Meetings ending otherwise, e.g.
+// when the backend crashed, are removed by the sweep.
+type RedisRIB struct {
+	rdb     *redis.Client
+	durable RIB
+}
+
+// NewRedisRIB creates a RIB with a redis client
+// in front of a durable RIB.
+func NewRedisRIB(rdb *redis.Client, durable RIB) *RedisRIB {
+	return &RedisRIB{
+		rdb:     rdb,
+		durable: durable,
+	}
+}
+
+// GetBackendID looks up the backend of the meeting
+func (rib *RedisRIB) GetBackendID(
+	ctx context.Context,
+	meetingID string,
+) (string, error) {
+	id, err := rib.rdb.Get(ctx, ribKeyPrefix+meetingID).Result()
+	if err == nil {
+		return id, nil
+	}
+	if err != redis.Nil {
+		log.Warn().
+			Err(err).
+			Str("meetingID", meetingID).
+			Msg("redis rib unavailable, using store")
+	}
+	id, err = rib.durable.GetBackendID(ctx, meetingID)
+	if err != nil || id == "" {
+		return id, err
+	}
+	if err := rib.rdb.Set(
+		ctx, ribKeyPrefix+meetingID, id, RIBEntryTTL,
+	).Err(); err != nil {
+		log.Warn().Err(err).Msg("could not update redis rib")
+	}
+	return id, nil
+}
+
+// SetBackendID associates the meeting with a backend
+// in redis and the durable RIB. An existing entry
+// is refreshed.
+func (rib *RedisRIB) SetBackendID(
+	ctx context.Context,
+	meetingID, backendID string,
+) error {
+	if err := rib.durable.SetBackendID(ctx, meetingID, backendID); err != nil {
+		return err
+	}
+	return rib.rdb.Set(
+		ctx, ribKeyPrefix+meetingID, backendID, RIBEntryTTL).Err()
+}
+
+// Delete removes the meeting from redis and
+// the durable RIB.
+func (rib *RedisRIB) Delete(
+	ctx context.Context,
+	meetingID string,
+) error {
+	if err := rib.durable.Delete(ctx, meetingID); err != nil {
+		return err
+	}
+	return rib.rdb.Del(ctx, ribKeyPrefix+meetingID).Err()
+}
+
+// Sweep removes the entries of meetings, which are
+// unknown to the store or which are associated
+// with another backend in the store.
+func (rib *RedisRIB) Sweep(ctx context.Context) (int, error) {
+	removed := 0
+	var cursor uint64
+	for {
+		keys, next, err := rib.rdb.Scan(
+			ctx, cursor, ribKeyPrefix+"*", ribSweepBatchSize).Result()
+		if err != nil {
+			return removed, err
+		}
+		n, err := rib.sweepKeys(ctx, keys)
+		removed += n
+		if err != nil {
+			return removed, err
+		}
+		cursor = next
+		if cursor == 0 {
+			return removed, nil
+		}
+	}
+}
+
+// sweepKeys compares a batch of entries with the store
+func (rib *RedisRIB) sweepKeys(
+	ctx context.Context,
+	keys []string,
+) (int, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	values, err := rib.rdb.MGet(ctx, keys...).Result()
+	if err != nil {
+		return 0, err
+	}
+	meetingIDs := make([]string, len(keys))
+	for i, key := range keys {
+		meetingIDs[i] = key[len(ribKeyPrefix):]
+	}
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback(ctx)
+	backends, err := store.GetMeetingBackendIDs(ctx, tx, meetingIDs)
+	if err != nil {
+		return 0, err
+	}
+	tx.Rollback(ctx) // Do not block the connection
+
+	stale := []string{}
+	for i, id := range meetingIDs {
+		backendID, ok := values[i].(string)
+		if !ok {
+			continue // Expired in the meantime
+		}
+		if backends[id] != backendID {
+			stale = append(stale, keys[i])
+		}
+	}
+	if len(stale) == 0 {
+		return 0, nil
+	}
+	if err := rib.rdb.Del(ctx, stale...).Err(); err != nil {
+		return 0, err
+	}
+	return len(stale), nil
+}
@@ -0,0 +1,89 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+)
+
+func TestNewRIB(t *testing.T) {
+	rib, err := NewRIB("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rib.(*PostgresRIB); !ok {
+		t.Errorf("unexpected rib: %T", rib)
+	}
+
+	rib, err = NewRIB("memory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rib.(*MemoryRIB); !ok {
+		t.Errorf("unexpected rib: %T", rib)
+	}
+
+	rib, err = NewRIB("redis://localhost:6379/1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := rib.(*RedisRIB); !ok {
+		t.Errorf("unexpected rib: %T", rib)
+	}
+
+	if _, err := NewRIB("etcd://localhost"); err == nil {
+		t.Error("expected an error for an unsupported rib")
+	}
+}
+
+// staticRIB is a durable RIB for testing
+type staticRIB map[string]string
+
+func (rib staticRIB) GetBackendID(
+	ctx context.Context, meetingID string,
+) (string, error) {
+	return rib[meetingID], nil
+}
+
+func (rib staticRIB) SetBackendID(
+	ctx context.Context, meetingID, backendID string,
+) error {
+	rib[meetingID] = backendID
+	return nil
+}
+
+func (rib staticRIB) Delete(ctx context.Context, meetingID string) error {
+	delete(rib, meetingID)
+	return nil
+}
+
+func TestMemoryRIB(t *testing.T) {
+	ctx := context.Background()
+	durable := staticRIB{"meeting1": "backend1"}
+	rib := NewMemoryRIB(durable)
+
+	// Unknown meetings are looked up in the durable RIB
+	id, err := rib.GetBackendID(ctx, "meeting1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != "backend1" {
+		t.Error("unexpected backend:", id)
+	}
+	delete(durable, "meeting1")
+	if id, _ := rib.GetBackendID(ctx, "meeting1"); id != "backend1" {
+		t.Error("expected the cached backend:", id)
+	}
+
+	if err := rib.SetBackendID(ctx, "meeting2", "backend2"); err != nil {
+		t.Fatal(err)
+	}
+	if durable["meeting2"] != "backend2" {
+		t.Error("durable rib was not updated")
+	}
+	if err := rib.Delete(ctx, "meeting2"); err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := rib.GetBackendID(ctx, "meeting2"); id != "" {
+		t.Error("unexpected backend:", id)
+	}
+}
@@ -0,0 +1,194 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// RoomPrecreateLead is the time before the scheduled
+// start of a room when the meeting is created.
+var RoomPrecreateLead = 5 * time.Minute
+
+// roomSchedulerInterval is the interval in which
+// the rooms are checked for upcoming starts.
+const roomSchedulerInterval = time.Minute
+
+// paramExpireIfNoUserJoined is the create parameter of the
+// time in minutes after which an empty meeting is ended.
+const paramExpireIfNoUserJoined = "meetingExpireIfNoUserJoinedInMinutes"
+
+// The RoomScheduler creates the meetings of rooms with
+// a schedule shortly before the start, so the first join
+// does not wait for the meeting to be created.
+type RoomScheduler struct {
+	gateway *Gateway
+}
+
+// NewRoomScheduler creates a room scheduler. The meetings
+// are created through the gateway, like create requests
+// of the frontends.
+func NewRoomScheduler(gateway *Gateway) *RoomScheduler {
+	return &RoomScheduler{
+		gateway: gateway,
+	}
+}
+
+// Start periodically creates the meetings of
+// rooms starting soon.
+func (s *RoomScheduler) Start() {
+	log.Info().
+		Dur("lead", RoomPrecreateLead).
+		Msg("starting room scheduler")
+	for {
+		time.Sleep(roomSchedulerInterval)
+		if err := s.createDueMeetings(); err != nil {
+			log.Error().Err(err).Msg("create meetings of scheduled rooms")
+		}
+	}
+}
+
+// createDueMeetings advances the schedules of the rooms
+// starting within the lead time and creates the meetings.
+func (s *RoomScheduler) createDueMeetings() error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	ctx = store.ContextWithConnection(ctx, conn)
+
+	due, err := s.advanceDueRooms(ctx)
+	if err != nil {
+		return err
+	}
+	for _, room := range due {
+		if err := s.createMeeting(ctx, conn, room); err != nil {
+			log.Error().
+				Err(err).
+				Str("room", room.ID).
+				Str("meetingID", room.MeetingID).
+				Msg("could not create meeting of scheduled room")
+		}
+	}
+	return nil
+}
+
+// advanceDueRooms sets the next start of the rooms starting
+// within the lead time. Only one instance advances a room,
+// so the meeting is created once. Starts missed for longer
+// than the lead time, e.g. during a downtime, are skipped.
+func (s *RoomScheduler) advanceDueRooms(
+	ctx context.Context,
+) ([]*store.Room, error) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	now := time.Now().UTC()
+	rooms, err := store.GetDueRooms(ctx, tx, now.Add(RoomPrecreateLead))
+	if err != nil {
+		return nil, err
+	}
+	due := make([]*store.Room, 0, len(rooms))
+	for _, room := range rooms {
+		start := *room.NextStartAt
+		after := start
+		if now.After(after) {
+			after = now
+		}
+		next, err := room.NextStart(after)
+		if err != nil {
+			// The schedule can not be continued
+			log.Error().
+				Err(err).
+				Str("room", room.ID).
+				Msg("could not calculate next start of room")
+			next = nil
+		}
+		room.NextStartAt = next
+		if err := room.SaveSchedule(ctx, tx); err != nil {
+			return nil, err
+		}
+		if now.Sub(start) > RoomPrecreateLead {
+			log.Warn().
+				Str("room", room.ID).
+				Time("start", start).
+				Msg("skipping missed start of room")
+			continue
+		}
+		due = append(due, room)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return due, nil
+}
+
+// SignFrontendRequest signs a request made in the
+// name of the frontend with the secret of the frontend,
+// as if it was sent by the frontend.
+func SignFrontendRequest(req *bbb.Request, frontend *Frontend) *bbb.Request {
+	secret := frontend.Frontend().Secret
+	req.Checksum = req.WithBackend(&bbb.Backend{Secret: secret}).Sign()
+	req.Backend = nil
+	return req.WithFrontend(frontend.Frontend())
+}
+
+// createMeeting dispatches a create request for the
+// meeting of the room in the name of the frontend.
+func (s *RoomScheduler) createMeeting(
+	ctx context.Context,
+	conn *pgxpool.Conn,
+	room *store.Room,
+) error {
+	frontend, err := GetFrontend(ctx, store.Q().
+		Where("id = ?", room.FrontendID))
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return fmt.Errorf("frontend not found: %s", room.FrontendID)
+	}
+
+	// The meeting must not end before the first join
+	params := room.CreateParams()
+	if _, ok := params[paramExpireIfNoUserJoined]; !ok {
+		expire := 2*RoomPrecreateLead + 10*time.Minute
+		params[paramExpireIfNoUserJoined] = strconv.Itoa(
+			int(expire / time.Minute))
+	}
+
+	req := SignFrontendRequest(bbb.CreateRequest(params, nil), frontend)
+
+	requestID := uuid.New().String()
+	ctx = ContextWithFrontend(ctx, frontend)
+	ctx = ContextWithRequestID(ctx, requestID)
+	res := s.gateway.Dispatch(ctx, conn, req)
+	createRes, ok := res.(*bbb.CreateResponse)
+	if !ok || createRes.XMLResponse == nil {
+		return fmt.Errorf("unexpected response: %v", res)
+	}
+	if createRes.Returncode != bbb.RetSuccess {
+		return fmt.Errorf("%s: %s", createRes.MessageKey, createRes.Message)
+	}
+
+	log.Info().
+		Str("room", room.ID).
+		Str("meetingID", room.MeetingID).
+		Str("requestID", requestID).
+		Msg("created meeting of scheduled room")
+	return nil
+}
@@ -27,12 +27,19 @@ var (
 	ErrMeetingIDMissing = errors.New("meetingID missing from request")
 )
 
+// heartbeatTimeout is the time after which a backend
+// without a node agent heartbeat is not considered
+// for new meetings.
+const heartbeatTimeout = 5 * time.Second
+
 // The Router provides a requets middleware for routing
 // requests to backends.
 // The routing middleware stack selects backends.
 type Router struct {
-	ctrl       *Controller
-	middleware RouterHandler
+	ctrl        *Controller
+	rib         RIB
+	middleware  RouterHandler
+	middlewares []RouterMiddleware
 }
 
 // NewRouter creates a new router middleware selecting
@@ -45,10 +52,82 @@ type Router struct {
 func NewRouter(ctrl *Controller) *Router {
 	return &Router{
 		ctrl:       ctrl,
+		rib:        &PostgresRIB{},
 		middleware: nilHandler,
 	}
 }
 
+// UseRIB replaces the routing information base
+// used for looking up the backends of meetings.
+func (r *Router) UseRIB(rib RIB) {
+	r.rib = rib
+}
+
+// RememberMeeting associates the meeting of the
+// request with the backend in the RIB.
+func (r *Router) RememberMeeting(
+	ctx context.Context,
+	req *bbb.Request,
+	backend *Backend,
+) error {
+	meetingID, ok := req.Params.MeetingID()
+	if !ok {
+		return ErrMeetingIDMissing
+	}
+	return r.rib.SetBackendID(ctx, meetingID, backend.ID())
+}
+
+// StartRIBSweep periodically removes entries from the RIB,
+// which do not match the meetings in the store.
+func (r *Router) StartRIBSweep() {
+	sweeper, ok := r.rib.(RIBSweeper)
+	if !ok {
+		return // Nothing to do here
+	}
+	for {
+		time.Sleep(RIBSweepInterval)
+		if err := sweepRIB(sweeper); err != nil {
+			log.Error().Err(err).Msg("sweep rib")
+		}
+	}
+}
+
+// sweepRIB runs the sweep with a database connection
+func sweepRIB(sweeper RIBSweeper) error {
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	ctx = store.ContextWithConnection(ctx, conn)
+
+	removed, err := sweeper.Sweep(ctx)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		log.Info().
+			Int("removed", removed).
+			Msg("removed stale meetings from the rib")
+	}
+	return nil
+}
+
+// ForgetMeeting removes the meeting of the
+// request from the RIB.
+func (r *Router) ForgetMeeting(
+	ctx context.Context,
+	req *bbb.Request,
+) error {
+	meetingID, ok := req.Params.MeetingID()
+	if !ok {
+		return ErrMeetingIDMissing
+	}
+	return r.rib.Delete(ctx, meetingID)
+}
+
 // The nil handler is the end of the middleware chain.
 // It will just return the backends as they are.
 func nilHandler(
@@ -62,6 +141,7 @@ func nilHandler(
 // Use will insert a middleware into the chain
 func (r *Router) Use(middleware RouterMiddleware) {
 	r.middleware = middleware(r.middleware)
+	r.middlewares = append(r.middlewares, middleware)
 }
 
 // SelectBackend will apply the routing middleware
@@ -75,7 +155,7 @@ func (r *Router) SelectBackend(
 	// Filter backends and only accept state active,
 	// and where the node agent is active on the host.
 	// Also we exclude stopped nodes.
-	deadline := time.Now().UTC().Add(-5 * time.Second)
+	deadline := time.Now().UTC().Add(-heartbeatTimeout)
 	backends, err := GetBackends(ctx, store.Q().
 		Where("agent_heartbeat >= ?", deadline).
 		Where("admin_state = ?", "ready").
@@ -114,15 +194,28 @@ func (r *Router) LookupBackend(
 		Str("meetingID", meetingID).
 		Msg("lookupBackendForRequest")
 
-	// Lookup backend for meeting in cluster, use backend
+	// Lookup backend for meeting in the RIB, use backend
 	// if there is one associated.
+	backendID, err := r.rib.GetBackendID(ctx, meetingID)
+	if err != nil {
+		return nil, err
+	}
+	if backendID == "" {
+		log.Debug().
+			Str("meetingID", meetingID).
+			Msg("no backend for meetingID")
+		return nil, nil
+	}
 	backend, err := GetBackend(ctx, store.Q().
-		Join("meetings ON meetings.backend_id = backends.id").
-		Where("meetings.id = ?", meetingID))
+		Where("id = ?", backendID))
 	if err != nil {
 		return nil, err
 	}
 	if backend == nil {
+		// The backend was removed
+		if err := r.rib.Delete(ctx, meetingID); err != nil {
+			return nil, err
+		}
 		log.Debug().
 			Str("meetingID", meetingID).
 			Msg("no backend for meetingID")
@@ -0,0 +1,106 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// Warm-up steps
+const (
+	// WarmupCreate creates a probe meeting, checks
+	// that it is running and ends it.
+	WarmupCreate = "create"
+
+	// WarmupRecordings checks that the recordings
+	// of the backend can be listed.
+	WarmupRecordings = "recordings"
+)
+
+// warmupMeetingPrefix marks the meeting IDs of probe meetings
+const warmupMeetingPrefix = "b3scale-warmup-"
+
+// WarmupSteps are run before a backend, which was added
+// or recovered from an error, is marked as ready.
+// The warm-up is disabled if empty.
+var WarmupSteps []string
+
+// ParseWarmupSteps reads a comma separated list of steps
+func ParseWarmupSteps(value string) ([]string, error) {
+	steps := []string{}
+	for _, s := range strings.Split(value, ",") {
+		s = strings.TrimSpace(s)
+		switch s {
+		case "":
+			continue
+		case WarmupCreate, WarmupRecordings:
+			steps = append(steps, s)
+		default:
+			return nil, fmt.Errorf("unknown warm-up step: %s", s)
+		}
+	}
+	return steps, nil
+}
+
+// warmUp runs the warm-up steps against the backend.
+// The first failing step is returned as error.
+func (b *Backend) warmUp(ctx context.Context) error {
+	for _, step := range WarmupSteps {
+		var err error
+		switch step {
+		case WarmupCreate:
+			err = b.warmUpCreate(ctx)
+		case WarmupRecordings:
+			err = b.warmUpRecordings(ctx)
+		}
+		if err != nil {
+			return fmt.Errorf("warm-up %s: %w", step, err)
+		}
+	}
+	if len(WarmupSteps) > 0 {
+		log.Info().
+			Str("backend", b.Host()).
+			Strs("steps", WarmupSteps).
+			Msg("backend warm-up passed")
+	}
+	return nil
+}
+
+// warmUpCreate creates, checks and ends a probe meeting
+func (b *Backend) warmUpCreate(ctx context.Context) error {
+	meetingID := warmupMeetingPrefix + uuid.New().String()
+	moderatorPW := uuid.New().String()
+	if err := b.createProbeMeeting(ctx, meetingID, moderatorPW); err != nil {
+		return err
+	}
+	// End the meeting in any case
+	defer b.endProbeMeeting(ctx, meetingID, moderatorPW)
+
+	req := bbb.GetMeetingInfoRequest(bbb.Params{
+		"meetingID": meetingID,
+	}).WithBackend(b.state.Backend)
+	res, err := b.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return probeResponseError(res)
+}
+
+// warmUpRecordings lists the recordings of the probe
+// meetings, which fails if the recording service is
+// misconfigured.
+func (b *Backend) warmUpRecordings(ctx context.Context) error {
+	req := bbb.GetRecordingsRequest(bbb.Params{
+		"meetingID": warmupMeetingPrefix + "recordings",
+	}).WithBackend(b.state.Backend)
+	res, err := b.client.Do(ctx, req)
+	if err != nil {
+		return err
+	}
+	return probeResponseError(res)
+}
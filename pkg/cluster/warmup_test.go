@@ -0,0 +1,30 @@
+package cluster
+
+import (
+	"testing"
+)
+
+func TestParseWarmupSteps(t *testing.T) {
+	steps, err := ParseWarmupSteps("create, recordings")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 2 {
+		t.Fatal("unexpected steps:", steps)
+	}
+	if steps[0] != WarmupCreate || steps[1] != WarmupRecordings {
+		t.Error("unexpected steps:", steps)
+	}
+
+	steps, err = ParseWarmupSteps("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(steps) != 0 {
+		t.Error("expected no steps:", steps)
+	}
+
+	if _, err := ParseWarmupSteps("create,reboot"); err == nil {
+		t.Error("expected an error for an unknown step")
+	}
+}
@@ -0,0 +1,84 @@
+package config
+
+/*
+ Join admission: At the top of the hour, many participants
+ join the same meeting within seconds. Joins exceeding a
+ burst are spread over a few seconds.
+
+ The policy is a comma separated list of options:
+
+    burst=20,window=1s,spread=5s,prewarm=true
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// AdmissionPolicy describes when joins to a meeting
+// are delayed and by how much.
+type AdmissionPolicy struct {
+	// Burst is the number of joins to a meeting
+	// within the window, which are admitted immediately.
+	Burst  int
+	Window time.Duration
+
+	// Spread is the maximum random delay of
+	// a join exceeding the burst.
+	Spread time.Duration
+
+	// Prewarm requests the meeting info from the backend
+	// right after the meeting was created.
+	Prewarm bool
+}
+
+// ParseAdmissionPolicy reads a join admission policy. An
+// empty policy string disables the smoothing and yields nil.
+func ParseAdmissionPolicy(policy string) (*AdmissionPolicy, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return nil, nil
+	}
+	p := &AdmissionPolicy{
+		Burst:  20,
+		Window: time.Second,
+		Spread: 5 * time.Second,
+	}
+	for _, opt := range strings.Split(policy, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid admission option: %s", opt)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "burst":
+			p.Burst, err = parseLimit(val)
+		case "window":
+			p.Window, err = time.ParseDuration(val)
+			if err == nil && p.Window <= 0 {
+				err = fmt.Errorf("window must be positive: %s", val)
+			}
+		case "spread":
+			p.Spread, err = time.ParseDuration(val)
+			if err == nil && p.Spread <= 0 {
+				err = fmt.Errorf("spread must be positive: %s", val)
+			}
+		case "prewarm":
+			p.Prewarm, err = strconv.ParseBool(val)
+		default:
+			err = fmt.Errorf("unknown admission option: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
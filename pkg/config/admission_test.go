@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseAdmissionPolicy(t *testing.T) {
+	p, err := ParseAdmissionPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Error("expected no policy")
+	}
+
+	p, err = ParseAdmissionPolicy("burst=50, spread=3s, prewarm=true")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Burst != 50 || p.Window != time.Second {
+		t.Error("unexpected burst:", p.Burst, p.Window)
+	}
+	if p.Spread != 3*time.Second {
+		t.Error("unexpected spread:", p.Spread)
+	}
+	if !p.Prewarm {
+		t.Error("expected prewarming")
+	}
+
+	for _, invalid := range []string{
+		"burst", "burst=0", "window=-1s", "spread=0s", "prewarm=maybe", "rate=3",
+	} {
+		if _, err := ParseAdmissionPolicy(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package config
+
+import (
+	// Embed the default configuration
+	_ "embed"
+)
+
+//go:embed default.env
+var defaultConfig []byte
+
+// DefaultConfig is an environment file with all options
+// and their defaults. All options are commented out.
+func DefaultConfig() []byte {
+	return defaultConfig
+}
@@ -0,0 +1,49 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDefaultConfig(t *testing.T) {
+	lines := map[string]bool{}
+	for _, l := range strings.Split(string(DefaultConfig()), "\n") {
+		lines[l] = true
+	}
+	defaults := map[string]string{
+		EnvDbURL:        EnvDbURLDefault,
+		EnvDbPoolSize:   EnvDbPoolSizeDefault,
+		EnvDbMinConns:   EnvDbMinConnsDefault,
+		EnvDbIdleTime:   EnvDbIdleTimeDefault,
+		EnvDbHealth:     EnvDbHealthDefault,
+		EnvDbConnect:    EnvDbConnectDefault,
+		EnvIDFormat:     EnvIDFormatDefault,
+		EnvListenHTTP:   EnvListenHTTPDefault,
+		EnvReverseProxy: EnvReverseProxyDefault,
+		EnvTrustProxies: EnvTrustProxiesDefault,
+		EnvLogLevel:     EnvLogLevelDefault,
+		EnvLogFormat:    EnvLogFormatDefault,
+		EnvSlowRequest:  EnvSlowRequestDefault,
+		EnvSlowQuery:    EnvSlowQueryDefault,
+		EnvCmdQueueAge:  EnvCmdQueueAgeDefault,
+		EnvTraceDir:     EnvTraceDirDefault,
+		EnvTimeouts:     EnvTimeoutsDefault,
+		EnvBackendH2C:   EnvBackendH2CDefault,
+		EnvProbes:       EnvProbesDefault,
+		EnvDNSRefresh:   EnvDNSRefreshDefault,
+		EnvMaxResponse:  EnvMaxResponseDefault,
+		EnvDiscovery:    EnvDiscoveryDefault,
+		EnvJoinCache:    EnvJoinCacheDefault,
+		EnvRIB:          EnvRIBDefault,
+		EnvRoomLead:     EnvRoomLeadDefault,
+		EnvBillingFmt:   EnvBillingFmtDefault,
+		EnvBBBConfig:    EnvBBBConfigDefault,
+		EnvBBBEvents:    EnvBBBEventsDefault,
+		EnvLoadFactor:   EnvLoadFactorDefault,
+	}
+	for key, value := range defaults {
+		if l := "#" + key + "=" + value; !lines[l] {
+			t.Error("missing default:", l)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package config
+
+/*
+ Fault injection: For rehearsing the failure handling of
+ an integration in a staging environment, latency, error
+ responses and unavailable backends can be simulated.
+
+ The policy is a comma separated list of options:
+
+    latency=2s,latency_rate=0.1,error_rate=0.05,
+    error_status=503,drop_rate=0.2,resources=create|join
+*/
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FaultPolicy describes which faults are injected
+// and how often. Rates are probabilities between 0 and 1.
+type FaultPolicy struct {
+	Latency     time.Duration
+	LatencyRate float64
+	ErrorRate   float64
+	ErrorStatus int
+	DropRate    float64
+
+	// Resources limits the injection to some
+	// API resources. All resources are affected if empty.
+	Resources []string
+}
+
+// AppliesTo checks if faults are injected for the resource
+func (p *FaultPolicy) AppliesTo(resource string) bool {
+	if len(p.Resources) == 0 {
+		return true
+	}
+	for _, r := range p.Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// parseRate parses a probability
+func parseRate(val string) (float64, error) {
+	rate, err := strconv.ParseFloat(val, 64)
+	if err != nil {
+		return 0, err
+	}
+	if rate < 0 || rate > 1 {
+		return 0, fmt.Errorf("rate must be between 0 and 1: %s", val)
+	}
+	return rate, nil
+}
+
+// ParseFaultPolicy reads a fault injection policy. An empty
+// policy string disables fault injection and yields nil.
+func ParseFaultPolicy(policy string) (*FaultPolicy, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return nil, nil
+	}
+	p := &FaultPolicy{
+		ErrorStatus: http.StatusServiceUnavailable,
+	}
+	for _, opt := range strings.Split(policy, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid fault option: %s", opt)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "latency":
+			p.Latency, err = time.ParseDuration(val)
+		case "latency_rate":
+			p.LatencyRate, err = parseRate(val)
+		case "error_rate":
+			p.ErrorRate, err = parseRate(val)
+		case "error_status":
+			p.ErrorStatus, err = strconv.Atoi(val)
+			if err == nil && (p.ErrorStatus < 100 || p.ErrorStatus > 599) {
+				err = fmt.Errorf("invalid status: %s", val)
+			}
+		case "drop_rate":
+			p.DropRate, err = parseRate(val)
+		case "resources":
+			p.Resources = strings.Split(val, "|")
+		default:
+			err = fmt.Errorf("unknown fault option: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// A latency without rate is always applied
+	if p.Latency > 0 && p.LatencyRate == 0 {
+		p.LatencyRate = 1
+	}
+	return p, nil
+}
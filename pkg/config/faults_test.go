@@ -0,0 +1,44 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseFaultPolicy(t *testing.T) {
+	p, err := ParseFaultPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Error("expected no policy")
+	}
+
+	p, err = ParseFaultPolicy(
+		"latency=2s, error_rate=0.1,error_status=502,drop_rate=0.5,resources=create|join")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Latency != 2*time.Second {
+		t.Error("unexpected latency:", p.Latency)
+	}
+	if p.LatencyRate != 1 {
+		t.Error("unexpected latency rate:", p.LatencyRate)
+	}
+	if p.ErrorRate != 0.1 || p.ErrorStatus != 502 {
+		t.Error("unexpected error:", p.ErrorRate, p.ErrorStatus)
+	}
+	if p.DropRate != 0.5 {
+		t.Error("unexpected drop rate:", p.DropRate)
+	}
+	if !p.AppliesTo("join") || p.AppliesTo("getMeetings") {
+		t.Error("unexpected resources:", p.Resources)
+	}
+
+	if _, err := ParseFaultPolicy("error_rate=2"); err == nil {
+		t.Error("expected invalid rate")
+	}
+	if _, err := ParseFaultPolicy("explode=1"); err == nil {
+		t.Error("expected unknown option")
+	}
+}
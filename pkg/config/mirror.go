@@ -0,0 +1,97 @@
+package config
+
+/*
+ Request mirroring: For load testing a staging cluster
+ with realistic traffic, a sample of the API calls is
+ sent to another b3scale instance or BBB backend.
+
+ The policy is a comma separated list of options:
+
+    url=https://staging.example.net/bigbluebutton/api/,
+    secret=...,rate=0.05,resources=create|getMeetings
+*/
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// MirrorPolicy describes where and how often
+// requests are mirrored.
+type MirrorPolicy struct {
+	// URL and Secret of the BBB API of the mirror.
+	// The requests are signed with the secret.
+	URL    string
+	Secret string
+
+	// Rate is the sampled share of the requests
+	Rate float64
+
+	// Resources limits the mirroring to some
+	// API resources. All resources are mirrored if empty.
+	Resources []string
+}
+
+// AppliesTo checks if requests of the resource are mirrored
+func (p *MirrorPolicy) AppliesTo(resource string) bool {
+	if len(p.Resources) == 0 {
+		return true
+	}
+	for _, r := range p.Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseMirrorPolicy reads a request mirroring policy. An
+// empty policy string disables mirroring and yields nil.
+func ParseMirrorPolicy(policy string) (*MirrorPolicy, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return nil, nil
+	}
+	p := &MirrorPolicy{}
+	for _, opt := range strings.Split(policy, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid mirror option: %s", opt)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "url":
+			p.URL = val
+		case "secret":
+			p.Secret = val
+		case "rate":
+			p.Rate, err = parseRate(val)
+		case "resources":
+			p.Resources = strings.Split(val, "|")
+		default:
+			err = fmt.Errorf("unknown mirror option: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	u, err := url.Parse(p.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("mirror url should start with http(s)://")
+	}
+	if p.Secret == "" {
+		return nil, fmt.Errorf("mirror secret is required")
+	}
+	return p, nil
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseMirrorPolicy(t *testing.T) {
+	p, err := ParseMirrorPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Error("expected no policy")
+	}
+
+	p, err = ParseMirrorPolicy(
+		"url=https://staging/bigbluebutton/api/, secret=s3cr3t,rate=0.05,resources=create|end")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.URL != "https://staging/bigbluebutton/api/" || p.Secret != "s3cr3t" {
+		t.Error("unexpected target:", p.URL, p.Secret)
+	}
+	if p.Rate != 0.05 {
+		t.Error("unexpected rate:", p.Rate)
+	}
+	if !p.AppliesTo("end") || p.AppliesTo("getMeetings") {
+		t.Error("unexpected resources:", p.Resources)
+	}
+
+	if _, err := ParseMirrorPolicy("url=https://staging/,rate=0.1"); err == nil {
+		t.Error("expected missing secret")
+	}
+	if _, err := ParseMirrorPolicy("url=staging,secret=s"); err == nil {
+		t.Error("expected invalid url")
+	}
+	if _, err := ParseMirrorPolicy("url=https://staging/,secret=s,rate=5"); err == nil {
+		t.Error("expected invalid rate")
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+/*
+ Networks: Trusted proxies are a comma separated list
+ of networks or addresses:
+
+    127.0.0.0/8,::1,10.23.0.0/16
+*/
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ParseNetworks parses a list of networks in CIDR
+// notation. A single address is treated as a network
+// with one host. IPv6 addresses may be enclosed in
+// brackets and IPv4-mapped IPv6 networks match IPv4
+// addresses.
+func ParseNetworks(cidrs []string) ([]*net.IPNet, error) {
+	networks := make([]*net.IPNet, 0, len(cidrs))
+	for _, cidr := range cidrs {
+		cidr = strings.TrimSpace(cidr)
+		cidr = strings.TrimPrefix(cidr, "[")
+		cidr = strings.Replace(cidr, "]", "", 1)
+		if !strings.Contains(cidr, "/") {
+			ip := net.ParseIP(cidr)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid address: %s", cidr)
+			}
+			bits := 8 * net.IPv6len
+			if ip4 := ip.To4(); ip4 != nil {
+				ip, bits = ip4, 8*net.IPv4len
+			}
+			networks = append(networks, &net.IPNet{
+				IP:   ip,
+				Mask: net.CIDRMask(bits, bits),
+			})
+			continue
+		}
+		_, network, err := net.ParseCIDR(cidr)
+		if err != nil {
+			return nil, err
+		}
+		ones, bits := network.Mask.Size()
+		if ip4 := network.IP.To4(); ip4 != nil && bits == 8*net.IPv6len {
+			if ones < 96 {
+				return nil, fmt.Errorf("invalid IPv4-mapped network: %s", cidr)
+			}
+			network = &net.IPNet{
+				IP:   ip4,
+				Mask: net.CIDRMask(ones-96, 8*net.IPv4len),
+			}
+		}
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// ParseNetworkList parses a comma separated list of networks
+func ParseNetworkList(value string) ([]*net.IPNet, error) {
+	cidrs := []string{}
+	for _, cidr := range strings.Split(value, ",") {
+		if cidr = strings.TrimSpace(cidr); cidr != "" {
+			cidrs = append(cidrs, cidr)
+		}
+	}
+	return ParseNetworks(cidrs)
+}
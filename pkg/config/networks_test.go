@@ -0,0 +1,25 @@
+package config
+
+import (
+	"net"
+	"testing"
+)
+
+func TestParseNetworkList(t *testing.T) {
+	networks, err := ParseNetworkList("127.0.0.0/8, ::1,[fd00::]/8,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(networks) != 3 {
+		t.Fatal("unexpected networks:", networks)
+	}
+	if !networks[0].Contains(net.ParseIP("127.0.0.23").To4()) {
+		t.Error("expected loopback network:", networks[0])
+	}
+	if !networks[1].Contains(net.IPv6loopback) {
+		t.Error("expected single address:", networks[1])
+	}
+	if _, err := ParseNetworkList("10.0.0.0/33"); err == nil {
+		t.Error("expected an error")
+	}
+}
@@ -0,0 +1,108 @@
+package config
+
+/*
+ Overload queue: When a backend is overloaded and responds
+ with 503 or 429, create and join requests are held and
+ retried for a while instead of failing immediately.
+
+ The policy is a comma separated list of options:
+
+    wait=10s,size=100,per_frontend=10,resources=create|join
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OverloadPolicy describes how long requests are held
+// and how many requests may wait at the same time.
+type OverloadPolicy struct {
+	// Wait is the maximum time a request is held
+	Wait time.Duration
+
+	// Size limits the number of waiting requests
+	Size int
+
+	// PerFrontend limits the waiting requests of a
+	// single frontend, so a frontend can not
+	// occupy the entire queue.
+	PerFrontend int
+
+	// Resources are the queued API resources.
+	Resources []string
+}
+
+// AppliesTo checks if requests of the resource are queued
+func (p *OverloadPolicy) AppliesTo(resource string) bool {
+	for _, r := range p.Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// parseLimit parses a positive number
+func parseLimit(val string) (int, error) {
+	n, err := strconv.Atoi(val)
+	if err != nil {
+		return 0, err
+	}
+	if n <= 0 {
+		return 0, fmt.Errorf("must be positive: %s", val)
+	}
+	return n, nil
+}
+
+// ParseOverloadPolicy reads an overload queue policy. An
+// empty policy string disables the queue and yields nil.
+func ParseOverloadPolicy(policy string) (*OverloadPolicy, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return nil, nil
+	}
+	p := &OverloadPolicy{
+		Wait:        10 * time.Second,
+		Size:        100,
+		PerFrontend: 10,
+		Resources:   []string{"create", "join"},
+	}
+	for _, opt := range strings.Split(policy, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid overload option: %s", opt)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "wait":
+			p.Wait, err = time.ParseDuration(val)
+			if err == nil && p.Wait <= 0 {
+				err = fmt.Errorf("wait must be positive: %s", val)
+			}
+		case "size":
+			p.Size, err = parseLimit(val)
+		case "per_frontend":
+			p.PerFrontend, err = parseLimit(val)
+		case "resources":
+			p.Resources = strings.Split(val, "|")
+		default:
+			err = fmt.Errorf("unknown overload option: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.PerFrontend > p.Size {
+		p.PerFrontend = p.Size
+	}
+	return p, nil
+}
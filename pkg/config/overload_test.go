@@ -0,0 +1,52 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseOverloadPolicy(t *testing.T) {
+	p, err := ParseOverloadPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Error("expected no policy")
+	}
+
+	p, err = ParseOverloadPolicy("wait=5s,size=20")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Wait != 5*time.Second {
+		t.Error("unexpected wait:", p.Wait)
+	}
+	if p.Size != 20 || p.PerFrontend != 10 {
+		t.Error("unexpected limits:", p.Size, p.PerFrontend)
+	}
+	if !p.AppliesTo("create") || !p.AppliesTo("join") {
+		t.Error("expected create and join to be queued")
+	}
+	if p.AppliesTo("getMeetings") {
+		t.Error("getMeetings should not be queued")
+	}
+
+	p, err = ParseOverloadPolicy("size=5,per_frontend=8,resources=create")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.PerFrontend != 5 {
+		t.Error("per frontend limit should not exceed size:", p.PerFrontend)
+	}
+	if p.AppliesTo("join") {
+		t.Error("join should not be queued")
+	}
+
+	for _, invalid := range []string{
+		"wait", "wait=soon", "wait=0s", "size=0", "per_frontend=-1", "depth=3",
+	} {
+		if _, err := ParseOverloadPolicy(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
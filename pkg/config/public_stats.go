@@ -0,0 +1,94 @@
+package config
+
+/*
+ Public statistics: Aggregated statistics of the cluster
+ can be published, e.g. for a transparency page. The counts
+ are anonymized with noise and rounding.
+
+ The policy is a comma separated list of options:
+
+    round=10,noise=5,hours=24,cache=5m
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// PublicStatsMaxHours limits the histogram to a week
+const PublicStatsMaxHours = 168
+
+// PublicStatsPolicy describes how the public
+// statistics are anonymized.
+type PublicStatsPolicy struct {
+	// Round the counts to a multiple
+	Round int
+
+	// Noise is the maximum random value added
+	// to or subtracted from a count before rounding.
+	Noise int
+
+	// Hours of the histogram
+	Hours int
+
+	// Cache is the time the statistics are reused,
+	// so the noise can not be averaged out by
+	// repeated requests.
+	Cache time.Duration
+}
+
+// ParsePublicStatsPolicy reads a public statistics policy.
+// An empty policy string disables the statistics and
+// yields nil.
+func ParsePublicStatsPolicy(policy string) (*PublicStatsPolicy, error) {
+	policy = strings.TrimSpace(policy)
+	if policy == "" {
+		return nil, nil
+	}
+	p := &PublicStatsPolicy{
+		Round: 10,
+		Hours: 24,
+		Cache: 5 * time.Minute,
+	}
+	for _, opt := range strings.Split(policy, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		kv := strings.SplitN(opt, "=", 2)
+		if len(kv) != 2 {
+			return nil, fmt.Errorf("invalid public stats option: %s", opt)
+		}
+		key, val := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+
+		var err error
+		switch key {
+		case "round":
+			p.Round, err = parseLimit(val)
+		case "noise":
+			p.Noise, err = strconv.Atoi(val)
+			if err == nil && p.Noise < 0 {
+				err = fmt.Errorf("noise must not be negative: %s", val)
+			}
+		case "hours":
+			p.Hours, err = parseLimit(val)
+			if err == nil && p.Hours > PublicStatsMaxHours {
+				err = fmt.Errorf(
+					"at most %d hours are allowed", PublicStatsMaxHours)
+			}
+		case "cache":
+			p.Cache, err = time.ParseDuration(val)
+			if err == nil && p.Cache < 0 {
+				err = fmt.Errorf("cache must not be negative: %s", val)
+			}
+		default:
+			err = fmt.Errorf("unknown public stats option: %s", key)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,43 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePublicStatsPolicy(t *testing.T) {
+	p, err := ParsePublicStatsPolicy("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != nil {
+		t.Error("expected no policy")
+	}
+
+	p, err = ParsePublicStatsPolicy("noise=5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Round != 10 || p.Noise != 5 {
+		t.Error("unexpected anonymization:", p.Round, p.Noise)
+	}
+	if p.Hours != 24 || p.Cache != 5*time.Minute {
+		t.Error("unexpected defaults:", p.Hours, p.Cache)
+	}
+
+	p, err = ParsePublicStatsPolicy("round=1, hours=48, cache=0s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Round != 1 || p.Hours != 48 || p.Cache != 0 {
+		t.Error("unexpected policy:", p)
+	}
+
+	for _, invalid := range []string{
+		"round", "round=0", "noise=-1", "hours=169", "cache=-1m", "users=1",
+	} {
+		if _, err := ParsePublicStatsPolicy(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package config
+
+/*
+ History retention: The usage of the frontends, the
+ history of the meetings and the audit log grow with
+ the installation. Older entries can be pruned.
+
+ The retention is a comma separated list of durations,
+ where days can be used:
+
+    usage=730d,meetings=365d,audit_log=365d
+*/
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention keys
+const (
+	RetentionUsage    = "usage"
+	RetentionMeetings = "meetings"
+	RetentionAuditLog = "audit_log"
+)
+
+// parseRetentionDuration parses a duration, with
+// an additional unit for days: 30d
+func parseRetentionDuration(val string) (time.Duration, error) {
+	if strings.HasSuffix(val, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(val, "d"))
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration: %s", val)
+		}
+		return time.Duration(days) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(val)
+}
+
+// ParseRetention reads the retention of the history.
+// The history is kept forever if no retention is set.
+func ParseRetention(value string) (map[string]time.Duration, error) {
+	retention := make(map[string]time.Duration)
+	for _, opt := range strings.Split(value, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		tokens := strings.SplitN(opt, "=", 2)
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("invalid retention: %s", opt)
+		}
+		key := strings.TrimSpace(tokens[0])
+		if key != RetentionUsage &&
+			key != RetentionMeetings &&
+			key != RetentionAuditLog {
+			return nil, fmt.Errorf("unknown history: %s", key)
+		}
+		d, err := parseRetentionDuration(strings.TrimSpace(tokens[1]))
+		if err != nil {
+			return nil, err
+		}
+		if d <= 0 {
+			return nil, fmt.Errorf("retention must be positive: %s", opt)
+		}
+		retention[key] = d
+	}
+	return retention, nil
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	retention, err := ParseRetention("usage=730d, meetings=365d, audit_log=2160h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if retention[RetentionUsage] != 730*24*time.Hour {
+		t.Error("unexpected usage retention:", retention[RetentionUsage])
+	}
+	if retention[RetentionMeetings] != 365*24*time.Hour {
+		t.Error("unexpected meetings retention:", retention[RetentionMeetings])
+	}
+	if retention[RetentionAuditLog] != 90*24*time.Hour {
+		t.Error("unexpected audit log retention:", retention[RetentionAuditLog])
+	}
+
+	retention, err = ParseRetention("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(retention) != 0 {
+		t.Error("expected no retention:", retention)
+	}
+
+	for _, invalid := range []string{
+		"usage", "recordings=30d", "usage=soon", "usage=0d", "usage=xd",
+	} {
+		if _, err := ParseRetention(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Size units, the suffixes are binary multiples.
+var sizeUnits = []struct {
+	suffix string
+	factor int64
+}{
+	{"G", 1 << 30},
+	{"M", 1 << 20},
+	{"K", 1 << 10},
+}
+
+// ParseSize reads a size in bytes. The value may have
+// a unit suffix like 512K, 64M or 1G. A trailing B is
+// accepted as well (64MB).
+func ParseSize(value string) (int64, error) {
+	val := strings.ToUpper(strings.TrimSpace(value))
+	val = strings.TrimSuffix(val, "B")
+	factor := int64(1)
+	for _, u := range sizeUnits {
+		if strings.HasSuffix(val, u.suffix) {
+			val = strings.TrimSuffix(val, u.suffix)
+			factor = u.factor
+			break
+		}
+	}
+	n, err := strconv.ParseInt(strings.TrimSpace(val), 10, 64)
+	if err != nil || n < 0 || n > math.MaxInt64/factor {
+		return 0, fmt.Errorf("invalid size: %s", value)
+	}
+	return n * factor, nil
+}
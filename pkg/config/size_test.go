@@ -0,0 +1,33 @@
+package config
+
+import (
+	"testing"
+)
+
+func TestParseSize(t *testing.T) {
+	sizes := map[string]int64{
+		"0":     0,
+		"1024":  1024,
+		"512K":  512 << 10,
+		"64M":   64 << 20,
+		"64MB":  64 << 20,
+		"1g":    1 << 30,
+		" 2 M ": 2 << 20,
+	}
+	for val, expected := range sizes {
+		size, err := ParseSize(val)
+		if err != nil {
+			t.Error(val, err)
+			continue
+		}
+		if size != expected {
+			t.Error("unexpected size for", val, ":", size)
+		}
+	}
+
+	for _, invalid := range []string{"", "M", "-1", "12T", "lots"} {
+		if _, err := ParseSize(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package config
+
+/*
+ Static configuration: Backends and frontends can be
+ declared in a config file or the environment, for
+ small single tenant installations.
+
+ The config file has one declaration per line:
+
+    node <host> <secret>
+    frontend <key> <secret>
+*/
+
+import (
+	"bufio"
+	"os"
+	"strings"
+)
+
+// Static config directives
+const (
+	directiveNode     = "node"
+	directiveFrontend = "frontend"
+)
+
+// readDirectives reads all lines starting with the
+// directive from a config file. The first argument
+// is split off, the rest of the line is the second one.
+func readDirectives(filename, directive string) ([][2]string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	results := [][2]string{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue // Skip comments
+		}
+		tokens := strings.Fields(line)
+		if len(tokens) < 3 || tokens[0] != directive {
+			continue
+		}
+		rest := strings.TrimSpace(line[len(tokens[0]):])
+		rest = strings.TrimSpace(rest[len(tokens[1]):])
+		results = append(results, [2]string{tokens[1], rest})
+	}
+	return results, scanner.Err()
+}
+
+// BackendsFileConfig loads the backends
+// from a static config file.
+type BackendsFileConfig struct {
+	Filename string
+}
+
+// Load reads all nodes from the config file
+func (c *BackendsFileConfig) Load() ([]*Backend, error) {
+	nodes, err := readDirectives(c.Filename, directiveNode)
+	if err != nil {
+		return nil, err
+	}
+	backends := make([]*Backend, 0, len(nodes))
+	for _, n := range nodes {
+		backends = append(backends, NewBackend(n[0], n[1]))
+	}
+	return backends, nil
+}
+
+// FrontendsFileConfig loads the frontends
+// from a static config file.
+type FrontendsFileConfig struct {
+	Filename string
+}
+
+// Load reads all frontends from the config file
+func (c *FrontendsFileConfig) Load() ([]*Frontend, error) {
+	decls, err := readDirectives(c.Filename, directiveFrontend)
+	if err != nil {
+		return nil, err
+	}
+	frontends := make([]*Frontend, 0, len(decls))
+	for _, f := range decls {
+		frontends = append(frontends, NewFrontend(f[0], f[1]))
+	}
+	return frontends, nil
+}
+
+// FrontendsEnvConfig loads a single frontend
+// from the environment.
+type FrontendsEnvConfig struct{}
+
+// Load gets the frontend key and secret from the
+// environment. If not configured, the result is empty.
+func (c *FrontendsEnvConfig) Load() ([]*Frontend, error) {
+	key := EnvOpt(EnvFrontendKey, "")
+	secret := EnvOpt(EnvFrontendSecret, "")
+	if key == "" || secret == "" {
+		return []*Frontend{}, nil
+	}
+	return []*Frontend{NewFrontend(key, secret)}, nil
+}
@@ -0,0 +1,56 @@
+package config
+
+import (
+	"os"
+	"testing"
+)
+
+func TestBackendsFileConfigLoad(t *testing.T) {
+	conf := &BackendsFileConfig{
+		Filename: "../../testdata/config/nodes.conf",
+	}
+	backends, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backends) != 2 {
+		t.Fatal("unexpected backends:", backends)
+	}
+	if backends[0].Host != "https://fooo.bar/api/" {
+		t.Error("unexpected host:", backends[0].Host)
+	}
+	if backends[0].Secret != "mysecret goes here" {
+		t.Error("unexpected secret:", backends[0].Secret)
+	}
+}
+
+func TestFrontendsFileConfigLoad(t *testing.T) {
+	conf := &FrontendsFileConfig{
+		Filename: "../../testdata/config/frontends.conf",
+	}
+	frontends, err := conf.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frontends) != 1 {
+		t.Fatal("unexpected frontends:", frontends)
+	}
+	if frontends[0].Key != "bigbluebutton" {
+		t.Error("unexpected key:", frontends[0].Key)
+	}
+}
+
+func TestFrontendsEnvConfigLoad(t *testing.T) {
+	os.Setenv(EnvFrontendKey, "frontend1")
+	os.Setenv(EnvFrontendSecret, "secret")
+	defer os.Unsetenv(EnvFrontendKey)
+	defer os.Unsetenv(EnvFrontendSecret)
+
+	frontends, err := (&FrontendsEnvConfig{}).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frontends) != 1 || frontends[0].Secret != "secret" {
+		t.Error("unexpected frontends:", frontends)
+	}
+}
@@ -0,0 +1,46 @@
+package config
+
+/*
+ Backend request timeouts: The timeout of a request to
+ a backend depends on the BBB API resource. A create
+ with many slides takes longer than isMeetingRunning.
+
+ The timeouts are a comma separated list:
+
+    default=60s,create=120s,isMeetingRunning=5s
+*/
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// TimeoutDefault is the key of the timeout
+// applied to all other resources.
+const TimeoutDefault = "default"
+
+// ParseTimeouts reads a list of resource timeouts
+func ParseTimeouts(value string) (map[string]time.Duration, error) {
+	timeouts := make(map[string]time.Duration)
+	for _, opt := range strings.Split(value, ",") {
+		opt = strings.TrimSpace(opt)
+		if opt == "" {
+			continue
+		}
+		tokens := strings.SplitN(opt, "=", 2)
+		if len(tokens) != 2 || strings.TrimSpace(tokens[0]) == "" {
+			return nil, fmt.Errorf("invalid timeout: %s", opt)
+		}
+		resource := strings.TrimSpace(tokens[0])
+		timeout, err := time.ParseDuration(strings.TrimSpace(tokens[1]))
+		if err != nil {
+			return nil, err
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout must be positive: %s", opt)
+		}
+		timeouts[resource] = timeout
+	}
+	return timeouts, nil
+}
@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeouts(t *testing.T) {
+	timeouts, err := ParseTimeouts("default=60s, create=2m,isMeetingRunning=5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeouts[TimeoutDefault] != 60*time.Second {
+		t.Error("unexpected default:", timeouts[TimeoutDefault])
+	}
+	if timeouts["create"] != 2*time.Minute {
+		t.Error("unexpected create timeout:", timeouts["create"])
+	}
+	if timeouts["isMeetingRunning"] != 5*time.Second {
+		t.Error("unexpected timeout:", timeouts["isMeetingRunning"])
+	}
+
+	timeouts, err = ParseTimeouts("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(timeouts) != 0 {
+		t.Error("expected no timeouts:", timeouts)
+	}
+
+	for _, invalid := range []string{"create", "create=fast", "=5s", "end=0s"} {
+		if _, err := ParseTimeouts(invalid); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
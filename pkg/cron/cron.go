@@ -0,0 +1,195 @@
+// Package cron implements cron expressions with five fields:
+//
+//	minute hour day-of-month month day-of-week
+//
+// Fields can be `*`, a value, a range `1-5`, a list
+// `1,15` or a step `*/15` or `0-30/10`. Day of week 0 and
+// 7 are sunday. If both day of month and day of week are
+// restricted, a time matches if either field matches.
+//
+// The macros @hourly, @daily (@midnight), @weekly,
+// @monthly and @yearly (@annually) are supported.
+// Schedules are in UTC, unless calculated with NextIn.
+package cron
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors
+var (
+	ErrInvalidExpression = errors.New("invalid cron expression")
+	ErrNoNextTime        = errors.New("cron schedule never matches")
+)
+
+// macros are shorthands for common schedules
+var macros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// A field is the set of allowed values
+type field struct {
+	bits uint64
+	any  bool // The field is *
+}
+
+func (f field) has(v int) bool {
+	return f.bits&(1<<uint(v)) != 0
+}
+
+// A Schedule is a parsed cron expression
+type Schedule struct {
+	expr   string
+	minute field
+	hour   field
+	dom    field
+	month  field
+	dow    field
+}
+
+// String returns the expression of the schedule
+func (s *Schedule) String() string {
+	return s.expr
+}
+
+// parseField decodes a field with values in the range
+func parseField(expr string, min, max int) (field, error) {
+	f := field{}
+	for _, part := range strings.Split(expr, ",") {
+		step := 1
+		if i := strings.Index(part, "/"); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n <= 0 {
+				return f, fmt.Errorf("invalid step: %s", part)
+			}
+			step = n
+			part = part[:i]
+		}
+		lo, hi := min, max
+		switch {
+		case part == "*":
+			if step == 1 {
+				f.any = true
+			}
+		case strings.Contains(part, "-"):
+			bounds := strings.SplitN(part, "-", 2)
+			var err error
+			if lo, err = strconv.Atoi(bounds[0]); err != nil {
+				return f, fmt.Errorf("invalid range: %s", part)
+			}
+			if hi, err = strconv.Atoi(bounds[1]); err != nil {
+				return f, fmt.Errorf("invalid range: %s", part)
+			}
+		default:
+			n, err := strconv.Atoi(part)
+			if err != nil {
+				return f, fmt.Errorf("invalid value: %s", part)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				hi = max // e.g. 5/15
+			}
+		}
+		if lo < min || hi > max || lo > hi {
+			return f, fmt.Errorf(
+				"value out of range %d-%d: %s", min, max, part)
+		}
+		for v := lo; v <= hi; v += step {
+			f.bits |= 1 << uint(v)
+		}
+	}
+	return f, nil
+}
+
+// Parse decodes a cron expression
+func Parse(expr string) (*Schedule, error) {
+	expr = strings.TrimSpace(expr)
+	spec := expr
+	if m, ok := macros[spec]; ok {
+		spec = m
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf(
+			"%w: expected 5 fields: %s", ErrInvalidExpression, expr)
+	}
+	s := &Schedule{expr: expr}
+	var err error
+	if s.minute, err = parseField(fields[0], 0, 59); err != nil {
+		return nil, fmt.Errorf("%w: minute: %v", ErrInvalidExpression, err)
+	}
+	if s.hour, err = parseField(fields[1], 0, 23); err != nil {
+		return nil, fmt.Errorf("%w: hour: %v", ErrInvalidExpression, err)
+	}
+	if s.dom, err = parseField(fields[2], 1, 31); err != nil {
+		return nil, fmt.Errorf("%w: day of month: %v", ErrInvalidExpression, err)
+	}
+	if s.month, err = parseField(fields[3], 1, 12); err != nil {
+		return nil, fmt.Errorf("%w: month: %v", ErrInvalidExpression, err)
+	}
+	if s.dow, err = parseField(fields[4], 0, 7); err != nil {
+		return nil, fmt.Errorf("%w: day of week: %v", ErrInvalidExpression, err)
+	}
+	if s.dow.has(7) {
+		s.dow.bits |= 1 // Sunday
+	}
+	return s, nil
+}
+
+// matchDay checks the day of month and day of week
+func (s *Schedule) matchDay(t time.Time) bool {
+	dom := s.dom.has(t.Day())
+	dow := s.dow.has(int(t.Weekday()))
+	if s.dom.any || s.dow.any {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next calculates the first time after t matching
+// the schedule.
+func (s *Schedule) Next(t time.Time) (time.Time, error) {
+	return s.NextIn(t, time.UTC)
+}
+
+// NextIn calculates the first time after t matching the
+// schedule in the location, e.g. "0 10 * * 1" is at 10:00
+// local time, regardless of daylight saving time.
+// The result is in UTC.
+func (s *Schedule) NextIn(t time.Time, loc *time.Location) (time.Time, error) {
+	t = t.In(loc).Truncate(time.Minute).Add(time.Minute)
+
+	// A matching time is found within a few years,
+	// e.g. the 29th of February.
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if !s.month.has(int(t.Month())) {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+		if !s.hour.has(t.Hour()) {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, loc)
+			continue
+		}
+		if !s.minute.has(t.Minute()) {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t.UTC(), nil
+	}
+	return time.Time{}, ErrNoNextTime
+}
@@ -0,0 +1,120 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		"* * * * *",
+		"*/15 * * * *",
+		"0 3 * * 1-5",
+		"0,30 8-18/2 1,15 * 7",
+		"@daily",
+		"@weekly",
+	}
+	for _, expr := range valid {
+		if _, err := Parse(expr); err != nil {
+			t.Error(expr, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"@sometimes",
+	}
+	for _, expr := range invalid {
+		if _, err := Parse(expr); err == nil {
+			t.Error("expected an error for:", expr)
+		}
+	}
+}
+
+func TestScheduleNext(t *testing.T) {
+	// A wednesday
+	now := time.Date(2021, 6, 2, 10, 17, 42, 0, time.UTC)
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"* * * * *", time.Date(2021, 6, 2, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2021, 6, 2, 10, 30, 0, 0, time.UTC)},
+		{"0 3 * * *", time.Date(2021, 6, 3, 3, 0, 0, 0, time.UTC)},
+		{"@daily", time.Date(2021, 6, 3, 0, 0, 0, 0, time.UTC)},
+		{"@weekly", time.Date(2021, 6, 6, 0, 0, 0, 0, time.UTC)},
+		{"0 4 * * 7", time.Date(2021, 6, 6, 4, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2021, 7, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		// Either the day of month or the day of week
+		{"0 0 15 * 5", time.Date(2021, 6, 4, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		s, err := Parse(test.expr)
+		if err != nil {
+			t.Fatal(test.expr, err)
+		}
+		next, err := s.Next(now)
+		if err != nil {
+			t.Fatal(test.expr, err)
+		}
+		if !next.Equal(test.next) {
+			t.Error(test.expr, "unexpected next time:", next)
+		}
+	}
+}
+
+func TestScheduleNextNever(t *testing.T) {
+	s, err := Parse("0 0 31 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Next(time.Now()); err != ErrNoNextTime {
+		t.Error("expected no next time, got:", err)
+	}
+}
+
+func TestScheduleNextIn(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skip("no timezone data:", err)
+	}
+	s, err := Parse("0 10 * * 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A wednesday in summer and winter time
+	tests := []struct {
+		now  time.Time
+		next time.Time
+	}{
+		{
+			time.Date(2021, 6, 2, 10, 17, 42, 0, time.UTC),
+			time.Date(2021, 6, 7, 8, 0, 0, 0, time.UTC),
+		},
+		{
+			time.Date(2021, 12, 1, 10, 17, 42, 0, time.UTC),
+			time.Date(2021, 12, 6, 9, 0, 0, 0, time.UTC),
+		},
+	}
+	for _, test := range tests {
+		next, err := s.NextIn(test.now, loc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !next.Equal(test.next) {
+			t.Error("unexpected next time:", next)
+		}
+		if next.Location() != time.UTC {
+			t.Error("expected the result in utc")
+		}
+	}
+}
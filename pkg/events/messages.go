@@ -18,3 +18,17 @@ type MessageCore struct {
 	Header map[string]interface{} `json:"header"`
 	Body   map[string]interface{} `json:"body"`
 }
+
+// A RapMessage is published by the recording
+// processing scripts on the from-rap channel
+type RapMessage struct {
+	Header  *RapMessageHeader      `json:"header"`
+	Payload map[string]interface{} `json:"payload"`
+}
+
+// RapMessageHeader of the recording message
+type RapMessageHeader struct {
+	Name      string `json:"name"`
+	Timestamp int    `json:"timestamp"`
+	Version   string `json:"version"`
+}
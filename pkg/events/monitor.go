@@ -3,7 +3,6 @@ package events
 import (
 	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"time"
 
@@ -13,63 +12,195 @@ import (
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 )
 
-// Errors
-var (
-	ErrChannelClosed = errors.New("subscription channel disconnected")
+// Transport is the way BBB publishes the events
+// in redis. This differs between BBB versions.
+type Transport string
+
+// Supported transports
+const (
+	// TransportPubSub is the channel subscription
+	// used by BBB
+	TransportPubSub Transport = "pubsub"
+
+	// TransportStreams reads the events from redis
+	// streams.
+	TransportStreams Transport = "streams"
+
+	// TransportAuto uses the streams if BBB writes
+	// the events to redis streams and pubsub otherwise.
+	// It is resolved with DetectTransport.
+	TransportAuto Transport = "auto"
 )
 
+// DetectTransport probes redis for the akka apps
+// event stream. The stream exists only after BBB
+// wrote an event to it.
+func DetectTransport(
+	ctx context.Context,
+	rdb *redis.Client,
+) (Transport, error) {
+	keyType, err := rdb.Type(ctx, akkaAppsStream).Result()
+	if err != nil {
+		return "", err
+	}
+	return transportForKeyType(keyType), nil
+}
+
+// transportForKeyType selects the transport by
+// the type of the akka apps stream key.
+func transportForKeyType(keyType string) Transport {
+	if keyType == "stream" {
+		return TransportStreams
+	}
+	return TransportPubSub
+}
+
+// MonitorOptions configure the event monitor
+type MonitorOptions struct {
+	// Transport defaults to pubsub
+	Transport Transport
+
+	// Offsets are the last processed IDs of each
+	// stream. Reading starts after these. Offsets
+	// are not supported with pubsub.
+	Offsets map[string]string
+}
+
+// A Delivery is a decoded BBB event with its
+// position in the redis stream. With pubsub the
+// position is unknown and the ID is empty.
+type Delivery struct {
+	Event  bbb.Event
+	Stream string
+	ID     string
+}
+
 // A Monitor is connected to a redis server and is
 // listening for BBB events.
 type Monitor struct {
-	rdb *redis.Client
+	rdb       *redis.Client
+	transport Transport
+	offsets   map[string]string
 }
 
 // NewMonitor creates a new monitor with a redis connection
-func NewMonitor(rdb *redis.Client) *Monitor {
+func NewMonitor(rdb *redis.Client, opts *MonitorOptions) *Monitor {
+	transport := TransportPubSub
+	offsets := map[string]string{}
+	if opts != nil {
+		if opts.Transport != "" {
+			transport = opts.Transport
+		}
+		// Pubsub has no positions to resume from
+		if transport == TransportStreams && opts.Offsets != nil {
+			offsets = opts.Offsets
+		}
+	}
 	return &Monitor{
-		rdb: rdb,
+		rdb:       rdb,
+		transport: transport,
+		offsets:   offsets,
 	}
 }
 
+// Channels with BBB events
+const (
+	akkaAppsChannelPattern = "*akka-apps-redis-channel"
+	rapChannel             = "bigbluebutton:from-rap"
+)
+
+// Reconnect backoff
+const (
+	reconnectMinDelay = 1 * time.Second
+	reconnectMaxDelay = 30 * time.Second
+)
+
+// nextReconnectDelay doubles the delay until
+// the maximum delay is reached.
+func nextReconnectDelay(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay > reconnectMaxDelay {
+		return reconnectMaxDelay
+	}
+	return delay
+}
+
 // Subscribe subscribes to the redis store and
 // retrievs messsges. These are decoded and returned
 // through a channel.
-func (m *Monitor) Subscribe() chan bbb.Event {
-	events := make(chan bbb.Event)
-	go func(events chan bbb.Event) {
-		ctx := context.Background()
-		pubsub := m.rdb.PSubscribe(ctx, "*akka-apps-redis-channel")
-		for {
-			err := receiveMessages(events, pubsub)
-			log.Error().
-				Err(err).
-				Msg("redis error on receiveMessages")
-			time.Sleep(1 * time.Second)
-		}
-	}(events)
+//
+// When the connection to redis is lost, e.g. because
+// the redis server was restarted, the subscription is
+// reestablished with an exponential backoff.
+func (m *Monitor) Subscribe() chan *Delivery {
+	events := make(chan *Delivery)
+	if m.transport == TransportStreams {
+		go m.readStreams(events)
+	} else {
+		go m.subscribePubSub(events)
+	}
 	return events
 }
 
-func receiveMessages(events chan bbb.Event, sub *redis.PubSub) error {
+// subscribePubSub receives the events through
+// redis pubsub. Messages published while disconnected
+// are lost.
+func (m *Monitor) subscribePubSub(events chan *Delivery) {
 	ctx := context.Background()
+	delay := reconnectMinDelay
+	for {
+		pubsub := m.rdb.PSubscribe(
+			ctx, akkaAppsChannelPattern, rapChannel)
+		subscribed, err := receiveMessages(ctx, events, pubsub)
+		pubsub.Close()
+
+		// Reset the backoff if the subscription was
+		// established before the error occurred.
+		if subscribed {
+			delay = reconnectMinDelay
+		}
+		log.Error().
+			Err(err).
+			Dur("retryIn", delay).
+			Msg("redis error on receiveMessages")
+		time.Sleep(delay)
+		delay = nextReconnectDelay(delay)
+	}
+}
+
+// receiveMessages reads from the subscription until
+// an error occures. The returned flag indicates if the
+// subscription was established.
+func receiveMessages(
+	ctx context.Context,
+	events chan *Delivery,
+	sub *redis.PubSub,
+) (bool, error) {
 	if _, err := sub.Receive(ctx); err != nil {
-		return err
+		return false, err
 	}
+	log.Info().Msg("subscribed to bbb events")
 
-	for msg := range sub.Channel() {
+	for {
+		msg, err := sub.ReceiveMessage(ctx)
+		if err != nil {
+			return true, err
+		}
 		// Decode message and push event
 		event := decodeEvent(msg)
 		if event == nil {
 			continue // We do not really care.
 		}
-		events <- event
+		events <- &Delivery{Event: event}
 	}
-
-	return ErrChannelClosed
 }
 
 // Decode incoming message into a BBB event
 func decodeEvent(msg *redis.Message) bbb.Event {
+	if msg.Channel == rapChannel {
+		return decodeRapEvent(msg)
+	}
+
 	m := &Message{}
 	if err := json.Unmarshal([]byte(msg.Payload), m); err != nil {
 		log.Error().
@@ -91,6 +222,38 @@ func decodeEvent(msg *redis.Message) bbb.Event {
 		return safeDecode(decodeUserJoinedMeetingEvent, m)
 	case "UserLeftMeetingEvtMsg":
 		return safeDecode(decodeUserLeftMeetingEvent, m)
+	case "UserRoleChangedEvtMsg":
+		return safeDecode(decodeUserRoleChangedEvent, m)
+	case "ScreenshareRtmpBroadcastStartedEvtMsg":
+		return safeDecode(decodeScreenshareStartedEvent, m)
+	case "ScreenshareRtmpBroadcastStoppedEvtMsg":
+		return safeDecode(decodeScreenshareStoppedEvent, m)
+	case "PadCreatedEvtMsg":
+		return safeDecode(decodePadCreatedEvent, m)
+	case "PadUpdatedEvtMsg":
+		return safeDecode(decodePadUpdatedEvent, m)
+	}
+
+	return nil
+}
+
+// Decode a message from the recording processing
+func decodeRapEvent(msg *redis.Message) bbb.Event {
+	m := &RapMessage{}
+	if err := json.Unmarshal([]byte(msg.Payload), m); err != nil {
+		log.Error().
+			Err(err).
+			Str("data", msg.Payload).
+			Msg("decoding rap event")
+		return nil
+	}
+	if m.Header == nil {
+		return nil
+	}
+
+	switch m.Header.Name {
+	case "rap_publish_ended":
+		return safeDecodeRap(decodeRecordingReadyEvent, m)
 	}
 
 	return nil
@@ -107,6 +270,17 @@ func safeDecode(decoder decoderFunc, m *Message) bbb.Event {
 	return decoder(m)
 }
 
+type rapDecoderFunc func(m *RapMessage) bbb.Event
+
+func safeDecodeRap(decoder rapDecoderFunc, m *RapMessage) bbb.Event {
+	defer func() {
+		if r := recover(); r != nil {
+			fmt.Println("Recovered from:", r)
+		}
+	}()
+	return decoder(m)
+}
+
 func decodeMeetingCreatedEvent(m *Message) bbb.Event {
 	// Decode "props"
 	props := m.Core.Body["props"].(map[string]interface{})
@@ -151,3 +325,57 @@ func decodeUserLeftMeetingEvent(m *Message) bbb.Event {
 		InternalUserID:    header["userId"].(string),
 	}
 }
+
+func decodeUserRoleChangedEvent(m *Message) bbb.Event {
+	body := m.Core.Body
+	return &bbb.UserRoleChangedEvent{
+		InternalMeetingID: m.Core.Header["meetingId"].(string),
+		InternalUserID:    body["userId"].(string),
+		Role:              body["role"].(string),
+		ChangedBy:         body["changedBy"].(string),
+	}
+}
+
+func decodeScreenshareStartedEvent(m *Message) bbb.Event {
+	return &bbb.ScreenshareStartedEvent{
+		InternalMeetingID: m.Core.Header["meetingId"].(string),
+		Stream:            m.Core.Body["stream"].(string),
+	}
+}
+
+func decodeScreenshareStoppedEvent(m *Message) bbb.Event {
+	return &bbb.ScreenshareStoppedEvent{
+		InternalMeetingID: m.Core.Header["meetingId"].(string),
+		Stream:            m.Core.Body["stream"].(string),
+	}
+}
+
+func decodePadCreatedEvent(m *Message) bbb.Event {
+	body := m.Core.Body
+	return &bbb.PadCreatedEvent{
+		InternalMeetingID: m.Core.Header["meetingId"].(string),
+		GroupID:           body["groupId"].(string),
+		PadID:             body["padId"].(string),
+		Name:              body["name"].(string),
+	}
+}
+
+func decodePadUpdatedEvent(m *Message) bbb.Event {
+	body := m.Core.Body
+	return &bbb.PadUpdatedEvent{
+		InternalMeetingID: m.Core.Header["meetingId"].(string),
+		GroupID:           body["groupId"].(string),
+		PadID:             body["padId"].(string),
+		InternalUserID:    body["userId"].(string),
+	}
+}
+
+func decodeRecordingReadyEvent(m *RapMessage) bbb.Event {
+	payload := m.Payload
+	return &bbb.RecordingReadyEvent{
+		MeetingID:         payload["external_meeting_id"].(string),
+		InternalMeetingID: payload["meeting_id"].(string),
+		Workflow:          payload["workflow"].(string),
+		Success:           payload["success"].(bool),
+	}
+}
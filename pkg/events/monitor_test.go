@@ -0,0 +1,212 @@
+package events
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestNextReconnectDelay(t *testing.T) {
+	delay := reconnectMinDelay
+	delay = nextReconnectDelay(delay)
+	if delay != 2*time.Second {
+		t.Error("unexpected delay:", delay)
+	}
+	for i := 0; i < 10; i++ {
+		delay = nextReconnectDelay(delay)
+	}
+	if delay != reconnectMaxDelay {
+		t.Error("delay should be capped:", delay)
+	}
+}
+
+func TestDecodeUserRoleChangedEvent(t *testing.T) {
+	msg := &redis.Message{
+		Channel: "to-akka-apps-redis-channel",
+		Payload: `{"envelope": {"name": "UserRoleChangedEvtMsg"},
+			"core": {
+				"header": {"meetingId": "meeting23"},
+				"body": {"userId": "w_42", "role": "MODERATOR",
+					"changedBy": "w_23"}}}`,
+	}
+	ev, ok := decodeEvent(msg).(*bbb.UserRoleChangedEvent)
+	if !ok {
+		t.Fatal("unexpected event:", ev)
+	}
+	if ev.InternalMeetingID != "meeting23" {
+		t.Error("unexpected meeting id:", ev.InternalMeetingID)
+	}
+	if ev.InternalUserID != "w_42" || ev.Role != "MODERATOR" {
+		t.Error("unexpected event:", ev)
+	}
+}
+
+func TestDecodeRecordingReadyEvent(t *testing.T) {
+	msg := &redis.Message{
+		Channel: rapChannel,
+		Payload: `{"header": {"name": "rap_publish_ended"},
+			"payload": {"success": true, "workflow": "presentation",
+				"meeting_id": "meeting23-1234",
+				"external_meeting_id": "meeting23"}}`,
+	}
+	ev, ok := decodeEvent(msg).(*bbb.RecordingReadyEvent)
+	if !ok {
+		t.Fatal("unexpected event:", ev)
+	}
+	if ev.MeetingID != "meeting23" || !ev.Success {
+		t.Error("unexpected event:", ev)
+	}
+}
+
+func TestDecodeIncompleteEvent(t *testing.T) {
+	msg := &redis.Message{
+		Channel: "to-akka-apps-redis-channel",
+		Payload: `{"envelope": {"name": "PadCreatedEvtMsg"},
+			"core": {"header": {}, "body": {}}}`,
+	}
+	if ev := decodeEvent(msg); ev != nil {
+		t.Error("expected nil event, got:", ev)
+	}
+}
+
+func TestDecodeStreamMessage(t *testing.T) {
+	msg := redis.XMessage{
+		ID: "1626358484423-0",
+		Values: map[string]interface{}{
+			"payload": `{"header": {"name": "rap_publish_ended"},
+				"payload": {"success": true, "workflow": "presentation",
+					"meeting_id": "meeting23-1234",
+					"external_meeting_id": "meeting23"}}`,
+		},
+	}
+	if _, ok := decodeStreamMessage(rapStream, msg).(*bbb.RecordingReadyEvent); !ok {
+		t.Error("expected recording ready event")
+	}
+
+	msg.Values = map[string]interface{}{}
+	if ev := decodeStreamMessage(rapStream, msg); ev != nil {
+		t.Error("unexpected event:", ev)
+	}
+}
+
+func TestLastStreamID(t *testing.T) {
+	if id := lastStreamID(nil); id != "0-0" {
+		t.Error("an empty stream should be read from the start:", id)
+	}
+	msgs := []redis.XMessage{{ID: "1700000000000-3"}}
+	if id := lastStreamID(msgs); id != "1700000000000-3" {
+		t.Error("unexpected id:", id)
+	}
+}
+
+func TestMonitorStreamStartIDs(t *testing.T) {
+	m := NewMonitor(nil, &MonitorOptions{
+		Transport: TransportStreams,
+		Offsets: map[string]string{
+			akkaAppsStream: "1700000000000-3",
+		},
+	})
+	ids := m.streamStartIDs()
+	if ids[akkaAppsStream] != "1700000000000-3" {
+		t.Error("expected to resume after the offset:", ids)
+	}
+	if ids[rapStream] != "" {
+		t.Error("a stream without offset should be resolved:", ids)
+	}
+}
+
+func TestMonitorPubSubIgnoresOffsets(t *testing.T) {
+	m := NewMonitor(nil, &MonitorOptions{
+		Transport: TransportPubSub,
+		Offsets: map[string]string{
+			akkaAppsStream: "1700000000000-3",
+		},
+	})
+	if len(m.offsets) != 0 {
+		t.Error("pubsub should ignore offsets:", m.offsets)
+	}
+}
+
+func TestTransportForKeyType(t *testing.T) {
+	if tr := transportForKeyType("stream"); tr != TransportStreams {
+		t.Error("unexpected transport:", tr)
+	}
+	// The stream does not exist
+	if tr := transportForKeyType("none"); tr != TransportPubSub {
+		t.Error("unexpected transport:", tr)
+	}
+}
+
+// streamEntry is an entry of a redis stream
+// as read with XRANGE
+type streamEntry struct {
+	Stream string                 `json:"stream"`
+	ID     string                 `json:"id"`
+	Values map[string]interface{} `json:"values"`
+}
+
+func TestDecodeStreamFixtures(t *testing.T) {
+	data, err := ioutil.ReadFile("../../testdata/events/streams.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := []*streamEntry{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := []bbb.Event{}
+	for _, e := range entries {
+		// The monitor reads only the known streams
+		if _, ok := streamChannels[e.Stream]; !ok {
+			t.Error("unknown stream:", e.Stream)
+		}
+		ev := decodeStreamMessage(e.Stream, redis.XMessage{
+			ID:     e.ID,
+			Values: e.Values,
+		})
+		if ev != nil {
+			decoded = append(decoded, ev)
+		}
+	}
+
+	// The presenter assignment is not handled
+	if len(decoded) != 4 {
+		t.Fatal("unexpected events:", decoded)
+	}
+	created, ok := decoded[0].(*bbb.MeetingCreatedEvent)
+	if !ok {
+		t.Fatal("unexpected event:", decoded[0])
+	}
+	if created.MeetingID != "Demo Meeting" {
+		t.Error("unexpected meeting id:", created.MeetingID)
+	}
+	internalID := created.InternalMeetingID
+	joined, ok := decoded[1].(*bbb.UserJoinedMeetingEvent)
+	if !ok {
+		t.Fatal("unexpected event:", decoded[1])
+	}
+	if joined.InternalMeetingID != internalID ||
+		joined.Attendee.FullName != "Ada" {
+		t.Error("unexpected event:", joined)
+	}
+	ended, ok := decoded[2].(*bbb.MeetingEndedEvent)
+	if !ok {
+		t.Fatal("unexpected event:", decoded[2])
+	}
+	if ended.InternalMeetingID != internalID {
+		t.Error("unexpected event:", ended)
+	}
+	ready, ok := decoded[3].(*bbb.RecordingReadyEvent)
+	if !ok {
+		t.Fatal("unexpected event:", decoded[3])
+	}
+	if ready.InternalMeetingID != internalID || !ready.Success {
+		t.Error("unexpected event:", ready)
+	}
+}
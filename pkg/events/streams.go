@@ -0,0 +1,157 @@
+package events
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// Streams with BBB events (BBB 2.4+)
+const (
+	akkaAppsStream = "from-akka-apps-redis-channel"
+	rapStream      = "bigbluebutton:from-rap"
+
+	// The field of a stream entry containing the message
+	streamPayloadField = "payload"
+
+	// streamReadTimeout is the time XREAD blocks
+	// when there are no new messages.
+	streamReadTimeout = 10 * time.Second
+)
+
+// streamChannels maps the streams to the channel
+// names used with pubsub, so the same decoders
+// can be used.
+var streamChannels = map[string]string{
+	akkaAppsStream: akkaAppsStream,
+	rapStream:      rapChannel,
+}
+
+// readStreams reads the events from the redis streams.
+// The last received ID of each stream is kept, so
+// reading resumes where it stopped after a reconnect,
+// as long as the entries were not trimmed.
+func (m *Monitor) readStreams(events chan *Delivery) {
+	ctx := context.Background()
+	delay := reconnectMinDelay
+
+	lastIDs := m.streamStartIDs()
+	for {
+		// The last entry is resolved once: Reading with `$`
+		// in every call would skip the entries added
+		// between the calls.
+		err := m.resolveLastIDs(ctx, lastIDs)
+		if err != nil {
+			log.Error().
+				Err(err).
+				Dur("retryIn", delay).
+				Msg("redis error on readStreams")
+			time.Sleep(delay)
+			delay = nextReconnectDelay(delay)
+			continue
+		}
+
+		res, err := m.rdb.XRead(ctx, &redis.XReadArgs{
+			Streams: []string{
+				akkaAppsStream, rapStream,
+				lastIDs[akkaAppsStream], lastIDs[rapStream],
+			},
+			Block: streamReadTimeout,
+		}).Result()
+		if err == redis.Nil {
+			delay = reconnectMinDelay
+			continue // Timeout, no new messages
+		}
+		if err != nil {
+			log.Error().
+				Err(err).
+				Dur("retryIn", delay).
+				Msg("redis error on readStreams")
+			time.Sleep(delay)
+			delay = nextReconnectDelay(delay)
+			continue
+		}
+		delay = reconnectMinDelay
+
+		for _, stream := range res {
+			for _, msg := range stream.Messages {
+				lastIDs[stream.Stream] = msg.ID
+				event := decodeStreamMessage(stream.Stream, msg)
+				if event == nil {
+					continue
+				}
+				events <- &Delivery{
+					Event:  event,
+					Stream: stream.Stream,
+					ID:     msg.ID,
+				}
+			}
+		}
+	}
+}
+
+// streamStartIDs are the IDs after which reading starts:
+// the persisted offsets. Without an offset only new messages
+// are of interest, the ID is resolved before reading.
+func (m *Monitor) streamStartIDs() map[string]string {
+	lastIDs := map[string]string{
+		akkaAppsStream: "",
+		rapStream:      "",
+	}
+	for stream := range lastIDs {
+		if id, ok := m.offsets[stream]; ok {
+			lastIDs[stream] = id
+		}
+	}
+	return lastIDs
+}
+
+// resolveLastIDs sets the IDs of the streams without
+// an offset to their latest entry. An empty stream
+// is read from the beginning.
+func (m *Monitor) resolveLastIDs(
+	ctx context.Context,
+	lastIDs map[string]string,
+) error {
+	for stream, id := range lastIDs {
+		if id != "" {
+			continue
+		}
+		msgs, err := m.rdb.XRevRangeN(ctx, stream, "+", "-", 1).Result()
+		if err != nil {
+			return err
+		}
+		lastIDs[stream] = lastStreamID(msgs)
+	}
+	return nil
+}
+
+// lastStreamID is the ID of the latest entry
+// or the start of the stream.
+func lastStreamID(msgs []redis.XMessage) string {
+	if len(msgs) == 0 {
+		return "0-0"
+	}
+	return msgs[0].ID
+}
+
+// decodeStreamMessage decodes a stream entry
+// into a BBB event
+func decodeStreamMessage(stream string, msg redis.XMessage) bbb.Event {
+	payload, ok := msg.Values[streamPayloadField].(string)
+	if !ok {
+		log.Warn().
+			Str("stream", stream).
+			Str("id", msg.ID).
+			Msg("stream entry without payload")
+		return nil
+	}
+	return decodeEvent(&redis.Message{
+		Channel: streamChannels[stream],
+		Payload: payload,
+	})
+}
@@ -0,0 +1,229 @@
+package experiments
+
+/*
+ Experiments: Frontends or meetings are assigned to
+ experiment arms. Each arm can route to a different
+ pool of backends and can set create parameters.
+
+ The assignment is deterministic: The same frontend
+ or meeting is always assigned to the same arm, as long
+ as the arms of the experiment are not changed.
+
+ Experiments are declared in a JSON file:
+
+    [{"name": "bbb26",
+      "assign_by": "meeting",
+      "frontends": ["frontend1"],
+      "arms": [
+        {"name": "control", "weight": 0.9},
+        {"name": "bbb26", "weight": 0.1,
+         "required_tags": ["bbb26"],
+         "create_params": {"meetingLayout": "SMART_LAYOUT"}}]}]
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/ioutil"
+)
+
+// Assignment subjects
+const (
+	AssignByFrontend = "frontend"
+	AssignByMeeting  = "meeting"
+)
+
+// Errors
+var (
+	// ErrNoArms will be returned if an experiment
+	// does not declare arms.
+	ErrNoArms = errors.New("experiment has no arms")
+)
+
+// An Arm is a variant of the experiment
+type Arm struct {
+	Name string `json:"name"`
+
+	// Weight is the relative share of assignments
+	Weight float64 `json:"weight"`
+
+	// RequiredTags restrict the backend pool
+	RequiredTags []string `json:"required_tags,omitempty"`
+
+	// CreateParams are set in the create request
+	CreateParams map[string]string `json:"create_params,omitempty"`
+}
+
+// An Experiment assigns subjects to arms
+type Experiment struct {
+	Name     string `json:"name"`
+	AssignBy string `json:"assign_by"`
+
+	// Frontends restricts the experiment to frontends
+	// with these keys. All frontends participate if empty.
+	Frontends []string `json:"frontends,omitempty"`
+
+	Arms []*Arm `json:"arms"`
+}
+
+// Validate checks the experiment declaration
+func (e *Experiment) Validate() error {
+	if e.Name == "" {
+		return fmt.Errorf("experiment name is required")
+	}
+	if e.AssignBy != AssignByFrontend && e.AssignBy != AssignByMeeting {
+		return fmt.Errorf(
+			"experiment %s: assign_by must be %s or %s",
+			e.Name, AssignByFrontend, AssignByMeeting)
+	}
+	if len(e.Arms) == 0 {
+		return fmt.Errorf("experiment %s: %w", e.Name, ErrNoArms)
+	}
+	names := make(map[string]bool, len(e.Arms))
+	for _, arm := range e.Arms {
+		if arm.Name == "" {
+			return fmt.Errorf("experiment %s: arm name is required", e.Name)
+		}
+		if names[arm.Name] {
+			return fmt.Errorf(
+				"experiment %s: duplicate arm %s", e.Name, arm.Name)
+		}
+		names[arm.Name] = true
+		if arm.Weight < 0 {
+			return fmt.Errorf(
+				"experiment %s: arm %s has a negative weight",
+				e.Name, arm.Name)
+		}
+	}
+	return nil
+}
+
+// Includes checks if the frontend participates
+func (e *Experiment) Includes(frontendKey string) bool {
+	if len(e.Frontends) == 0 {
+		return true
+	}
+	for _, key := range e.Frontends {
+		if key == frontendKey {
+			return true
+		}
+	}
+	return false
+}
+
+// bucket maps the subject to a stable
+// number in the interval [0, 1).
+func (e *Experiment) bucket(subject string) float64 {
+	h := fnv.New64a()
+	h.Write([]byte(e.Name + "\x00" + subject))
+	return float64(h.Sum64()>>11) / float64(1<<53)
+}
+
+// Assign selects the arm for a frontend and meeting.
+// The result is nil if the frontend does not participate.
+func (e *Experiment) Assign(frontendKey, meetingID string) *Arm {
+	if !e.Includes(frontendKey) {
+		return nil
+	}
+	subject := frontendKey
+	if e.AssignBy == AssignByMeeting {
+		subject = frontendKey + "\x00" + meetingID
+	}
+
+	total := 0.0
+	for _, arm := range e.Arms {
+		total += arm.weight()
+	}
+	x := e.bucket(subject) * total
+	for _, arm := range e.Arms {
+		x -= arm.weight()
+		if x < 0 {
+			return arm
+		}
+	}
+	return e.Arms[len(e.Arms)-1]
+}
+
+// weight of the arm. Arms without weight
+// are weighted equally.
+func (a *Arm) weight() float64 {
+	if a.Weight == 0 {
+		return 1
+	}
+	return a.Weight
+}
+
+// Load reads the experiments from a JSON file
+func Load(filename string) ([]*Experiment, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	experiments := []*Experiment{}
+	if err := json.Unmarshal(data, &experiments); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(experiments))
+	for _, e := range experiments {
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+		if names[e.Name] {
+			return nil, fmt.Errorf("duplicate experiment: %s", e.Name)
+		}
+		names[e.Name] = true
+	}
+	return experiments, nil
+}
+
+// An Assignment is the arm of an experiment
+// selected for a request.
+type Assignment struct {
+	Experiment *Experiment
+	Arm        *Arm
+}
+
+// Assign selects the arms of all experiments
+// the frontend participates in.
+func Assign(
+	experiments []*Experiment,
+	frontendKey, meetingID string,
+) []*Assignment {
+	assignments := []*Assignment{}
+	for _, e := range experiments {
+		if arm := e.Assign(frontendKey, meetingID); arm != nil {
+			assignments = append(assignments, &Assignment{
+				Experiment: e,
+				Arm:        arm,
+			})
+		}
+	}
+	return assignments
+}
+
+type experimentsContextKey int
+
+// Context key for the assignments
+var (
+	assignmentsContextKey = experimentsContextKey(1)
+)
+
+// ContextWithAssignments adds the assignments to a context
+func ContextWithAssignments(
+	ctx context.Context, assignments []*Assignment,
+) context.Context {
+	return context.WithValue(ctx, assignmentsContextKey, assignments)
+}
+
+// AssignmentsFromContext retrieves the assignments
+// from the context.
+func AssignmentsFromContext(ctx context.Context) []*Assignment {
+	assignments, ok := ctx.Value(assignmentsContextKey).([]*Assignment)
+	if !ok {
+		return nil
+	}
+	return assignments
+}
@@ -0,0 +1,122 @@
+package experiments
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func testExperiment() *Experiment {
+	return &Experiment{
+		Name:     "test",
+		AssignBy: AssignByMeeting,
+		Arms: []*Arm{
+			{Name: "control", Weight: 0.5},
+			{Name: "variant", Weight: 0.5},
+		},
+	}
+}
+
+func TestExperimentAssignSticky(t *testing.T) {
+	e := testExperiment()
+	for i := 0; i < 100; i++ {
+		id := fmt.Sprintf("meeting%d", i)
+		arm := e.Assign("frontend1", id)
+		if arm == nil {
+			t.Fatal("expected an arm")
+		}
+		if e.Assign("frontend1", id) != arm {
+			t.Error("assignment is not sticky for", id)
+		}
+	}
+}
+
+func TestExperimentAssignDistribution(t *testing.T) {
+	e := testExperiment()
+	e.Arms[0].Weight = 0.9
+	e.Arms[1].Weight = 0.1
+	counts := map[string]int{}
+	for i := 0; i < 10000; i++ {
+		arm := e.Assign("frontend1", fmt.Sprintf("meeting%d", i))
+		counts[arm.Name]++
+	}
+	if counts["variant"] < 800 || counts["variant"] > 1200 {
+		t.Error("unexpected distribution:", counts)
+	}
+}
+
+func TestExperimentAssignByFrontend(t *testing.T) {
+	e := testExperiment()
+	e.AssignBy = AssignByFrontend
+	arm := e.Assign("frontend1", "meeting1")
+	for i := 0; i < 10; i++ {
+		if e.Assign("frontend1", fmt.Sprintf("m%d", i)) != arm {
+			t.Error("all meetings of a frontend should have the same arm")
+		}
+	}
+}
+
+func TestExperimentIncludes(t *testing.T) {
+	e := testExperiment()
+	e.Frontends = []string{"frontend1"}
+	if e.Assign("frontend2", "meeting1") != nil {
+		t.Error("frontend2 should not participate")
+	}
+	if e.Assign("frontend1", "meeting1") == nil {
+		t.Error("frontend1 should participate")
+	}
+}
+
+func TestExperimentValidate(t *testing.T) {
+	e := testExperiment()
+	if err := e.Validate(); err != nil {
+		t.Error(err)
+	}
+	e.Arms = nil
+	if err := e.Validate(); !errors.Is(err, ErrNoArms) {
+		t.Error("unexpected error:", err)
+	}
+	e = testExperiment()
+	e.AssignBy = "user"
+	if err := e.Validate(); err == nil {
+		t.Error("expected an error for assign_by")
+	}
+}
+
+func TestLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "experiments")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "experiments.json")
+	data := `[{"name": "e1", "assign_by": "frontend",
+	           "arms": [{"name": "a"}, {"name": "b",
+	                     "required_tags": ["new"]}]}]`
+	if err := ioutil.WriteFile(filename, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	experiments, err := Load(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(experiments) != 1 || len(experiments[0].Arms) != 2 {
+		t.Error("unexpected experiments:", experiments)
+	}
+}
+
+func TestAssignmentsContext(t *testing.T) {
+	ctx := context.Background()
+	if AssignmentsFromContext(ctx) != nil {
+		t.Error("expected no assignments")
+	}
+	assignments := Assign([]*Experiment{testExperiment()}, "f", "m")
+	ctx = ContextWithAssignments(ctx, assignments)
+	if len(AssignmentsFromContext(ctx)) != 1 {
+		t.Error("expected assignments in context")
+	}
+}
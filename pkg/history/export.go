@@ -0,0 +1,88 @@
+package history
+
+import (
+	"context"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ExportDay exports the history of a day, unless it
+// was already exported by another instance. The day is
+// claimed in the same transaction, so a failed export
+// is retried.
+func ExportDay(
+	ctx context.Context,
+	target Target,
+	format Format,
+	day time.Time,
+) (bool, error) {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	claimed, err := store.ClaimHistoryExport(ctx, tx, day)
+	if err != nil || !claimed {
+		return false, err
+	}
+	files, err := BuildFiles(ctx, tx, format, day)
+	if err != nil {
+		return false, err
+	}
+	for _, f := range files {
+		if err := target.Put(ctx, f.Name, f.Data); err != nil {
+			return false, err
+		}
+	}
+	return true, tx.Commit(ctx)
+}
+
+// ExportInterval is the interval in which the
+// export of the previous day is attempted.
+const ExportInterval = 15 * time.Minute
+
+// Start exports the history of the previous day
+// periodically until the context is done. The usage
+// is flushed with a delay, so the day is exported
+// after the flush interval has passed.
+func Start(
+	ctx context.Context,
+	target Target,
+	format Format,
+	delay time.Duration,
+) {
+	ticker := time.NewTicker(ExportInterval)
+	defer ticker.Stop()
+	for {
+		now := time.Now().UTC()
+		today := time.Date(
+			now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		if now.Sub(today) > delay {
+			day := today.AddDate(0, 0, -1)
+			exported, err := ExportDay(ctx, target, format, day)
+			if err != nil {
+				log.Error().Err(err).
+					Str("day", day.Format(dayFormat)).
+					Msg("history export failed")
+			} else if exported {
+				log.Info().
+					Str("day", day.Format(dayFormat)).
+					Msg("exported history")
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
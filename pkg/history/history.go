@@ -0,0 +1,289 @@
+package history
+
+/*
+ History export: The history of the ended meetings and the
+ usage of the frontends are exported daily for the ingestion
+ into data warehouses. Each dataset of a day is written as
+ gzip compressed CSV file with a header or as parquet file, e.g.
+
+    meetings/2021-06-01.csv.gz
+    usage/2021-06-01.parquet
+
+ The files are written to a directory or uploaded to an
+ S3 compatible object storage.
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// dayFormat is the format of the days in the filenames
+const dayFormat = "2006-01-02"
+
+// Datasets of the export
+const (
+	DatasetMeetings = "meetings"
+	DatasetUsage    = "usage"
+)
+
+// A File is an encoded dataset of a day
+type File struct {
+	Name string
+	Data []byte
+}
+
+// Filename of the dataset of the day
+func Filename(dataset string, format Format, day time.Time) string {
+	return dataset + "/" + day.UTC().Format(dayFormat) + format.Extension()
+}
+
+// ColumnKind is the type of the values of a column
+type ColumnKind int
+
+// Column kinds: The values of a column are strings,
+// int64 or time.Time. Values of optional columns
+// may be nil.
+const (
+	KindString ColumnKind = iota
+	KindInt
+	KindTime
+)
+
+// A Column of a table
+type Column struct {
+	Name     string
+	Kind     ColumnKind
+	Optional bool
+}
+
+// A Table is a dataset with typed columns
+type Table struct {
+	Columns []Column
+	Rows    [][]interface{}
+}
+
+// A Format encodes the tables of the datasets
+type Format interface {
+	Name() string
+
+	// Extension of the files, e.g. .csv.gz
+	Extension() string
+
+	Encode(w io.Writer, t *Table) error
+}
+
+// Formats are the available export formats by name
+var Formats = map[string]Format{}
+
+// RegisterFormat makes a format available
+func RegisterFormat(f Format) {
+	Formats[f.Name()] = f
+}
+
+// GetFormat looks up a format by name
+func GetFormat(name string) (Format, error) {
+	f, ok := Formats[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown history format: %s", name)
+	}
+	return f, nil
+}
+
+func init() {
+	RegisterFormat(CSVFormat{})
+	RegisterFormat(ParquetFormat{})
+}
+
+// CSVFormat writes the datasets as gzip
+// compressed CSV with a header.
+type CSVFormat struct{}
+
+// Name implements the Format interface
+func (CSVFormat) Name() string { return "csv" }
+
+// Extension implements the Format interface
+func (CSVFormat) Extension() string { return ".csv.gz" }
+
+// Encode implements the Format interface
+func (CSVFormat) Encode(w io.Writer, t *Table) error {
+	zw := gzip.NewWriter(w)
+	out := csv.NewWriter(zw)
+	header := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		header[i] = c.Name
+	}
+	out.Write(header)
+	for _, row := range t.Rows {
+		record := make([]string, len(row))
+		for i, v := range row {
+			record[i] = csvValue(v)
+		}
+		out.Write(record)
+	}
+	out.Flush()
+	if err := out.Error(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// csvValue formats a value of a table
+func csvValue(v interface{}) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// optional returns nil for a nil string
+func optional(s *string) interface{} {
+	if s == nil {
+		return nil
+	}
+	return *s
+}
+
+// MeetingsTable creates the table of the meeting
+// history. The labels are encoded as JSON.
+func MeetingsTable(history []*store.MeetingHistory) (*Table, error) {
+	rows := make([][]interface{}, 0, len(history))
+	for _, h := range history {
+		labels, err := json.Marshal(h.Labels)
+		if err != nil {
+			return nil, err
+		}
+		duration := h.EndedAt.Sub(h.CreatedAt) / time.Second
+		rows = append(rows, []interface{}{
+			h.MeetingID,
+			optional(h.InternalID),
+			h.MeetingName,
+			optional(h.FrontendID),
+			optional(h.FrontendKey),
+			optional(h.BackendID),
+			optional(h.BackendHost),
+			string(labels),
+			int64(h.Participants),
+			h.CreatedAt.UTC(),
+			h.EndedAt.UTC(),
+			int64(duration),
+		})
+	}
+	return &Table{
+		Columns: []Column{
+			{Name: "meeting_id"},
+			{Name: "internal_id", Optional: true},
+			{Name: "meeting_name"},
+			{Name: "frontend_id", Optional: true},
+			{Name: "frontend_key", Optional: true},
+			{Name: "backend_id", Optional: true},
+			{Name: "backend_host", Optional: true},
+			{Name: "labels"},
+			{Name: "participants", Kind: KindInt},
+			{Name: "created_at", Kind: KindTime},
+			{Name: "ended_at", Kind: KindTime},
+			{Name: "duration_seconds", Kind: KindInt},
+		},
+		Rows: rows,
+	}, nil
+}
+
+// UsageTable creates the table of the
+// usage of the frontends.
+func UsageTable(usage []*store.FrontendUsage) *Table {
+	rows := make([][]interface{}, 0, len(usage))
+	for _, u := range usage {
+		rows = append(rows, []interface{}{
+			u.Day,
+			u.FrontendID,
+			u.FrontendKey,
+			u.Resource,
+			u.Requests,
+		})
+	}
+	return &Table{
+		Columns: []Column{
+			{Name: "day"},
+			{Name: "frontend_id"},
+			{Name: "frontend_key"},
+			{Name: "resource"},
+			{Name: "requests", Kind: KindInt},
+		},
+		Rows: rows,
+	}
+}
+
+// EncodeMeetings writes the meeting history
+func EncodeMeetings(
+	w io.Writer,
+	format Format,
+	history []*store.MeetingHistory,
+) error {
+	t, err := MeetingsTable(history)
+	if err != nil {
+		return err
+	}
+	return format.Encode(w, t)
+}
+
+// EncodeUsage writes the usage of the frontends
+func EncodeUsage(
+	w io.Writer,
+	format Format,
+	usage []*store.FrontendUsage,
+) error {
+	return format.Encode(w, UsageTable(usage))
+}
+
+// BuildFiles loads the history of the day
+// and encodes the datasets.
+func BuildFiles(
+	ctx context.Context,
+	tx pgx.Tx,
+	format Format,
+	day time.Time,
+) ([]*File, error) {
+	from := time.Date(
+		day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC)
+	meetings, err := store.GetMeetingHistory(ctx, tx, store.Q().
+		Where("meeting_history.ended_at >= ?", from).
+		Where("meeting_history.ended_at < ?", from.AddDate(0, 0, 1)))
+	if err != nil {
+		return nil, err
+	}
+	usage, err := store.GetFrontendUsage(ctx, tx, store.Q().
+		Where("frontend_usage.day = ?::date", from.Format(dayFormat)))
+	if err != nil {
+		return nil, err
+	}
+
+	meetingsData := &bytes.Buffer{}
+	if err := EncodeMeetings(meetingsData, format, meetings); err != nil {
+		return nil, err
+	}
+	usageData := &bytes.Buffer{}
+	if err := EncodeUsage(usageData, format, usage); err != nil {
+		return nil, err
+	}
+	return []*File{
+		{Name: Filename(DatasetMeetings, format, from), Data: meetingsData.Bytes()},
+		{Name: Filename(DatasetUsage, format, from), Data: usageData.Bytes()},
+	}, nil
+}
@@ -0,0 +1,87 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func decodeCSV(t *testing.T, data []byte) [][]string {
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(zr).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return records
+}
+
+func TestFilename(t *testing.T) {
+	day := time.Date(2021, 6, 1, 23, 0, 0, 0, time.UTC)
+	if name := Filename(DatasetMeetings, CSVFormat{}, day); name != "meetings/2021-06-01.csv.gz" {
+		t.Error("unexpected filename:", name)
+	}
+}
+
+func TestEncodeMeetings(t *testing.T) {
+	key := "frontend1"
+	created := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	buf := &bytes.Buffer{}
+	err := EncodeMeetings(buf, CSVFormat{}, []*store.MeetingHistory{
+		{
+			MeetingID:    "meeting1",
+			MeetingName:  "Lecture, Physics",
+			FrontendKey:  &key,
+			Labels:       store.Labels{"course": "physics-101"},
+			Participants: 42,
+			CreatedAt:    created,
+			EndedAt:      created.Add(90 * time.Minute),
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := decodeCSV(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatal("unexpected records:", records)
+	}
+	r := records[1]
+	if r[0] != "meeting1" || r[2] != "Lecture, Physics" || r[4] != "frontend1" {
+		t.Error("unexpected meeting:", r)
+	}
+	if r[7] != `{"course":"physics-101"}` {
+		t.Error("unexpected labels:", r[7])
+	}
+	if r[8] != "42" || r[11] != "5400" {
+		t.Error("unexpected participants or duration:", r)
+	}
+}
+
+func TestEncodeUsage(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := EncodeUsage(buf, CSVFormat{}, []*store.FrontendUsage{
+		{
+			Day:         "2021-06-01",
+			FrontendID:  "f1",
+			FrontendKey: "frontend1",
+			Resource:    "join",
+			Requests:    23,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := decodeCSV(t, buf.Bytes())
+	if len(records) != 2 {
+		t.Fatal("unexpected records:", records)
+	}
+	if records[0][0] != "day" || records[1][4] != "23" {
+		t.Error("unexpected records:", records)
+	}
+}
@@ -0,0 +1,379 @@
+package history
+
+/*
+ Parquet: The datasets are written as a single row group
+ with one data page (v1) per column. The values are PLAIN
+ encoded and the pages are gzip compressed. Optional columns
+ have RLE encoded definition levels. The metadata is
+ encoded with the thrift compact protocol.
+
+ Strings are BYTE_ARRAY (UTF8), integers INT64 and times
+ INT64 (TIMESTAMP_MILLIS, UTC).
+*/
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"time"
+)
+
+// parquetMagic starts and ends a parquet file
+const parquetMagic = "PAR1"
+
+// Parquet physical types, repetitions, converted
+// types, encodings and codecs
+const (
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetRequired = 0
+	parquetOptional = 1
+
+	parquetUTF8            = 0
+	parquetTimestampMillis = 9
+
+	parquetPlain = 0
+	parquetRLE   = 3
+
+	parquetGzip = 2
+
+	parquetDataPage = 0
+)
+
+// ParquetFormat writes the datasets as parquet files
+type ParquetFormat struct{}
+
+// Name implements the Format interface
+func (ParquetFormat) Name() string { return "parquet" }
+
+// Extension implements the Format interface
+func (ParquetFormat) Extension() string { return ".parquet" }
+
+// Encode implements the Format interface
+func (ParquetFormat) Encode(w io.Writer, t *Table) error {
+	out := &countingWriter{w: w}
+	if _, err := io.WriteString(out, parquetMagic); err != nil {
+		return err
+	}
+	chunks := make([]*thriftWriter, 0, len(t.Columns))
+	totalSize := int64(0)
+	for i, col := range t.Columns {
+		offset := out.n
+		page, numValues, uncompressed, err := parquetPage(t, i)
+		if err != nil {
+			return fmt.Errorf("column %s: %w", col.Name, err)
+		}
+		if _, err := out.Write(page); err != nil {
+			return err
+		}
+		size := out.n - offset
+		totalSize += uncompressed
+		chunks = append(chunks, parquetColumnChunk(
+			col, offset, numValues, uncompressed, size))
+	}
+
+	meta := &thriftWriter{}
+	meta.fieldI32(1, 1) // version
+	meta.fieldList(2, thriftStruct, len(t.Columns)+1)
+	root := &thriftWriter{}
+	root.fieldBinary(4, []byte("schema"))
+	root.fieldI32(5, int32(len(t.Columns)))
+	meta.structElem(root)
+	for _, col := range t.Columns {
+		meta.structElem(parquetSchemaElement(col))
+	}
+	meta.fieldI64(3, int64(len(t.Rows)))
+	meta.fieldList(4, thriftStruct, 1)
+	group := &thriftWriter{}
+	group.fieldList(1, thriftStruct, len(chunks))
+	for _, c := range chunks {
+		group.structElem(c)
+	}
+	group.fieldI64(2, totalSize)
+	group.fieldI64(3, int64(len(t.Rows)))
+	meta.structElem(group)
+	meta.fieldBinary(6, []byte("b3scale"))
+	meta.stop()
+
+	if _, err := out.Write(meta.buf.Bytes()); err != nil {
+		return err
+	}
+	footer := make([]byte, 4)
+	binary.LittleEndian.PutUint32(footer, uint32(meta.buf.Len()))
+	if _, err := out.Write(footer); err != nil {
+		return err
+	}
+	_, err := io.WriteString(out, parquetMagic)
+	return err
+}
+
+// parquetSchemaElement describes a column
+func parquetSchemaElement(col Column) *thriftWriter {
+	e := &thriftWriter{}
+	typ, converted := parquetTypes(col.Kind)
+	e.fieldI32(1, typ)
+	repetition := int32(parquetRequired)
+	if col.Optional {
+		repetition = parquetOptional
+	}
+	e.fieldI32(3, repetition)
+	e.fieldBinary(4, []byte(col.Name))
+	if converted >= 0 {
+		e.fieldI32(6, converted)
+	}
+	return e
+}
+
+// parquetTypes maps the kind of a column to the physical
+// type and the converted type. Without a converted type,
+// the result is -1.
+func parquetTypes(kind ColumnKind) (int32, int32) {
+	switch kind {
+	case KindInt:
+		return parquetInt64, -1
+	case KindTime:
+		return parquetInt64, parquetTimestampMillis
+	default:
+		return parquetByteArray, parquetUTF8
+	}
+}
+
+// parquetColumnChunk describes the page of a column
+func parquetColumnChunk(
+	col Column,
+	offset int64,
+	numValues int,
+	uncompressed int64,
+	size int64,
+) *thriftWriter {
+	typ, _ := parquetTypes(col.Kind)
+	meta := &thriftWriter{}
+	meta.fieldI32(1, typ)
+	meta.fieldList(2, thriftI32, 2)
+	meta.i32(parquetPlain)
+	meta.i32(parquetRLE)
+	meta.fieldList(3, thriftBinary, 1)
+	meta.binary([]byte(col.Name))
+	meta.fieldI32(4, parquetGzip)
+	meta.fieldI64(5, int64(numValues))
+	meta.fieldI64(6, uncompressed)
+	meta.fieldI64(7, size)
+	meta.fieldI64(9, offset)
+
+	chunk := &thriftWriter{}
+	chunk.fieldI64(2, offset)
+	chunk.fieldStruct(3, meta)
+	return chunk
+}
+
+// parquetPage encodes the values of a column as
+// compressed data page with the page header. The size
+// of the uncompressed page and header is returned.
+func parquetPage(t *Table, i int) ([]byte, int, int64, error) {
+	col := t.Columns[i]
+	levels := make([]byte, 0, len(t.Rows))
+	values := &bytes.Buffer{}
+	for _, row := range t.Rows {
+		v := row[i]
+		if v == nil {
+			if !col.Optional {
+				return nil, 0, 0, fmt.Errorf("missing value")
+			}
+			levels = append(levels, 0)
+			continue
+		}
+		levels = append(levels, 1)
+		if err := parquetPlainValue(values, col.Kind, v); err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	data := &bytes.Buffer{}
+	if col.Optional {
+		rle := parquetRLELevels(levels)
+		binary.Write(data, binary.LittleEndian, uint32(len(rle)))
+		data.Write(rle)
+	}
+	data.Write(values.Bytes())
+
+	compressed := &bytes.Buffer{}
+	zw := gzip.NewWriter(compressed)
+	if _, err := zw.Write(data.Bytes()); err != nil {
+		return nil, 0, 0, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, 0, 0, err
+	}
+
+	dataHeader := &thriftWriter{}
+	dataHeader.fieldI32(1, int32(len(t.Rows)))
+	dataHeader.fieldI32(2, parquetPlain)
+	dataHeader.fieldI32(3, parquetRLE)
+	dataHeader.fieldI32(4, parquetRLE)
+	header := &thriftWriter{}
+	header.fieldI32(1, parquetDataPage)
+	header.fieldI32(2, int32(data.Len()))
+	header.fieldI32(3, int32(compressed.Len()))
+	header.fieldStruct(5, dataHeader)
+	header.stop()
+
+	page := append(header.buf.Bytes(), compressed.Bytes()...)
+	uncompressed := int64(header.buf.Len() + data.Len())
+	return page, len(t.Rows), uncompressed, nil
+}
+
+// parquetPlainValue appends the PLAIN encoded value
+func parquetPlainValue(buf *bytes.Buffer, kind ColumnKind, v interface{}) error {
+	switch kind {
+	case KindInt:
+		n, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("unexpected value: %v", v)
+		}
+		return binary.Write(buf, binary.LittleEndian, n)
+	case KindTime:
+		t, ok := v.(time.Time)
+		if !ok {
+			return fmt.Errorf("unexpected value: %v", v)
+		}
+		ms := t.UnixNano() / int64(time.Millisecond)
+		return binary.Write(buf, binary.LittleEndian, ms)
+	default:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("unexpected value: %v", v)
+		}
+		binary.Write(buf, binary.LittleEndian, uint32(len(s)))
+		buf.WriteString(s)
+		return nil
+	}
+}
+
+// parquetRLELevels encodes the definition levels (0 or 1)
+// as RLE runs of the hybrid encoding with bit width 1.
+func parquetRLELevels(levels []byte) []byte {
+	buf := []byte{}
+	for i := 0; i < len(levels); {
+		j := i
+		for j < len(levels) && levels[j] == levels[i] {
+			j++
+		}
+		buf = appendUvarint(buf, uint64(j-i)<<1)
+		buf = append(buf, levels[i])
+		i = j
+	}
+	return buf
+}
+
+// appendUvarint appends the ULEB128 encoded value
+func appendUvarint(buf []byte, v uint64) []byte {
+	tmp := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(tmp, v)
+	return append(buf, tmp[:n]...)
+}
+
+// countingWriter tracks the offset in the file
+type countingWriter struct {
+	w io.Writer
+	n int64
+}
+
+// Write implements io.Writer
+func (c *countingWriter) Write(p []byte) (int, error) {
+	n, err := c.w.Write(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// Thrift compact protocol types
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftWriter encodes a struct with the thrift
+// compact protocol. Fields must be written in
+// ascending order.
+type thriftWriter struct {
+	buf     bytes.Buffer
+	lastID  int16
+	stopped bool
+}
+
+// field writes the header of a field
+func (t *thriftWriter) field(id int16, typ byte) {
+	delta := id - t.lastID
+	if delta > 0 && delta <= 15 {
+		t.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		t.buf.WriteByte(typ)
+		t.varint(int64(id))
+	}
+	t.lastID = id
+}
+
+// varint writes a zigzag encoded integer
+func (t *thriftWriter) varint(v int64) {
+	zz := uint64((v << 1) ^ (v >> 63))
+	t.buf.Write(appendUvarint(nil, zz))
+}
+
+func (t *thriftWriter) i32(v int32) { t.varint(int64(v)) }
+
+func (t *thriftWriter) binary(v []byte) {
+	t.buf.Write(appendUvarint(nil, uint64(len(v))))
+	t.buf.Write(v)
+}
+
+func (t *thriftWriter) fieldI32(id int16, v int32) {
+	t.field(id, thriftI32)
+	t.i32(v)
+}
+
+func (t *thriftWriter) fieldI64(id int16, v int64) {
+	t.field(id, thriftI64)
+	t.varint(v)
+}
+
+func (t *thriftWriter) fieldBinary(id int16, v []byte) {
+	t.field(id, thriftBinary)
+	t.binary(v)
+}
+
+// fieldList writes the header of a list,
+// the elements are written next.
+func (t *thriftWriter) fieldList(id int16, elem byte, size int) {
+	t.field(id, thriftList)
+	if size < 15 {
+		t.buf.WriteByte(byte(size)<<4 | elem)
+	} else {
+		t.buf.WriteByte(0xf0 | elem)
+		t.buf.Write(appendUvarint(nil, uint64(size)))
+	}
+}
+
+func (t *thriftWriter) fieldStruct(id int16, s *thriftWriter) {
+	t.field(id, thriftStruct)
+	t.structElem(s)
+}
+
+// structElem writes a nested struct
+func (t *thriftWriter) structElem(s *thriftWriter) {
+	s.stop()
+	t.buf.Write(s.buf.Bytes())
+}
+
+// stop ends the struct
+func (t *thriftWriter) stop() {
+	if t.stopped {
+		return
+	}
+	t.buf.WriteByte(0)
+	t.stopped = true
+}
@@ -0,0 +1,223 @@
+package history
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// thriftReader decodes the thrift compact protocol
+// into maps of the field ids.
+type thriftReader struct {
+	t   *testing.T
+	buf *bytes.Reader
+}
+
+func (r *thriftReader) varint() int64 {
+	u, err := binary.ReadUvarint(r.buf)
+	if err != nil {
+		r.t.Fatal(err)
+	}
+	return int64(u>>1) ^ -int64(u&1)
+}
+
+func (r *thriftReader) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return r.varint()
+	case thriftBinary:
+		n, err := binary.ReadUvarint(r.buf)
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		b := make([]byte, n)
+		r.buf.Read(b)
+		return string(b)
+	case thriftList:
+		h, _ := r.buf.ReadByte()
+		size, elem := int(h>>4), h&0x0f
+		if size == 15 {
+			n, _ := binary.ReadUvarint(r.buf)
+			size = int(n)
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = r.value(elem)
+		}
+		return list
+	case thriftStruct:
+		return r.structValue()
+	}
+	r.t.Fatal("unexpected thrift type:", typ)
+	return nil
+}
+
+func (r *thriftReader) structValue() map[int16]interface{} {
+	fields := map[int16]interface{}{}
+	id := int16(0)
+	for {
+		h, err := r.buf.ReadByte()
+		if err != nil {
+			r.t.Fatal(err)
+		}
+		if h == 0 {
+			return fields
+		}
+		if delta := int16(h >> 4); delta != 0 {
+			id += delta
+		} else {
+			id = int16(r.varint())
+		}
+		fields[id] = r.value(h & 0x0f)
+	}
+}
+
+// readParquet decodes the columns of the file
+func readParquet(t *testing.T, data []byte) (int64, map[string][]interface{}) {
+	if string(data[:4]) != parquetMagic ||
+		string(data[len(data)-4:]) != parquetMagic {
+		t.Fatal("magic is missing")
+	}
+	size := binary.LittleEndian.Uint32(data[len(data)-8:])
+	footer := data[len(data)-8-int(size) : len(data)-8]
+	meta := (&thriftReader{t, bytes.NewReader(footer)}).structValue()
+
+	schema := meta[2].([]interface{})
+	columns := map[string][]interface{}{}
+	group := meta[4].([]interface{})[0].(map[int16]interface{})
+	for i, c := range group[1].([]interface{}) {
+		elem := schema[i+1].(map[int16]interface{})
+		chunk := c.(map[int16]interface{})[3].(map[int16]interface{})
+		offset := chunk[9].(int64)
+
+		pr := &thriftReader{t, bytes.NewReader(data[offset:])}
+		header := pr.structValue()
+		headerLen := int64(len(data[offset:]) - pr.buf.Len())
+		compressed := data[offset+headerLen : offset+headerLen+header[3].(int64)]
+		zr, err := gzip.NewReader(bytes.NewReader(compressed))
+		if err != nil {
+			t.Fatal(err)
+		}
+		page, err := ioutil.ReadAll(zr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(page)) != header[2].(int64) {
+			t.Error("unexpected uncompressed size:", len(page))
+		}
+		numValues := int(header[5].(map[int16]interface{})[1].(int64))
+
+		// Definition levels
+		levels := make([]byte, 0, numValues)
+		pos := 0
+		if elem[3].(int64) == parquetOptional {
+			n := int(binary.LittleEndian.Uint32(page))
+			rle := bytes.NewReader(page[4 : 4+n])
+			for rle.Len() > 0 {
+				run, _ := binary.ReadUvarint(rle)
+				level, _ := rle.ReadByte()
+				for j := 0; j < int(run>>1); j++ {
+					levels = append(levels, level)
+				}
+			}
+			pos = 4 + n
+		} else {
+			for j := 0; j < numValues; j++ {
+				levels = append(levels, 1)
+			}
+		}
+
+		values := make([]interface{}, 0, numValues)
+		for _, level := range levels {
+			if level == 0 {
+				values = append(values, nil)
+				continue
+			}
+			switch elem[1].(int64) {
+			case parquetInt64:
+				v := int64(binary.LittleEndian.Uint64(page[pos:]))
+				values = append(values, v)
+				pos += 8
+			case parquetByteArray:
+				n := int(binary.LittleEndian.Uint32(page[pos:]))
+				values = append(values, string(page[pos+4:pos+4+n]))
+				pos += 4 + n
+			}
+		}
+		columns[elem[4].(string)] = values
+	}
+	return meta[3].(int64), columns
+}
+
+func TestParquetFormatEncodeMeetings(t *testing.T) {
+	key := "frontend1"
+	created := time.Date(2021, 6, 1, 10, 0, 0, 0, time.UTC)
+	history := []*store.MeetingHistory{}
+	for i := 0; i < 20; i++ {
+		h := &store.MeetingHistory{
+			MeetingID:    "meeting1",
+			MeetingName:  "Lecture, Physics",
+			Labels:       store.Labels{"course": "physics-101"},
+			Participants: i,
+			CreatedAt:    created,
+			EndedAt:      created.Add(90 * time.Minute),
+		}
+		if i%3 == 0 {
+			h.FrontendKey = &key
+		}
+		history = append(history, h)
+	}
+	buf := &bytes.Buffer{}
+	if err := EncodeMeetings(buf, ParquetFormat{}, history); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, columns := readParquet(t, buf.Bytes())
+	if rows != 20 {
+		t.Error("unexpected rows:", rows)
+	}
+	if len(columns) != 12 {
+		t.Error("unexpected columns:", columns)
+	}
+	keys := columns["frontend_key"]
+	if keys[0] != "frontend1" || keys[1] != nil || keys[3] != "frontend1" {
+		t.Error("unexpected frontend keys:", keys)
+	}
+	if columns["participants"][19] != int64(19) {
+		t.Error("unexpected participants:", columns["participants"])
+	}
+	if columns["labels"][0] != `{"course":"physics-101"}` {
+		t.Error("unexpected labels:", columns["labels"][0])
+	}
+	ms := created.UnixNano() / int64(time.Millisecond)
+	if columns["created_at"][0] != ms {
+		t.Error("unexpected created_at:", columns["created_at"][0])
+	}
+	if columns["duration_seconds"][5] != int64(5400) {
+		t.Error("unexpected duration:", columns["duration_seconds"][5])
+	}
+}
+
+func TestParquetFormatEncodeEmpty(t *testing.T) {
+	buf := &bytes.Buffer{}
+	if err := EncodeUsage(buf, ParquetFormat{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	rows, columns := readParquet(t, buf.Bytes())
+	if rows != 0 || len(columns) != 5 {
+		t.Error("unexpected usage:", rows, columns)
+	}
+}
+
+func TestParquetRLELevels(t *testing.T) {
+	rle := parquetRLELevels([]byte{1, 1, 1, 0, 1})
+	expected := []byte{3 << 1, 1, 1 << 1, 0, 1 << 1, 1}
+	if !bytes.Equal(rle, expected) {
+		t.Error("unexpected levels:", rle)
+	}
+}
@@ -0,0 +1,94 @@
+package history
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// A Target stores the exported files
+type Target interface {
+	Put(ctx context.Context, name string, data []byte) error
+}
+
+// NewTarget creates the target of the export.
+// Targets starting with s3:// are buckets of an
+// object storage, e.g.
+//
+//	s3://bucket/prefix?region=eu-central-1
+//
+// An endpoint for S3 compatible storages can be passed
+// with `endpoint=https://...`. All other targets
+// are directories.
+func NewTarget(target, accessKey, secretKey string) (Target, error) {
+	if !strings.HasPrefix(target, "s3://") {
+		return &DirTarget{Dir: target}, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("bucket is missing: %s", target)
+	}
+	if accessKey == "" || secretKey == "" {
+		return nil, fmt.Errorf("credentials are missing for %s", target)
+	}
+	region := u.Query().Get("region")
+	if region == "" {
+		region = S3DefaultRegion
+	}
+	endpoint := u.Query().Get("endpoint")
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	if _, err := url.Parse(endpoint); err != nil {
+		return nil, err
+	}
+	prefix := strings.Trim(u.Path, "/")
+	if prefix != "" {
+		prefix += "/"
+	}
+	return &S3Target{
+		Endpoint:  strings.TrimSuffix(endpoint, "/"),
+		Region:    region,
+		Bucket:    u.Host,
+		Prefix:    prefix,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+		Client:    &http.Client{Timeout: 5 * time.Minute},
+	}, nil
+}
+
+// DirTarget writes the files into a directory
+type DirTarget struct {
+	Dir string
+}
+
+// Put writes the data to a temporary file,
+// which is renamed when complete.
+func (t *DirTarget) Put(ctx context.Context, name string, data []byte) error {
+	filename := filepath.Join(t.Dir, filepath.FromSlash(name))
+	dir := filepath.Dir(filename)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), filename)
+}
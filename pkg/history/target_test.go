@@ -0,0 +1,62 @@
+package history
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNewTarget(t *testing.T) {
+	target, err := NewTarget("/var/lib/b3scale/history", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := target.(*DirTarget); !ok {
+		t.Error("expected a directory target:", target)
+	}
+
+	target, err = NewTarget("s3://warehouse/b3scale/", "access", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s3, ok := target.(*S3Target)
+	if !ok {
+		t.Fatal("expected an s3 target:", target)
+	}
+	if s3.Bucket != "warehouse" || s3.Prefix != "b3scale/" {
+		t.Error("unexpected bucket:", s3.Bucket, s3.Prefix)
+	}
+	if s3.Endpoint != "https://s3.us-east-1.amazonaws.com" {
+		t.Error("unexpected endpoint:", s3.Endpoint)
+	}
+
+	for _, invalid := range []string{"s3://", "s3:///prefix"} {
+		if _, err := NewTarget(invalid, "access", "secret"); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+	if _, err := NewTarget("s3://warehouse", "", ""); err == nil {
+		t.Error("expected an error for missing credentials")
+	}
+}
+
+func TestDirTargetPut(t *testing.T) {
+	dir := t.TempDir()
+	target := &DirTarget{Dir: dir}
+	err := target.Put(context.Background(), "meetings/2021-06-01.csv.gz", []byte("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "meetings", "2021-06-01.csv.gz"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "data" {
+		t.Error("unexpected data:", string(data))
+	}
+	files, _ := os.ReadDir(filepath.Join(dir, "meetings"))
+	if len(files) != 1 {
+		t.Error("temporary files should be removed:", files)
+	}
+}
@@ -0,0 +1,179 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// AgentStatus is the version of the node agent of
+// a backend compared with the desired release.
+type AgentStatus struct {
+	BackendID      string `json:"backend_id"`
+	Host           string `json:"host"`
+	AgentVersion   string `json:"agent_version"`
+	AgentProtocol  int    `json:"agent_protocol"`
+	AgentAlive     bool   `json:"agent_alive"`
+	DesiredVersion string `json:"desired_version"`
+	Skewed         bool   `json:"skewed"`
+
+	// ProtocolError is set if the agent is refused
+	ProtocolError string `json:"protocol_error,omitempty"`
+}
+
+// AgentsList retrieves the versions of the node agents
+// ! requires: `admin`
+func AgentsList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	release, err := store.GetAgentRelease(cctx, tx)
+	if err != nil {
+		return err
+	}
+	desired := ""
+	if release != nil {
+		desired = release.Version
+	}
+	backends, err := store.GetBackendStates(cctx, tx, store.Q().
+		OrderBy("backends.host"))
+	if err != nil {
+		return err
+	}
+	agents := make([]*AgentStatus, 0, len(backends))
+	for _, b := range backends {
+		status := &AgentStatus{
+			BackendID:      b.ID,
+			Host:           b.Backend.Host,
+			AgentVersion:   b.AgentVersion,
+			AgentProtocol:  b.AgentProtocol,
+			AgentAlive:     b.IsAgentAlive(),
+			DesiredVersion: desired,
+			Skewed:         release.IsSkewed(b.AgentVersion),
+		}
+		if err := cluster.CheckAgentProtocol(b.AgentProtocol); err != nil {
+			status.ProtocolError = err.Error()
+		}
+		agents = append(agents, status)
+	}
+	return c.JSON(http.StatusOK, agents)
+}
+
+// AgentReleaseShow retrieves the desired agent release
+// ! requires: `admin`
+func AgentReleaseShow(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	release, err := store.GetAgentRelease(cctx, tx)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return echo.ErrNotFound
+	}
+	return c.JSON(http.StatusOK, release)
+}
+
+// AgentReleaseSet sets the desired agent release. Agents
+// with another version report the skew.
+// ! requires: `admin`
+func AgentReleaseSet(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	release := &store.AgentRelease{}
+	if err := c.Bind(release); err != nil {
+		return err
+	}
+	if err := release.Validate(); err != nil {
+		return err
+	}
+	release.CreatedBy = ctx.AccountRef()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	if err := release.Save(cctx, tx); err != nil {
+		return err
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditAgentReleaseSet,
+		ResourceType: "cluster",
+		Details: map[string]interface{}{
+			"version": release.Version,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("version", release.Version).
+		Str("actor", ctx.AccountRef()).
+		Msg("agent release set")
+
+	return c.JSON(http.StatusOK, release)
+}
+
+// AgentReleaseDestroy removes the desired agent release
+// ! requires: `admin`
+func AgentReleaseDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	release, err := store.GetAgentRelease(cctx, tx)
+	if err != nil {
+		return err
+	}
+	if release == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteAgentRelease(cctx, tx); err != nil {
+		return err
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditAgentReleaseDeleted,
+		ResourceType: "cluster",
+		Details: map[string]interface{}{
+			"version": release.Version,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, release)
+}
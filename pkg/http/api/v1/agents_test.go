@@ -0,0 +1,51 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestAgentReleaseSet(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestBackend(); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"version": "1.4.2",
+	})
+	req, _ := http.NewRequest("PUT", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	if err := AgentReleaseSet(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", rec.Result().StatusCode)
+	}
+
+	// The agent of the backend never reported a version
+	req, _ = http.NewRequest("GET", "http:///", nil)
+	ctx, rec = MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	if err := AgentsList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	agents := []*AgentStatus{}
+	if err := readJSONResponse(rec.Result(), &agents); err != nil {
+		t.Fatal(err)
+	}
+	if len(agents) != 1 {
+		t.Fatal("unexpected agents:", agents)
+	}
+	if !agents[0].Skewed || agents[0].DesiredVersion != "1.4.2" {
+		t.Error("unexpected agent status:", agents[0])
+	}
+}
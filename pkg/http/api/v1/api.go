@@ -21,6 +21,7 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/rs/zerolog/log"
 
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
@@ -115,7 +116,13 @@ func RequireAdminScope(fn echo.HandlerFunc) echo.HandlerFunc {
 
 // Init sets up a group with authentication
 // for a restful management interface.
-func Init(e *echo.Echo) error {
+// The router is used for explaining routing decisions,
+// meetings are created through the gateway.
+func Init(
+	e *echo.Echo,
+	router *cluster.Router,
+	gateway *cluster.Gateway,
+) error {
 	// Initialize JWT middleware config
 	jwtConfig, err := NewAPIJWTConfig()
 	if err != nil {
@@ -162,16 +169,32 @@ func Init(e *echo.Echo) error {
 	// Frontends
 	a.GET("/frontends", FrontendsList)
 	a.POST("/frontends", FrontendCreate)
+	a.PUT("/frontends", FrontendPut)
 	a.GET("/frontends/:id", FrontendRetrieve)
 	a.DELETE("/frontends/:id", FrontendDestroy)
 	a.PATCH("/frontends/:id", FrontendUpdate)
+	a.POST("/frontends/:id/secret", FrontendRegenerateSecret)
+	a.GET("/frontends/:id/keys", FrontendKeysList)
+	a.POST("/frontends/:id/keys", FrontendKeyCreate)
+	a.DELETE("/frontends/:id/keys/:keyID", FrontendKeyDestroy)
+	a.GET("/frontends/:id/rooms", FrontendRoomsList)
+	a.POST("/frontends/:id/rooms", FrontendRoomCreate)
+	a.GET("/frontends/:id/rooms/calendar.ics", FrontendRoomsCalendar)
+	a.PUT("/frontends/:id/rooms/:roomID/schedule", FrontendRoomScheduleUpdate)
+	a.DELETE("/frontends/:id/rooms/:roomID", FrontendRoomDestroy)
 
 	// Backends
 	a.GET("/backends", RequireAdminScope(BackendsList))
 	a.POST("/backends", RequireAdminScope(BackendCreate))
+	a.PUT("/backends", RequireAdminScope(BackendPut))
 	a.GET("/backends/:id", RequireAdminScope(BackendRetrieve))
 	a.DELETE("/backends/:id", RequireAdminScope(BackendDestroy))
 	a.PATCH("/backends/:id", RequireAdminScope(BackendUpdate))
+	a.POST("/backends/:id/broadcast", RequireAdminScope(BackendBroadcast))
+	a.GET("/backends/:id/diagnostics",
+		RequireAdminScope(BackendDiagnosticsList))
+	a.GET("/backends/:id/diagnostics/:diagnosticsID",
+		RequireAdminScope(BackendDiagnosticsRetrieve))
 
 	// Meetings at backend. The backend is required because
 	// the returned response set might be really big.
@@ -179,6 +202,72 @@ func Init(e *echo.Echo) error {
 	// the backend ID or by host.
 	a.GET("/meetings", RequireAdminScope(BackendMeetingsList))
 	a.DELETE("/meetings", RequireAdminScope(BackendMeetingsEnd))
+	a.POST("/meetings", RequireAdminScope(MeetingCreate(gateway)))
+	a.POST("/meetings/end", RequireAdminScope(MeetingsEnd))
+	a.GET("/meetings/reconcile", RequireAdminScope(MeetingsReconcile))
+	a.GET("/meetings/labels", RequireAdminScope(MeetingsLabelStats))
+
+	// Search over meetings and recordings
+	a.GET("/search", RequireAdminScope(Search))
+
+	// Meetings pinned to backends
+	a.GET("/pins", RequireAdminScope(MeetingPinsList))
+	a.POST("/pins", RequireAdminScope(MeetingPinCreate))
+	a.DELETE("/pins/:id", RequireAdminScope(MeetingPinDestroy))
+
+	// Banned meeting IDs and user names
+	a.GET("/bans", RequireAdminScope(BansList))
+	a.POST("/bans", RequireAdminScope(BanCreate))
+	a.DELETE("/bans/:id", RequireAdminScope(BanDestroy))
+
+	// Versions of the node agents
+	a.GET("/agents", RequireAdminScope(AgentsList))
+	a.GET("/agents/release", RequireAdminScope(AgentReleaseShow))
+	a.PUT("/agents/release", RequireAdminScope(AgentReleaseSet))
+	a.DELETE("/agents/release", RequireAdminScope(AgentReleaseDestroy))
+
+	// Recordings of the backends compared with the store
+	a.GET("/recordings/reconcile", RequireAdminScope(RecordingsReconcile))
+	a.POST("/recordings/reconcile", RequireAdminScope(RecordingsReconcile))
+
+	// Declarative cluster configuration
+	a.POST("/cluster/apply", RequireAdminScope(ClusterApply))
+	a.GET("/cluster/dump", RequireAdminScope(ClusterDump))
+	a.POST("/cluster/restore", RequireAdminScope(ClusterRestore))
+	a.POST("/cluster/recover", RequireAdminScope(ClusterRecover))
+	a.GET("/cluster/topology", RequireAdminScope(ClusterTopology))
+
+	// Routing
+	a.POST("/routing/explain", RequireAdminScope(RoutingExplain(router)))
+
+	// Polling requests of the frontends
+	a.GET("/polling", RequireAdminScope(PollingTop))
+
+	// API usage of the frontends
+	a.GET("/usage", RequireAdminScope(FrontendsUsage))
+	a.GET("/billing", RequireAdminScope(BillingReport))
+
+	// Experiments
+	a.GET("/experiments", RequireAdminScope(ExperimentsStats))
+
+	// Commands
+	a.GET("/commands", RequireAdminScope(CommandsList))
+	a.POST("/commands", RequireAdminScope(CommandCreate))
+	a.GET("/commands/schemas", RequireAdminScope(CommandSchemasList))
+	a.GET("/commands/:id", RequireAdminScope(CommandRetrieve))
+	a.GET("/commands/:id/wait", RequireAdminScope(CommandWait))
+
+	// Recurring commands
+	a.GET("/schedules", RequireAdminScope(SchedulesList))
+	a.PUT("/schedules/:name", RequireAdminScope(ScheduleUpdate))
+	a.DELETE("/schedules/:name", RequireAdminScope(ScheduleDestroy))
+
+	// Cluster events
+	a.GET("/events", RequireAdminScope(ClusterEventsStream))
+
+	// Logging
+	a.GET("/logging", RequireAdminScope(LoggingRetrieve))
+	a.PATCH("/logging", RequireAdminScope(LoggingUpdate))
 
 	return nil
 }
@@ -191,18 +280,24 @@ type StatusResponse struct {
 	API        string `json:"api"`
 	AccountRef string `json:"account_ref"`
 	IsAdmin    bool   `json:"is_admin"`
+
+	// Database is ok or degraded if the
+	// database is unavailable.
+	Database string `json:"database"`
 }
 
 // Status will respond with the api version and b3scale
 // version.
 func Status(c echo.Context) error {
 	ctx := c.(*APIContext)
+	database, _ := store.Health()
 	status := &StatusResponse{
 		Version:    config.Version,
 		Build:      config.Build,
 		API:        "v1",
 		AccountRef: ctx.AccountRef(),
 		IsAdmin:    ctx.HasScope(ScopeAdmin),
+		Database:   database,
 	}
 	return c.JSON(http.StatusOK, status)
 }
@@ -106,6 +106,9 @@ func ClearState() error {
 	if _, err := tx.Exec(reqCtx, "DELETE FROM commands"); err != nil {
 		return err
 	}
+	if _, err := tx.Exec(reqCtx, "DELETE FROM command_schedules"); err != nil {
+		return err
+	}
 	if _, err := tx.Exec(reqCtx, "DELETE FROM meetings"); err != nil {
 		return err
 	}
@@ -115,6 +118,9 @@ func ClearState() error {
 	if _, err := tx.Exec(reqCtx, "DELETE FROM frontends"); err != nil {
 		return err
 	}
+	if _, err := tx.Exec(reqCtx, "DELETE FROM agent_releases"); err != nil {
+		return err
+	}
 	if err := tx.Commit(reqCtx); err != nil {
 		return err
 	}
@@ -0,0 +1,471 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Plan actions
+const (
+	PlanCreate = "create"
+	PlanUpdate = "update"
+	PlanRemove = "remove"
+)
+
+// ErrPlanChanged is returned when the declaration is
+// applied with a plan that no longer matches.
+var ErrPlanChanged = echo.NewHTTPError(
+	http.StatusPreconditionFailed,
+	"the cluster changed since the plan was made")
+
+// BackendDeclaration is the desired state of a backend.
+// The backend is identified by the host.
+type BackendDeclaration struct {
+	Host       string                `json:"host"`
+	Secret     string                `json:"secret"`
+	AdminState string                `json:"admin_state"`
+	LoadFactor float64               `json:"load_factor"`
+	Settings   store.BackendSettings `json:"settings"`
+}
+
+// FrontendDeclaration is the desired state of a frontend.
+// The frontend is identified by the key.
+type FrontendDeclaration struct {
+	Key        string                 `json:"key"`
+	Secret     string                 `json:"secret"`
+	Active     *bool                  `json:"active"`
+	AccountRef *string                `json:"account_ref"`
+	Settings   store.FrontendSettings `json:"settings"`
+}
+
+// ClusterDeclaration describes the desired backends and
+// frontends of the cluster. Backends and frontends not
+// declared are only removed when pruning. Only the kinds
+// present in the document are pruned: a declaration
+// without frontends leaves all frontends untouched.
+type ClusterDeclaration struct {
+	Backends  []*BackendDeclaration  `json:"backends"`
+	Frontends []*FrontendDeclaration `json:"frontends"`
+	Prune     bool                   `json:"prune"`
+}
+
+// A PlanChange is a change required to reach
+// the declared state.
+type PlanChange struct {
+	Action   string   `json:"action"`
+	Resource string   `json:"resource"`
+	Key      string   `json:"key"`
+	Fields   []string `json:"fields,omitempty"`
+}
+
+// ApplyResponse contains the plan and if
+// it was applied. The plan tag identifies the
+// declaration and the state it was planned against.
+type ApplyResponse struct {
+	Changes []*PlanChange `json:"changes"`
+	Applied bool          `json:"applied"`
+	Plan    string        `json:"plan"`
+}
+
+// sameJSON compares the JSON encoding of two values
+func sameJSON(a, b interface{}) bool {
+	da, _ := json.Marshal(a)
+	db, _ := json.Marshal(b)
+	return string(da) == string(db)
+}
+
+// backendChanges applies the declaration to the
+// backend and returns the names of the changed fields.
+func backendChanges(
+	backend *store.BackendState,
+	decl *BackendDeclaration,
+) []string {
+	fields := []string{}
+	if backend.Backend.Secret != decl.Secret {
+		backend.Backend.Secret = decl.Secret
+		fields = append(fields, "secret")
+	}
+	adminState := decl.AdminState
+	if adminState == "" {
+		adminState = "ready"
+	}
+	if backend.AdminState != adminState {
+		backend.AdminState = adminState
+		fields = append(fields, "admin_state")
+	}
+	loadFactor := decl.LoadFactor
+	if loadFactor == 0 {
+		loadFactor = 1.0
+	}
+	if backend.LoadFactor != loadFactor {
+		backend.LoadFactor = loadFactor
+		fields = append(fields, "load_factor")
+	}
+	if !sameJSON(backend.Settings, decl.Settings) {
+		backend.Settings = decl.Settings
+		fields = append(fields, "settings")
+	}
+	return fields
+}
+
+// frontendChanges applies the declaration to the
+// frontend and returns the names of the changed fields.
+func frontendChanges(
+	frontend *store.FrontendState,
+	decl *FrontendDeclaration,
+) []string {
+	fields := []string{}
+	if frontend.Frontend.Secret != decl.Secret {
+		frontend.Frontend.Secret = decl.Secret
+		fields = append(fields, "secret")
+	}
+	active := true
+	if decl.Active != nil {
+		active = *decl.Active
+	}
+	if frontend.Active != active {
+		frontend.Active = active
+		fields = append(fields, "active")
+	}
+	if !sameJSON(frontend.AccountRef, decl.AccountRef) {
+		frontend.AccountRef = decl.AccountRef
+		fields = append(fields, "account_ref")
+	}
+	if !sameJSON(frontend.Settings, decl.Settings) {
+		frontend.Settings = decl.Settings
+		fields = append(fields, "settings")
+	}
+	return fields
+}
+
+// applyBackends brings the backends into the declared state
+func applyBackends(
+	ctx context.Context,
+	tx pgx.Tx,
+	decls []*BackendDeclaration,
+	prune bool,
+) ([]*PlanChange, error) {
+	backends, err := store.GetBackendStates(ctx, tx, store.Q())
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*store.BackendState, len(backends))
+	for _, b := range backends {
+		existing[b.Backend.Host] = b
+	}
+
+	changes := []*PlanChange{}
+	saved := []*store.BackendState{}
+	declared := make(map[string]bool, len(decls))
+	for _, decl := range decls {
+		host := decl.Host
+		declared[host] = true
+		backend, ok := existing[host]
+		if !ok {
+			backend = store.InitBackendState(&store.BackendState{
+				Backend: &bbb.Backend{Host: host},
+			})
+		}
+		fields := backendChanges(backend, decl)
+		if ok && len(fields) == 0 {
+			continue
+		}
+		if err := backend.Validate(); err != nil {
+			return nil, err
+		}
+		change := &PlanChange{
+			Action:   PlanUpdate,
+			Resource: "backend",
+			Key:      host,
+			Fields:   fields,
+		}
+		if !ok {
+			change.Action = PlanCreate
+			change.Fields = nil
+		}
+		changes = append(changes, change)
+		saved = append(saved, backend)
+	}
+
+	if prune {
+		for host, backend := range existing {
+			if declared[host] || backend.AdminState == "decommissioned" {
+				continue
+			}
+			// Backends are decommissioned, so meetings
+			// can end gracefully.
+			backend.AdminState = "decommissioned"
+			changes = append(changes, &PlanChange{
+				Action:   PlanRemove,
+				Resource: "backend",
+				Key:      host,
+			})
+			saved = append(saved, backend)
+		}
+	}
+
+	for _, backend := range saved {
+		if err := backend.Save(ctx, tx); err != nil {
+			return nil, err
+		}
+		cmd := cluster.UpdateNodeState(&cluster.UpdateNodeStateRequest{
+			ID: backend.ID,
+		})
+		if err := store.QueueCommand(ctx, tx, cmd); err != nil {
+			return nil, err
+		}
+	}
+	return changes, nil
+}
+
+// applyFrontends brings the frontends into the declared state
+func applyFrontends(
+	ctx context.Context,
+	tx pgx.Tx,
+	decls []*FrontendDeclaration,
+	prune bool,
+) ([]*PlanChange, error) {
+	frontends, err := store.GetFrontendStates(ctx, tx, store.Q())
+	if err != nil {
+		return nil, err
+	}
+	existing := make(map[string]*store.FrontendState, len(frontends))
+	for _, f := range frontends {
+		existing[f.Frontend.Key] = f
+	}
+
+	changes := []*PlanChange{}
+	declared := make(map[string]bool, len(decls))
+	for _, decl := range decls {
+		declared[decl.Key] = true
+		frontend, ok := existing[decl.Key]
+		if !ok {
+			frontend = store.InitFrontendState(&store.FrontendState{
+				Frontend: &bbb.Frontend{Key: decl.Key},
+			})
+		}
+		fields := frontendChanges(frontend, decl)
+		if ok && len(fields) == 0 {
+			continue
+		}
+		if err := frontend.Validate(); err != nil {
+			return nil, err
+		}
+		if err := frontend.Save(ctx, tx); err != nil {
+			return nil, err
+		}
+		change := &PlanChange{
+			Action:   PlanUpdate,
+			Resource: "frontend",
+			Key:      decl.Key,
+			Fields:   fields,
+		}
+		if !ok {
+			change.Action = PlanCreate
+			change.Fields = nil
+		}
+		changes = append(changes, change)
+	}
+
+	if prune {
+		for key, frontend := range existing {
+			if declared[key] {
+				continue
+			}
+			if err := frontend.Delete(ctx, tx); err != nil {
+				return nil, err
+			}
+			changes = append(changes, &PlanChange{
+				Action:   PlanRemove,
+				Resource: "frontend",
+				Key:      key,
+			})
+		}
+	}
+	return changes, nil
+}
+
+// sortChanges orders the changes by resource and key,
+// so the plan is stable.
+func sortChanges(changes []*PlanChange) {
+	sort.SliceStable(changes, func(i, j int) bool {
+		if changes[i].Resource != changes[j].Resource {
+			return changes[i].Resource < changes[j].Resource
+		}
+		return changes[i].Key < changes[j].Key
+	})
+}
+
+// planETag identifies a plan by the declaration and the
+// current state of all backends and frontends. If either
+// changes, the plan is different.
+func planETag(
+	ctx context.Context,
+	tx pgx.Tx,
+	decl *ClusterDeclaration,
+) (string, error) {
+	backends, err := store.GetBackendStates(ctx, tx, store.Q())
+	if err != nil {
+		return "", err
+	}
+	frontends, err := store.GetFrontendStates(ctx, tx, store.Q())
+	if err != nil {
+		return "", err
+	}
+	tags := make([]string, 0, len(backends)+len(frontends))
+	for _, b := range backends {
+		tags = append(tags, backendETag(b))
+	}
+	for _, f := range frontends {
+		tags = append(tags, frontendETag(f))
+	}
+	sort.Strings(tags)
+	return makeETag([]interface{}{tags, decl}), nil
+}
+
+// ClusterApply brings the backends and frontends into
+// the declared state within a single transaction.
+// With the query parameter `dry_run` only the plan is
+// returned and nothing is changed.
+// The plan is returned as ETag. When applying with an
+// If-Match header, the changes are only made if they
+// match the plan.
+// ! requires: `admin`
+func ClusterApply(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+	dryRun := config.IsEnabled(c.QueryParam("dry_run"))
+
+	decl := &ClusterDeclaration{}
+	if err := c.Bind(decl); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	plan, err := planETag(reqCtx, tx, decl)
+	if err != nil {
+		return err
+	}
+	ifMatch := c.Request().Header.Get("If-Match")
+	if !dryRun && ifMatch != "" && !matchETag(ifMatch, plan) {
+		return ErrPlanChanged
+	}
+	setETag(c, plan)
+
+	// Kinds missing in the document are not pruned
+	pruneBackends := decl.Prune && decl.Backends != nil
+	pruneFrontends := decl.Prune && decl.Frontends != nil
+
+	backendPlan, err := applyBackends(
+		reqCtx, tx, decl.Backends, pruneBackends)
+	if err != nil {
+		return err
+	}
+	frontendPlan, err := applyFrontends(
+		reqCtx, tx, decl.Frontends, pruneFrontends)
+	if err != nil {
+		return err
+	}
+	changes := append(backendPlan, frontendPlan...)
+	sortChanges(changes)
+
+	if dryRun || len(changes) == 0 {
+		// Nothing is persisted, the transaction
+		// is rolled back.
+		return c.JSON(http.StatusOK, &ApplyResponse{
+			Changes: changes,
+			Applied: false,
+			Plan:    plan,
+		})
+	}
+
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+	log.Info().
+		Int("changes", len(changes)).
+		Str("actor", ctx.AccountRef()).
+		Msg("cluster declaration applied")
+
+	return c.JSON(http.StatusOK, &ApplyResponse{
+		Changes: changes,
+		Applied: true,
+		Plan:    plan,
+	})
+}
+
+// ClusterDump exports the configuration of the cluster.
+// Meetings and recordings are included with the query
+// parameter `meetings`.
+// ! requires: `admin`
+func ClusterDump(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+	withMeetings := config.IsEnabled(c.QueryParam("meetings"))
+
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	dump, err := store.CreateDump(reqCtx, tx, withMeetings)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, dump)
+}
+
+// ClusterRestore imports a dump within a single transaction.
+// ! requires: `admin`
+func ClusterRestore(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	dump := &store.Dump{}
+	if err := c.Bind(dump); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	res, err := store.RestoreDump(reqCtx, tx, dump)
+	if err == store.ErrUnsupportedDumpVersion {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Int("frontends", res.Frontends).
+		Int("backends", res.Backends).
+		Int("meetings", res.Meetings).
+		Str("actor", ctx.AccountRef()).
+		Msg("cluster dump restored")
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBackendChanges(t *testing.T) {
+	backend := store.InitBackendState(&store.BackendState{
+		Backend: &bbb.Backend{
+			Host:   "http://testhost",
+			Secret: "testsec",
+		},
+	})
+	decl := &BackendDeclaration{
+		Host:   "http://testhost",
+		Secret: "testsec",
+	}
+	if fields := backendChanges(backend, decl); len(fields) != 0 {
+		t.Error("unexpected changes:", fields)
+	}
+
+	decl.LoadFactor = 2
+	decl.Settings.Tags = store.Tags{"sip"}
+	fields := backendChanges(backend, decl)
+	if len(fields) != 2 {
+		t.Fatal("unexpected changes:", fields)
+	}
+	if fields[0] != "load_factor" || fields[1] != "settings" {
+		t.Error("unexpected changes:", fields)
+	}
+	if backend.LoadFactor != 2 {
+		t.Error("change was not applied")
+	}
+}
+
+func TestFrontendChanges(t *testing.T) {
+	frontend := store.InitFrontendState(&store.FrontendState{
+		Frontend: &bbb.Frontend{
+			Key:    "frontend1",
+			Secret: "secret",
+		},
+	})
+	decl := &FrontendDeclaration{
+		Key:    "frontend1",
+		Secret: "secret",
+	}
+	if fields := frontendChanges(frontend, decl); len(fields) != 0 {
+		t.Error("unexpected changes:", fields)
+	}
+
+	inactive := false
+	decl.Active = &inactive
+	decl.Secret = "rotated"
+	fields := frontendChanges(frontend, decl)
+	if len(fields) != 2 {
+		t.Fatal("unexpected changes:", fields)
+	}
+	if frontend.Active || frontend.Frontend.Secret != "rotated" {
+		t.Error("changes were not applied")
+	}
+}
+
+func TestSortChanges(t *testing.T) {
+	changes := []*PlanChange{
+		{Resource: "frontend", Key: "b"},
+		{Resource: "backend", Key: "z"},
+		{Resource: "frontend", Key: "a"},
+	}
+	sortChanges(changes)
+	if changes[0].Key != "z" || changes[1].Key != "a" || changes[2].Key != "b" {
+		t.Error("unexpected order")
+	}
+}
+
+func TestClusterApply(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestBackend(); err != nil {
+		t.Fatal(err)
+	}
+
+	apply := func(
+		decl interface{}, dryRun bool, ifMatch string,
+	) (*http.Response, *ApplyResponse) {
+		body, _ := json.Marshal(decl)
+		url := "http:///"
+		if dryRun {
+			url += "?dry_run=true"
+		}
+		req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
+		req.Header.Set("content-type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		ctx, rec := MakeTestContext(req)
+		defer ctx.Release()
+		ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+		if err := ClusterApply(ctx); err != nil {
+			if herr, ok := err.(*echo.HTTPError); ok {
+				rec.Code = herr.Code
+				return rec.Result(), nil
+			}
+			t.Fatal(err)
+		}
+		res := &ApplyResponse{}
+		if err := json.NewDecoder(rec.Body).Decode(res); err != nil {
+			t.Fatal(err)
+		}
+		return rec.Result(), res
+	}
+
+	// The document only declares frontends, the
+	// backend must not be pruned.
+	decl := map[string]interface{}{
+		"frontends": []map[string]interface{}{
+			{"key": "frontend1", "secret": "secret"},
+		},
+		"prune": true,
+	}
+	_, plan := apply(decl, true, "")
+	if len(plan.Changes) != 1 || plan.Changes[0].Resource != "frontend" {
+		t.Fatal("unexpected plan:", plan.Changes)
+	}
+	if plan.Plan == "" {
+		t.Fatal("missing plan tag")
+	}
+
+	// The state changes between plan and apply
+	if _, err := CreateTestFrontend(); err != nil {
+		t.Fatal(err)
+	}
+	res, _ := apply(decl, false, plan.Plan)
+	if res.StatusCode != http.StatusPreconditionFailed {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+
+	// Apply the new plan
+	_, plan = apply(decl, true, "")
+	res, applied := apply(decl, false, plan.Plan)
+	if res.StatusCode != http.StatusOK {
+		t.Fatal("unexpected status code:", res.StatusCode)
+	}
+	if !applied.Applied || len(applied.Changes) != len(plan.Changes) {
+		t.Error("unexpected result:", applied)
+	}
+	for _, change := range applied.Changes {
+		if change.Resource == "backend" {
+			t.Error("backend should not be pruned:", change)
+		}
+	}
+}
@@ -1,11 +1,12 @@
 package v1
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 
+	"github.com/jackc/pgx/v4"
 	"github.com/labstack/echo/v4"
-	"github.com/rs/zerolog/log"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
@@ -40,7 +41,11 @@ func BackendsList(c echo.Context) error {
 	}
 
 	// Set ordering
-	q = q.OrderBy("backends.host ASC")
+	if c.QueryParam("order") == "created" {
+		q = q.OrderBy(store.CreationOrder("backends"))
+	} else {
+		q = q.OrderBy("backends.host ASC")
+	}
 
 	backends, err := store.GetBackendStates(reqCtx, tx, q)
 	return c.JSON(http.StatusOK, backends)
@@ -72,12 +77,36 @@ func BackendCreate(c echo.Context) error {
 	// Begin transaction and save new backend state
 	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("could not start transaction")
 		return err
 	}
 	defer tx.Rollback(reqCtx)
 
-	if err := backend.Save(reqCtx, tx); err != nil {
+	// The host must be unique
+	existing, err := store.GetBackendState(reqCtx, tx, store.Q().
+		Where("host = ?", backend.Backend.Host))
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrBackendExists
+	}
+
+	if err := saveBackend(reqCtx, tx, backend); err != nil {
+		return err
+	}
+
+	setETag(c, backendETag(backend))
+	return c.JSON(http.StatusOK, backend)
+}
+
+// saveBackend persists the backend and requests
+// a refresh of the node state.
+func saveBackend(
+	ctx context.Context,
+	tx pgx.Tx,
+	backend *store.BackendState,
+) error {
+	if err := backend.Save(ctx, tx); err != nil {
 		return err
 	}
 
@@ -85,15 +114,69 @@ func BackendCreate(c echo.Context) error {
 	cmd := cluster.UpdateNodeState(&cluster.UpdateNodeStateRequest{
 		ID: backend.ID,
 	})
-	if err := store.QueueCommand(reqCtx, tx, cmd); err != nil {
+	if err := store.QueueCommand(ctx, tx, cmd); err != nil {
 		return err
 	}
 
-	if err := tx.Commit(reqCtx); err != nil {
+	return tx.Commit(ctx)
+}
+
+// BackendPut will create or replace the backend
+// identified by its host. This allows managing the
+// backends declaratively. Conditional requests with
+// If-Match and If-None-Match are supported.
+// ! requires: `admin`
+func BackendPut(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	b := &store.BackendState{}
+	if err := c.Bind(b); err != nil {
+		return err
+	}
+	desired := store.InitBackendState(&store.BackendState{
+		Backend:    b.Backend,
+		Settings:   b.Settings,
+		AdminState: b.AdminState,
+		LoadFactor: b.LoadFactor,
+	})
+	if err := desired.Validate(); err != nil {
 		return err
 	}
 
-	return c.JSON(http.StatusOK, backend)
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	backend, err := store.GetBackendState(reqCtx, tx, store.Q().
+		Where("host = ?", desired.Backend.Host))
+	if err != nil {
+		return err
+	}
+	if err := checkPreconditions(c, backendETag(backend)); err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if backend == nil {
+		status = http.StatusCreated
+		backend = desired
+	} else {
+		backend.Backend = desired.Backend
+		backend.Settings = desired.Settings
+		backend.AdminState = desired.AdminState
+		backend.LoadFactor = desired.LoadFactor
+	}
+
+	if err := saveBackend(reqCtx, tx, backend); err != nil {
+		return err
+	}
+
+	setETag(c, backendETag(backend))
+	return c.JSON(status, backend)
 }
 
 // BackendRetrieve will retrieve a single backend by ID.
@@ -107,7 +190,6 @@ func BackendRetrieve(c echo.Context) error {
 	// Begin TX
 	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("could not start transaction")
 		return err
 	}
 	defer tx.Rollback(reqCtx)
@@ -116,14 +198,21 @@ func BackendRetrieve(c echo.Context) error {
 	q := store.Q().Where("id = ?", id)
 	backend, err := store.GetBackendState(reqCtx, tx, q)
 
+	if err != nil {
+		return err
+	}
 	if backend == nil {
 		return echo.ErrNotFound
 	}
 
+	setETag(c, backendETag(backend))
 	return c.JSON(http.StatusOK, backend)
 }
 
 // BackendDestroy will start a backend decommissioning.
+// With `hard`, the backend is deleted right away unless
+// meetings are running. With `force`, the backend is
+// deleted in any case.
 // ! requires: `admin`
 func BackendDestroy(c echo.Context) error {
 	ctx := c.(*APIContext)
@@ -132,11 +221,11 @@ func BackendDestroy(c echo.Context) error {
 	id := c.Param("id")
 
 	force := config.IsEnabled(c.QueryParam("force"))
+	hard := config.IsEnabled(c.QueryParam("hard"))
 
 	// Begin TX
 	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("could not start transaction")
 		return err
 	}
 	defer tx.Rollback(reqCtx)
@@ -144,13 +233,27 @@ func BackendDestroy(c echo.Context) error {
 	// Begin Query
 	q := store.Q().Where("id = ?", id)
 	backend, err := store.GetBackendState(reqCtx, tx, q)
+	if err != nil {
+		return err
+	}
 	if backend == nil {
 		return echo.ErrNotFound
 	}
+	if err := checkPreconditions(c, backendETag(backend)); err != nil {
+		return err
+	}
 
 	if force {
 		// force removal of backend. this is a hard delete
 		// without decommissioning.
+		if err := backend.ForceDelete(reqCtx, tx); err != nil {
+			return err
+		}
+		backend.AdminState = "destroyed"
+
+	} else if hard {
+		// remove the backend without decommissioning,
+		// if no meetings are running.
 		if err := backend.Delete(reqCtx, tx); err != nil {
 			return err
 		}
@@ -184,7 +287,6 @@ func BackendUpdate(c echo.Context) error {
 	// Begin TX
 	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
 	if err != nil {
-		log.Fatal().Err(err).Msg("could not start transaction")
 		return err
 	}
 	defer tx.Rollback(reqCtx)
@@ -203,6 +305,9 @@ func BackendUpdate(c echo.Context) error {
 	if err != nil {
 		return err
 	}
+	if err := checkPreconditions(c, backendETag(backend)); err != nil {
+		return err
+	}
 
 	// Update backend
 	if err := c.Bind(update); err != nil {
@@ -220,21 +325,52 @@ func BackendUpdate(c echo.Context) error {
 	}
 
 	// Persist updated backend
-	if err := backend.Save(reqCtx, tx); err != nil {
+	if err := saveBackend(reqCtx, tx, backend); err != nil {
 		return err
 	}
 
-	// Enqueue node refresh command
-	cmd := cluster.UpdateNodeState(&cluster.UpdateNodeStateRequest{
-		ID: backend.ID,
-	})
-	if err := store.QueueCommand(reqCtx, tx, cmd); err != nil {
+	setETag(c, backendETag(backend))
+	return c.JSON(http.StatusOK, backend)
+}
+
+// BackendBroadcast posts a message to the chat of all
+// running meetings of the backend, e.g. to announce
+// a maintenance before draining the backend.
+// ! requires: `admin`
+func BackendBroadcast(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	req := &cluster.BackendBroadcastRequest{}
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	if err := req.Validate(); err != nil {
 		return err
 	}
 
-	if err := tx.Commit(reqCtx); err != nil {
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
 		return err
 	}
+	defer tx.Rollback(reqCtx)
 
-	return c.JSON(http.StatusOK, backend)
+	backend, err := store.GetBackendState(reqCtx, tx, store.Q().
+		Where("id = ?", c.Param("id")))
+	if err != nil {
+		return err
+	}
+	if backend == nil {
+		return echo.ErrNotFound
+	}
+
+	cmds, err := cluster.QueueBackendBroadcast(reqCtx, tx, backend, req)
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, cmds)
 }
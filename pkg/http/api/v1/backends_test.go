@@ -5,8 +5,11 @@ import (
 	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
+	"github.com/labstack/echo/v4"
+
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
@@ -97,6 +100,68 @@ func TestBackendCreate(t *testing.T) {
 	t.Log("create:", string(resBody))
 }
 
+func TestBackendPut(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+
+	put := func(loadFactor float64, ifMatch string) *http.Response {
+		body, _ := json.Marshal(map[string]interface{}{
+			"bbb": map[string]interface{}{
+				"host":   "http://testhost",
+				"secret": "testsec",
+			},
+			"load_factor": loadFactor,
+		})
+		req, _ := http.NewRequest("PUT", "http:///", bytes.NewBuffer(body))
+		req.Header.Set("content-type", "application/json")
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		ctx, rec := MakeTestContext(req)
+		defer ctx.Release()
+		ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+		if err := BackendPut(ctx); err != nil {
+			if herr, ok := err.(*echo.HTTPError); ok {
+				rec.Code = herr.Code
+				return rec.Result()
+			}
+			t.Fatal(err)
+		}
+		return rec.Result()
+	}
+
+	res := put(1.0, "")
+	if res.StatusCode != http.StatusCreated {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	etag := res.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("missing etag")
+	}
+
+	// Applying the same state is idempotent
+	res = put(1.0, etag)
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	if res.Header.Get("ETag") != etag {
+		t.Error("etag changed without a change")
+	}
+
+	// Change the load factor
+	res = put(2.0, etag)
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+
+	// The etag is now outdated
+	res = put(3.0, etag)
+	if res.StatusCode != http.StatusPreconditionFailed {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+}
+
 func TestBackendUpdate(t *testing.T) {
 	if err := ClearState(); err != nil {
 		t.Fatal(err)
@@ -248,3 +313,73 @@ func TestBackendRetrieve(t *testing.T) {
 	resBody, _ := ioutil.ReadAll(res.Body)
 	t.Log("retrieve:", string(resBody))
 }
+
+func TestBackendBroadcast(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestMeeting(backend); err != nil {
+		t.Fatal(err)
+	}
+
+	broadcast := func() (*httptest.ResponseRecorder, error) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"message": "This server goes into maintenance in 10 minutes.",
+		})
+		req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+		req.Header.Set("content-type", "application/json")
+
+		ctx, rec := MakeTestContext(req)
+		defer ctx.Release()
+		ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+		ctx.Context.SetParamNames("id")
+		ctx.Context.SetParamValues(backend.ID)
+		return rec, BackendBroadcast(ctx)
+	}
+
+	// The version of the test backend is unknown,
+	// so the message can not be posted.
+	if _, err := broadcast(); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	} else if _, ok := err.(store.ValidationError); !ok {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ctx, _ := MakeTestContext(nil)
+	defer ctx.Release()
+	cctx := ctx.Ctx()
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Version = "2.7.3"
+	if err := backend.Save(cctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(cctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := broadcast()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusAccepted {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	cmds := []*store.Command{}
+	if err := readJSONResponse(res, &cmds); err != nil {
+		t.Fatal(err)
+	}
+	if len(cmds) != 1 {
+		t.Fatal("unexpected commands:", cmds)
+	}
+	if cmds[0].Handler != nil {
+		t.Error("unexpected handler:", *cmds[0].Handler)
+	}
+}
@@ -0,0 +1,150 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// BansList retrieves the bans, optionally
+// filtered by frontend_id.
+// ! requires: `admin`
+func BansList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	q := store.Q().OrderBy(store.CreationOrder("bans"))
+	if id := c.QueryParam("frontend_id"); id != "" {
+		q = q.Where("frontend_id = ?", id)
+	}
+	bans, err := store.GetBans(cctx, tx, q)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, bans)
+}
+
+// BanCreate adds a ban. The ban is enforced by the
+// gateway within a few seconds.
+// ! requires: `admin`
+func BanCreate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	ban := &store.Ban{}
+	if err := c.Bind(ban); err != nil {
+		return err
+	}
+	if err := ban.Validate(); err != nil {
+		return err
+	}
+	ban.CreatedBy = ctx.AccountRef()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	resourceType, resourceID := "cluster", ""
+	if ban.FrontendID != nil {
+		frontend, err := store.GetFrontendState(cctx, tx, store.Q().
+			Where("id = ?", *ban.FrontendID))
+		if err != nil {
+			return err
+		}
+		if frontend == nil {
+			verr := store.ValidationError{}
+			verr.Add("frontend_id", "frontend not found")
+			return verr
+		}
+		resourceType, resourceID = "frontend", frontend.ID
+	}
+
+	if err := ban.Save(cctx, tx); err != nil {
+		return err
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditBanCreated,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Details: map[string]interface{}{
+			"ban_id":  ban.ID,
+			"kind":    ban.Kind,
+			"pattern": ban.Pattern,
+			"reason":  ban.Reason,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("ban", ban.ID).
+		Str("kind", ban.Kind).
+		Str("actor", ctx.AccountRef()).
+		Msg("ban added")
+
+	return c.JSON(http.StatusOK, ban)
+}
+
+// BanDestroy removes a ban
+// ! requires: `admin`
+func BanDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	ban, err := store.GetBan(cctx, tx, store.Q().
+		Where("id = ?", c.Param("id")))
+	if err != nil {
+		return err
+	}
+	if ban == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteBan(cctx, tx, ban.ID); err != nil {
+		return err
+	}
+
+	resourceType, resourceID := "cluster", ""
+	if ban.FrontendID != nil {
+		resourceType, resourceID = "frontend", *ban.FrontendID
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditBanDeleted,
+		ResourceType: resourceType,
+		ResourceID:   resourceID,
+		Details: map[string]interface{}{
+			"ban_id":  ban.ID,
+			"kind":    ban.Kind,
+			"pattern": ban.Pattern,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, ban)
+}
@@ -0,0 +1,61 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBanCreate(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"frontend_id": f.ID,
+		"kind":        store.BanFullName,
+		"pattern":     "^spam",
+		"reason":      "spam bot",
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "support1", []string{ScopeAdmin})
+
+	if err := BanCreate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	ban := &store.Ban{}
+	if err := readJSONResponse(res, ban); err != nil {
+		t.Fatal(err)
+	}
+	if ban.ID == "" || ban.CreatedBy != "support1" {
+		t.Error("unexpected ban:", ban)
+	}
+
+	// The pattern must be valid
+	body, _ = json.Marshal(map[string]interface{}{
+		"kind":    store.BanFullName,
+		"pattern": "(spam",
+	})
+	req, _ = http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, _ = MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "support1", []string{ScopeAdmin})
+	if _, ok := BanCreate(ctx).(store.ValidationError); !ok {
+		t.Error("expected a validation error")
+	}
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/billing"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// BillingReport generates the billing report of a
+// period in a format. The period defaults to the
+// previous day.
+// ! requires: `admin`
+func BillingReport(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	now := time.Now().UTC()
+	yesterday := time.Date(
+		now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).
+		AddDate(0, 0, -1)
+
+	from, until := yesterday, yesterday
+	if d := c.QueryParam("from"); d != "" {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid from")
+		}
+		from, until = t, t
+	}
+	if d := c.QueryParam("until"); d != "" {
+		t, err := time.Parse("2006-01-02", d)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid until")
+		}
+		until = t
+	}
+	if until.Before(from) {
+		return echo.NewHTTPError(
+			http.StatusBadRequest, "until is before from")
+	}
+
+	name := c.QueryParam("format")
+	if name == "" {
+		name = "json"
+	}
+	format, err := billing.GetFormat(name)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	report, err := billing.BuildReport(reqCtx, tx, from, until)
+	if err != nil {
+		return err
+	}
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, format.ContentType())
+	res.WriteHeader(http.StatusOK)
+	return format.Encode(res, report)
+}
@@ -11,6 +11,7 @@ import (
 	"path"
 	"strings"
 
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
@@ -33,6 +34,40 @@ type Client interface {
 	FrontendDelete(
 		ctx context.Context, frontend *store.FrontendState,
 	) (*store.FrontendState, error)
+	FrontendRegenerateSecret(
+		ctx context.Context, frontend *store.FrontendState,
+		query url.Values,
+	) (*FrontendSecretResponse, error)
+	FrontendKeysList(
+		ctx context.Context, frontend *store.FrontendState,
+	) ([]*store.FrontendKey, error)
+	FrontendKeyCreate(
+		ctx context.Context, frontend *store.FrontendState,
+		key *store.FrontendKey,
+	) (*store.FrontendKey, error)
+	FrontendKeyDelete(
+		ctx context.Context, frontend *store.FrontendState,
+		keyID string,
+	) (*store.FrontendKey, error)
+	FrontendRoomsList(
+		ctx context.Context, frontend *store.FrontendState,
+	) ([]*store.Room, error)
+	FrontendRoomCreate(
+		ctx context.Context,
+		frontend *store.FrontendState,
+		room *store.Room,
+	) (*store.Room, error)
+	FrontendRoomDelete(
+		ctx context.Context,
+		frontend *store.FrontendState,
+		roomID string,
+	) (*store.Room, error)
+	FrontendRoomScheduleUpdate(
+		ctx context.Context,
+		frontend *store.FrontendState,
+		roomID string,
+		schedule *RoomScheduleRequest,
+	) (*store.Room, error)
 
 	BackendsList(
 		ctx context.Context, query url.Values,
@@ -61,6 +96,94 @@ type Client interface {
 		ctx context.Context,
 		backendID string,
 	) (*store.Command, error)
+	MeetingsEnd(
+		ctx context.Context, req *cluster.EndMeetingsRequest,
+	) (*cluster.EndMeetingsResponse, error)
+	MeetingCreate(
+		ctx context.Context, req *CreateMeetingRequest,
+	) (*CreateMeetingResponse, error)
+	MeetingsReconcile(
+		ctx context.Context, query url.Values,
+	) ([]*cluster.MeetingsReconciliation, error)
+	MeetingPinsList(
+		ctx context.Context, query url.Values,
+	) ([]*store.MeetingPin, error)
+	MeetingPinCreate(
+		ctx context.Context, pin *store.MeetingPin,
+	) (*store.MeetingPin, error)
+	MeetingPinDelete(
+		ctx context.Context, id string,
+	) (*store.MeetingPin, error)
+
+	BansList(
+		ctx context.Context, query url.Values,
+	) ([]*store.Ban, error)
+	BanCreate(
+		ctx context.Context, ban *store.Ban,
+	) (*store.Ban, error)
+	BanDelete(
+		ctx context.Context, id string,
+	) (*store.Ban, error)
+
+	AgentsList(
+		ctx context.Context,
+	) ([]*AgentStatus, error)
+	AgentReleaseRetrieve(
+		ctx context.Context,
+	) (*store.AgentRelease, error)
+	AgentReleaseSet(
+		ctx context.Context, release *store.AgentRelease,
+	) (*store.AgentRelease, error)
+	AgentReleaseDelete(
+		ctx context.Context,
+	) (*store.AgentRelease, error)
+
+	RecordingsReconcile(
+		ctx context.Context, query url.Values, importMissing bool,
+	) ([]*cluster.RecordingsReconciliation, error)
+	ClusterRecover(
+		ctx context.Context, query url.Values,
+	) ([]*cluster.Recovery, error)
+
+	BackendBroadcast(
+		ctx context.Context, backendID string,
+		req *cluster.BackendBroadcastRequest,
+	) ([]*store.Command, error)
+
+	BackendDiagnosticsList(
+		ctx context.Context, backendID string,
+	) ([]*store.BackendDiagnostics, error)
+	BackendDiagnosticsRetrieve(
+		ctx context.Context, backendID, id string,
+	) ([]byte, error)
+
+	CommandCreate(
+		ctx context.Context, req *cluster.CommandRequest,
+	) (*store.Command, error)
+	CommandRetrieve(
+		ctx context.Context, id string,
+	) (*store.Command, error)
+	CommandWait(
+		ctx context.Context, id string,
+	) (*store.Command, error)
+
+	LoggingUpdate(
+		ctx context.Context, opts *LoggingOptions,
+	) (*LoggingOptions, error)
+
+	ClusterApply(
+		ctx context.Context, decl *ClusterDeclaration,
+		dryRun bool, plan string,
+	) (*ApplyResponse, error)
+	ClusterDump(
+		ctx context.Context, query url.Values,
+	) (*store.Dump, error)
+	ClusterRestore(
+		ctx context.Context, dump *store.Dump,
+	) (*store.RestoreResult, error)
+	ClusterTopology(
+		ctx context.Context, query url.Values,
+	) (*store.ClusterTopology, error)
 }
 
 // JSON helper
@@ -289,6 +412,212 @@ func (c *JWTClient) FrontendDelete(
 	return frontend, err
 }
 
+// FrontendRegenerateSecret requests a new secret for
+// the frontend. The response contains the new secret.
+func (c *JWTClient) FrontendRegenerateSecret(
+	ctx context.Context, frontend *store.FrontendState, query url.Values,
+) (*FrontendSecretResponse, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("frontends/"+frontend.ID+"/secret", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	secret := &FrontendSecretResponse{}
+	err = readJSONResponse(res, secret)
+	return secret, err
+}
+
+// FrontendKeysList retrieves the additional
+// keys of the frontend.
+func (c *JWTClient) FrontendKeysList(
+	ctx context.Context, frontend *store.FrontendState,
+) ([]*store.FrontendKey, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("frontends/"+frontend.ID+"/keys", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	keys := []*store.FrontendKey{}
+	err = readJSONResponse(res, &keys)
+	return keys, err
+}
+
+// FrontendKeyCreate adds a key to the frontend. The
+// secret is generated if not provided.
+func (c *JWTClient) FrontendKeyCreate(
+	ctx context.Context,
+	frontend *store.FrontendState,
+	key *store.FrontendKey,
+) (*store.FrontendKey, error) {
+	payload, err := json.Marshal(key)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("frontends/"+frontend.ID+"/keys", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	key = &store.FrontendKey{}
+	err = readJSONResponse(res, key)
+	return key, err
+}
+
+// FrontendKeyDelete removes an additional key
+// of the frontend.
+func (c *JWTClient) FrontendKeyDelete(
+	ctx context.Context,
+	frontend *store.FrontendState,
+	keyID string,
+) (*store.FrontendKey, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "DELETE",
+		c.apiURL("frontends/"+frontend.ID+"/keys/"+keyID, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	key := &store.FrontendKey{}
+	err = readJSONResponse(res, key)
+	return key, err
+}
+
+// FrontendRoomsList retrieves the rooms of the frontend
+func (c *JWTClient) FrontendRoomsList(
+	ctx context.Context, frontend *store.FrontendState,
+) ([]*store.Room, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("frontends/"+frontend.ID+"/rooms", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	rooms := []*store.Room{}
+	err = readJSONResponse(res, &rooms)
+	return rooms, err
+}
+
+// FrontendRoomCreate adds a room to the frontend. The
+// passwords are generated if not provided.
+func (c *JWTClient) FrontendRoomCreate(
+	ctx context.Context,
+	frontend *store.FrontendState,
+	room *store.Room,
+) (*store.Room, error) {
+	payload, err := json.Marshal(room)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("frontends/"+frontend.ID+"/rooms", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	room = &store.Room{}
+	err = readJSONResponse(res, room)
+	return room, err
+}
+
+// FrontendRoomDelete removes a room of the frontend
+func (c *JWTClient) FrontendRoomDelete(
+	ctx context.Context,
+	frontend *store.FrontendState,
+	roomID string,
+) (*store.Room, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "DELETE",
+		c.apiURL("frontends/"+frontend.ID+"/rooms/"+roomID, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	room := &store.Room{}
+	err = readJSONResponse(res, room)
+	return room, err
+}
+
+// FrontendRoomScheduleUpdate sets the schedule of a room
+func (c *JWTClient) FrontendRoomScheduleUpdate(
+	ctx context.Context,
+	frontend *store.FrontendState,
+	roomID string,
+	schedule *RoomScheduleRequest,
+) (*store.Room, error) {
+	payload, err := json.Marshal(schedule)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "PUT",
+		c.apiURL("frontends/"+frontend.ID+"/rooms/"+roomID+"/schedule", nil),
+		body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	room := &store.Room{}
+	err = readJSONResponse(res, room)
+	return room, err
+}
+
 // BackendsList retrievs a list of backends from the server
 func (c *JWTClient) BackendsList(
 	ctx context.Context, query url.Values,
@@ -460,3 +789,650 @@ func (c *JWTClient) BackendMeetingsEnd(
 	err = readJSONResponse(res, cmd)
 	return cmd, err
 }
+
+// MeetingsEnd gracefully ends the meetings of a
+// backend or of the whole cluster.
+func (c *JWTClient) MeetingsEnd(
+	ctx context.Context, endReq *cluster.EndMeetingsRequest,
+) (*cluster.EndMeetingsResponse, error) {
+	payload, err := json.Marshal(endReq)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("meetings/end", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	endRes := &cluster.EndMeetingsResponse{}
+	err = readJSONResponse(res, endRes)
+	return endRes, err
+}
+
+// MeetingCreate creates a meeting for a frontend and
+// retrieves the join URLs.
+func (c *JWTClient) MeetingCreate(
+	ctx context.Context, createReq *CreateMeetingRequest,
+) (*CreateMeetingResponse, error) {
+	payload, err := json.Marshal(createReq)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("meetings", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	createRes := &CreateMeetingResponse{}
+	err = readJSONResponse(res, createRes)
+	return createRes, err
+}
+
+// MeetingPinsList retrieves the meeting pins
+func (c *JWTClient) MeetingPinsList(
+	ctx context.Context, query url.Values,
+) ([]*store.MeetingPin, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("pins", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	pins := []*store.MeetingPin{}
+	err = readJSONResponse(res, &pins)
+	return pins, err
+}
+
+// MeetingPinCreate pins a meeting of a frontend to a backend
+func (c *JWTClient) MeetingPinCreate(
+	ctx context.Context, pin *store.MeetingPin,
+) (*store.MeetingPin, error) {
+	payload, err := json.Marshal(pin)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("pins", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	pin = &store.MeetingPin{}
+	err = readJSONResponse(res, pin)
+	return pin, err
+}
+
+// MeetingPinDelete removes a meeting pin
+func (c *JWTClient) MeetingPinDelete(
+	ctx context.Context, id string,
+) (*store.MeetingPin, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "DELETE", c.apiURL("pins/"+id, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	pin := &store.MeetingPin{}
+	err = readJSONResponse(res, pin)
+	return pin, err
+}
+
+// BansList retrieves the bans
+func (c *JWTClient) BansList(
+	ctx context.Context, query url.Values,
+) ([]*store.Ban, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("bans", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	bans := []*store.Ban{}
+	err = readJSONResponse(res, &bans)
+	return bans, err
+}
+
+// BanCreate adds a ban
+func (c *JWTClient) BanCreate(
+	ctx context.Context, ban *store.Ban,
+) (*store.Ban, error) {
+	payload, err := json.Marshal(ban)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("bans", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	ban = &store.Ban{}
+	err = readJSONResponse(res, ban)
+	return ban, err
+}
+
+// BanDelete removes a ban
+func (c *JWTClient) BanDelete(
+	ctx context.Context, id string,
+) (*store.Ban, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "DELETE", c.apiURL("bans/"+id, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	ban := &store.Ban{}
+	err = readJSONResponse(res, ban)
+	return ban, err
+}
+
+// AgentsList retrieves the versions of the node agents
+func (c *JWTClient) AgentsList(
+	ctx context.Context,
+) ([]*AgentStatus, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("agents", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	agents := []*AgentStatus{}
+	err = readJSONResponse(res, &agents)
+	return agents, err
+}
+
+// AgentReleaseRetrieve gets the desired agent release
+func (c *JWTClient) AgentReleaseRetrieve(
+	ctx context.Context,
+) (*store.AgentRelease, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("agents/release", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	release := &store.AgentRelease{}
+	err = readJSONResponse(res, release)
+	return release, err
+}
+
+// AgentReleaseSet sets the desired agent release
+func (c *JWTClient) AgentReleaseSet(
+	ctx context.Context, release *store.AgentRelease,
+) (*store.AgentRelease, error) {
+	payload, err := json.Marshal(release)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "PUT", c.apiURL("agents/release", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	release = &store.AgentRelease{}
+	err = readJSONResponse(res, release)
+	return release, err
+}
+
+// AgentReleaseDelete removes the desired agent release
+func (c *JWTClient) AgentReleaseDelete(
+	ctx context.Context,
+) (*store.AgentRelease, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "DELETE", c.apiURL("agents/release", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	release := &store.AgentRelease{}
+	err = readJSONResponse(res, release)
+	return release, err
+}
+
+// MeetingsReconcile compares the meetings in the store
+// with the live meetings of the backends.
+func (c *JWTClient) MeetingsReconcile(
+	ctx context.Context, query url.Values,
+) ([]*cluster.MeetingsReconciliation, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("meetings/reconcile", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	reports := []*cluster.MeetingsReconciliation{}
+	err = readJSONResponse(res, &reports)
+	return reports, err
+}
+
+// RecordingsReconcile compares the recordings in the store
+// with the recordings of the backends. Missing recordings
+// are imported into the store if requested.
+func (c *JWTClient) RecordingsReconcile(
+	ctx context.Context, query url.Values, importMissing bool,
+) ([]*cluster.RecordingsReconciliation, error) {
+	method := "GET"
+	if importMissing {
+		method = "POST"
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, method, c.apiURL("recordings/reconcile", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	reports := []*cluster.RecordingsReconciliation{}
+	err = readJSONResponse(res, &reports)
+	return reports, err
+}
+
+// ClusterRecover rebuilds the meetings and recordings
+// in the store from the backends.
+func (c *JWTClient) ClusterRecover(
+	ctx context.Context, query url.Values,
+) ([]*cluster.Recovery, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("cluster/recover", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	reports := []*cluster.Recovery{}
+	err = readJSONResponse(res, &reports)
+	return reports, err
+}
+
+// BackendBroadcast posts a message to the running
+// meetings of a backend.
+func (c *JWTClient) BackendBroadcast(
+	ctx context.Context,
+	backendID string,
+	broadcastReq *cluster.BackendBroadcastRequest,
+) ([]*store.Command, error) {
+	payload, err := json.Marshal(broadcastReq)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("backends/"+backendID+"/broadcast", nil), body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	cmds := []*store.Command{}
+	err = readJSONResponse(res, &cmds)
+	return cmds, err
+}
+
+// BackendDiagnosticsList retrieves the diagnostic
+// bundles of a backend without the content.
+func (c *JWTClient) BackendDiagnosticsList(
+	ctx context.Context, backendID string,
+) ([]*store.BackendDiagnostics, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("backends/"+backendID+"/diagnostics", nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	bundles := []*store.BackendDiagnostics{}
+	err = readJSONResponse(res, &bundles)
+	return bundles, err
+}
+
+// BackendDiagnosticsRetrieve downloads a diagnostic
+// bundle. The bundle is a compressed tar archive.
+func (c *JWTClient) BackendDiagnosticsRetrieve(
+	ctx context.Context, backendID, id string,
+) ([]byte, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET",
+		c.apiURL("backends/"+backendID+"/diagnostics/"+id, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	defer res.Body.Close()
+	return io.ReadAll(res.Body)
+}
+
+// CommandCreate queues a well known command
+func (c *JWTClient) CommandCreate(
+	ctx context.Context, cmdReq *cluster.CommandRequest,
+) (*store.Command, error) {
+	payload, err := json.Marshal(cmdReq)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("commands", nil),
+		bytes.NewBuffer(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	cmd := &store.Command{}
+	err = readJSONResponse(res, cmd)
+	return cmd, err
+}
+
+// CommandRetrieve gets a command with its
+// state and result.
+func (c *JWTClient) CommandRetrieve(
+	ctx context.Context, id string,
+) (*store.Command, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("commands/"+id, nil), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	cmd := &store.Command{}
+	err = readJSONResponse(res, cmd)
+	return cmd, err
+}
+
+// CommandWait waits until the command is finished.
+// The request is repeated while the command is pending,
+// until the context is done.
+func (c *JWTClient) CommandWait(
+	ctx context.Context, id string,
+) (*store.Command, error) {
+	for {
+		req, err := http.NewRequestWithContext(
+			ctx, "GET", c.apiURL("commands/"+id+"/wait", nil), nil)
+		if err != nil {
+			return nil, err
+		}
+		res, err := c.Client.Do(c.AuthorizeRequest(req))
+		if err != nil {
+			return nil, err
+		}
+		if !httpSuccess(res) {
+			return nil, APIErrorFromResponse(res)
+		}
+		cmd := &store.Command{}
+		if err := readJSONResponse(res, cmd); err != nil {
+			return nil, err
+		}
+		if cmd.Finished() {
+			return cmd, nil
+		}
+	}
+}
+
+// LoggingUpdate changes the log level and format
+// of the server.
+func (c *JWTClient) LoggingUpdate(
+	ctx context.Context, opts *LoggingOptions,
+) (*LoggingOptions, error) {
+	payload, err := json.Marshal(opts)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "PATCH", c.apiURL("logging", nil), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	opts = &LoggingOptions{}
+	err = readJSONResponse(res, opts)
+	return opts, err
+}
+
+// ClusterApply sends the cluster declaration. With dryRun
+// only the plan is returned. When a plan is given, the
+// declaration is only applied if it still matches.
+func (c *JWTClient) ClusterApply(
+	ctx context.Context, decl *ClusterDeclaration,
+	dryRun bool, plan string,
+) (*ApplyResponse, error) {
+	payload, err := json.Marshal(decl)
+	if err != nil {
+		return nil, err
+	}
+	query := url.Values{}
+	if dryRun {
+		query.Set("dry_run", "true")
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("cluster/apply", query), body)
+	if err != nil {
+		return nil, err
+	}
+	if plan != "" {
+		req.Header.Set("If-Match", plan)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	result := &ApplyResponse{}
+	err = readJSONResponse(res, result)
+	return result, err
+}
+
+// ClusterDump exports the cluster configuration
+func (c *JWTClient) ClusterDump(
+	ctx context.Context, query url.Values,
+) (*store.Dump, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("cluster/dump", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	dump := &store.Dump{}
+	err = readJSONResponse(res, dump)
+	return dump, err
+}
+
+// ClusterRestore imports a dump of the cluster configuration
+func (c *JWTClient) ClusterRestore(
+	ctx context.Context, dump *store.Dump,
+) (*store.RestoreResult, error) {
+	payload, err := json.Marshal(dump)
+	if err != nil {
+		return nil, err
+	}
+	body := bytes.NewBuffer(payload)
+	req, err := http.NewRequestWithContext(
+		ctx, "POST", c.apiURL("cluster/restore", nil), body)
+	if err != nil {
+		return nil, err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	result := &store.RestoreResult{}
+	err = readJSONResponse(res, result)
+	return result, err
+}
+
+// ClusterTopology retrieves the backends with their meetings
+func (c *JWTClient) ClusterTopology(
+	ctx context.Context, query url.Values,
+) (*store.ClusterTopology, error) {
+	req, err := http.NewRequestWithContext(
+		ctx, "GET", c.apiURL("cluster/topology", query), nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := c.Client.Do(c.AuthorizeRequest(req))
+	if err != nil {
+		return nil, err
+	}
+	if !httpSuccess(res) {
+		return nil, APIErrorFromResponse(res)
+	}
+	topology := &store.ClusterTopology{}
+	err = readJSONResponse(res, topology)
+	return topology, err
+}
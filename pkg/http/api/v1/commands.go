@@ -0,0 +1,188 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// commandsListLimit is the default number of
+// commands returned.
+const commandsListLimit = 100
+
+// Waiting for a command is limited to stay
+// within the request timeout.
+const (
+	commandWaitDefault = 30 * time.Second
+	commandWaitMax     = 50 * time.Second
+)
+
+// CommandsList retrieves the most recent commands
+// in the queue. The commands can be filtered by state.
+// ! requires: `admin`
+func CommandsList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	limit := uint64(commandsListLimit)
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.ParseUint(l, 10, 32)
+		if err != nil {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = n
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	q := store.Q().
+		OrderBy("seq DESC").
+		Limit(limit)
+	if state := c.QueryParam("state"); state != "" {
+		q = q.Where("state = ?", state)
+	}
+
+	commands, err := store.GetCommands(reqCtx, tx, q)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, commands)
+}
+
+// CommandSchemasList describes the params and
+// results of the commands.
+// ! requires: `admin`
+func CommandSchemasList(c echo.Context) error {
+	return c.JSON(http.StatusOK, cluster.CommandSchemas())
+}
+
+// CommandCreate queues a well known command. The
+// command is accepted and can be polled by ID.
+// ! requires: `admin`
+func CommandCreate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	req := &cluster.CommandRequest{}
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	cmd, err := req.Command()
+	if err != nil {
+		return err
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	// The backend or meeting must exist
+	if req.BackendID != "" {
+		backend, err := store.GetBackendState(reqCtx, tx, store.Q().
+			Where("id = ?", req.BackendID))
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return store.ValidationError{
+				"backend_id": []string{"no such backend"},
+			}
+		}
+	}
+	if req.MeetingID != "" {
+		meeting, err := store.GetMeetingState(reqCtx, tx, store.Q().
+			Where("id = ?", req.MeetingID))
+		if err != nil {
+			return err
+		}
+		if meeting == nil {
+			return store.ValidationError{
+				"meeting_id": []string{"no such meeting"},
+			}
+		}
+	}
+
+	if err := store.QueueCommand(reqCtx, tx, cmd); err != nil {
+		return err
+	}
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, cmd)
+}
+
+// CommandRetrieve gets a command with its state
+// and result.
+// ! requires: `admin`
+func CommandRetrieve(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	cmd, err := store.GetCommand(reqCtx, tx, store.Q().
+		Where("id = ?", c.Param("id")))
+	if err != nil {
+		return err
+	}
+	if cmd == nil {
+		return echo.ErrNotFound
+	}
+	return c.JSON(http.StatusOK, cmd)
+}
+
+// CommandWait waits until the command is finished and
+// returns it with the result. If the command is still
+// pending after the timeout, the response status
+// is 202 and the request can be repeated.
+// ! requires: `admin`
+func CommandWait(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	timeout := commandWaitDefault
+	if t := c.QueryParam("timeout"); t != "" {
+		d, err := time.ParseDuration(t)
+		if err != nil || d < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid timeout")
+		}
+		timeout = d
+	}
+	if timeout > commandWaitMax {
+		timeout = commandWaitMax
+	}
+
+	// Waiting uses its own connection for listening,
+	// so the connection of the request is released.
+	ctx.Release()
+
+	cmd, err := store.AwaitCommand(reqCtx, c.Param("id"), timeout)
+	if err != nil {
+		return err
+	}
+	if cmd == nil {
+		return echo.ErrNotFound
+	}
+	if !cmd.Finished() {
+		return c.JSON(http.StatusAccepted, cmd)
+	}
+	return c.JSON(http.StatusOK, cmd)
+}
@@ -0,0 +1,85 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestCommandCreateAndRetrieve(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	b, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"action":     cluster.CmdSyncBackendMeetings,
+		"backend_id": b.ID,
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	if err := CommandCreate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusAccepted {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	cmd := &store.Command{}
+	if err := readJSONResponse(res, cmd); err != nil {
+		t.Fatal(err)
+	}
+
+	// Poll the command
+	ctx, rec = MakeTestContext(nil)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(cmd.ID)
+	if err := CommandRetrieve(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res = rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	polled := &store.Command{}
+	if err := readJSONResponse(res, polled); err != nil {
+		t.Fatal(err)
+	}
+	if polled.Action != cluster.CmdSyncBackendMeetings {
+		t.Error("unexpected action:", polled.Action)
+	}
+}
+
+func TestCommandCreateUnknownBackend(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"action":     cluster.CmdEndAllMeetings,
+		"backend_id": "3a4d2d6c-4d1e-4a8b-9f3e-0b1c2d3e4f50",
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	err := CommandCreate(ctx)
+	if _, ok := err.(store.ValidationError); !ok {
+		t.Error("expected a validation error, got:", err)
+	}
+}
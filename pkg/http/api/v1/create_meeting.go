@@ -0,0 +1,222 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb/client"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// CreateMeetingRequest creates a meeting for a frontend
+// with named options, without implementing the BBB API.
+type CreateMeetingRequest struct {
+	FrontendID string `json:"frontend_id"`
+
+	// MeetingID is generated if empty, the
+	// name defaults to the meeting ID.
+	MeetingID string `json:"meeting_id"`
+	Name      string `json:"name"`
+
+	Record          bool   `json:"record"`
+	Duration        int    `json:"duration"` // Minutes
+	MaxParticipants int    `json:"max_participants"`
+	Welcome         string `json:"welcome"`
+	GuestPolicy     string `json:"guest_policy"`
+
+	// The names of the users joining through the
+	// join URLs of the response.
+	ModeratorName string `json:"moderator_name"`
+	AttendeeName  string `json:"attendee_name"`
+}
+
+// Validate the create meeting request
+func (r *CreateMeetingRequest) Validate() error {
+	err := store.ValidationError{}
+	if strings.TrimSpace(r.FrontendID) == "" {
+		err.Add("frontend_id", store.ErrFieldRequired)
+	}
+	if r.Duration < 0 {
+		err.Add("duration", "must not be negative")
+	}
+	if r.MaxParticipants < 0 {
+		err.Add("max_participants", "must not be negative")
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// params encodes the request as create params
+func (r *CreateMeetingRequest) params(
+	moderatorPW, attendeePW string,
+) bbb.Params {
+	params := bbb.Params{
+		"meetingID":   r.MeetingID,
+		"name":        r.Name,
+		"moderatorPW": moderatorPW,
+		"attendeePW":  attendeePW,
+	}
+	if r.Record {
+		params["record"] = "true"
+	}
+	if r.Duration > 0 {
+		params["duration"] = strconv.Itoa(r.Duration)
+	}
+	if r.MaxParticipants > 0 {
+		params["maxParticipants"] = strconv.Itoa(r.MaxParticipants)
+	}
+	if r.Welcome != "" {
+		params["welcome"] = r.Welcome
+	}
+	if r.GuestPolicy != "" {
+		params["guestPolicy"] = r.GuestPolicy
+	}
+	return params
+}
+
+// CreateMeetingResponse contains the signed join URLs
+// for moderators and attendees.
+type CreateMeetingResponse struct {
+	MeetingID         string `json:"meeting_id"`
+	InternalMeetingID string `json:"internal_meeting_id"`
+	ModeratorJoinURL  string `json:"moderator_join_url"`
+	AttendeeJoinURL   string `json:"attendee_join_url"`
+}
+
+// MeetingCreate creates a meeting for a frontend through
+// the gateway, like a create request of the frontend.
+// The join URLs point to the BBB API of the frontend.
+// ! requires: `admin`
+func MeetingCreate(gateway *cluster.Gateway) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.(*APIContext)
+		reqCtx := ctx.Ctx()
+
+		create := &CreateMeetingRequest{}
+		if err := c.Bind(create); err != nil {
+			return err
+		}
+		if err := create.Validate(); err != nil {
+			return err
+		}
+		if create.MeetingID == "" {
+			create.MeetingID = uuid.New().String()
+		}
+		if create.Name == "" {
+			create.Name = create.MeetingID
+		}
+		if create.ModeratorName == "" {
+			create.ModeratorName = "Moderator"
+		}
+		if create.AttendeeName == "" {
+			create.AttendeeName = "Attendee"
+		}
+
+		frontend, err := cluster.GetFrontend(reqCtx, store.Q().
+			Where("id = ?", create.FrontendID))
+		if err != nil {
+			return err
+		}
+		if frontend == nil {
+			return echo.NewHTTPError(
+				http.StatusNotFound, "frontend not found")
+		}
+
+		moderatorPW, err := store.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		attendeePW, err := store.GenerateSecret()
+		if err != nil {
+			return err
+		}
+
+		// The request is signed with the frontend secret,
+		// so it is handled like a request of the frontend.
+		api := client.New(bbbAPIEndpoint(c, frontend), frontend.Frontend().Secret)
+		params := create.params(moderatorPW, attendeePW)
+		req := bbb.CreateRequest(params, nil).
+			WithFrontend(frontend.Frontend())
+		req.Checksum = req.
+			WithBackend(&bbb.Backend{Secret: frontend.Frontend().Secret}).
+			Sign()
+		req.Backend = nil
+
+		dispatchCtx := cluster.ContextWithFrontend(reqCtx, frontend)
+		dispatchCtx = cluster.ContextWithRequestID(dispatchCtx,
+			c.Response().Header().Get(echo.HeaderXRequestID))
+		res := gateway.Dispatch(
+			dispatchCtx, store.ConnectionFromContext(reqCtx), req)
+		createRes, ok := res.(*bbb.CreateResponse)
+		if !ok {
+			msg := "meeting was not created"
+			if xmlRes, ok := res.(*bbb.XMLResponse); ok {
+				msg = xmlRes.MessageKey + ": " + xmlRes.Message
+			}
+			return echo.NewHTTPError(http.StatusBadGateway, msg)
+		}
+		if createRes.Returncode != bbb.RetSuccess {
+			return echo.NewHTTPError(http.StatusBadGateway,
+				createRes.MessageKey+": "+createRes.Message)
+		}
+
+		log.Info().
+			Str("frontend", frontend.Frontend().Key).
+			Str("meetingID", create.MeetingID).
+			Str("actor", ctx.AccountRef()).
+			Msg("meeting created through the api")
+
+		internalID := ""
+		if createRes.Meeting != nil {
+			internalID = createRes.Meeting.InternalMeetingID
+		}
+		now := time.Now()
+		return c.JSON(http.StatusCreated, &CreateMeetingResponse{
+			MeetingID:         create.MeetingID,
+			InternalMeetingID: internalID,
+			ModeratorJoinURL: api.JoinURL(withJoinTimestamp(frontend, bbb.Params{
+				"meetingID": create.MeetingID,
+				"fullName":  create.ModeratorName,
+				"password":  moderatorPW,
+			}, now)),
+			AttendeeJoinURL: api.JoinURL(withJoinTimestamp(frontend, bbb.Params{
+				"meetingID": create.MeetingID,
+				"fullName":  create.AttendeeName,
+				"password":  attendeePW,
+			}, now)),
+		})
+	}
+}
+
+// withJoinTimestamp adds the time of the create to
+// the params of a join link, if the frontend has a
+// join expiry. The links expire after the maximum age.
+func withJoinTimestamp(
+	frontend *cluster.Frontend,
+	params bbb.Params,
+	now time.Time,
+) bbb.Params {
+	opts := frontend.Settings().JoinExpiry
+	if opts == nil {
+		return params
+	}
+	params[opts.TimestampParam()] = strconv.FormatInt(now.Unix(), 10)
+	return params
+}
+
+// bbbAPIEndpoint builds the URL of the BBB API of the
+// frontend from the host of the request.
+func bbbAPIEndpoint(c echo.Context, frontend *cluster.Frontend) string {
+	return c.Scheme() + "://" + c.Request().Host +
+		"/bbb/" + frontend.Frontend().Key + "/bigbluebutton/api/"
+}
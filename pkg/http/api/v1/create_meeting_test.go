@@ -0,0 +1,74 @@
+package v1
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestCreateMeetingRequestValidate(t *testing.T) {
+	req := &CreateMeetingRequest{Duration: -1}
+	err := req.Validate()
+	if err == nil {
+		t.Fatal("expected validation error")
+	}
+	t.Log(err)
+
+	req = &CreateMeetingRequest{FrontendID: "frontend"}
+	if err := req.Validate(); err != nil {
+		t.Error("unexpected error:", err)
+	}
+}
+
+func TestCreateMeetingRequestParams(t *testing.T) {
+	req := &CreateMeetingRequest{
+		MeetingID:       "meeting",
+		Name:            "Meeting",
+		Record:          true,
+		Duration:        60,
+		MaxParticipants: 25,
+		GuestPolicy:     "ASK_MODERATOR",
+	}
+	params := req.params("mod", "att")
+	if params["meetingID"] != "meeting" {
+		t.Error("unexpected meetingID:", params["meetingID"])
+	}
+	if params["record"] != "true" {
+		t.Error("unexpected record:", params["record"])
+	}
+	if params["duration"] != "60" {
+		t.Error("unexpected duration:", params["duration"])
+	}
+	if params["maxParticipants"] != "25" {
+		t.Error("unexpected maxParticipants:", params["maxParticipants"])
+	}
+	if params["moderatorPW"] != "mod" || params["attendeePW"] != "att" {
+		t.Error("unexpected passwords:", params)
+	}
+	if _, ok := params["welcome"]; ok {
+		t.Error("welcome should not be set")
+	}
+}
+
+func TestWithJoinTimestamp(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	frontend := clustertest.NewFrontend(store.FrontendSettings{})
+	params := withJoinTimestamp(frontend, bbb.Params{}, now)
+	if _, ok := params["timestamp"]; ok {
+		t.Error("unexpected timestamp without join expiry:", params)
+	}
+
+	frontend = clustertest.NewFrontend(store.FrontendSettings{
+		JoinExpiry: &store.JoinExpirySettings{
+			MaxAge: "15m",
+			Param:  "issued",
+		},
+	})
+	params = withJoinTimestamp(frontend, bbb.Params{}, now)
+	if params["issued"] != "1700000000" {
+		t.Error("unexpected params:", params)
+	}
+}
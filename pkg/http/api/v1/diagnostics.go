@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// BackendDiagnosticsList retrieves the diagnostic bundles
+// uploaded by the node agent of the backend.
+// ! requires: `admin`
+func BackendDiagnosticsList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	bundles, err := store.GetBackendDiagnostics(reqCtx, tx, store.Q().
+		Where("backend_id = ?", c.Param("id")).
+		OrderBy("created_at DESC"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, bundles)
+}
+
+// BackendDiagnosticsRetrieve downloads a diagnostic
+// bundle as compressed tar archive.
+// ! requires: `admin`
+func BackendDiagnosticsRetrieve(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	bundle, err := store.GetBackendDiagnosticsBundle(
+		reqCtx, tx, c.Param("id"), c.Param("diagnosticsID"))
+	if err != nil {
+		return err
+	}
+	if bundle == nil {
+		return echo.ErrNotFound
+	}
+
+	filename := fmt.Sprintf("diagnostics-%s-%s.tar.gz",
+		bundle.BackendID,
+		bundle.CreatedAt.UTC().Format("20060102-150405"))
+	c.Response().Header().Set(
+		echo.HeaderContentDisposition,
+		fmt.Sprintf("attachment; filename=%q", filename))
+	return c.Blob(http.StatusOK, "application/gzip", bundle.Bundle)
+}
@@ -0,0 +1,77 @@
+package v1
+
+import (
+	"io"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBackendDiagnostics(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Upload a bundle like the node agent
+	ctx, _ := MakeTestContext(nil)
+	cctx := ctx.Ctx()
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := &store.BackendDiagnostics{
+		BackendID: backend.ID,
+		Bundle:    []byte("bundle"),
+	}
+	if err := bundle.Save(cctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(cctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Release()
+
+	// List
+	ctx, rec := MakeTestContext(nil)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(backend.ID)
+	if err := BackendDiagnosticsList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	bundles := []*store.BackendDiagnostics{}
+	if err := readJSONResponse(res, &bundles); err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != 1 || bundles[0].ID != bundle.ID {
+		t.Error("unexpected bundles:", bundles)
+	}
+
+	// Download
+	ctx, rec = MakeTestContext(nil)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("id", "diagnosticsID")
+	ctx.Context.SetParamValues(backend.ID, bundle.ID)
+	if err := BackendDiagnosticsRetrieve(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res = rec.Result()
+	if res.Header.Get("Content-Type") != "application/gzip" {
+		t.Error("unexpected content type:", res.Header.Get("Content-Type"))
+	}
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != "bundle" {
+		t.Error("unexpected body:", string(body))
+	}
+}
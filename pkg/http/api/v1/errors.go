@@ -28,6 +28,17 @@ func ErrorValidationFailed(c echo.Context, err store.ValidationError) error {
 	})
 }
 
+// ErrorBackendInUse creates an API response when deleting
+// a backend with running meetings was refused.
+func ErrorBackendInUse(c echo.Context, err *store.BackendInUseError) error {
+	return c.JSON(http.StatusConflict, map[string]interface{}{
+		"error":            "backend_in_use",
+		"message":          err.Error(),
+		"backend_id":       err.BackendID,
+		"running_meetings": err.RunningMeetings,
+	})
+}
+
 // APIErrorHandler intercepts well known errors
 // and renders a response.
 func APIErrorHandler(next echo.HandlerFunc) echo.HandlerFunc {
@@ -39,6 +50,9 @@ func APIErrorHandler(next echo.HandlerFunc) echo.HandlerFunc {
 		if validationErr, ok := err.(store.ValidationError); ok {
 			return ErrorValidationFailed(c, validationErr)
 		}
+		if inUseErr, ok := err.(*store.BackendInUseError); ok {
+			return ErrorBackendInUse(c, inUseErr)
+		}
 		return err
 	}
 }
@@ -34,3 +34,27 @@ func TestAPIErrorHandler(t *testing.T) {
 	body, _ := ioutil.ReadAll(res.Body)
 	t.Log(string(body))
 }
+
+func TestAPIErrorHandlerBackendInUse(t *testing.T) {
+	ctx, rec := MakeTestContext(nil)
+	errFunc := func(_ echo.Context) error {
+		return &store.BackendInUseError{
+			BackendID:       "backend23",
+			RunningMeetings: []string{"meeting42"},
+		}
+	}
+	h := APIErrorHandler(errFunc)
+
+	if err := h(ctx); err != nil {
+		t.Error(err) // Error was not handled
+	}
+
+	res := rec.Result()
+	if res.StatusCode != http.StatusConflict {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	apiErr := APIErrorFromResponse(res)
+	if apiErr["error"] != "backend_in_use" {
+		t.Error("unexpected error:", apiErr)
+	}
+}
@@ -0,0 +1,103 @@
+package v1
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Errors
+var (
+	// ErrPreconditionFailed will be returned if the
+	// If-Match or If-None-Match header does not match
+	// the current state of the resource.
+	ErrPreconditionFailed = echo.NewHTTPError(
+		http.StatusPreconditionFailed,
+		"the resource was modified")
+
+	// ErrBackendExists will be returned when creating
+	// a backend with a host already in use.
+	ErrBackendExists = echo.NewHTTPError(
+		http.StatusConflict,
+		"a backend with this host already exists")
+
+	// ErrFrontendExists will be returned when creating
+	// a frontend with a key already in use.
+	ErrFrontendExists = echo.NewHTTPError(
+		http.StatusConflict,
+		"a frontend with this key already exists")
+)
+
+// makeETag creates an entity tag from the JSON encoding
+// of the configurable fields of a resource.
+func makeETag(v interface{}) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// backendETag is calculated from the configuration of
+// the backend. The node state and statistics change
+// all the time and are not part of the tag.
+func backendETag(b *store.BackendState) string {
+	if b == nil {
+		return ""
+	}
+	return makeETag([]interface{}{
+		b.ID, b.Backend, b.Settings, b.AdminState, b.LoadFactor,
+	})
+}
+
+// frontendETag is calculated from the configuration
+// of the frontend.
+func frontendETag(f *store.FrontendState) string {
+	if f == nil {
+		return ""
+	}
+	return makeETag([]interface{}{
+		f.ID, f.Frontend, f.Settings, f.Active, f.AccountRef,
+	})
+}
+
+// matchETag checks if the etag is in the list of tags
+// of a If-Match or If-None-Match header.
+func matchETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkPreconditions evaluates the conditional request
+// headers against the current etag of the resource. An
+// empty etag means the resource does not exist.
+func checkPreconditions(c echo.Context, etag string) error {
+	ifMatch := c.Request().Header.Get("If-Match")
+	if ifMatch != "" && (etag == "" || !matchETag(ifMatch, etag)) {
+		return ErrPreconditionFailed
+	}
+	ifNoneMatch := c.Request().Header.Get("If-None-Match")
+	if ifNoneMatch != "" && etag != "" && matchETag(ifNoneMatch, etag) {
+		return ErrPreconditionFailed
+	}
+	return nil
+}
+
+// setETag adds the ETag header to the response
+func setETag(c echo.Context, etag string) {
+	if etag != "" {
+		c.Response().Header().Set("ETag", etag)
+	}
+}
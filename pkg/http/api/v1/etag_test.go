@@ -0,0 +1,73 @@
+package v1
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBackendETag(t *testing.T) {
+	b := store.InitBackendState(&store.BackendState{
+		ID: "b1",
+		Backend: &bbb.Backend{
+			Host:   "http://testhost",
+			Secret: "testsec",
+		},
+	})
+	etag := backendETag(b)
+	if etag == "" {
+		t.Fatal("expected an etag")
+	}
+
+	// Statistics are not part of the etag
+	b.MeetingsCount = 42
+	if backendETag(b) != etag {
+		t.Error("etag changed with statistics")
+	}
+
+	b.LoadFactor = 2
+	if backendETag(b) == etag {
+		t.Error("etag did not change with config")
+	}
+
+	if backendETag(nil) != "" {
+		t.Error("expected no etag")
+	}
+}
+
+func TestCheckPreconditions(t *testing.T) {
+	check := func(header, value, etag string) error {
+		req := httptest.NewRequest("PUT", "http:///", nil)
+		if header != "" {
+			req.Header.Set(header, value)
+		}
+		c := echo.New().NewContext(req, httptest.NewRecorder())
+		return checkPreconditions(c, etag)
+	}
+
+	if err := check("", "", `"abc"`); err != nil {
+		t.Error("unconditional request failed:", err)
+	}
+	if err := check("If-Match", `"abc"`, `"abc"`); err != nil {
+		t.Error("matching etag failed:", err)
+	}
+	if err := check("If-Match", `"old", W/"abc"`, `"abc"`); err != nil {
+		t.Error("matching etag list failed:", err)
+	}
+	if err := check("If-Match", `"old"`, `"abc"`); err != ErrPreconditionFailed {
+		t.Error("expected precondition failed, got:", err)
+	}
+	if err := check("If-Match", "*", ""); err != ErrPreconditionFailed {
+		t.Error("expected precondition failed for missing resource")
+	}
+	if err := check("If-None-Match", "*", ""); err != nil {
+		t.Error("create only failed:", err)
+	}
+	if err := check("If-None-Match", "*", `"abc"`); err != ErrPreconditionFailed {
+		t.Error("expected precondition failed for existing resource")
+	}
+}
@@ -0,0 +1,128 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// eventsKeepAliveInterval is the interval in which
+// a comment is sent to keep idle streams open.
+const eventsKeepAliveInterval = 15 * time.Second
+
+// clusterEvents distributes the cluster events
+// to all connected streams.
+var clusterEvents = store.NewClusterEventsBroker()
+
+// StartClusterEvents receives the cluster events from
+// the database until the context is cancelled.
+func StartClusterEvents(ctx context.Context) {
+	clusterEvents.Start(ctx)
+}
+
+// parseEventTypes reads a comma separated list of
+// event types. Nil is returned if all types are accepted.
+func parseEventTypes(types string) map[string]bool {
+	if types == "" {
+		return nil
+	}
+	filter := make(map[string]bool)
+	for _, t := range strings.Split(types, ",") {
+		filter[strings.TrimSpace(t)] = true
+	}
+	return filter
+}
+
+// writeEvent encodes the event as server-sent event
+func writeEvent(res *echo.Response, event *store.ClusterEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(res, "event: %s\ndata: %s\n\n", event.Type, data)
+	return err
+}
+
+// writeDeadliner is implemented by the response
+// writers of the http server since go 1.20.
+type writeDeadliner interface {
+	SetWriteDeadline(time.Time) error
+}
+
+// SetWriteDeadline overrides the write timeout of the
+// server for a response. The zero time clears the deadline.
+func SetWriteDeadline(res http.ResponseWriter, deadline time.Time) error {
+	for {
+		switch w := res.(type) {
+		case writeDeadliner:
+			return w.SetWriteDeadline(deadline)
+		case *echo.Response:
+			res = w.Writer
+		case interface{ Unwrap() http.ResponseWriter }:
+			res = w.Unwrap()
+		default:
+			return http.ErrNotSupported
+		}
+	}
+}
+
+// ClusterEventsStream streams the cluster events
+// as server-sent events. The events can be filtered
+// with the `type` query parameter.
+// ! requires: `admin`
+func ClusterEventsStream(c echo.Context) error {
+	ctx := c.(*APIContext)
+	// The stream is long lived and must not hold
+	// a connection of the pool.
+	ctx.Release()
+
+	// The stream must outlive the write timeout
+	// of the server.
+	if err := SetWriteDeadline(c.Response(), time.Time{}); err != nil {
+		log.Warn().
+			Err(err).
+			Msg("could not clear the write deadline of the event stream")
+	}
+
+	filter := parseEventTypes(c.QueryParam("type"))
+	events := clusterEvents.Subscribe()
+	defer clusterEvents.Unsubscribe(events)
+
+	res := c.Response()
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set("Cache-Control", "no-cache")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAliveInterval)
+	defer keepAlive.Stop()
+
+	done := ctx.Ctx().Done()
+	for {
+		select {
+		case <-done:
+			return nil
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(res, ": keep-alive\n\n"); err != nil {
+				return nil // Client is gone
+			}
+		case event := <-events:
+			if filter != nil && !filter[event.Type] {
+				continue
+			}
+			if err := writeEvent(res, event); err != nil {
+				return nil // Client is gone
+			}
+		}
+		res.Flush()
+	}
+}
@@ -0,0 +1,99 @@
+package v1
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestParseEventTypes(t *testing.T) {
+	if parseEventTypes("") != nil {
+		t.Error("expected no filter")
+	}
+	filter := parseEventTypes("meeting_created, meeting_ended")
+	if !filter[store.ClusterEventMeetingCreated] ||
+		!filter[store.ClusterEventMeetingEnded] {
+		t.Error("unexpected filter:", filter)
+	}
+	if filter[store.ClusterEventBackendAdded] {
+		t.Error("unexpected filter:", filter)
+	}
+}
+
+func TestWriteEvent(t *testing.T) {
+	rec := httptest.NewRecorder()
+	res := echo.NewResponse(rec, echo.New())
+	event := &store.ClusterEvent{
+		Type: store.ClusterEventMeetingCreated,
+		Data: json.RawMessage(`{"id":"meeting23"}`),
+	}
+	if err := writeEvent(res, event); err != nil {
+		t.Fatal(err)
+	}
+	body := rec.Body.String()
+	if !strings.HasPrefix(body, "event: meeting_created\ndata: {") {
+		t.Error("unexpected event:", body)
+	}
+	if !strings.HasSuffix(body, "\n\n") {
+		t.Error("event not terminated:", body)
+	}
+	if !strings.Contains(body, `"id":"meeting23"`) {
+		t.Error("missing data:", body)
+	}
+}
+
+func TestClusterEventsStreamWriteTimeout(t *testing.T) {
+	supported := true
+	e := echo.New()
+	e.GET("/api/v1/events", func(c echo.Context) error {
+		if SetWriteDeadline(c.Response(), time.Time{}) == http.ErrNotSupported {
+			supported = false
+		}
+		req := c.Request()
+		conn, err := store.Acquire(req.Context())
+		if err != nil {
+			return err
+		}
+		defer conn.Release()
+		c.SetRequest(req.WithContext(
+			store.ContextWithConnection(req.Context(), conn)))
+		return ClusterEventsStream(&APIContext{c})
+	})
+	srv := httptest.NewUnstartedServer(e)
+	srv.Config.WriteTimeout = 100 * time.Millisecond
+	srv.Start()
+	defer srv.Close()
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	res, err := client.Get(srv.URL + "/api/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	if !supported {
+		t.Skip("the write deadline can not be cleared")
+	}
+
+	// Send an event after the write timeout
+	time.Sleep(300 * time.Millisecond)
+	clusterEvents.Publish(&store.ClusterEvent{
+		Type: store.ClusterEventMeetingCreated,
+		Data: json.RawMessage(`{"id":"meeting23"}`),
+	})
+
+	line, err := bufio.NewReader(res.Body).ReadString('\n')
+	if err != nil {
+		t.Fatal("stream closed:", err)
+	}
+	if line != "event: meeting_created\n" {
+		t.Error("unexpected line:", line)
+	}
+}
@@ -0,0 +1,30 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ExperimentsStats retrieves the usage of the
+// experiment arms for comparison.
+// ! requires: `admin`
+func ExperimentsStats(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	stats, err := store.GetExperimentStats(reqCtx, tx)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, stats)
+}
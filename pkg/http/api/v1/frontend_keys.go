@@ -0,0 +1,190 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ErrFrontendKeyExists will be returned when adding
+// a key already in use by a frontend.
+var ErrFrontendKeyExists = echo.NewHTTPError(
+	http.StatusConflict,
+	"a frontend with this key already exists")
+
+// scopedFrontend retrieves the frontend identified by
+// the id parameter within the scope of the user.
+func scopedFrontend(
+	c echo.Context,
+	tx pgx.Tx,
+) (*store.FrontendState, error) {
+	ctx := c.(*APIContext)
+	q := store.Q().Where("id = ?", c.Param("id"))
+	if !ctx.HasScope(ScopeAdmin) {
+		q = q.Where("account_ref = ?", ctx.AccountRef())
+	}
+	return store.GetFrontendState(ctx.Ctx(), tx, q)
+}
+
+// FrontendKeysList retrieves the additional keys
+// of the frontend.
+func FrontendKeysList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	keys, err := store.GetFrontendKeys(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		OrderBy(store.CreationOrder("frontend_keys")))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, keys)
+}
+
+// FrontendKeyCreate adds a key to the frontend. Without
+// a secret in the request, a random secret is generated.
+func FrontendKeyCreate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	key := &store.FrontendKey{}
+	if err := c.Bind(key); err != nil {
+		return err
+	}
+	if key.Secret == "" {
+		secret, err := store.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		key.Secret = secret
+	}
+	if err := key.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+
+	// The key must be unique
+	inUse, err := store.FrontendKeyInUse(cctx, tx, key.Key)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrFrontendKeyExists
+	}
+
+	key.FrontendID = frontend.ID
+	if err := key.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditFrontendKeyCreated,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"key":      key.Key,
+			"frontend": frontend.Frontend.Key,
+			"is_admin": ctx.HasScope(ScopeAdmin),
+			"key_id":   key.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("frontendID", frontend.ID).
+		Str("key", key.Key).
+		Msg("frontend key added")
+
+	return c.JSON(http.StatusOK, key)
+}
+
+// FrontendKeyDestroy removes an additional key
+// of the frontend.
+func FrontendKeyDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	key, err := store.GetFrontendKey(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		Where("id = ?", c.Param("keyID")))
+	if err != nil {
+		return err
+	}
+	if key == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteFrontendKey(cctx, tx, frontend.ID, key.ID); err != nil {
+		return err
+	}
+
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditFrontendKeyDeleted,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"key":      key.Key,
+			"frontend": frontend.Frontend.Key,
+			"is_admin": ctx.HasScope(ScopeAdmin),
+			"key_id":   key.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, key)
+}
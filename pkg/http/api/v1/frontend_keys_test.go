@@ -0,0 +1,88 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestFrontendKeyCreate(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"key":         "testkey-staging",
+		"description": "staging",
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user23", []string{})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+
+	if err := FrontendKeyCreate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	key := &store.FrontendKey{}
+	if err := readJSONResponse(res, key); err != nil {
+		t.Fatal(err)
+	}
+	if key.Secret == "" {
+		t.Error("a secret should be generated")
+	}
+	if key.FrontendID != f.ID {
+		t.Error("unexpected frontend:", key.FrontendID)
+	}
+
+	// The key of the frontend can not be used
+	body, _ = json.Marshal(map[string]interface{}{
+		"key": f.Frontend.Key,
+	})
+	req, _ = http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, _ = MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user23", []string{})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+	if err := FrontendKeyCreate(ctx); err != ErrFrontendKeyExists {
+		t.Error("expected a conflict, got:", err)
+	}
+}
+
+func TestFrontendKeysOtherAccount(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("GET", "http:///", nil)
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user42", []string{})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+
+	if err := FrontendKeysList(ctx); err != echo.ErrNotFound {
+		t.Error("expected not found, got:", err)
+	}
+}
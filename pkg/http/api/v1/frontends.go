@@ -3,12 +3,25 @@ package v1
 import (
 	"fmt"
 	"net/http"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
+// Secret rotation
+const (
+	// DefaultSecretOverlap is the time the previous secret
+	// is still accepted after regenerating a frontend secret.
+	DefaultSecretOverlap = 24 * time.Hour
+
+	// MaxSecretOverlap limits the overlap window.
+	MaxSecretOverlap = 7 * 24 * time.Hour
+)
+
 // FrontendsList will list all frontends known
 // to the cluster or within the user scope.
 func FrontendsList(c echo.Context) error {
@@ -30,6 +43,9 @@ func FrontendsList(c echo.Context) error {
 	if queryKeyLike != "" {
 		q = q.Where("key LIKE ?", fmt.Sprintf("%%%s%%", queryKeyLike))
 	}
+	if c.QueryParam("order") == "created" {
+		q = q.OrderBy(store.CreationOrder("frontends"))
+	}
 
 	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
 	if err != nil {
@@ -56,6 +72,7 @@ func FrontendCreate(c echo.Context) error {
 		Frontend: f.Frontend,
 		Settings: f.Settings,
 		Active:   f.Active,
+		ParentID: f.ParentID,
 	})
 
 	if isAdmin {
@@ -74,6 +91,20 @@ func FrontendCreate(c echo.Context) error {
 	}
 	defer tx.Rollback(cctx)
 
+	// The key must be unique, also among the
+	// additional keys of the frontends.
+	inUse, err := store.FrontendKeyInUse(cctx, tx, frontend.Frontend.Key)
+	if err != nil {
+		return err
+	}
+	if inUse {
+		return ErrFrontendExists
+	}
+	if err := frontend.ValidateParent(
+		cctx, tx, parentScope(isAdmin, accountRef)); err != nil {
+		return err
+	}
+
 	if err := frontend.Save(cctx, tx); err != nil {
 		return err
 	}
@@ -82,9 +113,106 @@ func FrontendCreate(c echo.Context) error {
 		return err
 	}
 
+	setETag(c, frontendETag(frontend))
 	return c.JSON(http.StatusOK, frontend)
 }
 
+// FrontendPut will create or replace the frontend
+// identified by its key. This allows managing the
+// frontends declaratively. Conditional requests with
+// If-Match and If-None-Match are supported.
+func FrontendPut(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+	isAdmin := ctx.HasScope(ScopeAdmin)
+	accountRef := ctx.AccountRef()
+
+	// Frontends are active unless declared otherwise
+	f := store.InitFrontendState(&store.FrontendState{})
+	if err := c.Bind(f); err != nil {
+		return err
+	}
+	desired := store.InitFrontendState(&store.FrontendState{
+		Frontend: f.Frontend,
+		Settings: f.Settings,
+		ParentID: f.ParentID,
+	})
+	desired.Active = f.Active
+	if isAdmin {
+		desired.AccountRef = f.AccountRef
+	} else {
+		desired.AccountRef = &accountRef
+	}
+	if err := desired.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := store.GetFrontendState(cctx, tx, store.Q().
+		Where("key = ?", desired.Frontend.Key))
+	if err != nil {
+		return err
+	}
+
+	// The key is taken by a frontend of another account
+	if frontend != nil && !isAdmin &&
+		(frontend.AccountRef == nil || *frontend.AccountRef != accountRef) {
+		return ErrFrontendExists
+	}
+	if err := checkPreconditions(c, frontendETag(frontend)); err != nil {
+		return err
+	}
+
+	status := http.StatusOK
+	if frontend == nil {
+		// The key may be an additional key of a frontend
+		inUse, err := store.FrontendKeyInUse(cctx, tx, desired.Frontend.Key)
+		if err != nil {
+			return err
+		}
+		if inUse {
+			return ErrFrontendExists
+		}
+		status = http.StatusCreated
+		frontend = desired
+	} else {
+		frontend.Frontend = desired.Frontend
+		frontend.Settings = desired.Settings
+		frontend.Active = desired.Active
+		frontend.AccountRef = desired.AccountRef
+		frontend.ParentID = desired.ParentID
+	}
+	if err := frontend.ValidateParent(
+		cctx, tx, parentScope(isAdmin, accountRef)); err != nil {
+		return err
+	}
+
+	if err := frontend.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	setETag(c, frontendETag(frontend))
+	return c.JSON(status, frontend)
+}
+
+// parentScope restricts the parent frontends to
+// the account, unless the request has the admin scope.
+func parentScope(isAdmin bool, accountRef string) sq.SelectBuilder {
+	q := store.Q()
+	if !isAdmin {
+		q = q.Where("account_ref = ?", accountRef)
+	}
+	return q
+}
+
 // FrontendRetrieve will retrieve a single frontend
 // identified by ID.
 func FrontendRetrieve(c echo.Context) error {
@@ -114,6 +242,7 @@ func FrontendRetrieve(c echo.Context) error {
 		return echo.ErrNotFound
 	}
 
+	setETag(c, frontendETag(frontend))
 	return c.JSON(http.StatusOK, frontend)
 }
 
@@ -145,6 +274,9 @@ func FrontendDestroy(c echo.Context) error {
 	if frontend == nil {
 		return echo.ErrNotFound
 	}
+	if err := checkPreconditions(c, frontendETag(frontend)); err != nil {
+		return err
+	}
 
 	if err := frontend.Delete(cctx, tx); err != nil {
 		return err
@@ -186,6 +318,9 @@ func FrontendUpdate(c echo.Context) error {
 	if frontend == nil {
 		return echo.ErrNotFound
 	}
+	if err := checkPreconditions(c, frontendETag(frontend)); err != nil {
+		return err
+	}
 
 	update, err := store.GetFrontendState(cctx, tx, q)
 	if err != nil {
@@ -200,6 +335,7 @@ func FrontendUpdate(c echo.Context) error {
 	frontend.Frontend = update.Frontend
 	frontend.Active = update.Active
 	frontend.Settings = update.Settings
+	frontend.ParentID = update.ParentID
 
 	if isAdmin {
 		frontend.AccountRef = update.AccountRef
@@ -208,6 +344,10 @@ func FrontendUpdate(c echo.Context) error {
 	if err := frontend.Validate(); err != nil {
 		return err
 	}
+	if err := frontend.ValidateParent(
+		cctx, tx, parentScope(isAdmin, accountRef)); err != nil {
+		return err
+	}
 	if err := frontend.Save(cctx, tx); err != nil {
 		return err
 	}
@@ -216,5 +356,98 @@ func FrontendUpdate(c echo.Context) error {
 		return err
 	}
 
+	setETag(c, frontendETag(frontend))
 	return c.JSON(http.StatusOK, frontend)
 }
+
+// FrontendSecretResponse contains the regenerated secret.
+// This is the only time the new secret is returned
+// by this endpoint.
+type FrontendSecretResponse struct {
+	ID                      string    `json:"id"`
+	Secret                  string    `json:"secret"`
+	PreviousSecretExpiresAt time.Time `json:"previous_secret_expires_at"`
+}
+
+// FrontendRegenerateSecret will replace the secret of the
+// frontend with a new random secret. The previous secret
+// will be accepted until the overlap window is over.
+// The overlap can be set through the query parameter
+// `overlap` as a duration, e.g. `overlap=2h`.
+func FrontendRegenerateSecret(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+	isAdmin := ctx.HasScope(ScopeAdmin)
+	accountRef := ctx.AccountRef()
+	id := c.Param("id")
+
+	overlap := DefaultSecretOverlap
+	if value := c.QueryParam("overlap"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 || d > MaxSecretOverlap {
+			return echo.NewHTTPError(
+				http.StatusBadRequest,
+				"overlap must be a duration between 0 and "+
+					MaxSecretOverlap.String())
+		}
+		overlap = d
+	}
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	q := store.Q().Where("id = ?", id)
+	if !isAdmin {
+		q = q.Where("account_ref = ?", accountRef)
+	}
+
+	frontend, err := store.GetFrontendState(cctx, tx, q)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+
+	secret, err := frontend.RegenerateSecret(cctx, tx, overlap)
+	if err != nil {
+		return err
+	}
+
+	// Record the rotation in the audit log
+	entry := &store.AuditLogEntry{
+		Actor:        accountRef,
+		Action:       store.AuditFrontendSecretRegenerated,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"key":                        frontend.Frontend.Key,
+			"is_admin":                   isAdmin,
+			"overlap":                    overlap.String(),
+			"previous_secret_expires_at": frontend.PreviousSecretExpiresAt,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("frontendID", frontend.ID).
+		Str("key", frontend.Frontend.Key).
+		Str("actor", accountRef).
+		Dur("overlap", overlap).
+		Msg("frontend secret regenerated")
+
+	return c.JSON(http.StatusOK, &FrontendSecretResponse{
+		ID:                      frontend.ID,
+		Secret:                  secret,
+		PreviousSecretExpiresAt: *frontend.PreviousSecretExpiresAt,
+	})
+}
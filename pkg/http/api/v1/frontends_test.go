@@ -7,6 +7,8 @@ import (
 	"net/http"
 	"testing"
 
+	"github.com/labstack/echo/v4"
+
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
@@ -285,3 +287,64 @@ func TestFrontendDestroy(t *testing.T) {
 	resBody, _ := ioutil.ReadAll(res.Body)
 	t.Log("destroy:", string(resBody))
 }
+
+func TestFrontendRegenerateSecret(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "http:///?overlap=1h", nil)
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user23", []string{})
+
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+
+	if err := FrontendRegenerateSecret(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	resBody, _ := ioutil.ReadAll(res.Body)
+	data := &FrontendSecretResponse{}
+	if err := json.Unmarshal(resBody, data); err != nil {
+		t.Fatal(err)
+	}
+	if data.Secret == "" || data.Secret == f.Frontend.Secret {
+		t.Error("unexpected secret:", data.Secret)
+	}
+	if data.PreviousSecretExpiresAt.IsZero() {
+		t.Error("expected previous secret expiry")
+	}
+}
+
+func TestFrontendRegenerateSecretOtherAccount(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest("POST", "http:///", nil)
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user42", []string{})
+
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+
+	if err := FrontendRegenerateSecret(ctx); err != echo.ErrNotFound {
+		t.Error("expected not found, got:", err)
+	}
+}
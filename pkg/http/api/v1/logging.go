@@ -0,0 +1,48 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/logging"
+)
+
+// LoggingOptions are the log level and format
+// of the b3scale instance.
+type LoggingOptions struct {
+	Level    string `json:"level"`
+	Format   string `json:"format"`
+	Sampling string `json:"sampling"`
+}
+
+// LoggingRetrieve responds with the current
+// logging configuration.
+// ! requires: `admin`
+func LoggingRetrieve(c echo.Context) error {
+	opts := logging.Current()
+	return c.JSON(http.StatusOK, &LoggingOptions{
+		Level:    opts.Level,
+		Format:   opts.Format,
+		Sampling: opts.Sampling,
+	})
+}
+
+// LoggingUpdate changes the log level and format
+// at runtime. This only affects the instance handling
+// the request.
+// ! requires: `admin`
+func LoggingUpdate(c echo.Context) error {
+	update := &LoggingOptions{}
+	if err := c.Bind(update); err != nil {
+		return err
+	}
+	if err := logging.Update(&logging.Options{
+		Level:    update.Level,
+		Format:   update.Format,
+		Sampling: update.Sampling,
+	}); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	return LoggingRetrieve(c)
+}
@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"bytes"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/logging"
+)
+
+func TestLoggingUpdate(t *testing.T) {
+	if err := logging.Setup(&logging.Options{
+		Level:  "info",
+		Format: "structured",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	body := bytes.NewBufferString(`{"level": "debug"}`)
+	req, _ := http.NewRequest("PATCH", "http:///", body)
+	req.Header.Set("Content-Type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := LoggingUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Result().StatusCode != http.StatusOK {
+		t.Error("unexpected status:", rec.Result().StatusCode)
+	}
+	if logging.Current().Level != "debug" {
+		t.Error("unexpected level:", logging.Current().Level)
+	}
+
+	// Invalid level
+	body = bytes.NewBufferString(`{"level": "loud"}`)
+	req, _ = http.NewRequest("PATCH", "http:///", body)
+	req.Header.Set("Content-Type", "application/json")
+	ctx, _ = MakeTestContext(req)
+	defer ctx.Release()
+	if err := LoggingUpdate(ctx); err == nil {
+		t.Error("expected an error")
+	}
+}
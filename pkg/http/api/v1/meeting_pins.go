@@ -0,0 +1,154 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// MeetingPinsList retrieves the meeting pins,
+// optionally filtered by frontend_id.
+// ! requires: `admin`
+func MeetingPinsList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	q := store.Q().OrderBy("expires_at ASC")
+	if id := c.QueryParam("frontend_id"); id != "" {
+		q = q.Where("frontend_id = ?", id)
+	}
+	pins, err := store.GetMeetingPins(cctx, tx, q)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, pins)
+}
+
+// MeetingPinCreate pins a meeting of a frontend to a
+// backend. An existing pin of the meeting is replaced.
+// ! requires: `admin`
+func MeetingPinCreate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	pin := &store.MeetingPin{}
+	if err := c.Bind(pin); err != nil {
+		return err
+	}
+	if err := pin.Validate(); err != nil {
+		return err
+	}
+	pin.CreatedBy = ctx.AccountRef()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := store.GetFrontendState(cctx, tx, store.Q().
+		Where("id = ?", pin.FrontendID))
+	if err != nil {
+		return err
+	}
+	backend, err := store.GetBackendState(cctx, tx, store.Q().
+		Where("id = ?", pin.BackendID))
+	if err != nil {
+		return err
+	}
+	verr := store.ValidationError{}
+	if frontend == nil {
+		verr.Add("frontend_id", "frontend not found")
+	}
+	if backend == nil {
+		verr.Add("backend_id", "backend not found")
+	}
+	if len(verr) > 0 {
+		return verr
+	}
+
+	if err := pin.Save(cctx, tx); err != nil {
+		return err
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditMeetingPinned,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"meeting_id": pin.MeetingID,
+			"frontend":   frontend.Frontend.Key,
+			"backend":    backend.Backend.Host,
+			"expires_at": pin.ExpiresAt,
+			"pin_id":     pin.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("frontend", frontend.Frontend.Key).
+		Str("meetingID", pin.MeetingID).
+		Str("backend", backend.Backend.Host).
+		Time("expiresAt", pin.ExpiresAt).
+		Msg("meeting pinned")
+
+	return c.JSON(http.StatusOK, pin)
+}
+
+// MeetingPinDestroy removes a meeting pin. A meeting
+// already running on the backend is not moved.
+// ! requires: `admin`
+func MeetingPinDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	pin, err := store.GetMeetingPin(cctx, tx, store.Q().
+		Where("id = ?", c.Param("id")))
+	if err != nil {
+		return err
+	}
+	if pin == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteMeetingPin(cctx, tx, pin.ID); err != nil {
+		return err
+	}
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditMeetingUnpinned,
+		ResourceType: "frontend",
+		ResourceID:   pin.FrontendID,
+		Details: map[string]interface{}{
+			"meeting_id": pin.MeetingID,
+			"backend_id": pin.BackendID,
+			"pin_id":     pin.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, pin)
+}
@@ -0,0 +1,71 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestMeetingPinCreate(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"frontend_id": f.ID,
+		"meeting_id":  "keynote",
+		"backend_id":  b.ID,
+		"expires_at":  time.Now().Add(time.Hour),
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "support1", []string{ScopeAdmin})
+
+	if err := MeetingPinCreate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	pin := &store.MeetingPin{}
+	if err := readJSONResponse(res, pin); err != nil {
+		t.Fatal(err)
+	}
+	if pin.ID == "" || pin.BackendID != b.ID {
+		t.Error("unexpected pin:", pin)
+	}
+	if pin.CreatedBy != "support1" {
+		t.Error("unexpected creator:", pin.CreatedBy)
+	}
+
+	// The backend must exist
+	body, _ = json.Marshal(map[string]interface{}{
+		"frontend_id": f.ID,
+		"meeting_id":  "keynote",
+		"backend_id":  f.ID,
+		"expires_at":  time.Now().Add(time.Hour),
+	})
+	req, _ = http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, _ = MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "support1", []string{ScopeAdmin})
+	if _, ok := MeetingPinCreate(ctx).(store.ValidationError); !ok {
+		t.Error("expected a validation error")
+	}
+}
@@ -3,7 +3,9 @@ package v1
 import (
 	"net/http"
 	"strings"
+	"time"
 
+	sq "github.com/Masterminds/squirrel"
 	"github.com/jackc/pgx/v4"
 	"github.com/labstack/echo/v4"
 
@@ -43,12 +45,45 @@ func backendFromRequest(
 	return backend, nil
 }
 
+// MeetingLogs is a meeting state with links to the
+// logs of the backend, rendered from the log URL
+// templates of the backend settings.
+type MeetingLogs struct {
+	*store.MeetingState
+	LogLinks map[string]string `json:"log_links,omitempty"`
+}
+
+// meetingsLabelFilter restricts the meetings query
+// to the meetings with all labels of the `label` query
+// parameters, like `label=course:physics-101`.
+func meetingsLabelFilter(
+	c echo.Context,
+	q sq.SelectBuilder,
+) (sq.SelectBuilder, error) {
+	for _, l := range c.QueryParams()["label"] {
+		key, value, err := store.ParseLabelSelector(l)
+		if err != nil {
+			return q, store.ValidationError{
+				"label": []string{err.Error()},
+			}
+		}
+		q = store.WhereLabel(q, key, value)
+	}
+	return q, nil
+}
+
 // BackendMeetingsList will retrieve all meetings for a
-// given backend_id.
+// given backend_id. The meetings can be filtered by
+// labels, the backend is optional then.
 func BackendMeetingsList(c echo.Context) error {
 	ctx := c.(*APIContext)
 	cctx := ctx.Ctx()
 
+	q, err := meetingsLabelFilter(c, store.Q())
+	if err != nil {
+		return err
+	}
+
 	// Begin TX
 	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
 	if err != nil {
@@ -56,19 +91,85 @@ func BackendMeetingsList(c echo.Context) error {
 	}
 	defer tx.Rollback(cctx)
 
-	backend, err := backendFromRequest(c, tx)
+	backends := map[string]*store.BackendState{}
+	hasBackend := c.QueryParam("backend_id") != "" ||
+		c.QueryParam("backend_host") != ""
+	if hasBackend || len(c.QueryParams()["label"]) == 0 {
+		backend, err := backendFromRequest(c, tx)
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return echo.ErrNotFound
+		}
+		backends[backend.ID] = backend
+		q = q.Where("backend_id = ?", backend.ID)
+	}
+
+	// Begin Query
+	meetings, err := store.GetMeetingStates(cctx, tx, q)
 	if err != nil {
 		return err
 	}
+	res := make([]*MeetingLogs, 0, len(meetings))
+	for _, m := range meetings {
+		logs := &MeetingLogs{MeetingState: m}
+		if m.BackendID != nil {
+			backend, ok := backends[*m.BackendID]
+			if !ok {
+				backend, err = store.GetBackendState(cctx, tx, store.Q().
+					Where("id = ?", *m.BackendID))
+				if err != nil {
+					return err
+				}
+				backends[*m.BackendID] = backend
+			}
+			if backend != nil {
+				logs.LogLinks = backend.Settings.LogLinks(m.ID, m.InternalID)
+			}
+		}
+		res = append(res, logs)
+	}
+	return c.JSON(http.StatusOK, res)
+}
 
-	if backend == nil {
-		return echo.ErrNotFound
+// MeetingsLabelStats counts the meetings and attendees
+// by the value of the label `key`. The meetings can
+// be filtered by frontend, backend and labels.
+// ! requires: `admin`
+func MeetingsLabelStats(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	key := strings.TrimSpace(c.QueryParam("key"))
+	if !store.ValidLabelKey(key) {
+		return store.ValidationError{
+			"key": []string{"a valid label key is required"},
+		}
+	}
+	q, err := meetingsLabelFilter(c, store.Q())
+	if err != nil {
+		return err
+	}
+	if id := strings.TrimSpace(c.QueryParam("frontend_id")); id != "" {
+		q = q.Where("meetings.frontend_id = ?", id)
+	}
+	if id := strings.TrimSpace(c.QueryParam("backend_id")); id != "" {
+		q = q.Where("meetings.backend_id = ?", id)
 	}
 
-	// Begin Query
-	q := store.Q().Where("backend_id = ?", backend.ID)
-	meetings, err := store.GetMeetingStates(cctx, tx, q)
-	return c.JSON(http.StatusOK, meetings)
+	// Begin TX
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	stats, err := store.GetMeetingLabelStats(cctx, tx, key, q)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, stats)
 }
 
 // BackendMeetingsEndResponse is the result of the end
@@ -112,3 +213,78 @@ func BackendMeetingsEnd(c echo.Context) error {
 	// Make response
 	return c.JSON(http.StatusAccepted, cmd)
 }
+
+// MeetingsEnd gracefully ends all meetings of a backend
+// or of the whole cluster: The message is posted to the
+// meetings, which are ended after the delay.
+// ! requires: `admin`
+func MeetingsEnd(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	req := &cluster.EndMeetingsRequest{}
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	if req.BackendID != "" {
+		backend, err := store.GetBackendState(cctx, tx, store.Q().
+			Where("id = ?", req.BackendID))
+		if err != nil {
+			return err
+		}
+		if backend == nil {
+			return store.ValidationError{
+				"backend_id": []string{"no such backend"},
+			}
+		}
+	}
+
+	res, err := cluster.QueueEndMeetings(cctx, tx, req, time.Now())
+	if err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusAccepted, res)
+}
+
+// MeetingsReconcile compares the meetings in the store
+// with the live meetings of the backends and reports
+// meetings known only to one side. The backends can
+// be filtered by backend_id or backend_host.
+// ! requires: `admin`
+func MeetingsReconcile(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	q := store.Q()
+	if id := strings.TrimSpace(c.QueryParam("backend_id")); id != "" {
+		q = q.Where("id = ?", id)
+	}
+	if host := strings.TrimSpace(c.QueryParam("backend_host")); host != "" {
+		q = q.Where("host = ?", host)
+	}
+	backends, err := cluster.GetBackends(cctx, q)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]*cluster.MeetingsReconciliation, 0, len(backends))
+	for _, b := range backends {
+		r, err := b.ReconcileMeetings(cctx)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	}
+	return c.JSON(http.StatusOK, reports)
+}
@@ -1,18 +1,30 @@
 package v1
 
 import (
+	"bytes"
+	"encoding/json"
 	"io/ioutil"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
 func CreateTestMeeting(backend *store.BackendState) (*store.MeetingState, error) {
+	return CreateTestMeetingWithLabels(backend, nil)
+}
+
+func CreateTestMeetingWithLabels(
+	backend *store.BackendState,
+	labels store.Labels,
+) (*store.MeetingState, error) {
 	ctx, _ := MakeTestContext(nil)
 	defer ctx.Release()
 	cctx := ctx.Ctx()
@@ -27,6 +39,7 @@ func CreateTestMeeting(backend *store.BackendState) (*store.MeetingState, error)
 			MeetingID:         uuid.New().String(),
 			InternalMeetingID: uuid.New().String(),
 		},
+		Labels: labels,
 	})
 
 	if err := m.Save(cctx, tx); err != nil {
@@ -138,6 +151,107 @@ func TestBackendMeetingsList(t *testing.T) {
 	t.Log("list:", string(resBody))
 }
 
+func TestBackendMeetingsListByLabel(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	m, err := CreateTestMeetingWithLabels(backend, store.Labels{
+		"course": "physics-101",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestMeeting(backend); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("http:///?label=course:physics-101")
+	req := &http.Request{
+		URL: u,
+	}
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := BackendMeetingsList(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	meetings := []*MeetingLogs{}
+	if err := json.NewDecoder(res.Body).Decode(&meetings); err != nil {
+		t.Fatal(err)
+	}
+	if len(meetings) != 1 {
+		t.Fatal("unexpected meetings:", meetings)
+	}
+	if meetings[0].ID != m.ID {
+		t.Error("unexpected meeting:", meetings[0].ID)
+	}
+}
+
+func TestBackendMeetingsListInvalidLabel(t *testing.T) {
+	u, _ := url.Parse("http:///?label=Not%20A%20Key:foo")
+	req := &http.Request{
+		URL: u,
+	}
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if _, ok := BackendMeetingsList(ctx).(store.ValidationError); !ok {
+		t.Error("expected validation error")
+	}
+}
+
+func TestMeetingsLabelStats(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, course := range []string{"physics-101", "physics-101", "math-201"} {
+		if _, err := CreateTestMeetingWithLabels(backend, store.Labels{
+			"course": course,
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	u, _ := url.Parse("http:///?key=course")
+	req := &http.Request{
+		URL: u,
+	}
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := MeetingsLabelStats(ctx); err != nil {
+		t.Fatal(err)
+	}
+	stats := []*store.MeetingLabelStats{}
+	if err := json.NewDecoder(rec.Result().Body).Decode(&stats); err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 2 {
+		t.Fatal("unexpected stats:", stats)
+	}
+	if stats[0].Value != "math-201" || stats[0].Meetings != 1 {
+		t.Error("unexpected stats:", stats[0])
+	}
+	if stats[1].Value != "physics-101" || stats[1].Meetings != 2 {
+		t.Error("unexpected stats:", stats[1])
+	}
+}
+
 func TestBackendMeetingsEnd(t *testing.T) {
 	if err := ClearState(); err != nil {
 		t.Fatal(err)
@@ -169,3 +283,135 @@ func TestBackendMeetingsEnd(t *testing.T) {
 	t.Log("list:", string(resBody))
 
 }
+
+func TestMeetingsEnd(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestMeeting(backend); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestMeeting(backend); err != nil {
+		t.Fatal(err)
+	}
+
+	end := func() (*httptest.ResponseRecorder, error) {
+		body, _ := json.Marshal(map[string]interface{}{
+			"message": "The server is shut down for maintenance.",
+			"delay":   "5m",
+		})
+		req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+		req.Header.Set("content-type", "application/json")
+
+		ctx, rec := MakeTestContext(req)
+		defer ctx.Release()
+		ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+		return rec, MeetingsEnd(ctx)
+	}
+
+	// The version of the test backend is unknown,
+	// so the message can not be posted.
+	if _, err := end(); err == nil {
+		t.Fatal("expected an error for an unknown version")
+	} else if _, ok := err.(store.ValidationError); !ok {
+		t.Fatal("unexpected error:", err)
+	}
+
+	ctx, _ := MakeTestContext(nil)
+	defer ctx.Release()
+	cctx := ctx.Ctx()
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	backend.Version = "2.7.3"
+	if err := backend.Save(cctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(cctx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec, err := end()
+	if err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusAccepted {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	endRes := &cluster.EndMeetingsResponse{}
+	if err := readJSONResponse(res, endRes); err != nil {
+		t.Fatal(err)
+	}
+	if endRes.Meetings != 2 {
+		t.Error("unexpected meetings:", endRes.Meetings)
+	}
+	// A broadcast and an end command for each meeting
+	if len(endRes.Commands) != 4 {
+		t.Error("unexpected commands:", endRes.Commands)
+	}
+	if time.Until(endRes.EndAt) < 4*time.Minute {
+		t.Error("the meetings should end later:", endRes.EndAt)
+	}
+}
+
+func TestMeetingsEndInvalidDelay(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"delay": "1 week",
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	err := MeetingsEnd(ctx)
+	if _, ok := err.(store.ValidationError); !ok {
+		t.Error("expected a validation error, got:", err)
+	}
+}
+
+func TestMeetingsReconcile(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CreateTestMeeting(backend); err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("http:///?backend_id=" + backend.ID)
+	req := &http.Request{
+		URL: u,
+	}
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := MeetingsReconcile(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	reports := []*cluster.MeetingsReconciliation{}
+	if err := readJSONResponse(res, &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatal("unexpected reports:", reports)
+	}
+	// The test backend is not reachable
+	if reports[0].Error == "" {
+		t.Error("expected an error for the backend")
+	}
+}
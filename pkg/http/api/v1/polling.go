@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+)
+
+// pollingTopLimit is the default number of
+// frontends returned.
+const pollingTopLimit = 10
+
+// PollingResponse contains the frontends with
+// the most polling requests.
+type PollingResponse struct {
+	Since           time.Time                       `json:"since"`
+	DuplicateWindow string                          `json:"duplicate_window"`
+	Frontends       []*metrics.FrontendPollingStats `json:"frontends"`
+}
+
+// PollingTop retrieves the frontends with the most
+// polling requests like isMeetingRunning. The counts
+// are local to the instance handling the request.
+// ! requires: `admin`
+func PollingTop(c echo.Context) error {
+	limit := pollingTopLimit
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n < 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = n
+	}
+	return c.JSON(http.StatusOK, &PollingResponse{
+		Since:           metrics.Polling.Since(),
+		DuplicateWindow: metrics.DuplicateWindow.String(),
+		Frontends:       metrics.Polling.Top(limit, time.Now()),
+	})
+}
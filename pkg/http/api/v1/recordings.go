@@ -0,0 +1,45 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// RecordingsReconcile compares the recordings in the
+// store with the recordings of the backends. With POST,
+// recordings missing in the store are imported.
+// The backends can be filtered by backend_id or backend_host.
+// ! requires: `admin`
+func RecordingsReconcile(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	importMissing := c.Request().Method == http.MethodPost
+
+	q := store.Q()
+	if id := strings.TrimSpace(c.QueryParam("backend_id")); id != "" {
+		q = q.Where("id = ?", id)
+	}
+	if host := strings.TrimSpace(c.QueryParam("backend_host")); host != "" {
+		q = q.Where("host = ?", host)
+	}
+	backends, err := cluster.GetBackends(cctx, q)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]*cluster.RecordingsReconciliation, 0, len(backends))
+	for _, b := range backends {
+		r, err := b.ReconcileRecordings(cctx, importMissing)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	}
+	return c.JSON(http.StatusOK, reports)
+}
@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ClusterRecover rebuilds the meetings and recordings
+// in the store from the live state of the backends.
+// The backends can be filtered by backend_id or backend_host.
+// ! requires: `admin`
+func ClusterRecover(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	q := store.Q()
+	if id := strings.TrimSpace(c.QueryParam("backend_id")); id != "" {
+		q = q.Where("id = ?", id)
+	}
+	if host := strings.TrimSpace(c.QueryParam("backend_host")); host != "" {
+		q = q.Where("host = ?", host)
+	}
+	backends, err := cluster.GetBackends(cctx, q)
+	if err != nil {
+		return err
+	}
+
+	reports := make([]*cluster.Recovery, 0, len(backends))
+	for _, b := range backends {
+		r, err := b.Recover(cctx)
+		if err != nil {
+			return err
+		}
+		reports = append(reports, r)
+	}
+
+	log.Info().
+		Int("backends", len(reports)).
+		Str("actor", ctx.AccountRef()).
+		Msg("cluster state recovered from backends")
+
+	return c.JSON(http.StatusOK, reports)
+}
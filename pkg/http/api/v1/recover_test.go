@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+)
+
+func TestClusterRecover(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, _ := url.Parse("http:///?backend_id=" + backend.ID)
+	req := &http.Request{
+		Method: http.MethodPost,
+		URL:    u,
+	}
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := ClusterRecover(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	reports := []*cluster.Recovery{}
+	if err := readJSONResponse(res, &reports); err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 1 {
+		t.Fatal("unexpected reports:", reports)
+	}
+	// The test backend is not reachable
+	if reports[0].Error == "" {
+		t.Error("expected an error for the backend")
+	}
+	if reports[0].Meetings != 0 || reports[0].Recordings != 0 {
+		t.Error("nothing should be recovered:", reports[0])
+	}
+}
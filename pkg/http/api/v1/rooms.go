@@ -0,0 +1,248 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ErrRoomExists will be returned when adding a room
+// with a meeting ID already used by a room of the frontend.
+var ErrRoomExists = echo.NewHTTPError(
+	http.StatusConflict,
+	"a room with this meeting id already exists")
+
+// FrontendRoomsList retrieves the rooms of the frontend
+func FrontendRoomsList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	rooms, err := store.GetRooms(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		OrderBy(store.CreationOrder("rooms")))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, rooms)
+}
+
+// FrontendRoomCreate adds a room to the frontend. Without
+// passwords in the request, random passwords are generated.
+func FrontendRoomCreate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	room := &store.Room{}
+	if err := c.Bind(room); err != nil {
+		return err
+	}
+	for _, pw := range []*string{&room.ModeratorPW, &room.AttendeePW} {
+		if *pw != "" {
+			continue
+		}
+		secret, err := store.GenerateSecret()
+		if err != nil {
+			return err
+		}
+		*pw = secret
+	}
+	if err := room.Validate(); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+
+	// The meeting ID must be unique for the frontend
+	existing, err := store.GetRoomByMeetingID(
+		cctx, tx, frontend.ID, room.MeetingID)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		return ErrRoomExists
+	}
+
+	room.FrontendID = frontend.ID
+	if err := room.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditRoomCreated,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"meeting_id": room.MeetingID,
+			"frontend":   frontend.Frontend.Key,
+			"is_admin":   ctx.HasScope(ScopeAdmin),
+			"room_id":    room.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("frontendID", frontend.ID).
+		Str("meetingID", room.MeetingID).
+		Msg("room added")
+
+	return c.JSON(http.StatusOK, room)
+}
+
+// FrontendRoomDestroy removes a room of the frontend.
+// A running meeting of the room is not ended.
+func FrontendRoomDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	room, err := store.GetRoom(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		Where("id = ?", c.Param("roomID")))
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteRoom(cctx, tx, frontend.ID, room.ID); err != nil {
+		return err
+	}
+
+	entry := &store.AuditLogEntry{
+		Actor:        ctx.AccountRef(),
+		Action:       store.AuditRoomDeleted,
+		ResourceType: "frontend",
+		ResourceID:   frontend.ID,
+		Details: map[string]interface{}{
+			"meeting_id": room.MeetingID,
+			"frontend":   frontend.Frontend.Key,
+			"is_admin":   ctx.HasScope(ScopeAdmin),
+			"room_id":    room.ID,
+		},
+	}
+	if err := entry.Save(cctx, tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, room)
+}
+
+// RoomScheduleRequest attaches a schedule to a room.
+// An empty schedule removes the schedule.
+type RoomScheduleRequest struct {
+	Schedule string `json:"schedule"`
+	Timezone string `json:"timezone"`
+	Duration int    `json:"duration"`
+}
+
+// FrontendRoomScheduleUpdate sets the schedule of a room.
+// The meeting is created shortly before the next start.
+func FrontendRoomScheduleUpdate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	schedule := &RoomScheduleRequest{}
+	if err := c.Bind(schedule); err != nil {
+		return err
+	}
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	room, err := store.GetRoom(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		Where("id = ?", c.Param("roomID")))
+	if err != nil {
+		return err
+	}
+	if room == nil {
+		return echo.ErrNotFound
+	}
+
+	room.Schedule = schedule.Schedule
+	room.Timezone = schedule.Timezone
+	room.Duration = schedule.Duration
+	if err := room.Validate(); err != nil {
+		return err
+	}
+	next, err := room.NextStart(time.Now())
+	if err != nil {
+		return err
+	}
+	room.NextStartAt = next
+	if err := room.SaveSchedule(cctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(cctx); err != nil {
+		return err
+	}
+
+	log.Info().
+		Str("frontendID", frontend.ID).
+		Str("meetingID", room.MeetingID).
+		Str("schedule", room.Schedule).
+		Msg("room schedule updated")
+
+	return c.JSON(http.StatusOK, room)
+}
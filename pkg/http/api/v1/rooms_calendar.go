@@ -0,0 +1,110 @@
+package v1
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// calendarPeriod is the time the upcoming
+// starts of the rooms are listed.
+const calendarPeriod = 28 * 24 * time.Hour
+
+// calendarDefaultDuration is used for rooms
+// scheduled without a duration.
+const calendarDefaultDuration = time.Hour
+
+// icsTime is the UTC time format of iCalendar
+const icsTime = "20060102T150405Z"
+
+// icsEscape escapes a text value
+var icsEscape = strings.NewReplacer(
+	`\`, `\\`,
+	";", `\;`,
+	",", `\,`,
+	"\n", `\n`)
+
+// renderRoomsCalendar encodes the scheduled starts of the
+// rooms in the period as iCalendar events.
+func renderRoomsCalendar(
+	rooms []*store.Room,
+	from time.Time,
+	until time.Time,
+) ([]byte, error) {
+	buf := &bytes.Buffer{}
+	line := func(format string, args ...interface{}) {
+		fmt.Fprintf(buf, format+"\r\n", args...)
+	}
+	stamp := from.UTC().Format(icsTime)
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//b3scale//rooms//EN")
+	for _, room := range rooms {
+		starts, err := room.Occurrences(from, until)
+		if err != nil {
+			return nil, err
+		}
+		duration := time.Duration(room.Duration) * time.Minute
+		if duration == 0 {
+			duration = calendarDefaultDuration
+		}
+		name := room.Name
+		if name == "" {
+			name = room.MeetingID
+		}
+		for _, start := range starts {
+			line("BEGIN:VEVENT")
+			line("UID:%s-%s@b3scale", room.ID, start.Format(icsTime))
+			line("DTSTAMP:%s", stamp)
+			line("DTSTART:%s", start.Format(icsTime))
+			line("DTEND:%s", start.Add(duration).Format(icsTime))
+			line("SUMMARY:%s", icsEscape.Replace(name))
+			line("DESCRIPTION:%s", icsEscape.Replace(
+				"Meeting ID: "+room.MeetingID))
+			line("END:VEVENT")
+		}
+	}
+	line("END:VCALENDAR")
+	return buf.Bytes(), nil
+}
+
+// FrontendRoomsCalendar retrieves the upcoming starts of
+// the scheduled rooms of the frontend as iCalendar feed.
+func FrontendRoomsCalendar(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	frontend, err := scopedFrontend(c, tx)
+	if err != nil {
+		return err
+	}
+	if frontend == nil {
+		return echo.ErrNotFound
+	}
+	rooms, err := store.GetRooms(cctx, tx, store.Q().
+		Where("frontend_id = ?", frontend.ID).
+		Where("schedule <> ''").
+		OrderBy(store.CreationOrder("rooms")))
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	ics, err := renderRoomsCalendar(rooms, now, now.Add(calendarPeriod))
+	if err != nil {
+		return err
+	}
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", ics)
+}
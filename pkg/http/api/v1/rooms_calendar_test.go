@@ -0,0 +1,43 @@
+package v1
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestRenderRoomsCalendar(t *testing.T) {
+	rooms := []*store.Room{
+		{
+			ID:        "room1",
+			MeetingID: "lecture",
+			Name:      "Lecture; Part 1, 2",
+			Schedule:  "0 10 * * 1",
+			Timezone:  "UTC",
+			Duration:  90,
+		},
+	}
+	// A wednesday
+	from := time.Date(2021, 6, 2, 10, 17, 42, 0, time.UTC)
+	ics, err := renderRoomsCalendar(rooms, from, from.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cal := string(ics)
+	t.Log(cal)
+
+	if n := strings.Count(cal, "BEGIN:VEVENT"); n != 2 {
+		t.Error("expected 2 events, got:", n)
+	}
+	if !strings.Contains(cal, "DTSTART:20210607T100000Z\r\n") {
+		t.Error("missing first start")
+	}
+	if !strings.Contains(cal, "DTEND:20210607T113000Z\r\n") {
+		t.Error("missing first end")
+	}
+	if !strings.Contains(cal, `SUMMARY:Lecture\; Part 1\, 2`) {
+		t.Error("summary should be escaped")
+	}
+}
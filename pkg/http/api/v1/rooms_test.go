@@ -0,0 +1,65 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestFrontendRoomCreate(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	f, err := CreateTestFrontend()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"meeting_id": "weekly",
+		"name":       "Weekly Meeting",
+		"params": map[string]string{
+			"welcome": "Welcome!",
+		},
+	})
+	req, _ := http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user23", []string{})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+
+	if err := FrontendRoomCreate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	room := &store.Room{}
+	if err := readJSONResponse(res, room); err != nil {
+		t.Fatal(err)
+	}
+	if room.ModeratorPW == "" || room.AttendeePW == "" {
+		t.Error("passwords should be generated")
+	}
+	if room.FrontendID != f.ID {
+		t.Error("unexpected frontend:", room.FrontendID)
+	}
+
+	// The meeting ID must be unique
+	req, _ = http.NewRequest("POST", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+	ctx, _ = MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "user23", []string{})
+	ctx.Context.SetParamNames("id")
+	ctx.Context.SetParamValues(f.ID)
+	if err := FrontendRoomCreate(ctx); err != ErrRoomExists {
+		t.Error("expected a conflict, got:", err)
+	}
+}
@@ -0,0 +1,70 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ExplainRequest is a hypothetical request
+// to the BBB API of a frontend.
+type ExplainRequest struct {
+	Frontend string            `json:"frontend"`
+	Resource string            `json:"resource"`
+	Params   map[string]string `json:"params"`
+}
+
+// RoutingExplain returns the backend candidates for a
+// hypothetical request and how each routing middleware
+// filtered and ordered them. Nothing is routed.
+// ! requires: `admin`
+func RoutingExplain(router *cluster.Router) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		ctx := c.(*APIContext)
+		reqCtx := ctx.Ctx()
+
+		explain := &ExplainRequest{}
+		if err := c.Bind(explain); err != nil {
+			return err
+		}
+		if explain.Resource == "" {
+			explain.Resource = bbb.ResourceCreate
+		}
+
+		req := &bbb.Request{
+			Request: &http.Request{
+				Method: http.MethodGet,
+				Header: http.Header{},
+			},
+			Resource: explain.Resource,
+			Params:   bbb.Params(explain.Params),
+		}
+		if req.Params == nil {
+			req.Params = bbb.Params{}
+		}
+
+		if explain.Frontend != "" {
+			frontend, err := cluster.GetFrontend(reqCtx, store.Q().
+				Where("key = ?", explain.Frontend))
+			if err != nil {
+				return err
+			}
+			if frontend == nil {
+				return echo.NewHTTPError(
+					http.StatusNotFound, "frontend not found")
+			}
+			req.Frontend = frontend.Frontend()
+			reqCtx = cluster.ContextWithFrontend(reqCtx, frontend)
+		}
+
+		res, err := router.Explain(reqCtx, req)
+		if err != nil {
+			return err
+		}
+		return c.JSON(http.StatusOK, res)
+	}
+}
@@ -0,0 +1,101 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// CommandScheduleRequest declares a recurring command
+type CommandScheduleRequest struct {
+	Cron string `json:"cron"`
+	cluster.CommandRequest
+}
+
+// SchedulesList retrieves all command schedules
+// ! requires: `admin`
+func SchedulesList(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	schedules, err := store.GetCommandSchedules(reqCtx, tx, store.Q().
+		OrderBy("name ASC"))
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, schedules)
+}
+
+// ScheduleUpdate creates or replaces the command
+// schedule identified by name.
+// ! requires: `admin`
+func ScheduleUpdate(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	req := &CommandScheduleRequest{}
+	if err := c.Bind(req); err != nil {
+		return err
+	}
+	schedule, err := cluster.NewCommandSchedule(
+		c.Param("name"), req.Cron, &req.CommandRequest, time.Now())
+	if err != nil {
+		return err
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	if err := schedule.Save(reqCtx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
+
+// ScheduleDestroy removes a command schedule
+// ! requires: `admin`
+func ScheduleDestroy(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	schedule, err := store.GetCommandSchedule(reqCtx, tx, store.Q().
+		Where("name = ?", c.Param("name")))
+	if err != nil {
+		return err
+	}
+	if schedule == nil {
+		return echo.ErrNotFound
+	}
+	if err := store.DeleteCommandSchedule(reqCtx, tx, schedule.Name); err != nil {
+		return err
+	}
+	if err := tx.Commit(reqCtx); err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, schedule)
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestScheduleUpdateAndDestroy(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+
+	body, _ := json.Marshal(map[string]interface{}{
+		"cron":       "@weekly",
+		"action":     cluster.CmdSyncBackendMeetings,
+		"backend_id": "backend23",
+	})
+	req, _ := http.NewRequest("PUT", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("name")
+	ctx.Context.SetParamValues("weekly-sync")
+	if err := ScheduleUpdate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res := rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+	schedule := &store.CommandSchedule{}
+	if err := readJSONResponse(res, schedule); err != nil {
+		t.Fatal(err)
+	}
+	if schedule.NextRunAt.IsZero() {
+		t.Error("next run should be set")
+	}
+
+	ctx, rec = MakeTestContext(nil)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("name")
+	ctx.Context.SetParamValues("weekly-sync")
+	if err := ScheduleDestroy(ctx); err != nil {
+		t.Fatal(err)
+	}
+	res = rec.Result()
+	if res.StatusCode != http.StatusOK {
+		t.Error("unexpected status code:", res.StatusCode)
+	}
+}
+
+func TestScheduleUpdateInvalidCron(t *testing.T) {
+	body, _ := json.Marshal(map[string]interface{}{
+		"cron":       "every night",
+		"action":     cluster.CmdSyncBackendMeetings,
+		"backend_id": "backend23",
+	})
+	req, _ := http.NewRequest("PUT", "http:///", bytes.NewBuffer(body))
+	req.Header.Set("content-type", "application/json")
+
+	ctx, _ := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+	ctx.Context.SetParamNames("name")
+	ctx.Context.SetParamValues("nightly-sync")
+	err := ScheduleUpdate(ctx)
+	if _, ok := err.(store.ValidationError); !ok {
+		t.Error("expected a validation error, got:", err)
+	}
+}
@@ -0,0 +1,68 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Number of search results
+const (
+	searchLimit    = 20
+	searchLimitMax = 100
+)
+
+// Search finds meetings and recordings by name,
+// meeting ID and metadata. The results are ordered
+// by relevance and can be restricted to a `type`.
+// ! requires: `admin`
+func Search(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	query := store.SearchQuery(c.QueryParam("q"))
+	if query == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "missing query")
+	}
+	limit := searchLimit
+	if l := c.QueryParam("limit"); l != "" {
+		n, err := strconv.Atoi(l)
+		if err != nil || n <= 0 || n > searchLimitMax {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid limit")
+		}
+		limit = n
+	}
+	resultType := c.QueryParam("type")
+	switch resultType {
+	case "", store.SearchResultMeeting, store.SearchResultRecording:
+	default:
+		return echo.NewHTTPError(http.StatusBadRequest, "invalid type")
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	results := []*store.SearchResult{}
+	if resultType != store.SearchResultRecording {
+		meetings, err := store.SearchMeetings(reqCtx, tx, query, limit)
+		if err != nil {
+			return err
+		}
+		results = append(results, meetings...)
+	}
+	if resultType != store.SearchResultMeeting {
+		recordings, err := store.SearchRecordings(reqCtx, tx, query, limit)
+		if err != nil {
+			return err
+		}
+		results = append(results, recordings...)
+	}
+	return c.JSON(http.StatusOK, store.SortSearchResults(results, limit))
+}
@@ -0,0 +1,78 @@
+package v1
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestSearch(t *testing.T) {
+	if err := ClearState(); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := CreateTestBackend()
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, _ := MakeTestContext(nil)
+	cctx := ctx.Ctx()
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m := store.InitMeetingState(&store.MeetingState{
+		BackendID: &backend.ID,
+		Meeting: &bbb.Meeting{
+			MeetingID:         uuid.New().String(),
+			InternalMeetingID: uuid.New().String(),
+			MeetingName:       "Department Assembly",
+		},
+	})
+	if err := m.Save(cctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(cctx); err != nil {
+		t.Fatal(err)
+	}
+	ctx.Release()
+
+	u, _ := url.Parse("http:///?q=assem&type=meeting")
+	req := &http.Request{
+		URL: u,
+	}
+	ctx, rec := MakeTestContext(req)
+	defer ctx.Release()
+	ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+
+	if err := Search(ctx); err != nil {
+		t.Fatal(err)
+	}
+	results := []*store.SearchResult{}
+	if err := json.NewDecoder(rec.Result().Body).Decode(&results); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].ID != m.ID {
+		t.Error("unexpected results:", results)
+	}
+}
+
+func TestSearchInvalid(t *testing.T) {
+	for _, q := range []string{"", "q=%26%21", "q=foo&limit=0", "q=foo&type=backend"} {
+		u, _ := url.Parse("http:///?" + q)
+		req := &http.Request{
+			URL: u,
+		}
+		ctx, _ := MakeTestContext(req)
+		ctx = AuthorizeTestContext(ctx, "admin42", []string{ScopeAdmin})
+		if err := Search(ctx); err == nil {
+			t.Error("expected an error for:", q)
+		}
+		ctx.Release()
+	}
+}
@@ -0,0 +1,44 @@
+package v1
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ClusterTopology retrieves the backends with their
+// meetings, versions, tags and health in one document.
+// The backends can be filtered by backend_id, backend_host
+// or a tag.
+// ! requires: `admin`
+func ClusterTopology(c echo.Context) error {
+	ctx := c.(*APIContext)
+	cctx := ctx.Ctx()
+
+	q := store.Q()
+	if id := strings.TrimSpace(c.QueryParam("backend_id")); id != "" {
+		q = q.Where("backends.id = ?", id)
+	}
+	if host := strings.TrimSpace(c.QueryParam("backend_host")); host != "" {
+		q = q.Where("backends.host = ?", host)
+	}
+	if tag := strings.TrimSpace(c.QueryParam("tag")); tag != "" {
+		q = q.Where("backends.settings->'tags' ?? ?", tag)
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(cctx).Begin(cctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(cctx)
+
+	topology, err := store.GetClusterTopology(cctx, tx, q)
+	if err != nil {
+		return err
+	}
+	return c.JSON(http.StatusOK, topology)
+}
@@ -0,0 +1,82 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// usageDays is the default number of days
+// of the usage report.
+const usageDays = 30
+
+// FrontendsUsage retrieves the number of requests of
+// the frontends per day and resource. The results can
+// be filtered by frontend id or key and are limited
+// to the last days. With `rollup=true` the usage of
+// frontends with a parent is reported as usage of
+// the parent.
+// ! requires: `admin`
+func FrontendsUsage(c echo.Context) error {
+	ctx := c.(*APIContext)
+	reqCtx := ctx.Ctx()
+
+	days := usageDays
+	if d := c.QueryParam("days"); d != "" {
+		n, err := strconv.Atoi(d)
+		if err != nil || n <= 0 {
+			return echo.NewHTTPError(http.StatusBadRequest, "invalid days")
+		}
+		days = n
+	}
+	since := time.Now().UTC().AddDate(0, 0, -(days - 1))
+	rollup := c.QueryParam("rollup") == "true"
+
+	q := store.Q().
+		Where("frontend_usage.day >= ?::date", since.Format("2006-01-02"))
+	if id := c.QueryParam("frontend_id"); id != "" {
+		if rollup {
+			q = q.Where(`(frontend_usage.frontend_id = ?
+				OR frontends.parent_id = ?)`, id, id)
+		} else {
+			q = q.Where("frontend_usage.frontend_id = ?", id)
+		}
+	}
+	if key := c.QueryParam("frontend_key"); key != "" {
+		if rollup {
+			q = q.Where(`(frontends.key = ?
+				OR frontends.parent_id IN (
+					SELECT p.id FROM frontends AS p WHERE p.key = ?))`,
+				key, key)
+		} else {
+			q = q.Where("frontends.key = ?", key)
+		}
+	}
+	if resource := c.QueryParam("resource"); resource != "" {
+		q = q.Where("frontend_usage.resource = ?", resource)
+	}
+
+	// Begin TX
+	tx, err := store.ConnectionFromContext(reqCtx).Begin(reqCtx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(reqCtx)
+
+	usage, err := store.GetFrontendUsage(reqCtx, tx, q)
+	if err != nil {
+		return err
+	}
+	if rollup {
+		frontends, err := store.GetFrontendStates(reqCtx, tx, store.Q())
+		if err != nil {
+			return err
+		}
+		usage = store.RollupFrontendUsage(usage, frontends)
+	}
+	return c.JSON(http.StatusOK, usage)
+}
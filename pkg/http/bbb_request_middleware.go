@@ -4,16 +4,20 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net"
 	netHTTP "net/http"
 	"strings"
 	"time"
 
 	"github.com/labstack/echo/v4"
-	//	"github.com/rs/zerolog/log"
+	"github.com/rs/zerolog/log"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
+	"gitlab.com/infra.run/public/b3scale/pkg/middlewares/requests"
 	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
@@ -38,7 +42,8 @@ func BBBRequestMiddleware(
 			// backend.
 			// ctx := c.Request().Context()
 
-			ctx, cancel := context.WithTimeout(context.Background(), 60*time.Second)
+			ctx, cancel := context.WithTimeout(
+				context.Background(), bbbRequestTimeout())
 			defer cancel()
 
 			path := c.Path()
@@ -46,23 +51,35 @@ func BBBRequestMiddleware(
 				return next(c) // nothing to do here.
 			}
 
+			// Requests to the backends may take longer
+			// than the write timeout of the server.
+			deadline := time.Now().Add(bbbRequestTimeout())
+			if err := v1.SetWriteDeadline(c.Response(), deadline); err != nil {
+				log.Debug().Err(err).Msg("could not extend the write deadline")
+			}
+
 			// We acquire a connection to the database here,
 			// if this fails it does not really make sense to move on.
 			// TODO: See if we actually can use this context.
 			conn, err := store.Acquire(ctx)
 			if err != nil {
+				if cluster.Joins.Serving() {
+					return serveCachedJoin(ctx, c, path[len(mountPoint):], err)
+				}
 				return err
 			}
 			defer conn.Release()
 			ctx = store.ContextWithConnection(ctx, conn)
+			ctx = cluster.ContextWithRequestID(ctx,
+				c.Response().Header().Get(echo.HeaderXRequestID))
 
 			// Decode HTTP request into a BBB request
 			// and verify it.
 			path = path[len(mountPoint):]
 			frontendKey, resource := decodePath(path)
 
-			frontend, err := cluster.GetFrontend(ctx, store.Q().
-				Where("key = ?", frontendKey))
+			// The key may be an additional key of the frontend.
+			frontend, alias, err := cluster.GetFrontendByKey(ctx, frontendKey)
 			if err != nil {
 				return handleAPIError(c, err)
 			}
@@ -74,6 +91,17 @@ func BBBRequestMiddleware(
 			}
 			ctx = cluster.ContextWithFrontend(ctx, frontend)
 
+			// The frontend may be restricted to trusted networks,
+			// even if the checksum is valid.
+			ip := c.RealIP()
+			if !frontend.Settings().AllowsIP(net.ParseIP(ip)) {
+				log.Warn().
+					Str("frontend", frontendKey).
+					Str("ip", ip).
+					Msg("rejected request from network not allowed")
+				return handleNetworkNotAllowed(c)
+			}
+
 			// We have an action, we have a frontend, now
 			// we need the query parameters and request body.
 			params := decodeParams(c)
@@ -89,10 +117,10 @@ func BBBRequestMiddleware(
 				Checksum: checksum,
 			}
 
-			// Authenticate request
-			if err := bbbReq.Verify(); err != nil {
+			if err := authenticateRequest(bbbReq, frontend, alias); err != nil {
 				return handleAPIError(c, err)
 			}
+			cluster.Joins.PutFrontend(frontendKey, frontend, alias)
 
 			// Before we dispatch, let's check if the original
 			// request context is still valid
@@ -107,9 +135,98 @@ func BBBRequestMiddleware(
 	}
 }
 
+// authenticateRequest verifies the checksum of the request.
+// After a secret rotation the previous secret is accepted
+// for a while. Requests with an additional key are signed
+// with its secret, but are handled like requests of
+// the frontend.
+func authenticateRequest(
+	req *bbb.Request,
+	frontend *cluster.Frontend,
+	alias *store.FrontendKey,
+) error {
+	if alias != nil {
+		return req.VerifySecret(alias.Secret)
+	}
+	err := req.Verify()
+	if err == nil {
+		return nil
+	}
+	prev, ok := frontend.PreviousSecret()
+	if !ok || req.VerifySecret(prev) != nil {
+		return err
+	}
+	return nil
+}
+
+// serveCachedJoin handles a join from the join cache
+// while the database is unavailable. All other requests
+// fail with the database error.
+func serveCachedJoin(
+	ctx context.Context,
+	c echo.Context,
+	path string,
+	dbErr error,
+) error {
+	frontendKey, resource := decodePath(path)
+	if resource != bbb.ResourceJoin {
+		return dbErr
+	}
+	frontend, alias := cluster.Joins.Frontend(frontendKey)
+	if frontend == nil {
+		return dbErr
+	}
+	ip := c.RealIP()
+	if !frontend.Settings().AllowsIP(net.ParseIP(ip)) {
+		return handleNetworkNotAllowed(c)
+	}
+	params := decodeParams(c)
+	checksum, _ := params.Checksum()
+	req := &bbb.Request{
+		Request:  c.Request(),
+		Frontend: frontend.Frontend(),
+		Resource: resource,
+		Params:   params,
+		Checksum: checksum,
+	}
+	if err := authenticateRequest(req, frontend, alias); err != nil {
+		return handleAPIError(c, err)
+	}
+
+	// The meetings are cached and known to the backend by
+	// the meeting ID rewritten in the gateway.
+	join := requests.RewriteUniqueMeetingID()(func(
+		ctx context.Context,
+		req *bbb.Request,
+	) (bbb.Response, error) {
+		meetingID, _ := req.Params.MeetingID()
+		backend := cluster.Joins.Backend(frontend.ID(), meetingID)
+		if backend == nil {
+			return nil, dbErr
+		}
+		log.Warn().
+			Err(dbErr).
+			Str("frontend", frontendKey).
+			Str("meetingID", meetingID).
+			Str("backend", backend.Host()).
+			Msg("database unavailable, serving join from cache")
+		return backend.Join(ctx, req)
+	})
+	res, err := join(ctx, req)
+	if err != nil {
+		return err
+	}
+	return writeBBBResponse(c, res)
+}
+
 // writeBBBResponse takes a response from the cluster
 // and writes it as a response to the request.
 func writeBBBResponse(c echo.Context, res bbb.Response) error {
+	// Streamed responses hold the backend response
+	if closer, ok := res.(io.Closer); ok {
+		defer closer.Close()
+	}
+
 	// Check if the context is still valid
 	if err := c.Request().Context().Err(); err != nil {
 		return err
@@ -127,6 +244,14 @@ func writeBBBResponse(c echo.Context, res bbb.Response) error {
 			c.Response().Header().Add(key, v)
 		}
 	}
+
+	// Large responses are encoded while sending. The length
+	// is not known in advance, so the response is chunked.
+	if streamer, ok := res.(bbb.ResponseStreamer); ok {
+		c.Response().Header().Del(echo.HeaderContentLength)
+		c.Response().WriteHeader(status)
+		return streamer.Stream(c.Response())
+	}
 	c.Response().WriteHeader(status)
 
 	// Serialize BBB response and send
@@ -163,6 +288,17 @@ func handleAPIError(c echo.Context, err error) error {
 	return c.XML(netHTTP.StatusInternalServerError, res)
 }
 
+// handleNetworkNotAllowed responds to requests from
+// addresses outside the allowed networks of the frontend.
+func handleNetworkNotAllowed(c echo.Context) error {
+	res := &bbb.XMLResponse{
+		Returncode: bbb.RetFailed,
+		Message:    "requests from this network are not allowed",
+		MessageKey: "b3scaleNetworkNotAllowed",
+	}
+	return c.XML(netHTTP.StatusForbidden, res)
+}
+
 // readRequestBody will load the entire request body.
 func readRequestBody(c echo.Context) []byte {
 	body := []byte{}
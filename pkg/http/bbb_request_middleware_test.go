@@ -1,7 +1,21 @@
 package http
 
 import (
+	"context"
+	netHTTP "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/middlewares/requests"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
 func TestDecodePath(t *testing.T) {
@@ -14,3 +28,145 @@ func TestDecodePath(t *testing.T) {
 		t.Error("unexepcted action:", action)
 	}
 }
+
+func TestWriteBBBResponseStreamed(t *testing.T) {
+	res := &bbb.GetRecordingsResponse{
+		XMLResponse: &bbb.XMLResponse{
+			Returncode: bbb.RetSuccess,
+		},
+		Recordings: []*bbb.Recording{
+			{RecordID: "rec1"},
+		},
+	}
+	res.SetStatus(netHTTP.StatusOK)
+	res.Header().Set("Content-Length", "23")
+
+	req := httptest.NewRequest(netHTTP.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	if err := writeBBBResponse(c, res); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != netHTTP.StatusOK {
+		t.Error("unexpected status:", rec.Code)
+	}
+	if rec.Header().Get("Content-Length") != "" {
+		t.Error("unexpected content length:", rec.Header())
+	}
+	if !strings.Contains(rec.Body.String(), "<recordID>rec1</recordID>") {
+		t.Error("unexpected body:", rec.Body.String())
+	}
+}
+
+func TestWriteBBBResponseChunked(t *testing.T) {
+	res := &bbb.GetRecordingsResponse{
+		XMLResponse: &bbb.XMLResponse{
+			Returncode: bbb.RetSuccess,
+		},
+		Recordings: []*bbb.Recording{
+			{RecordID: "rec1"},
+			{RecordID: "rec2"},
+		},
+	}
+	res.SetStatus(netHTTP.StatusOK)
+
+	e := echo.New()
+	e.GET("/", func(c echo.Context) error {
+		return writeBBBResponse(c, res)
+	})
+	srv := httptest.NewServer(e)
+	defer srv.Close()
+
+	httpRes, err := netHTTP.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer httpRes.Body.Close()
+	if len(httpRes.TransferEncoding) != 1 ||
+		httpRes.TransferEncoding[0] != "chunked" {
+		t.Error("unexpected transfer encoding:", httpRes.TransferEncoding)
+	}
+	decoded, err := bbb.DecodeGetRecordingsResponse(httpRes.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Recordings) != 2 {
+		t.Error("unexpected recordings:", decoded.Recordings)
+	}
+}
+
+func TestBBBRequestMiddlewareCachedJoin(t *testing.T) {
+	cluster.JoinCacheTTL = time.Minute
+	defer func() { cluster.JoinCacheTTL = 0 }()
+
+	frontend := cluster.NewFrontend(&store.FrontendState{
+		ID:       "frontend1",
+		Frontend: &bbb.Frontend{Key: "frontend1", Secret: "secret"},
+	})
+	backend := clustertest.NewBackendWithHost(
+		"backend1", "https://bbb1.example.net/bigbluebutton/api/",
+		store.BackendSettings{})
+
+	// While the database is available, the meeting is
+	// remembered after the gateway rewrote the meeting ID.
+	remember := requests.RewriteUniqueMeetingID()(func(
+		ctx context.Context,
+		req *bbb.Request,
+	) (bbb.Response, error) {
+		meetingID, _ := req.Params.MeetingID()
+		cluster.Joins.PutMeeting(meetingID, frontend.ID(), backend)
+		return &bbb.JoinResponse{XMLResponse: new(bbb.XMLResponse)}, nil
+	})
+	cluster.Joins.PutFrontend("frontend1", frontend, nil)
+	if _, err := remember(context.Background(), &bbb.Request{
+		Resource: bbb.ResourceJoin,
+		Frontend: frontend.Frontend(),
+		Params:   bbb.Params{"meetingID": "meeting1"},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The database is not connected in this test
+	signed := (&bbb.Request{
+		Resource: bbb.ResourceJoin,
+		Params: bbb.Params{
+			"meetingID": "meeting1",
+			"fullName":  "Jane",
+		},
+		Backend: &bbb.Backend{
+			Host:   "http://b3scale/bbb/frontend1/bigbluebutton/api/",
+			Secret: "secret",
+		},
+	}).URL()
+	req := httptest.NewRequest(netHTTP.MethodGet, signed, nil)
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath(req.URL.Path)
+
+	handler := BBBRequestMiddleware("/bbb", nil, nil)(
+		func(c echo.Context) error {
+			return echo.ErrNotFound
+		})
+	if err := handler(c); err != nil {
+		t.Fatal(err)
+	}
+
+	if rec.Code != netHTTP.StatusFound {
+		t.Fatal("unexpected status:", rec.Code, rec.Body.String())
+	}
+	location, err := url.Parse(rec.Header().Get("location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if location.Host != "bbb1.example.net" {
+		t.Error("unexpected backend:", location)
+	}
+	expected := (&requests.FrontendKeyMeetingID{
+		FrontendKey: "frontend1",
+		MeetingID:   "meeting1",
+	}).EncodeToString()
+	if id := location.Query().Get("meetingID"); id != expected {
+		t.Error("unexpected meeting id:", id)
+	}
+}
@@ -0,0 +1,78 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// parseForwardedIP parses an address from the X-Forwarded-For
+// header. Some proxies add a port or enclose IPv6 addresses
+// in brackets, e.g. [2001:db8::1]:4711. Zones are removed.
+func parseForwardedIP(s string) net.IP {
+	s = strings.TrimSpace(s)
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	if i := strings.IndexByte(s, '%'); i >= 0 {
+		s = s[:i]
+	}
+	return net.ParseIP(s)
+}
+
+// TrustedProxies are the networks of proxies allowed
+// to set the X-Forwarded-For header. By default only
+// a proxy on the same host, like a local nginx, is
+// trusted.
+var TrustedProxies = []*net.IPNet{
+	{IP: net.IPv4(127, 0, 0, 0).To4(), Mask: net.CIDRMask(8, 32)},
+	{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+}
+
+// isTrustedProxy is true for addresses
+// in the trusted proxy networks.
+func isTrustedProxy(ip net.IP) bool {
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+	}
+	for _, network := range TrustedProxies {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractClientIP is an echo.IPExtractor taking the client
+// address from the X-Forwarded-For header if the request was
+// passed by TrustedProxies. The header is evaluated from the
+// right, the first untrusted address is the client.
+// Addresses are normalized, so IPv4-mapped IPv6 addresses
+// are returned as IPv4 addresses.
+func extractClientIP(req *http.Request) string {
+	direct := parseForwardedIP(req.RemoteAddr)
+	if direct == nil {
+		return ""
+	}
+	xffs := req.Header[echo.HeaderXForwardedFor]
+	if len(xffs) == 0 || !isTrustedProxy(direct) {
+		return direct.String()
+	}
+	forwarded := strings.Split(strings.Join(xffs, ","), ",")
+	client := direct
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		ip := parseForwardedIP(forwarded[i])
+		if ip == nil {
+			// The remaining addresses can not be trusted
+			return client.String()
+		}
+		client = ip
+		if !isTrustedProxy(ip) {
+			break
+		}
+	}
+	return client.String()
+}
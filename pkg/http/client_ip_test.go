@@ -0,0 +1,76 @@
+package http
+
+import (
+	"net"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+func TestExtractClientIP(t *testing.T) {
+	tests := []struct {
+		remote   string
+		xff      string
+		expected string
+	}{
+		{"198.51.100.1:4711", "", "198.51.100.1"},
+		{"[2001:db8::1]:4711", "", "2001:db8::1"},
+		{"[::ffff:198.51.100.1]:4711", "", "198.51.100.1"},
+		// Untrusted proxy
+		{"198.51.100.1:4711", "192.0.2.1", "198.51.100.1"},
+		// Local proxies
+		{"127.0.0.1:4711", "192.0.2.1", "192.0.2.1"},
+		{"[::1]:4711", "2001:db8::23", "2001:db8::23"},
+		{"[::1]:4711", "[2001:db8::23]:4711", "2001:db8::23"},
+		{"[::1]:4711", "192.0.2.1:4711", "192.0.2.1"},
+		{"[::1]:4711", "fe80::1%eth0", "fe80::1"},
+		// Proxies in private networks are not trusted by default
+		{"[fd00::1]:4711", "2001:db8::23", "fd00::1"},
+		{"10.0.0.1:4711", "2001:db8::23", "10.0.0.1"},
+		// Invalid forwarded addresses are not trusted
+		{"127.0.0.1:4711", "garbage, 192.0.2.1", "192.0.2.1"},
+		{"127.0.0.1:4711", "192.0.2.1, garbage", "127.0.0.1"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remote
+		if test.xff != "" {
+			req.Header.Set("X-Forwarded-For", test.xff)
+		}
+		if ip := extractClientIP(req); ip != test.expected {
+			t.Error("unexpected client ip for", test, ":", ip)
+		}
+	}
+}
+
+func TestExtractClientIPTrustedProxies(t *testing.T) {
+	defer func(networks []*net.IPNet) {
+		TrustedProxies = networks
+	}(TrustedProxies)
+
+	networks, err := config.ParseNetworkList("10.0.0.0/8, fd00::/8")
+	if err != nil {
+		t.Fatal(err)
+	}
+	TrustedProxies = networks
+
+	tests := []struct {
+		remote   string
+		xff      string
+		expected string
+	}{
+		{"[fd00::1]:4711", "2001:db8::23, fd00::2", "2001:db8::23"},
+		{"10.0.0.1:4711", "2001:db8::42, 2001:db8::23", "2001:db8::23"},
+		// Loopback is no longer trusted
+		{"127.0.0.1:4711", "192.0.2.1", "127.0.0.1"},
+	}
+	for _, test := range tests {
+		req, _ := http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = test.remote
+		req.Header.Set("X-Forwarded-For", test.xff)
+		if ip := extractClientIP(req); ip != test.expected {
+			t.Error("unexpected client ip for", test, ":", ip)
+		}
+	}
+}
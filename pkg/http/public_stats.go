@@ -0,0 +1,134 @@
+package http
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/labstack/echo/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// PublicStats are the anonymized statistics
+// of the cluster.
+type PublicStats struct {
+	GeneratedAt time.Time `json:"generated_at"`
+
+	// Rounding is the precision of the counts
+	Rounding int `json:"rounding"`
+
+	Meetings      int                `json:"meetings"`
+	Attendees     int                `json:"attendees"`
+	MeetingsTotal int64              `json:"meetings_total"`
+	Hours         []*PublicStatsHour `json:"hours"`
+}
+
+// PublicStatsHour is the anonymized number
+// of meetings running in an hour.
+type PublicStatsHour struct {
+	Hour     time.Time `json:"hour"`
+	Meetings int       `json:"meetings"`
+}
+
+// anonymize adds noise to the count and rounds
+// it to a multiple. Counts are never negative.
+func anonymize(n int64, p *config.PublicStatsPolicy, rnd *rand.Rand) int64 {
+	if p.Noise > 0 {
+		n += rnd.Int63n(int64(2*p.Noise+1)) - int64(p.Noise)
+	}
+	round := int64(p.Round)
+	n = (n + round/2) / round * round
+	if n < 0 {
+		return 0
+	}
+	return n
+}
+
+// anonymizePublicStats applies the policy
+// to the statistics of the store.
+func anonymizePublicStats(
+	stats *store.PublicStats,
+	p *config.PublicStatsPolicy,
+	rnd *rand.Rand,
+	now time.Time,
+) *PublicStats {
+	res := &PublicStats{
+		GeneratedAt:   now.UTC(),
+		Rounding:      p.Round,
+		Meetings:      int(anonymize(int64(stats.Meetings), p, rnd)),
+		Attendees:     int(anonymize(int64(stats.Attendees), p, rnd)),
+		MeetingsTotal: anonymize(stats.MeetingsTotal, p, rnd),
+		Hours:         make([]*PublicStatsHour, 0, len(stats.Hours)),
+	}
+	for _, h := range stats.Hours {
+		res.Hours = append(res.Hours, &PublicStatsHour{
+			Hour:     h.Hour.UTC(),
+			Meetings: int(anonymize(int64(h.Meetings), p, rnd)),
+		})
+	}
+	return res
+}
+
+// publicStatsHandler serves the anonymized statistics.
+// The statistics are cached for the duration of the
+// policy, so the noise can not be averaged out.
+type publicStatsHandler struct {
+	policy *config.PublicStatsPolicy
+
+	mtx   sync.Mutex
+	rnd   *rand.Rand
+	stats *PublicStats
+}
+
+// newPublicStatsHandler creates the handler for the policy
+func newPublicStatsHandler(p *config.PublicStatsPolicy) *publicStatsHandler {
+	return &publicStatsHandler{
+		policy: p,
+		rnd:    rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// get returns the cached statistics or
+// aggregates the statistics of the store.
+func (h *publicStatsHandler) get(c echo.Context) (*PublicStats, error) {
+	h.mtx.Lock()
+	defer h.mtx.Unlock()
+	now := time.Now()
+	if h.stats != nil && now.Sub(h.stats.GeneratedAt) < h.policy.Cache {
+		return h.stats, nil
+	}
+
+	ctx := c.Request().Context()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+
+	since := now.Add(-time.Duration(h.policy.Hours-1) * time.Hour)
+	stats, err := store.GetPublicStats(ctx, tx, since)
+	if err != nil {
+		return nil, err
+	}
+	h.stats = anonymizePublicStats(stats, h.policy, h.rnd, now)
+	return h.stats, nil
+}
+
+// Handle serves the statistics to any origin,
+// e.g. for a transparency page.
+func (h *publicStatsHandler) Handle(c echo.Context) error {
+	stats, err := h.get(c)
+	if err != nil {
+		return err
+	}
+	c.Response().Header().Set("Access-Control-Allow-Origin", "*")
+	return c.JSON(http.StatusOK, stats)
+}
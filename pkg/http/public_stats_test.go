@@ -0,0 +1,92 @@
+package http
+
+import (
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestAnonymize(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	p := &config.PublicStatsPolicy{Round: 10}
+	tests := map[int64]int64{
+		0:   0,
+		4:   0,
+		5:   10,
+		123: 120,
+		125: 130,
+	}
+	for n, expected := range tests {
+		if a := anonymize(n, p, rnd); a != expected {
+			t.Error("unexpected count for", n, ":", a)
+		}
+	}
+
+	p = &config.PublicStatsPolicy{Round: 10, Noise: 20}
+	for i := 0; i < 1000; i++ {
+		a := anonymize(100, p, rnd)
+		if a < 80 || a > 120 || a%10 != 0 {
+			t.Fatal("unexpected anonymized count:", a)
+		}
+		if anonymize(0, p, rnd) < 0 {
+			t.Fatal("counts must not be negative")
+		}
+	}
+}
+
+func TestAnonymizePublicStats(t *testing.T) {
+	rnd := rand.New(rand.NewSource(42))
+	p := &config.PublicStatsPolicy{Round: 5}
+	hour := time.Date(2026, 10, 16, 8, 0, 0, 0, time.UTC)
+	stats := anonymizePublicStats(&store.PublicStats{
+		Meetings:      12,
+		Attendees:     341,
+		MeetingsTotal: 10007,
+		Hours: []*store.PublicStatsHour{
+			{Hour: hour, Meetings: 3},
+			{Hour: hour.Add(time.Hour), Meetings: 12},
+		},
+	}, p, rnd, hour)
+	if stats.Meetings != 10 || stats.Attendees != 340 {
+		t.Error("unexpected stats:", stats.Meetings, stats.Attendees)
+	}
+	if stats.MeetingsTotal != 10005 {
+		t.Error("unexpected total:", stats.MeetingsTotal)
+	}
+	if stats.Rounding != 5 || len(stats.Hours) != 2 {
+		t.Fatal("unexpected stats:", stats)
+	}
+	if stats.Hours[0].Meetings != 5 || stats.Hours[1].Meetings != 10 {
+		t.Error("unexpected hours:", stats.Hours[0], stats.Hours[1])
+	}
+}
+
+func TestPublicStatsHandlerCache(t *testing.T) {
+	h := newPublicStatsHandler(&config.PublicStatsPolicy{
+		Round: 10,
+		Hours: 24,
+		Cache: time.Minute,
+	})
+	h.stats = &PublicStats{
+		GeneratedAt: time.Now(),
+		Meetings:    20,
+	}
+
+	e := newEcho()
+	req := httptest.NewRequest(http.MethodGet, "/b3s/stats", nil)
+	rec := httptest.NewRecorder()
+	if err := h.Handle(e.NewContext(req, rec)); err != nil {
+		t.Fatal(err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Error("unexpected status:", rec.Code)
+	}
+	if rec.Header().Get("Access-Control-Allow-Origin") != "*" {
+		t.Error("expected the statistics to be shared with any origin")
+	}
+}
@@ -18,21 +18,50 @@ import (
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
 	"gitlab.com/infra.run/public/b3scale/pkg/config"
 	"gitlab.com/infra.run/public/b3scale/pkg/http/api/v1"
+	"gitlab.com/infra.run/public/b3scale/pkg/http/ui"
+	"gitlab.com/infra.run/public/b3scale/pkg/logging"
 	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
 	"gitlab.com/infra.run/public/b3scale/pkg/templates"
 )
 
 const (
 	// RequestTimeout until the request has to be finished
 	RequestTimeout = 60 * time.Second
+
+	// BBBRequestTimeoutMargin is added to the longest
+	// backend request timeout for the time a BBB request
+	// may take in total.
+	BBBRequestTimeoutMargin = 30 * time.Second
 )
 
+// bbbRequestTimeout is the ceiling of a BBB request.
+// It is longer than any request to a backend.
+func bbbRequestTimeout() time.Duration {
+	return bbb.MaxRequestTimeout() + BBBRequestTimeoutMargin
+}
+
 // Server provides the http server for the application.
 type Server struct {
 	serviceID  string
 	echo       *echo.Echo
+	admin      *echo.Echo
 	gateway    *cluster.Gateway
 	controller *cluster.Controller
+
+	listenAdmin string
+}
+
+// ServerOptions configure the http server
+type ServerOptions struct {
+	// ListenAdmin is the address of a separate listener
+	// for the REST API, the admin UI and the metrics.
+	// If empty, they are served with the BBB API.
+	ListenAdmin string
+
+	// PublicStats enables the anonymized statistics
+	// of the cluster at /b3s/stats.
+	PublicStats *config.PublicStatsPolicy
 }
 
 // NewServer configures and creates a new http interface
@@ -41,62 +70,142 @@ func NewServer(
 	serviceID string,
 	ctrl *cluster.Controller,
 	gateway *cluster.Gateway,
+	router *cluster.Router,
+	opts *ServerOptions,
 ) *Server {
-	logger := lecho.From(log.Logger)
-
-	// Setup and configure echo framework
-	e := echo.New()
-	e.HideBanner = true
-
-	// Middleware order: The middlewares are executed
-	// in order of Use.
-	e.Use(middleware.Recover())
-	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
-		Timeout: RequestTimeout,
-	}))
-	e.Use(lecho.Middleware(lecho.Config{
-		Logger: logger,
-	}))
+	e := newEcho()
+	admin := e
+	if opts.ListenAdmin != "" {
+		admin = newEcho()
+	}
 
 	// Prometheus Middleware - Find it under /metrics
 	p := prometheus.NewPrometheus(serviceID, nil)
-	p.Use(e)
+	e.Use(p.HandlerFunc)
+	if admin != e {
+		admin.Use(p.HandlerFunc)
+	}
+	p.SetMetricsPath(admin)
 
-	pclient.MustRegister(metrics.Collector{})
+	pclient.MustRegister(metrics.Collector{}, metrics.PoolCollector{})
+	pclient.MustRegister(metrics.CommandQueueCollector{})
+	pclient.MustRegister(metrics.PollRequests, metrics.DuplicateRequests)
+	pclient.MustRegister(metrics.OverloadQueued, metrics.OverloadRejected)
+	pclient.MustRegister(metrics.JoinLinksRejected)
+	pclient.MustRegister(bbb.BackendRequests, bbb.BackendTLSHandshakes)
+	pclient.MustRegister(store.QueryDurations, store.CommandsProcessed)
+	pclient.MustRegister(cluster.ProbeDurations, cluster.ProbeFailures)
 
 	// We handle BBB requests in a custom middleware
 	e.Use(BBBRequestMiddleware("/bbb", ctrl, gateway))
 
 	s := &Server{
-		echo:       e,
-		gateway:    gateway,
-		controller: ctrl,
+		echo:        e,
+		gateway:     gateway,
+		controller:  ctrl,
+		listenAdmin: opts.ListenAdmin,
+	}
+	if admin != e {
+		s.admin = admin
 	}
 
 	// Register routes
 	e.GET("/", s.httpIndex)
 	e.GET("/b3s/retry-join/:req", s.httpRetryJoin)
+	if opts.PublicStats != nil {
+		e.GET("/b3s/stats", newPublicStatsHandler(opts.PublicStats).Handle)
+	}
 
-	if err := v1.Init(e); err != nil {
+	if err := v1.Init(admin, router, gateway); err != nil {
 		log.Warn().Err(err).Msg("could not initialize rest API")
+	} else if err := ui.Init(admin); err != nil {
+		log.Warn().Err(err).Msg("could not initialize admin ui")
 	}
 
 	return s
 }
 
-// Start the HTTP interface
+// newEcho creates and configures an echo instance
+// with the common middlewares.
+func newEcho() *echo.Echo {
+	logger := lecho.From(log.Logger)
+
+	// Setup and configure echo framework
+	e := echo.New()
+	e.HideBanner = true
+
+	// The client address is taken from the X-Forwarded-For
+	// header only if the request was passed by one of the
+	// TrustedProxies, like a local nginx.
+	e.IPExtractor = extractClientIP
+
+	// Middleware order: The middlewares are executed
+	// in order of Use.
+	e.Use(middleware.Recover())
+	// Requests are identified by the X-Request-Id header,
+	// which is generated if not passed by a proxy.
+	e.Use(middleware.RequestID())
+	e.Use(middleware.TimeoutWithConfig(middleware.TimeoutConfig{
+		Timeout: RequestTimeout,
+		// Event streams are long lived and must be flushed.
+		// BBB requests are limited by the bbbRequestTimeout,
+		// as requests to the backends can take longer.
+		Skipper: func(c echo.Context) bool {
+			return c.Path() == "/api/v1/events" ||
+				strings.HasPrefix(c.Request().URL.Path, "/bbb/")
+		},
+	}))
+	e.Use(lecho.Middleware(lecho.Config{
+		Logger:  logger,
+		Skipper: sampleRequestLog("/bbb"),
+	}))
+
+	return e
+}
+
+// sampleRequestLog skips logging BBB requests
+// for resources with configured log sampling.
+func sampleRequestLog(mountPoint string) middleware.Skipper {
+	return func(c echo.Context) bool {
+		path := c.Request().URL.Path
+		if !strings.HasPrefix(path, mountPoint) {
+			return false
+		}
+		_, resource := decodePath(path[len(mountPoint):])
+		return !logging.Sampled(resource)
+	}
+}
+
+// Start the HTTP interface. The admin interface is
+// started on the separate listener, if configured.
 func (s *Server) Start(listen string) {
-	log.Info().Msg("starting interface: HTTP")
-	httpServer := &http.Server{
+	if s.admin != nil {
+		go s.startAdmin()
+	}
+	log.Info().Str("listen", listen).Msg("starting interface: HTTP")
+	log.Fatal().
+		Err(s.echo.StartServer(newHTTPServer(listen))).
+		Msg("starting http server")
+}
+
+// startAdmin starts the separate admin interface
+func (s *Server) startAdmin() {
+	log.Info().Str("listen", s.listenAdmin).Msg("starting interface: admin HTTP")
+	log.Fatal().
+		Err(s.admin.StartServer(newHTTPServer(s.listenAdmin))).
+		Msg("starting admin http server")
+}
+
+// newHTTPServer creates a http server with timeouts.
+// BBB requests and event streams override the write
+// deadline.
+func newHTTPServer(listen string) *http.Server {
+	return &http.Server{
 		Addr:              listen,
 		ReadHeaderTimeout: 5 * time.Second,
-		WriteTimeout:      60 * time.Second,
+		WriteTimeout:      RequestTimeout,
 		IdleTimeout:       120 * time.Second,
 	}
-
-	log.Fatal().
-		Err(s.echo.StartServer(httpServer)).
-		Msg("starting http server")
 }
 
 // StartCleartextHTTP2 starts a HTTP2 interface without
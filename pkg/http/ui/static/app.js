@@ -0,0 +1,229 @@
+// b3scale admin ui
+//
+// All data is retrieved from the REST API with the
+// admin token. The token is kept in the session storage.
+
+(function () {
+  "use strict";
+
+  const API = "../api/v1";
+  const TOKEN_KEY = "b3scale.token";
+
+  const $ = (id) => document.getElementById(id);
+
+  function token() {
+    return sessionStorage.getItem(TOKEN_KEY);
+  }
+
+  async function api(method, path, body) {
+    const opts = {
+      method: method,
+      headers: { "Authorization": "Bearer " + token() },
+    };
+    if (body !== undefined) {
+      opts.headers["Content-Type"] = "application/json";
+      opts.body = JSON.stringify(body);
+    }
+    const res = await fetch(API + path, opts);
+    if (res.status === 401) {
+      signOut();
+      throw new Error("the token was rejected");
+    }
+    const data = await res.json();
+    if (!res.ok) {
+      throw new Error(data.message || res.statusText);
+    }
+    return data;
+  }
+
+  // Rendering helpers
+  function el(tag, attrs, ...children) {
+    const e = document.createElement(tag);
+    for (const [k, v] of Object.entries(attrs || {})) {
+      if (k.startsWith("on")) {
+        e.addEventListener(k.substring(2), v);
+      } else {
+        e.setAttribute(k, v);
+      }
+    }
+    for (const c of children) {
+      if (c === null || c === undefined) {
+        continue;
+      }
+      e.append(c instanceof Node ? c : String(c));
+    }
+    return e;
+  }
+
+  function table(columns, rows) {
+    return el("table", {},
+      el("thead", {}, el("tr", {}, ...columns.map((c) => el("th", {}, c[0])))),
+      el("tbody", {}, ...rows.map((r) =>
+        el("tr", {}, ...columns.map((c) => el("td", {}, c[1](r)))))));
+  }
+
+  function state(s) {
+    return el("span", { class: "state-" + s }, s);
+  }
+
+  function time(t) {
+    if (!t) {
+      return "-";
+    }
+    return new Date(t).toLocaleString();
+  }
+
+  function logLinks(links) {
+    return el("span", {}, ...Object.keys(links || {}).sort().map((name) =>
+      el("a", {
+        href: links[name],
+        target: "_blank",
+        rel: "noopener noreferrer",
+        class: "log-link",
+      }, name)));
+  }
+
+  // Views
+  const views = {
+    async health() {
+      const [status, backends] = await Promise.all([
+        api("GET", ""), api("GET", "/backends")]);
+      const now = Date.now();
+      const alive = backends.filter(
+        (b) => now - new Date(b.agent_heartbeat).getTime() < 5000);
+      const facts = [
+        ["Version", status.version + " (" + status.build + ")"],
+        ["API", status.api],
+        ["Backends", backends.length],
+        ["Node agents alive", alive.length],
+        ["Meetings", backends.reduce((n, b) => n + b.meetings_count, 0)],
+        ["Attendees", backends.reduce((n, b) => n + b.attendees_count, 0)],
+      ];
+      return el("div", {},
+        el("h2", {}, "Health"),
+        table([["", (f) => f[0]], ["", (f) => f[1]]], facts));
+    },
+
+    async backends() {
+      const backends = await api("GET", "/backends");
+      const setAdminState = (b, s) => async () => {
+        await api("PATCH", "/backends/" + b.id, { admin_state: s });
+        render();
+      };
+      return el("div", {},
+        el("h2", {}, "Backends"),
+        table([
+          ["Host", (b) => b.bbb.host],
+          ["Node", (b) => state(b.node_state)],
+          ["Admin", (b) => state(b.admin_state)],
+          ["Meetings", (b) => b.meetings_count],
+          ["Attendees", (b) => b.attendees_count],
+          ["Load factor", (b) => b.load_factor],
+          ["Heartbeat", (b) => time(b.agent_heartbeat)],
+          ["Error", (b) => b.last_error || ""],
+          ["", (b) => b.admin_state === "ready" ?
+            el("button", { onclick: setAdminState(b, "stopped") }, "Stop") :
+            el("button", { onclick: setAdminState(b, "ready") }, "Start")],
+        ], backends));
+    },
+
+    async frontends() {
+      const frontends = await api("GET", "/frontends");
+      return el("div", {},
+        el("h2", {}, "Frontends"),
+        table([
+          ["Key", (f) => f.bbb.key],
+          ["Active", (f) => f.active ? "yes" : "no"],
+          ["Account", (f) => f.account_ref || ""],
+          ["Created", (f) => time(f.created_at)],
+        ], frontends));
+    },
+
+    async meetings() {
+      const backends = await api("GET", "/backends");
+      const rows = [];
+      for (const b of backends) {
+        const meetings = await api("GET",
+          "/meetings?backend_id=" + encodeURIComponent(b.id));
+        for (const m of meetings) {
+          rows.push({ backend: b, meeting: m });
+        }
+      }
+      return el("div", {},
+        el("h2", {}, "Meetings"),
+        table([
+          ["Name", (r) => r.meeting.Meeting ? r.meeting.Meeting.MeetingName : ""],
+          ["ID", (r) => r.meeting.ID],
+          ["Backend", (r) => r.backend.bbb.host],
+          ["Participants", (r) => r.meeting.Meeting ?
+            r.meeting.Meeting.ParticipantCount : ""],
+          ["Created", (r) => time(r.meeting.CreatedAt)],
+          ["Logs", (r) => logLinks(r.meeting.log_links)],
+        ], rows));
+    },
+
+    async commands() {
+      const commands = await api("GET", "/commands");
+      return el("div", {},
+        el("h2", {}, "Commands"),
+        table([
+          ["Seq", (c) => c.seq],
+          ["Action", (c) => c.action],
+          ["State", (c) => state(c.state)],
+          ["Created", (c) => time(c.created_at)],
+          ["Stopped", (c) => time(c.stopped_at)],
+          ["Result", (c) => c.result === null ? "" : JSON.stringify(c.result)],
+        ], commands));
+    },
+  };
+
+  async function render() {
+    if (!token()) {
+      $("nav").hidden = true;
+      $("view").hidden = true;
+      $("login").hidden = false;
+      return;
+    }
+    $("login").hidden = true;
+    $("nav").hidden = false;
+    $("view").hidden = false;
+
+    const name = location.hash.substring(1) || "health";
+    const view = views[name] || views.health;
+    for (const a of document.querySelectorAll("nav a")) {
+      a.classList.toggle("active", a.getAttribute("href") === "#" + name);
+    }
+    $("view-error").textContent = "";
+    try {
+      $("view-content").replaceChildren(await view());
+    } catch (err) {
+      $("view-error").textContent = err.message;
+    }
+  }
+
+  function signOut() {
+    sessionStorage.removeItem(TOKEN_KEY);
+    render();
+  }
+
+  $("login-form").addEventListener("submit", async (e) => {
+    e.preventDefault();
+    sessionStorage.setItem(TOKEN_KEY, $("token").value.trim());
+    $("login-error").textContent = "";
+    try {
+      const status = await api("GET", "");
+      if (!status.is_admin) {
+        throw new Error("the token has no admin scope");
+      }
+      $("token").value = "";
+      render();
+    } catch (err) {
+      sessionStorage.removeItem(TOKEN_KEY);
+      $("login-error").textContent = err.message;
+    }
+  });
+  $("logout").addEventListener("click", signOut);
+  window.addEventListener("hashchange", render);
+
+  render();
+})();
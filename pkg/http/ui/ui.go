@@ -0,0 +1,47 @@
+package ui
+
+/*
+ Admin UI: A minimal single page application for
+ managing the cluster. All data is retrieved from
+ the REST API, which requires an admin token. The
+ static files do not contain any cluster information.
+*/
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// MountPoint is the path of the admin UI
+const MountPoint = "/admin"
+
+//go:embed static
+var staticFiles embed.FS
+
+// Init registers the admin UI routes
+func Init(e *echo.Echo) error {
+	static, err := fs.Sub(staticFiles, "static")
+	if err != nil {
+		return err
+	}
+	log.Info().Str("path", MountPoint).Msg("initializing admin ui")
+
+	files := http.StripPrefix(MountPoint, http.FileServer(http.FS(static)))
+	handler := func(c echo.Context) error {
+		c.Response().Header().Set("X-Frame-Options", "DENY")
+		c.Response().Header().Set("Referrer-Policy", "no-referrer")
+		c.Response().Header().Set("Content-Security-Policy",
+			"default-src 'self'; frame-ancestors 'none'")
+		files.ServeHTTP(c.Response(), c.Request())
+		return nil
+	}
+	e.GET(MountPoint, func(c echo.Context) error {
+		return c.Redirect(http.StatusMovedPermanently, MountPoint+"/")
+	})
+	e.GET(MountPoint+"/*", handler)
+	return nil
+}
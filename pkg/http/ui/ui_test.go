@@ -0,0 +1,34 @@
+package ui
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+)
+
+func TestInit(t *testing.T) {
+	e := echo.New()
+	if err := Init(e); err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/admin/", nil)
+	rec := httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatal("unexpected status:", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "b3scale") {
+		t.Error("unexpected index:", rec.Body.String())
+	}
+
+	req = httptest.NewRequest("GET", "/admin/app.js", nil)
+	rec = httptest.NewRecorder()
+	e.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Error("unexpected status:", rec.Code)
+	}
+}
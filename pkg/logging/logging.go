@@ -4,8 +4,13 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"os"
+	"os/signal"
 	"strconv"
+	"sync"
+	"sync/atomic"
+	"syscall"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -16,6 +21,10 @@ import (
 type Options struct {
 	Level  string
 	Format string
+
+	// Sampling of high volume log events per module,
+	// see ParseSampling.
+	Sampling string
 }
 
 // Parse the log level string. This can either be a
@@ -44,19 +53,176 @@ func parseLogLevel(level string) (zerolog.Level, error) {
 	return loglevel, nil
 }
 
+// Log formats
+const (
+	FormatPlain      = "plain"
+	FormatStructured = "structured"
+)
+
+// parseFormat returns the writer for the format.
+// Without a format, plain logging is used.
+func parseFormat(format string) (io.Writer, error) {
+	switch format {
+	case "", FormatPlain:
+		return zerolog.ConsoleWriter{Out: os.Stderr}, nil
+	case FormatStructured:
+		return os.Stderr, nil
+	}
+	return nil, fmt.Errorf(
+		"invalid log format, use %s or %s: %s",
+		FormatPlain, FormatStructured, format)
+}
+
+// The formatWriter passes the log events to the writer
+// of the current format. The logger is only created once,
+// the format is switched by replacing the writer.
+type formatWriter struct {
+	w atomic.Value // writerRef
+}
+
+// writerRef wraps the writers, as an atomic.Value
+// requires values of the same type.
+type writerRef struct {
+	io.Writer
+}
+
+// Write implements io.Writer
+func (f *formatWriter) Write(p []byte) (int, error) {
+	return f.w.Load().(writerRef).Write(p)
+}
+
+// output is the writer of the global logger
+var (
+	output     = &formatWriter{}
+	outputOnce sync.Once
+)
+
+// Validate checks the options without applying them
+func (opts *Options) Validate() error {
+	if _, err := parseLogLevel(opts.Level); err != nil {
+		return err
+	}
+	if _, err := parseFormat(opts.Format); err != nil {
+		return err
+	}
+	_, err := ParseSampling(opts.Sampling)
+	return err
+}
+
+// The current logging configuration
+var (
+	current     Options
+	configured  Options
+	currentLock sync.Mutex
+)
+
 // Setup configures the log level and sets a
 // console write unless not confgured otherwise
 func Setup(opts *Options) error {
+	if err := apply(opts); err != nil {
+		return err
+	}
+	currentLock.Lock()
+	configured = *opts
+	currentLock.Unlock()
+	return nil
+}
+
+// apply the logging options
+func apply(opts *Options) error {
 	loglevel, err := parseLogLevel(opts.Level)
 	if err != nil {
 		return err
 	}
+	sampling, err := ParseSampling(opts.Sampling)
+	if err != nil {
+		return err
+	}
+	w, err := parseFormat(opts.Format)
+	if err != nil {
+		return err
+	}
+
+	currentLock.Lock()
+	defer currentLock.Unlock()
+
+	setSampling(sampling)
 
-	// Setup logging
+	// Setup logging. The global logger is replaced only
+	// once, later changes are safe while logging.
 	zerolog.SetGlobalLevel(loglevel)
+	output.w.Store(writerRef{w})
+	outputOnce.Do(func() {
+		log.Logger = log.Output(output)
+	})
+
+	current = *opts
+	return nil
+}
 
-	if opts.Format != "structured" {
-		log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+// Current returns the active logging options
+func Current() *Options {
+	currentLock.Lock()
+	defer currentLock.Unlock()
+	opts := current
+	return &opts
+}
+
+// Update changes the log level and format at runtime.
+// Empty options are not changed.
+func Update(opts *Options) error {
+	update := Current()
+	if opts.Level != "" {
+		update.Level = opts.Level
+	}
+	if opts.Format != "" {
+		update.Format = opts.Format
+	}
+	if opts.Sampling != "" {
+		update.Sampling = opts.Sampling
+	}
+	if err := apply(update); err != nil {
+		return err
 	}
+	log.Info().
+		Str("level", update.Level).
+		Str("format", update.Format).
+		Msg("logging reconfigured")
 	return nil
 }
+
+// HandleSignals listens for SIGUSR1 and SIGUSR2.
+// SIGUSR1 increases the verbosity by one level,
+// SIGUSR2 resets the logging to the initial configuration.
+func HandleSignals() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+	go func() {
+		for sig := range signals {
+			var err error
+			if sig == syscall.SIGUSR1 {
+				err = increaseVerbosity()
+			} else {
+				currentLock.Lock()
+				initial := configured
+				currentLock.Unlock()
+				err = Update(&initial)
+			}
+			if err != nil {
+				log.Error().Err(err).Msg("changing log level")
+			}
+		}
+	}()
+}
+
+// increaseVerbosity lowers the log level by one,
+// down to trace.
+func increaseVerbosity() error {
+	level := zerolog.GlobalLevel()
+	if level <= zerolog.TraceLevel {
+		return nil
+	}
+	return Update(&Options{
+		Level: (level - 1).String(),
+	})
+}
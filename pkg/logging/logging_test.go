@@ -0,0 +1,124 @@
+package logging
+
+import (
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+func TestUpdate(t *testing.T) {
+	if err := Setup(&Options{
+		Level:  "info",
+		Format: "structured",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := Update(&Options{Level: "debug"}); err != nil {
+		t.Fatal(err)
+	}
+	if zerolog.GlobalLevel() != zerolog.DebugLevel {
+		t.Error("unexpected level:", zerolog.GlobalLevel())
+	}
+	if Current().Format != "structured" {
+		t.Error("format should not change:", Current().Format)
+	}
+
+	if err := Update(&Options{Level: "loud"}); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+}
+
+func TestIncreaseVerbosity(t *testing.T) {
+	if err := Setup(&Options{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := increaseVerbosity(); err != nil {
+		t.Fatal(err)
+	}
+	if zerolog.GlobalLevel() != zerolog.DebugLevel {
+		t.Error("unexpected level:", zerolog.GlobalLevel())
+	}
+}
+
+func TestSampled(t *testing.T) {
+	if err := Setup(&Options{
+		Level:    "info",
+		Sampling: "isMeetingRunning=3",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	logged := 0
+	for i := 0; i < 9; i++ {
+		if Sampled("isMeetingRunning") {
+			logged++
+		}
+	}
+	if logged != 3 {
+		t.Error("unexpected logged events:", logged)
+	}
+	if !Sampled("create") {
+		t.Error("modules without sampling should be logged")
+	}
+
+	if _, err := ParseSampling("isMeetingRunning"); err == nil {
+		t.Error("expected error for missing rate")
+	}
+	if _, err := ParseSampling("isMeetingRunning=0"); err == nil {
+		t.Error("expected error for zero rate")
+	}
+}
+
+func TestOptionsValidate(t *testing.T) {
+	level := zerolog.GlobalLevel()
+	if err := (&Options{Level: "debug"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if zerolog.GlobalLevel() != level {
+		t.Error("validation should not change the level")
+	}
+	if err := (&Options{Level: "loud"}).Validate(); err == nil {
+		t.Error("expected an error for an invalid level")
+	}
+	if err := (&Options{
+		Level:    "info",
+		Sampling: "getMeetings",
+	}).Validate(); err == nil {
+		t.Error("expected an error for an invalid sampling")
+	}
+}
+
+func TestUpdateInvalidFormat(t *testing.T) {
+	if err := Setup(&Options{
+		Level:  "info",
+		Format: "structured",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := Update(&Options{Format: "fancy"}); err == nil {
+		t.Error("expected an error for an invalid format")
+	}
+	if Current().Format != "structured" {
+		t.Error("format should not change:", Current().Format)
+	}
+}
+
+func TestUpdateConcurrentLogging(t *testing.T) {
+	if err := Setup(&Options{Level: "info"}); err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < 100; i++ {
+			log.Debug().Int("i", i).Msg("logging while reconfigured")
+		}
+		close(done)
+	}()
+	for _, format := range []string{"plain", "structured", "plain"} {
+		if err := Update(&Options{Format: format}); err != nil {
+			t.Error(err)
+		}
+	}
+	<-done
+}
@@ -0,0 +1,70 @@
+package logging
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// A sampler counts the log events of a module
+// and lets only every nth event pass.
+type sampler struct {
+	n       uint32
+	counter uint32
+}
+
+// Sampling configuration per module
+var (
+	samplers     = map[string]*sampler{}
+	samplersLock sync.RWMutex
+)
+
+// ParseSampling parses the sampling configuration.
+// The configuration is a comma separated list of
+// module=n pairs, e.g. `isMeetingRunning=10,getMeetings=5`
+// where only every nth log event of the module is logged.
+func ParseSampling(value string) (map[string]uint32, error) {
+	rates := map[string]uint32{}
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		tokens := strings.SplitN(pair, "=", 2)
+		if len(tokens) != 2 {
+			return nil, fmt.Errorf("invalid sampling: %s", pair)
+		}
+		n, err := strconv.ParseUint(strings.TrimSpace(tokens[1]), 10, 32)
+		if err != nil || n == 0 {
+			return nil, fmt.Errorf("invalid sampling rate: %s", pair)
+		}
+		rates[strings.TrimSpace(tokens[0])] = uint32(n)
+	}
+	return rates, nil
+}
+
+// setSampling configures the sampling of the modules
+func setSampling(rates map[string]uint32) {
+	samplersLock.Lock()
+	defer samplersLock.Unlock()
+	samplers = make(map[string]*sampler, len(rates))
+	for module, n := range rates {
+		samplers[module] = &sampler{n: n}
+	}
+}
+
+// Sampled returns true if the log event of the module
+// should be logged. Modules without sampling configuration
+// are always logged.
+func Sampled(module string) bool {
+	samplersLock.RLock()
+	s, ok := samplers[module]
+	samplersLock.RUnlock()
+	if !ok || s.n <= 1 {
+		return true
+	}
+	c := atomic.AddUint32(&s.counter, 1)
+	return c%s.n == 1
+}
@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Metric descriptors of the command queue
+var (
+	commandQueueLengthDesc = prometheus.NewDesc(
+		"command_queue_length",
+		"Number of commands waiting to be processed",
+		nil, nil)
+
+	commandQueueOldestAgeDesc = prometheus.NewDesc(
+		"command_queue_oldest_age_seconds",
+		"Time the oldest command is waiting to be processed",
+		nil, nil)
+)
+
+// The CommandQueueCollector exposes the length
+// and age of the command queue.
+type CommandQueueCollector struct{}
+
+// Describe the collector
+func (c CommandQueueCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- commandQueueLengthDesc
+	ch <- commandQueueOldestAgeDesc
+}
+
+// Collect the queue statistics
+func (c CommandQueueCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not collect command queue metrics")
+		return
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not collect command queue metrics")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	stats, err := store.GetCommandQueueStats(ctx, tx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not collect command queue metrics")
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(
+		commandQueueLengthDesc, prometheus.GaugeValue,
+		float64(stats.Requested))
+	ch <- prometheus.MustNewConstMetric(
+		commandQueueOldestAgeDesc, prometheus.GaugeValue,
+		stats.OldestAge.Seconds())
+}
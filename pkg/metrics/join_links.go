@@ -0,0 +1,21 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Counter of join links rejected by the join expiry
+var (
+	JoinLinksRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "join_links_rejected_total",
+			Help: "Number of join links rejected by the " +
+				"join expiry of a frontend",
+		},
+		[]string{
+			// Frontend Key
+			"frontend",
+			// Either expired or missing_timestamp
+			"reason",
+		})
+)
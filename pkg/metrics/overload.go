@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Metrics of the requests held while backends are overloaded
+var (
+	OverloadQueued = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "overload_queued_requests",
+			Help: "Number of requests waiting for an " +
+				"overloaded backend",
+		},
+		[]string{
+			// Frontend Key
+			"frontend",
+		})
+
+	OverloadRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "overload_rejected_requests_total",
+			Help: "Number of requests failed immediately " +
+				"because the overload queue was full",
+		},
+		[]string{
+			// Frontend Key
+			"frontend",
+		})
+)
@@ -0,0 +1,177 @@
+package metrics
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// DuplicateWindow is the time in which an identical
+// request of a frontend is counted as duplicate.
+const DuplicateWindow = 2 * time.Second
+
+// Counters for polling requests of the frontends
+var (
+	PollRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "poll_requests_total",
+			Help: "Number of polling requests of a frontend",
+		},
+		[]string{
+			// Frontend Key
+			"frontend",
+			// BBB API resource
+			"resource",
+		})
+
+	DuplicateRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "duplicate_requests_total",
+			Help: "Number of identical polling requests of a " +
+				"frontend within the duplicate window",
+		},
+		[]string{
+			// Frontend Key
+			"frontend",
+			// BBB API resource
+			"resource",
+		})
+)
+
+// IsPolling checks if the resource is typically
+// polled by integrations.
+func IsPolling(resource string) bool {
+	switch resource {
+	case bbb.ResourceIsMeetingRunning,
+		bbb.ResourceGetMeetingInfo,
+		bbb.ResourceGetMeetings,
+		bbb.ResourceGetRecordings:
+		return true
+	}
+	return false
+}
+
+// FrontendPollingStats are the polling requests
+// of a frontend.
+type FrontendPollingStats struct {
+	Frontend          string            `json:"frontend"`
+	Requests          uint64            `json:"requests"`
+	Duplicates        uint64            `json:"duplicates"`
+	Resources         map[string]uint64 `json:"resources"`
+	RequestsPerMinute float64           `json:"requests_per_minute"`
+}
+
+// The PollingTracker counts the polling requests per
+// frontend and detects duplicates. The counts are kept
+// in memory and are local to the instance.
+type PollingTracker struct {
+	mtx       sync.Mutex
+	window    time.Duration
+	since     time.Time
+	frontends map[string]*FrontendPollingStats
+	seen      map[string]time.Time
+	pruned    time.Time
+}
+
+// NewPollingTracker creates a new tracker
+func NewPollingTracker(window time.Duration) *PollingTracker {
+	now := time.Now()
+	return &PollingTracker{
+		window:    window,
+		since:     now,
+		frontends: make(map[string]*FrontendPollingStats),
+		seen:      make(map[string]time.Time),
+		pruned:    now,
+	}
+}
+
+// Polling is the tracker of the instance
+var Polling = NewPollingTracker(DuplicateWindow)
+
+// Track counts a polling request of a frontend. The
+// fingerprint identifies identical requests. The result
+// is true if the request is a duplicate.
+func (t *PollingTracker) Track(
+	frontend, resource, fingerprint string,
+	now time.Time,
+) bool {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	stats, ok := t.frontends[frontend]
+	if !ok {
+		stats = &FrontendPollingStats{
+			Frontend:  frontend,
+			Resources: make(map[string]uint64),
+		}
+		t.frontends[frontend] = stats
+	}
+	stats.Requests++
+	stats.Resources[resource]++
+
+	key := frontend + "\x00" + resource + "\x00" + fingerprint
+	last, ok := t.seen[key]
+	duplicate := ok && now.Sub(last) < t.window
+	t.seen[key] = now
+	if duplicate {
+		stats.Duplicates++
+	}
+
+	// Forget requests outside the window
+	if now.Sub(t.pruned) > t.window {
+		for k, seen := range t.seen {
+			if now.Sub(seen) >= t.window {
+				delete(t.seen, k)
+			}
+		}
+		t.pruned = now
+	}
+	return duplicate
+}
+
+// Top returns the n frontends with the most
+// polling requests.
+func (t *PollingTracker) Top(n int, now time.Time) []*FrontendPollingStats {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+
+	minutes := now.Sub(t.since).Minutes()
+	top := make([]*FrontendPollingStats, 0, len(t.frontends))
+	for _, s := range t.frontends {
+		res := make(map[string]uint64, len(s.Resources))
+		for k, v := range s.Resources {
+			res[k] = v
+		}
+		stats := &FrontendPollingStats{
+			Frontend:   s.Frontend,
+			Requests:   s.Requests,
+			Duplicates: s.Duplicates,
+			Resources:  res,
+		}
+		if minutes > 0 {
+			stats.RequestsPerMinute = float64(s.Requests) / minutes
+		}
+		top = append(top, stats)
+	}
+	sort.Slice(top, func(i, j int) bool {
+		if top[i].Requests != top[j].Requests {
+			return top[i].Requests > top[j].Requests
+		}
+		return top[i].Frontend < top[j].Frontend
+	})
+	if n > 0 && len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// Since returns the start of the tracking
+func (t *PollingTracker) Since() time.Time {
+	t.mtx.Lock()
+	defer t.mtx.Unlock()
+	return t.since
+}
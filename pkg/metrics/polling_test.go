@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestPollingTrackerDuplicates(t *testing.T) {
+	tracker := NewPollingTracker(2 * time.Second)
+	now := time.Now()
+
+	if tracker.Track("f1", "isMeetingRunning", "meetingID=1", now) {
+		t.Error("first request should not be a duplicate")
+	}
+	if !tracker.Track("f1", "isMeetingRunning", "meetingID=1",
+		now.Add(time.Second)) {
+		t.Error("expected a duplicate")
+	}
+	if tracker.Track("f1", "isMeetingRunning", "meetingID=2",
+		now.Add(time.Second)) {
+		t.Error("different params should not be a duplicate")
+	}
+	if tracker.Track("f1", "isMeetingRunning", "meetingID=1",
+		now.Add(5*time.Second)) {
+		t.Error("request outside the window should not be a duplicate")
+	}
+}
+
+func TestPollingTrackerTop(t *testing.T) {
+	tracker := NewPollingTracker(2 * time.Second)
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		tracker.Track("f1", "getMeetingInfo", "meetingID=1", now)
+	}
+	tracker.Track("f2", "getMeetings", "", now)
+
+	top := tracker.Top(1, now.Add(time.Minute))
+	if len(top) != 1 {
+		t.Fatal("unexpected top:", top)
+	}
+	if top[0].Frontend != "f1" || top[0].Requests != 3 {
+		t.Error("unexpected stats:", top[0])
+	}
+	if top[0].Duplicates != 2 {
+		t.Error("unexpected duplicates:", top[0].Duplicates)
+	}
+	if top[0].Resources["getMeetingInfo"] != 3 {
+		t.Error("unexpected resources:", top[0].Resources)
+	}
+}
+
+func TestIsPolling(t *testing.T) {
+	if !IsPolling("isMeetingRunning") {
+		t.Error("isMeetingRunning is a polling resource")
+	}
+	if IsPolling("create") {
+		t.Error("create is not a polling resource")
+	}
+}
@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Metric descriptors of the database connection pool
+var (
+	poolAcquiredConnsDesc = prometheus.NewDesc(
+		"db_pool_acquired_conns",
+		"Number of currently acquired database connections",
+		nil, nil)
+
+	poolIdleConnsDesc = prometheus.NewDesc(
+		"db_pool_idle_conns",
+		"Number of idle database connections in the pool",
+		nil, nil)
+
+	poolTotalConnsDesc = prometheus.NewDesc(
+		"db_pool_total_conns",
+		"Number of database connections in the pool",
+		nil, nil)
+
+	poolMaxConnsDesc = prometheus.NewDesc(
+		"db_pool_max_conns",
+		"Maximum size of the database connection pool",
+		nil, nil)
+
+	poolAcquiresDesc = prometheus.NewDesc(
+		"db_pool_acquires_total",
+		"Number of successful acquires from the pool",
+		nil, nil)
+
+	poolEmptyAcquiresDesc = prometheus.NewDesc(
+		"db_pool_empty_acquires_total",
+		"Number of acquires that had to wait for a connection",
+		nil, nil)
+
+	poolCanceledAcquiresDesc = prometheus.NewDesc(
+		"db_pool_canceled_acquires_total",
+		"Number of acquires canceled by a context",
+		nil, nil)
+
+	poolAcquireWaitDesc = prometheus.NewDesc(
+		"db_pool_acquire_wait_seconds_total",
+		"Total time spent waiting for a connection",
+		nil, nil)
+)
+
+// The PoolCollector exposes the statistics of
+// the database connection pool.
+type PoolCollector struct{}
+
+// Describe the collector
+func (c PoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolAcquiredConnsDesc
+	ch <- poolIdleConnsDesc
+	ch <- poolTotalConnsDesc
+	ch <- poolMaxConnsDesc
+	ch <- poolAcquiresDesc
+	ch <- poolEmptyAcquiresDesc
+	ch <- poolCanceledAcquiresDesc
+	ch <- poolAcquireWaitDesc
+}
+
+// Collect the pool statistics
+func (c PoolCollector) Collect(ch chan<- prometheus.Metric) {
+	stat := store.PoolStat()
+	if stat == nil {
+		return // Not connected
+	}
+	gauge := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+	}
+	counter := func(desc *prometheus.Desc, v float64) {
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.CounterValue, v)
+	}
+	gauge(poolAcquiredConnsDesc, float64(stat.AcquiredConns()))
+	gauge(poolIdleConnsDesc, float64(stat.IdleConns()))
+	gauge(poolTotalConnsDesc, float64(stat.TotalConns()))
+	gauge(poolMaxConnsDesc, float64(stat.MaxConns()))
+	counter(poolAcquiresDesc, float64(stat.AcquireCount()))
+	counter(poolEmptyAcquiresDesc, float64(stat.EmptyAcquireCount()))
+	counter(poolCanceledAcquiresDesc, float64(stat.CanceledAcquireCount()))
+	counter(poolAcquireWaitDesc, stat.AcquireDuration().Seconds())
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// UsageFlushInterval is the interval in which the
+// counted requests are added to the store.
+const UsageFlushInterval = 30 * time.Second
+
+// usageKey identifies the requests of a frontend
+// to a resource on a day.
+type usageKey struct {
+	frontendID string
+	day        time.Time
+	resource   string
+}
+
+// The UsageCounter counts the requests of the frontends
+// per day and resource. The counts are added to the store
+// periodically, combining the usage of all instances.
+type UsageCounter struct {
+	mtx    sync.Mutex
+	counts map[usageKey]int64
+}
+
+// NewUsageCounter creates a new counter
+func NewUsageCounter() *UsageCounter {
+	return &UsageCounter{
+		counts: make(map[usageKey]int64),
+	}
+}
+
+// Usage is the counter of the instance
+var Usage = NewUsageCounter()
+
+// Count a request of a frontend
+func (u *UsageCounter) Count(frontendID, resource string, now time.Time) {
+	now = now.UTC()
+	key := usageKey{
+		frontendID: frontendID,
+		day:        time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC),
+		resource:   resource,
+	}
+	u.mtx.Lock()
+	u.counts[key]++
+	u.mtx.Unlock()
+}
+
+// drain takes the counts and resets the counter
+func (u *UsageCounter) drain() map[usageKey]int64 {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	counts := u.counts
+	u.counts = make(map[usageKey]int64)
+	return counts
+}
+
+// restore adds counts which could not be stored
+func (u *UsageCounter) restore(counts map[usageKey]int64) {
+	u.mtx.Lock()
+	defer u.mtx.Unlock()
+	for key, n := range counts {
+		u.counts[key] += n
+	}
+}
+
+// Flush adds the counted requests to the store. The
+// counts are kept for the next attempt if this fails.
+func (u *UsageCounter) Flush(ctx context.Context) error {
+	counts := u.drain()
+	if len(counts) == 0 {
+		return nil
+	}
+	if err := storeUsage(ctx, counts); err != nil {
+		u.restore(counts)
+		return err
+	}
+	return nil
+}
+
+// storeUsage adds the counts in a single transaction
+func storeUsage(ctx context.Context, counts map[usageKey]int64) error {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	for key, n := range counts {
+		if err := store.AddFrontendUsage(
+			ctx, tx, key.frontendID, key.day, key.resource, n,
+		); err != nil {
+			return err
+		}
+	}
+	return tx.Commit(ctx)
+}
+
+// Start flushes the counter periodically until
+// the context is done.
+func (u *UsageCounter) Start(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		flushCtx, cancel := context.WithTimeout(ctx, interval)
+		if err := u.Flush(flushCtx); err != nil {
+			log.Error().Err(err).Msg("could not store frontend usage")
+		}
+		cancel()
+	}
+}
@@ -0,0 +1,37 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUsageCounterCount(t *testing.T) {
+	u := NewUsageCounter()
+	now := time.Date(2021, 6, 1, 23, 59, 0, 0, time.UTC)
+	u.Count("fe1", "join", now)
+	u.Count("fe1", "join", now.Add(30*time.Second))
+	u.Count("fe1", "join", now.Add(2*time.Minute)) // Next day
+	u.Count("fe2", "create", now)
+
+	counts := u.drain()
+	if len(counts) != 3 {
+		t.Fatal("unexpected counts:", counts)
+	}
+	day := time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)
+	if n := counts[usageKey{"fe1", day, "join"}]; n != 2 {
+		t.Error("unexpected count:", n)
+	}
+	if n := counts[usageKey{"fe1", day.AddDate(0, 0, 1), "join"}]; n != 1 {
+		t.Error("unexpected count:", n)
+	}
+	if len(u.drain()) != 0 {
+		t.Error("counter should be reset")
+	}
+
+	// Restore counts after a failed flush
+	u.Count("fe2", "create", now)
+	u.restore(counts)
+	if n := u.drain()[usageKey{"fe2", day, "create"}]; n != 2 {
+		t.Error("unexpected count:", n)
+	}
+}
@@ -9,34 +9,27 @@ import (
 
 // AdminHandler will handle all meetings related API requests
 type AdminHandler struct {
-	router *cluster.Router
+	router        *cluster.Router
+	lookupBackend backendLookup
 }
 
-// AdminRequestHandler creates a new request middleware for handling
-// all requests related to meetings.
-func AdminRequestHandler(
+// NewAdminHandler creates a new handler for the
+// version and config xml requests.
+func NewAdminHandler(
 	router *cluster.Router,
-) cluster.RequestMiddleware {
-	h := &AdminHandler{
-		router: router,
+) *AdminHandler {
+	return &AdminHandler{
+		router:        router,
+		lookupBackend: router.LookupBackend,
 	}
-	return func(next cluster.RequestHandler) cluster.RequestHandler {
-		return func(
-			ctx context.Context,
-			req *bbb.Request,
-		) (bbb.Response, error) {
-			// Dispatch API resources
-			switch req.Resource {
-			case bbb.ResourceIndex:
-				return h.Version(ctx, req)
-			case bbb.ResourceGetDefaultConfigXML:
-				return h.GetDefaultConfigXML(ctx, req)
-			case bbb.ResourceSetConfigXML:
-				return h.SetConfigXML(ctx, req)
-			}
-			// Invoke next middlewares
-			return next(ctx, req)
-		}
+}
+
+// Resources maps the admin resources to the handlers
+func (h *AdminHandler) Resources() cluster.ResourceHandlers {
+	return cluster.ResourceHandlers{
+		bbb.ResourceIndex:               h.Version,
+		bbb.ResourceGetDefaultConfigXML: h.GetDefaultConfigXML,
+		bbb.ResourceSetConfigXML:        h.SetConfigXML,
 	}
 }
 
@@ -61,7 +54,7 @@ func (h *AdminHandler) GetDefaultConfigXML(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -77,11 +70,11 @@ func (h *AdminHandler) SetConfigXML(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
-	if err != nil {
+	if backend != nil {
 		return backend.SetConfigXML(ctx, req)
 	}
 	return unknownMeetingResponse(), nil
@@ -0,0 +1,46 @@
+package requests
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+)
+
+func TestAdminHandlerSetConfigXML(t *testing.T) {
+	node := clustertest.NewNode()
+	defer node.Close()
+	node.Respond(bbb.ResourceSetConfigXML, `<response>
+<returncode>SUCCESS</returncode>
+<token>t0k3n</token>
+</response>`)
+
+	backend := node.Backend("b1")
+	h := &AdminHandler{
+		lookupBackend: func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (*cluster.Backend, error) {
+			return backend, nil
+		},
+	}
+	req := clustertest.NewRequest(bbb.ResourceSetConfigXML, bbb.Params{
+		"meetingID": "m1",
+	})
+	res, err := h.SetConfigXML(clustertest.NewContext(nil), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if node.Requests(bbb.ResourceSetConfigXML) != 1 {
+		t.Error("expected setConfigXML to be sent to the backend")
+	}
+	configRes, ok := res.(*bbb.SetConfigXMLResponse)
+	if !ok {
+		t.Fatalf("unexpected response: %T", res)
+	}
+	if configRes.Token != "t0k3n" {
+		t.Error("unexpected token:", configRes.Token)
+	}
+}
@@ -0,0 +1,96 @@
+package requests
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/experiments"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// AssignExperiments produces a middleware assigning
+// create requests to the arms of the experiments. The
+// create parameters of the arms are applied and the
+// assignments are passed to the router in the context.
+// The assignments of created meetings are recorded.
+func AssignExperiments(
+	exps []*experiments.Experiment,
+) cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceCreate {
+				return next(ctx, req) // pass
+			}
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req) // pass
+			}
+			meetingID, ok := req.Params.MeetingID()
+			if !ok {
+				return next(ctx, req) // pass
+			}
+
+			assignments := experiments.Assign(
+				exps, frontend.Frontend().Key, meetingID)
+			if len(assignments) == 0 {
+				return next(ctx, req) // pass
+			}
+			for _, a := range assignments {
+				for k, v := range a.Arm.CreateParams {
+					req.Params[k] = v
+				}
+			}
+			ctx = experiments.ContextWithAssignments(ctx, assignments)
+
+			res, err := next(ctx, req)
+			if err != nil {
+				return nil, err
+			}
+			if createRes, ok := res.(*bbb.CreateResponse); ok &&
+				createRes.XMLResponse != nil &&
+				createRes.Returncode == bbb.RetSuccess {
+				recordAssignments(ctx, assignments, meetingID, frontend.ID())
+			}
+			return res, nil
+		}
+	}
+}
+
+// recordAssignments stores the assignments of a
+// created meeting. Failing to record the assignments
+// does not fail the request.
+func recordAssignments(
+	ctx context.Context,
+	assignments []*experiments.Assignment,
+	meetingID string,
+	frontendID string,
+) {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		log.Error().Err(err).Msg("could not record experiment assignments")
+		return
+	}
+	defer tx.Rollback(ctx)
+
+	for _, a := range assignments {
+		if err := store.InsertExperimentAssignment(ctx, tx,
+			&store.ExperimentAssignment{
+				Experiment: a.Experiment.Name,
+				Arm:        a.Arm.Name,
+				MeetingID:  meetingID,
+				FrontendID: &frontendID,
+			}); err != nil {
+			log.Error().Err(err).Msg("could not record experiment assignments")
+			return
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		log.Error().Err(err).Msg("could not record experiment assignments")
+	}
+}
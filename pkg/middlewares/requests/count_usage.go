@@ -0,0 +1,26 @@
+package requests
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+)
+
+// CountUsage produces a middleware counting the requests
+// of each frontend per day and resource.
+func CountUsage() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if frontend := cluster.FrontendFromContext(ctx); frontend != nil {
+				metrics.Usage.Count(frontend.ID(), req.Resource, time.Now())
+			}
+			return next(ctx, req)
+		}
+	}
+}
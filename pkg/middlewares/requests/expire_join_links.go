@@ -0,0 +1,119 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+	"gitlab.com/infra.run/public/b3scale/pkg/templates"
+)
+
+// maxClockSkew is the tolerated difference between
+// the clocks of the frontend and the cluster.
+const maxClockSkew = time.Minute
+
+// ExpireJoinLinks produces a middleware rejecting join
+// links older than the maximum age of the frontend. This
+// prevents sharing stale links. The join links must carry
+// the unix time they were issued at:
+//
+//   join_expiry.max_age = 15m
+//   join_expiry.param = timestamp
+//
+// The timestamp is added by the frontend when signing the
+// link. The join links of meetings created through the
+// API get the timestamp of the create. Links without a
+// timestamp are rejected.
+func ExpireJoinLinks() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceJoin {
+				return next(ctx, req) // pass
+			}
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req)
+			}
+			opts := frontend.Settings().JoinExpiry
+			if opts == nil {
+				return next(ctx, req)
+			}
+			maxAge, err := opts.MaxAgeDuration()
+			if err != nil {
+				return nil, err
+			}
+			key := frontend.Frontend().Key
+			param := opts.TimestampParam()
+			timestamp, ok := req.Params[param]
+			if !ok || timestamp == "" {
+				log.Warn().
+					Str("frontend", key).
+					Str("param", param).
+					Msg("rejected join link without timestamp")
+				metrics.JoinLinksRejected.
+					WithLabelValues(key, "missing_timestamp").
+					Inc()
+				return joinLinkExpiredResponse(), nil
+			}
+			if joinLinkExpired(timestamp, maxAge, time.Now()) {
+				log.Info().
+					Str("frontend", key).
+					Str("timestamp", timestamp).
+					Msg("rejected expired join link")
+				metrics.JoinLinksRejected.
+					WithLabelValues(key, "expired").
+					Inc()
+				return joinLinkExpiredResponse(), nil
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// joinLinkExpired checks the timestamp of the link.
+// The timestamp is the unix time in seconds or
+// milliseconds. Invalid timestamps are treated
+// as expired.
+func joinLinkExpired(
+	timestamp string,
+	maxAge time.Duration,
+	now time.Time,
+) bool {
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || ts <= 0 {
+		return true
+	}
+	var issuedAt time.Time
+	if ts > 1e12 {
+		issuedAt = time.Unix(0, ts*int64(time.Millisecond))
+	} else {
+		issuedAt = time.Unix(ts, 0)
+	}
+	if issuedAt.After(now.Add(maxClockSkew)) {
+		return true // Issued in the future
+	}
+	return now.Sub(issuedAt) > maxAge
+}
+
+// joinLinkExpiredResponse renders a human readable
+// page for an expired join link.
+func joinLinkExpiredResponse() *bbb.JoinResponse {
+	res := &bbb.JoinResponse{
+		XMLResponse: new(bbb.XMLResponse),
+	}
+	res.SetRaw(templates.JoinLinkExpired())
+	res.SetStatus(http.StatusForbidden)
+	res.SetHeader(http.Header{
+		"content-type": []string{"text/html"},
+	})
+	return res
+}
@@ -0,0 +1,111 @@
+package requests
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestJoinLinkExpired(t *testing.T) {
+	now := time.Now()
+	maxAge := 15 * time.Minute
+
+	ts := strconv.FormatInt(now.Add(-5*time.Minute).Unix(), 10)
+	if joinLinkExpired(ts, maxAge, now) {
+		t.Error("recent link should be valid")
+	}
+
+	ts = strconv.FormatInt(now.Add(-5*time.Minute).UnixNano()/1e6, 10)
+	if joinLinkExpired(ts, maxAge, now) {
+		t.Error("recent link with milliseconds should be valid")
+	}
+
+	ts = strconv.FormatInt(now.Add(-20*time.Minute).Unix(), 10)
+	if !joinLinkExpired(ts, maxAge, now) {
+		t.Error("old link should be expired")
+	}
+
+	ts = strconv.FormatInt(now.Add(time.Hour).Unix(), 10)
+	if !joinLinkExpired(ts, maxAge, now) {
+		t.Error("link from the future should be rejected")
+	}
+
+	if !joinLinkExpired("", maxAge, now) {
+		t.Error("link without timestamp should be rejected")
+	}
+	if !joinLinkExpired("yesterday", maxAge, now) {
+		t.Error("link with invalid timestamp should be rejected")
+	}
+}
+
+func TestExpireJoinLinks(t *testing.T) {
+	frontend := clustertest.NewFrontend(store.FrontendSettings{
+		JoinExpiry: &store.JoinExpirySettings{MaxAge: "15m"},
+	})
+	ctx := clustertest.NewContext(frontend)
+	now := time.Now()
+
+	// Recent links are passed
+	next := clustertest.NewHandler(nil)
+	req := clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{
+		"meetingID": "m1",
+		"timestamp": strconv.FormatInt(now.Unix(), 10),
+	})
+	if _, err := ExpireJoinLinks()(next.Handle)(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if !next.Called() {
+		t.Error("recent link should be passed")
+	}
+
+	// Expired links are rejected with a page
+	next = clustertest.NewHandler(nil)
+	req = clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{
+		"meetingID": "m1",
+		"timestamp": strconv.FormatInt(now.Add(-time.Hour).Unix(), 10),
+	})
+	res, err := ExpireJoinLinks()(next.Handle)(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Called() {
+		t.Error("expired link should not be passed")
+	}
+	if res.Status() != http.StatusForbidden {
+		t.Error("unexpected status:", res.Status())
+	}
+	clustertest.AssertGolden(t, res,
+		"../../../testdata/golden/joinLinkExpired.html")
+
+	// Links without a timestamp are rejected
+	next = clustertest.NewHandler(nil)
+	req = clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{
+		"meetingID": "m1",
+	})
+	res, err = ExpireJoinLinks()(next.Handle)(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Called() {
+		t.Error("link without timestamp should not be passed")
+	}
+	if res.Status() != http.StatusForbidden {
+		t.Error("unexpected status:", res.Status())
+	}
+
+	// Without join expiry, links are passed
+	ctx = clustertest.NewContext(
+		clustertest.NewFrontend(store.FrontendSettings{}))
+	next = clustertest.NewHandler(nil)
+	if _, err := ExpireJoinLinks()(next.Handle)(ctx, req); err != nil {
+		t.Fatal(err)
+	}
+	if !next.Called() {
+		t.Error("link should be passed without join expiry")
+	}
+}
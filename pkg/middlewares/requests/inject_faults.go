@@ -0,0 +1,57 @@
+package requests
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// InjectFaults produces a middleware delaying requests
+// and responding with errors according to the policy.
+// This is intended for staging environments only.
+func InjectFaults(policy *config.FaultPolicy) cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if !policy.AppliesTo(req.Resource) {
+				return next(ctx, req) // pass
+			}
+
+			if policy.Latency > 0 && rand.Float64() < policy.LatencyRate {
+				log.Debug().
+					Str("resource", req.Resource).
+					Dur("latency", policy.Latency).
+					Msg("injecting latency")
+				select {
+				case <-time.After(policy.Latency):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+			}
+
+			if rand.Float64() < policy.ErrorRate {
+				log.Debug().
+					Str("resource", req.Resource).
+					Int("status", policy.ErrorStatus).
+					Msg("injecting error response")
+				res := &bbb.XMLResponse{
+					Returncode: bbb.RetFailed,
+					MessageKey: "b3scaleInjectedFault",
+					Message:    "this error was injected by fault injection",
+				}
+				res.SetStatus(policy.ErrorStatus)
+				return res, nil
+			}
+
+			return next(ctx, req)
+		}
+	}
+}
@@ -2,7 +2,12 @@ package requests
 
 import (
 	"context"
+	"fmt"
 	"net/http"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
@@ -19,6 +24,22 @@ type MeetingsHandlerOptions struct {
 	// When deployed in reverse proxy mode we will handle the
 	// join internally and the proxy needs to handle subsequent requests.
 	UseReverseProxy bool
+
+	// DiscoverMeetings is the number of backends asked
+	// for a meeting unknown to the store before the join
+	// fails, e.g. after restoring the database.
+	// Discovery is disabled with 0.
+	DiscoverMeetings int
+
+	// PrewarmMeetings requests the meeting info from the
+	// backend after a meeting was created, before the
+	// participants join.
+	PrewarmMeetings bool
+
+	// Gateway dispatches the create requests of rooms,
+	// so they pass the middlewares like the creates
+	// of the frontends.
+	Gateway *cluster.Gateway
 }
 
 // MeetingsHandler will handle all meetings related API requests
@@ -27,38 +48,28 @@ type MeetingsHandler struct {
 	router *cluster.Router
 }
 
-// MeetingsRequestHandler creates a new request middleware for handling
+// NewMeetingsHandler creates a new handler for
 // all requests related to meetings.
-func MeetingsRequestHandler(
+func NewMeetingsHandler(
 	router *cluster.Router,
 	opts *MeetingsHandlerOptions,
-) cluster.RequestMiddleware {
-	h := &MeetingsHandler{
+) *MeetingsHandler {
+	return &MeetingsHandler{
 		opts:   opts,
 		router: router,
 	}
-	return func(next cluster.RequestHandler) cluster.RequestHandler {
-		return func(
-			ctx context.Context,
-			req *bbb.Request,
-		) (bbb.Response, error) {
-			switch req.Resource {
-			case bbb.ResourceJoin:
-				return h.Join(ctx, req)
-			case bbb.ResourceCreate:
-				return h.Create(ctx, req)
-			case bbb.ResourceIsMeetingRunning:
-				return h.IsMeetingRunning(ctx, req)
-			case bbb.ResourceEnd:
-				return h.End(ctx, req)
-			case bbb.ResourceGetMeetingInfo:
-				return h.GetMeetingInfo(ctx, req)
-			case bbb.ResourceGetMeetings:
-				return h.GetMeetings(ctx, req)
-			}
-			// Invoke next middlewares
-			return next(ctx, req)
-		}
+}
+
+// Resources maps the meeting resources to the handlers
+func (h *MeetingsHandler) Resources() cluster.ResourceHandlers {
+	return cluster.ResourceHandlers{
+		bbb.ResourceJoin:             h.Join,
+		bbb.ResourceCreate:           h.Create,
+		bbb.ResourceIsMeetingRunning: h.IsMeetingRunning,
+		bbb.ResourceEnd:              h.End,
+		bbb.ResourceGetMeetingInfo:   h.GetMeetingInfo,
+		bbb.ResourceGetMeetings:      h.GetMeetings,
+		bbb.ResourceSendChatMessage:  h.SendChatMessage,
 	}
 }
 
@@ -70,6 +81,46 @@ func MeetingsRequestHandler(
 // after some seconds.
 func (h *MeetingsHandler) Join(
 	ctx context.Context, req *bbb.Request,
+) (bbb.Response, error) {
+	// Lookup the backend of the meeting
+	backend, err := h.router.LookupBackend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return h.joinUnassigned(ctx, req)
+	}
+
+	// We have a backend - yay! check that the backend is
+	// ok and the node agent is alive.
+	if !backend.IsNodeReady() {
+		return retryJoinResponse(req), nil
+	}
+
+	// Refresh the entry of the running meeting in the RIB
+	if err := h.router.RememberMeeting(ctx, req, backend); err != nil {
+		log.Error().Err(err).Msg("could not update rib")
+	}
+
+	// Remember the backend for joins while
+	// the database is unavailable.
+	if frontend := cluster.FrontendFromContext(ctx); frontend != nil {
+		meetingID, _ := req.Params.MeetingID()
+		cluster.Joins.PutMeeting(meetingID, frontend.ID(), backend)
+	}
+
+	// Dispatch to backend
+	if h.opts.UseReverseProxy {
+		return backend.JoinProxy(ctx, req)
+	}
+	return backend.Join(ctx, req)
+}
+
+// joinUnassigned handles joins of meetings without
+// a backend: The meeting is either unknown or not yet
+// assigned to a backend.
+func (h *MeetingsHandler) joinUnassigned(
+	ctx context.Context, req *bbb.Request,
 ) (bbb.Response, error) {
 	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
 	if err != nil {
@@ -83,36 +134,127 @@ func (h *MeetingsHandler) Join(
 	if err != nil {
 		return nil, err
 	}
+	if meeting == nil && req.Frontend != nil {
+		room, err := h.lookupRoom(ctx, tx, meetingID)
+		if err != nil {
+			return nil, err
+		}
+		if room != nil {
+			tx.Rollback(ctx) // Creating needs the connection
+			return h.joinRoom(ctx, req, room)
+		}
+	}
+	if meeting == nil && h.opts.DiscoverMeetings > 0 {
+		tx.Rollback(ctx) // The discovery needs the connection
+		return h.discoverAndJoin(ctx, req)
+	}
 	if meeting == nil {
 		// The meeting is not known to the cluster.
 		// To prevent endless loops we fail here.
 		return unknownMeetingBrowserResponse(), nil
 	}
 
-	// Get backend do redirect
-	backendState, err := meeting.GetBackendState(ctx, tx)
+	// In case the meeting is not assigned to backend (yet)
+	return retryJoinResponse(req), nil
+}
+
+// lookupRoom retrieves the room of the requesting
+// frontend with the meeting ID, if any.
+func (h *MeetingsHandler) lookupRoom(
+	ctx context.Context, tx pgx.Tx, meetingID string,
+) (*store.Room, error) {
+	frontend := cluster.FrontendFromContext(ctx)
+	if frontend == nil {
+		return nil, nil
+	}
+	// The meeting ID of the request is rewritten
+	// to be unique, while rooms use the meeting ID
+	// of the frontend.
+	return store.GetRoomByMeetingID(
+		ctx, tx, frontend.ID(), maybeDecodeMeetingID(meetingID))
+}
+
+// joinRoom creates the meeting of a room and joins it.
+// Concurrent first joins must not create the meeting on
+// different backends, so the room is locked while the
+// meeting is created.
+func (h *MeetingsHandler) joinRoom(
+	ctx context.Context, req *bbb.Request, room *store.Room,
+) (bbb.Response, error) {
+	// The lock is held on a separate connection, the
+	// connection of the request is used for creating.
+	conn, err := store.Acquire(ctx)
 	if err != nil {
 		return nil, err
 	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	if err := store.LockRoom(ctx, tx, room.ID); err != nil {
+		return nil, err
+	}
 
-	// In case the meeting is not assigned to backend (yet)
-	if backendState == nil {
-		return retryJoinResponse(req), nil
+	// The meeting may have been created while
+	// waiting for the lock.
+	backend, err := h.router.LookupBackend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		if err := h.createRoomMeeting(ctx, room); err != nil {
+			return nil, err
+		}
 	}
+	tx.Rollback(ctx) // The meeting is known, release the lock
 
-	// We have a backend - yay! check that the backend is
-	// ok and the node agent is alive.
-	if !backendState.IsNodeReady() {
-		return retryJoinResponse(req), nil
+	// The meeting is known to the store now, so
+	// this will not create the meeting again.
+	return h.Join(ctx, req)
+}
+
+// createRoomMeeting creates the meeting of the room
+// with a create request in the name of the frontend.
+func (h *MeetingsHandler) createRoomMeeting(
+	ctx context.Context, room *store.Room,
+) error {
+	frontend := cluster.FrontendFromContext(ctx)
+	if h.opts.Gateway == nil || frontend == nil {
+		return fmt.Errorf(
+			"can not create meeting of room %s", room.ID)
+	}
+	req := cluster.SignFrontendRequest(
+		bbb.CreateRequest(room.CreateParams(), nil), frontend)
+	res := h.opts.Gateway.Dispatch(
+		ctx, store.ConnectionFromContext(ctx), req)
+	if createRes, ok := res.(*bbb.CreateResponse); !ok ||
+		createRes.XMLResponse == nil ||
+		createRes.Returncode != bbb.RetSuccess {
+		return fmt.Errorf(
+			"could not create meeting of room %s: %v", room.ID, res)
 	}
+	log.Info().
+		Str("room", room.ID).
+		Str("meetingID", room.MeetingID).
+		Msg("created meeting of room")
+	return nil
+}
 
-	// Commit changes
-	if err := tx.Commit(ctx); err != nil {
+// discoverAndJoin asks the backends for a meeting
+// unknown to the store and joins the meeting if found.
+func (h *MeetingsHandler) discoverAndJoin(
+	ctx context.Context, req *bbb.Request,
+) (bbb.Response, error) {
+	backend, err := h.router.DiscoverMeeting(
+		ctx, req, h.opts.DiscoverMeetings)
+	if err != nil {
 		return nil, err
 	}
-
-	// Dispatch to backend
-	backend := cluster.NewBackend(backendState)
+	if backend == nil {
+		return unknownMeetingBrowserResponse(), nil
+	}
 	if h.opts.UseReverseProxy {
 		return backend.JoinProxy(ctx, req)
 	}
@@ -135,6 +277,13 @@ func (h *MeetingsHandler) Create(
 	if err != nil {
 		return nil, err
 	}
+	// A pinned meeting is created on the pinned backend
+	if backend == nil {
+		backend, err = h.pinnedBackend(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+	}
 	// When no backend is found, select a new one.
 	if backend == nil {
 		backend, err = h.router.SelectBackend(ctx, req)
@@ -142,7 +291,69 @@ func (h *MeetingsHandler) Create(
 	if err != nil {
 		return nil, err
 	}
-	return backend.Create(ctx, req)
+	res, err := backend.Create(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.router.RememberMeeting(ctx, req, backend); err != nil {
+		log.Error().Err(err).Msg("could not update rib")
+	}
+	if h.opts.PrewarmMeetings && res.Returncode == bbb.RetSuccess {
+		meetingID, _ := req.Params.MeetingID()
+		go backend.PrewarmMeeting(meetingID)
+	}
+	return res, nil
+}
+
+// pinnedBackend retrieves the backend of the meeting
+// pin of the requesting frontend, if any. When the node
+// of the pinned backend is not ready, the router is used.
+func (h *MeetingsHandler) pinnedBackend(
+	ctx context.Context, req *bbb.Request,
+) (*cluster.Backend, error) {
+	frontend := cluster.FrontendFromContext(ctx)
+	if frontend == nil {
+		return nil, nil
+	}
+	meetingID, ok := req.Params.MeetingID()
+	if !ok {
+		return nil, cluster.ErrMeetingIDMissing
+	}
+	// Pins use the meeting ID of the frontend
+	meetingID = maybeDecodeMeetingID(meetingID)
+
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback(ctx)
+	pin, err := store.GetActiveMeetingPin(
+		ctx, tx, frontend.ID(), meetingID)
+	if err != nil {
+		return nil, err
+	}
+	tx.Rollback(ctx) // Retrieving the backend needs the connection
+	if pin == nil {
+		return nil, nil
+	}
+
+	backend, err := cluster.GetBackend(ctx, store.Q().
+		Where("id = ?", pin.BackendID))
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil || !backend.IsNodeReady() {
+		log.Warn().
+			Str("meetingID", meetingID).
+			Str("backendID", pin.BackendID).
+			Msg("pinned backend is not ready, using router")
+		return nil, nil
+	}
+	log.Info().
+		Str("meetingID", meetingID).
+		Str("backend", backend.Host()).
+		Msg("creating pinned meeting")
+	return backend, nil
 }
 
 // IsMeetingRunning will check on a backend if the meeting is still running
@@ -184,10 +395,33 @@ func (h *MeetingsHandler) End(
 	if err != nil {
 		return nil, err
 	}
-	if backend != nil {
-		return backend.End(ctx, req)
+	if backend == nil {
+		return unknownMeetingResponse(), nil
 	}
-	return unknownMeetingResponse(), nil
+	res, err := backend.End(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.router.ForgetMeeting(ctx, req); err != nil {
+		log.Error().Err(err).Msg("could not update rib")
+	}
+	return res, nil
+}
+
+// SendChatMessage will post the message to the chat
+// of the meeting on the backend. The resource is
+// available since BBB 2.7.
+func (h *MeetingsHandler) SendChatMessage(
+	ctx context.Context, req *bbb.Request,
+) (bbb.Response, error) {
+	backend, err := h.router.LookupBackend(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if backend == nil {
+		return unknownMeetingResponse(), nil
+	}
+	return backend.SendChatMessage(ctx, req)
 }
 
 // GetMeetingInfo will not hit a backend, but we will query
@@ -246,11 +480,14 @@ func (h *MeetingsHandler) GetMeetings(
 	return res, nil
 }
 
+// retryJoinPath is the waiting page for joins
+const retryJoinPath = "/b3s/retry-join/"
+
 // retryJoinResponse makes a new JoinResponse with
 // a redirect to a waiting page. The original request will be
 // encoded and passed to the page as a parameter.
 func retryJoinResponse(req *bbb.Request) *bbb.JoinResponse {
-	retryURL := "/b3s/retry-join/" + string(req.MarshalURLSafe())
+	retryURL := retryJoinPath + string(req.MarshalURLSafe())
 	body := templates.Redirect(retryURL)
 
 	// Create custom join response
@@ -266,6 +503,26 @@ func retryJoinResponse(req *bbb.Request) *bbb.JoinResponse {
 	return res
 }
 
+// isRetryJoinResponse checks if the client is sent to
+// the waiting page and the join will be repeated.
+func isRetryJoinResponse(res bbb.Response) bool {
+	join, ok := res.(*bbb.JoinResponse)
+	if !ok || join.XMLResponse == nil {
+		return false
+	}
+	for key, values := range join.Header() {
+		if !strings.EqualFold(key, "location") {
+			continue
+		}
+		for _, location := range values {
+			if strings.HasPrefix(location, retryJoinPath) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // unknownMeetingResponse is a standard error response,
 // when the meeting could not be found by a lookup.
 func unknownMeetingResponse() *bbb.XMLResponse {
@@ -0,0 +1,128 @@
+package requests
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+const (
+	// mirrorRefreshInterval is the interval for
+	// refreshing the list of mirror backends.
+	mirrorRefreshInterval = 10 * time.Second
+
+	// mirrorTimeout limits the duration of a
+	// mirrored request.
+	mirrorTimeout = 10 * time.Second
+)
+
+// isReadOnly checks if a resource does not
+// change the state of a backend.
+func isReadOnly(resource string) bool {
+	switch resource {
+	case bbb.ResourceIsMeetingRunning,
+		bbb.ResourceGetMeetingInfo,
+		bbb.ResourceGetMeetings,
+		bbb.ResourceGetRecordings,
+		bbb.ResourceGetDefaultConfigXML,
+		bbb.ResourceGetRecordingTextTracks:
+		return true
+	}
+	return false
+}
+
+// MirrorCanary sends copies of read-only requests to
+// canary backends with mirroring enabled. The copies are
+// sent in the background; the responses are only logged
+// and never returned to the client.
+func MirrorCanary() cluster.RequestMiddleware {
+	var (
+		mtx       sync.Mutex
+		backends  []*cluster.Backend
+		refreshed time.Time
+	)
+
+	// mirrors retrieves the mirror backends. The list
+	// is cached, as read-only requests are frequent.
+	mirrors := func(ctx context.Context) ([]*cluster.Backend, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if time.Since(refreshed) < mirrorRefreshInterval {
+			return backends, nil
+		}
+		res, err := cluster.GetBackends(ctx, store.Q().
+			Where("admin_state = ?", "ready").
+			Where("backends.settings->'canary'->>'mirror' = ?", "true"))
+		if err != nil {
+			return nil, err
+		}
+		backends = res
+		refreshed = time.Now()
+		return backends, nil
+	}
+
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if !isReadOnly(req.Resource) {
+				return next(ctx, req) // pass
+			}
+			targets, err := mirrors(ctx)
+			if err != nil {
+				log.Error().Err(err).Msg("could not get mirror backends")
+				return next(ctx, req)
+			}
+			for _, be := range targets {
+				go mirrorRequest(be, copyRequest(req))
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// copyRequest creates a copy of the request, so the
+// request can be modified by the following handlers.
+func copyRequest(req *bbb.Request) *bbb.Request {
+	params := make(bbb.Params, len(req.Params))
+	for k, v := range req.Params {
+		params[k] = v
+	}
+	return &bbb.Request{
+		Request:  req.Request,
+		Resource: req.Resource,
+		Params:   params,
+		Frontend: req.Frontend,
+	}
+}
+
+// mirrorRequest sends the request to the backend
+// and logs the outcome.
+func mirrorRequest(backend *cluster.Backend, req *bbb.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	defer cancel()
+
+	t0 := time.Now()
+	res, err := backend.Mirror(ctx, req)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("backend", backend.Host()).
+			Str("resource", req.Resource).
+			Msg("mirrored request failed")
+		return
+	}
+	log.Debug().
+		Str("backend", backend.Host()).
+		Str("resource", req.Resource).
+		Int("status", res.Status()).
+		Dur("duration", time.Since(t0)).
+		Msg("mirrored request")
+}
@@ -0,0 +1,72 @@
+package requests
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// MirrorRequests produces a middleware sending copies of
+// a sample of the requests to a staging cluster. The copies
+// are signed with the secret of the mirror and sent in the
+// background; the responses are only logged and never
+// returned to the client.
+//
+// Joins are not mirrored: They are redirects for the
+// browser of the user and can not be replayed.
+func MirrorRequests(policy *config.MirrorPolicy) cluster.RequestMiddleware {
+	client := bbb.NewClient()
+	mirror := &bbb.Backend{
+		Host:   policy.URL,
+		Secret: policy.Secret,
+	}
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource == bbb.ResourceJoin ||
+				!policy.AppliesTo(req.Resource) ||
+				rand.Float64() >= policy.Rate {
+				return next(ctx, req) // pass
+			}
+			mreq := copyRequest(req)
+			mreq.Body = req.Body
+			if req.Request != nil {
+				mreq.Request = req.Request.Clone(context.Background())
+			}
+			go mirrorToStaging(client, mreq.WithBackend(mirror))
+			return next(ctx, req)
+		}
+	}
+}
+
+// mirrorToStaging sends the request to the mirror
+// and logs the outcome.
+func mirrorToStaging(client *bbb.Client, req *bbb.Request) {
+	ctx, cancel := context.WithTimeout(context.Background(), mirrorTimeout)
+	defer cancel()
+
+	t0 := time.Now()
+	res, err := client.Do(ctx, req)
+	if err != nil {
+		log.Warn().
+			Err(err).
+			Str("mirror", req.Backend.Host).
+			Str("resource", req.Resource).
+			Msg("mirrored request failed")
+		return
+	}
+	log.Debug().
+		Str("mirror", req.Backend.Host).
+		Str("resource", req.Resource).
+		Int("status", res.Status()).
+		Dur("duration", time.Since(t0)).
+		Msg("mirrored request to staging")
+}
@@ -0,0 +1,174 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+	"gitlab.com/infra.run/public/b3scale/pkg/notify"
+)
+
+// Retry delays of held requests. The delay is doubled
+// with each attempt until the maximum is reached.
+var (
+	overloadRetryDelay    = 250 * time.Millisecond
+	overloadRetryDelayMax = 2 * time.Second
+)
+
+// overloadQueue counts the waiting requests
+// in total and of each frontend.
+type overloadQueue struct {
+	policy *config.OverloadPolicy
+
+	mtx       sync.Mutex
+	waiting   int
+	frontends map[string]int
+}
+
+// enter takes a place in the queue. If the queue or
+// the share of the frontend is full, false is returned.
+func (q *overloadQueue) enter(frontend string) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	if q.waiting >= q.policy.Size {
+		return false
+	}
+	if q.frontends[frontend] >= q.policy.PerFrontend {
+		return false
+	}
+	q.waiting++
+	q.frontends[frontend]++
+	metrics.OverloadQueued.WithLabelValues(frontend).Inc()
+	return true
+}
+
+// exceedsShare checks if the frontend occupies
+// its entire share of the queue.
+func (q *overloadQueue) exceedsShare(frontend string) bool {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	return q.frontends[frontend] >= q.policy.PerFrontend
+}
+
+// leave frees the place in the queue
+func (q *overloadQueue) leave(frontend string) {
+	q.mtx.Lock()
+	defer q.mtx.Unlock()
+	q.waiting--
+	q.frontends[frontend]--
+	if q.frontends[frontend] == 0 {
+		delete(q.frontends, frontend)
+	}
+	metrics.OverloadQueued.WithLabelValues(frontend).Dec()
+}
+
+// isOverloaded checks if the backend signaled an overload,
+// either by the response status or an error. The requested
+// delay until the next attempt is returned as well.
+func isOverloaded(res bbb.Response, err error) (bool, time.Duration) {
+	if err != nil {
+		var overloadErr *bbb.OverloadError
+		if errors.As(err, &overloadErr) {
+			return true, overloadErr.RetryAfter
+		}
+		return false, 0
+	}
+	if res == nil || !bbb.IsOverloadStatus(res.Status()) {
+		return false, 0
+	}
+	var retryAfter time.Duration
+	if h := res.Header(); h != nil {
+		retryAfter = bbb.ParseRetryAfter(h)
+	}
+	return true, retryAfter
+}
+
+// overloadRetryBackoff calculates the jittered delay
+// before the next attempt. Requests of a thundering herd
+// are spread, so they do not hit the backend at once.
+func overloadRetryBackoff(attempt int, retryAfter time.Duration) time.Duration {
+	delay := overloadRetryDelay << attempt
+	if delay > overloadRetryDelayMax || delay <= 0 {
+		delay = overloadRetryDelayMax
+	}
+	delay = delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1))
+	if retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// QueueOverloaded produces a middleware holding requests
+// when the backend is overloaded and responds with 503 or 429.
+// The request is retried with a jittered delay until the
+// wait of the policy is exceeded. If the queue is full,
+// the overload response is passed to the frontend immediately.
+func QueueOverloaded(policy *config.OverloadPolicy) cluster.RequestMiddleware {
+	q := &overloadQueue{
+		policy:    policy,
+		frontends: make(map[string]int),
+	}
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if !policy.AppliesTo(req.Resource) {
+				return next(ctx, req) // pass
+			}
+			res, err := next(ctx, req)
+			overloaded, retryAfter := isOverloaded(res, err)
+			if !overloaded {
+				return res, err
+			}
+
+			frontend := ""
+			if req.Frontend != nil {
+				frontend = req.Frontend.Key
+			}
+			if !q.enter(frontend) {
+				metrics.OverloadRejected.WithLabelValues(frontend).Inc()
+				log.Warn().
+					Str("frontend", frontend).
+					Str("resource", req.Resource).
+					Msg("overload queue is full")
+				if q.exceedsShare(frontend) {
+					notify.Notify(notify.NewEvent(
+						notify.EventQuotaExceeded, frontend,
+						"the frontend exceeds its share of the overload queue"))
+				}
+				return res, err
+			}
+			defer q.leave(frontend)
+
+			deadline := time.Now().Add(policy.Wait)
+			for attempt := 0; overloaded; attempt++ {
+				delay := overloadRetryBackoff(attempt, retryAfter)
+				if time.Now().Add(delay).After(deadline) {
+					break
+				}
+				log.Debug().
+					Str("frontend", frontend).
+					Str("resource", req.Resource).
+					Dur("delay", delay).
+					Msg("backend overloaded, holding request")
+				select {
+				case <-time.After(delay):
+				case <-ctx.Done():
+					return nil, ctx.Err()
+				}
+				res, err = next(ctx, req)
+				overloaded, retryAfter = isOverloaded(res, err)
+			}
+			return res, err
+		}
+	}
+}
@@ -0,0 +1,122 @@
+package requests
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// overloadedHandler responds with 503 until
+// the number of attempts is reached.
+func overloadedHandler(overloadedAttempts int) (*int, func(
+	context.Context, *bbb.Request,
+) (bbb.Response, error)) {
+	calls := 0
+	return &calls, func(
+		ctx context.Context, req *bbb.Request,
+	) (bbb.Response, error) {
+		calls++
+		res := &bbb.XMLResponse{Returncode: bbb.RetSuccess}
+		res.SetStatus(http.StatusOK)
+		if calls <= overloadedAttempts {
+			res.Returncode = bbb.RetFailed
+			res.SetStatus(http.StatusServiceUnavailable)
+		}
+		return res, nil
+	}
+}
+
+func TestQueueOverloaded(t *testing.T) {
+	overloadRetryDelay = time.Millisecond
+	overloadRetryDelayMax = 5 * time.Millisecond
+
+	policy, _ := config.ParseOverloadPolicy("wait=1s")
+	calls, handler := overloadedHandler(3)
+	res, err := QueueOverloaded(policy)(handler)(
+		context.Background(),
+		clustertest.NewRequest(bbb.ResourceCreate, nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Status() != http.StatusOK {
+		t.Error("unexpected status:", res.Status())
+	}
+	if *calls != 4 {
+		t.Error("unexpected number of attempts:", *calls)
+	}
+
+	// Other resources are not held
+	calls, handler = overloadedHandler(3)
+	res, _ = QueueOverloaded(policy)(handler)(
+		context.Background(),
+		clustertest.NewRequest(bbb.ResourceGetMeetings, nil))
+	if res.Status() != http.StatusServiceUnavailable || *calls != 1 {
+		t.Error("request should not be held:", res.Status(), *calls)
+	}
+}
+
+func TestQueueOverloadedWaitExceeded(t *testing.T) {
+	overloadRetryDelay = time.Millisecond
+	overloadRetryDelayMax = 5 * time.Millisecond
+
+	policy, _ := config.ParseOverloadPolicy("wait=50ms")
+	handler := clustertest.NewHandler(nil)
+	handler.Err = &bbb.OverloadError{Status: http.StatusTooManyRequests}
+	_, err := QueueOverloaded(policy)(handler.Handle)(
+		context.Background(),
+		clustertest.NewRequest(bbb.ResourceJoin, nil))
+	var overloadErr *bbb.OverloadError
+	if !errors.As(err, &overloadErr) {
+		t.Error("expected overload error:", err)
+	}
+	if len(handler.Requests()) < 2 {
+		t.Error("request should be retried:", len(handler.Requests()))
+	}
+}
+
+func TestOverloadQueueLimits(t *testing.T) {
+	policy, _ := config.ParseOverloadPolicy("size=3,per_frontend=2")
+	q := &overloadQueue{
+		policy:    policy,
+		frontends: make(map[string]int),
+	}
+	if !q.enter("a") || !q.enter("a") {
+		t.Fatal("expected to enter the queue")
+	}
+	if q.enter("a") {
+		t.Error("frontend should not exceed its share")
+	}
+	if !q.exceedsShare("a") || q.exceedsShare("b") {
+		t.Error("only frontend a should exceed its share")
+	}
+	if !q.enter("b") {
+		t.Error("other frontend should enter the queue")
+	}
+	if q.enter("c") {
+		t.Error("queue should be full")
+	}
+	q.leave("a")
+	if !q.enter("c") {
+		t.Error("expected to enter the queue after leave")
+	}
+}
+
+func TestOverloadRetryBackoff(t *testing.T) {
+	overloadRetryDelay = 100 * time.Millisecond
+	overloadRetryDelayMax = time.Second
+	for attempt := 0; attempt < 100; attempt++ {
+		delay := overloadRetryBackoff(attempt, 0)
+		if delay <= 0 || delay > time.Second {
+			t.Error("unexpected delay:", attempt, delay)
+		}
+	}
+	if d := overloadRetryBackoff(0, 3*time.Second); d != 3*time.Second {
+		t.Error("retry after should be respected:", d)
+	}
+}
@@ -15,49 +15,48 @@ type RecordingsHandlerOptions struct {
 	// config or whatever...
 }
 
+// backendLookup finds the backend of the meeting in
+// the request. It is the LookupBackend of the router.
+type backendLookup func(
+	ctx context.Context,
+	req *bbb.Request,
+) (*cluster.Backend, error)
+
 // RecordingsHandler will handle all meetings related API requests
 type RecordingsHandler struct {
-	opts   *RecordingsHandlerOptions
-	router *cluster.Router
+	opts          *RecordingsHandlerOptions
+	router        *cluster.Router
+	lookupBackend backendLookup
 }
 
-// RecordingsRequestHandler creates a new request middleware for handling
-// all requests related to meetings.
-func RecordingsRequestHandler(
+// NewRecordingsHandler creates a new handler for
+// all requests related to recordings.
+func NewRecordingsHandler(
 	router *cluster.Router,
 	opts *RecordingsHandlerOptions,
-) cluster.RequestMiddleware {
-	h := &RecordingsHandler{
-		opts:   opts,
-		router: router,
-	}
-	return func(next cluster.RequestHandler) cluster.RequestHandler {
-		return func(
-			ctx context.Context,
-			req *bbb.Request,
-		) (bbb.Response, error) {
-			switch req.Resource {
-			case bbb.ResourceGetRecordings:
-				return h.GetRecordings(ctx, req)
-			case bbb.ResourcePublishRecordings:
-				return h.PublishRecordings(ctx, req)
-			case bbb.ResourceDeleteRecordings:
-				return h.DeleteRecordings(ctx, req)
-			case bbb.ResourceUpdateRecordings:
-				return h.UpdateRecordings(ctx, req)
-			case bbb.ResourceGetRecordingTextTracks:
-				return h.GetRecordingTextTracks(ctx, req)
-			case bbb.ResourcePutRecordingTextTrack:
-				return h.PutRecordingTextTrack(ctx, req)
-			}
-			// Invoke next middlewares
-			return next(ctx, req)
-		}
+) *RecordingsHandler {
+	return &RecordingsHandler{
+		opts:          opts,
+		router:        router,
+		lookupBackend: router.LookupBackend,
+	}
+}
+
+// Resources maps the recording resources to the handlers
+func (h *RecordingsHandler) Resources() cluster.ResourceHandlers {
+	return cluster.ResourceHandlers{
+		bbb.ResourceGetRecordings:          h.GetRecordings,
+		bbb.ResourcePublishRecordings:      h.PublishRecordings,
+		bbb.ResourceDeleteRecordings:       h.DeleteRecordings,
+		bbb.ResourceUpdateRecordings:       h.UpdateRecordings,
+		bbb.ResourceGetRecordingTextTracks: h.GetRecordingTextTracks,
+		bbb.ResourcePutRecordingTextTrack:  h.PutRecordingTextTrack,
 	}
 }
 
 // GetRecordings will lookup a backend for the request
-// and will invoke the backend.
+// and will invoke the backend. The recordings are
+// streamed from the backend to the client.
 func (h *RecordingsHandler) GetRecordings(
 	ctx context.Context,
 	req *bbb.Request,
@@ -69,7 +68,7 @@ func (h *RecordingsHandler) GetRecordings(
 	}
 	noRecordingsRes.SetStatus(http.StatusOK)
 
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -77,7 +76,7 @@ func (h *RecordingsHandler) GetRecordings(
 		return noRecordingsRes, nil
 	}
 
-	res, err := backend.GetRecordings(ctx, req)
+	res, err := backend.StreamRecordings(ctx, req)
 	if err != nil {
 		// Return failed successfully response
 		return noRecordingsRes, nil
@@ -92,7 +91,7 @@ func (h *RecordingsHandler) PublishRecordings(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -108,7 +107,7 @@ func (h *RecordingsHandler) UpdateRecordings(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -124,12 +123,12 @@ func (h *RecordingsHandler) DeleteRecordings(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
 	if backend != nil {
-		return backend.GetRecordings(ctx, req)
+		return backend.DeleteRecordings(ctx, req)
 	}
 	return unknownMeetingResponse(), nil
 }
@@ -140,7 +139,7 @@ func (h *RecordingsHandler) GetRecordingTextTracks(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -156,7 +155,7 @@ func (h *RecordingsHandler) PutRecordingTextTrack(
 	ctx context.Context,
 	req *bbb.Request,
 ) (bbb.Response, error) {
-	backend, err := h.router.LookupBackend(ctx, req)
+	backend, err := h.lookupBackend(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,46 @@
+package requests
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+)
+
+func TestRecordingsHandlerDeleteRecordings(t *testing.T) {
+	node := clustertest.NewNode()
+	defer node.Close()
+	node.Respond(bbb.ResourceDeleteRecordings, `<response>
+<returncode>SUCCESS</returncode>
+<deleted>true</deleted>
+</response>`)
+
+	backend := node.Backend("b1")
+	h := &RecordingsHandler{
+		lookupBackend: func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (*cluster.Backend, error) {
+			return backend, nil
+		},
+	}
+	req := clustertest.NewRequest(bbb.ResourceDeleteRecordings, bbb.Params{
+		"meetingID": "m1",
+		"recordID":  "r1",
+	})
+	res, err := h.DeleteRecordings(clustertest.NewContext(nil), req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := res.(*bbb.DeleteRecordingsResponse); !ok {
+		t.Errorf("unexpected response: %T", res)
+	}
+	if node.Requests(bbb.ResourceDeleteRecordings) != 1 {
+		t.Error("expected deleteRecordings to be sent to the backend")
+	}
+	if node.Requests(bbb.ResourceGetRecordings) != 0 {
+		t.Error("unexpected getRecordings request")
+	}
+}
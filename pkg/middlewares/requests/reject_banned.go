@@ -0,0 +1,190 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+	"gitlab.com/infra.run/public/b3scale/pkg/templates"
+)
+
+// banlistRefreshInterval is the interval for
+// reloading the bans from the store.
+const banlistRefreshInterval = 10 * time.Second
+
+// paramFullName is the name of the user in a join
+const paramFullName = "fullName"
+
+// bannedName is a full name ban with the
+// compiled pattern.
+type bannedName struct {
+	ban *store.Ban
+	re  *regexp.Regexp
+}
+
+// A banlist matches requests against the bans
+type banlist struct {
+	meetings []*store.Ban
+	names    []*bannedName
+}
+
+// newBanlist compiles the bans. Bans with invalid
+// patterns are skipped.
+func newBanlist(bans []*store.Ban) *banlist {
+	l := &banlist{}
+	for _, b := range bans {
+		switch b.Kind {
+		case store.BanMeetingID:
+			l.meetings = append(l.meetings, b)
+		case store.BanFullName:
+			re, err := b.NamePattern()
+			if err != nil {
+				log.Warn().Err(err).Str("ban", b.ID).Msg("invalid ban pattern")
+				continue
+			}
+			l.names = append(l.names, &bannedName{ban: b, re: re})
+		}
+	}
+	return l
+}
+
+// match finds the ban of the meeting or the user
+// of the frontend. The result is nil if there is none.
+func (l *banlist) match(frontendID, meetingID, fullName string) *store.Ban {
+	for _, b := range l.meetings {
+		if b.AppliesTo(frontendID) && b.Pattern == meetingID {
+			return b
+		}
+	}
+	// Padding and repeated whitespace must
+	// not get past a ban.
+	fullName = strings.Join(strings.Fields(fullName), " ")
+	if fullName == "" {
+		return nil
+	}
+	for _, n := range l.names {
+		if n.ban.AppliesTo(frontendID) && n.re.MatchString(fullName) {
+			return n.ban
+		}
+	}
+	return nil
+}
+
+// RejectBanned produces a middleware rejecting creates
+// and joins of banned meeting IDs and joins of users with
+// a banned full name. The bans are managed through the
+// admin API.
+//
+// The full names must be sanitized first. As the gateway
+// runs the middlewares in reverse order, RejectBanned is
+// registered before SanitizeNames.
+func RejectBanned() cluster.RequestMiddleware {
+	return rejectBanned(storeBanlist())
+}
+
+// storeBanlist retrieves the bans from the store. The
+// list is cached, as it is checked for every create
+// and join.
+func storeBanlist() func(context.Context) (*banlist, error) {
+	var (
+		mtx       sync.Mutex
+		bans      *banlist
+		refreshed time.Time
+	)
+	return func(ctx context.Context) (*banlist, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if time.Since(refreshed) < banlistRefreshInterval {
+			return bans, nil
+		}
+		tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		defer tx.Rollback(ctx)
+		res, err := store.GetBans(ctx, tx, store.Q())
+		if err != nil {
+			return nil, err
+		}
+		bans = newBanlist(res)
+		refreshed = time.Now()
+		return bans, nil
+	}
+}
+
+// rejectBanned creates the middleware with
+// the source of the banlist.
+func rejectBanned(
+	current func(context.Context) (*banlist, error),
+) cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceCreate &&
+				req.Resource != bbb.ResourceJoin {
+				return next(ctx, req) // pass
+			}
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req)
+			}
+			list, err := current(ctx)
+			if err != nil {
+				return nil, err
+			}
+			meetingID, _ := req.Params.MeetingID()
+			ban := list.match(
+				frontend.ID(), meetingID, req.Params[paramFullName])
+			if ban == nil {
+				return next(ctx, req)
+			}
+			log.Warn().
+				Str("frontend", frontend.Frontend().Key).
+				Str("resource", req.Resource).
+				Str("meetingID", meetingID).
+				Str("ban", ban.ID).
+				Str("kind", ban.Kind).
+				Msg("rejected banned request")
+			if req.Resource == bbb.ResourceJoin {
+				return joinBannedResponse(), nil
+			}
+			return bannedResponse(), nil
+		}
+	}
+}
+
+// bannedResponse is the response for a
+// create of a banned meeting.
+func bannedResponse() *bbb.XMLResponse {
+	res := &bbb.XMLResponse{
+		Returncode: bbb.RetFailed,
+		Message:    "This meeting is not allowed.",
+		MessageKey: "meetingBanned",
+	}
+	res.SetStatus(http.StatusForbidden)
+	return res
+}
+
+// joinBannedResponse renders a human readable
+// page for a banned join.
+func joinBannedResponse() *bbb.JoinResponse {
+	res := &bbb.JoinResponse{
+		XMLResponse: new(bbb.XMLResponse),
+	}
+	res.SetRaw(templates.JoinBanned())
+	res.SetStatus(http.StatusForbidden)
+	res.SetHeader(http.Header{
+		"content-type": []string{"text/html"},
+	})
+	return res
+}
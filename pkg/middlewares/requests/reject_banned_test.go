@@ -0,0 +1,80 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestBanlistMatch(t *testing.T) {
+	frontendID := "f1"
+	list := newBanlist([]*store.Ban{
+		{ID: "b1", Kind: store.BanMeetingID, Pattern: "spam-room"},
+		{ID: "b2", Kind: store.BanFullName, Pattern: "^buy cheap"},
+		{ID: "b3", Kind: store.BanMeetingID, Pattern: "other-room",
+			FrontendID: &frontendID},
+		{ID: "b4", Kind: store.BanFullName, Pattern: "(invalid"},
+	})
+
+	if b := list.match("f2", "spam-room", ""); b == nil || b.ID != "b1" {
+		t.Error("expected global meeting ban:", b)
+	}
+	if b := list.match("f2", "room", "Buy Cheap Pills"); b == nil || b.ID != "b2" {
+		t.Error("expected name ban:", b)
+	}
+	if b := list.match("f1", "other-room", ""); b == nil || b.ID != "b3" {
+		t.Error("expected frontend meeting ban:", b)
+	}
+	if b := list.match("f2", "other-room", ""); b != nil {
+		t.Error("ban of another frontend should not match:", b)
+	}
+	if b := list.match("f1", "room", "Jane"); b != nil {
+		t.Error("unexpected ban:", b)
+	}
+}
+
+func TestRejectBannedSanitizedName(t *testing.T) {
+	list := newBanlist([]*store.Ban{
+		{ID: "b1", Kind: store.BanFullName, Pattern: "^buy cheap pills$"},
+	})
+	current := func(context.Context) (*banlist, error) {
+		return list, nil
+	}
+	frontend := clustertest.NewFrontend(store.FrontendSettings{
+		NameSanitization: &store.NameSanitizationSettings{},
+	})
+	ctx := clustertest.NewContext(frontend)
+
+	// The middlewares run in reverse order of registration,
+	// as in the gateway: sanitize, then check the bans.
+	next := clustertest.NewHandler(nil)
+	handler := SanitizeNames()(rejectBanned(current)(next.Handle))
+
+	req := clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{
+		"meetingID": "m1",
+		"fullName":  "  Buy \u202e Cheap\tPills  ",
+	})
+	res, err := handler(ctx, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Called() {
+		t.Error("padded banned name should be rejected")
+	}
+	if res.Status() != http.StatusForbidden {
+		t.Error("unexpected status:", res.Status())
+	}
+}
+
+func TestBanlistMatchPadded(t *testing.T) {
+	list := newBanlist([]*store.Ban{
+		{ID: "b1", Kind: store.BanFullName, Pattern: "^buy cheap pills$"},
+	})
+	if b := list.match("f1", "room", "  Buy  Cheap Pills "); b == nil {
+		t.Error("padded name should match the ban")
+	}
+}
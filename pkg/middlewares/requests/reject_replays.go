@@ -0,0 +1,164 @@
+package requests
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Parameters distinguishing otherwise identical requests.
+// A frontend with replay protection should add a nonce
+// to each signed URL.
+const (
+	paramNonce      = "nonce"
+	paramCreateTime = "createTime"
+)
+
+// RejectReplays produces a middleware rejecting requests
+// with a checksum already seen within the replay window
+// of the frontend. This prevents signed join URLs from
+// being used more than once.
+//
+//   replay_protection.window = 10m
+//   replay_protection.resources = ["join"]
+//
+// A request is only consumed if it was handled: When the
+// client is sent to the waiting page or the request failed,
+// the checksum is released, so the retry is accepted.
+func RejectReplays() cluster.RequestMiddleware {
+	return rejectReplays(storeChecksums{})
+}
+
+// replayChecksums remember the checksums of requests
+type replayChecksums interface {
+	// Record returns false if the checksum was
+	// already recorded within the window.
+	Record(
+		ctx context.Context,
+		frontend *cluster.Frontend,
+		req *bbb.Request,
+	) (bool, error)
+
+	// Release forgets the checksum of the request
+	Release(
+		ctx context.Context,
+		frontend *cluster.Frontend,
+		req *bbb.Request,
+	) error
+}
+
+// rejectReplays creates the middleware with
+// the checksums store.
+func rejectReplays(checksums replayChecksums) cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req) // pass
+			}
+			opts := frontend.Settings().ReplayProtection
+			if opts == nil || !opts.Protects(req.Resource) {
+				return next(ctx, req)
+			}
+			fresh, err := checksums.Record(ctx, frontend, req)
+			if err != nil {
+				return nil, err
+			}
+			if !fresh {
+				log.Warn().
+					Str("frontend", frontend.Frontend().Key).
+					Str("resource", req.Resource).
+					Msg("rejected replayed request")
+				return replayedRequestResponse(), nil
+			}
+			res, err := next(ctx, req)
+			if err != nil || isRetryJoinResponse(res) {
+				if rerr := checksums.Release(ctx, frontend, req); rerr != nil {
+					log.Error().
+						Err(rerr).
+						Str("frontend", frontend.Frontend().Key).
+						Msg("could not release request checksum")
+				}
+			}
+			return res, err
+		}
+	}
+}
+
+// requestNonce gets the value distinguishing the
+// request from others with the same parameters.
+func requestNonce(req *bbb.Request) string {
+	if nonce, ok := req.Params[paramNonce]; ok {
+		return nonce
+	}
+	return req.Params[paramCreateTime]
+}
+
+// storeChecksums records the checksums in the store
+type storeChecksums struct{}
+
+// Record remembers the checksum of the
+// request. The result is false for a replay.
+func (storeChecksums) Record(
+	ctx context.Context,
+	frontend *cluster.Frontend,
+	req *bbb.Request,
+) (bool, error) {
+	window, err := frontend.Settings().ReplayProtection.WindowDuration()
+	if err != nil {
+		return false, err
+	}
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+	fresh, err := store.RecordRequestChecksum(
+		ctx, tx, frontend.ID(), req.Checksum, requestNonce(req), window)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return fresh, nil
+}
+
+// Release forgets the checksum of the request
+func (storeChecksums) Release(
+	ctx context.Context,
+	frontend *cluster.Frontend,
+	req *bbb.Request,
+) error {
+	tx, err := store.ConnectionFromContext(ctx).Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	err = store.DeleteRequestChecksum(
+		ctx, tx, frontend.ID(), req.Checksum, requestNonce(req))
+	if err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// replayedRequestResponse is the response for a
+// request that was already seen.
+func replayedRequestResponse() *bbb.XMLResponse {
+	res := &bbb.XMLResponse{
+		Returncode: bbb.RetFailed,
+		Message:    "This link was already used.",
+		MessageKey: "checksumReplayed",
+	}
+	res.SetStatus(http.StatusForbidden)
+	return res
+}
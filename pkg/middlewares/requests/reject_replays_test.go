@@ -0,0 +1,97 @@
+package requests
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// memoryChecksums records the checksums in memory
+type memoryChecksums struct {
+	mtx  sync.Mutex
+	seen map[string]bool
+}
+
+func (m *memoryChecksums) Record(
+	ctx context.Context,
+	frontend *cluster.Frontend,
+	req *bbb.Request,
+) (bool, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	key := req.Checksum + requestNonce(req)
+	if m.seen[key] {
+		return false, nil
+	}
+	m.seen[key] = true
+	return true, nil
+}
+
+func (m *memoryChecksums) Release(
+	ctx context.Context,
+	frontend *cluster.Frontend,
+	req *bbb.Request,
+) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	delete(m.seen, req.Checksum+requestNonce(req))
+	return nil
+}
+
+func TestRejectReplaysRetryJoin(t *testing.T) {
+	frontend := clustertest.NewFrontend(store.FrontendSettings{
+		ReplayProtection: &store.ReplayProtectionSettings{Window: "10m"},
+	})
+	ctx := clustertest.NewContext(frontend)
+	checksums := &memoryChecksums{seen: map[string]bool{}}
+	newReq := func() *bbb.Request {
+		req := clustertest.NewRequest(bbb.ResourceJoin, bbb.Params{
+			"meetingID": "m1",
+			"fullName":  "Jane",
+		})
+		req.Checksum = "checksum23"
+		return req
+	}
+
+	// The meeting is not ready, the client is sent
+	// to the waiting page.
+	next := clustertest.NewHandler(retryJoinResponse(newReq()))
+	res, err := rejectReplays(checksums)(next.Handle)(ctx, newReq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !isRetryJoinResponse(res) {
+		t.Fatal("expected a retry response:", res)
+	}
+
+	// The retry succeeds
+	join := &bbb.JoinResponse{XMLResponse: &bbb.XMLResponse{
+		Returncode: bbb.RetSuccess,
+	}}
+	next = clustertest.NewHandler(join)
+	res, err = rejectReplays(checksums)(next.Handle)(ctx, newReq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res != join {
+		t.Fatal("retry should be handled, got:", res)
+	}
+
+	// Using the link again is rejected
+	next = clustertest.NewHandler(join)
+	res, err = rejectReplays(checksums)(next.Handle)(ctx, newReq())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.Called() {
+		t.Error("replay should not be handled")
+	}
+	if res.(*bbb.XMLResponse).MessageKey != "checksumReplayed" {
+		t.Error("unexpected response:", res)
+	}
+}
@@ -94,13 +94,6 @@ func maybeRewriteRecording(r *bbb.Recording) *bbb.Recording {
 	return r
 }
 
-func maybeRewriteRecordingsCollection(c []*bbb.Recording) []*bbb.Recording {
-	for _, r := range c {
-		r = maybeRewriteRecording(r)
-	}
-	return c
-}
-
 // RewriteUniqueMeetingID ensures that the meeting id is unique
 // by combining FrontendKey and MeetingID.
 //
@@ -163,7 +156,9 @@ func rewriteUniqueMeetingIDResponse(res bbb.Response) (bbb.Response, error) {
 	case *bbb.GetMeetingsResponse:
 		r.Meetings = maybeRewriteMeetingsCollection(r.Meetings)
 	case *bbb.GetRecordingsResponse:
-		r.Recordings = maybeRewriteRecordingsCollection(r.Recordings)
+		r.EachRecording(func(rec *bbb.Recording) {
+			maybeRewriteRecording(rec)
+		})
 	}
 
 	return res, nil
@@ -0,0 +1,112 @@
+package requests
+
+import (
+	"context"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// SanitizeNames produces a middleware for cleaning the
+// full name of joining users. Control characters and
+// very long names break the user list of BBB:
+//
+//   name_sanitization.max_length = 64
+//   name_sanitization.strip_emoji = true | false
+//   name_sanitization.fallback = Guest
+//
+func SanitizeNames() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceJoin {
+				return next(ctx, req) // pass
+			}
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req)
+			}
+			opts := frontend.Settings().NameSanitization
+			if opts == nil {
+				return next(ctx, req)
+			}
+			name, ok := req.Params[paramFullName]
+			if !ok {
+				return next(ctx, req)
+			}
+			clean := sanitizeName(name, opts)
+			if clean != name {
+				log.Debug().
+					Str("frontend", frontend.Frontend().Key).
+					Str("fullName", clean).
+					Msg("sanitized full name")
+				req.Params[paramFullName] = clean
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// isEmoji checks if the rune is an emoji or a
+// modifier of an emoji.
+func isEmoji(r rune) bool {
+	switch {
+	case unicode.Is(unicode.So, r):
+		return true
+	case r >= 0x1F3FB && r <= 0x1F3FF: // Skin tones
+		return true
+	case r == 0xFE0E || r == 0xFE0F: // Variation selectors
+		return true
+	case r == 0x20E3: // Keycap
+		return true
+	}
+	return false
+}
+
+// sanitizeName removes control and format characters,
+// collapses whitespace and limits the length.
+func sanitizeName(
+	name string,
+	opts *store.NameSanitizationSettings,
+) string {
+	var b strings.Builder
+	space := false
+	for _, r := range name {
+		if unicode.IsSpace(r) {
+			space = b.Len() > 0
+			continue
+		}
+		// Control and format characters, like
+		// bidirectional overrides, are removed.
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			continue
+		}
+		if opts.StripEmoji && isEmoji(r) {
+			continue
+		}
+		if r == unicode.ReplacementChar {
+			continue
+		}
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		b.WriteRune(r)
+	}
+
+	clean := []rune(b.String())
+	if max := opts.MaxNameLength(); len(clean) > max {
+		clean = []rune(strings.TrimSpace(string(clean[:max])))
+	}
+	if len(clean) == 0 {
+		return opts.Fallback
+	}
+	return string(clean)
+}
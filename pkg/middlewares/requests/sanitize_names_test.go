@@ -0,0 +1,75 @@
+package requests
+
+import (
+	"strings"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestSanitizeName(t *testing.T) {
+	opts := &store.NameSanitizationSettings{}
+	if n := sanitizeName("  Jane \t\n Doe ", opts); n != "Jane Doe" {
+		t.Error("whitespace should be collapsed:", n)
+	}
+	if n := sanitizeName("Jane\u202eeoD\x00", opts); n != "JaneeoD" {
+		t.Error("control characters should be removed:", n)
+	}
+	if n := sanitizeName("Jane 🎉", opts); n != "Jane 🎉" {
+		t.Error("emoji should be kept:", n)
+	}
+	long := strings.Repeat("ä", 100)
+	if n := sanitizeName(long, opts); len([]rune(n)) != store.DefaultNameMaxLength {
+		t.Error("name should be limited:", len([]rune(n)))
+	}
+
+	opts = &store.NameSanitizationSettings{
+		MaxLength:  5,
+		StripEmoji: true,
+		Fallback:   "Guest",
+	}
+	if n := sanitizeName("Jane 👍🏽 Doe", opts); n != "Jane" {
+		t.Error("emoji should be removed and name limited:", n)
+	}
+	if n := sanitizeName(" 🎉\u200b ", opts); n != "Guest" {
+		t.Error("expected fallback:", n)
+	}
+}
+
+func TestSanitizeNames(t *testing.T) {
+	frontend := clustertest.NewFrontend(store.FrontendSettings{
+		NameSanitization: &store.NameSanitizationSettings{
+			Fallback: "Guest",
+		},
+	})
+	tests := []struct {
+		name     string
+		resource string
+		params   bbb.Params
+		expected string
+	}{
+		{"join", bbb.ResourceJoin,
+			bbb.Params{"fullName": " Jane \t Doe"}, "Jane Doe"},
+		{"fallback", bbb.ResourceJoin,
+			bbb.Params{"fullName": "\u200b"}, "Guest"},
+		{"other resource", bbb.ResourceCreate,
+			bbb.Params{"fullName": " Jane \t Doe"}, " Jane \t Doe"},
+	}
+	for _, test := range tests {
+		next := clustertest.NewHandler(nil)
+		req := clustertest.NewRequest(test.resource, test.params)
+		if _, err := SanitizeNames()(next.Handle)(
+			clustertest.NewContext(frontend), req,
+		); err != nil {
+			t.Fatal(err)
+		}
+		if !next.Called() {
+			t.Error(test.name, ": request should be passed")
+		}
+		if name := next.LastRequest().Params["fullName"]; name != test.expected {
+			t.Error(test.name, ": unexpected name:", name)
+		}
+	}
+}
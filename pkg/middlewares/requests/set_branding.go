@@ -0,0 +1,109 @@
+package requests
+
+import (
+	"context"
+	"strings"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// Branding parameters of create and join requests
+const (
+	paramLogo           = "logo"
+	paramWelcome        = "welcome"
+	paramBannerText     = "bannerText"
+	paramBannerColor    = "bannerColor"
+	paramCustomStyleURL = "userdata-bbb_custom_style_url"
+)
+
+// SetBranding produces a middleware for injecting the
+// branding of the frontend into create requests. The
+// custom style is a parameter of the join.
+//
+//   branding.logo = https://path-to-logo
+//   branding.custom_style_url = https://path-to-css
+//   branding.welcome = Welcome to {meeting_name} at {frontend}
+//   branding.params = {"bannerColor": "#ff0000"}
+//   branding.force = true | false
+//
+func SetBranding() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceCreate &&
+				req.Resource != bbb.ResourceJoin {
+				return next(ctx, req) // pass
+			}
+			frontend := cluster.FrontendFromContext(ctx)
+			if frontend == nil {
+				return next(ctx, req)
+			}
+			opts := frontend.Settings().Branding
+			if opts == nil {
+				return next(ctx, req)
+			}
+			applyBranding(req, frontend.Frontend().Key, opts)
+			return next(ctx, req)
+		}
+	}
+}
+
+// brandingParams are the parameters of the
+// request resource before expanding the templates.
+func brandingParams(
+	resource string,
+	opts *store.BrandingSettings,
+) bbb.Params {
+	params := bbb.Params{}
+	if resource == bbb.ResourceJoin {
+		if opts.CustomStyleURL != "" {
+			params[paramCustomStyleURL] = opts.CustomStyleURL
+		}
+		return params
+	}
+	for k, v := range opts.Params {
+		params[k] = v
+	}
+	for k, v := range map[string]string{
+		paramLogo:        opts.Logo,
+		paramWelcome:     opts.Welcome,
+		paramBannerText:  opts.BannerText,
+		paramBannerColor: opts.BannerColor,
+	} {
+		if v != "" {
+			params[k] = v
+		}
+	}
+	return params
+}
+
+// applyBranding sets the branding parameters. Parameters
+// sent by the frontend are kept, unless forced.
+func applyBranding(
+	req *bbb.Request,
+	frontendKey string,
+	opts *store.BrandingSettings,
+) {
+	// The meeting ID may already be rewritten to be unique
+	meetingID, _ := req.Params.MeetingID()
+	meetingID = maybeDecodeMeetingID(meetingID)
+	name := req.Params["name"]
+	if name == "" {
+		name = meetingID
+	}
+	r := strings.NewReplacer(
+		store.BrandingMeetingName, name,
+		store.BrandingMeetingID, meetingID,
+		store.BrandingFrontend, frontendKey)
+
+	for k, v := range brandingParams(req.Resource, opts) {
+		if _, ok := req.Params[k]; ok && !opts.Force {
+			continue
+		}
+		req.Params[k] = r.Replace(v)
+	}
+}
@@ -0,0 +1,56 @@
+package requests
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestApplyBranding(t *testing.T) {
+	opts := &store.BrandingSettings{
+		Logo:           "https://example.net/logo.png",
+		CustomStyleURL: "https://example.net/style.css",
+		Welcome:        "Welcome to {meeting_name} at {frontend}",
+		Params: map[string]string{
+			"bannerColor": "#ff0000",
+		},
+	}
+
+	req := bbb.CreateRequest(bbb.Params{
+		"meetingID":   "m1",
+		"name":        "Lecture",
+		"bannerColor": "#00ff00",
+	}, nil)
+	applyBranding(req, "uni", opts)
+	if req.Params["logo"] != opts.Logo {
+		t.Error("unexpected logo:", req.Params["logo"])
+	}
+	if req.Params["welcome"] != "Welcome to Lecture at uni" {
+		t.Error("unexpected welcome:", req.Params["welcome"])
+	}
+	if req.Params["bannerColor"] != "#00ff00" {
+		t.Error("params of the frontend should be kept:", req.Params)
+	}
+	if _, ok := req.Params[paramCustomStyleURL]; ok {
+		t.Error("the style is a join parameter")
+	}
+
+	opts.Force = true
+	applyBranding(req, "uni", opts)
+	if req.Params["bannerColor"] != "#ff0000" {
+		t.Error("params should be forced:", req.Params)
+	}
+
+	join := &bbb.Request{
+		Resource: bbb.ResourceJoin,
+		Params:   bbb.Params{"meetingID": "m1"},
+	}
+	applyBranding(join, "uni", opts)
+	if join.Params[paramCustomStyleURL] != opts.CustomStyleURL {
+		t.Error("unexpected join params:", join.Params)
+	}
+	if _, ok := join.Params["logo"]; ok {
+		t.Error("the logo is a create parameter")
+	}
+}
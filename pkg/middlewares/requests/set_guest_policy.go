@@ -0,0 +1,78 @@
+package requests
+
+import (
+	"context"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// SetGuestPolicy produces a middleware for validating
+// the guest policy of create requests. Some integrations
+// send values BBB does not accept. These are removed,
+// mapped or replaced by the default of the frontend:
+//
+//   guest_policy.default = ASK_MODERATOR
+//   guest_policy.force = true | false
+//   guest_policy.map = {"MODERATOR": "ASK_MODERATOR"}
+//
+func SetGuestPolicy() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceCreate {
+				return next(ctx, req) // pass
+			}
+			var opts *store.GuestPolicySettings
+			if frontend := cluster.FrontendFromContext(ctx); frontend != nil {
+				opts = frontend.Settings().GuestPolicy
+			}
+
+			value, hasValue := req.Params[bbb.ParamGuestPolicy]
+			policy, ok := guestPolicy(value, opts)
+			if hasValue && policy != value {
+				log.Debug().
+					Str("guestPolicy", value).
+					Str("policy", policy).
+					Msg("replacing guest policy")
+			}
+			if ok {
+				req.Params[bbb.ParamGuestPolicy] = policy
+			} else {
+				delete(req.Params, bbb.ParamGuestPolicy)
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// guestPolicy selects the guest policy for a value of
+// the create request. The result is false if no policy
+// should be set.
+func guestPolicy(
+	value string,
+	opts *store.GuestPolicySettings,
+) (string, bool) {
+	if opts != nil && opts.Force {
+		return opts.Default, true
+	}
+	if opts != nil {
+		if mapped, ok := opts.Map[value]; ok {
+			return mapped, true
+		}
+	}
+	policy := strings.ToUpper(strings.TrimSpace(value))
+	if bbb.IsValidGuestPolicy(policy) {
+		return policy, true
+	}
+	if opts != nil && opts.Default != "" {
+		return opts.Default, true
+	}
+	return "", false
+}
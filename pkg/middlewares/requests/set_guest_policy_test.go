@@ -0,0 +1,46 @@
+package requests
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestGuestPolicy(t *testing.T) {
+	// Without settings, only valid values are passed
+	if p, ok := guestPolicy("ask_moderator ", nil); !ok ||
+		p != bbb.GuestPolicyAskModerator {
+		t.Error("unexpected policy:", p, ok)
+	}
+	if p, ok := guestPolicy("MODERATOR", nil); ok {
+		t.Error("invalid policy should be removed:", p)
+	}
+	if _, ok := guestPolicy("", nil); ok {
+		t.Error("missing policy should not be set")
+	}
+
+	opts := &store.GuestPolicySettings{
+		Default: bbb.GuestPolicyAskModerator,
+		Map: map[string]string{
+			"deny": bbb.GuestPolicyAlwaysDeny,
+		},
+	}
+	if p, _ := guestPolicy("deny", opts); p != bbb.GuestPolicyAlwaysDeny {
+		t.Error("value should be mapped:", p)
+	}
+	if p, _ := guestPolicy("ALWAYS_ACCEPT", opts); p != bbb.GuestPolicyAlwaysAccept {
+		t.Error("valid policy should be kept:", p)
+	}
+	if p, _ := guestPolicy("MODERATOR", opts); p != bbb.GuestPolicyAskModerator {
+		t.Error("invalid policy should be replaced by default:", p)
+	}
+	if p, _ := guestPolicy("", opts); p != bbb.GuestPolicyAskModerator {
+		t.Error("missing policy should be set to default:", p)
+	}
+
+	opts.Force = true
+	if p, _ := guestPolicy("ALWAYS_ACCEPT", opts); p != bbb.GuestPolicyAskModerator {
+		t.Error("default should be forced:", p)
+	}
+}
@@ -0,0 +1,102 @@
+package requests
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// joinBurst counts the joins to a meeting
+// since the start of the window.
+type joinBurst struct {
+	start time.Time
+	count int
+}
+
+// joinAdmission tracks the join bursts of the meetings
+type joinAdmission struct {
+	policy *config.AdmissionPolicy
+
+	mtx    sync.Mutex
+	bursts map[string]*joinBurst
+}
+
+// delay counts the join to the meeting and returns the
+// delay before the join is admitted. Joins within the
+// burst are admitted immediately.
+func (a *joinAdmission) delay(key string, now time.Time) time.Duration {
+	a.mtx.Lock()
+	defer a.mtx.Unlock()
+
+	burst, ok := a.bursts[key]
+	if !ok || now.Sub(burst.start) >= a.policy.Window {
+		a.sweep(now)
+		burst = &joinBurst{start: now}
+		a.bursts[key] = burst
+	}
+	burst.count++
+	if burst.count <= a.policy.Burst {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(a.policy.Spread)))
+}
+
+// sweep removes the bursts of expired windows
+func (a *joinAdmission) sweep(now time.Time) {
+	for key, burst := range a.bursts {
+		if now.Sub(burst.start) >= a.policy.Window {
+			delete(a.bursts, key)
+		}
+	}
+}
+
+// SmoothJoins produces a middleware spreading mass joins
+// to the same meeting: Joins exceeding the burst of the
+// policy are delayed by a random duration, so the backend
+// is not hit by all participants at once.
+func SmoothJoins(policy *config.AdmissionPolicy) cluster.RequestMiddleware {
+	a := &joinAdmission{
+		policy: policy,
+		bursts: make(map[string]*joinBurst),
+	}
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceJoin {
+				return next(ctx, req) // pass
+			}
+			meetingID, ok := req.Params.MeetingID()
+			if !ok {
+				return next(ctx, req)
+			}
+			key := meetingID
+			if req.Frontend != nil {
+				key = req.Frontend.Key + "/" + meetingID
+			}
+
+			delay := a.delay(key, time.Now())
+			if delay == 0 {
+				return next(ctx, req)
+			}
+			log.Debug().
+				Str("meetingID", meetingID).
+				Dur("delay", delay).
+				Msg("delaying join of burst")
+			select {
+			case <-time.After(delay):
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,70 @@
+package requests
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+func TestJoinAdmissionDelay(t *testing.T) {
+	policy, _ := config.ParseAdmissionPolicy("burst=2,window=1s,spread=3s")
+	a := &joinAdmission{
+		policy: policy,
+		bursts: make(map[string]*joinBurst),
+	}
+	now := time.Now()
+	if a.delay("m1", now) != 0 || a.delay("m1", now) != 0 {
+		t.Error("joins within the burst should not be delayed")
+	}
+	for i := 0; i < 100; i++ {
+		d := a.delay("m1", now)
+		if d < 0 || d >= 3*time.Second {
+			t.Error("unexpected delay:", d)
+		}
+	}
+	if a.delay("m2", now) != 0 {
+		t.Error("other meetings should not be delayed")
+	}
+
+	// A new window starts and expired bursts are removed
+	later := now.Add(time.Second)
+	if a.delay("m1", later) != 0 {
+		t.Error("join in new window should not be delayed")
+	}
+	if _, ok := a.bursts["m2"]; ok {
+		t.Error("expired burst should be removed")
+	}
+}
+
+func TestSmoothJoins(t *testing.T) {
+	policy, _ := config.ParseAdmissionPolicy("burst=1,spread=20ms")
+	handler := clustertest.NewHandler(nil)
+	h := SmoothJoins(policy)(handler.Handle)
+	params := bbb.Params{"meetingID": "lecture"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := h(
+			context.Background(),
+			clustertest.NewRequest(bbb.ResourceJoin, params),
+		); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(handler.Requests()) != 3 {
+		t.Error("all joins should be admitted:", len(handler.Requests()))
+	}
+
+	// A cancelled join is not admitted
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	policy.Spread = time.Hour
+	h = SmoothJoins(policy)(handler.Handle)
+	h(ctx, clustertest.NewRequest(bbb.ResourceJoin, params))
+	if _, err := h(ctx, clustertest.NewRequest(bbb.ResourceJoin, params)); err == nil {
+		t.Error("expected the context error")
+	}
+}
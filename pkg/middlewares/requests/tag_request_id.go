@@ -0,0 +1,52 @@
+package requests
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+)
+
+// ParamMetaRequestID is the create parameter carrying
+// the correlation ID of the create request. BBB passes
+// meta parameters to the recordings and callbacks,
+// so the meeting can be traced back to the request.
+const ParamMetaRequestID = "meta_b3scale-request-id"
+
+// TagRequestID produces a middleware for adding the
+// correlation ID of the request to the metadata of a
+// meeting when it is created. A request ID passed by the
+// frontend is not overwritten.
+func TagRequestID() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(ctx context.Context, req *bbb.Request) (bbb.Response, error) {
+			if req.Resource != bbb.ResourceCreate {
+				return next(ctx, req) // pass
+			}
+			id := cluster.RequestIDFromContext(ctx)
+			if tagRequestID(req, id) {
+				meetingID, _ := req.Params.MeetingID()
+				log.Debug().
+					Str("meetingID", meetingID).
+					Str("requestID", id).
+					Msg("tagged meeting with request id")
+			}
+			return next(ctx, req)
+		}
+	}
+}
+
+// tagRequestID sets the meta parameter if the request
+// has an ID and the parameter is not present.
+func tagRequestID(req *bbb.Request, id string) bool {
+	if id == "" {
+		return false
+	}
+	if _, ok := req.Params[ParamMetaRequestID]; ok {
+		return false
+	}
+	req.Params[ParamMetaRequestID] = id
+	return true
+}
@@ -0,0 +1,28 @@
+package requests
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestTagRequestID(t *testing.T) {
+	req := bbb.CreateRequest(bbb.Params{"meetingID": "m1"}, nil)
+	if tagRequestID(req, "") {
+		t.Error("request without id should not be tagged")
+	}
+	if !tagRequestID(req, "req-1") {
+		t.Error("request should be tagged")
+	}
+	if req.Params[ParamMetaRequestID] != "req-1" {
+		t.Error("unexpected params:", req.Params)
+	}
+
+	// The id of the frontend is kept
+	if tagRequestID(req, "req-2") {
+		t.Error("existing id should not be overwritten")
+	}
+	if req.Params[ParamMetaRequestID] != "req-1" {
+		t.Error("unexpected params:", req.Params)
+	}
+}
@@ -0,0 +1,35 @@
+package requests
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/metrics"
+)
+
+// TrackPolling produces a middleware counting the polling
+// requests of each frontend, like isMeetingRunning or
+// getMeetingInfo, to identify badly behaved integrations.
+func TrackPolling() cluster.RequestMiddleware {
+	return func(next cluster.RequestHandler) cluster.RequestHandler {
+		return func(
+			ctx context.Context,
+			req *bbb.Request,
+		) (bbb.Response, error) {
+			if !metrics.IsPolling(req.Resource) || req.Frontend == nil {
+				return next(ctx, req) // pass
+			}
+			key := req.Frontend.Key
+			metrics.PollRequests.WithLabelValues(key, req.Resource).Inc()
+			if metrics.Polling.Track(
+				key, req.Resource, req.Params.String(), time.Now(),
+			) {
+				metrics.DuplicateRequests.
+					WithLabelValues(key, req.Resource).Inc()
+			}
+			return next(ctx, req)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package routing
+
+import (
+	"context"
+	"math/rand"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+)
+
+// Canary limits the share of new meetings created on
+// canary backends. A canary backend is selected with the
+// probability of its weight and is then preferred over all
+// other backends. Otherwise it is removed from the selection,
+// unless there are only canary backends left.
+//
+// This middleware should be executed after sorting.
+func Canary(next cluster.RouterHandler) cluster.RouterHandler {
+	return func(
+		ctx context.Context,
+		backends []*cluster.Backend,
+		req *bbb.Request,
+	) ([]*cluster.Backend, error) {
+		// This middleware only applies to create meeting requests
+		if req.Resource != bbb.ResourceCreate {
+			return next(ctx, backends, req) // pass
+		}
+		// The selection is random and not explained
+		if cluster.IsExplaining(ctx) {
+			cluster.ExplainSkipped(ctx, "canary backends are selected at random")
+			return next(ctx, backends, req)
+		}
+		backends = selectCanary(backends, rand.Float64)
+		return next(ctx, backends, req)
+	}
+}
+
+// selectCanary moves a selected canary backend to the
+// front and removes all other canary backends.
+func selectCanary(
+	backends []*cluster.Backend,
+	random func() float64,
+) []*cluster.Backend {
+	var canary *cluster.Backend
+	regular := make([]*cluster.Backend, 0, len(backends))
+	for _, be := range backends {
+		if !be.IsCanary() {
+			regular = append(regular, be)
+			continue
+		}
+		if canary == nil && random() < be.Canary().Weight {
+			canary = be
+		}
+	}
+	if canary != nil {
+		return append([]*cluster.Backend{canary}, regular...)
+	}
+	if len(regular) == 0 {
+		return backends // Only canaries are available
+	}
+	return regular
+}
@@ -0,0 +1,38 @@
+package routing
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestSelectCanary(t *testing.T) {
+	canary := cluster.NewBackend(&store.BackendState{
+		ID: "canary",
+		Settings: store.BackendSettings{
+			Canary: &store.CanarySettings{Weight: 0.2},
+		},
+	})
+	b := []*cluster.Backend{
+		cluster.NewBackend(&store.BackendState{ID: "A"}),
+		canary,
+		cluster.NewBackend(&store.BackendState{ID: "B"}),
+	}
+
+	res := selectCanary(b, func() float64 { return 0.1 })
+	if len(res) != 3 || res[0].ID() != "canary" {
+		t.Error("expected canary to be selected:", res)
+	}
+
+	res = selectCanary(b, func() float64 { return 0.5 })
+	if len(res) != 2 || res[0].ID() != "A" || res[1].ID() != "B" {
+		t.Error("expected canary to be removed:", res)
+	}
+
+	// Only canaries are available
+	res = selectCanary(b[1:2], func() float64 { return 0.5 })
+	if len(res) != 1 {
+		t.Error("expected canary as fallback:", res)
+	}
+}
@@ -0,0 +1,51 @@
+package routing
+
+import (
+	"context"
+	"math/rand"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
+// DropBackends simulates unavailable backends by
+// removing each backend from the selection with the
+// drop rate of the fault injection policy.
+func DropBackends(policy *config.FaultPolicy) cluster.RouterMiddleware {
+	return func(next cluster.RouterHandler) cluster.RouterHandler {
+		return func(
+			ctx context.Context,
+			backends []*cluster.Backend,
+			req *bbb.Request,
+		) ([]*cluster.Backend, error) {
+			if !policy.AppliesTo(req.Resource) {
+				return next(ctx, backends, req) // pass
+			}
+			// Faults are random and not explained
+			if cluster.IsExplaining(ctx) {
+				cluster.ExplainSkipped(ctx, "backends are dropped at random")
+				return next(ctx, backends, req)
+			}
+			backends = dropBackends(backends, policy.DropRate, rand.Float64)
+			return next(ctx, backends, req)
+		}
+	}
+}
+
+// dropBackends removes backends if the random
+// number is below the rate.
+func dropBackends(
+	backends []*cluster.Backend,
+	rate float64,
+	random func() float64,
+) []*cluster.Backend {
+	filtered := make([]*cluster.Backend, 0, len(backends))
+	for _, be := range backends {
+		if random() < rate {
+			continue
+		}
+		filtered = append(filtered, be)
+	}
+	return filtered
+}
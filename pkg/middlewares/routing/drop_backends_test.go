@@ -0,0 +1,37 @@
+package routing
+
+import (
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestDropBackends(t *testing.T) {
+	b := []*cluster.Backend{
+		cluster.NewBackend(&store.BackendState{ID: "A"}),
+		cluster.NewBackend(&store.BackendState{ID: "B"}),
+		cluster.NewBackend(&store.BackendState{ID: "C"}),
+	}
+
+	values := []float64{0.1, 0.9, 0.4}
+	i := 0
+	random := func() float64 {
+		v := values[i]
+		i++
+		return v
+	}
+
+	res := dropBackends(b, 0.5, random)
+	if len(res) != 1 {
+		t.Fatal("unexpected backends:", res)
+	}
+	if res[0].ID() != "B" {
+		t.Error("unexpected:", res[0])
+	}
+
+	never := func() float64 { return 0 }
+	if len(dropBackends(b, 0, never)) != 3 {
+		t.Error("expected no backend to be dropped")
+	}
+}
@@ -0,0 +1,33 @@
+package routing
+
+import (
+	"context"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/experiments"
+)
+
+// ExperimentPools restricts the backends to the pools of
+// the experiment arms assigned to the request. A pool is
+// defined by the required tags of the arm.
+func ExperimentPools(next cluster.RouterHandler) cluster.RouterHandler {
+	return func(
+		ctx context.Context,
+		backends []*cluster.Backend,
+		req *bbb.Request,
+	) ([]*cluster.Backend, error) {
+		// This middleware only applies to create meeting requests
+		if req.Resource != bbb.ResourceCreate {
+			return next(ctx, backends, req) // pass
+		}
+		for _, a := range experiments.AssignmentsFromContext(ctx) {
+			filtered := filterRequiredTags(backends, a.Arm.RequiredTags)
+			explainRemoved(ctx, backends, filtered,
+				"not in the pool of arm "+a.Arm.Name+
+					" of experiment "+a.Experiment.Name)
+			backends = filtered
+		}
+		return next(ctx, backends, req)
+	}
+}
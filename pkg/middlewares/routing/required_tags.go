@@ -2,6 +2,7 @@ package routing
 
 import (
 	"context"
+	"strings"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
@@ -31,12 +32,36 @@ func RequiredTags(next cluster.RouterHandler) cluster.RouterHandler {
 		}
 
 		tags := frontend.Settings().RequiredTags
-		backends = filterRequiredTags(backends, tags)
+		filtered := filterRequiredTags(backends, tags)
+		explainRemoved(ctx, backends, filtered,
+			"missing required tags: "+strings.Join(tags, ", "))
+		backends = filtered
 
 		return next(ctx, backends, req)
 	}
 }
 
+// explainRemoved reports the reason for the backends
+// removed from the selection when explaining a request.
+func explainRemoved(
+	ctx context.Context,
+	before, after []*cluster.Backend,
+	reason string,
+) {
+	if !cluster.IsExplaining(ctx) {
+		return
+	}
+	kept := make(map[*cluster.Backend]bool, len(after))
+	for _, be := range after {
+		kept[be] = true
+	}
+	for _, be := range before {
+		if !kept[be] {
+			cluster.ExplainReason(ctx, be, reason)
+		}
+	}
+}
+
 // filterRequiredTags retrievs the required tags
 // for a frontend from the configuration state and
 // removes backends not providing all of the tags
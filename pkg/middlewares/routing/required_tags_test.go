@@ -1,9 +1,13 @@
 package routing
 
 import (
+	"strings"
 	"testing"
 
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
 	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
 )
 
 func TestFilterRequiredTags(t *testing.T) {
@@ -14,3 +18,43 @@ func TestFilterRequiredTags(t *testing.T) {
 		t.Error("unexpected:", filtered)
 	}
 }
+
+func TestRequiredTags(t *testing.T) {
+	backends := []*cluster.Backend{
+		clustertest.NewBackend("b1", store.BackendSettings{
+			Tags: store.Tags{"sip"},
+		}),
+		clustertest.NewBackend("b2", store.BackendSettings{}),
+		clustertest.NewBackend("b3", store.BackendSettings{
+			Tags: store.Tags{"sip", "gpu"},
+		}),
+	}
+	tests := []struct {
+		name     string
+		resource string
+		required store.Tags
+		expected string
+	}{
+		{"no tags", bbb.ResourceCreate, nil, "b1,b2,b3"},
+		{"sip", bbb.ResourceCreate, store.Tags{"sip"}, "b1,b3"},
+		{"sip and gpu", bbb.ResourceCreate, store.Tags{"sip", "gpu"}, "b3"},
+		{"not a create", bbb.ResourceJoin, store.Tags{"gpu"}, "b1,b2,b3"},
+	}
+	for _, test := range tests {
+		frontend := clustertest.NewFrontend(store.FrontendSettings{
+			RequiredTags: test.required,
+		})
+		router := clustertest.NewRouter()
+		if _, err := router.Route(
+			clustertest.NewContext(frontend),
+			RequiredTags,
+			backends,
+			clustertest.NewRequest(test.resource, nil),
+		); err != nil {
+			t.Fatal(err)
+		}
+		if ids := strings.Join(router.BackendIDs(), ","); ids != test.expected {
+			t.Error(test.name, ": unexpected backends:", ids)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package routing
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// ReservationsRefreshInterval is the time after which
+// the exclusive routing schedules of all frontends
+// are reloaded from the store.
+var ReservationsRefreshInterval = 30 * time.Second
+
+// A reservation is an exclusive routing
+// schedule of a frontend.
+type reservation struct {
+	frontendID string
+	schedule   *store.RoutingScheduleSettings
+}
+
+// reservationsCache holds the reservations
+// of all frontends.
+type reservationsCache struct {
+	mtx          sync.Mutex
+	loadedAt     time.Time
+	reservations []*reservation
+}
+
+// reservations are shared by all requests
+var reservations = &reservationsCache{}
+
+// get returns the reservations and reloads them when
+// the refresh interval passed. If the reload fails, the
+// previous reservations are used.
+func (c *reservationsCache) get(ctx context.Context) []*reservation {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	if time.Since(c.loadedAt) < ReservationsRefreshInterval {
+		return c.reservations
+	}
+	frontends, err := cluster.GetFrontends(ctx, store.Q())
+	if err != nil {
+		log.Error().Err(err).Msg("could not load routing schedules")
+		return c.reservations
+	}
+	c.reservations = frontendReservations(frontends)
+	c.loadedAt = time.Now()
+	return c.reservations
+}
+
+// frontendReservations collects the exclusive
+// routing schedules of the frontends.
+func frontendReservations(frontends []*cluster.Frontend) []*reservation {
+	res := []*reservation{}
+	for _, f := range frontends {
+		for _, rs := range f.Settings().RoutingSchedule {
+			if rs == nil || !rs.Exclusive {
+				continue
+			}
+			res = append(res, &reservation{
+				frontendID: f.ID(),
+				schedule:   rs,
+			})
+		}
+	}
+	return res
+}
+
+// RoutingSchedule applies the routing schedules of the
+// frontends stored in the settings, e.g.
+//
+//   routing_schedule = [{"days": ["mon"], "from": "08:00",
+//                        "until": "12:00", "required_tags": ["exam"],
+//                        "exclusive": true}]
+//
+// During an active window, the frontend requires the tags
+// from the backends. Backends reserved by an exclusive window
+// of another frontend are removed from the selection.
+func RoutingSchedule(next cluster.RouterHandler) cluster.RouterHandler {
+	return func(
+		ctx context.Context,
+		backends []*cluster.Backend,
+		req *bbb.Request,
+	) ([]*cluster.Backend, error) {
+		// This middleware only applies to create meeting requests
+		if req.Resource != bbb.ResourceCreate {
+			return next(ctx, backends, req) // pass
+		}
+
+		frontendID := ""
+		var schedule *store.RoutingScheduleSettings
+		now := time.Now()
+		if frontend := cluster.FrontendFromContext(ctx); frontend != nil {
+			frontendID = frontend.ID()
+			schedule = frontend.Settings().ActiveRoutingSchedule(now)
+		}
+
+		filtered := filterRoutingSchedule(
+			backends, frontendID, schedule, reservations.get(ctx), now)
+		if schedule != nil {
+			explainRemoved(ctx, backends, filtered,
+				"missing tags of the active routing schedule or "+
+					"reserved by another frontend")
+		} else {
+			explainRemoved(ctx, backends, filtered,
+				"reserved by another frontend")
+		}
+		return next(ctx, filtered, req)
+	}
+}
+
+// filterRoutingSchedule keeps the backends with the tags
+// of the active schedule and removes the backends reserved
+// by other frontends.
+func filterRoutingSchedule(
+	backends []*cluster.Backend,
+	frontendID string,
+	schedule *store.RoutingScheduleSettings,
+	reserved []*reservation,
+	now time.Time,
+) []*cluster.Backend {
+	if schedule != nil {
+		backends = filterRequiredTags(backends, schedule.RequiredTags)
+	}
+	for _, r := range reserved {
+		if r.frontendID == frontendID || !r.schedule.Active(now) {
+			continue
+		}
+		filtered := make([]*cluster.Backend, 0, len(backends))
+		for _, be := range backends {
+			if !be.HasTags(r.schedule.RequiredTags) {
+				filtered = append(filtered, be)
+			}
+		}
+		backends = filtered
+	}
+	return backends
+}
@@ -0,0 +1,101 @@
+package routing
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster/clustertest"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func backendIDs(backends []*cluster.Backend) string {
+	ids := make([]string, 0, len(backends))
+	for _, be := range backends {
+		ids = append(ids, be.ID())
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestFilterRoutingSchedule(t *testing.T) {
+	backends := []*cluster.Backend{
+		clustertest.NewBackend("b1", store.BackendSettings{
+			Tags: store.Tags{"exam"},
+		}),
+		clustertest.NewBackend("b2", store.BackendSettings{}),
+		clustertest.NewBackend("b3", store.BackendSettings{
+			Tags: store.Tags{"exam", "sip"},
+		}),
+	}
+	exams := &store.RoutingScheduleSettings{
+		Days:         []string{"mon"},
+		From:         "08:00",
+		Until:        "12:00",
+		RequiredTags: store.Tags{"exam"},
+		Exclusive:    true,
+	}
+	reserved := []*reservation{
+		{frontendID: "exams", schedule: exams},
+	}
+	during := time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC) // Monday
+	after := time.Date(2026, 10, 12, 13, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name       string
+		frontendID string
+		schedule   *store.RoutingScheduleSettings
+		now        time.Time
+		expected   string
+	}{
+		{"exams during window", "exams", exams, during, "b1,b3"},
+		{"other during window", "lectures", nil, during, "b2"},
+		{"other after window", "lectures", nil, after, "b1,b2,b3"},
+	}
+	for _, test := range tests {
+		filtered := filterRoutingSchedule(
+			backends, test.frontendID, test.schedule, reserved, test.now)
+		if ids := backendIDs(filtered); ids != test.expected {
+			t.Error(test.name, ": unexpected backends:", ids)
+		}
+	}
+}
+
+func TestRoutingSchedule(t *testing.T) {
+	// The reservations are not loaded from the store
+	reservations.loadedAt = time.Now().Add(time.Hour)
+	defer func() { reservations.loadedAt = time.Time{} }()
+
+	backends := []*cluster.Backend{
+		clustertest.NewBackend("b1", store.BackendSettings{
+			Tags: store.Tags{"exam"},
+		}),
+		clustertest.NewBackend("b2", store.BackendSettings{}),
+	}
+	now := time.Now().UTC()
+	frontend := clustertest.NewFrontend(store.FrontendSettings{
+		RoutingSchedule: []*store.RoutingScheduleSettings{{
+			From:         now.Add(-time.Hour).Format("15:04"),
+			Until:        now.Add(time.Hour).Format("15:04"),
+			RequiredTags: store.Tags{"exam"},
+		}},
+	})
+	for resource, expected := range map[string]string{
+		bbb.ResourceCreate: "b1",
+		bbb.ResourceJoin:   "b1,b2",
+	} {
+		router := clustertest.NewRouter()
+		if _, err := router.Route(
+			clustertest.NewContext(frontend),
+			RoutingSchedule,
+			backends,
+			clustertest.NewRequest(resource, nil),
+		); err != nil {
+			t.Fatal(err)
+		}
+		if ids := strings.Join(router.BackendIDs(), ","); ids != expected {
+			t.Error(resource, ": unexpected backends:", ids)
+		}
+	}
+}
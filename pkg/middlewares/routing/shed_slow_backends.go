@@ -0,0 +1,60 @@
+package routing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+)
+
+// ShedSlowBackends produces a middleware moving backends
+// with a latency (p95) above the threshold to the end of
+// the selection. Slow backends are only selected, if all
+// backends are slow.
+//
+// This middleware should be executed after sorting.
+func ShedSlowBackends(threshold time.Duration) cluster.RouterMiddleware {
+	return func(next cluster.RouterHandler) cluster.RouterHandler {
+		return func(
+			ctx context.Context,
+			backends []*cluster.Backend,
+			req *bbb.Request,
+		) ([]*cluster.Backend, error) {
+			// This middleware only applies to create meeting requests
+			if req.Resource != bbb.ResourceCreate {
+				return next(ctx, backends, req) // pass
+			}
+			backends = shedSlowBackends(
+				backends, threshold, (*cluster.Backend).Latency)
+			if cluster.IsExplaining(ctx) {
+				for _, be := range backends {
+					if be.Latency() > threshold {
+						cluster.ExplainReason(ctx, be, fmt.Sprintf(
+							"latency %v above %v", be.Latency(), threshold))
+					}
+				}
+			}
+			return next(ctx, backends, req)
+		}
+	}
+}
+
+// shedSlowBackends moves the slow backends to the end
+func shedSlowBackends(
+	backends []*cluster.Backend,
+	threshold time.Duration,
+	latency func(*cluster.Backend) time.Duration,
+) []*cluster.Backend {
+	fast := make([]*cluster.Backend, 0, len(backends))
+	slow := []*cluster.Backend{}
+	for _, be := range backends {
+		if latency(be) > threshold {
+			slow = append(slow, be)
+		} else {
+			fast = append(fast, be)
+		}
+	}
+	return append(fast, slow...)
+}
@@ -0,0 +1,36 @@
+package routing
+
+import (
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/cluster"
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+func TestShedSlowBackends(t *testing.T) {
+	b := []*cluster.Backend{
+		cluster.NewBackend(&store.BackendState{ID: "A"}),
+		cluster.NewBackend(&store.BackendState{ID: "B"}),
+		cluster.NewBackend(&store.BackendState{ID: "C"}),
+	}
+	latencies := map[string]time.Duration{
+		"A": 3 * time.Second,
+		"B": 100 * time.Millisecond,
+		"C": 200 * time.Millisecond,
+	}
+	latency := func(be *cluster.Backend) time.Duration {
+		return latencies[be.ID()]
+	}
+
+	res := shedSlowBackends(b, time.Second, latency)
+	if len(res) != 3 || res[0].ID() != "B" || res[2].ID() != "A" {
+		t.Error("expected A to be deprioritized:", res)
+	}
+
+	// All backends are slow
+	res = shedSlowBackends(b, time.Millisecond, latency)
+	if len(res) != 3 || res[0].ID() != "A" {
+		t.Error("expected the order to be kept:", res)
+	}
+}
@@ -2,6 +2,7 @@ package routing
 
 import (
 	"context"
+	"fmt"
 	"sort"
 
 	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
@@ -30,6 +31,12 @@ func SortLoad(next cluster.RouterHandler) cluster.RouterHandler {
 		req *bbb.Request,
 	) ([]*cluster.Backend, error) {
 		sort.Sort(BackendsByLoad(backends))
+		if cluster.IsExplaining(ctx) {
+			for _, be := range backends {
+				cluster.ExplainReason(ctx, be,
+					fmt.Sprintf("stress %.2f", be.Stress()))
+			}
+		}
 		return next(ctx, backends, req)
 	}
 }
@@ -0,0 +1,156 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNotifierDeliver(t *testing.T) {
+	received := []string{}
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			payload := map[string]string{}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Error(err)
+			}
+			received = append(received, payload["text"])
+		}))
+	defer srv.Close()
+
+	n, err := New(&Config{
+		Channels: map[string]*ChannelConfig{
+			"chat": {Webhook: srv.URL},
+		},
+		Routes: map[string][]string{
+			EventBackendDown: {"chat"},
+			RouteAll:         {"chat"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	e := NewEvent(EventBackendDown, "bbb1", "noded is not available")
+	n.Deliver(ctx, e)
+	if len(received) != 1 {
+		t.Fatal("expected one notification, got:", received)
+	}
+	if !strings.Contains(received[0], "bbb1") {
+		t.Error("unexpected text:", received[0])
+	}
+
+	// Repeated events are not sent again
+	n.Deliver(ctx, NewEvent(EventBackendDown, "bbb1", "still down"))
+	if len(received) != 1 {
+		t.Error("repeated event should not be sent:", received)
+	}
+	n.Deliver(ctx, NewEvent(EventBackendDown, "bbb2", "down"))
+	if len(received) != 2 {
+		t.Error("expected event of another subject:", received)
+	}
+}
+
+// claimFunc implements the Dedup interface
+type claimFunc func(key string) (bool, error)
+
+func (f claimFunc) Claim(
+	_ context.Context,
+	key string,
+	_ time.Time,
+	_ time.Duration,
+) (bool, error) {
+	return f(key)
+}
+
+func TestNotifierDeliverDedup(t *testing.T) {
+	received := 0
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			received++
+		}))
+	defer srv.Close()
+
+	n, err := New(&Config{
+		Channels: map[string]*ChannelConfig{
+			"chat": {Webhook: srv.URL},
+		},
+		Routes: map[string][]string{
+			RouteAll: {"chat"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	// Claimed by another instance
+	claimed := []string{}
+	n.SetDedup(claimFunc(func(key string) (bool, error) {
+		claimed = append(claimed, key)
+		return false, nil
+	}))
+	n.Deliver(ctx, NewEvent(EventQuotaExceeded, "frontend1", "queue"))
+	if received != 0 {
+		t.Error("claimed event should not be sent")
+	}
+	if len(claimed) != 1 || claimed[0] != "quota_exceeded:frontend1" {
+		t.Error("unexpected claims:", claimed)
+	}
+
+	// The event is sent if the dedup fails
+	n.SetDedup(claimFunc(func(key string) (bool, error) {
+		return false, errors.New("database is down")
+	}))
+	n.Deliver(ctx, NewEvent(EventQuotaExceeded, "frontend1", "queue"))
+	if received != 1 {
+		t.Error("event should be sent, got:", received)
+	}
+}
+
+func TestNewNotifierInvalid(t *testing.T) {
+	channels := map[string]*ChannelConfig{
+		"chat": {Webhook: "http://localhost/hook"},
+	}
+	if _, err := New(&Config{
+		Channels: channels,
+		Routes:   map[string][]string{"unknown": {"chat"}},
+	}); err == nil {
+		t.Error("expected error for unknown event type")
+	}
+	if _, err := New(&Config{
+		Channels: channels,
+		Routes:   map[string][]string{RouteAll: {"mail"}},
+	}); err == nil {
+		t.Error("expected error for unknown channel")
+	}
+	if _, err := New(&Config{
+		Channels: map[string]*ChannelConfig{"empty": {}},
+	}); err == nil {
+		t.Error("expected error for channel without target")
+	}
+}
+
+func TestSMTPChannelMessage(t *testing.T) {
+	ch, err := NewSMTPChannel(
+		"smtp://user:pw@mail.example.net",
+		"b3scale@example.net",
+		[]string{"ops@example.net"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ch.Addr != "mail.example.net:25" {
+		t.Error("unexpected addr:", ch.Addr)
+	}
+	msg := string(ch.Message(
+		NewEvent(EventQueueStuck, "commands", "waiting for 5m")))
+	if !strings.Contains(msg, "Subject: [b3scale] queue_stuck: commands\r\n") {
+		t.Error("unexpected message:", msg)
+	}
+}
@@ -0,0 +1,41 @@
+package notify
+
+import (
+	"context"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/store"
+)
+
+// StoreDedup remembers the sent events in the
+// database, so an event is sent once by all
+// instances within the repeat interval.
+type StoreDedup struct{}
+
+// Claim implements the Dedup interface
+func (StoreDedup) Claim(
+	ctx context.Context,
+	key string,
+	t time.Time,
+	repeat time.Duration,
+) (bool, error) {
+	conn, err := store.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Release()
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback(ctx)
+
+	ok, err := store.ClaimNotification(ctx, tx, key, t, repeat)
+	if err != nil {
+		return false, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return false, err
+	}
+	return ok, nil
+}
@@ -0,0 +1,93 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// The AgentRelease is the desired version of the node
+// agents. Agents with another version report the skew.
+type AgentRelease struct {
+	Version string `json:"version"`
+
+	CreatedBy string    `json:"created_by"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetAgentRelease retrieves the desired agent release.
+// This may return nil without an error.
+func GetAgentRelease(
+	ctx context.Context,
+	tx pgx.Tx,
+) (*AgentRelease, error) {
+	qry := `
+		SELECT version, created_by, updated_at
+		  FROM agent_releases
+		 WHERE id = 1`
+	r := &AgentRelease{}
+	err := tx.QueryRow(ctx, qry).Scan(
+		&r.Version,
+		&r.CreatedBy,
+		&r.UpdatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Validate checks for presence of the version
+func (r *AgentRelease) Validate() error {
+	err := ValidationError{}
+	r.Version = strings.TrimSpace(r.Version)
+	if r.Version == "" {
+		err.Add("version", ErrFieldRequired)
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// IsSkewed checks if an agent with the version differs
+// from the release. Without a release, there is no skew.
+func (r *AgentRelease) IsSkewed(version string) bool {
+	return r != nil && r.Version != version
+}
+
+// Save sets the release, replacing the current release
+func (r *AgentRelease) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		INSERT INTO agent_releases (
+			id, version, created_by
+		) VALUES (
+			1, $1, $2
+		)
+		ON CONFLICT (id) DO UPDATE
+		   SET version    = EXCLUDED.version,
+		       created_by = EXCLUDED.created_by,
+		       updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+	return tx.QueryRow(ctx, qry,
+		r.Version,
+		r.CreatedBy).Scan(&r.UpdatedAt)
+}
+
+// DeleteAgentRelease removes the release. The agents
+// no longer report a version skew.
+func DeleteAgentRelease(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `DELETE FROM agent_releases WHERE id = 1`
+	_, err := tx.Exec(ctx, qry)
+	return err
+}
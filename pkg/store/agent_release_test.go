@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAgentRelease(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	if err := DeleteAgentRelease(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	r, err := GetAgentRelease(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != nil {
+		t.Error("there should be no release:", r)
+	}
+
+	r = &AgentRelease{Version: "1.4.2"}
+	if err := r.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	r = &AgentRelease{Version: "1.4.3", CreatedBy: "admin"}
+	if err := r.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	r, err = GetAgentRelease(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r == nil || r.Version != "1.4.3" || r.CreatedBy != "admin" {
+		t.Error("unexpected release:", r)
+	}
+}
+
+func TestAgentReleaseValidate(t *testing.T) {
+	r := &AgentRelease{Version: " 1.4.2 "}
+	if err := r.Validate(); err != nil {
+		t.Error(err)
+	}
+	if r.Version != "1.4.2" {
+		t.Error("the version should be trimmed:", r.Version)
+	}
+
+	r = &AgentRelease{}
+	verr, ok := r.Validate().(ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error")
+	}
+	if _, ok := verr["version"]; !ok {
+		t.Error("the version should be required:", verr)
+	}
+}
@@ -0,0 +1,103 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// Audit log actions
+const (
+	AuditFrontendSecretRegenerated = "frontend_secret_regenerated"
+	AuditFrontendKeyCreated        = "frontend_key_created"
+	AuditFrontendKeyDeleted        = "frontend_key_deleted"
+	AuditRoomCreated               = "room_created"
+	AuditRoomDeleted               = "room_deleted"
+	AuditMeetingPinned             = "meeting_pinned"
+	AuditMeetingUnpinned           = "meeting_unpinned"
+	AuditBanCreated                = "ban_created"
+	AuditBanDeleted                = "ban_deleted"
+	AuditAgentReleaseSet           = "agent_release_set"
+	AuditAgentReleaseDeleted       = "agent_release_deleted"
+)
+
+// An AuditLogEntry records an administrative action
+// on a resource.
+type AuditLogEntry struct {
+	ID string `json:"id"`
+
+	Actor  string `json:"actor"`
+	Action string `json:"action"`
+
+	ResourceType string `json:"resource_type"`
+	ResourceID   string `json:"resource_id"`
+
+	// Details must never contain any secrets.
+	Details map[string]interface{} `json:"details"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetAuditLogEntries retrieves audit log entries
+// matching the query.
+func GetAuditLogEntries(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*AuditLogEntry, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"actor",
+		"action",
+		"resource_type",
+		"resource_id",
+		"details",
+		"created_at").
+		From("audit_log").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	cmd := rows.CommandTag()
+	results := make([]*AuditLogEntry, 0, cmd.RowsAffected())
+	for rows.Next() {
+		entry := &AuditLogEntry{}
+		err := rows.Scan(
+			&entry.ID,
+			&entry.Actor,
+			&entry.Action,
+			&entry.ResourceType,
+			&entry.ResourceID,
+			&entry.Details,
+			&entry.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, entry)
+	}
+	return results, nil
+}
+
+// Save adds the entry to the audit log. Entries
+// are never updated.
+func (e *AuditLogEntry) Save(ctx context.Context, tx pgx.Tx) error {
+	if e.Details == nil {
+		e.Details = map[string]interface{}{}
+	}
+	qry := `
+		INSERT INTO audit_log (
+			actor, action, resource_type, resource_id, details
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		RETURNING id, created_at`
+	return tx.QueryRow(ctx, qry,
+		e.Actor,
+		e.Action,
+		e.ResourceType,
+		e.ResourceID,
+		e.Details).Scan(&e.ID, &e.CreatedAt)
+}
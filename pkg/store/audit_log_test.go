@@ -0,0 +1,37 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestAuditLogEntrySave(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	entry := &AuditLogEntry{
+		Actor:        "admin42",
+		Action:       AuditFrontendSecretRegenerated,
+		ResourceType: "frontend",
+		ResourceID:   "frontend23",
+		Details: map[string]interface{}{
+			"overlap": "1h",
+		},
+	}
+	if err := entry.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if entry.ID == "" {
+		t.Error("expected ID to be assigned")
+	}
+
+	entries, err := GetAuditLogEntries(ctx, tx, Q().
+		Where("resource_id = ?", "frontend23"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Error("unexpected entries:", entries)
+	}
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// BackendDiagnosticsRetained is the number of bundles
+// kept for each backend. Older bundles are removed.
+const BackendDiagnosticsRetained = 5
+
+// BackendDiagnostics is a bundle of diagnostic
+// information collected by the node agent.
+type BackendDiagnostics struct {
+	ID        string    `json:"id"`
+	BackendID string    `json:"backend_id"`
+	CommandID *string   `json:"command_id"`
+	Size      int       `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// Bundle is the compressed tar archive. It is
+	// only loaded by GetBackendDiagnosticsBundle.
+	Bundle []byte `json:"-"`
+}
+
+// GetBackendDiagnostics retrieves the bundles matching
+// the query without the content.
+func GetBackendDiagnostics(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*BackendDiagnostics, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"backend_id",
+		"command_id",
+		"length(bundle)",
+		"created_at").
+		From("backend_diagnostics").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*BackendDiagnostics{}
+	for rows.Next() {
+		d := &BackendDiagnostics{}
+		if err := rows.Scan(
+			&d.ID,
+			&d.BackendID,
+			&d.CommandID,
+			&d.Size,
+			&d.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, d)
+	}
+	return results, rows.Err()
+}
+
+// GetBackendDiagnosticsBundle retrieves a bundle with
+// the content. This may return nil without an error.
+func GetBackendDiagnosticsBundle(
+	ctx context.Context,
+	tx pgx.Tx,
+	backendID string,
+	id string,
+) (*BackendDiagnostics, error) {
+	qry := `
+		SELECT id, backend_id, command_id, bundle, created_at
+		  FROM backend_diagnostics
+		 WHERE backend_id = $1
+		   AND id = $2`
+	d := &BackendDiagnostics{}
+	err := tx.QueryRow(ctx, qry, backendID, id).Scan(
+		&d.ID,
+		&d.BackendID,
+		&d.CommandID,
+		&d.Bundle,
+		&d.CreatedAt)
+	if err == pgx.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	d.Size = len(d.Bundle)
+	return d, nil
+}
+
+// Save stores the bundle and removes the old
+// bundles of the backend.
+func (d *BackendDiagnostics) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		INSERT INTO backend_diagnostics (
+			id, backend_id, command_id, bundle, created_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		RETURNING id, created_at`
+	if err := tx.QueryRow(ctx, qry,
+		newID(),
+		d.BackendID,
+		d.CommandID,
+		d.Bundle,
+		time.Now().UTC(),
+	).Scan(&d.ID, &d.CreatedAt); err != nil {
+		return err
+	}
+	d.Size = len(d.Bundle)
+
+	qry = `
+		DELETE FROM backend_diagnostics
+		 WHERE backend_id = $1
+		   AND id NOT IN (
+		     SELECT id FROM backend_diagnostics
+		      WHERE backend_id = $1
+		      ORDER BY created_at DESC, id
+		      LIMIT $2)`
+	_, err := tx.Exec(ctx, qry, d.BackendID, BackendDiagnosticsRetained)
+	return err
+}
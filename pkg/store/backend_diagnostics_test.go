@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBackendDiagnostics(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	var last *BackendDiagnostics
+	for i := 0; i < BackendDiagnosticsRetained+2; i++ {
+		last = &BackendDiagnostics{
+			BackendID: backend.ID,
+			Bundle:    []byte("bundle"),
+		}
+		if err := last.Save(ctx, tx); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Old bundles are removed
+	bundles, err := GetBackendDiagnostics(ctx, tx, Q().
+		Where("backend_id = ?", backend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bundles) != BackendDiagnosticsRetained {
+		t.Error("unexpected bundles:", len(bundles))
+	}
+	if bundles[0].Size != 6 {
+		t.Error("unexpected size:", bundles[0].Size)
+	}
+
+	d, err := GetBackendDiagnosticsBundle(ctx, tx, backend.ID, last.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d == nil {
+		t.Fatal("bundle not found")
+	}
+	if string(d.Bundle) != "bundle" {
+		t.Error("unexpected bundle:", string(d.Bundle))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"net/url"
 	"strings"
 	"time"
 
@@ -37,6 +38,18 @@ type BackendState struct {
 
 	LoadFactor float64 `json:"load_factor"`
 
+	// Version is the BBB version detected by
+	// the node agent, e.g. 2.6.10
+	Version string `json:"version"`
+
+	// AgentVersion is the version of the node agent.
+	// It is only updated by the agent.
+	AgentVersion string `json:"agent_version"`
+
+	// AgentProtocol is the protocol version advertised
+	// by the node agent, 0 for agents without handshake.
+	AgentProtocol int `json:"agent_protocol"`
+
 	Backend *bbb.Backend `json:"bbb"`
 
 	Settings BackendSettings `json:"settings"`
@@ -81,6 +94,9 @@ func GetBackendStates(
 		"backends.meetings_count",
 		"backends.attendees_count",
 		"backends.load_factor",
+		"backends.bbb_version",
+		"backends.agent_version",
+		"backends.agent_protocol",
 		"backends.host",
 		"backends.secret",
 		"backends.settings",
@@ -108,6 +124,9 @@ func GetBackendStates(
 			&state.MeetingsCount,
 			&state.AttendeesCount,
 			&state.LoadFactor,
+			&state.Version,
+			&state.AgentVersion,
+			&state.AgentProtocol,
 			&state.Backend.Host,
 			&state.Backend.Secret,
 			&state.Settings,
@@ -187,6 +206,8 @@ func (s *BackendState) insert(
 ) (string, error) {
 	qry := `
 		INSERT INTO backends (
+			id,
+
 			host,
 			secret,
 
@@ -195,20 +216,23 @@ func (s *BackendState) insert(
 
 			settings,
 
-			load_factor
+			load_factor,
+			bbb_version
 		)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
 		RETURNING id
 	`
 	insertID := ""
 	err := tx.QueryRow(ctx, qry,
 		// Values
+		newID(),
 		s.Backend.Host,
 		s.Backend.Secret,
 		s.NodeState,
 		s.AdminState,
 		s.Settings,
-		s.LoadFactor).Scan(&insertID)
+		s.LoadFactor,
+		s.Version).Scan(&insertID)
 
 	return insertID, err
 }
@@ -233,9 +257,10 @@ func (s *BackendState) update(
 			   settings     = $8,
 
 			   load_factor  = $9,
+			   bbb_version  = $10,
 
-			   synced_at    = $10,
-			   updated_at   = $11
+			   synced_at    = $11,
+			   updated_at   = $12
 
 		 WHERE id = $1
 	`
@@ -252,16 +277,81 @@ func (s *BackendState) update(
 		s.Backend.Secret,
 		s.Settings,
 		s.LoadFactor,
+		s.Version,
 		s.SyncedAt,
 		time.Now().UTC())
 
 	return err
 }
 
-// Delete will remove the backend from the store
+// A BackendInUseError is returned when deleting a backend
+// which still has running meetings.
+type BackendInUseError struct {
+	BackendID       string   `json:"backend_id"`
+	RunningMeetings []string `json:"running_meetings"`
+}
+
+// Error implements the error interface
+func (e *BackendInUseError) Error() string {
+	return fmt.Sprintf(
+		"backend %s has %d running meetings",
+		e.BackendID, len(e.RunningMeetings))
+}
+
+// RunningMeetingIDs retrieves the IDs of the meetings
+// running on the backend.
+func (s *BackendState) RunningMeetingIDs(
+	ctx context.Context,
+	tx pgx.Tx,
+) ([]string, error) {
+	qry := `
+		SELECT id FROM meetings
+		 WHERE backend_id = $1
+		   AND state->'Running' = 'true'::jsonb
+		 ORDER BY id
+		   FOR UPDATE
+	`
+	rows, err := tx.Query(ctx, qry, s.ID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	ids := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
+// Delete will remove the backend from the store.
+// A *BackendInUseError is returned if meetings are
+// still running on the backend.
 func (s *BackendState) Delete(
 	ctx context.Context,
 	tx pgx.Tx,
+) error {
+	running, err := s.RunningMeetingIDs(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if len(running) > 0 {
+		return &BackendInUseError{
+			BackendID:       s.ID,
+			RunningMeetings: running,
+		}
+	}
+	return s.ForceDelete(ctx, tx)
+}
+
+// ForceDelete will remove the backend from the store,
+// even if meetings are running.
+func (s *BackendState) ForceDelete(
+	ctx context.Context,
+	tx pgx.Tx,
 ) error {
 	// For now we take all the meetings with us.
 	qry := `
@@ -302,12 +392,42 @@ func (s *BackendState) UpdateAgentHeartbeat(
 	return nil
 }
 
+// UpdateAgentVersion sets the version and the
+// protocol version of the node agent
+func (s *BackendState) UpdateAgentVersion(
+	ctx context.Context,
+	tx pgx.Tx,
+	version string,
+	protocol int,
+) error {
+	qry := `
+		UPDATE backends
+		   SET agent_version  = $2,
+		       agent_protocol = $3
+		 WHERE id = $1
+	`
+	if _, err := tx.Exec(ctx, qry, s.ID, version, protocol); err != nil {
+		return err
+	}
+	s.AgentVersion = version
+	s.AgentProtocol = protocol
+	return nil
+}
+
+// AgentAliveThreshold is the maximum age of the
+// heartbeat of a node agent considered alive.
+const AgentAliveThreshold = 5 * time.Second
+
 // IsAgentAlive checks if the heartbeat is older
 // than the threshold
 func (s *BackendState) IsAgentAlive() bool {
-	threshold := 5 * time.Second
+	return isAgentAlive(s.AgentHeartbeat)
+}
+
+// isAgentAlive checks the age of the heartbeat
+func isAgentAlive(heartbeat time.Time) bool {
 	now := time.Now().UTC()
-	return now.Sub(s.AgentHeartbeat) <= threshold
+	return now.Sub(heartbeat) <= AgentAliveThreshold
 }
 
 // IsNodeReady checks if the agent is alive and the node
@@ -376,15 +496,18 @@ func (s *BackendState) UpdateStatCounters(
 
 // CreateMeetingState will create a new state for the
 // current backend state. A frontend is attached if present.
+// The labels may be nil.
 func (s *BackendState) CreateMeetingState(
 	ctx context.Context,
 	tx pgx.Tx,
 	frontend *bbb.Frontend,
 	meeting *bbb.Meeting,
+	labels Labels,
 ) (*MeetingState, error) {
 	mstate := InitMeetingState(&MeetingState{
 		BackendID: &s.ID,
 		Meeting:   meeting,
+		Labels:    labels,
 	})
 	mstate.MarkSynced()
 
@@ -456,9 +579,88 @@ func (s *BackendState) Validate() ValidationError {
 		err.Add("bbb.secret", ErrFieldRequired)
 	}
 
+	// Timeouts
+	if _, terr := s.Settings.RequestTimeouts(); terr != nil {
+		err.Add("settings.timeouts", terr.Error())
+	}
+
+	// Log links
+	if lerr := s.Settings.ValidateLogURLs(); lerr != nil {
+		err.Add("settings.log_urls", lerr.Error())
+	}
+
+	// TLS
+	if s.Settings.TLS != nil {
+		if terr := s.Settings.TLS.Validate(); terr != nil {
+			err.Add("settings.tls", terr.Error())
+		}
+	}
+
+	// Proxy
+	if proxy := s.Settings.Proxy; proxy != "" && proxy != bbb.ProxyDirect {
+		if _, perr := bbb.ParseProxyURL(proxy); perr != nil {
+			err.Add("settings.proxy", perr.Error())
+		}
+	}
+
+	// Address family
+	if ferr := bbb.ValidateAddressFamily(s.Settings.AddressFamily); ferr != nil {
+		err.Add("settings.address_family", ferr.Error())
+	}
+
+	// Pinned addresses
+	if _, aerr := bbb.ParseAddresses(s.Settings.Addresses); aerr != nil {
+		err.Add("settings.addresses", aerr.Error())
+	}
+
+	// Connect address and server name
+	if connect := s.Settings.ConnectAddress; connect != "" {
+		if cerr := bbb.ValidateConnectAddress(connect); cerr != nil {
+			err.Add("settings.connect_address", cerr.Error())
+		}
+		if len(s.Settings.Addresses) > 0 {
+			err.Add("settings.connect_address",
+				bbb.ErrConnectAddressPinned.Error())
+		}
+	}
+	if strings.ContainsAny(s.Settings.ServerName, ":/ ") {
+		err.Add("settings.server_name", "should be a host name")
+	}
+
+	// Canary
+	canary := s.Settings.Canary
+	if canary != nil && (canary.Weight < 0 || canary.Weight > 1) {
+		err.Add("settings.canary.weight", "should be between 0 and 1")
+	}
+
 	if len(err) > 0 {
 		return err
 	}
 
 	return nil
 }
+
+// ConnOptions creates the connection options
+// of the backend client.
+func (s *BackendState) ConnOptions() *bbb.ConnOptions {
+	settings := s.Settings
+	opts := &bbb.ConnOptions{
+		Proxy:         settings.Proxy,
+		AddressFamily: settings.AddressFamily,
+		Addresses:     settings.Addresses,
+
+		ConnectAddress: settings.ConnectAddress,
+		ServerName:     settings.ServerName,
+	}
+	if settings.TLS != nil {
+		opts.ClientCert = settings.TLS.ClientCert
+		opts.ClientKey = settings.TLS.ClientKey
+		opts.RootCA = settings.TLS.RootCA
+	}
+	if s.Backend != nil {
+		if u, err := url.Parse(s.Backend.Host); err == nil {
+			opts.Host = u.Hostname()
+		}
+	}
+	return opts
+}
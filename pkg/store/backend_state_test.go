@@ -112,12 +112,64 @@ func TestCreateMeeting(t *testing.T) {
 		MeetingID:         uuid.New().String(),
 		InternalMeetingID: uuid.New().String(),
 		MeetingName:       "foo",
-	})
+	}, Labels{"course": "physics-101"})
 	if err != nil {
 		t.Error(err)
 		return
 	}
 	t.Log(mstate.ID)
+	if mstate.Labels["course"] != "physics-101" {
+		t.Error("unexpected labels:", mstate.Labels)
+	}
+}
+
+func TestBackendStateDelete(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	m, err := meetingStateFactory(ctx, tx, &MeetingState{
+		ID:         uuid.New().String(),
+		InternalID: uuid.New().String(),
+		Meeting: &bbb.Meeting{
+			Running: true,
+		}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.backend.Delete(ctx, tx)
+	inUse, ok := err.(*BackendInUseError)
+	if !ok {
+		t.Fatal("expected a BackendInUseError, got:", err)
+	}
+	if len(inUse.RunningMeetings) != 1 ||
+		inUse.RunningMeetings[0] != m.ID {
+		t.Error("unexpected running meetings:", inUse.RunningMeetings)
+	}
+
+	if err := m.backend.ForceDelete(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	state, err := GetBackendState(ctx, tx, Q().Where("id = ?", m.backend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state != nil {
+		t.Error("backend should be deleted")
+	}
+
+	// Backends without running meetings can be deleted
+	bstate := backendStateFactory()
+	if err := bstate.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := bstate.Delete(ctx, tx); err != nil {
+		t.Error(err)
+	}
 }
 
 func TestBackendStateAgentHeartbeat(t *testing.T) {
@@ -146,6 +198,29 @@ func TestBackendStateAgentHeartbeat(t *testing.T) {
 	}
 }
 
+func TestBackendStateAgentVersion(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	state := backendStateFactory()
+	if err := state.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := state.UpdateAgentVersion(ctx, tx, "1.4.2", 1); err != nil {
+		t.Fatal(err)
+	}
+
+	// Saving the state must not reset the version
+	if err := state.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if state.AgentVersion != "1.4.2" || state.AgentProtocol != 1 {
+		t.Error("unexpected agent version:",
+			state.AgentVersion, state.AgentProtocol)
+	}
+}
+
 func TestBackendStateValidate(t *testing.T) {
 	valid := &BackendState{
 		Backend: &bbb.Backend{
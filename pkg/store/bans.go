@@ -0,0 +1,161 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// Kinds of bans
+const (
+	// BanMeetingID blocks a meeting ID of the frontend
+	BanMeetingID = "meeting_id"
+
+	// BanFullName blocks joins with a full name matching
+	// a regular expression. The case is ignored.
+	BanFullName = "full_name"
+)
+
+// A Ban blocks meetings or users at the gateway,
+// e.g. in case of spam or abuse.
+type Ban struct {
+	ID string `json:"id"`
+
+	// FrontendID is nil for bans of all frontends
+	FrontendID *string `json:"frontend_id"`
+
+	Kind    string `json:"kind"`
+	Pattern string `json:"pattern"`
+
+	Reason    string    `json:"reason"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetBans retrieves the bans matching the query
+func GetBans(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*Ban, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"frontend_id",
+		"kind",
+		"pattern",
+		"reason",
+		"created_by",
+		"created_at").
+		From("bans").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*Ban{}
+	for rows.Next() {
+		b := &Ban{}
+		if err := rows.Scan(
+			&b.ID,
+			&b.FrontendID,
+			&b.Kind,
+			&b.Pattern,
+			&b.Reason,
+			&b.CreatedBy,
+			&b.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, b)
+	}
+	return results, rows.Err()
+}
+
+// GetBan retrieves a single ban.
+// This may return nil without an error.
+func GetBan(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*Ban, error) {
+	bans, err := GetBans(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(bans) == 0 {
+		return nil, nil
+	}
+	return bans[0], nil
+}
+
+// Validate checks the kind and the pattern of the ban
+func (b *Ban) Validate() error {
+	err := ValidationError{}
+	b.Pattern = strings.TrimSpace(b.Pattern)
+	if b.Pattern == "" {
+		err.Add("pattern", ErrFieldRequired)
+	}
+	switch b.Kind {
+	case BanMeetingID:
+	case BanFullName:
+		if _, rerr := b.NamePattern(); rerr != nil {
+			err.Add("pattern", rerr.Error())
+		}
+	case "":
+		err.Add("kind", ErrFieldRequired)
+	default:
+		err.Add("kind", "must be meeting_id or full_name")
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// NamePattern compiles the case insensitive
+// pattern of a full name ban.
+func (b *Ban) NamePattern() (*regexp.Regexp, error) {
+	return regexp.Compile("(?i)" + b.Pattern)
+}
+
+// AppliesTo checks if the ban applies to the frontend
+func (b *Ban) AppliesTo(frontendID string) bool {
+	return b.FrontendID == nil || *b.FrontendID == frontendID
+}
+
+// Save creates the ban
+func (b *Ban) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		INSERT INTO bans (
+			id, frontend_id, kind, pattern, reason, created_by
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		RETURNING id, created_at`
+	return tx.QueryRow(ctx, qry,
+		newID(),
+		b.FrontendID,
+		b.Kind,
+		b.Pattern,
+		b.Reason,
+		b.CreatedBy).Scan(&b.ID, &b.CreatedAt)
+}
+
+// DeleteBan removes a ban
+func DeleteBan(
+	ctx context.Context,
+	tx pgx.Tx,
+	id string,
+) error {
+	qry := `DELETE FROM bans WHERE id = $1`
+	_, err := tx.Exec(ctx, qry, id)
+	return err
+}
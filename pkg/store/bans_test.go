@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBans(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	ban := &Ban{
+		FrontendID: &frontend.ID,
+		Kind:       BanFullName,
+		Pattern:    "^spam ",
+		Reason:     "spam bot",
+	}
+	if err := ban.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := ban.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := GetBan(ctx, tx, Q().Where("id = ?", ban.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || !found.AppliesTo(frontend.ID) || found.AppliesTo("other") {
+		t.Error("unexpected ban:", found)
+	}
+
+	if err := DeleteBan(ctx, tx, ban.ID); err != nil {
+		t.Fatal(err)
+	}
+	found, err = GetBan(ctx, tx, Q().Where("id = ?", ban.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != nil {
+		t.Error("the ban should be deleted")
+	}
+}
+
+func TestBanValidate(t *testing.T) {
+	ban := &Ban{Kind: BanFullName, Pattern: "(spam"}
+	verr, ok := ban.Validate().(ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error")
+	}
+	if _, ok := verr["pattern"]; !ok {
+		t.Error("expected an error for the pattern:", verr)
+	}
+
+	ban = &Ban{Kind: "ip", Pattern: "192.0.2.1"}
+	verr, ok = ban.Validate().(ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error")
+	}
+	if _, ok := verr["kind"]; !ok {
+		t.Error("expected an error for the kind:", verr)
+	}
+
+	ban = &Ban{Kind: BanFullName, Pattern: "^SPAM"}
+	re, err := ban.NamePattern()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !re.MatchString("spam user") {
+		t.Error("the pattern should ignore the case")
+	}
+}
@@ -0,0 +1,26 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ClaimBillingExport marks the day as exported. The
+// result is false if the day was already claimed.
+func ClaimBillingExport(
+	ctx context.Context,
+	tx pgx.Tx,
+	day time.Time,
+) (bool, error) {
+	qry := `
+		INSERT INTO billing_exports (day)
+		VALUES ($1::date)
+		ON CONFLICT (day) DO NOTHING`
+	tag, err := tx.Exec(ctx, qry, day.UTC().Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,29 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClaimBillingExport(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	day := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	claimed, err := ClaimBillingExport(ctx, tx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Error("day should be claimed")
+	}
+	claimed, err = ClaimBillingExport(ctx, tx, day.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed {
+		t.Error("day should not be claimed twice")
+	}
+}
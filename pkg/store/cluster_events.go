@@ -0,0 +1,130 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+const clusterEventsChannel = "cluster_events"
+
+// Cluster event types
+const (
+	ClusterEventBackendAdded        = "backend_added"
+	ClusterEventBackendRemoved      = "backend_removed"
+	ClusterEventBackendStateChanged = "backend_state_changed"
+	ClusterEventMeetingCreated      = "meeting_created"
+	ClusterEventMeetingEnded        = "meeting_ended"
+	ClusterEventCommandExecuted     = "command_executed"
+)
+
+// A ClusterEvent is published by the database triggers
+// when the state of the cluster changes.
+type ClusterEvent struct {
+	Type string          `json:"type"`
+	Time time.Time       `json:"time"`
+	Data json.RawMessage `json:"data"`
+}
+
+// clusterEventsBufferSize is the number of events
+// buffered for each subscriber. Events are dropped
+// for slow subscribers.
+const clusterEventsBufferSize = 64
+
+// The ClusterEventsBroker listens for cluster events
+// on a single connection and distributes them to all
+// subscribers.
+type ClusterEventsBroker struct {
+	mu          sync.Mutex
+	subscribers map[chan *ClusterEvent]bool
+}
+
+// NewClusterEventsBroker creates a new broker
+func NewClusterEventsBroker() *ClusterEventsBroker {
+	return &ClusterEventsBroker{
+		subscribers: make(map[chan *ClusterEvent]bool),
+	}
+}
+
+// Subscribe creates a new channel receiving
+// all cluster events.
+func (b *ClusterEventsBroker) Subscribe() chan *ClusterEvent {
+	ch := make(chan *ClusterEvent, clusterEventsBufferSize)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subscribers[ch] = true
+	return ch
+}
+
+// Unsubscribe removes the channel from the subscribers
+func (b *ClusterEventsBroker) Unsubscribe(ch chan *ClusterEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.subscribers, ch)
+}
+
+// Publish sends the event to all subscribers.
+// A subscriber not keeping up will miss the event.
+func (b *ClusterEventsBroker) Publish(event *ClusterEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			log.Warn().
+				Str("type", event.Type).
+				Msg("dropping cluster event for slow subscriber")
+		}
+	}
+}
+
+// Start listens for cluster events until the context
+// is canceled. The connection is reestablished on errors.
+func (b *ClusterEventsBroker) Start(ctx context.Context) {
+	for {
+		err := b.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Error().Err(err).Msg("listening for cluster events")
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// listen receives the notifications and
+// publishes the decoded events
+func (b *ClusterEventsBroker) listen(ctx context.Context) error {
+	conn, err := Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	listen := "LISTEN " + pgx.Identifier{clusterEventsChannel}.Sanitize()
+	if _, err := conn.Exec(ctx, listen); err != nil {
+		return err
+	}
+	defer func() {
+		// The connection is returned to the pool
+		unlisten := "UNLISTEN " + pgx.Identifier{clusterEventsChannel}.Sanitize()
+		conn.Exec(context.Background(), unlisten)
+	}()
+
+	for {
+		n, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		event := &ClusterEvent{}
+		if err := json.Unmarshal([]byte(n.Payload), event); err != nil {
+			log.Error().Err(err).Msg("decoding cluster event")
+			continue
+		}
+		b.Publish(event)
+	}
+}
@@ -0,0 +1,36 @@
+package store
+
+import (
+	"testing"
+)
+
+func TestClusterEventsBrokerPublish(t *testing.T) {
+	b := NewClusterEventsBroker()
+	ch1 := b.Subscribe()
+	ch2 := b.Subscribe()
+
+	b.Publish(&ClusterEvent{Type: ClusterEventMeetingCreated})
+	if e := <-ch1; e.Type != ClusterEventMeetingCreated {
+		t.Error("unexpected event:", e)
+	}
+	if e := <-ch2; e.Type != ClusterEventMeetingCreated {
+		t.Error("unexpected event:", e)
+	}
+
+	b.Unsubscribe(ch2)
+	b.Publish(&ClusterEvent{Type: ClusterEventMeetingEnded})
+	if e := <-ch1; e.Type != ClusterEventMeetingEnded {
+		t.Error("unexpected event:", e)
+	}
+	if len(ch2) != 0 {
+		t.Error("unsubscribed channel received event")
+	}
+
+	// Slow subscribers must not block
+	for i := 0; i < clusterEventsBufferSize+1; i++ {
+		b.Publish(&ClusterEvent{Type: ClusterEventCommandExecuted})
+	}
+	if len(ch1) != clusterEventsBufferSize {
+		t.Error("unexpected buffered events:", len(ch1))
+	}
+}
@@ -0,0 +1,172 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// Health of a backend in the topology
+const (
+	BackendHealthOK      = "ok"
+	BackendHealthStopped = "stopped"
+	BackendHealthOffline = "offline"
+	BackendHealthError   = "error"
+	BackendHealthInit    = "init"
+)
+
+// TopologyMeeting is a meeting running on
+// a backend of the cluster topology.
+type TopologyMeeting struct {
+	ID          string    `json:"id"`
+	InternalID  *string   `json:"internal_id"`
+	MeetingName string    `json:"meeting_name"`
+	FrontendID  *string   `json:"frontend_id"`
+	FrontendKey *string   `json:"frontend_key"`
+	Running     bool      `json:"running"`
+	Attendees   int       `json:"attendees"`
+	Labels      Labels    `json:"labels"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// TopologyBackend is a backend with its meetings
+type TopologyBackend struct {
+	ID   string `json:"id"`
+	Host string `json:"host"`
+
+	// Health is derived from the admin and node
+	// state and the heartbeat of the node agent.
+	Health     string `json:"health"`
+	NodeState  string `json:"node_state"`
+	AdminState string `json:"admin_state"`
+	AgentAlive bool   `json:"agent_alive"`
+
+	LastError *string       `json:"last_error"`
+	Latency   time.Duration `json:"latency"`
+
+	LoadFactor   float64 `json:"load_factor"`
+	Version      string  `json:"version"`
+	AgentVersion string  `json:"agent_version"`
+	Tags         Tags    `json:"tags"`
+
+	MeetingsCount  int                `json:"meetings_count"`
+	AttendeesCount int                `json:"attendees_count"`
+	Meetings       []*TopologyMeeting `json:"meetings"`
+
+	SyncedAt time.Time `json:"synced_at"`
+}
+
+// ClusterTopology is a snapshot of the backends
+// and the meetings in the cluster.
+type ClusterTopology struct {
+	Backends  []*TopologyBackend `json:"backends"`
+	Meetings  int                `json:"meetings"`
+	Attendees int                `json:"attendees"`
+	CreatedAt time.Time          `json:"created_at"`
+}
+
+// backendHealth summarizes the state of a backend
+func backendHealth(b *TopologyBackend) string {
+	if b.AdminState != "ready" {
+		return BackendHealthStopped
+	}
+	if !b.AgentAlive {
+		return BackendHealthOffline
+	}
+	switch b.NodeState {
+	case "ready":
+		return BackendHealthOK
+	case "error":
+		return BackendHealthError
+	}
+	return BackendHealthInit
+}
+
+// GetClusterTopology retrieves the backends matching
+// the query with their meetings in a single query.
+// The meetings and attendees are counted from the
+// meetings in the store.
+func GetClusterTopology(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*ClusterTopology, error) {
+	qry, params, _ := q.
+		Columns(
+			"backends.id",
+			"backends.host",
+			"backends.node_state",
+			"backends.admin_state",
+			"backends.agent_heartbeat",
+			"backends.last_error",
+			"backends.latency",
+			"backends.load_factor",
+			"backends.bbb_version",
+			"backends.agent_version",
+			"COALESCE(backends.settings->'tags', '[]'::jsonb)",
+			"backends.synced_at",
+			"COUNT(meetings.id)",
+			`COALESCE(SUM(
+				(meetings.state->>'ParticipantCount')::int), 0)`,
+			`COALESCE(json_agg(json_build_object(
+				'id', meetings.id,
+				'internal_id', meetings.internal_id,
+				'meeting_name', meetings.state->>'MeetingName',
+				'frontend_id', meetings.frontend_id,
+				'frontend_key', frontends.key,
+				'running', COALESCE(
+					(meetings.state->>'Running')::boolean, false),
+				'attendees', COALESCE(
+					(meetings.state->>'ParticipantCount')::int, 0),
+				'labels', meetings.labels,
+				'created_at', meetings.created_at)
+				ORDER BY meetings.created_at, meetings.id)
+				FILTER (WHERE meetings.id IS NOT NULL), '[]')`).
+		From("backends").
+		LeftJoin("meetings ON meetings.backend_id = backends.id").
+		LeftJoin("frontends ON frontends.id = meetings.frontend_id").
+		GroupBy("backends.id").
+		OrderBy("backends.host ASC").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	topology := &ClusterTopology{
+		Backends:  []*TopologyBackend{},
+		CreatedAt: time.Now().UTC(),
+	}
+	for rows.Next() {
+		b := &TopologyBackend{}
+		var heartbeat time.Time
+		if err := rows.Scan(
+			&b.ID,
+			&b.Host,
+			&b.NodeState,
+			&b.AdminState,
+			&heartbeat,
+			&b.LastError,
+			&b.Latency,
+			&b.LoadFactor,
+			&b.Version,
+			&b.AgentVersion,
+			&b.Tags,
+			&b.SyncedAt,
+			&b.MeetingsCount,
+			&b.AttendeesCount,
+			&b.Meetings,
+		); err != nil {
+			return nil, err
+		}
+		b.AgentAlive = isAgentAlive(heartbeat)
+		b.Health = backendHealth(b)
+		topology.Meetings += b.MeetingsCount
+		topology.Attendees += b.AttendeesCount
+		topology.Backends = append(topology.Backends, b)
+	}
+	return topology, rows.Err()
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestBackendHealth(t *testing.T) {
+	tests := []struct {
+		backend *TopologyBackend
+		health  string
+	}{
+		{&TopologyBackend{
+			AdminState: "ready", NodeState: "ready", AgentAlive: true,
+		}, BackendHealthOK},
+		{&TopologyBackend{
+			AdminState: "stopped", NodeState: "ready", AgentAlive: true,
+		}, BackendHealthStopped},
+		{&TopologyBackend{
+			AdminState: "ready", NodeState: "ready", AgentAlive: false,
+		}, BackendHealthOffline},
+		{&TopologyBackend{
+			AdminState: "ready", NodeState: "error", AgentAlive: true,
+		}, BackendHealthError},
+		{&TopologyBackend{
+			AdminState: "ready", NodeState: "init", AgentAlive: true,
+		}, BackendHealthInit},
+	}
+	for _, tt := range tests {
+		if h := backendHealth(tt.backend); h != tt.health {
+			t.Error("unexpected health:", h, "expected:", tt.health)
+		}
+	}
+}
+
+func TestGetClusterTopology(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	m, err := meetingStateFactory(ctx, tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Meeting.ParticipantCount = 42
+	m.Labels = Labels{"course": "physics-101"}
+	if err := m.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	topology, err := GetClusterTopology(ctx, tx, Q().
+		Where("backends.id = ?", m.backend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topology.Backends) != 1 {
+		t.Fatal("unexpected backends:", topology.Backends)
+	}
+	b := topology.Backends[0]
+	if b.Host != m.backend.Backend.Host {
+		t.Error("unexpected host:", b.Host)
+	}
+	if len(b.Tags) != 3 {
+		t.Error("unexpected tags:", b.Tags)
+	}
+	if b.MeetingsCount != 1 || b.AttendeesCount != 42 {
+		t.Error("unexpected counts:", b.MeetingsCount, b.AttendeesCount)
+	}
+	if topology.Meetings != 1 || topology.Attendees != 42 {
+		t.Error("unexpected totals:", topology.Meetings, topology.Attendees)
+	}
+	if len(b.Meetings) != 1 {
+		t.Fatal("unexpected meetings:", b.Meetings)
+	}
+	meeting := b.Meetings[0]
+	if meeting.ID != m.ID {
+		t.Error("unexpected meeting:", meeting.ID)
+	}
+	if meeting.Attendees != 42 {
+		t.Error("unexpected attendees:", meeting.Attendees)
+	}
+	if meeting.FrontendKey == nil ||
+		*meeting.FrontendKey != m.frontend.Frontend.Key {
+		t.Error("unexpected frontend:", meeting.FrontendKey)
+	}
+	if meeting.Labels["course"] != "physics-101" {
+		t.Error("unexpected labels:", meeting.Labels)
+	}
+	if b.Health != BackendHealthOffline {
+		t.Error("backend without agent should be offline:", b.Health)
+	}
+}
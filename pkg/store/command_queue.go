@@ -12,21 +12,48 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
+	sq "github.com/Masterminds/squirrel"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 )
 
-const cmdQueue = "commands_queue"
+const (
+	cmdQueue    = "commands_queue"
+	cmdFinished = "commands_finished"
+)
+
+// CommandsProcessed counts the processed commands
+// by action and resulting state (success or error).
+var CommandsProcessed = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "commands_processed_total",
+		Help: "Number of commands processed by this instance",
+	},
+	[]string{
+		// Command action, e.g. update_node_state
+		"action",
+		// State is either "success" or "error"
+		"state",
+	})
 
 // CommandHandler is a callback function for handling
 // commands. The command was successful if no error was
 // returned.
 type CommandHandler func(context.Context, *Command) (interface{}, error)
 
+// validatedParams are checked before the
+// command is added to the queue.
+type validatedParams interface {
+	Validate() error
+}
+
 // A Command is a representation of an operation
 type Command struct {
 	ID  string `json:"id"`
@@ -38,6 +65,14 @@ type Command struct {
 	Params interface{} `json:"params"`
 	Result interface{} `json:"result"`
 
+	// The command is not processed before RunAt
+	RunAt *time.Time `json:"run_at"`
+
+	// Handler is the identity of the queue processing
+	// the command. Without a handler, the command is
+	// processed by any b3scale instance.
+	Handler *string `json:"handler"`
+
 	Deadline  time.Time  `json:"deadline"`
 	StartedAt *time.Time `json:"started_at"`
 	StoppedAt *time.Time `json:"stopped_at"`
@@ -46,6 +81,12 @@ type Command struct {
 	tx pgx.Tx
 }
 
+// Finished is true if the command was processed
+// successfully or failed.
+func (cmd *Command) Finished() bool {
+	return cmd.State == "success" || cmd.State == "error"
+}
+
 // FetchParams loads the parameters and decodes them
 func (cmd *Command) FetchParams(
 	ctx context.Context,
@@ -55,63 +96,189 @@ func (cmd *Command) FetchParams(
 	return cmd.tx.QueryRow(ctx, qry, cmd.ID).Scan(req)
 }
 
+// BackendAgentHandler is the identity of the node
+// agent of a backend, handling commands for the host.
+func BackendAgentHandler(backendID string) string {
+	return "agent:" + backendID
+}
+
 // The CommandQueue is connected to the database and
 // provides methods for queuing and dequeuing commands.
 type CommandQueue struct {
 	subscription *pgxpool.Conn
 	trigger      chan bool
+
+	// handler is the identity of the queue. Only
+	// commands for the handler are dequeued.
+	handler string
 }
 
 // NewCommandQueue initializes a new command queue
+// for commands without a handler.
 func NewCommandQueue() *CommandQueue {
-	return &CommandQueue{}
+	return NewHandlerCommandQueue("")
 }
 
-// QueueCommand adds a new command to the queue
+// NewHandlerCommandQueue initializes a new command queue
+// dequeuing only the commands for the handler.
+func NewHandlerCommandQueue(handler string) *CommandQueue {
+	return &CommandQueue{
+		trigger: make(chan bool, 1),
+		handler: handler,
+	}
+}
+
+// QueueCommand adds a new command to the queue.
+// Invalid params are rejected.
 func QueueCommand(ctx context.Context, tx pgx.Tx, cmd *Command) error {
-	// Our command will always expire. For now 2 minutes.
-	deadline := time.Now().UTC().Add(120 * time.Second)
+	if v, ok := cmd.Params.(validatedParams); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// Our command will always expire. Without a deadline
+	// of the command, this is after 2 minutes. Scheduled
+	// commands expire relative to the run time.
+	now := time.Now().UTC()
+	window := 120 * time.Second
+	if !cmd.Deadline.IsZero() {
+		window = cmd.Deadline.Sub(now)
+	}
+	start := now
+	if cmd.RunAt != nil {
+		runAt := cmd.RunAt.UTC()
+		cmd.RunAt = &runAt
+		if runAt.After(now) {
+			start = runAt
+		}
+	}
+	deadline := start.Add(window)
 	// Marshal payload
 	params, err := json.Marshal(cmd.Params)
+	if err != nil {
+		return err
+	}
 	// Add command to queue and notify instances
 	qry := `
 	  INSERT INTO commands (
+	  	id,
 	  	action,
 		params,
-		deadline
+		run_at,
+		deadline,
+		handler
 	  ) VALUES (
-		$1, $2, $3
+		$1, $2, $3, $4, $5, $6
 	  )
-	  RETURNING id`
-	var cmdID string
-	err = tx.QueryRow(ctx, qry, cmd.Action, params, deadline).
-		Scan(&cmdID)
+	  RETURNING id, seq`
+	var (
+		cmdID  string
+		cmdSeq int
+	)
+	err = tx.QueryRow(ctx, qry,
+		newID(), cmd.Action, params, cmd.RunAt, deadline, cmd.Handler).
+		Scan(&cmdID, &cmdSeq)
 	if err != nil {
 		return err
 	}
 
 	// Update command
 	cmd.ID = cmdID
+	cmd.Seq = cmdSeq
+	cmd.State = "requested"
+	cmd.Deadline = deadline
 	cmd.CreatedAt = time.Now().UTC()
 	return nil
 }
 
+// commandPollInterval is the interval in which the queue
+// is checked without a notification, e.g. for scheduled
+// commands or commands queued while reconnecting.
+const commandPollInterval = 1 * time.Second
+
+// decodeQueueNotification parses the payload of a
+// notification about a new command: "<seq>:<action>".
+func decodeQueueNotification(payload string) (int, string, error) {
+	tokens := strings.SplitN(payload, ":", 2)
+	if len(tokens) != 2 {
+		return 0, "", fmt.Errorf("invalid command notification: %s", payload)
+	}
+	seq, err := strconv.Atoi(tokens[0])
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid command notification: %s", payload)
+	}
+	return seq, tokens[1], nil
+}
+
 // Receive will await a command and will block
-// until a command can be processed. If the handler
-// responds with an error, the error will be returned.
+// until a command can be processed. Only commands for
+// the handler of the queue are processed. The notification
+// identifies the new command, so it is fetched directly.
+// Only if listening fails, an error is returned.
 func (q *CommandQueue) Receive(handler CommandHandler) error {
+	ctx := context.Background()
+	conn, err := Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+
+	listen := "LISTEN " + pgx.Identifier{cmdQueue}.Sanitize()
+	if _, err := conn.Exec(ctx, listen); err != nil {
+		return err
+	}
+	defer func() {
+		// The connection is returned to the pool
+		unlisten := "UNLISTEN " + pgx.Identifier{cmdQueue}.Sanitize()
+		conn.Exec(context.Background(), unlisten)
+	}()
+	q.subscription = conn
+
+	// Wait for notifications in the background, so
+	// the queue can be polled in the meantime.
+	queued := make(chan int)
+	errs := make(chan error, 1)
+	go func() {
+		for {
+			n, err := conn.Conn().WaitForNotification(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+			seq, action, err := decodeQueueNotification(n.Payload)
+			if err != nil {
+				log.Warn().Err(err).Msg("command notification")
+			} else {
+				log.Debug().
+					Int("seq", seq).
+					Str("action", action).
+					Msg("command queued")
+			}
+			queued <- seq
+		}
+	}()
+
+	poll := time.NewTicker(commandPollInterval)
+	defer poll.Stop()
 	for {
+		seq := 0 // Next in queue
+		select {
+		case err := <-errs:
+			return err
+		case seq = <-queued:
+		case <-q.trigger:
+		case <-poll.C:
+		}
+
 		// Start processing in the background, so we can take
 		// care of the next incomming command.
-		go func(q *CommandQueue) {
-			err := q.process(handler)
+		go func(seq int) {
+			err := q.process(handler, seq)
 			if err != nil {
 				log.Error().Err(err).Msg("processing job failed")
 			}
-		}(q)
-
-		// Limit polling frequency to 15 Hz
-		time.Sleep(66 * time.Millisecond)
+		}(seq)
 	}
 }
 
@@ -147,9 +314,9 @@ func safeExecHandler(
 }
 
 // Process will dequeue a command and apply the
-// handler function to it. If not command was dequeued 'false'
-// will be returned.
-func (q *CommandQueue) process(handler CommandHandler) error {
+// handler function to it. The command is identified by
+// seq, with 0 the next command in the queue is dequeued.
+func (q *CommandQueue) process(handler CommandHandler, seq int) error {
 	// Begin transaction with a total timelimit
 	// of X seconds for the entire command. The safeExecHandler
 	// will instanciate a child context with a stricter timelimit
@@ -166,22 +333,33 @@ func (q *CommandQueue) process(handler CommandHandler) error {
 
 	// We dequeue and fetch a command within a transaction.
 	// During handling the command will be locked.
-	qry := `
-		SELECT 
-			id,
-			seq,
-			action,
-			deadline,
-			created_at
-		  FROM commands
-		 WHERE state = 'requested'
-		 ORDER BY seq ASC
-		 LIMIT 1
-		   FOR UPDATE SKIP LOCKED`
+	sel := Q().
+		Columns(
+			"id",
+			"seq",
+			"action",
+			"deadline",
+			"created_at").
+		From("commands").
+		Where("state = 'requested'").
+		Where("(run_at IS NULL OR run_at <= now() AT TIME ZONE 'utc')")
+	if q.handler == "" {
+		sel = sel.Where("handler IS NULL")
+	} else {
+		sel = sel.Where("handler = ?", q.handler)
+	}
+	if seq > 0 {
+		sel = sel.Where("seq = ?", seq)
+	}
+	qry, params, _ := sel.
+		OrderBy("seq ASC").
+		Limit(1).
+		Suffix("FOR UPDATE SKIP LOCKED").
+		ToSql()
 
 	// Select command
 	cmd := &Command{}
-	err = tx.QueryRow(ctx, qry).Scan(
+	err = tx.QueryRow(ctx, qry, params...).Scan(
 		&cmd.ID,
 		&cmd.Seq,
 		&cmd.Action,
@@ -195,6 +373,15 @@ func (q *CommandQueue) process(handler CommandHandler) error {
 
 	cmd.tx = tx
 
+	// More commands might be waiting in the queue,
+	// so the next one is dequeued right away.
+	if seq == 0 {
+		select {
+		case q.trigger <- true:
+		default:
+		}
+	}
+
 	// Check deadline
 	state := "success"
 	var result interface{}
@@ -246,15 +433,185 @@ func (q *CommandQueue) process(handler CommandHandler) error {
 	if err != nil {
 		return err
 	}
+	CommandsProcessed.WithLabelValues(cmd.Action, state).Inc()
 	return nil
 }
 
+// GetCommands retrieves the commands in the queue
+// matching the query, including the results.
+func GetCommands(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*Command, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"seq",
+		"state",
+		"action",
+		"params",
+		"result",
+		"run_at",
+		"handler",
+		"deadline",
+		"started_at",
+		"stopped_at",
+		"created_at").
+		From("commands").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*Command{}
+	for rows.Next() {
+		cmd := &Command{}
+		if err := rows.Scan(
+			&cmd.ID,
+			&cmd.Seq,
+			&cmd.State,
+			&cmd.Action,
+			&cmd.Params,
+			&cmd.Result,
+			&cmd.RunAt,
+			&cmd.Handler,
+			&cmd.Deadline,
+			&cmd.StartedAt,
+			&cmd.StoppedAt,
+			&cmd.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, cmd)
+	}
+	return results, rows.Err()
+}
+
+// GetCommand retrieves a single command from the queue.
+// This may return nil without an error.
+func GetCommand(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*Command, error) {
+	cmds, err := GetCommands(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(cmds) == 0 {
+		return nil, nil
+	}
+	return cmds[0], nil
+}
+
+// AwaitCommand waits until the command identified by
+// the id is finished. After the timeout, the command is
+// returned in its current state. This may return nil
+// without an error if the command does not exist.
+//
+// Like AwaitMeetingState, we listen for the notification
+// of the commands trigger instead of polling.
+func AwaitCommand(
+	ctx context.Context,
+	id string,
+	timeout time.Duration,
+) (*Command, error) {
+	conn, err := Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	// Start listening before checking the state, so
+	// we can not miss the notification.
+	listen := "LISTEN " + pgx.Identifier{cmdFinished}.Sanitize()
+	if _, err := conn.Exec(ctx, listen); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// The connection is returned to the pool
+		unlisten := "UNLISTEN " + pgx.Identifier{cmdFinished}.Sanitize()
+		conn.Exec(context.Background(), unlisten)
+	}()
+
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		cmd, err := GetCommand(ctx, tx, Q().Where("id = ?", id))
+		tx.Rollback(ctx) // Close transaction
+		if err != nil {
+			return nil, err
+		}
+		if cmd == nil || cmd.Finished() || waitCtx.Err() != nil {
+			return cmd, nil
+		}
+
+		// Wait for the command to finish
+		for {
+			n, err := conn.Conn().WaitForNotification(waitCtx)
+			if waitCtx.Err() == context.DeadlineExceeded {
+				break // Return the current state
+			}
+			if err != nil {
+				return nil, err
+			}
+			if n.Payload == id {
+				break
+			}
+		}
+	}
+}
+
 // NextDeadline calculates the deadline for a
 // newly requested command
 func NextDeadline(dt time.Duration) time.Time {
 	return time.Now().UTC().Add(dt)
 }
 
+// CommandQueueStats describe the commands
+// waiting to be processed.
+type CommandQueueStats struct {
+	// Requested is the number of commands in the queue
+	// which are ready to be processed.
+	Requested int `json:"requested"`
+
+	// OldestAge is the time the oldest command is
+	// waiting to be processed.
+	OldestAge time.Duration `json:"oldest_age"`
+}
+
+// GetCommandQueueStats retrieves the length and the
+// age of the queue. Commands scheduled for later are
+// not waiting yet and are not included.
+func GetCommandQueueStats(
+	ctx context.Context,
+	tx pgx.Tx,
+) (*CommandQueueStats, error) {
+	qry := `
+		SELECT COUNT(1),
+		       COALESCE(EXTRACT(EPOCH FROM
+		         now() AT TIME ZONE 'utc' -
+		         MIN(COALESCE(run_at, created_at))), 0)
+		  FROM commands
+		 WHERE state = 'requested'
+		   AND (run_at IS NULL
+		        OR run_at <= now() AT TIME ZONE 'utc')`
+	stats := &CommandQueueStats{}
+	var age float64
+	if err := tx.QueryRow(ctx, qry).Scan(&stats.Requested, &age); err != nil {
+		return nil, err
+	}
+	if age > 0 {
+		stats.OldestAge = time.Duration(age * float64(time.Second))
+	}
+	return stats, nil
+}
+
 // CountCommandsWithState retrievs the number of commands
 // in the queue with a given state. e.g. requested, error,
 // etc.
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 )
 
 func TestSafeExecHandler(t *testing.T) {
@@ -82,3 +83,165 @@ func TestCountCommandsRequested(t *testing.T) {
 	}
 
 }
+
+func TestQueueCommandHandler(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	handler := BackendAgentHandler("backend23")
+	cmd := &Command{
+		Action:  "test_action",
+		Handler: &handler,
+	}
+	if err := QueueCommand(ctx, tx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := GetCommand(ctx, tx, Q().Where("id = ?", cmd.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Handler == nil || *stored.Handler != "agent:backend23" {
+		t.Error("unexpected handler:", stored.Handler)
+	}
+
+	// Commands without handler
+	cmd = &Command{Action: "test_action"}
+	if err := QueueCommand(ctx, tx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = GetCommand(ctx, tx, Q().Where("id = ?", cmd.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Handler != nil {
+		t.Error("unexpected handler:", *stored.Handler)
+	}
+}
+
+func TestGetCommandQueueStats(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	tx.Exec(ctx, "DELETE FROM commands")
+
+	stats, err := GetCommandQueueStats(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Requested != 0 || stats.OldestAge != 0 {
+		t.Error("did not expect anything in the queue:", stats)
+	}
+
+	// A command waiting for some minutes and one
+	// scheduled for later, which is not waiting yet.
+	past := time.Now().UTC().Add(-5 * time.Minute)
+	if err := QueueCommand(ctx, tx, &Command{RunAt: &past}); err != nil {
+		t.Fatal(err)
+	}
+	future := time.Now().UTC().Add(time.Hour)
+	if err := QueueCommand(ctx, tx, &Command{RunAt: &future}); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err = GetCommandQueueStats(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Requested != 1 {
+		t.Error("unexpected queue length:", stats.Requested)
+	}
+	if stats.OldestAge < 4*time.Minute {
+		t.Error("unexpected oldest age:", stats.OldestAge)
+	}
+}
+
+func TestGetCommand(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	deadline := NextDeadline(10 * time.Minute)
+	cmd := &Command{
+		Action:   "test_action",
+		Params:   map[string]string{"id": "backend23"},
+		Deadline: deadline,
+	}
+	if err := QueueCommand(ctx, tx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if cmd.State != "requested" || cmd.Seq == 0 {
+		t.Error("unexpected command:", cmd)
+	}
+
+	stored, err := GetCommand(ctx, tx, Q().Where("id = ?", cmd.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil {
+		t.Fatal("command not found")
+	}
+	if stored.Action != "test_action" {
+		t.Error("unexpected action:", stored.Action)
+	}
+	if stored.Deadline.Sub(deadline) > time.Second ||
+		deadline.Sub(stored.Deadline) > time.Second {
+		t.Error("deadline of the command was not used:", stored.Deadline)
+	}
+}
+
+func TestAwaitCommand(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	cmd := &Command{Action: "test_await"}
+	if err := QueueCommand(ctx, tx, cmd); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Exec(ctx, "DELETE FROM commands WHERE id = $1", cmd.ID)
+
+	// The command is pending after the timeout
+	pending, err := AwaitCommand(ctx, cmd.ID, 100*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending.Finished() {
+		t.Error("command should be pending:", pending.State)
+	}
+
+	// Finish the command while waiting
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		pool.Exec(ctx, `
+			UPDATE commands
+			   SET state = 'success', result = 'true'
+			 WHERE id = $1`, cmd.ID)
+	}()
+	finished, err := AwaitCommand(ctx, cmd.ID, 5*time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if finished.State != "success" {
+		t.Error("command should be finished:", finished.State)
+	}
+}
+
+func TestDecodeQueueNotification(t *testing.T) {
+	seq, action, err := decodeQueueNotification("42:update_node_state")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seq != 42 || action != "update_node_state" {
+		t.Error("unexpected notification:", seq, action)
+	}
+
+	if _, _, err := decodeQueueNotification(""); err == nil {
+		t.Error("expected an error for an empty payload")
+	}
+	if _, _, err := decodeQueueNotification("seq:action"); err == nil {
+		t.Error("expected an error for an invalid seq")
+	}
+}
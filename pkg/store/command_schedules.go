@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// A CommandSchedule queues a command periodically.
+// The schedule is identified by name.
+type CommandSchedule struct {
+	Name string `json:"name"`
+
+	// Cron is the expression of the schedule,
+	// e.g. "0 3 * * *" or "@weekly".
+	Cron string `json:"cron"`
+
+	Action string      `json:"action"`
+	Params interface{} `json:"params"`
+
+	NextRunAt time.Time  `json:"next_run_at"`
+	LastRunAt *time.Time `json:"last_run_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// GetCommandSchedules retrieves the schedules
+// matching the query.
+func GetCommandSchedules(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*CommandSchedule, error) {
+	qry, params, _ := q.Columns(
+		"name",
+		"cron",
+		"action",
+		"params",
+		"next_run_at",
+		"last_run_at",
+		"created_at",
+		"updated_at").
+		From("command_schedules").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*CommandSchedule{}
+	for rows.Next() {
+		s := &CommandSchedule{}
+		if err := rows.Scan(
+			&s.Name,
+			&s.Cron,
+			&s.Action,
+			&s.Params,
+			&s.NextRunAt,
+			&s.LastRunAt,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+	return results, rows.Err()
+}
+
+// GetCommandSchedule retrieves a single schedule.
+// This may return nil without an error.
+func GetCommandSchedule(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*CommandSchedule, error) {
+	schedules, err := GetCommandSchedules(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(schedules) == 0 {
+		return nil, nil
+	}
+	return schedules[0], nil
+}
+
+// GetDueCommandSchedules retrieves the schedules where
+// the next run is due. The schedules are locked, so
+// the run is queued by only one instance.
+func GetDueCommandSchedules(
+	ctx context.Context,
+	tx pgx.Tx,
+) ([]*CommandSchedule, error) {
+	return GetCommandSchedules(ctx, tx, Q().
+		Where("next_run_at <= now() AT TIME ZONE 'utc'").
+		OrderBy("next_run_at ASC").
+		Suffix("FOR UPDATE SKIP LOCKED"))
+}
+
+// Save creates or updates the schedule
+func (s *CommandSchedule) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	params, err := json.Marshal(s.Params)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	qry := `
+		INSERT INTO command_schedules (
+			name, cron, action, params, next_run_at, last_run_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6
+		)
+		ON CONFLICT (name) DO UPDATE
+		   SET cron        = EXCLUDED.cron,
+		       action      = EXCLUDED.action,
+		       params      = EXCLUDED.params,
+		       next_run_at = EXCLUDED.next_run_at,
+		       last_run_at = EXCLUDED.last_run_at,
+		       updated_at  = $7
+		RETURNING created_at, updated_at`
+	return tx.QueryRow(ctx, qry,
+		s.Name,
+		s.Cron,
+		s.Action,
+		params,
+		s.NextRunAt.UTC(),
+		s.LastRunAt,
+		now).Scan(&s.CreatedAt, &s.UpdatedAt)
+}
+
+// DeleteCommandSchedule removes a schedule. Commands
+// already queued are not affected.
+func DeleteCommandSchedule(
+	ctx context.Context,
+	tx pgx.Tx,
+	name string,
+) error {
+	qry := `DELETE FROM command_schedules WHERE name = $1`
+	_, err := tx.Exec(ctx, qry, name)
+	return err
+}
@@ -0,0 +1,67 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestCommandScheduleSave(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	s := &CommandSchedule{
+		Name:      "nightly-sync",
+		Cron:      "0 3 * * *",
+		Action:    "sync_backend_meetings",
+		Params:    map[string]string{"backend_id": "backend23"},
+		NextRunAt: time.Now().UTC().Add(-time.Minute),
+	}
+	if err := s.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// Update the schedule
+	s.Cron = "@weekly"
+	if err := s.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	stored, err := GetCommandSchedule(ctx, tx, Q().
+		Where("name = ?", "nightly-sync"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored == nil {
+		t.Fatal("schedule not found")
+	}
+	if stored.Cron != "@weekly" {
+		t.Error("unexpected cron:", stored.Cron)
+	}
+
+	due, err := GetDueCommandSchedules(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, d := range due {
+		if d.Name == "nightly-sync" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("schedule should be due")
+	}
+
+	if err := DeleteCommandSchedule(ctx, tx, "nightly-sync"); err != nil {
+		t.Fatal(err)
+	}
+	stored, err = GetCommandSchedule(ctx, tx, Q().
+		Where("name = ?", "nightly-sync"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored != nil {
+		t.Error("schedule should be deleted")
+	}
+}
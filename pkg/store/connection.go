@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/jackc/pgx/v4"
@@ -24,6 +26,10 @@ var (
 	ErrMaxConnsUnconfigured = errors.New("MaxConns not configured")
 )
 
+// SchemaVersion is the database schema version
+// required by this version of b3scale.
+const SchemaVersion = 31
+
 // Pool is the stores global connection pool and
 // will be initialized during Connect.
 // Database transactions can then be started with store.Begin.
@@ -34,20 +40,57 @@ type ConnectOpts struct {
 	URL      string
 	MaxConns int32
 	MinConns int32
+
+	// MaxConnIdleTime and HealthCheckPeriod use
+	// the pgxpool defaults if zero.
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+
+	// ConnectTimeout is the time the connection is retried
+	// with a backoff, e.g. while the primary fails over.
+	// Without a timeout, the connection is tried once.
+	ConnectTimeout time.Duration
+
+	// AcceptNewerSchema allows connecting to a database
+	// migrated by a newer version, e.g. for node agents
+	// which are upgraded after the b3scale instances.
+	AcceptNewerSchema bool
 }
 
+// Health states of the database
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+)
+
+// maxConnectBackoff limits the delay between
+// connection attempts.
+const maxConnectBackoff = 10 * time.Second
+
+// unavailableSince is the time of the first failed
+// health check. It is zero when the database is available.
+var (
+	unavailableSince time.Time
+	healthMtx        sync.Mutex
+)
+
 // Connect initializes the connection pool and
 // checks the schema version of the database.
 func Connect(opts *ConnectOpts) error {
 	log.Debug().Str("url", opts.URL).Msg("using database")
 
-	// Initialize postgres connection
-	cfg, err := pgxpool.ParseConfig(opts.URL)
+	// Initialize postgres connection. After a failover
+	// only the new primary is accepted.
+	cfg, err := pgxpool.ParseConfig(withReadWriteSession(opts.URL))
 	if err != nil {
 		return err
 	}
 
 	cfg.ConnConfig.RuntimeParams["application_name"] = filepath.Base(os.Args[0])
+
+	// Observe queries and log slow queries
+	cfg.ConnConfig.Logger = &queryLogger{}
+	cfg.ConnConfig.LogLevel = pgx.LogLevelInfo
 	if opts.MaxConns == 0 {
 		return ErrMaxConnsUnconfigured
 	}
@@ -55,12 +98,27 @@ func Connect(opts *ConnectOpts) error {
 	// We need some more connections
 	cfg.MaxConns = opts.MaxConns
 	cfg.MinConns = opts.MinConns
+	if opts.MaxConnIdleTime > 0 {
+		cfg.MaxConnIdleTime = opts.MaxConnIdleTime
+	}
+	if opts.HealthCheckPeriod > 0 {
+		cfg.HealthCheckPeriod = opts.HealthCheckPeriod
+	}
 
-	p, err := pgxpool.ConnectConfig(context.Background(), cfg)
+	p, err := connectRetry(cfg, opts.ConnectTimeout)
 	if err != nil {
 		return err
 	}
-	if err = AssertDatabaseVersion(p, 1); err != nil {
+	err = AssertDatabaseVersion(p, SchemaVersion)
+	if skew, ok := err.(*SchemaSkewError); ok && opts.AcceptNewerSchema && skew.Newer() {
+		log.Warn().
+			Int("version", skew.Current).
+			Int("required", skew.Required).
+			Msg("the database schema is newer than this binary")
+		err = nil
+	}
+	if err != nil {
+		p.Close()
 		return err
 	}
 
@@ -70,6 +128,51 @@ func Connect(opts *ConnectOpts) error {
 	return nil
 }
 
+// connectRetry creates the pool. Failed attempts are
+// retried with an exponential backoff until the timeout.
+func connectRetry(
+	cfg *pgxpool.Config,
+	timeout time.Duration,
+) (*pgxpool.Pool, error) {
+	deadline := time.Now().Add(timeout)
+	backoff := 500 * time.Millisecond
+	for {
+		p, err := pgxpool.ConnectConfig(context.Background(), cfg)
+		if err == nil {
+			return p, nil
+		}
+		if time.Now().Add(backoff).After(deadline) {
+			return nil, err
+		}
+		log.Warn().
+			Err(err).
+			Dur("retry", backoff).
+			Msg("database connection failed")
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > maxConnectBackoff {
+			backoff = maxConnectBackoff
+		}
+	}
+}
+
+// withReadWriteSession requires a writable session,
+// unless the target_session_attrs are configured. With
+// multiple hosts in the URL, the primary is used.
+func withReadWriteSession(url string) string {
+	if strings.Contains(url, "target_session_attrs") {
+		return url
+	}
+	if !strings.Contains(url, "://") {
+		// Key value connection string
+		return url + " target_session_attrs=read-write"
+	}
+	if strings.Contains(url, "?") {
+		return url + "&target_session_attrs=read-write"
+	}
+	return url + "?target_session_attrs=read-write"
+}
+
 // ConnectTest to pgx db pool. Use b3scale defaults if
 // environment variable is not set.
 func ConnectTest() error {
@@ -92,6 +195,71 @@ func Acquire(ctx context.Context) (*pgxpool.Conn, error) {
 	return pool.Acquire(ctx)
 }
 
+// Ping checks if the database is reachable.
+func Ping(ctx context.Context) error {
+	if pool == nil {
+		return ErrNotInitialized
+	}
+	return pool.Ping(ctx)
+}
+
+// CheckHealth pings the database and tracks
+// since when the database is unavailable.
+func CheckHealth(ctx context.Context) error {
+	err := Ping(ctx)
+	healthMtx.Lock()
+	defer healthMtx.Unlock()
+	if err == nil {
+		if !unavailableSince.IsZero() {
+			log.Info().Msg("database is available again")
+		}
+		unavailableSince = time.Time{}
+		return nil
+	}
+	if unavailableSince.IsZero() {
+		unavailableSince = time.Now()
+		log.Warn().Err(err).Msg("database is unavailable, degraded")
+	}
+	return err
+}
+
+// MonitorHealth checks the database in an interval
+// until the context is done.
+func MonitorHealth(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		CheckHealth(checkCtx)
+		cancel()
+	}
+}
+
+// Health returns the state of the database from the
+// last health check and since when it is unavailable.
+func Health() (string, time.Time) {
+	healthMtx.Lock()
+	defer healthMtx.Unlock()
+	if unavailableSince.IsZero() {
+		return HealthOK, unavailableSince
+	}
+	return HealthDegraded, unavailableSince
+}
+
+// PoolStat returns the statistics of the connection
+// pool. The result is nil if the pool is not initialized.
+func PoolStat() *pgxpool.Stat {
+	if pool == nil {
+		return nil
+	}
+	return pool.Stat()
+}
+
 // begin starts a transaction in the database pool.
 func begin(ctx context.Context) (pgx.Tx, error) {
 	if pool == nil {
@@ -144,9 +312,27 @@ func AssertDatabaseVersion(pool *pgxpool.Pool, version int) error {
 		Msg("checking database schema")
 
 	if current != version {
-		return fmt.Errorf(
-			"unexpected database version: %d, required: %d",
-			current, version)
+		return &SchemaSkewError{Current: current, Required: version}
 	}
 	return nil
 }
+
+// A SchemaSkewError is returned when the version of the
+// database differs from the required version.
+type SchemaSkewError struct {
+	Current  int
+	Required int
+}
+
+// Error implements the error interface
+func (e *SchemaSkewError) Error() string {
+	return fmt.Sprintf(
+		"unexpected database version: %d, required: %d",
+		e.Current, e.Required)
+}
+
+// Newer is true if the database was migrated
+// beyond the required version.
+func (e *SchemaSkewError) Newer() bool {
+	return e.Current > e.Required
+}
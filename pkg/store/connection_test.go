@@ -21,3 +21,21 @@ func beginTest(ctx context.Context, t *testing.T) pgx.Tx {
 	}
 	return tx
 }
+
+func TestWithReadWriteSession(t *testing.T) {
+	tests := map[string]string{
+		"postgres://db1,db2/b3scale": "postgres://db1,db2/b3scale" +
+			"?target_session_attrs=read-write",
+		"postgres://db/b3scale?sslmode=require": "postgres://db/b3scale" +
+			"?sslmode=require&target_session_attrs=read-write",
+		"postgres://db/b3scale?target_session_attrs=any": "postgres://db/b3scale" +
+			"?target_session_attrs=any",
+		"host=db dbname=b3scale": "host=db dbname=b3scale" +
+			" target_session_attrs=read-write",
+	}
+	for url, expected := range tests {
+		if res := withReadWriteSession(url); res != expected {
+			t.Error("unexpected url:", res)
+		}
+	}
+}
@@ -0,0 +1,331 @@
+package store
+
+/*
+ Dumps: The configuration of the cluster can be
+ exported and restored, for backups and for cloning
+ an environment.
+*/
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// DumpVersion is the version of the dump format
+const DumpVersion = 1
+
+// Errors
+var (
+	// ErrUnsupportedDumpVersion is returned when restoring
+	// a dump with an unknown format version.
+	ErrUnsupportedDumpVersion = errors.New("unsupported dump version")
+)
+
+// A Dump contains the frontends, backends and
+// optionally the meetings and recordings.
+type Dump struct {
+	Version       int       `json:"version"`
+	SchemaVersion int       `json:"schema_version"`
+	CreatedAt     time.Time `json:"created_at"`
+
+	Frontends  []*DumpedFrontend  `json:"frontends"`
+	Backends   []*DumpedBackend   `json:"backends"`
+	Meetings   []*DumpedMeeting   `json:"meetings,omitempty"`
+	Recordings []*DumpedRecording `json:"recordings,omitempty"`
+}
+
+// DumpedFrontend is the configuration of a frontend
+type DumpedFrontend struct {
+	ID         string           `json:"id"`
+	Key        string           `json:"key"`
+	Secret     string           `json:"secret"`
+	Active     bool             `json:"active"`
+	AccountRef *string          `json:"account_ref"`
+	Settings   FrontendSettings `json:"settings"`
+}
+
+// DumpedBackend is the configuration of a backend
+type DumpedBackend struct {
+	ID         string          `json:"id"`
+	Host       string          `json:"host"`
+	Secret     string          `json:"secret"`
+	AdminState string          `json:"admin_state"`
+	LoadFactor float64         `json:"load_factor"`
+	Settings   BackendSettings `json:"settings"`
+}
+
+// DumpedMeeting is the stored state of a meeting
+type DumpedMeeting struct {
+	ID         string          `json:"id"`
+	InternalID *string         `json:"internal_id"`
+	FrontendID *string         `json:"frontend_id"`
+	BackendID  *string         `json:"backend_id"`
+	State      json.RawMessage `json:"state"`
+	Labels     Labels          `json:"labels,omitempty"`
+}
+
+// DumpedRecording is the stored state of a recording
+type DumpedRecording struct {
+	ID                string          `json:"id"`
+	BackendID         string          `json:"backend_id"`
+	InternalMeetingID string          `json:"internal_meeting_id"`
+	State             json.RawMessage `json:"state"`
+}
+
+// RestoreResult counts the restored entities
+type RestoreResult struct {
+	Frontends  int `json:"frontends"`
+	Backends   int `json:"backends"`
+	Meetings   int `json:"meetings"`
+	Recordings int `json:"recordings"`
+}
+
+// CreateDump serializes the cluster configuration.
+// Meetings and recordings are included if requested.
+func CreateDump(
+	ctx context.Context,
+	tx pgx.Tx,
+	withMeetings bool,
+) (*Dump, error) {
+	dump := &Dump{
+		Version:       DumpVersion,
+		SchemaVersion: SchemaVersion,
+		CreatedAt:     time.Now().UTC(),
+		Frontends:     []*DumpedFrontend{},
+		Backends:      []*DumpedBackend{},
+	}
+
+	frontends, err := GetFrontendStates(ctx, tx, Q().OrderBy("key ASC"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range frontends {
+		dump.Frontends = append(dump.Frontends, &DumpedFrontend{
+			ID:         f.ID,
+			Key:        f.Frontend.Key,
+			Secret:     f.Frontend.Secret,
+			Active:     f.Active,
+			AccountRef: f.AccountRef,
+			Settings:   f.Settings,
+		})
+	}
+
+	backends, err := GetBackendStates(ctx, tx, Q().OrderBy("host ASC"))
+	if err != nil {
+		return nil, err
+	}
+	for _, b := range backends {
+		dump.Backends = append(dump.Backends, &DumpedBackend{
+			ID:         b.ID,
+			Host:       b.Backend.Host,
+			Secret:     b.Backend.Secret,
+			AdminState: b.AdminState,
+			LoadFactor: b.LoadFactor,
+			Settings:   b.Settings,
+		})
+	}
+
+	if !withMeetings {
+		return dump, nil
+	}
+	if dump.Meetings, err = dumpMeetings(ctx, tx); err != nil {
+		return nil, err
+	}
+	if dump.Recordings, err = dumpRecordings(ctx, tx); err != nil {
+		return nil, err
+	}
+	return dump, nil
+}
+
+// dumpMeetings reads all stored meetings
+func dumpMeetings(ctx context.Context, tx pgx.Tx) ([]*DumpedMeeting, error) {
+	qry := `
+		SELECT id, internal_id, frontend_id, backend_id, state, labels
+		  FROM meetings
+		 ORDER BY id ASC`
+	rows, err := tx.Query(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	meetings := []*DumpedMeeting{}
+	for rows.Next() {
+		m := &DumpedMeeting{}
+		if err := rows.Scan(
+			&m.ID, &m.InternalID, &m.FrontendID, &m.BackendID, &m.State,
+			&m.Labels,
+		); err != nil {
+			return nil, err
+		}
+		meetings = append(meetings, m)
+	}
+	return meetings, rows.Err()
+}
+
+// dumpRecordings reads all stored recordings
+func dumpRecordings(ctx context.Context, tx pgx.Tx) ([]*DumpedRecording, error) {
+	qry := `
+		SELECT id, backend_id, internal_meeting_id, state
+		  FROM recordings
+		 ORDER BY id ASC`
+	rows, err := tx.Query(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	recordings := []*DumpedRecording{}
+	for rows.Next() {
+		r := &DumpedRecording{}
+		if err := rows.Scan(
+			&r.ID, &r.BackendID, &r.InternalMeetingID, &r.State,
+		); err != nil {
+			return nil, err
+		}
+		recordings = append(recordings, r)
+	}
+	return recordings, rows.Err()
+}
+
+// RestoreDump writes the dump into the store. Frontends
+// and backends are matched by key and host; existing
+// ones are updated. References of meetings and recordings
+// are mapped to the restored IDs.
+func RestoreDump(
+	ctx context.Context,
+	tx pgx.Tx,
+	dump *Dump,
+) (*RestoreResult, error) {
+	if dump.Version != DumpVersion {
+		return nil, ErrUnsupportedDumpVersion
+	}
+	res := &RestoreResult{}
+
+	// Restored IDs may differ if the frontend or
+	// backend already existed.
+	frontendIDs := make(map[string]string, len(dump.Frontends))
+	for _, f := range dump.Frontends {
+		qry := `
+			INSERT INTO frontends (
+				id, key, secret, active, settings, account_ref
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+			ON CONFLICT (key) DO UPDATE
+			   SET secret      = EXCLUDED.secret,
+			       active      = EXCLUDED.active,
+			       settings    = EXCLUDED.settings,
+			       account_ref = EXCLUDED.account_ref,
+			       updated_at  = NOW()
+			RETURNING id`
+		id := ""
+		if err := tx.QueryRow(ctx, qry,
+			f.ID, f.Key, f.Secret, f.Active, f.Settings, f.AccountRef,
+		).Scan(&id); err != nil {
+			return nil, err
+		}
+		frontendIDs[f.ID] = id
+		res.Frontends++
+	}
+
+	backendIDs := make(map[string]string, len(dump.Backends))
+	for _, b := range dump.Backends {
+		qry := `
+			INSERT INTO backends (
+				id, host, secret, admin_state, settings, load_factor
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+			ON CONFLICT (host) DO UPDATE
+			   SET secret      = EXCLUDED.secret,
+			       admin_state = EXCLUDED.admin_state,
+			       settings    = EXCLUDED.settings,
+			       load_factor = EXCLUDED.load_factor,
+			       updated_at  = NOW()
+			RETURNING id`
+		id := ""
+		if err := tx.QueryRow(ctx, qry,
+			b.ID, b.Host, b.Secret, b.AdminState, b.Settings, b.LoadFactor,
+		).Scan(&id); err != nil {
+			return nil, err
+		}
+		backendIDs[b.ID] = id
+		res.Backends++
+	}
+
+	mapID := func(ids map[string]string, id *string) *string {
+		if id == nil {
+			return nil
+		}
+		if mapped, ok := ids[*id]; ok {
+			return &mapped
+		}
+		return nil // Unknown reference
+	}
+
+	for _, m := range dump.Meetings {
+		labels := m.Labels
+		if labels == nil {
+			labels = Labels{} // Dumps before the labels
+		}
+		qry := `
+			INSERT INTO meetings (
+				id, internal_id, frontend_id, backend_id, state, labels
+			) VALUES (
+				$1, $2, $3, $4, $5, $6
+			)
+			ON CONFLICT (id) DO UPDATE
+			   SET internal_id = EXCLUDED.internal_id,
+			       frontend_id = EXCLUDED.frontend_id,
+			       backend_id  = EXCLUDED.backend_id,
+			       state       = EXCLUDED.state,
+			       labels      = EXCLUDED.labels,
+			       updated_at  = NOW()`
+		if _, err := tx.Exec(ctx, qry,
+			m.ID,
+			m.InternalID,
+			mapID(frontendIDs, m.FrontendID),
+			mapID(backendIDs, m.BackendID),
+			m.State,
+			labels,
+		); err != nil {
+			return nil, err
+		}
+		res.Meetings++
+	}
+
+	for _, r := range dump.Recordings {
+		backendID := mapID(backendIDs, &r.BackendID)
+		if backendID == nil {
+			continue // The backend is required
+		}
+		qry := `
+			INSERT INTO recordings (
+				id, backend_id, internal_meeting_id, state
+			) VALUES (
+				$1, $2, $3, $4
+			)
+			ON CONFLICT (id) DO UPDATE
+			   SET backend_id          = EXCLUDED.backend_id,
+			       internal_meeting_id = EXCLUDED.internal_meeting_id,
+			       state               = EXCLUDED.state,
+			       updated_at          = NOW()`
+		if _, err := tx.Exec(ctx, qry,
+			r.ID, *backendID, r.InternalMeetingID, r.State,
+		); err != nil {
+			return nil, err
+		}
+		res.Recordings++
+	}
+
+	// Meeting counters of the backends
+	for _, id := range backendIDs {
+		if err := updateBackendStatCounters(ctx, tx, id); err != nil {
+			return nil, err
+		}
+	}
+	return res, nil
+}
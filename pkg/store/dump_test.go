@@ -0,0 +1,70 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestCreateAndRestoreDump(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	dump, err := CreateDump(ctx, tx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dump.Version != DumpVersion {
+		t.Error("unexpected version:", dump.Version)
+	}
+
+	// Change the secret and restore a new frontend
+	var dumped *DumpedFrontend
+	for _, f := range dump.Frontends {
+		if f.ID == frontend.ID {
+			dumped = f
+		}
+	}
+	if dumped == nil {
+		t.Fatal("frontend missing in dump")
+	}
+	dumped.Secret = "restored"
+	dump.Frontends = append(dump.Frontends, &DumpedFrontend{
+		ID:     uuid.New().String(),
+		Key:    "restored-" + uuid.New().String(),
+		Secret: "secret",
+		Active: true,
+	})
+
+	res, err := RestoreDump(ctx, tx, dump)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Frontends != len(dump.Frontends) {
+		t.Error("unexpected result:", res)
+	}
+
+	restored, err := GetFrontendState(ctx, tx, Q().Where("id = ?", frontend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.Frontend.Secret != "restored" {
+		t.Error("frontend was not updated")
+	}
+
+	dump.Version = 0
+	if _, err := RestoreDump(ctx, tx, dump); err != ErrUnsupportedDumpVersion {
+		t.Error("expected unsupported version, got:", err)
+	}
+}
@@ -0,0 +1,58 @@
+package store
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// GetEventOffsets retrieves the last processed
+// event stream IDs of a backend. The result maps
+// the stream name to the ID.
+func GetEventOffsets(
+	ctx context.Context,
+	tx pgx.Tx,
+	backendID string,
+) (map[string]string, error) {
+	qry := `
+		SELECT stream, last_id
+		  FROM event_offsets
+		 WHERE backend_id = $1`
+	rows, err := tx.Query(ctx, qry, backendID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	offsets := make(map[string]string)
+	for rows.Next() {
+		var stream, lastID string
+		if err := rows.Scan(&stream, &lastID); err != nil {
+			return nil, err
+		}
+		offsets[stream] = lastID
+	}
+	return offsets, rows.Err()
+}
+
+// SetEventOffset stores the last processed event
+// stream ID of a backend.
+func SetEventOffset(
+	ctx context.Context,
+	tx pgx.Tx,
+	backendID string,
+	stream string,
+	lastID string,
+) error {
+	qry := `
+		INSERT INTO event_offsets (
+			backend_id, stream, last_id
+		) VALUES (
+			$1, $2, $3
+		)
+		ON CONFLICT (backend_id, stream) DO UPDATE
+		   SET last_id    = EXCLUDED.last_id,
+		       updated_at = CURRENT_TIMESTAMP`
+	_, err := tx.Exec(ctx, qry, backendID, stream, lastID)
+	return err
+}
@@ -0,0 +1,74 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestEventOffsets(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := SetEventOffset(ctx, tx, backend.ID, "stream", "1-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEventOffset(ctx, tx, backend.ID, "stream", "2-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err := GetEventOffsets(ctx, tx, backend.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets["stream"] != "2-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
+
+func TestEventOffsetsPerBackend(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	b1 := backendStateFactory()
+	if err := b1.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	b2 := backendStateFactory()
+	if err := b2.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new backend has no offsets
+	offsets, err := GetEventOffsets(ctx, tx, b1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 0 {
+		t.Error("unexpected offsets:", offsets)
+	}
+
+	if err := SetEventOffset(ctx, tx, b1.ID, "a", "1-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEventOffset(ctx, tx, b1.ID, "b", "3-0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SetEventOffset(ctx, tx, b2.ID, "a", "7-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	offsets, err = GetEventOffsets(ctx, tx, b1.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(offsets) != 2 || offsets["a"] != "1-0" || offsets["b"] != "3-0" {
+		t.Error("unexpected offsets:", offsets)
+	}
+}
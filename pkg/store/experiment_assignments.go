@@ -0,0 +1,101 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// An ExperimentAssignment records the arm of an
+// experiment a meeting was assigned to.
+type ExperimentAssignment struct {
+	Experiment string
+	Arm        string
+	MeetingID  string
+	FrontendID *string
+	BackendID  *string
+	CreatedAt  time.Time
+}
+
+// InsertExperimentAssignment stores the assignment of
+// a meeting. A meeting is only recorded once per experiment,
+// even if the create request is repeated. Without a backend
+// the backend of the meeting is used.
+func InsertExperimentAssignment(
+	ctx context.Context,
+	tx pgx.Tx,
+	a *ExperimentAssignment,
+) error {
+	qry := `
+		INSERT INTO experiment_assignments (
+			experiment, arm, meeting_id, frontend_id, backend_id
+		)
+		SELECT $1::text, $2::text, $3::text, $4::uuid, COALESCE($5::uuid, (
+			SELECT backend_id FROM meetings WHERE id = $3))
+		 WHERE NOT EXISTS (
+			SELECT 1 FROM experiment_assignments
+			 WHERE experiment = $1
+			   AND meeting_id = $3)`
+	_, err := tx.Exec(ctx, qry,
+		a.Experiment,
+		a.Arm,
+		a.MeetingID,
+		a.FrontendID,
+		a.BackendID)
+	return err
+}
+
+// ExperimentArmStats summarizes the usage of
+// an experiment arm.
+type ExperimentArmStats struct {
+	Experiment string `json:"experiment"`
+	Arm        string `json:"arm"`
+
+	// Meetings is the number of meetings created
+	// since the experiment started.
+	Meetings int `json:"meetings"`
+
+	// ActiveMeetings and Attendees are the meetings
+	// and attendees currently in the cluster.
+	ActiveMeetings int `json:"active_meetings"`
+	Attendees      int `json:"attendees"`
+}
+
+// GetExperimentStats aggregates the assignments
+// per experiment and arm.
+func GetExperimentStats(
+	ctx context.Context,
+	tx pgx.Tx,
+) ([]*ExperimentArmStats, error) {
+	qry := `
+		SELECT a.experiment,
+		       a.arm,
+		       COUNT(*),
+		       COUNT(m.id),
+		       COALESCE(SUM((m.state->>'ParticipantCount')::int), 0)
+		  FROM experiment_assignments AS a
+		  LEFT JOIN meetings AS m ON m.id = a.meeting_id
+		 GROUP BY a.experiment, a.arm
+		 ORDER BY a.experiment, a.arm`
+	rows, err := tx.Query(ctx, qry)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := []*ExperimentArmStats{}
+	for rows.Next() {
+		s := &ExperimentArmStats{}
+		if err := rows.Scan(
+			&s.Experiment,
+			&s.Arm,
+			&s.Meetings,
+			&s.ActiveMeetings,
+			&s.Attendees,
+		); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
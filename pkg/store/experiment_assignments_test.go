@@ -0,0 +1,45 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestExperimentAssignments(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	a := &ExperimentAssignment{
+		Experiment: "test-experiment",
+		Arm:        "variant",
+		MeetingID:  "meeting-exp-1",
+	}
+	if err := InsertExperimentAssignment(ctx, tx, a); err != nil {
+		t.Fatal(err)
+	}
+	// Repeated create requests are recorded only once
+	if err := InsertExperimentAssignment(ctx, tx, a); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetExperimentStats(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var found *ExperimentArmStats
+	for _, s := range stats {
+		if s.Experiment == "test-experiment" && s.Arm == "variant" {
+			found = s
+		}
+	}
+	if found == nil {
+		t.Fatal("stats for arm missing:", stats)
+	}
+	if found.Meetings != 1 {
+		t.Error("unexpected meetings count:", found.Meetings)
+	}
+	if found.ActiveMeetings != 0 {
+		t.Error("unexpected active meetings:", found.ActiveMeetings)
+	}
+}
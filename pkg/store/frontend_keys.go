@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// A FrontendKey is an additional key and secret pair
+// of a frontend. Requests authenticated with the key
+// are handled like requests of the frontend.
+type FrontendKey struct {
+	ID         string `json:"id"`
+	FrontendID string `json:"frontend_id"`
+
+	Key    string `json:"key"`
+	Secret string `json:"secret"`
+
+	// Description like "staging"
+	Description string `json:"description"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetFrontendKeys retrieves the additional keys
+// matching the query.
+func GetFrontendKeys(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*FrontendKey, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"frontend_id",
+		"key",
+		"secret",
+		"description",
+		"created_at").
+		From("frontend_keys").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*FrontendKey{}
+	for rows.Next() {
+		k := &FrontendKey{}
+		if err := rows.Scan(
+			&k.ID,
+			&k.FrontendID,
+			&k.Key,
+			&k.Secret,
+			&k.Description,
+			&k.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, k)
+	}
+	return results, rows.Err()
+}
+
+// GetFrontendKey retrieves a single additional key.
+// This may return nil without an error.
+func GetFrontendKey(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*FrontendKey, error) {
+	keys, err := GetFrontendKeys(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return keys[0], nil
+}
+
+// FrontendKeyInUse checks if the key is used by a
+// frontend or as an additional key.
+func FrontendKeyInUse(
+	ctx context.Context,
+	tx pgx.Tx,
+	key string,
+) (bool, error) {
+	qry := `
+		SELECT EXISTS (SELECT 1 FROM frontends WHERE key = $1)
+		    OR EXISTS (SELECT 1 FROM frontend_keys WHERE key = $1)`
+	inUse := false
+	err := tx.QueryRow(ctx, qry, key).Scan(&inUse)
+	return inUse, err
+}
+
+// Validate checks for presence of required fields.
+func (k *FrontendKey) Validate() error {
+	err := ValidationError{}
+	k.Key = strings.TrimSpace(k.Key)
+	k.Secret = strings.TrimSpace(k.Secret)
+	if k.Key == "" {
+		err.Add("key", ErrFieldRequired)
+	}
+	if k.Secret == "" {
+		err.Add("secret", ErrFieldRequired)
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// Save creates the additional key
+func (k *FrontendKey) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		INSERT INTO frontend_keys (
+			id, frontend_id, key, secret, description
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		RETURNING id, created_at`
+	return tx.QueryRow(ctx, qry,
+		newID(),
+		k.FrontendID,
+		k.Key,
+		k.Secret,
+		k.Description).Scan(&k.ID, &k.CreatedAt)
+}
+
+// DeleteFrontendKey removes an additional key
+// of a frontend.
+func DeleteFrontendKey(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	id string,
+) error {
+	qry := `DELETE FROM frontend_keys WHERE frontend_id = $1 AND id = $2`
+	_, err := tx.Exec(ctx, qry, frontendID, id)
+	return err
+}
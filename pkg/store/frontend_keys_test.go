@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestFrontendKeys(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	key := &FrontendKey{
+		FrontendID:  frontend.ID,
+		Key:         uuid.New().String(),
+		Secret:      "s74g1ng",
+		Description: "staging",
+	}
+	if err := key.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := key.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if key.ID == "" {
+		t.Error("expected an id")
+	}
+
+	for _, k := range []string{frontend.Frontend.Key, key.Key} {
+		inUse, err := FrontendKeyInUse(ctx, tx, k)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !inUse {
+			t.Error("key should be in use:", k)
+		}
+	}
+
+	keys, err := GetFrontendKeys(ctx, tx, Q().
+		Where("frontend_id = ?", frontend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 1 || keys[0].Secret != "s74g1ng" {
+		t.Error("unexpected keys:", keys)
+	}
+
+	if err := DeleteFrontendKey(ctx, tx, frontend.ID, key.ID); err != nil {
+		t.Fatal(err)
+	}
+	inUse, err := FrontendKeyInUse(ctx, tx, key.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if inUse {
+		t.Error("the key should be deleted")
+	}
+}
+
+func TestFrontendKeyValidate(t *testing.T) {
+	if err := (&FrontendKey{Key: " "}).Validate(); err == nil {
+		t.Error("expected a validation error")
+	}
+}
@@ -2,6 +2,9 @@ package store
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
 	"strings"
 	"time"
 
@@ -23,6 +26,15 @@ type FrontendState struct {
 
 	AccountRef *string `json:"account_ref"`
 
+	// ParentID references the parent frontend. Settings
+	// not set are inherited from the parent.
+	ParentID *string `json:"parent_id"`
+
+	// After regenerating the secret, the previous secret
+	// is accepted until it expires.
+	PreviousSecret          *string    `json:"-"`
+	PreviousSecretExpiresAt *time.Time `json:"previous_secret_expires_at"`
+
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -51,6 +63,9 @@ func GetFrontendStates(
 		"active",
 		"settings",
 		"account_ref",
+		"parent_id",
+		"previous_secret",
+		"previous_secret_expires_at",
 		"created_at",
 		"updated_at").
 		From("frontends").
@@ -71,6 +86,9 @@ func GetFrontendStates(
 			&state.Active,
 			&state.Settings,
 			&state.AccountRef,
+			&state.ParentID,
+			&state.PreviousSecret,
+			&state.PreviousSecretExpiresAt,
 			&state.CreatedAt, &state.UpdatedAt)
 		if err != nil {
 			return nil, err
@@ -97,6 +115,46 @@ func GetFrontendState(
 	return states[0], nil
 }
 
+// GetFrontendParents retrieves the parents of the
+// frontends by ID.
+func GetFrontendParents(
+	ctx context.Context,
+	tx pgx.Tx,
+	states []*FrontendState,
+) (map[string]*FrontendState, error) {
+	ids := []string{}
+	for _, s := range states {
+		if s.ParentID != nil {
+			ids = append(ids, *s.ParentID)
+		}
+	}
+	parents := make(map[string]*FrontendState, len(ids))
+	if len(ids) == 0 {
+		return parents, nil
+	}
+	results, err := GetFrontendStates(ctx, tx, Q().
+		Where(sq.Eq{"id": ids}))
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range results {
+		parents[p.ID] = p
+	}
+	return parents, nil
+}
+
+// EffectiveSettings are the settings of the frontend,
+// where settings not set are inherited from the parent.
+// The parent may be nil.
+func (s *FrontendState) EffectiveSettings(
+	parent *FrontendState,
+) FrontendSettings {
+	if parent == nil {
+		return s.Settings
+	}
+	return s.Settings.Inherit(parent.Settings)
+}
+
 // Save will create or update a frontend state
 func (s *FrontendState) Save(
 	ctx context.Context,
@@ -113,9 +171,9 @@ func (s *FrontendState) Save(
 func (s *FrontendState) insert(ctx context.Context, tx pgx.Tx) error {
 	qry := `
 		INSERT INTO frontends (
-			key, secret, active, settings, account_ref
+			id, key, secret, active, settings, account_ref, parent_id
 		) VALUES (
-			$1, $2, $3, $4, $5
+			$1, $2, $3, $4, $5, $6, $7
 		)
 		RETURNING id, created_at`
 
@@ -124,11 +182,13 @@ func (s *FrontendState) insert(ctx context.Context, tx pgx.Tx) error {
 		createdAt time.Time
 	)
 	if err := tx.QueryRow(ctx, qry,
+		newID(),
 		s.Frontend.Key,
 		s.Frontend.Secret,
 		s.Active,
 		s.Settings,
-		s.AccountRef).Scan(&id, &createdAt); err != nil {
+		s.AccountRef,
+		s.ParentID).Scan(&id, &createdAt); err != nil {
 		return err
 	}
 	// Update local state
@@ -147,7 +207,10 @@ func (s *FrontendState) update(ctx context.Context, tx pgx.Tx) error {
 			   active      = $4,
 			   settings    = $5,
 			   account_ref = $6,
-			   updated_at  = $7
+			   updated_at  = $7,
+			   previous_secret            = $8,
+			   previous_secret_expires_at = $9,
+			   parent_id   = $10
 		 WHERE id = $1`
 	if _, err := tx.Exec(ctx, qry,
 		s.ID,
@@ -157,7 +220,10 @@ func (s *FrontendState) update(ctx context.Context, tx pgx.Tx) error {
 		s.Active,
 		s.Settings,
 		s.AccountRef,
-		s.UpdatedAt); err != nil {
+		s.UpdatedAt,
+		s.PreviousSecret,
+		s.PreviousSecretExpiresAt,
+		s.ParentID); err != nil {
 		return err
 	}
 	return nil
@@ -172,6 +238,55 @@ func (s *FrontendState) Delete(ctx context.Context, tx pgx.Tx) error {
 	return err
 }
 
+// GenerateSecret creates a new random secret
+// for a frontend.
+func GenerateSecret() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// RegenerateSecret replaces the frontend secret with a
+// new random secret. The current secret will still be
+// accepted until the overlap window is over.
+// The new secret is returned.
+func (s *FrontendState) RegenerateSecret(
+	ctx context.Context,
+	tx pgx.Tx,
+	overlap time.Duration,
+) (string, error) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		return "", err
+	}
+
+	prev := s.Frontend.Secret
+	expiresAt := time.Now().UTC().Add(overlap)
+
+	s.PreviousSecret = &prev
+	s.PreviousSecretExpiresAt = &expiresAt
+	s.Frontend.Secret = secret
+
+	if err := s.Save(ctx, tx); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// ValidPreviousSecret returns the previous secret if the
+// overlap window of the secret rotation is not yet over.
+func (s *FrontendState) ValidPreviousSecret() (string, bool) {
+	if s.PreviousSecret == nil || s.PreviousSecretExpiresAt == nil {
+		return "", false
+	}
+	if time.Now().UTC().After(*s.PreviousSecretExpiresAt) {
+		return "", false
+	}
+	return *s.PreviousSecret, true
+}
+
 // Validate checks for presence of required fields.
 func (s *FrontendState) Validate() ValidationError {
 	err := ValidationError{}
@@ -191,8 +306,124 @@ func (s *FrontendState) Validate() ValidationError {
 		err.Add("bbb.secret", ErrFieldRequired)
 	}
 
+	// Allowed networks
+	if _, nerr := s.Settings.Networks(); nerr != nil {
+		err.Add("settings.allowed_networks", nerr.Error())
+	}
+
+	// Replay protection
+	if rp := s.Settings.ReplayProtection; rp != nil {
+		if _, werr := rp.WindowDuration(); werr != nil {
+			err.Add("settings.replay_protection.window", werr.Error())
+		}
+	}
+
+	// Join link expiry
+	if je := s.Settings.JoinExpiry; je != nil {
+		if _, merr := je.MaxAgeDuration(); merr != nil {
+			err.Add("settings.join_expiry.max_age", merr.Error())
+		}
+	}
+
+	// Guest policy
+	if gp := s.Settings.GuestPolicy; gp != nil {
+		if gerr := gp.Validate(); gerr != nil {
+			err.Add("settings.guest_policy", gerr.Error())
+		}
+	}
+
+	// Meeting lifetime
+	if ml := s.Settings.MeetingLifetime; ml != nil {
+		if _, merr := ml.Duration(); merr != nil {
+			err.Add("settings.meeting_lifetime.max_duration", merr.Error())
+		}
+	}
+
+	// Name sanitization
+	if ns := s.Settings.NameSanitization; ns != nil {
+		if nerr := ns.Validate(); nerr != nil {
+			err.Add("settings.name_sanitization.max_length", nerr.Error())
+		}
+	}
+
+	// Branding
+	if b := s.Settings.Branding; b != nil {
+		if berr := b.Validate(); berr != nil {
+			err.Add("settings.branding", berr.Error())
+		}
+	}
+
+	// Routing schedule
+	for i, rs := range s.Settings.RoutingSchedule {
+		if rs == nil {
+			continue
+		}
+		if rerr := rs.Validate(); rerr != nil {
+			err.Add(
+				fmt.Sprintf("settings.routing_schedule.%d", i),
+				rerr.Error())
+		}
+	}
+
+	// Meeting labels
+	if ml := s.Settings.MeetingLabels; ml != nil {
+		if lerr := ml.Validate(); lerr != nil {
+			err.Add("settings.meeting_labels", lerr.Error())
+		}
+	}
+
+	// Billing
+	if b := s.Settings.Billing; b != nil {
+		if berr := b.Validate(); berr != nil {
+			err.Add("settings.billing.prices", berr.Error())
+		}
+	}
+
 	if len(err) > 0 {
 		return err
 	}
 	return nil
 }
+
+// ValidateParent checks the parent of the frontend.
+// The parent must be found by the query and only
+// one level of parents is supported.
+func (s *FrontendState) ValidateParent(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) error {
+	if s.ParentID == nil {
+		return nil
+	}
+	err := ValidationError{}
+	if *s.ParentID == s.ID {
+		err.Add("parent_id", "a frontend can not be its own parent")
+		return err
+	}
+	parent, perr := GetFrontendState(ctx, tx, q.
+		Where("id = ?", *s.ParentID))
+	if perr != nil {
+		return perr
+	}
+	if parent == nil {
+		err.Add("parent_id", "the parent frontend does not exist")
+		return err
+	}
+	if parent.ParentID != nil {
+		err.Add("parent_id", "the parent frontend has a parent")
+		return err
+	}
+	if s.ID != "" {
+		children, cerr := GetFrontendStates(ctx, tx, Q().
+			Where("parent_id = ?", s.ID))
+		if cerr != nil {
+			return cerr
+		}
+		if len(children) > 0 {
+			err.Add("parent_id", "the frontend is a parent itself")
+			return err
+		}
+	}
+	return nil
+}
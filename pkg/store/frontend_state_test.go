@@ -3,6 +3,7 @@ package store
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/google/uuid"
 
@@ -82,3 +83,84 @@ func TestFrontendValidate(t *testing.T) {
 	}
 	t.Log(err)
 }
+
+func TestFrontendStateRegenerateSecret(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	state := frontendStateFactory()
+	if err := state.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	prev := state.Frontend.Secret
+
+	secret, err := state.RegenerateSecret(ctx, tx, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if secret == prev {
+		t.Error("secret should have changed")
+	}
+
+	ret, err := GetFrontendState(ctx, tx, Q().Where("id = ?", state.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ret.Frontend.Secret != secret {
+		t.Error("unexpected secret:", ret.Frontend.Secret)
+	}
+	s, ok := ret.ValidPreviousSecret()
+	if !ok || s != prev {
+		t.Error("previous secret should be valid")
+	}
+
+	// Expired overlap window
+	if _, err := ret.RegenerateSecret(ctx, tx, 0); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond)
+	if _, ok := ret.ValidPreviousSecret(); ok {
+		t.Error("previous secret should be expired")
+	}
+}
+
+func TestFrontendStateValidateParent(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	parent := frontendStateFactory()
+	if err := parent.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	child := frontendStateFactory()
+	child.ParentID = &parent.ID
+	if err := child.ValidateParent(ctx, tx, Q()); err != nil {
+		t.Fatal(err)
+	}
+	if err := child.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The parent can not become a child
+	parent.ParentID = &child.ID
+	if err := parent.ValidateParent(ctx, tx, Q()); err == nil {
+		t.Error("expected a validation error")
+	}
+
+	// Only one level of parents
+	grandchild := frontendStateFactory()
+	grandchild.ParentID = &child.ID
+	if err := grandchild.ValidateParent(ctx, tx, Q()); err == nil {
+		t.Error("expected a validation error")
+	}
+
+	parents, err := GetFrontendParents(ctx, tx, []*FrontendState{child})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parents[parent.ID] == nil {
+		t.Error("expected parent in result:", parents)
+	}
+}
@@ -0,0 +1,138 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// FrontendUsage is the number of requests of
+// a frontend to a resource on a day.
+type FrontendUsage struct {
+	FrontendID  string `json:"frontend_id"`
+	FrontendKey string `json:"frontend_key"`
+	Day         string `json:"day"`
+	Resource    string `json:"resource"`
+	Requests    int64  `json:"requests"`
+}
+
+// AddFrontendUsage adds the number of requests to
+// the daily usage of a frontend.
+func AddFrontendUsage(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	day time.Time,
+	resource string,
+	requests int64,
+) error {
+	qry := `
+		INSERT INTO frontend_usage (
+			frontend_id, day, resource, requests
+		) VALUES (
+			$1, $2::date, $3, $4
+		)
+		ON CONFLICT (frontend_id, day, resource) DO UPDATE
+		   SET requests = frontend_usage.requests + EXCLUDED.requests`
+	_, err := tx.Exec(ctx, qry,
+		frontendID, day.UTC().Format("2006-01-02"), resource, requests)
+	return err
+}
+
+// GetFrontendUsage retrieves the daily usage of the
+// frontends matching the query, ordered by day.
+func GetFrontendUsage(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*FrontendUsage, error) {
+	qry, params, _ := q.
+		Columns(
+			"frontend_usage.frontend_id",
+			"frontends.key",
+			"to_char(frontend_usage.day, 'YYYY-MM-DD')",
+			"frontend_usage.resource",
+			"frontend_usage.requests").
+		From("frontend_usage").
+		Join("frontends ON frontends.id = frontend_usage.frontend_id").
+		OrderBy(
+			"frontend_usage.day ASC",
+			"frontends.key ASC",
+			"frontend_usage.resource ASC").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	usage := []*FrontendUsage{}
+	for rows.Next() {
+		u := &FrontendUsage{}
+		if err := rows.Scan(
+			&u.FrontendID,
+			&u.FrontendKey,
+			&u.Day,
+			&u.Resource,
+			&u.Requests,
+		); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
+
+// RollupFrontendUsage reports the usage of frontends with
+// a parent as usage of the parent. The frontends are
+// identified by ID.
+func RollupFrontendUsage(
+	usage []*FrontendUsage,
+	frontends []*FrontendState,
+) []*FrontendUsage {
+	byID := make(map[string]*FrontendState, len(frontends))
+	for _, f := range frontends {
+		byID[f.ID] = f
+	}
+	type usageKey struct {
+		frontendID string
+		day        string
+		resource   string
+	}
+	sums := map[usageKey]*FrontendUsage{}
+	result := []*FrontendUsage{}
+	for _, u := range usage {
+		id, key := u.FrontendID, u.FrontendKey
+		if f := byID[u.FrontendID]; f != nil && f.ParentID != nil {
+			if p := byID[*f.ParentID]; p != nil {
+				id, key = p.ID, p.Frontend.Key
+			}
+		}
+		k := usageKey{id, u.Day, u.Resource}
+		if sum, ok := sums[k]; ok {
+			sum.Requests += u.Requests
+			continue
+		}
+		sum := &FrontendUsage{
+			FrontendID:  id,
+			FrontendKey: key,
+			Day:         u.Day,
+			Resource:    u.Resource,
+			Requests:    u.Requests,
+		}
+		sums[k] = sum
+		result = append(result, sum)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Day != result[j].Day {
+			return result[i].Day < result[j].Day
+		}
+		if result[i].FrontendKey != result[j].FrontendKey {
+			return result[i].FrontendKey < result[j].FrontendKey
+		}
+		return result[i].Resource < result[j].Resource
+	})
+	return result
+}
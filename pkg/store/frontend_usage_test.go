@@ -0,0 +1,78 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestFrontendUsage(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2021, 6, 1, 12, 0, 0, 0, time.UTC)
+	if err := AddFrontendUsage(ctx, tx, frontend.ID, day, "join", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFrontendUsage(ctx, tx, frontend.ID, day, "join", 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFrontendUsage(
+		ctx, tx, frontend.ID, day.Add(24*time.Hour), "create", 1,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	usage, err := GetFrontendUsage(ctx, tx, Q().
+		Where("frontend_usage.frontend_id = ?", frontend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 {
+		t.Fatal("unexpected usage:", usage)
+	}
+	if usage[0].Day != "2021-06-01" || usage[0].Requests != 5 {
+		t.Error("unexpected usage:", usage[0])
+	}
+	if usage[1].Resource != "create" || usage[1].Requests != 1 {
+		t.Error("unexpected usage:", usage[1])
+	}
+	if usage[0].FrontendKey != frontend.Frontend.Key {
+		t.Error("unexpected frontend key:", usage[0].FrontendKey)
+	}
+}
+
+func TestRollupFrontendUsage(t *testing.T) {
+	parentID := "university"
+	frontends := []*FrontendState{
+		{ID: "university", Frontend: &bbb.Frontend{Key: "uni"}},
+		{ID: "faculty", Frontend: &bbb.Frontend{Key: "fac"},
+			ParentID: &parentID},
+	}
+	usage := []*FrontendUsage{
+		{FrontendID: "university", FrontendKey: "uni",
+			Day: "2021-06-01", Resource: "join", Requests: 10},
+		{FrontendID: "faculty", FrontendKey: "fac",
+			Day: "2021-06-01", Resource: "join", Requests: 5},
+		{FrontendID: "faculty", FrontendKey: "fac",
+			Day: "2021-06-01", Resource: "create", Requests: 1},
+	}
+	rollup := RollupFrontendUsage(usage, frontends)
+	if len(rollup) != 2 {
+		t.Fatal("unexpected usage:", rollup)
+	}
+	if rollup[0].Resource != "create" || rollup[0].FrontendKey != "uni" {
+		t.Error("unexpected usage:", rollup[0])
+	}
+	if rollup[1].Requests != 15 {
+		t.Error("unexpected requests:", rollup[1].Requests)
+	}
+}
@@ -0,0 +1,124 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// EnsureUsagePartitions creates the partitions of the
+// frontend usage for the month of now and the next month,
+// so the usage is not stored in the default partition.
+func EnsureUsagePartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) error {
+	return ensureMonthPartitions(ctx, tx,
+		`SELECT create_frontend_usage_partition($1::date)`, now)
+}
+
+// EnsureMeetingHistoryPartitions creates the partitions
+// of the meeting history for the month of now and the
+// next month.
+func EnsureMeetingHistoryPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) error {
+	return ensureMonthPartitions(ctx, tx,
+		`SELECT create_meeting_history_partition($1::date)`, now)
+}
+
+// EnsureAuditLogPartitions creates the partitions
+// of the audit log for the month of now and the
+// next month.
+func EnsureAuditLogPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) error {
+	return ensureMonthPartitions(ctx, tx,
+		`SELECT create_audit_log_partition($1::date)`, now)
+}
+
+// EnsureCommandsPartitions creates the partitions of
+// the commands for the day of now and the next day.
+func EnsureCommandsPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) error {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	qry := `SELECT create_commands_partition($1::date)`
+	for _, d := range []time.Time{day, day.AddDate(0, 0, 1)} {
+		if _, err := tx.Exec(ctx, qry, d.Format("2006-01-02")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteExpiredCommandsPartitions drops the partitions
+// of the days before now, where all commands are expired.
+// The number of dropped partitions is returned.
+func DeleteExpiredCommandsPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	now time.Time,
+) (int, error) {
+	qry := `SELECT drop_commands_partitions($1)`
+	dropped := 0
+	err := tx.QueryRow(ctx, qry, now.UTC()).Scan(&dropped)
+	return dropped, err
+}
+
+// ensureMonthPartitions calls the partition function
+// for the month of now and the next month.
+func ensureMonthPartitions(
+	ctx context.Context,
+	tx pgx.Tx,
+	qry string,
+	now time.Time,
+) error {
+	now = now.UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	for _, m := range []time.Time{month, month.AddDate(0, 1, 0)} {
+		if _, err := tx.Exec(ctx, qry, m.Format("2006-01-02")); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DeleteFrontendUsageBefore removes the usage of the
+// frontends before a day. The partitions of whole months
+// are dropped. The number of dropped partitions is returned.
+func DeleteFrontendUsageBefore(
+	ctx context.Context,
+	tx pgx.Tx,
+	day time.Time,
+) (int, error) {
+	qry := `SELECT drop_frontend_usage_partitions($1::date)`
+	dropped := 0
+	err := tx.QueryRow(ctx, qry, day.UTC().Format("2006-01-02")).
+		Scan(&dropped)
+	return dropped, err
+}
+
+// DeleteAuditLogEntriesBefore removes old entries
+// from the audit log. The partitions of whole months
+// are dropped, the remaining entries are deleted.
+// The number of dropped partitions is returned.
+func DeleteAuditLogEntriesBefore(
+	ctx context.Context,
+	tx pgx.Tx,
+	t time.Time,
+) (int, error) {
+	qry := `SELECT drop_audit_log_partitions($1)`
+	dropped := 0
+	err := tx.QueryRow(ctx, qry, t.UTC()).Scan(&dropped)
+	return dropped, err
+}
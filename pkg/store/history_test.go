@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestDeleteFrontendUsageBefore(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	// The usage is stored in the default partition until
+	// the partition of the month is created.
+	day := time.Date(2001, 3, 14, 12, 0, 0, 0, time.UTC)
+	if err := AddFrontendUsage(ctx, tx, frontend.ID, day, "join", 3); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureUsagePartitions(ctx, tx, day); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureUsagePartitions(ctx, tx, day); err != nil {
+		t.Fatal(err)
+	}
+	if err := AddFrontendUsage(
+		ctx, tx, frontend.ID, day.AddDate(0, 1, 0), "join", 1,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := DeleteFrontendUsageBefore(ctx, tx, day.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped < 1 {
+		t.Error("expected the partition to be dropped")
+	}
+	usage, err := GetFrontendUsage(ctx, tx, Q().
+		Where("frontend_usage.frontend_id = ?", frontend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 1 || usage[0].Day != "2001-04-14" {
+		t.Error("unexpected usage:", usage)
+	}
+}
+
+func TestDeleteAuditLogEntriesBefore(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	entry := &AuditLogEntry{
+		Actor:        "admin42",
+		Action:       AuditFrontendKeyCreated,
+		ResourceType: "frontend",
+		ResourceID:   "frontend42",
+	}
+	if err := entry.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := DeleteAuditLogEntriesBefore(
+		ctx, tx, time.Now().Add(time.Hour),
+	); err != nil {
+		t.Fatal(err)
+	}
+	entries, err := GetAuditLogEntries(ctx, tx, Q().
+		Where("resource_id = ?", "frontend42"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Error("unexpected entries:", entries)
+	}
+}
+
+func TestDeleteExpiredCommandsPartitions(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	day := time.Date(2001, 3, 14, 12, 0, 0, 0, time.UTC)
+	if err := EnsureCommandsPartitions(ctx, tx, day); err != nil {
+		t.Fatal(err)
+	}
+	qry := `
+		INSERT INTO commands (action, deadline, created_at)
+		VALUES ('test', $1, $2)`
+	// Expired on the first day, still running on the next
+	if _, err := tx.Exec(ctx, qry, day, day); err != nil {
+		t.Fatal(err)
+	}
+	next := day.AddDate(0, 0, 1)
+	if _, err := tx.Exec(
+		ctx, qry, time.Now().Add(time.Hour), next,
+	); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := DeleteExpiredCommandsPartitions(
+		ctx, tx, day.AddDate(0, 0, 7))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped != 1 {
+		t.Error("expected one dropped partition, got:", dropped)
+	}
+	exists := false
+	if err := tx.QueryRow(ctx,
+		`SELECT to_regclass('commands_2001_03_15') IS NOT NULL`,
+	).Scan(&exists); err != nil {
+		t.Fatal(err)
+	}
+	if !exists {
+		t.Error("the partition with a running command was dropped")
+	}
+}
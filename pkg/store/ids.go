@@ -0,0 +1,166 @@
+package store
+
+import (
+	"crypto/rand"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// An IDGenerator creates the IDs of new backends,
+// frontends, frontend keys, commands and diagnostics.
+// The IDs must be valid UUIDs: the id columns in the
+// schema are of type uuid. Prefixed IDs like `be_...`
+// would require migrating these columns to text first.
+type IDGenerator interface {
+	Name() string
+
+	// Ordered is true if the IDs sort by the
+	// time of their creation.
+	Ordered() bool
+
+	NewID() string
+}
+
+// IDGenerators are the available generators by name
+var IDGenerators = map[string]IDGenerator{}
+
+// RegisterIDGenerator makes a generator available
+func RegisterIDGenerator(g IDGenerator) {
+	IDGenerators[g.Name()] = g
+}
+
+// GetIDGenerator looks up a generator by name
+func GetIDGenerator(name string) (IDGenerator, error) {
+	g, ok := IDGenerators[name]
+	if !ok && name == "prefixed" {
+		return nil, fmt.Errorf(
+			"prefixed ids are not supported, the id columns are uuids")
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown id format: %s", name)
+	}
+	return g, nil
+}
+
+func init() {
+	RegisterIDGenerator(UUIDv4Generator{})
+	RegisterIDGenerator(&UUIDv7Generator{})
+}
+
+// IDs is the generator used for new entities.
+var IDs IDGenerator = UUIDv4Generator{}
+
+// newID creates an ID with the configured generator
+func newID() string {
+	return IDs.NewID()
+}
+
+// CreationOrder sorts the query by the creation time
+// of the rows in the table. When the IDs are ordered,
+// the primary key index is used.
+func CreationOrder(table string) string {
+	if IDs.Ordered() {
+		return table + ".id ASC"
+	}
+	return table + ".created_at ASC, " + table + ".id ASC"
+}
+
+// UUIDv4Generator creates random UUIDs. This is
+// the default.
+type UUIDv4Generator struct{}
+
+// Name implements the IDGenerator interface
+func (UUIDv4Generator) Name() string { return "uuid4" }
+
+// Ordered implements the IDGenerator interface
+func (UUIDv4Generator) Ordered() bool { return false }
+
+// NewID implements the IDGenerator interface
+func (UUIDv4Generator) NewID() string {
+	return uuid.New().String()
+}
+
+// UUIDv7Generator creates UUIDs starting with a
+// millisecond timestamp (RFC 9562). IDs created by
+// the same instance within a millisecond are
+// incremented, so they are strictly ordered.
+type UUIDv7Generator struct {
+	mtx  sync.Mutex
+	last uuid.UUID
+}
+
+// Name implements the IDGenerator interface
+func (*UUIDv7Generator) Name() string { return "uuid7" }
+
+// Ordered implements the IDGenerator interface
+func (*UUIDv7Generator) Ordered() bool { return true }
+
+// NewID implements the IDGenerator interface
+func (g *UUIDv7Generator) NewID() string {
+	g.mtx.Lock()
+	defer g.mtx.Unlock()
+	id := newUUIDv7(time.Now())
+	if uuidLess(g.last, id) {
+		g.last = id
+	} else {
+		g.last = nextUUIDv7(g.last)
+	}
+	return g.last.String()
+}
+
+// newUUIDv7 creates a UUIDv7 for a point in time
+func newUUIDv7(t time.Time) uuid.UUID {
+	var id uuid.UUID
+	if _, err := rand.Read(id[6:]); err != nil {
+		panic(err)
+	}
+	ms := uint64(t.UnixNano() / int64(time.Millisecond))
+	for i := 0; i < 6; i++ {
+		id[i] = byte(ms >> (40 - 8*i))
+	}
+	id[6] = (id[6] & 0x0f) | 0x70 // Version 7
+	id[8] = (id[8] & 0x3f) | 0x80 // Variant RFC 4122
+	return id
+}
+
+// nextUUIDv7 increments the random part of the id,
+// skipping the version and variant bits.
+func nextUUIDv7(id uuid.UUID) uuid.UUID {
+	for i := 15; i > 8; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return id
+		}
+	}
+	if id[8]&0x3f != 0x3f {
+		id[8]++
+		return id
+	}
+	id[8] = 0x80
+	for i := 7; i > 6; i-- {
+		id[i]++
+		if id[i] != 0 {
+			return id
+		}
+	}
+	if id[6]&0x0f != 0x0f {
+		id[6]++
+		return id
+	}
+	// The random part is exhausted; this is
+	// practically impossible.
+	return newUUIDv7(time.Now().Add(time.Millisecond))
+}
+
+// uuidLess compares two UUIDs bytewise
+func uuidLess(a, b uuid.UUID) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return false
+}
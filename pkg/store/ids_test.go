@@ -0,0 +1,82 @@
+package store
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestGetIDGenerator(t *testing.T) {
+	g, err := GetIDGenerator("uuid7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !g.Ordered() {
+		t.Error("uuid7 should be ordered")
+	}
+	if _, err := GetIDGenerator("snowflake"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if _, err := GetIDGenerator("prefixed"); err == nil {
+		t.Error("expected an error for prefixed ids")
+	}
+}
+
+func TestUUIDv7GeneratorNewID(t *testing.T) {
+	g := &UUIDv7Generator{}
+	prev := ""
+	for i := 0; i < 1000; i++ {
+		id := g.NewID()
+		parsed, err := uuid.Parse(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parsed.Version() != 7 {
+			t.Error("unexpected version:", parsed.Version())
+		}
+		if parsed.Variant() != uuid.RFC4122 {
+			t.Error("unexpected variant:", parsed.Variant())
+		}
+		if id <= prev {
+			t.Error("ids are not ordered:", prev, id)
+		}
+		prev = id
+	}
+}
+
+func TestNewUUIDv7Timestamp(t *testing.T) {
+	t0 := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	id := newUUIDv7(t0)
+	ms := int64(0)
+	for i := 0; i < 6; i++ {
+		ms = ms<<8 | int64(id[i])
+	}
+	if ms != t0.UnixNano()/int64(time.Millisecond) {
+		t.Error("unexpected timestamp:", ms)
+	}
+}
+
+func TestNextUUIDv7(t *testing.T) {
+	id := uuid.MustParse("01890a5d-ac96-7fff-bfff-ffffffffffff")
+	next := nextUUIDv7(id)
+	if !uuidLess(id, next) {
+		t.Error("next id should be greater:", next)
+	}
+	if next.Version() != 7 || next.Variant() != uuid.RFC4122 {
+		t.Error("unexpected version or variant:", next)
+	}
+}
+
+func TestCreationOrder(t *testing.T) {
+	defer func(ids IDGenerator) { IDs = ids }(IDs)
+
+	IDs = UUIDv4Generator{}
+	if o := CreationOrder("backends"); o != "backends.created_at ASC, backends.id ASC" {
+		t.Error("unexpected order:", o)
+	}
+	IDs = &UUIDv7Generator{}
+	if o := CreationOrder("backends"); o != "backends.id ASC" {
+		t.Error("unexpected order:", o)
+	}
+}
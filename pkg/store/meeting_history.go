@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// MeetingHistory is the summary of an ended meeting.
+// The history is recorded by the database when
+// the meeting is removed from the store.
+type MeetingHistory struct {
+	ID          int64   `json:"id"`
+	MeetingID   string  `json:"meeting_id"`
+	InternalID  *string `json:"internal_id"`
+	MeetingName string  `json:"meeting_name"`
+
+	FrontendID  *string `json:"frontend_id"`
+	FrontendKey *string `json:"frontend_key"`
+	BackendID   *string `json:"backend_id"`
+	BackendHost *string `json:"backend_host"`
+
+	Labels Labels `json:"labels"`
+
+	// Participants is the count of the last sync
+	Participants int `json:"participants"`
+
+	CreatedAt time.Time `json:"created_at"`
+	EndedAt   time.Time `json:"ended_at"`
+}
+
+// GetMeetingHistory retrieves the ended meetings matching
+// the query, ordered by the end. The frontend and backend
+// are included, if they still exist.
+func GetMeetingHistory(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*MeetingHistory, error) {
+	qry, params, _ := q.
+		Columns(
+			"meeting_history.id",
+			"meeting_history.meeting_id",
+			"meeting_history.internal_id",
+			"meeting_history.meeting_name",
+			"meeting_history.frontend_id",
+			"frontends.key",
+			"meeting_history.backend_id",
+			"backends.host",
+			"meeting_history.labels",
+			"meeting_history.participants",
+			"meeting_history.created_at",
+			"meeting_history.ended_at").
+		From("meeting_history").
+		LeftJoin("frontends ON frontends.id = meeting_history.frontend_id").
+		LeftJoin("backends ON backends.id = meeting_history.backend_id").
+		OrderBy(
+			"meeting_history.ended_at ASC",
+			"meeting_history.id ASC").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	history := []*MeetingHistory{}
+	for rows.Next() {
+		h := &MeetingHistory{}
+		if err := rows.Scan(
+			&h.ID,
+			&h.MeetingID,
+			&h.InternalID,
+			&h.MeetingName,
+			&h.FrontendID,
+			&h.FrontendKey,
+			&h.BackendID,
+			&h.BackendHost,
+			&h.Labels,
+			&h.Participants,
+			&h.CreatedAt,
+			&h.EndedAt,
+		); err != nil {
+			return nil, err
+		}
+		history = append(history, h)
+	}
+	return history, rows.Err()
+}
+
+// DeleteMeetingHistoryBefore removes the meetings
+// ended before a time from the history. The partitions
+// of whole months are dropped. The number of dropped
+// partitions is returned.
+func DeleteMeetingHistoryBefore(
+	ctx context.Context,
+	tx pgx.Tx,
+	t time.Time,
+) (int, error) {
+	qry := `SELECT drop_meeting_history_partitions($1)`
+	dropped := 0
+	err := tx.QueryRow(ctx, qry, t.UTC()).Scan(&dropped)
+	return dropped, err
+}
+
+// ClaimHistoryExport marks the day as exported. The
+// result is false if the day was already claimed.
+func ClaimHistoryExport(
+	ctx context.Context,
+	tx pgx.Tx,
+	day time.Time,
+) (bool, error) {
+	qry := `
+		INSERT INTO history_exports (day)
+		VALUES ($1::date)
+		ON CONFLICT (day) DO NOTHING`
+	tag, err := tx.Exec(ctx, qry, day.UTC().Format("2006-01-02"))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMeetingHistory(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	m, err := meetingStateFactory(ctx, tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Labels = Labels{"course": "physics-101"}
+	m.Meeting.ParticipantCount = 23
+	if err := m.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteMeetingStateByID(ctx, tx, m.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	history, err := GetMeetingHistory(ctx, tx, Q().
+		Where("meeting_history.meeting_id = ?", m.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 {
+		t.Fatal("unexpected history:", history)
+	}
+	h := history[0]
+	if h.MeetingName != m.Meeting.MeetingName {
+		t.Error("unexpected name:", h.MeetingName)
+	}
+	if h.Participants != 23 {
+		t.Error("unexpected participants:", h.Participants)
+	}
+	if h.Labels["course"] != "physics-101" {
+		t.Error("unexpected labels:", h.Labels)
+	}
+	if h.BackendHost == nil || *h.BackendHost != m.backend.Backend.Host {
+		t.Error("unexpected backend:", h.BackendHost)
+	}
+}
+
+func TestDeleteMeetingHistoryBefore(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	// The history is stored in the default partition
+	// until the partition of the month is created.
+	endedAt := time.Date(2001, 3, 14, 12, 0, 0, 0, time.UTC)
+	qry := `
+		INSERT INTO meeting_history (meeting_id, created_at, ended_at)
+		VALUES ($1, $2, $2)`
+	if _, err := tx.Exec(ctx, qry, "march", endedAt); err != nil {
+		t.Fatal(err)
+	}
+	if err := EnsureMeetingHistoryPartitions(ctx, tx, endedAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Exec(ctx, qry, "april", endedAt.AddDate(0, 1, 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	dropped, err := DeleteMeetingHistoryBefore(ctx, tx, endedAt.AddDate(0, 1, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dropped < 1 {
+		t.Error("expected the partition to be dropped")
+	}
+	history, err := GetMeetingHistory(ctx, tx, Q().
+		Where("meeting_history.meeting_id IN (?, ?)", "march", "april"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].MeetingID != "april" {
+		t.Error("unexpected history:", history)
+	}
+}
+
+func TestClaimHistoryExport(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	day := time.Date(2001, 2, 3, 4, 5, 6, 0, time.UTC)
+	claimed, err := ClaimHistoryExport(ctx, tx, day)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !claimed {
+		t.Error("day should be claimed")
+	}
+	claimed, err = ClaimHistoryExport(ctx, tx, day.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claimed {
+		t.Error("day should not be claimed twice")
+	}
+}
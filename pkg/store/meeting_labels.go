@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// Limits of the meeting labels
+const (
+	MaxLabels           = 16
+	MaxLabelValueLength = 128
+)
+
+// Label keys are lowercase like "course" or "dept.name"
+var labelKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,62}$`)
+
+// Labels of a meeting, like the course or the
+// department, e.g. {"course": "physics-101"}.
+type Labels map[string]string
+
+// ValidLabelKey checks if the key can be used as label
+func ValidLabelKey(key string) bool {
+	return labelKeyPattern.MatchString(key)
+}
+
+// Set adds a label. Invalid keys, empty values and labels
+// exceeding the limit are ignored. Long values are truncated.
+// The result indicates if the label was set.
+func (l Labels) Set(key, value string) bool {
+	value = strings.TrimSpace(value)
+	if !ValidLabelKey(key) || value == "" {
+		return false
+	}
+	if _, ok := l[key]; !ok && len(l) >= MaxLabels {
+		return false
+	}
+	if r := []rune(value); len(r) > MaxLabelValueLength {
+		value = string(r[:MaxLabelValueLength])
+	}
+	l[key] = value
+	return true
+}
+
+// Validate checks the keys, values and the number of labels
+func (l Labels) Validate() error {
+	if len(l) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+	for key, value := range l {
+		if !ValidLabelKey(key) {
+			return fmt.Errorf("invalid label key: %s", key)
+		}
+		if value == "" {
+			return fmt.Errorf("empty value of label: %s", key)
+		}
+		if len([]rune(value)) > MaxLabelValueLength {
+			return fmt.Errorf("value of label %s is too long", key)
+		}
+	}
+	return nil
+}
+
+// ParseLabelSelector parses a label filter like
+// "course:physics-101". Without a value, all meetings
+// with the label match.
+func ParseLabelSelector(s string) (string, string, error) {
+	key, value := s, ""
+	if i := strings.Index(s, ":"); i >= 0 {
+		key, value = s[:i], s[i+1:]
+	}
+	key = strings.TrimSpace(key)
+	if !ValidLabelKey(key) {
+		return "", "", fmt.Errorf("invalid label key: %s", key)
+	}
+	return key, strings.TrimSpace(value), nil
+}
+
+// WhereLabel restricts the meetings query to meetings
+// with the label. If the value is empty, all meetings
+// with the label key match.
+func WhereLabel(q sq.SelectBuilder, key, value string) sq.SelectBuilder {
+	if value == "" {
+		return q.Where("meetings.labels ?? ?", key)
+	}
+	match, _ := json.Marshal(Labels{key: value})
+	return q.Where("meetings.labels @> ?::jsonb", string(match))
+}
+
+// MeetingLabelStats are the number of meetings
+// and attendees with a label value.
+type MeetingLabelStats struct {
+	Value     string `json:"value"`
+	Meetings  int    `json:"meetings"`
+	Attendees int    `json:"attendees"`
+}
+
+// GetMeetingLabelStats aggregates the meetings
+// matching the query by the value of the label.
+func GetMeetingLabelStats(
+	ctx context.Context,
+	tx pgx.Tx,
+	key string,
+	q sq.SelectBuilder,
+) ([]*MeetingLabelStats, error) {
+	qry, params, _ := q.
+		Column("meetings.labels->>?", key).
+		Columns(
+			"COUNT(*)",
+			"COALESCE(SUM((meetings.state->>'ParticipantCount')::int), 0)").
+		From("meetings").
+		Where("meetings.labels ?? ?", key).
+		GroupBy("1").
+		OrderBy("1 ASC").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	stats := []*MeetingLabelStats{}
+	for rows.Next() {
+		s := &MeetingLabelStats{}
+		if err := rows.Scan(
+			&s.Value,
+			&s.Meetings,
+			&s.Attendees,
+		); err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+	return stats, rows.Err()
+}
@@ -0,0 +1,88 @@
+package store
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLabelsSet(t *testing.T) {
+	l := Labels{}
+	if !l.Set("course", " physics-101 ") {
+		t.Error("expected label to be set")
+	}
+	if l["course"] != "physics-101" {
+		t.Error("unexpected value:", l["course"])
+	}
+	if l.Set("Course", "foo") {
+		t.Error("uppercase keys should be ignored")
+	}
+	if l.Set("empty", "") {
+		t.Error("empty values should be ignored")
+	}
+	l.Set("long", strings.Repeat("a", 200))
+	if len(l["long"]) != MaxLabelValueLength {
+		t.Error("value should be truncated")
+	}
+
+	for i := 0; i < 2*MaxLabels; i++ {
+		l.Set("l"+strings.Repeat("x", i), "v")
+	}
+	if len(l) != MaxLabels {
+		t.Error("unexpected number of labels:", len(l))
+	}
+	if !l.Set("course", "math-201") {
+		t.Error("existing labels should be replaced")
+	}
+}
+
+func TestLabelsValidate(t *testing.T) {
+	if err := (Labels{"course": "physics-101"}).Validate(); err != nil {
+		t.Error(err)
+	}
+	if err := (Labels{"a b": "c"}).Validate(); err == nil {
+		t.Error("expected invalid key")
+	}
+	if err := (Labels{"course": ""}).Validate(); err == nil {
+		t.Error("expected empty value error")
+	}
+}
+
+func TestParseLabelSelector(t *testing.T) {
+	key, value, err := ParseLabelSelector("course:physics-101")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "course" || value != "physics-101" {
+		t.Error("unexpected selector:", key, value)
+	}
+	key, value, err = ParseLabelSelector("department")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key != "department" || value != "" {
+		t.Error("unexpected selector:", key, value)
+	}
+	if _, _, err := ParseLabelSelector(":foo"); err == nil {
+		t.Error("expected error for missing key")
+	}
+}
+
+func TestWhereLabel(t *testing.T) {
+	qry, params, _ := WhereLabel(Q(), "course", "").
+		Columns("id").From("meetings").ToSql()
+	if !strings.Contains(qry, "meetings.labels ? $1") {
+		t.Error("unexpected query:", qry)
+	}
+	if params[0] != "course" {
+		t.Error("unexpected params:", params)
+	}
+
+	qry, params, _ = WhereLabel(Q(), "course", "physics-101").
+		Columns("id").From("meetings").ToSql()
+	if !strings.Contains(qry, "meetings.labels @> $1::jsonb") {
+		t.Error("unexpected query:", qry)
+	}
+	if params[0] != `{"course":"physics-101"}` {
+		t.Error("unexpected params:", params)
+	}
+}
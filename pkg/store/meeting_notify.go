@@ -0,0 +1,81 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Errors
+var (
+	ErrDeadlineReached = errors.New(
+		"meeting was not found within the deadline")
+)
+
+const meetingsAvailableChannel = "meetings_available"
+
+// AwaitMeetingState waits for a meeting identified by
+// the internal ID to become available in the store.
+//
+// BBB might fire an event for a meeting before the
+// meeting state was stored. Instead of polling, we
+// listen for the notification of the meetings trigger.
+// No transaction is kept open while waiting.
+func AwaitMeetingState(
+	ctx context.Context,
+	internalID string,
+	deadlineAfter time.Duration,
+) (*MeetingState, error) {
+	ctx, cancel := context.WithTimeout(ctx, deadlineAfter)
+	defer cancel()
+
+	conn, err := Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	// Start listening before checking the state, so
+	// we can not miss the notification.
+	listen := "LISTEN " + pgx.Identifier{meetingsAvailableChannel}.Sanitize()
+	if _, err := conn.Exec(ctx, listen); err != nil {
+		return nil, err
+	}
+	defer func() {
+		// The connection is returned to the pool
+		unlisten := "UNLISTEN " + pgx.Identifier{meetingsAvailableChannel}.Sanitize()
+		conn.Exec(context.Background(), unlisten)
+	}()
+
+	for {
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		mstate, err := GetMeetingState(ctx, tx, Q().
+			Where("meetings.internal_id = ?", internalID))
+		tx.Rollback(ctx) // Close transaction
+		if err != nil {
+			return nil, err
+		}
+		if mstate != nil {
+			return mstate, nil
+		}
+
+		// Wait for the meeting to become available
+		for {
+			n, err := conn.Conn().WaitForNotification(ctx)
+			if ctx.Err() == context.DeadlineExceeded {
+				return nil, ErrDeadlineReached
+			}
+			if err != nil {
+				return nil, err
+			}
+			if n.Payload == internalID {
+				break
+			}
+		}
+	}
+}
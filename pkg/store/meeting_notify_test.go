@@ -0,0 +1,18 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestAwaitMeetingStateDeadline(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	tx.Rollback(ctx)
+
+	_, err := AwaitMeetingState(ctx, "unknown-meeting", 100*time.Millisecond)
+	if err != ErrDeadlineReached {
+		t.Error("expected deadline reached, got:", err)
+	}
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+)
+
+// A MeetingPin places a new meeting of a frontend on a
+// backend, overriding the router, e.g. for a high-profile
+// event on the strongest backend. The pin is ignored
+// after it expired.
+type MeetingPin struct {
+	ID         string `json:"id"`
+	FrontendID string `json:"frontend_id"`
+
+	// MeetingID is the meeting ID used by the frontend
+	MeetingID string `json:"meeting_id"`
+	BackendID string `json:"backend_id"`
+
+	Note      string `json:"note"`
+	CreatedBy string `json:"created_by"`
+
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetMeetingPins retrieves the pins matching the query
+func GetMeetingPins(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*MeetingPin, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"frontend_id",
+		"meeting_id",
+		"backend_id",
+		"note",
+		"created_by",
+		"expires_at",
+		"created_at").
+		From("meeting_pins").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*MeetingPin{}
+	for rows.Next() {
+		p := &MeetingPin{}
+		if err := rows.Scan(
+			&p.ID,
+			&p.FrontendID,
+			&p.MeetingID,
+			&p.BackendID,
+			&p.Note,
+			&p.CreatedBy,
+			&p.ExpiresAt,
+			&p.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, p)
+	}
+	return results, rows.Err()
+}
+
+// GetMeetingPin retrieves a single pin.
+// This may return nil without an error.
+func GetMeetingPin(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*MeetingPin, error) {
+	pins, err := GetMeetingPins(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(pins) == 0 {
+		return nil, nil
+	}
+	return pins[0], nil
+}
+
+// GetActiveMeetingPin retrieves the pin of a meeting of
+// the frontend, unless it expired. This may return nil
+// without an error.
+func GetActiveMeetingPin(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	meetingID string,
+) (*MeetingPin, error) {
+	return GetMeetingPin(ctx, tx, Q().
+		Where("frontend_id = ?", frontendID).
+		Where("meeting_id = ?", meetingID).
+		Where("expires_at > ?", time.Now().UTC()))
+}
+
+// Validate checks for presence of required fields.
+// The pin must expire in the future.
+func (p *MeetingPin) Validate() error {
+	err := ValidationError{}
+	p.MeetingID = strings.TrimSpace(p.MeetingID)
+	if p.FrontendID == "" {
+		err.Add("frontend_id", ErrFieldRequired)
+	}
+	if p.MeetingID == "" {
+		err.Add("meeting_id", ErrFieldRequired)
+	}
+	if p.BackendID == "" {
+		err.Add("backend_id", ErrFieldRequired)
+	}
+	if p.ExpiresAt.IsZero() {
+		err.Add("expires_at", ErrFieldRequired)
+	} else if !p.ExpiresAt.After(time.Now()) {
+		err.Add("expires_at", "must be in the future")
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// Save creates the pin. An existing pin of the
+// meeting is replaced.
+func (p *MeetingPin) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		INSERT INTO meeting_pins (
+			id, frontend_id, meeting_id, backend_id,
+			note, created_by, expires_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7
+		)
+		ON CONFLICT (frontend_id, meeting_id) DO UPDATE
+		   SET backend_id = EXCLUDED.backend_id,
+		       note       = EXCLUDED.note,
+		       created_by = EXCLUDED.created_by,
+		       expires_at = EXCLUDED.expires_at,
+		       created_at = CURRENT_TIMESTAMP
+		RETURNING id, created_at`
+	return tx.QueryRow(ctx, qry,
+		newID(),
+		p.FrontendID,
+		p.MeetingID,
+		p.BackendID,
+		p.Note,
+		p.CreatedBy,
+		p.ExpiresAt.UTC()).Scan(&p.ID, &p.CreatedAt)
+}
+
+// DeleteMeetingPin removes a pin. A meeting already
+// created on the backend is not moved.
+func DeleteMeetingPin(
+	ctx context.Context,
+	tx pgx.Tx,
+	id string,
+) error {
+	qry := `DELETE FROM meeting_pins WHERE id = $1`
+	_, err := tx.Exec(ctx, qry, id)
+	return err
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMeetingPins(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	pin := &MeetingPin{
+		FrontendID: frontend.ID,
+		MeetingID:  "keynote",
+		BackendID:  backend.ID,
+		ExpiresAt:  time.Now().Add(time.Hour),
+	}
+	if err := pin.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := pin.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if pin.ID == "" {
+		t.Error("expected an id")
+	}
+
+	found, err := GetActiveMeetingPin(ctx, tx, frontend.ID, "keynote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.BackendID != backend.ID {
+		t.Error("unexpected pin:", found)
+	}
+
+	// Pinning the meeting again replaces the pin
+	pin.ExpiresAt = time.Now().Add(-time.Minute)
+	if err := pin.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	found, err = GetActiveMeetingPin(ctx, tx, frontend.ID, "keynote")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != nil {
+		t.Error("the pin should be expired")
+	}
+
+	if err := DeleteMeetingPin(ctx, tx, pin.ID); err != nil {
+		t.Fatal(err)
+	}
+	pins, err := GetMeetingPins(ctx, tx, Q())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pins) != 0 {
+		t.Error("the pin should be deleted")
+	}
+}
+
+func TestMeetingPinValidate(t *testing.T) {
+	pin := &MeetingPin{
+		FrontendID: "f",
+		MeetingID:  " m ",
+		BackendID:  "b",
+		ExpiresAt:  time.Now().Add(-time.Hour),
+	}
+	err := pin.Validate()
+	verr, ok := err.(ValidationError)
+	if !ok {
+		t.Fatal("expected a validation error:", err)
+	}
+	if _, ok := verr["expires_at"]; !ok {
+		t.Error("expected an error for expires_at:", verr)
+	}
+	if pin.MeetingID != "m" {
+		t.Error("meeting id should be trimmed:", pin.MeetingID)
+	}
+}
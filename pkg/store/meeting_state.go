@@ -25,6 +25,9 @@ type MeetingState struct {
 
 	Meeting *bbb.Meeting
 
+	// Labels are attached when the meeting is created
+	Labels Labels
+
 	FrontendID *string
 	frontend   *FrontendState
 
@@ -56,6 +59,7 @@ func GetMeetingStates(
 		"meetings.frontend_id",
 		"meetings.backend_id",
 		"meetings.state",
+		"meetings.labels",
 		"meetings.created_at",
 		"meetings.updated_at",
 		"meetings.synced_at").
@@ -104,6 +108,52 @@ func GetMeetingStateByID(
 		Where("id = ?", id))
 }
 
+// GetMeetingBackendID retrieves the ID of the backend
+// of a meeting. The ID is empty if the meeting is unknown
+// or not associated with a backend.
+func GetMeetingBackendID(
+	ctx context.Context,
+	tx pgx.Tx,
+	id string,
+) (string, error) {
+	var backendID *string
+	qry := `SELECT backend_id FROM meetings WHERE id = $1`
+	err := tx.QueryRow(ctx, qry, id).Scan(&backendID)
+	if err == pgx.ErrNoRows || backendID == nil {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return *backendID, nil
+}
+
+// GetMeetingBackendIDs retrieves the IDs of the backends
+// of the meetings. Meetings which are unknown or not
+// associated with a backend are not included.
+func GetMeetingBackendIDs(
+	ctx context.Context,
+	tx pgx.Tx,
+	ids []string,
+) (map[string]string, error) {
+	qry := `SELECT id, backend_id FROM meetings
+			 WHERE id = ANY($1) AND backend_id IS NOT NULL`
+	rows, err := tx.Query(ctx, qry, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	backends := make(map[string]string, len(ids))
+	for rows.Next() {
+		var id, backendID string
+		if err := rows.Scan(&id, &backendID); err != nil {
+			return nil, err
+		}
+		backends[id] = backendID
+	}
+	return backends, rows.Err()
+}
+
 func meetingStateFromRow(
 	row pgx.Row,
 ) (*MeetingState, error) {
@@ -114,6 +164,7 @@ func meetingStateFromRow(
 		&state.FrontendID,
 		&state.BackendID,
 		&state.Meeting,
+		&state.Labels,
 		&state.CreatedAt,
 		&state.UpdatedAt,
 		&state.SyncedAt)
@@ -330,16 +381,19 @@ func (s *MeetingState) insert(ctx context.Context, tx pgx.Tx) (string, error) {
 			state,
 
 			frontend_id,
-			backend_id
+			backend_id,
+
+			labels
 		) VALUES (
-			$1, $2, $3, $4, $5
+			$1, $2, $3, $4, $5, $6
 		) RETURNING id`
 	err := tx.QueryRow(ctx, qry,
 		s.Meeting.MeetingID,
 		s.Meeting.InternalMeetingID,
 		s.Meeting,
 		s.FrontendID,
-		s.BackendID).Scan(&s.ID)
+		s.BackendID,
+		s.labels()).Scan(&s.ID)
 	if err != nil {
 		return "", err
 	}
@@ -357,7 +411,8 @@ func (s *MeetingState) update(ctx context.Context, tx pgx.Tx) error {
 		       frontend_id  = $4,
 			   backend_id   = $5,
 		  	   synced_at    = $6,
-			   updated_at   = $7 
+			   updated_at   = $7,
+			   labels       = $8
 	 	 WHERE id = $1`
 	_, err := tx.Exec(ctx, qry,
 		s.ID,
@@ -366,10 +421,19 @@ func (s *MeetingState) update(ctx context.Context, tx pgx.Tx) error {
 		s.FrontendID,
 		s.BackendID,
 		s.SyncedAt,
-		s.UpdatedAt)
+		s.UpdatedAt,
+		s.labels())
 	return err
 }
 
+// labels are never stored as null
+func (s *MeetingState) labels() Labels {
+	if s.Labels == nil {
+		return Labels{}
+	}
+	return s.Labels
+}
+
 // Upsert meeting state will create the meeting state
 // or will fall back to a state update.
 func (s *MeetingState) Upsert(ctx context.Context, tx pgx.Tx) (string, error) {
@@ -362,3 +362,35 @@ func TestMeetingStateUpsert(t *testing.T) {
 		t.Error("unexpected meeting running:", m0.Meeting)
 	}
 }
+
+func TestGetMeetingBackendIDs(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	state, err := meetingStateFactory(ctx, tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	backendID, err := GetMeetingBackendID(ctx, tx, state.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if backendID != *state.BackendID {
+		t.Error("unexpected backend id:", backendID)
+	}
+
+	unknown := uuid.New().String()
+	backends, err := GetMeetingBackendIDs(
+		ctx, tx, []string{state.ID, unknown})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backends) != 1 || backends[state.ID] != *state.BackendID {
+		t.Error("unexpected backends:", backends)
+	}
+}
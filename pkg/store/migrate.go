@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+)
+
+// A Migration is a file of the schema. The
+// name starts with the version, e.g. 0023_bans.sql.
+type Migration struct {
+	Version int
+	Name    string
+}
+
+// GetMigrations lists the migrations of the schema
+// ordered by version.
+func GetMigrations(schema fs.FS) ([]*Migration, error) {
+	files, err := fs.Glob(schema, "schema/*.sql")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]*Migration, 0, len(files))
+	for _, f := range files {
+		name := path.Base(f)
+		i := strings.Index(name, "_")
+		if i < 0 {
+			return nil, fmt.Errorf("migration without version: %s", name)
+		}
+		version, err := strconv.Atoi(name[:i])
+		if err != nil {
+			return nil, fmt.Errorf("migration without version: %s", name)
+		}
+		migrations = append(migrations, &Migration{
+			Version: version,
+			Name:    f,
+		})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrate applies the migrations of the schema newer
+// than the version of the database. Each migration is
+// applied in a transaction. The applied migrations
+// are returned.
+func Migrate(
+	ctx context.Context,
+	url string,
+	schema fs.FS,
+) ([]*Migration, error) {
+	migrations, err := GetMigrations(schema)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pgx.Connect(ctx, withReadWriteSession(url))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close(ctx)
+
+	current, err := currentSchemaVersion(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := []*Migration{}
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		sql, err := fs.ReadFile(schema, m.Name)
+		if err != nil {
+			return applied, err
+		}
+		log.Info().
+			Int("version", m.Version).
+			Str("migration", m.Name).
+			Msg("applying migration")
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return applied, err
+		}
+		if _, err := tx.Exec(ctx, string(sql)); err != nil {
+			tx.Rollback(ctx)
+			return applied, fmt.Errorf("%s: %w", m.Name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return applied, err
+		}
+		applied = append(applied, m)
+	}
+	return applied, nil
+}
+
+// currentSchemaVersion retrieves the version of the
+// database, which is 0 for an empty database.
+func currentSchemaVersion(
+	ctx context.Context,
+	conn *pgx.Conn,
+) (int, error) {
+	var exists bool
+	qry := `SELECT to_regclass('__meta__') IS NOT NULL`
+	if err := conn.QueryRow(ctx, qry).Scan(&exists); err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int
+	qry = `SELECT COALESCE(MAX(version), 0) FROM __meta__`
+	if err := conn.QueryRow(ctx, qry).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
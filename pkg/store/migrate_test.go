@@ -0,0 +1,30 @@
+package store
+
+import (
+	"testing"
+	"testing/fstest"
+)
+
+func TestGetMigrations(t *testing.T) {
+	schema := fstest.MapFS{
+		"schema/0002_keys.sql":    &fstest.MapFile{},
+		"schema/0001_initial.sql": &fstest.MapFile{},
+		"schema/0010_rooms.sql":   &fstest.MapFile{},
+		"schema/README.md":        &fstest.MapFile{},
+	}
+	migrations, err := GetMigrations(schema)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 3 {
+		t.Fatal("unexpected migrations:", migrations)
+	}
+	if migrations[0].Version != 1 || migrations[2].Version != 10 {
+		t.Error("unexpected order:", migrations[0], migrations[2])
+	}
+
+	schema["schema/keys.sql"] = &fstest.MapFile{}
+	if _, err := GetMigrations(schema); err == nil {
+		t.Error("expected an error for a migration without version")
+	}
+}
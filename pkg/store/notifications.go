@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// ClaimNotification remembers that the notification
+// with the key is sent at t. The result is false if the
+// notification was already sent within the repeat interval.
+func ClaimNotification(
+	ctx context.Context,
+	tx pgx.Tx,
+	key string,
+	t time.Time,
+	repeat time.Duration,
+) (bool, error) {
+	t = t.UTC()
+	qry := `
+		INSERT INTO notifications_sent (
+			key, sent_at, expires_at
+		) VALUES (
+			$1, $2, $3
+		)
+		ON CONFLICT (key) DO UPDATE
+		   SET sent_at = EXCLUDED.sent_at,
+		       expires_at = EXCLUDED.expires_at
+		 WHERE notifications_sent.expires_at <= $2`
+	tag, err := tx.Exec(ctx, qry, key, t, t.Add(repeat))
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteExpiredNotifications removes the notifications
+// where the repeat interval is over.
+func DeleteExpiredNotifications(
+	ctx context.Context,
+	tx pgx.Tx,
+) (int64, error) {
+	qry := `
+		DELETE FROM notifications_sent
+		 WHERE expires_at < $1`
+	tag, err := tx.Exec(ctx, qry, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
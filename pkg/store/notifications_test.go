@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestClaimNotification(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	now := time.Now()
+	ok, err := ClaimNotification(
+		ctx, tx, "backend_down:bbb1", now, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("first notification should be claimed")
+	}
+
+	ok, err = ClaimNotification(
+		ctx, tx, "backend_down:bbb1", now.Add(time.Minute), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("repeated notification should not be claimed")
+	}
+
+	// After the repeat interval
+	ok, err = ClaimNotification(
+		ctx, tx, "backend_down:bbb1", now.Add(2*time.Hour), time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("notification should be claimed after the interval")
+	}
+}
+
+func TestDeleteExpiredNotifications(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	if _, err := ClaimNotification(
+		ctx, tx, "queue_stuck:commands",
+		time.Now().Add(-time.Hour), time.Minute,
+	); err != nil {
+		t.Fatal(err)
+	}
+	n, err := DeleteExpiredNotifications(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Error("expected one deleted notification, got:", n)
+	}
+}
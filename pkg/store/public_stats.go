@@ -0,0 +1,83 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// PublicStats are the aggregated statistics of the
+// cluster. They are anonymized before publishing.
+type PublicStats struct {
+	// Meetings and Attendees running now
+	Meetings  int
+	Attendees int
+
+	// MeetingsTotal includes the meeting history
+	MeetingsTotal int64
+
+	Hours []*PublicStatsHour
+}
+
+// PublicStatsHour is the number of meetings
+// running in an hour.
+type PublicStatsHour struct {
+	Hour     time.Time
+	Meetings int
+}
+
+// GetPublicStats aggregates the meetings and the
+// meeting history. The histogram starts at the
+// hour of since and ends with the current hour.
+func GetPublicStats(
+	ctx context.Context,
+	tx pgx.Tx,
+	since time.Time,
+) (*PublicStats, error) {
+	stats := &PublicStats{}
+	qry := `
+		SELECT COUNT(*),
+		       COALESCE(SUM((state->>'ParticipantCount')::int), 0),
+		       COUNT(*) + (SELECT COUNT(*) FROM meeting_history)
+		  FROM meetings`
+	if err := tx.QueryRow(ctx, qry).Scan(
+		&stats.Meetings,
+		&stats.Attendees,
+		&stats.MeetingsTotal,
+	); err != nil {
+		return nil, err
+	}
+
+	qry = `
+		SELECT h.hour, COUNT(m.created_at)
+		  FROM generate_series(
+		         date_trunc('hour', $1::timestamp),
+		         date_trunc('hour', $2::timestamp),
+		         interval '1 hour') AS h(hour)
+		  LEFT JOIN (
+		         SELECT created_at, NULL::timestamp AS ended_at
+		           FROM meetings
+		          UNION ALL
+		         SELECT created_at, ended_at
+		           FROM meeting_history
+		          WHERE ended_at >= $1::timestamp
+		       ) AS m
+		    ON m.created_at < h.hour + interval '1 hour'
+		   AND (m.ended_at IS NULL OR m.ended_at >= h.hour)
+		 GROUP BY h.hour
+		 ORDER BY h.hour ASC`
+	rows, err := tx.Query(ctx, qry, since.UTC(), time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		h := &PublicStatsHour{}
+		if err := rows.Scan(&h.Hour, &h.Meetings); err != nil {
+			return nil, err
+		}
+		stats.Hours = append(stats.Hours, h)
+	}
+	return stats, rows.Err()
+}
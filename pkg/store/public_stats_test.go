@@ -0,0 +1,39 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestGetPublicStats(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	m, err := meetingStateFactory(ctx, tx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.Meeting.ParticipantCount = 5
+	if err := m.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	stats, err := GetPublicStats(ctx, tx, time.Now().Add(-3*time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Meetings < 1 || stats.Attendees < 5 {
+		t.Error("unexpected stats:", stats.Meetings, stats.Attendees)
+	}
+	if stats.MeetingsTotal < int64(stats.Meetings) {
+		t.Error("unexpected total:", stats.MeetingsTotal)
+	}
+	if len(stats.Hours) != 4 {
+		t.Fatal("unexpected hours:", len(stats.Hours))
+	}
+	if stats.Hours[3].Meetings < 1 {
+		t.Error("the meeting should be running now:", stats.Hours[3])
+	}
+}
@@ -0,0 +1,149 @@
+package store
+
+import (
+	"context"
+	"regexp"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+// SlowQueryThreshold is the duration after which a
+// query is considered slow and will be logged as
+// a warning. Setting the threshold to zero disables this.
+var SlowQueryThreshold = 500 * time.Millisecond
+
+// QueryDurations are the durations of the database
+// queries by the store function issuing the query.
+var QueryDurations = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name: "store_query_duration_seconds",
+		Help: "Duration of database queries",
+	},
+	[]string{
+		// Query label, e.g. store.GetBackendStates
+		"query",
+	})
+
+type queryLabelContextKey int
+
+// Context key for the query label
+var (
+	queryLabelKey = queryLabelContextKey(1)
+)
+
+// ContextWithQueryLabel sets the label of the queries
+// executed with the context. Without a label, the
+// calling function is used.
+func ContextWithQueryLabel(
+	ctx context.Context, label string,
+) context.Context {
+	return context.WithValue(ctx, queryLabelKey, label)
+}
+
+// funcSuffix matches the name of anonymous functions
+var funcSuffix = regexp.MustCompile(`(\.func\d+)+$`)
+
+// queryCaller finds the first function on the stack
+// outside of pgx and the query logger.
+func queryCaller() string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		name := frame.Function
+		if name != "" &&
+			!strings.HasPrefix(name, "github.com/jackc/") &&
+			!strings.Contains(name, "store.(*queryLogger)") &&
+			!strings.Contains(name, "store.queryLabel") {
+			if i := strings.LastIndex(name, "/"); i >= 0 {
+				name = name[i+1:]
+			}
+			return funcSuffix.ReplaceAllString(name, "")
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// queryLabel gets the label from the context or
+// derives it from the caller.
+func queryLabel(ctx context.Context) string {
+	if ctx != nil {
+		if label, ok := ctx.Value(queryLabelKey).(string); ok {
+			return label
+		}
+	}
+	return queryCaller()
+}
+
+// compactSQL collapses the whitespace of a query
+// for a single line log output.
+func compactSQL(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// The queryLogger receives the pgx log events and
+// records the query durations. Slow and failed queries
+// are logged, all other queries on trace level.
+type queryLogger struct{}
+
+// Log implements the pgx.Logger interface
+func (l *queryLogger) Log(
+	ctx context.Context,
+	level pgx.LogLevel,
+	msg string,
+	data map[string]interface{},
+) {
+	sql, ok := data["sql"].(string)
+	if !ok {
+		if level <= pgx.LogLevelError {
+			log.Error().
+				Interface("err", data["err"]).
+				Msg("database: " + msg)
+		}
+		return // Not a query
+	}
+	label := queryLabel(ctx)
+
+	if err, ok := data["err"].(error); ok {
+		log.Error().
+			Err(err).
+			Str("query", label).
+			Str("sql", compactSQL(sql)).
+			Msg("query failed")
+		return
+	}
+
+	duration, ok := data["time"].(time.Duration)
+	if !ok {
+		return
+	}
+	QueryDurations.WithLabelValues(label).Observe(duration.Seconds())
+
+	var event *zerolog.Event
+	if SlowQueryThreshold > 0 && duration > SlowQueryThreshold {
+		event = log.Warn()
+		msg = "slow query"
+	} else {
+		event = log.Trace()
+	}
+	if !event.Enabled() {
+		return
+	}
+	if rows, ok := data["rowCount"].(int); ok {
+		event = event.Int("rows", rows)
+	}
+	event.
+		Str("query", label).
+		Dur("duration", duration).
+		Str("sql", compactSQL(sql)).
+		Msg(msg)
+}
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCompactSQL(t *testing.T) {
+	sql := `
+		SELECT id
+		  FROM meetings
+		 WHERE id = $1`
+	if compactSQL(sql) != "SELECT id FROM meetings WHERE id = $1" {
+		t.Error("unexpected sql:", compactSQL(sql))
+	}
+}
+
+func TestQueryLabel(t *testing.T) {
+	ctx := ContextWithQueryLabel(context.Background(), "meetings")
+	if label := queryLabel(ctx); label != "meetings" {
+		t.Error("unexpected label:", label)
+	}
+
+	label := func() string {
+		return queryLabel(context.Background())
+	}()
+	if label != "store.TestQueryLabel" {
+		t.Error("unexpected label:", label)
+	}
+}
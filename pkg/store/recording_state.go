@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+// The RecordingState holds a recording and the
+// backend, where the recording is stored.
+type RecordingState struct {
+	// ID is the BBB record ID
+	ID                string `json:"id"`
+	BackendID         string `json:"backend_id"`
+	InternalMeetingID string `json:"internal_meeting_id"`
+
+	Recording *bbb.Recording `json:"recording"`
+
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+	SyncedAt  time.Time `json:"synced_at"`
+}
+
+// NewRecordingState creates the state of a
+// recording on the backend.
+func NewRecordingState(
+	backendID string,
+	recording *bbb.Recording,
+) *RecordingState {
+	return &RecordingState{
+		ID:                recording.RecordID,
+		BackendID:         backendID,
+		InternalMeetingID: recording.InternalMeetingID,
+		Recording:         recording,
+	}
+}
+
+// GetRecordingStates retrieves the recordings
+// matching the query.
+func GetRecordingStates(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*RecordingState, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"backend_id",
+		"internal_meeting_id",
+		"state",
+		"created_at",
+		"updated_at",
+		"synced_at").
+		From("recordings").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*RecordingState{}
+	for rows.Next() {
+		s := &RecordingState{}
+		if err := rows.Scan(
+			&s.ID,
+			&s.BackendID,
+			&s.InternalMeetingID,
+			&s.Recording,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+			&s.SyncedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, s)
+	}
+	return results, rows.Err()
+}
+
+// Save creates or updates the recording state
+func (s *RecordingState) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	now := time.Now().UTC()
+	qry := `
+		INSERT INTO recordings (
+			id, backend_id, internal_meeting_id, state, synced_at
+		) VALUES (
+			$1, $2, $3, $4, $5
+		)
+		ON CONFLICT (id) DO UPDATE
+		   SET backend_id          = EXCLUDED.backend_id,
+		       internal_meeting_id = EXCLUDED.internal_meeting_id,
+		       state               = EXCLUDED.state,
+		       synced_at           = EXCLUDED.synced_at,
+		       updated_at          = $5
+		RETURNING created_at, updated_at, synced_at`
+	return tx.QueryRow(ctx, qry,
+		s.ID,
+		s.BackendID,
+		s.InternalMeetingID,
+		s.Recording,
+		now).Scan(&s.CreatedAt, &s.UpdatedAt, &s.SyncedAt)
+}
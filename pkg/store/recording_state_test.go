@@ -0,0 +1,49 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestRecordingStateSave(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := NewRecordingState(backend.ID, &bbb.Recording{
+		RecordID:          "183f0bf3a0982a127bdb8161e0c44eb696b3e75c-1531240571142",
+		InternalMeetingID: "183f0bf3a0982a127bdb8161e0c44eb696b3e75c-1531240571142",
+		MeetingID:         "meeting23",
+		Name:              "Lecture",
+	})
+	if err := rec.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	// Update the recording
+	rec.Recording.Published = true
+	if err := rec.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	recordings, err := GetRecordingStates(ctx, tx, Q().
+		Where("backend_id = ?", backend.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recordings) != 1 {
+		t.Fatal("unexpected recordings:", recordings)
+	}
+	if recordings[0].Recording.Name != "Lecture" {
+		t.Error("unexpected recording:", recordings[0].Recording)
+	}
+	if !recordings[0].Recording.Published {
+		t.Error("the recording should be published")
+	}
+}
@@ -0,0 +1,72 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// RecordRequestChecksum remembers the checksum and nonce
+// of a request of a frontend until the window is over.
+// The result is false if the tuple was already seen
+// within the window, i.e. the request is a replay.
+func RecordRequestChecksum(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	checksum string,
+	nonce string,
+	window time.Duration,
+) (bool, error) {
+	now := time.Now().UTC()
+	qry := `
+		INSERT INTO request_checksums (
+			frontend_id, checksum, nonce, expires_at
+		) VALUES (
+			$1, $2, $3, $4
+		)
+		ON CONFLICT (frontend_id, checksum, nonce) DO UPDATE
+		   SET expires_at = EXCLUDED.expires_at
+		 WHERE request_checksums.expires_at < $5`
+	tag, err := tx.Exec(ctx, qry,
+		frontendID, checksum, nonce, now.Add(window), now)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() == 1, nil
+}
+
+// DeleteRequestChecksum forgets a checksum, e.g. when
+// the request was not handled and may be repeated.
+func DeleteRequestChecksum(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	checksum string,
+	nonce string,
+) error {
+	qry := `
+		DELETE FROM request_checksums
+		 WHERE frontend_id = $1
+		   AND checksum = $2
+		   AND nonce = $3`
+	_, err := tx.Exec(ctx, qry, frontendID, checksum, nonce)
+	return err
+}
+
+// DeleteExpiredRequestChecksums removes the checksums
+// where the replay window is over.
+func DeleteExpiredRequestChecksums(
+	ctx context.Context,
+	tx pgx.Tx,
+) (int64, error) {
+	qry := `
+		DELETE FROM request_checksums
+		 WHERE expires_at < $1`
+	tag, err := tx.Exec(ctx, qry, time.Now().UTC())
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRecordRequestChecksum(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	fresh, err := RecordRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fresh {
+		t.Error("first request should not be a replay")
+	}
+
+	fresh, err = RecordRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fresh {
+		t.Error("repeated request should be a replay")
+	}
+
+	// Other nonce
+	fresh, err = RecordRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", "nonce", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fresh {
+		t.Error("request with other nonce should not be a replay")
+	}
+}
+
+func TestDeleteExpiredRequestChecksums(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordRequestChecksum(
+		ctx, tx, frontend.ID, "expired", "", -time.Minute,
+	); err != nil {
+		t.Fatal(err)
+	}
+	n, err := DeleteExpiredRequestChecksums(ctx, tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 1 {
+		t.Error("expected expired checksums to be deleted")
+	}
+
+	// The window is over, so the request is accepted again
+	fresh, err := RecordRequestChecksum(
+		ctx, tx, frontend.ID, "expired", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fresh {
+		t.Error("request after the window should not be a replay")
+	}
+}
+
+func TestDeleteRequestChecksum(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := RecordRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", "", time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	if err := DeleteRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	// The request can be repeated
+	fresh, err := RecordRequestChecksum(
+		ctx, tx, frontend.ID, "checksum", "", time.Minute)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fresh {
+		t.Error("released checksum should be fresh")
+	}
+}
@@ -0,0 +1,336 @@
+package store
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/jackc/pgx/v4"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/cron"
+)
+
+// A Room is a persistent meeting of a frontend. The
+// meeting is created with the parameters of the room
+// when the room is joined.
+type Room struct {
+	ID         string `json:"id"`
+	FrontendID string `json:"frontend_id"`
+
+	// MeetingID is the stable ID of the room
+	// used by the frontend in joins.
+	MeetingID string `json:"meeting_id"`
+	Name      string `json:"name"`
+
+	// Params are additional create parameters,
+	// e.g. the welcome message.
+	Params bbb.Params `json:"params"`
+
+	ModeratorPW string `json:"moderator_pw"`
+	AttendeePW  string `json:"attendee_pw"`
+
+	// Schedule is a cron expression of the start
+	// of the meeting in the timezone, e.g. "0 10 * * 1"
+	// for a lecture on mondays at 10:00. The duration
+	// in minutes is used in the calendar.
+	Schedule    string     `json:"schedule"`
+	Timezone    string     `json:"timezone"`
+	Duration    int        `json:"duration"`
+	NextStartAt *time.Time `json:"next_start_at"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// GetRooms retrieves the rooms matching the query
+func GetRooms(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) ([]*Room, error) {
+	qry, params, _ := q.Columns(
+		"id",
+		"frontend_id",
+		"meeting_id",
+		"name",
+		"params",
+		"moderator_pw",
+		"attendee_pw",
+		"schedule",
+		"timezone",
+		"duration",
+		"next_start_at",
+		"created_at").
+		From("rooms").
+		ToSql()
+	rows, err := tx.Query(ctx, qry, params...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*Room{}
+	for rows.Next() {
+		r := &Room{}
+		if err := rows.Scan(
+			&r.ID,
+			&r.FrontendID,
+			&r.MeetingID,
+			&r.Name,
+			&r.Params,
+			&r.ModeratorPW,
+			&r.AttendeePW,
+			&r.Schedule,
+			&r.Timezone,
+			&r.Duration,
+			&r.NextStartAt,
+			&r.CreatedAt,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// GetRoom retrieves a single room.
+// This may return nil without an error.
+func GetRoom(
+	ctx context.Context,
+	tx pgx.Tx,
+	q sq.SelectBuilder,
+) (*Room, error) {
+	rooms, err := GetRooms(ctx, tx, q)
+	if err != nil {
+		return nil, err
+	}
+	if len(rooms) == 0 {
+		return nil, nil
+	}
+	return rooms[0], nil
+}
+
+// GetRoomByMeetingID retrieves the room of a frontend
+// with the meeting ID. This may return nil without an error.
+func GetRoomByMeetingID(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	meetingID string,
+) (*Room, error) {
+	return GetRoom(ctx, tx, Q().
+		Where("frontend_id = ?", frontendID).
+		Where("meeting_id = ?", meetingID))
+}
+
+// Validate checks for presence of required fields.
+// The parameters must not override the meeting ID
+// or the passwords of the room.
+func (r *Room) Validate() error {
+	err := ValidationError{}
+	r.MeetingID = strings.TrimSpace(r.MeetingID)
+	if r.MeetingID == "" {
+		err.Add("meeting_id", ErrFieldRequired)
+	}
+	if r.ModeratorPW == "" {
+		err.Add("moderator_pw", ErrFieldRequired)
+	}
+	if r.AttendeePW == "" {
+		err.Add("attendee_pw", ErrFieldRequired)
+	}
+	if r.ModeratorPW != "" && r.ModeratorPW == r.AttendeePW {
+		err.Add("attendee_pw", "must differ from the moderator_pw")
+	}
+	if r.Timezone == "" {
+		r.Timezone = "UTC"
+	}
+	if _, terr := time.LoadLocation(r.Timezone); terr != nil {
+		err.Add("timezone", terr.Error())
+	}
+	if r.Schedule != "" {
+		if _, serr := cron.Parse(r.Schedule); serr != nil {
+			err.Add("schedule", serr.Error())
+		}
+	}
+	if r.Duration < 0 {
+		err.Add("duration", "must not be negative")
+	}
+	for _, p := range []string{
+		"meetingID", "name", "moderatorPW", "attendeePW", "checksum",
+	} {
+		if _, ok := r.Params[p]; ok {
+			err.Add("params", p+" can not be set")
+		}
+	}
+	if len(err) > 0 {
+		return err
+	}
+	return nil
+}
+
+// CreateParams are the parameters for creating
+// the meeting of the room.
+func (r *Room) CreateParams() bbb.Params {
+	params := bbb.Params{}
+	for k, v := range r.Params {
+		params[k] = v
+	}
+	name := r.Name
+	if name == "" {
+		name = r.MeetingID
+	}
+	params["meetingID"] = r.MeetingID
+	params["name"] = name
+	params["moderatorPW"] = r.ModeratorPW
+	params["attendeePW"] = r.AttendeePW
+	return params
+}
+
+// Save creates the room
+func (r *Room) Save(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	if r.Params == nil {
+		r.Params = bbb.Params{}
+	}
+	next, err := r.NextStart(time.Now())
+	if err != nil {
+		return err
+	}
+	r.NextStartAt = next
+	qry := `
+		INSERT INTO rooms (
+			id, frontend_id, meeting_id, name, params,
+			moderator_pw, attendee_pw,
+			schedule, timezone, duration, next_start_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
+		)
+		RETURNING id, created_at`
+	return tx.QueryRow(ctx, qry,
+		newID(),
+		r.FrontendID,
+		r.MeetingID,
+		r.Name,
+		r.Params,
+		r.ModeratorPW,
+		r.AttendeePW,
+		r.Schedule,
+		r.Timezone,
+		r.Duration,
+		r.NextStartAt).Scan(&r.ID, &r.CreatedAt)
+}
+
+// SaveSchedule updates the schedule and
+// the next start of the room.
+func (r *Room) SaveSchedule(
+	ctx context.Context,
+	tx pgx.Tx,
+) error {
+	qry := `
+		UPDATE rooms
+		   SET schedule      = $2,
+		       timezone      = $3,
+		       duration      = $4,
+		       next_start_at = $5
+		 WHERE id = $1`
+	_, err := tx.Exec(ctx, qry,
+		r.ID,
+		r.Schedule,
+		r.Timezone,
+		r.Duration,
+		r.NextStartAt)
+	return err
+}
+
+// GetDueRooms retrieves the rooms with a scheduled start
+// before the time. The rooms are locked, so the meeting
+// is created by only one instance.
+func GetDueRooms(
+	ctx context.Context,
+	tx pgx.Tx,
+	before time.Time,
+) ([]*Room, error) {
+	return GetRooms(ctx, tx, Q().
+		Where("next_start_at <= ?", before.UTC()).
+		OrderBy("next_start_at ASC").
+		Suffix("FOR UPDATE SKIP LOCKED"))
+}
+
+// LockRoom locks the room until the end of the
+// transaction. Creating the meeting of the room
+// is serialized this way.
+func LockRoom(
+	ctx context.Context,
+	tx pgx.Tx,
+	id string,
+) error {
+	qry := `SELECT id FROM rooms WHERE id = $1 FOR UPDATE`
+	_, err := tx.Exec(ctx, qry, id)
+	return err
+}
+
+// location is the timezone of the schedule
+func (r *Room) location() (*time.Location, error) {
+	if r.Timezone == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(r.Timezone)
+}
+
+// NextStart calculates the first scheduled start after
+// the time. The result is nil without a schedule.
+func (r *Room) NextStart(after time.Time) (*time.Time, error) {
+	if r.Schedule == "" {
+		return nil, nil
+	}
+	schedule, err := cron.Parse(r.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	loc, err := r.location()
+	if err != nil {
+		return nil, err
+	}
+	next, err := schedule.NextIn(after, loc)
+	if err != nil {
+		return nil, err
+	}
+	return &next, nil
+}
+
+// Occurrences lists the scheduled starts in
+// the interval [from, until).
+func (r *Room) Occurrences(from, until time.Time) ([]time.Time, error) {
+	starts := []time.Time{}
+	// The start at from is included
+	t := from.Add(-time.Minute)
+	for {
+		next, err := r.NextStart(t)
+		if err == cron.ErrNoNextTime {
+			return starts, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if next == nil || !next.Before(until) {
+			return starts, nil
+		}
+		starts = append(starts, *next)
+		t = *next
+	}
+}
+
+// DeleteRoom removes a room of a frontend. A running
+// meeting of the room is not ended.
+func DeleteRoom(
+	ctx context.Context,
+	tx pgx.Tx,
+	frontendID string,
+	id string,
+) error {
+	qry := `DELETE FROM rooms WHERE frontend_id = $1 AND id = $2`
+	_, err := tx.Exec(ctx, qry, frontendID, id)
+	return err
+}
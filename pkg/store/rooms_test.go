@@ -0,0 +1,176 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestRooms(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	room := &Room{
+		FrontendID:  frontend.ID,
+		MeetingID:   "weekly",
+		Name:        "Weekly Meeting",
+		Params:      bbb.Params{"welcome": "Hi!"},
+		ModeratorPW: "mod",
+		AttendeePW:  "att",
+	}
+	if err := room.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := room.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if room.ID == "" {
+		t.Error("expected an id")
+	}
+
+	found, err := GetRoomByMeetingID(ctx, tx, frontend.ID, "weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found == nil || found.Params["welcome"] != "Hi!" {
+		t.Error("unexpected room:", found)
+	}
+
+	if err := DeleteRoom(ctx, tx, frontend.ID, room.ID); err != nil {
+		t.Fatal(err)
+	}
+	found, err = GetRoomByMeetingID(ctx, tx, frontend.ID, "weekly")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if found != nil {
+		t.Error("the room should be deleted")
+	}
+}
+
+func TestRoomValidate(t *testing.T) {
+	room := &Room{
+		MeetingID:   "room",
+		ModeratorPW: "pw",
+		AttendeePW:  "pw",
+		Params:      bbb.Params{"meetingID": "other"},
+	}
+	err := room.Validate()
+	if err == nil {
+		t.Fatal("expected a validation error")
+	}
+	verr := err.(ValidationError)
+	if _, ok := verr["attendee_pw"]; !ok {
+		t.Error("expected an error for equal passwords")
+	}
+	if _, ok := verr["params"]; !ok {
+		t.Error("expected an error for the params")
+	}
+}
+
+func TestRoomCreateParams(t *testing.T) {
+	room := &Room{
+		MeetingID:   "room",
+		ModeratorPW: "mod",
+		AttendeePW:  "att",
+		Params:      bbb.Params{"record": "true"},
+	}
+	params := room.CreateParams()
+	if params["meetingID"] != "room" || params["name"] != "room" {
+		t.Error("unexpected params:", params)
+	}
+	if params["record"] != "true" {
+		t.Error("expected the room params:", params)
+	}
+	if _, ok := room.Params["meetingID"]; ok {
+		t.Error("the room params must not be modified")
+	}
+}
+
+func TestRoomSchedule(t *testing.T) {
+	room := &Room{
+		MeetingID:   "lecture",
+		ModeratorPW: "mod",
+		AttendeePW:  "att",
+		Schedule:    "0 10 * * 1",
+		Timezone:    "Not/AZone",
+	}
+	if err := room.Validate(); err == nil {
+		t.Error("expected an error for the timezone")
+	}
+	room.Timezone = "UTC"
+	if err := room.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	// A wednesday
+	now := time.Date(2021, 6, 2, 10, 17, 42, 0, time.UTC)
+	next, err := room.NextStart(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !next.Equal(time.Date(2021, 6, 7, 10, 0, 0, 0, time.UTC)) {
+		t.Error("unexpected next start:", next)
+	}
+
+	starts, err := room.Occurrences(*next, next.AddDate(0, 0, 14))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(starts) != 2 || !starts[0].Equal(*next) {
+		t.Error("unexpected occurrences:", starts)
+	}
+
+	room.Schedule = ""
+	next, err = room.NextStart(now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next != nil {
+		t.Error("expected no start without schedule")
+	}
+}
+
+func TestLockRoom(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	frontend := frontendStateFactory()
+	if err := frontend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	room := &Room{
+		FrontendID: frontend.ID,
+		MeetingID:  "locked",
+	}
+	if err := room.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatal(err)
+	}
+	defer pool.Exec(ctx, "DELETE FROM frontends WHERE id = $1", frontend.ID)
+
+	tx1 := beginTest(ctx, t)
+	defer tx1.Rollback(ctx)
+	if err := LockRoom(ctx, tx1, room.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	// A second lock waits for the first
+	tx2 := beginTest(ctx, t)
+	defer tx2.Rollback(ctx)
+	if _, err := tx2.Exec(ctx, "SET LOCAL lock_timeout = '100ms'"); err != nil {
+		t.Fatal(err)
+	}
+	if err := LockRoom(ctx, tx2, room.ID); err == nil {
+		t.Error("expected the room to be locked")
+	}
+}
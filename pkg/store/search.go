@@ -0,0 +1,147 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jackc/pgx/v4"
+)
+
+// Types of search results
+const (
+	SearchResultMeeting   = "meeting"
+	SearchResultRecording = "recording"
+)
+
+// MaxSearchTerms limits the terms of a search query
+const MaxSearchTerms = 8
+
+// A SearchResult is a meeting or recording
+// matching the search query.
+type SearchResult struct {
+	Type       string  `json:"type"`
+	ID         string  `json:"id"`
+	Name       string  `json:"name"`
+	MeetingID  string  `json:"meeting_id"`
+	FrontendID *string `json:"frontend_id,omitempty"`
+	BackendID  *string `json:"backend_id,omitempty"`
+	Rank       float64 `json:"rank"`
+}
+
+// SearchQuery converts the input of a search box into
+// a text search query. All terms must match, the last
+// term as prefix. Only letters and digits are used, so
+// the query can not contain operators.
+// If there are no terms, the query is empty.
+func SearchQuery(input string) string {
+	terms := strings.FieldsFunc(strings.ToLower(input), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(terms) > MaxSearchTerms {
+		terms = terms[:MaxSearchTerms]
+	}
+	for i, t := range terms {
+		terms[i] = t + ":*"
+	}
+	return strings.Join(terms, " & ")
+}
+
+// SearchMeetings finds the meetings by name,
+// meeting ID and metadata.
+func SearchMeetings(
+	ctx context.Context,
+	tx pgx.Tx,
+	query string,
+	limit int,
+) ([]*SearchResult, error) {
+	qry := `
+		SELECT meetings.id,
+		       COALESCE(meetings.state->>'MeetingName', ''),
+		       meetings.frontend_id,
+		       meetings.backend_id,
+		       ts_rank(meeting_search_document(meetings.id, meetings.state),
+		               q.query)::float8
+		  FROM meetings,
+		       to_tsquery('simple', $1) AS q(query)
+		 WHERE meeting_search_document(meetings.id, meetings.state)
+		       @@ q.query
+		 ORDER BY 5 DESC, meetings.id ASC
+		 LIMIT $2`
+	rows, err := tx.Query(ctx, qry, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*SearchResult{}
+	for rows.Next() {
+		r := &SearchResult{Type: SearchResultMeeting}
+		if err := rows.Scan(
+			&r.ID,
+			&r.Name,
+			&r.FrontendID,
+			&r.BackendID,
+			&r.Rank,
+		); err != nil {
+			return nil, err
+		}
+		r.MeetingID = r.ID
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// SearchRecordings finds the recordings by name,
+// meeting ID and metadata.
+func SearchRecordings(
+	ctx context.Context,
+	tx pgx.Tx,
+	query string,
+	limit int,
+) ([]*SearchResult, error) {
+	qry := `
+		SELECT recordings.id,
+		       COALESCE(recordings.state->>'Name', ''),
+		       COALESCE(recordings.state->>'MeetingID', ''),
+		       recordings.backend_id,
+		       ts_rank(recording_search_document(recordings.state),
+		               q.query)::float8
+		  FROM recordings,
+		       to_tsquery('simple', $1) AS q(query)
+		 WHERE recording_search_document(recordings.state) @@ q.query
+		 ORDER BY 5 DESC, recordings.id ASC
+		 LIMIT $2`
+	rows, err := tx.Query(ctx, qry, query, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	results := []*SearchResult{}
+	for rows.Next() {
+		r := &SearchResult{Type: SearchResultRecording}
+		if err := rows.Scan(
+			&r.ID,
+			&r.Name,
+			&r.MeetingID,
+			&r.BackendID,
+			&r.Rank,
+		); err != nil {
+			return nil, err
+		}
+		results = append(results, r)
+	}
+	return results, rows.Err()
+}
+
+// SortSearchResults orders the results by rank
+// and limits them.
+func SortSearchResults(results []*SearchResult, limit int) []*SearchResult {
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Rank > results[j].Rank
+	})
+	if len(results) > limit {
+		results = results[:limit]
+	}
+	return results
+}
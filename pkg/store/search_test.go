@@ -0,0 +1,114 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+)
+
+func TestSearchQuery(t *testing.T) {
+	tests := map[string]string{
+		"Physics 101":       "physics:* & 101:*",
+		"  lab-meeting ":    "lab:* & meeting:*",
+		"foo & !bar | (baz": "foo:* & bar:* & baz:*",
+		"Übung":             "übung:*",
+		"':* &":             "",
+	}
+	for input, expected := range tests {
+		if q := SearchQuery(input); q != expected {
+			t.Errorf("unexpected query for %q: %q", input, q)
+		}
+	}
+	q := SearchQuery("a b c d e f g h i j")
+	if q != "a:* & b:* & c:* & d:* & e:* & f:* & g:* & h:*" {
+		t.Error("unexpected query:", q)
+	}
+}
+
+func TestSortSearchResults(t *testing.T) {
+	results := SortSearchResults([]*SearchResult{
+		{ID: "a", Rank: 0.1},
+		{ID: "b", Rank: 0.5},
+		{ID: "c", Rank: 0.3},
+	}, 2)
+	if len(results) != 2 {
+		t.Fatal("unexpected results:", results)
+	}
+	if results[0].ID != "b" || results[1].ID != "c" {
+		t.Error("unexpected order:", results[0].ID, results[1].ID)
+	}
+}
+
+func TestSearchMeetings(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	m, err := meetingStateFactory(ctx, tx, &MeetingState{
+		ID:         uuid.New().String(),
+		InternalID: uuid.New().String(),
+		Meeting: &bbb.Meeting{
+			MeetingName: "Quantum Physics Lecture",
+			Metadata: bbb.Metadata{
+				"course": "physics-101",
+			},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, input := range []string{"quantum lec", "physics-101"} {
+		results, err := SearchMeetings(ctx, tx, SearchQuery(input), 10)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(results) != 1 || results[0].ID != m.ID {
+			t.Error("unexpected results for", input, results)
+		}
+	}
+	results, err := SearchMeetings(ctx, tx, SearchQuery("chemistry"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Error("unexpected results:", results)
+	}
+}
+
+func TestSearchRecordings(t *testing.T) {
+	ctx := context.Background()
+	tx := beginTest(ctx, t)
+	defer tx.Rollback(ctx)
+
+	backend := backendStateFactory()
+	if err := backend.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+	rec := NewRecordingState(backend.ID, &bbb.Recording{
+		RecordID:          "a0cb6b4a9c7d5d7ecf0b7a6cbd7d6e5d3c2b1a0f-1531240571142",
+		InternalMeetingID: "a0cb6b4a9c7d5d7ecf0b7a6cbd7d6e5d3c2b1a0f-1531240571142",
+		MeetingID:         "meeting42",
+		Name:              "Organic Chemistry",
+	})
+	if err := rec.Save(ctx, tx); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := SearchRecordings(ctx, tx, SearchQuery("organic"), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatal("unexpected results:", results)
+	}
+	if results[0].ID != rec.ID || results[0].MeetingID != "meeting42" {
+		t.Error("unexpected result:", results[0])
+	}
+}
@@ -1,12 +1,156 @@
 package store
 
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gitlab.com/infra.run/public/b3scale/pkg/bbb"
+	"gitlab.com/infra.run/public/b3scale/pkg/config"
+)
+
 // Tags are a list of strings with labels to declare
 // for example backend capabilities
 type Tags []string
 
 // BackendSettings hold per backend runtime configuration.
 type BackendSettings struct {
-	Tags Tags `json:"tags,omitempty"`
+	Tags   Tags            `json:"tags,omitempty"`
+	Canary *CanarySettings `json:"canary,omitempty"`
+
+	// Timeouts of requests to the backend by resource,
+	// e.g. {"create": "2m"}
+	Timeouts map[string]string `json:"timeouts,omitempty"`
+
+	// LogURLs are templates of links to the logs of the
+	// backend by name, e.g. {"loki": "https://grafana/explore?..."}
+	LogURLs map[string]string `json:"log_urls,omitempty"`
+
+	// TLS configures client certificates and the
+	// verification of the backend certificate.
+	TLS *BackendTLSSettings `json:"tls,omitempty"`
+
+	// Proxy is the URL of the proxy for requests to the
+	// backend or "direct". If empty, the global proxy
+	// configuration is used.
+	Proxy string `json:"proxy,omitempty"`
+
+	// AddressFamily prefers "ipv4" or "ipv6" addresses
+	// when connecting to a dual-stack backend.
+	AddressFamily string `json:"address_family,omitempty"`
+
+	// Addresses pin the backend host to IP addresses
+	// instead of resolving the name.
+	Addresses []string `json:"addresses,omitempty"`
+
+	// ConnectAddress is the host or host:port connected
+	// to instead of the backend host, keeping the Host
+	// header and the TLS server name.
+	ConnectAddress string `json:"connect_address,omitempty"`
+
+	// ServerName overrides the Host header and the
+	// TLS server name of the requests.
+	ServerName string `json:"server_name,omitempty"`
+}
+
+// Placeholders of the log URL templates
+const (
+	LogURLMeetingID         = "{meeting_id}"
+	LogURLInternalMeetingID = "{internal_meeting_id}"
+)
+
+// ValidateLogURLs checks that all log URL
+// templates are http(s) URLs.
+func (s BackendSettings) ValidateLogURLs() error {
+	for name, tmpl := range s.LogURLs {
+		u, err := url.Parse(tmpl)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("%s: should start with http(s)://", name)
+		}
+	}
+	return nil
+}
+
+// LogLinks renders the log URL templates for a meeting.
+// The placeholders are replaced with the query escaped IDs.
+func (s BackendSettings) LogLinks(
+	meetingID, internalMeetingID string,
+) map[string]string {
+	if len(s.LogURLs) == 0 {
+		return nil
+	}
+	r := strings.NewReplacer(
+		LogURLMeetingID, url.QueryEscape(meetingID),
+		LogURLInternalMeetingID, url.QueryEscape(internalMeetingID))
+	links := make(map[string]string, len(s.LogURLs))
+	for name, tmpl := range s.LogURLs {
+		links[name] = r.Replace(tmpl)
+	}
+	return links
+}
+
+// RequestTimeouts decodes the timeouts of the backend
+func (s BackendSettings) RequestTimeouts() (bbb.Timeouts, error) {
+	timeouts := make(bbb.Timeouts, len(s.Timeouts))
+	for resource, val := range s.Timeouts {
+		timeout, err := time.ParseDuration(val)
+		if err != nil {
+			return nil, err
+		}
+		if timeout <= 0 {
+			return nil, fmt.Errorf("timeout must be positive: %s", val)
+		}
+		if timeout > bbb.MaxBackendTimeout {
+			return nil, fmt.Errorf(
+				"timeout must not exceed %v: %s", bbb.MaxBackendTimeout, val)
+		}
+		timeouts[resource] = timeout
+	}
+	return timeouts, nil
+}
+
+// BackendTLSSettings are the paths of PEM files on
+// the host of b3scaled for connecting to the backend.
+type BackendTLSSettings struct {
+	// ClientCert and ClientKey are presented to
+	// the backend (mTLS).
+	ClientCert string `json:"client_cert,omitempty"`
+	ClientKey  string `json:"client_key,omitempty"`
+
+	// RootCA verifies the backend certificate
+	// instead of the system roots.
+	RootCA string `json:"root_ca,omitempty"`
+}
+
+// Validate checks that the client certificate and
+// key are set together and all paths are absolute.
+func (s *BackendTLSSettings) Validate() error {
+	if (s.ClientCert == "") != (s.ClientKey == "") {
+		return fmt.Errorf("client_cert and client_key are required together")
+	}
+	for _, p := range []string{s.ClientCert, s.ClientKey, s.RootCA} {
+		if p != "" && !filepath.IsAbs(p) {
+			return fmt.Errorf("path must be absolute: %s", p)
+		}
+	}
+	return nil
+}
+
+// CanarySettings mark a backend as canary, e.g. for
+// validating a new BBB version. A canary backend only
+// receives a share of the new meetings and can get
+// copies of read-only requests.
+type CanarySettings struct {
+	// Weight is the share of new meetings (0..1)
+	Weight float64 `json:"weight"`
+	// Mirror read-only requests to the backend
+	Mirror bool `json:"mirror"`
 }
 
 // FrontendSettings hold all well known settings for a
@@ -14,6 +158,508 @@ type BackendSettings struct {
 type FrontendSettings struct {
 	RequiredTags        Tags                         `json:"required_tags,omitempty"`
 	DefaultPresentation *DefaultPresentationSettings `json:"default_presentation,omitempty"`
+
+	// AllowedNetworks restrict the source addresses of
+	// requests of the frontend, e.g. ["192.0.2.0/24"].
+	// All addresses are allowed if empty.
+	AllowedNetworks []string `json:"allowed_networks,omitempty"`
+
+	// ReplayProtection rejects requests with a checksum
+	// that was already seen.
+	ReplayProtection *ReplayProtectionSettings `json:"replay_protection,omitempty"`
+
+	// JoinExpiry rejects join links older than
+	// the maximum age.
+	JoinExpiry *JoinExpirySettings `json:"join_expiry,omitempty"`
+
+	// GuestPolicy sets and maps the guest policy
+	// of created meetings.
+	GuestPolicy *GuestPolicySettings `json:"guest_policy,omitempty"`
+
+	// Billing are hints for the billing export
+	Billing *BillingSettings `json:"billing,omitempty"`
+
+	// MeetingLifetime limits the duration of meetings,
+	// even if the frontend did not pass a duration.
+	MeetingLifetime *MeetingLifetimeSettings `json:"meeting_lifetime,omitempty"`
+
+	// NameSanitization cleans the full names of
+	// joining users.
+	NameSanitization *NameSanitizationSettings `json:"name_sanitization,omitempty"`
+
+	// Branding sets create parameters like
+	// the logo of the meetings.
+	Branding *BrandingSettings `json:"branding,omitempty"`
+
+	// RoutingSchedule changes the routing of
+	// new meetings during time windows.
+	RoutingSchedule []*RoutingScheduleSettings `json:"routing_schedule,omitempty"`
+
+	// MeetingLabels are attached to the
+	// meetings created by the frontend.
+	MeetingLabels *MeetingLabelsSettings `json:"meeting_labels,omitempty"`
+}
+
+// Inherit returns the settings, where settings not set
+// are taken from the parent settings.
+func (s FrontendSettings) Inherit(parent FrontendSettings) FrontendSettings {
+	if s.RequiredTags == nil {
+		s.RequiredTags = parent.RequiredTags
+	}
+	if s.DefaultPresentation == nil {
+		s.DefaultPresentation = parent.DefaultPresentation
+	}
+	if len(s.AllowedNetworks) == 0 {
+		s.AllowedNetworks = parent.AllowedNetworks
+	}
+	if s.ReplayProtection == nil {
+		s.ReplayProtection = parent.ReplayProtection
+	}
+	if s.JoinExpiry == nil {
+		s.JoinExpiry = parent.JoinExpiry
+	}
+	if s.GuestPolicy == nil {
+		s.GuestPolicy = parent.GuestPolicy
+	}
+	if s.Billing == nil {
+		s.Billing = parent.Billing
+	}
+	if s.MeetingLifetime == nil {
+		s.MeetingLifetime = parent.MeetingLifetime
+	}
+	if s.NameSanitization == nil {
+		s.NameSanitization = parent.NameSanitization
+	}
+	if s.Branding == nil {
+		s.Branding = parent.Branding
+	}
+	if s.RoutingSchedule == nil {
+		s.RoutingSchedule = parent.RoutingSchedule
+	}
+	if s.MeetingLabels == nil {
+		s.MeetingLabels = parent.MeetingLabels
+	}
+	return s
+}
+
+// ActiveRoutingSchedule returns the first routing
+// schedule active at the time, or nil.
+func (s FrontendSettings) ActiveRoutingSchedule(t time.Time) *RoutingScheduleSettings {
+	for _, rs := range s.RoutingSchedule {
+		if rs != nil && rs.Active(t) {
+			return rs
+		}
+	}
+	return nil
+}
+
+// ReplayProtectionSettings configure how long request
+// checksums are remembered and for which resources.
+type ReplayProtectionSettings struct {
+	// Window is a duration like "10m"
+	Window string `json:"window"`
+
+	// Resources are protected, default is join
+	Resources []string `json:"resources,omitempty"`
+}
+
+// WindowDuration parses the replay window
+func (s *ReplayProtectionSettings) WindowDuration() (time.Duration, error) {
+	window, err := time.ParseDuration(s.Window)
+	if err != nil {
+		return 0, err
+	}
+	if window <= 0 {
+		return 0, fmt.Errorf("window must be positive: %s", s.Window)
+	}
+	return window, nil
+}
+
+// Protects checks if requests to the resource are
+// protected against replays.
+func (s *ReplayProtectionSettings) Protects(resource string) bool {
+	if len(s.Resources) == 0 {
+		return resource == bbb.ResourceJoin
+	}
+	for _, r := range s.Resources {
+		if r == resource {
+			return true
+		}
+	}
+	return false
+}
+
+// Networks parses the allowed networks. A single
+// address is treated as a network with one host.
+func (s FrontendSettings) Networks() ([]*net.IPNet, error) {
+	return config.ParseNetworks(s.AllowedNetworks)
+}
+
+// AllowsIP checks if requests from the address are
+// allowed. Invalid networks do not match any address.
+func (s FrontendSettings) AllowsIP(ip net.IP) bool {
+	if len(s.AllowedNetworks) == 0 {
+		return true
+	}
+	if ip == nil {
+		return false
+	}
+	networks, err := s.Networks()
+	if err != nil {
+		return false
+	}
+	for _, network := range networks {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultJoinTimestampParam is the parameter of a join
+// link with the unix time the link was issued.
+const DefaultJoinTimestampParam = "timestamp"
+
+// JoinExpirySettings configure the maximum age of join
+// links. The age is derived from a timestamp parameter
+// added by the frontend when signing the link.
+type JoinExpirySettings struct {
+	// MaxAge is a duration like "15m"
+	MaxAge string `json:"max_age"`
+
+	// Param is the name of the timestamp parameter,
+	// default is "timestamp".
+	Param string `json:"param,omitempty"`
+}
+
+// MaxAgeDuration parses the maximum age
+func (s *JoinExpirySettings) MaxAgeDuration() (time.Duration, error) {
+	maxAge, err := time.ParseDuration(s.MaxAge)
+	if err != nil {
+		return 0, err
+	}
+	if maxAge <= 0 {
+		return 0, fmt.Errorf("max age must be positive: %s", s.MaxAge)
+	}
+	return maxAge, nil
+}
+
+// TimestampParam gets the name of the timestamp parameter
+func (s *JoinExpirySettings) TimestampParam() string {
+	if s.Param == "" {
+		return DefaultJoinTimestampParam
+	}
+	return s.Param
+}
+
+// GuestPolicySettings configure the guest policy of
+// meetings created by a frontend.
+type GuestPolicySettings struct {
+	// Default is used if the create request has no
+	// or an invalid guest policy.
+	Default string `json:"default,omitempty"`
+
+	// Force the default policy
+	Force bool `json:"force,omitempty"`
+
+	// Map translates values sent by the frontend,
+	// e.g. {"MODERATOR": "ASK_MODERATOR"}
+	Map map[string]string `json:"map,omitempty"`
+}
+
+// Validate checks that only valid guest
+// policies are set.
+func (s *GuestPolicySettings) Validate() error {
+	if s.Default != "" && !bbb.IsValidGuestPolicy(s.Default) {
+		return fmt.Errorf("invalid guest policy: %s", s.Default)
+	}
+	if s.Force && s.Default == "" {
+		return fmt.Errorf("a default is required when forced")
+	}
+	for from, to := range s.Map {
+		if !bbb.IsValidGuestPolicy(to) {
+			return fmt.Errorf("invalid guest policy for %s: %s", from, to)
+		}
+	}
+	return nil
+}
+
+// MeetingLifetimeSettings configure the maximum duration
+// of meetings created by a frontend. Meetings running
+// longer are ended by the controller.
+type MeetingLifetimeSettings struct {
+	// MaxDuration is a duration like "4h"
+	MaxDuration string `json:"max_duration"`
+}
+
+// Duration parses the maximum duration
+func (s *MeetingLifetimeSettings) Duration() (time.Duration, error) {
+	max, err := time.ParseDuration(s.MaxDuration)
+	if err != nil {
+		return 0, err
+	}
+	if max <= 0 {
+		return 0, fmt.Errorf(
+			"max duration must be positive: %s", s.MaxDuration)
+	}
+	return max, nil
+}
+
+// DefaultNameMaxLength is the maximum number of characters
+// of a sanitized full name, if not configured.
+const DefaultNameMaxLength = 64
+
+// NameSanitizationSettings configure how the full
+// names of joining users are cleaned. Whitespace is
+// always trimmed and collapsed and control characters
+// are removed.
+type NameSanitizationSettings struct {
+	// MaxLength limits the number of characters
+	MaxLength int `json:"max_length,omitempty"`
+
+	// StripEmoji removes emoji and other symbols
+	StripEmoji bool `json:"strip_emoji,omitempty"`
+
+	// Fallback is used if the name is empty
+	// after the sanitization.
+	Fallback string `json:"fallback,omitempty"`
+}
+
+// Validate checks the maximum length
+func (s *NameSanitizationSettings) Validate() error {
+	if s.MaxLength < 0 {
+		return fmt.Errorf("max length must not be negative: %d", s.MaxLength)
+	}
+	return nil
+}
+
+// MaxNameLength gets the maximum number of characters
+func (s *NameSanitizationSettings) MaxNameLength() int {
+	if s.MaxLength == 0 {
+		return DefaultNameMaxLength
+	}
+	return s.MaxLength
+}
+
+// Placeholders of the branding templates
+const (
+	BrandingMeetingName = "{meeting_name}"
+	BrandingMeetingID   = "{meeting_id}"
+	BrandingFrontend    = "{frontend}"
+)
+
+// BrandingSettings configure the look of the meetings
+// of a frontend. Texts may contain placeholders, which
+// are replaced with the meeting name and ID and the
+// frontend key.
+type BrandingSettings struct {
+	Logo           string `json:"logo,omitempty"`
+	CustomStyleURL string `json:"custom_style_url,omitempty"`
+	Welcome        string `json:"welcome,omitempty"`
+	BannerText     string `json:"banner_text,omitempty"`
+	BannerColor    string `json:"banner_color,omitempty"`
+
+	// Params are additional create parameters,
+	// e.g. a virtual background.
+	Params map[string]string `json:"params,omitempty"`
+
+	// Force replaces parameters sent by the frontend
+	Force bool `json:"force,omitempty"`
+}
+
+// Validate checks that the logo and the
+// style are http(s) URLs.
+func (s *BrandingSettings) Validate() error {
+	for name, val := range map[string]string{
+		"logo":             s.Logo,
+		"custom_style_url": s.CustomStyleURL,
+	} {
+		if val == "" {
+			continue
+		}
+		u, err := url.Parse(val)
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("%s: should start with http(s)://", name)
+		}
+	}
+	return nil
+}
+
+// MeetingLabelsSettings attach labels to the meetings
+// of a frontend, in addition to the labels passed as
+// meta_label_<key> create parameters.
+type MeetingLabelsSettings struct {
+	// Labels are attached to all meetings,
+	// e.g. {"department": "physics"}
+	Labels Labels `json:"labels,omitempty"`
+
+	// Meta maps labels to meta parameters of the
+	// create request, e.g. {"course": "meta_course-id"}
+	Meta map[string]string `json:"meta,omitempty"`
+}
+
+// Validate checks the labels and the meta parameters
+func (s *MeetingLabelsSettings) Validate() error {
+	if err := s.Labels.Validate(); err != nil {
+		return err
+	}
+	if len(s.Labels)+len(s.Meta) > MaxLabels {
+		return fmt.Errorf("at most %d labels are allowed", MaxLabels)
+	}
+	for key, param := range s.Meta {
+		if !ValidLabelKey(key) {
+			return fmt.Errorf("invalid label key: %s", key)
+		}
+		if !strings.HasPrefix(param, "meta_") {
+			return fmt.Errorf("%s is not a meta parameter", param)
+		}
+	}
+	return nil
+}
+
+// Days of the week in routing schedules
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// RoutingScheduleSettings change the routing of new
+// meetings of a frontend during a recurring time window,
+// e.g. exams are held on dedicated backends.
+type RoutingScheduleSettings struct {
+	// Days of the week like "mon" the window starts.
+	// The window starts every day if empty.
+	Days []string `json:"days,omitempty"`
+
+	// From and Until are times of the day like "08:00".
+	// A window ending before it starts ends the next day.
+	From  string `json:"from"`
+	Until string `json:"until"`
+
+	// Timezone is a location like "Europe/Berlin",
+	// the default is UTC.
+	Timezone string `json:"timezone,omitempty"`
+
+	// RequiredTags are required from the backends during
+	// the window, in addition to the required tags of
+	// the frontend.
+	RequiredTags Tags `json:"required_tags,omitempty"`
+
+	// Exclusive reserves the backends with the required
+	// tags for the frontend during the window.
+	Exclusive bool `json:"exclusive,omitempty"`
+}
+
+// parseTimeOfDay parses a time like 08:30 as
+// duration since midnight.
+func parseTimeOfDay(val string) (time.Duration, error) {
+	t, err := time.Parse("15:04", val)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day: %s", val)
+	}
+	return time.Duration(t.Hour())*time.Hour +
+		time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Validate checks the days, times and timezone
+func (s *RoutingScheduleSettings) Validate() error {
+	for _, day := range s.Days {
+		if _, ok := weekdays[day]; !ok {
+			return fmt.Errorf("unknown day: %s", day)
+		}
+	}
+	from, err := parseTimeOfDay(s.From)
+	if err != nil {
+		return err
+	}
+	until, err := parseTimeOfDay(s.Until)
+	if err != nil {
+		return err
+	}
+	if from == until {
+		return fmt.Errorf("window is empty: %s-%s", s.From, s.Until)
+	}
+	if _, err := time.LoadLocation(s.Timezone); err != nil {
+		return err
+	}
+	if s.Exclusive && len(s.RequiredTags) == 0 {
+		return fmt.Errorf("exclusive requires tags")
+	}
+	return nil
+}
+
+// startsOn checks if the window starts on the day
+func (s *RoutingScheduleSettings) startsOn(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if weekdays[d] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// Active checks if the time is within the window.
+// Invalid settings are never active.
+func (s *RoutingScheduleSettings) Active(t time.Time) bool {
+	from, err := parseTimeOfDay(s.From)
+	if err != nil {
+		return false
+	}
+	until, err := parseTimeOfDay(s.Until)
+	if err != nil {
+		return false
+	}
+	loc, err := time.LoadLocation(s.Timezone)
+	if err != nil {
+		return false
+	}
+	t = t.In(loc)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	now := t.Sub(midnight)
+
+	if from < until {
+		return s.startsOn(t.Weekday()) && now >= from && now < until
+	}
+	// The window passes midnight: it either started today
+	// or on the day before.
+	if now >= from {
+		return s.startsOn(t.Weekday())
+	}
+	return now < until && s.startsOn(midnight.AddDate(0, 0, -1).Weekday())
+}
+
+// BillingSettings are pricing hints of a frontend
+// used in the billing export.
+type BillingSettings struct {
+	// Customer is a reference in the billing system
+	Customer string `json:"customer,omitempty"`
+	Plan     string `json:"plan,omitempty"`
+	Currency string `json:"currency,omitempty"`
+
+	// Prices per request by resource,
+	// e.g. {"create": 0.5, "join": 0.01}
+	Prices map[string]float64 `json:"prices,omitempty"`
+}
+
+// Validate checks the prices
+func (s *BillingSettings) Validate() error {
+	for resource, price := range s.Prices {
+		if price < 0 {
+			return fmt.Errorf("negative price for %s", resource)
+		}
+	}
+	return nil
 }
 
 // DefaultPresentationSettings configure a per frontend
@@ -0,0 +1,232 @@
+package store
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestFrontendSettingsAllowsIP(t *testing.T) {
+	s := FrontendSettings{}
+	if !s.AllowsIP(net.ParseIP("198.51.100.1")) {
+		t.Error("all addresses should be allowed without networks")
+	}
+
+	s.AllowedNetworks = []string{"192.0.2.0/24", "2001:db8::1"}
+	if !s.AllowsIP(net.ParseIP("192.0.2.42")) {
+		t.Error("address in network should be allowed")
+	}
+	if !s.AllowsIP(net.ParseIP("2001:db8::1")) {
+		t.Error("single address should be allowed")
+	}
+	if s.AllowsIP(net.ParseIP("2001:db8::2")) {
+		t.Error("address should not be allowed")
+	}
+	if s.AllowsIP(net.ParseIP("198.51.100.1")) {
+		t.Error("address outside network should not be allowed")
+	}
+	if s.AllowsIP(nil) {
+		t.Error("invalid address should not be allowed")
+	}
+}
+
+func TestFrontendSettingsAllowsIPv6(t *testing.T) {
+	s := FrontendSettings{
+		AllowedNetworks: []string{
+			"2001:db8:23::/48",
+			"[2001:db8:42::1]",
+			"::ffff:192.0.2.0/120",
+		},
+	}
+	allowed := []string{
+		"2001:db8:23::1",
+		"2001:db8:42::1",
+		"192.0.2.42",
+		"::ffff:192.0.2.23",
+	}
+	for _, ip := range allowed {
+		if !s.AllowsIP(net.ParseIP(ip)) {
+			t.Error("address should be allowed:", ip)
+		}
+	}
+	denied := []string{
+		"2001:db8:24::1",
+		"2001:db8:42::2",
+		"198.51.100.1",
+	}
+	for _, ip := range denied {
+		if s.AllowsIP(net.ParseIP(ip)) {
+			t.Error("address should not be allowed:", ip)
+		}
+	}
+}
+
+func TestFrontendSettingsNetworks(t *testing.T) {
+	s := FrontendSettings{
+		AllowedNetworks: []string{"192.0.2.0/24", "192.0.2.300"},
+	}
+	if _, err := s.Networks(); err == nil {
+		t.Error("expected an error for an invalid address")
+	}
+	if s.AllowsIP(net.ParseIP("192.0.2.1")) {
+		t.Error("invalid networks should not allow any address")
+	}
+}
+
+func TestMeetingLifetimeSettingsDuration(t *testing.T) {
+	s := &MeetingLifetimeSettings{MaxDuration: "4h"}
+	max, err := s.Duration()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if max != 4*time.Hour {
+		t.Error("unexpected max duration:", max)
+	}
+	for _, invalid := range []string{"", "forever", "0s", "-1h"} {
+		s := &MeetingLifetimeSettings{MaxDuration: invalid}
+		if _, err := s.Duration(); err == nil {
+			t.Error("expected an error for:", invalid)
+		}
+	}
+}
+
+func TestFrontendSettingsInherit(t *testing.T) {
+	parent := FrontendSettings{
+		RequiredTags: Tags{"uni"},
+		JoinExpiry:   &JoinExpirySettings{MaxAge: "15m"},
+	}
+	s := FrontendSettings{
+		RequiredTags: Tags{"faculty"},
+	}
+	s = s.Inherit(parent)
+	if s.RequiredTags[0] != "faculty" {
+		t.Error("the own settings should be kept:", s.RequiredTags)
+	}
+	if s.JoinExpiry == nil || s.JoinExpiry.MaxAge != "15m" {
+		t.Error("the join expiry should be inherited")
+	}
+}
+
+func TestRoutingScheduleSettingsActive(t *testing.T) {
+	rs := &RoutingScheduleSettings{
+		Days:     []string{"mon", "wed"},
+		From:     "08:00",
+		Until:    "12:30",
+		Timezone: "Europe/Berlin",
+	}
+	if err := rs.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	berlin, _ := time.LoadLocation("Europe/Berlin")
+	tests := map[time.Time]bool{
+		time.Date(2026, 10, 12, 8, 0, 0, 0, berlin):    true,  // Monday
+		time.Date(2026, 10, 12, 12, 29, 0, 0, berlin):  true,  // Monday
+		time.Date(2026, 10, 12, 12, 30, 0, 0, berlin):  false, // Monday
+		time.Date(2026, 10, 12, 7, 59, 0, 0, berlin):   false, // Monday
+		time.Date(2026, 10, 13, 9, 0, 0, 0, berlin):    false, // Tuesday
+		time.Date(2026, 10, 14, 6, 30, 0, 0, time.UTC): true,  // Wednesday
+	}
+	for tt, active := range tests {
+		if rs.Active(tt) != active {
+			t.Error("unexpected active state:", tt, !active)
+		}
+	}
+
+	// Windows passing midnight
+	rs = &RoutingScheduleSettings{
+		Days:  []string{"fri"},
+		From:  "22:00",
+		Until: "02:00",
+	}
+	if !rs.Active(time.Date(2026, 10, 16, 23, 0, 0, 0, time.UTC)) {
+		t.Error("expected window to be active on friday")
+	}
+	if !rs.Active(time.Date(2026, 10, 17, 1, 0, 0, 0, time.UTC)) {
+		t.Error("expected window to be active on saturday morning")
+	}
+	if rs.Active(time.Date(2026, 10, 16, 1, 0, 0, 0, time.UTC)) {
+		t.Error("window should not be active on friday morning")
+	}
+}
+
+func TestRoutingScheduleSettingsValidate(t *testing.T) {
+	invalid := []*RoutingScheduleSettings{
+		{From: "8", Until: "12:00"},
+		{From: "08:00", Until: "08:00"},
+		{Days: []string{"monday"}, From: "08:00", Until: "12:00"},
+		{From: "08:00", Until: "12:00", Timezone: "Mars/Olympus"},
+		{From: "08:00", Until: "12:00", Exclusive: true},
+	}
+	for _, rs := range invalid {
+		if err := rs.Validate(); err == nil {
+			t.Error("expected an error for:", rs)
+		}
+	}
+}
+
+func TestMeetingLabelsSettingsValidate(t *testing.T) {
+	valid := &MeetingLabelsSettings{
+		Labels: Labels{"department": "physics"},
+		Meta:   map[string]string{"course": "meta_course-id"},
+	}
+	if err := valid.Validate(); err != nil {
+		t.Error(err)
+	}
+	invalid := []*MeetingLabelsSettings{
+		{Labels: Labels{"Department": "physics"}},
+		{Meta: map[string]string{"course": "course-id"}},
+		{Meta: map[string]string{"a course": "meta_course"}},
+	}
+	for _, ml := range invalid {
+		if err := ml.Validate(); err == nil {
+			t.Error("expected an error for:", ml)
+		}
+	}
+}
+
+func TestBackendSettingsLogLinks(t *testing.T) {
+	s := BackendSettings{}
+	if links := s.LogLinks("m1", "i1"); links != nil {
+		t.Error("unexpected links:", links)
+	}
+
+	s.LogURLs = map[string]string{
+		"loki":   "https://grafana/explore?q={internal_meeting_id}",
+		"kibana": "https://kibana/app/discover?meeting={meeting_id}",
+	}
+	if err := s.ValidateLogURLs(); err != nil {
+		t.Error(err)
+	}
+	links := s.LogLinks("room 1", "i1-23")
+	if links["loki"] != "https://grafana/explore?q=i1-23" {
+		t.Error("unexpected link:", links["loki"])
+	}
+	if links["kibana"] != "https://kibana/app/discover?meeting=room+1" {
+		t.Error("unexpected link:", links["kibana"])
+	}
+
+	s.LogURLs["files"] = "file:///var/log/bbb"
+	if err := s.ValidateLogURLs(); err == nil {
+		t.Error("expected an error for a non http url")
+	}
+}
+
+func TestBackendSettingsRequestTimeouts(t *testing.T) {
+	s := BackendSettings{
+		Timeouts: map[string]string{"create": "2m"},
+	}
+	timeouts, err := s.RequestTimeouts()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeouts["create"] != 2*time.Minute {
+		t.Error("unexpected timeouts:", timeouts)
+	}
+
+	for _, val := range []string{"soon", "-1s", "1h"} {
+		s.Timeouts["create"] = val
+		if _, err := s.RequestTimeouts(); err == nil {
+			t.Error("expected an error for:", val)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package systemd
+
+// Minimal sd_notify implementation for
+// supervising the b3scale daemons with systemd.
+
+import (
+	"context"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Well known notify states
+const (
+	StateReady     = "READY=1"
+	StateReloading = "RELOADING=1"
+	StateStopping  = "STOPPING=1"
+	StateWatchdog  = "WATCHDOG=1"
+)
+
+// Notify sends a state to the systemd notify socket.
+// When the socket is not configured, e.g. because the
+// daemon was not started by systemd, false is returned.
+func Notify(state string) (bool, error) {
+	socketAddr := &net.UnixAddr{
+		Name: os.Getenv("NOTIFY_SOCKET"),
+		Net:  "unixgram",
+	}
+	if socketAddr.Name == "" {
+		return false, nil
+	}
+
+	conn, err := net.DialUnix(socketAddr.Net, nil, socketAddr)
+	if err != nil {
+		return false, err
+	}
+	defer conn.Close()
+
+	if _, err = conn.Write([]byte(state)); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// WatchdogInterval returns the interval configured
+// in the service with WatchdogSec. If the watchdog is
+// not enabled for this process, the interval is zero.
+func WatchdogInterval() (time.Duration, error) {
+	usecStr := os.Getenv("WATCHDOG_USEC")
+	if usecStr == "" {
+		return 0, nil
+	}
+	usec, err := strconv.ParseInt(usecStr, 10, 64)
+	if err != nil || usec <= 0 {
+		return 0, err
+	}
+
+	// The watchdog might be meant for another process
+	if pidStr := os.Getenv("WATCHDOG_PID"); pidStr != "" {
+		pid, err := strconv.Atoi(pidStr)
+		if err != nil {
+			return 0, err
+		}
+		if pid != os.Getpid() {
+			return 0, nil
+		}
+	}
+
+	return time.Duration(usec) * time.Microsecond, nil
+}
+
+// Watchdog sends a heartbeat to systemd in half
+// of the watchdog interval, until the context is done.
+// The heartbeat is only sent when the check succeeds.
+// The check can be nil.
+func Watchdog(ctx context.Context, check func() error) {
+	interval, err := WatchdogInterval()
+	if err != nil {
+		log.Error().Err(err).Msg("invalid watchdog configuration")
+		return
+	}
+	if interval == 0 {
+		return // Watchdog is disabled
+	}
+
+	log.Info().
+		Dur("interval", interval).
+		Msg("starting systemd watchdog")
+
+	ticker := time.NewTicker(interval / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if check != nil {
+			if err := check(); err != nil {
+				log.Warn().
+					Err(err).
+					Msg("health check failed, skipping watchdog heartbeat")
+				continue
+			}
+		}
+		if _, err := Notify(StateWatchdog); err != nil {
+			log.Error().Err(err).Msg("watchdog notify")
+		}
+	}
+}
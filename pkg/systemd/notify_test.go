@@ -0,0 +1,85 @@
+package systemd
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	os.Setenv("NOTIFY_SOCKET", "")
+	ok, err := Notify(StateReady)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ok {
+		t.Error("notify should be skipped without socket")
+	}
+
+	addr := &net.UnixAddr{
+		Name: filepath.Join(t.TempDir(), "notify.sock"),
+		Net:  "unixgram",
+	}
+	conn, err := net.ListenUnixgram(addr.Net, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	os.Setenv("NOTIFY_SOCKET", addr.Name)
+	defer os.Unsetenv("NOTIFY_SOCKET")
+
+	ok, err = Notify(StateReady)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok {
+		t.Error("expected notify to be sent")
+	}
+
+	buf := make([]byte, 64)
+	n, err := conn.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(buf[:n]) != StateReady {
+		t.Error("unexpected state:", string(buf[:n]))
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+
+	os.Setenv("WATCHDOG_USEC", "")
+	interval, err := WatchdogInterval()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != 0 {
+		t.Error("unexpected interval:", interval)
+	}
+
+	os.Setenv("WATCHDOG_USEC", "30000000")
+	os.Setenv("WATCHDOG_PID", strconv.Itoa(os.Getpid()))
+	interval, err = WatchdogInterval()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != 30*time.Second {
+		t.Error("unexpected interval:", interval)
+	}
+
+	// Other process
+	os.Setenv("WATCHDOG_PID", "1")
+	interval, err = WatchdogInterval()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if interval != 0 {
+		t.Error("unexpected interval:", interval)
+	}
+}
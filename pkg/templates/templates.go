@@ -17,12 +17,20 @@ var (
 	//go:embed html/meeting-not-found.html
 	tmplMeetingNotFoundHTML string
 
+	//go:embed html/join-link-expired.html
+	tmplJoinLinkExpiredHTML string
+
+	//go:embed html/join-banned.html
+	tmplJoinBannedHTML string
+
 	//go:embed xml/default-presentation-body.xml
 	tmplDefaultPresentationBodyXML string
 
 	tmplRedirect                *template.Template
 	tmplRetryJoin               *template.Template
 	tmplMeetingNotFound         *template.Template
+	tmplJoinLinkExpired         *template.Template
+	tmplJoinBanned              *template.Template
 	tmplDefaultPresentationBody *template.Template
 )
 
@@ -32,6 +40,10 @@ func init() {
 	tmplRetryJoin, _ = template.New("retry_join").Parse(tmplRetryJoinHTML)
 	tmplMeetingNotFound, _ = template.New("meeting_not_found").
 		Parse(tmplMeetingNotFoundHTML)
+	tmplJoinLinkExpired, _ = template.New("join_link_expired").
+		Parse(tmplJoinLinkExpiredHTML)
+	tmplJoinBanned, _ = template.New("join_banned").
+		Parse(tmplJoinBannedHTML)
 	tmplDefaultPresentationBody, _ = template.New("default_presentation").
 		Parse(tmplDefaultPresentationBodyXML)
 }
@@ -57,6 +69,20 @@ func MeetingNotFound() []byte {
 	return res.Bytes()
 }
 
+// JoinLinkExpired applies the join link expired template
+func JoinLinkExpired() []byte {
+	res := new(bytes.Buffer)
+	tmplJoinLinkExpired.Execute(res, nil)
+	return res.Bytes()
+}
+
+// JoinBanned applies the join banned template
+func JoinBanned() []byte {
+	res := new(bytes.Buffer)
+	tmplJoinBanned.Execute(res, nil)
+	return res.Bytes()
+}
+
 // DefaultPresentationBody renders the xml body for
 // a default presentation.
 func DefaultPresentationBody(u, filename string) []byte {
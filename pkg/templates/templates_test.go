@@ -56,3 +56,17 @@ func TestTmplMeetingNotFound(t *testing.T) {
 	res := MeetingNotFound()
 	t.Log(res)
 }
+
+func TestTmplJoinLinkExpired(t *testing.T) {
+	res := JoinLinkExpired()
+	if !bytes.Contains(res, []byte("expired")) {
+		t.Error("unexpected result:", string(res))
+	}
+}
+
+func TestTmplJoinBanned(t *testing.T) {
+	res := JoinBanned()
+	if !bytes.Contains(res, []byte("denied")) {
+		t.Error("unexpected result:", string(res))
+	}
+}